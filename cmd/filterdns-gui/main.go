@@ -0,0 +1,20 @@
+// Command filterdns-gui is the desktop GUI. It drives the daemon
+// (filterdnsd) over its control socket and needs no root itself.
+package main
+
+import (
+	"os"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/gui"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/onboard"
+)
+
+func main() {
+	// Opened through a filterdns:// link (Linux, Windows)
+	if len(os.Args) == 2 && onboard.IsDeepLink(os.Args[1]) {
+		gui.RunDeepLink(os.Args[1])
+		return
+	}
+
+	gui.Run()
+}
@@ -0,0 +1,9 @@
+// Command filterdnsd is the daemon and CLI without any GUI dependencies,
+// for servers and for running the root service.
+package main
+
+import "github.com/zkmkarlsruhe/filterdns-client/internal/cli"
+
+func main() {
+	cli.Run()
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"slices"
 	"sync"
 
 	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
@@ -49,12 +50,11 @@ func (a *App) Enable() error {
 	}
 
 	a.proxy = dns.NewProxy(a.config)
+	if err := a.proxy.Start(); err != nil {
+		return err
+	}
 
-	go func() {
-		a.proxy.Start()
-	}()
-
-	if err := system.SetDNS("127.0.0.1"); err != nil {
+	if err := system.SetDNS(a.proxy.Addresses(), a.config.ManagesInterface); err != nil {
 		a.proxy.Stop()
 		return err
 	}
@@ -94,7 +94,8 @@ func (a *App) UpdateConfig(cfg *config.Config) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	needsRestart := a.running && (cfg.Profile != a.config.Profile || cfg.ServerURL != a.config.ServerURL)
+	needsRestart := a.running && (cfg.Profile != a.config.Profile || cfg.ServerURL != a.config.ServerURL ||
+		!slices.Equal(cfg.BootstrapDNS, a.config.BootstrapDNS) || cfg.CacheSize != a.config.CacheSize)
 
 	a.config = cfg
 	if err := config.Save(cfg); err != nil {
@@ -104,7 +105,9 @@ func (a *App) UpdateConfig(cfg *config.Config) error {
 	if needsRestart && a.proxy != nil {
 		a.proxy.Stop()
 		a.proxy = dns.NewProxy(a.config)
-		go a.proxy.Start()
+		if err := a.proxy.Start(); err != nil {
+			return err
+		}
 	}
 
 	return nil
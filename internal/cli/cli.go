@@ -0,0 +1,3452 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"os/user"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/daemon"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/dns"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/leaktest"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/loadtest"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/onboard"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/privsep"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/service"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/stats"
+	filtersync "github.com/zkmkarlsruhe/filterdns-client/internal/sync"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/system"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/top"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/update"
+)
+
+// Values of --output
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// statusOutput is what "status" prints with --output json
+type statusOutput struct {
+	Profile       string             `json:"profile"`
+	ServerURL     string             `json:"serverUrl"`
+	DaemonRunning bool               `json:"daemonRunning"`
+	Daemon        *daemon.Status     `json:"daemon,omitempty"`
+	DaemonError   string             `json:"daemonError,omitempty"`
+	Forwarders    []config.Forwarder `json:"forwarders"`
+}
+
+// doctorOutput is what "doctor" prints with --output json
+type doctorOutput struct {
+	ServerURL         string                     `json:"serverUrl"`
+	Connectivity      *dns.Connectivity          `json:"connectivity"`
+	Capabilities      *config.ServerCapabilities `json:"capabilities,omitempty"`
+	CapabilitiesError string                     `json:"capabilitiesError,omitempty"`
+	Clock             *filtersync.ClockCheck     `json:"clock,omitempty"`
+	ClockError        string                     `json:"clockError,omitempty"`
+}
+
+// queryOutput is what "query" prints with --output json
+type queryOutput struct {
+	*dns.LookupResult
+
+	// From the daemon's query log, if it is running
+	Source        string            `json:"source,omitempty"`
+	Blocked       bool              `json:"blocked"`
+	ProxyDuration time.Duration     `json:"proxyDuration,omitempty"`
+	Forwarder     *config.Forwarder `json:"forwarder,omitempty"`
+}
+
+// serviceOutput is what "service status" prints with --output json
+type serviceOutput struct {
+	*service.State
+	DaemonReachable bool `json:"daemonReachable"`
+}
+
+// Run parses the command line and executes the CLI command
+func Run() {
+	log.SetOutput(config.RedactingWriter(os.Stderr))
+
+	// Help and messages in the user's language. A config that does not
+	// load is reported by the commands that need it.
+	if cfg, err := config.Load(); err == nil {
+		i18n.Init(cfg.Language)
+	} else {
+		i18n.Init("")
+	}
+
+	rootCmd := &cobra.Command{
+		Use:     "filterdns-client",
+		Short:   i18n.T("FilterDNS desktop client"),
+		Long:    "A DNS filtering client that connects to your FilterDNS server",
+		Version: update.Version,
+	}
+
+	// Output format for scripts and fleet tooling, set with --output
+	var output string
+
+	// Start command - enable DNS filtering via daemon
+	var lifecycleWait, lifecycleQuiet bool
+	var lifecycleTimeout time.Duration
+	startCmd := &cobra.Command{
+		Use:   "start",
+		Short: i18n.T("Start DNS filtering (via daemon)"),
+		Long: `Starts DNS filtering through the daemon. With --wait, returns only once
+filtering is verifiably active: system DNS points at the proxy, the proxy
+answers and FilterDNS accepts the profile.
+
+Exit codes: 0 ok, 1 other error, 2 daemon not running, 3 server rejected
+the profile password, 4 system DNS or the proxy could not be set up,
+5 --wait timed out, 6 no profile configured.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			client := daemon.NewClient()
+			if !client.IsRunning() {
+				fmt.Fprintln(os.Stderr, i18n.T("Daemon not running. Start with: sudo systemctl start filterdns"))
+				os.Exit(exitNoDaemon)
+			}
+
+			status, err := client.Enable()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(exitCode(err))
+			}
+			if lifecycleWait {
+				if err := waitForFiltering(client, true, lifecycleTimeout); err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+					os.Exit(exitCode(err))
+				}
+			}
+			if lifecycleQuiet {
+				return
+			}
+			if output == outputJSON {
+				printJSON(status)
+				return
+			}
+			fmt.Println(i18n.T("DNS filtering enabled for profile: %s", status.Profile))
+		},
+	}
+
+	// Stop command - disable DNS filtering via daemon
+	stopCmd := &cobra.Command{
+		Use:   "stop",
+		Short: i18n.T("Stop DNS filtering (via daemon)"),
+		Long: `Stops DNS filtering through the daemon. With --wait, returns only once
+system DNS no longer points at the proxy.
+
+Exit codes as for start.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			client := daemon.NewClient()
+			if !client.IsRunning() {
+				fmt.Fprintln(os.Stderr, i18n.T("Daemon not running."))
+				os.Exit(exitNoDaemon)
+			}
+
+			status, err := client.Disable()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(exitCode(err))
+			}
+			if lifecycleWait {
+				if err := waitForFiltering(client, false, lifecycleTimeout); err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+					os.Exit(exitCode(err))
+				}
+			}
+			if lifecycleQuiet {
+				return
+			}
+			if output == outputJSON {
+				printJSON(status)
+				return
+			}
+			fmt.Println(i18n.T("DNS filtering disabled."))
+		},
+	}
+	for _, c := range []*cobra.Command{startCmd, stopCmd} {
+		c.Flags().BoolVar(&lifecycleWait, "wait", false, i18n.T("Wait until the change is verifiably in effect"))
+		c.Flags().DurationVar(&lifecycleTimeout, "timeout", 30*time.Second, i18n.T("How long --wait waits"))
+		c.Flags().BoolVarP(&lifecycleQuiet, "quiet", "q", false, i18n.T("Print nothing on success"))
+	}
+
+	// Pause command - temporary bypass that re-enables itself
+	pauseCmd := &cobra.Command{
+		Use:   "pause <duration> | until <time>",
+		Short: i18n.T("Pause DNS filtering for a while (e.g. 'pause 30m', 'pause until 17:00')"),
+		Long: `Pauses DNS filtering and turns it back on automatically, after a
+duration such as 30m or 2h, or at a time of day such as 17:00 (the next
+one, so a time that has passed today means tomorrow).`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			duration, err := parsePause(args, time.Now())
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+
+			client := daemon.NewClient()
+			if !client.IsRunning() {
+				fmt.Fprintln(os.Stderr, i18n.T("Daemon not running."))
+				os.Exit(1)
+			}
+
+			status, err := client.Pause(duration)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			if output == outputJSON {
+				printJSON(status)
+				return
+			}
+			if status.PausedUntil != nil {
+				fmt.Println(i18n.T("DNS filtering paused until %s (%s left).",
+					status.PausedUntil.Format("15:04"), remaining(*status.PausedUntil)))
+			} else {
+				fmt.Println(i18n.T("DNS filtering paused."))
+			}
+		},
+	}
+
+	// Resume command - end a pause early
+	resumeCmd := &cobra.Command{
+		Use:   "resume",
+		Short: i18n.T("End a pause and turn DNS filtering back on"),
+		Run: func(cmd *cobra.Command, args []string) {
+			client := daemon.NewClient()
+			if !client.IsRunning() {
+				fmt.Fprintln(os.Stderr, i18n.T("Daemon not running."))
+				os.Exit(1)
+			}
+
+			status, err := client.Resume()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			if output == outputJSON {
+				printJSON(status)
+				return
+			}
+			fmt.Println(i18n.T("DNS filtering resumed for profile: %s", status.Profile))
+		},
+	}
+
+	// Status command - show status from daemon
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: i18n.T("Show current status"),
+		Run: func(cmd *cobra.Command, args []string) {
+			client := daemon.NewClient()
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+
+			if output == outputJSON {
+				result := statusOutput{
+					Profile:    cfg.Profile,
+					ServerURL:  cfg.ServerURL,
+					Forwarders: cfg.Effective().Forwarders,
+				}
+				if client.IsRunning() {
+					result.DaemonRunning = true
+					status, err := client.Status()
+					if err != nil {
+						result.DaemonError = err.Error()
+					}
+					result.Daemon = status
+				}
+				printJSON(result)
+				return
+			}
+
+			// Show config
+			fmt.Println(i18n.T("Profile:    %s", cfg.Profile))
+			fmt.Println(i18n.T("Server:     %s", config.RedactURL(cfg.ServerURL)))
+
+			// Show daemon status
+			if !client.IsRunning() {
+				fmt.Println(i18n.T("Daemon:     not running"))
+				return
+			}
+
+			status, err := client.Status()
+			if err != nil {
+				fmt.Println(i18n.T("Daemon:     error (%v)", err))
+				return
+			}
+
+			if status.Running {
+				fmt.Println(i18n.T("Filtering:  enabled (%d queries, %d blocked)", status.QueriesTotal, status.QueriesBlocked))
+			} else if status.PausedUntil != nil {
+				fmt.Println(i18n.T("Filtering:  paused until %s (%s left)",
+					status.PausedUntil.Format("15:04"), remaining(*status.PausedUntil)))
+			} else {
+				fmt.Println(i18n.T("Filtering:  disabled"))
+			}
+
+			if st := status.Stats; st != nil {
+				fmt.Println(i18n.T("Stats:      today %d queries (%d blocked), 7 days %d (%d blocked)",
+					st.Today.Queries, st.Today.Blocked, st.Week.Queries, st.Week.Blocked))
+			}
+
+			if status.OutsideDNSBlocked {
+				fmt.Println(i18n.T("Firewall:   DNS outside the proxy blocked"))
+			}
+
+			for _, c := range status.VPNConflicts {
+				switch c.Kind {
+				case daemon.VPNMissingForwarders:
+					fmt.Println(i18n.T("VPN:        %s on %s has no forwarders for its domains", c.VPN, c.Interface))
+					for _, f := range c.Forwarders {
+						fmt.Println(i18n.T("            add with: filterdns-client forwarder add %s %s", f.Domain, f.Server))
+					}
+					continue
+				case daemon.VPNTakeover:
+					fmt.Println(i18n.T("VPN:        %s on %s took over system DNS, pointed back at the proxy", c.VPN, c.Interface))
+				case daemon.VPNStalemate:
+					fmt.Println(i18n.T("VPN:        %s on %s keeps taking over system DNS, left alone", c.VPN, c.Interface))
+				}
+				fmt.Println("            " + i18n.T(daemon.VPNRemedy(c.VPN), c.Interface))
+			}
+
+			if status.SearchShortcuts > 0 {
+				fmt.Println(i18n.T("Search:     %d search-domain expansions answered locally", status.SearchShortcuts))
+			}
+
+			if i := status.Integrity; i != nil {
+				fmt.Println(i18n.T("Integrity:  %d checked, %d mismatches", i.Checked, i.Mismatches))
+				if m := i.Last; m != nil {
+					fmt.Println(i18n.T("            last: %s %s served %v, verified %v (%s)",
+						m.Name, m.Type, m.Upstream, m.Verified, m.At.Format("15:04")))
+				}
+			}
+
+			if h := status.Health; h != nil {
+				if h.OK {
+					fmt.Println(i18n.T("Health:     ok (checked %s)", h.CheckedAt.Format("15:04")))
+				} else {
+					fmt.Println(i18n.T("Health:     degraded (proxy answering: %s, system DNS set: %s, %d repairs)",
+						yesNo(h.ProxyOK), yesNo(h.SystemDNSOK), h.Repairs))
+				}
+				if n := len(h.Events); n > 0 {
+					e := h.Events[n-1]
+					fmt.Println(i18n.T("            last: %s (%s)", e.Message, e.Time.Format("15:04")))
+				}
+			}
+
+			if s := status.Sync; s != nil {
+				switch {
+				case s.Error != "":
+					fmt.Println(i18n.T("Sync:       failed (%s)", s.Error))
+					if s.Offline {
+						fmt.Println(i18n.T("            the network looks offline"))
+					}
+					if s.LastSuccess != nil {
+						fmt.Println(i18n.T("            last worked %s, %d attempts since", s.LastSuccess.Format("Jan 2 15:04"), s.Failures))
+					}
+					if s.NextSync != nil {
+						fmt.Println(i18n.T("            next try at %s", s.NextSync.Format("15:04:05")))
+					}
+				case s.FilteringEnabled:
+					fmt.Println(i18n.T("Sync:       filtering active on server (synced %s)", s.LastSync.Format("15:04")))
+				case s.PausedUntil != nil:
+					fmt.Println(i18n.T("Sync:       paused on server until %s", s.PausedUntil.Format("15:04")))
+				default:
+					fmt.Println(i18n.T("Sync:       paused on server"))
+				}
+				if s.Push {
+					fmt.Println(i18n.T("            live updates from server"))
+				}
+				if s.ClockSkew.Abs() > filtersync.MaxClockSkew {
+					fmt.Println(i18n.T("            warning: this computer's clock is %s the server's", describeSkew(s.ClockSkew)))
+				}
+			}
+
+			if c := status.Connectivity; c != nil {
+				fmt.Println(i18n.T("Network:    udp/53 %s, tcp/853 %s, tcp/443 %s, udp/443 %s (checked %s)",
+					okString(c.UDP53), okString(c.TCP853), okString(c.TCP443), okString(c.UDP443),
+					c.CheckedAt.Format("15:04")))
+			}
+
+			if e := status.Schedule; e != nil && status.ScheduleUntil != nil {
+				fmt.Println(i18n.T("Schedule:   %s until %s (%s)", e.Name, status.ScheduleUntil.Format("15:04"), describeScheduleAction(e)))
+			}
+
+			switch {
+			case status.Network != "":
+				fmt.Println(i18n.T("Wi-Fi:      %s, using the settings for network %s", strings.Join(status.WiFi, ", "), status.Network))
+			case len(status.WiFi) > 0:
+				fmt.Println(i18n.T("Wi-Fi:      %s", strings.Join(status.WiFi, ", ")))
+			}
+
+			if eff := cfg.Effective(); len(eff.Forwarders) > 0 {
+				fmt.Println(i18n.T("Forwarders:"))
+				for _, f := range eff.Forwarders {
+					fmt.Printf("  %s → %s%s\n", f.Domain, f.Server, fromServer(cfg.ServerForwarder(f.Domain)))
+				}
+			}
+		},
+	}
+
+	// Reload command - make the daemon re-read config.json
+	reloadCmd := &cobra.Command{
+		Use:   "reload",
+		Short: i18n.T("Reload configuration in the running daemon"),
+		Run: func(cmd *cobra.Command, args []string) {
+			client := daemon.NewClient()
+			if !client.IsRunning() {
+				fmt.Fprintln(os.Stderr, i18n.T("Daemon not running."))
+				os.Exit(1)
+			}
+
+			cfg, err := client.Reload()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			if output == outputJSON {
+				printJSON(cfg)
+				return
+			}
+			fmt.Println(i18n.T("Configuration reloaded."))
+		},
+	}
+
+	// Doctor command - diagnose upstream connectivity
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: i18n.T("Check which upstream transports this network allows"),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				cfg = config.Default()
+			}
+
+			if output == outputJSON {
+				result := doctorOutput{
+					ServerURL:    cfg.ServerURL,
+					Connectivity: dns.NewDoHClientURL(cfg.DoHURL()).Probe(),
+				}
+				caps, err := filtersync.ProbeCapabilities(cfg.ServerURL, cfg.Profile)
+				if err != nil {
+					result.CapabilitiesError = err.Error()
+				}
+				result.Capabilities = caps
+				if result.Clock, err = filtersync.MeasureClock(cfg.ServerURL); err != nil {
+					result.ClockError = err.Error()
+				}
+				printJSON(result)
+				if result.Connectivity.Preferred == "" {
+					os.Exit(1)
+				}
+				return
+			}
+
+			fmt.Println(i18n.T("Probing %s...\n", config.RedactURL(cfg.ServerURL)))
+			c := dns.NewDoHClientURL(cfg.DoHURL()).Probe()
+
+			fmt.Println(i18n.T("  udp/53  (plain DNS)       %s", okString(c.UDP53)))
+			fmt.Println(i18n.T("  tcp/853 (DNS-over-TLS)    %s", okString(c.TCP853)))
+			fmt.Println(i18n.T("  tcp/443 (DNS-over-HTTPS)  %s", okString(c.TCP443)))
+			fmt.Println(i18n.T("  udp/443 (DNS-over-HTTP/3) %s", okString(c.UDP443)))
+			fmt.Println()
+
+			// A wrong clock makes TLS fail, so check it before giving up
+			if clock, err := filtersync.MeasureClock(cfg.ServerURL); err != nil {
+				fmt.Println(i18n.T("Clock: unknown (%v)", err))
+			} else {
+				printClock(clock)
+			}
+			fmt.Println()
+
+			if c.Preferred == "" {
+				fmt.Println(i18n.T("No upstream transport works from this network."))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Suggested transport: %s", c.Preferred))
+			if !c.TCP443 {
+				fmt.Println(i18n.T("Warning: DNS-over-HTTPS is blocked; filtering will not work on this network."))
+			}
+
+			caps, err := filtersync.ProbeCapabilities(cfg.ServerURL, cfg.Profile)
+			if err != nil {
+				fmt.Println(i18n.T("Server features: unknown (%v)", err))
+				return
+			}
+			version := caps.ServerVersion
+			if version == "" {
+				version = "unknown version"
+			}
+			fmt.Println(i18n.T("Server features (%s): sync %s, onboarding %s, EDE %s, push %s, leak test %s", version,
+				yesNo(caps.Sync), yesNo(caps.Onboarding), yesNo(caps.EDE), yesNo(caps.Push), yesNo(caps.LeakTest)))
+		},
+	}
+
+	// Leaktest command - check that lookups really go through the profile
+	var leakJSON bool
+	leaktestCmd := &cobra.Command{
+		Use:   "leaktest",
+		Short: i18n.T("Check that DNS lookups reach FilterDNS and not another resolver"),
+		Long: `Looks up unique names through the system resolver and asks the server
+where they arrived. Lookups that reach the server from another resolver,
+e.g. a VPN's or one hard-coded elsewhere, are reported as leaks.
+
+Browsers with their own DNS-over-HTTPS bypass the system resolver and
+are not covered; use the server's test page in the browser for those.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+			if cfg.Profile == "" {
+				fmt.Fprintln(os.Stderr, i18n.T("No profile configured."))
+				os.Exit(1)
+			}
+
+			leakJSON = leakJSON || output == outputJSON
+			if !leakJSON {
+				fmt.Println(i18n.T("Testing lookups against %s...", config.RedactURL(cfg.ServerURL)))
+			}
+			result, err := leaktest.Run(context.Background(), cfg.ServerURL, cfg.Profile)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+
+			if leakJSON {
+				printJSON(result)
+			} else {
+				fmt.Println(result.Summary())
+			}
+			if !result.OK() {
+				os.Exit(1)
+			}
+		},
+	}
+	leaktestCmd.Flags().BoolVar(&leakJSON, "json", false, i18n.T("Print the result as JSON (same as --output json)"))
+
+	// Loadtest command - generate load against the local proxy
+	var (
+		loadQPS         int
+		loadDuration    time.Duration
+		loadConcurrency int
+		loadDomainsFile string
+		loadServer      string
+	)
+	loadtestCmd := &cobra.Command{
+		Use:   "loadtest",
+		Short: i18n.T("Generate load against the local proxy and report performance"),
+		Run: func(cmd *cobra.Command, args []string) {
+			opts := loadtest.Options{
+				Server:      loadServer,
+				QPS:         loadQPS,
+				Duration:    loadDuration,
+				Concurrency: loadConcurrency,
+			}
+			if loadDomainsFile != "" {
+				domains, err := loadtest.LoadDomains(loadDomainsFile)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error loading domains: %v", err))
+					os.Exit(1)
+				}
+				opts.Domains = domains
+			}
+
+			// Daemon stats are optional; the proxy may run without it
+			client := daemon.NewClient()
+			before, _ := client.Status()
+
+			fmt.Println(i18n.T("Sending %d qps to %s for %s...", loadQPS, loadServer, loadDuration))
+			result, err := loadtest.Run(opts)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+
+			after, _ := client.Status()
+
+			fmt.Println()
+			fmt.Println(i18n.T("Queries:    %d sent, %d ok, %d failed, %d dropped (%.0f qps achieved)",
+				result.Sent, result.Succeeded, result.Failed, result.Dropped, result.AchievedQPS()))
+			fmt.Println(i18n.T("Error rate: %.2f%%", result.ErrorRate()*100))
+			fmt.Println(i18n.T("Latency:    p50 %s, p90 %s, p99 %s, max %s",
+				result.P50.Round(time.Microsecond), result.P90.Round(time.Microsecond),
+				result.P99.Round(time.Microsecond), result.Max.Round(time.Microsecond)))
+
+			if before != nil && after != nil {
+				queries := after.QueriesTotal - before.QueriesTotal
+				hits := after.CacheHits - before.CacheHits
+				if queries > 0 {
+					fmt.Println(i18n.T("Cache:      %.1f%% hit rate (%d of %d)", float64(hits)/float64(queries)*100, hits, queries))
+				}
+				fmt.Println(i18n.T("Goroutines: %d → %d", before.Goroutines, after.Goroutines))
+				fmt.Println(i18n.T("Memory:     %.1f MiB → %.1f MiB",
+					float64(before.MemoryBytes)/(1<<20), float64(after.MemoryBytes)/(1<<20)))
+			} else {
+				fmt.Println(i18n.T("(daemon not reachable - cache and resource stats unavailable)"))
+			}
+		},
+	}
+	loadtestCmd.Flags().IntVar(&loadQPS, "qps", 100, i18n.T("Target queries per second"))
+	loadtestCmd.Flags().DurationVar(&loadDuration, "duration", 10*time.Second, i18n.T("How long to generate load"))
+	loadtestCmd.Flags().IntVar(&loadConcurrency, "concurrency", 100, i18n.T("Maximum in-flight queries"))
+	loadtestCmd.Flags().StringVar(&loadDomainsFile, "domains", "", i18n.T("File with one domain per line (default: built-in list)"))
+	loadtestCmd.Flags().StringVar(&loadServer, "server", "127.0.0.1:53", i18n.T("Proxy address to test"))
+
+	// Config command group
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: i18n.T("Manage configuration"),
+	}
+
+	configSetCmd := &cobra.Command{
+		Use:               "set <key> <value>",
+		Short:             i18n.T("Set a configuration value"),
+		Long:              "Sets a configuration value. Keys:\n\n" + configKeyHelp() + "\n" + overrideHelp,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeConfigKey,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				cfg = config.Default()
+			}
+
+			name, value := args[0], args[1]
+			if name == passwordKey {
+				if err := config.SetPassword(cfg.Profile, value); err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error storing password: %v", err))
+					os.Exit(1)
+				}
+				fmt.Println(i18n.T("Password stored securely."))
+				return
+			}
+
+			key, err := findConfigKey(name)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			if err := key.set(cfg, value); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+
+			saveConfigKey(cfg, name)
+			fmt.Println(i18n.T("Set %s = %s", name, showConfigValue(key.get(cfg))))
+			warnOverridden(name)
+		},
+	}
+
+	configGetCmd := &cobra.Command{
+		Use:               "get [key]",
+		Short:             i18n.T("Show one configuration value, or all of them"),
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeConfigKey,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+
+			if len(args) == 0 {
+				values := make(map[string]any, len(configKeys))
+				for _, key := range configKeys {
+					values[key.name] = key.get(cfg)
+				}
+				if output == outputJSON {
+					printJSON(values)
+					return
+				}
+				for _, key := range configKeys {
+					if keyLocked(key.name) {
+						fmt.Printf("%-20s %s  (%s)\n", key.name, showConfigValue(values[key.name]), i18n.T("locked by your administrator"))
+						continue
+					}
+					if source := overrideSources[key.name]; source != "" {
+						fmt.Printf("%-20s %s  (%s)\n", key.name, showConfigValue(values[key.name]), source)
+						continue
+					}
+					fmt.Printf("%-20s %s\n", key.name, showConfigValue(values[key.name]))
+				}
+				return
+			}
+
+			if args[0] == passwordKey {
+				fmt.Fprintln(os.Stderr, i18n.T("The password is kept in the system keychain and cannot be shown."))
+				os.Exit(1)
+			}
+			key, err := findConfigKey(args[0])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			if output == outputJSON {
+				printJSON(key.get(cfg))
+				return
+			}
+			fmt.Println(showConfigValue(key.get(cfg)))
+		},
+	}
+
+	configUnsetCmd := &cobra.Command{
+		Use:               "unset <key>",
+		Short:             i18n.T("Reset a configuration value to its default"),
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeConfigKey,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				cfg = config.Default()
+			}
+
+			name := args[0]
+			if name == passwordKey {
+				if err := config.DeletePassword(cfg.Profile); err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error removing password: %v", err))
+					os.Exit(1)
+				}
+				fmt.Println(i18n.T("Password removed."))
+				return
+			}
+
+			key, err := findConfigKey(name)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			key.unset(cfg)
+
+			saveConfigKey(cfg, name)
+			fmt.Println(i18n.T("Unset %s (now %s)", name, showConfigValue(key.get(cfg))))
+			warnOverridden(name)
+		},
+	}
+
+	// Config export/import for provisioning machines with the same settings
+	var exportSecrets bool
+	configExportCmd := &cobra.Command{
+		Use:   "export",
+		Short: i18n.T("Print the configuration for 'config import' on another machine"),
+		Long: `Prints the configuration as JSON, without runtime state such as whether
+filtering is on. Profile passwords are written as references to their
+keychain entry, such as "keyring:kids", which must then exist on the
+importing machine, unless --include-secrets puts the passwords
+themselves into the export.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+
+			export, err := config.NewExport(cfg, exportSecrets)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			if !exportSecrets {
+				printJSON(export)
+				return
+			}
+			if len(export.Secrets) > 0 {
+				fmt.Fprintln(os.Stderr, i18n.T("Warning: the export contains passwords; keep it safe."))
+			}
+			// Not redacted: the passwords are what was asked for
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(export); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+		},
+	}
+	configExportCmd.Flags().BoolVar(&exportSecrets, "include-secrets", false, i18n.T("Include profile passwords instead of keychain references"))
+
+	var importMerge bool
+	configImportCmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: i18n.T("Replace the configuration with an export ('-' reads standard input)"),
+		Long: `Replaces the configuration with one written by 'config export', or a
+plain config.json. With --merge only the settings in the file change, and
+its forwarders and saved profiles are added to the existing ones.
+Passwords in the file are stored in the keychain.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var data []byte
+			var err error
+			if args[0] == "-" {
+				data, err = io.ReadAll(os.Stdin)
+			} else {
+				data, err = os.ReadFile(args[0])
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+
+			export, err := config.ParseExport(data)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Invalid export: %v", err))
+				os.Exit(1)
+			}
+
+			current, err := config.Load()
+			if err != nil {
+				current = config.Default()
+			}
+			cfg, err := export.Apply(current, importMerge)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			if err := config.Save(cfg); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error saving config: %v", configProblems(err)))
+				os.Exit(1)
+			}
+
+			missing, err := export.ImportSecrets()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			for _, profile := range missing {
+				fmt.Fprintln(os.Stderr, i18n.T("Warning: no password in the keychain for %s; set it with 'config set password'", profile))
+			}
+
+			fmt.Println(i18n.T("Imported configuration for profile: %s", cfg.Profile))
+			if daemon.NewClient().IsRunning() {
+				fmt.Println(i18n.T("Run 'filterdns-client reload' to apply it to the running daemon."))
+			}
+		},
+	}
+	configImportCmd.Flags().BoolVar(&importMerge, "merge", false, i18n.T("Merge into the existing configuration instead of replacing it"))
+
+	configValidateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: i18n.T("Check the configuration for invalid or conflicting settings"),
+		Long: `Checks every setting in the configuration, including ones edited into
+config.json by hand, and lists each problem with the name of the setting
+in config.json. Exits non-zero if there is any.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+			err = config.Validate(cfg)
+
+			if output == outputJSON {
+				problems := map[string]string{}
+				var v config.ValidationError
+				if errors.As(err, &v) {
+					for _, e := range v {
+						problems[e.Field] = e.Err.Error()
+					}
+				}
+				printJSON(problems)
+			} else if err != nil {
+				fmt.Println(i18n.T("The configuration has problems:%s", configProblems(err)))
+			} else {
+				fmt.Println(i18n.T("The configuration is valid"))
+			}
+			if err != nil {
+				os.Exit(1)
+			}
+		},
+	}
+
+	configShowCmd := &cobra.Command{
+		Use:   "show",
+		Short: i18n.T("Show current configuration"),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+			if output == outputJSON {
+				printJSON(config.RedactConfig(cfg))
+				return
+			}
+			if pure := config.PureConfig(); pure != "" {
+				fmt.Println(i18n.T("Config:    %s (read-only)", pure))
+			}
+			fmt.Println(i18n.T("Profile:   %s", cfg.Profile))
+			fmt.Println(i18n.T("Server:    %s", config.RedactURL(cfg.ServerURL)))
+			if doh := cfg.DoHURL(); doh != config.DefaultDoHURL(cfg.ServerURL, cfg.Profile) {
+				fmt.Println(i18n.T("DNS:       %s", doh) + fromServer(true))
+			}
+			fmt.Println(i18n.T("Autostart: %v", cfg.Autostart))
+			if len(cfg.ManagedInterfaces) > 0 {
+				fmt.Println(i18n.T("Interfaces: %s", strings.Join(cfg.ManagedInterfaces, ", ")))
+			}
+			if len(cfg.IgnoredInterfaces) > 0 {
+				fmt.Println(i18n.T("Ignored interfaces: %s", strings.Join(cfg.IgnoredInterfaces, ", ")))
+			}
+			// With the server's client settings merged in, marked as such
+			eff := cfg.Effective()
+			if s := cfg.ServerSettings; s != nil && s.Managed {
+				fmt.Println(i18n.T("Managed:   the server profile's client settings win over local ones"))
+			}
+			if eff.CacheSize > 0 {
+				fmt.Println(i18n.T("Cache size: %d", eff.CacheSize) + fromServer(eff.CacheSize != cfg.CacheSize))
+			}
+			if len(eff.BootstrapDNS) > 0 {
+				fmt.Println(i18n.T("Bootstrap DNS: %s", strings.Join(eff.BootstrapDNS, ", ")) +
+					fromServer(!slices.Equal(eff.BootstrapDNS, cfg.BootstrapDNS)))
+			}
+			if cfg.AutoUpdate {
+				fmt.Println(i18n.T("Automatic updates: on"))
+			}
+			if len(cfg.Heartbeat) > 0 {
+				fmt.Println(i18n.T("Reported to the server: %s", strings.Join(cfg.Heartbeat, ", ")))
+			}
+			if cfg.ServerCommands {
+				fmt.Println(i18n.T("Commands from the server: on"))
+			}
+			if len(eff.Forwarders) > 0 {
+				fmt.Println(i18n.T("Forwarders:"))
+				for _, f := range eff.Forwarders {
+					fmt.Printf("  %s → %s%s\n", f.Domain, f.Server, fromServer(cfg.ServerForwarder(f.Domain)))
+				}
+			}
+			if len(cfg.Rules) > 0 {
+				fmt.Println(i18n.T("Rules:"))
+				for _, r := range cfg.Rules {
+					fmt.Printf("  %s %s\n", r.Action, r.Domain)
+				}
+			}
+		},
+	}
+
+	// Forwarder commands for split DNS
+	forwarderCmd := &cobra.Command{
+		Use:   "forwarder",
+		Short: i18n.T("Manage DNS forwarders (split DNS)"),
+	}
+
+	forwarderAddCmd := &cobra.Command{
+		Use:   "add <domain> <server>",
+		Short: i18n.T("Add a forwarder (e.g., 'add ts.net 100.100.100.100')"),
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				cfg = config.Default()
+			}
+
+			cfg.Forwarders = append(cfg.Forwarders, config.Forwarder{
+				Domain: args[0],
+				Server: args[1],
+			})
+
+			if err := config.Save(cfg); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error saving config: %v", configProblems(err)))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Added forwarder: %s → %s", args[0], args[1]))
+		},
+	}
+
+	forwarderListCmd := &cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List all forwarders"),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+			if output == outputJSON {
+				forwarders := cfg.Effective().Forwarders
+				if forwarders == nil {
+					forwarders = []config.Forwarder{}
+				}
+				printJSON(forwarders)
+				return
+			}
+			eff := cfg.Effective()
+			if len(eff.Forwarders) == 0 {
+				fmt.Println(i18n.T("No forwarders configured."))
+				return
+			}
+			for _, f := range eff.Forwarders {
+				fmt.Printf("%s → %s%s\n", f.Domain, f.Server, fromServer(cfg.ServerForwarder(f.Domain)))
+			}
+		},
+	}
+
+	var testRule, testServer, testType string
+	forwarderTestCmd := &cobra.Command{
+		Use:   "test <domain>",
+		Short: i18n.T("Show which forwarder a domain goes to and query it directly"),
+		Long: `Shows which forwarder rule matches a domain, queries that rule's server
+directly and prints the answer and how long it took.
+
+--server tries a rule before adding it: it is checked first, for the
+pattern given with --rule or else for the domain itself.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+
+			forwarders := cfg.Forwarders
+			if testServer != "" {
+				rule := testRule
+				if rule == "" {
+					rule = args[0]
+				}
+				forwarders = append([]config.Forwarder{{Domain: rule, Server: testServer}}, forwarders...)
+			}
+
+			result, err := dns.TestForwarder(forwarders, args[0], testType)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			if output == outputJSON {
+				printJSON(result)
+			} else if result.Rule == nil {
+				fmt.Println(i18n.T("No forwarder matches %s; it is resolved through FilterDNS.", result.Domain))
+			} else {
+				fmt.Println(i18n.T("Rule:    %s → %s", result.Rule.Domain, result.Rule.Server))
+				fmt.Println(i18n.T("Query:   %s %s", result.Domain, result.Type))
+				switch {
+				case result.Error != "":
+					fmt.Println(i18n.T("Answer:  failed (%s)", result.Error))
+				case len(result.Answers) > 0:
+					fmt.Println(i18n.T("Answer:  %s, %s (%s)", result.Rcode, strings.Join(result.Answers, ", "),
+						result.Duration.Round(time.Millisecond)))
+				default:
+					fmt.Println(i18n.T("Answer:  %s, no records (%s)", result.Rcode, result.Duration.Round(time.Millisecond)))
+				}
+			}
+
+			if result.Rule == nil || result.Error != "" {
+				os.Exit(1)
+			}
+		},
+	}
+	forwarderTestCmd.Flags().StringVar(&testServer, "server", "", i18n.T("Try an unsaved rule forwarding to this server"))
+	forwarderTestCmd.Flags().StringVar(&testRule, "rule", "", i18n.T("Pattern of the unsaved rule, e.g. '*.corp' (default: the domain)"))
+	forwarderTestCmd.Flags().StringVarP(&testType, "type", "t", "A", i18n.T("Record type to query"))
+
+	forwarderRemoveCmd := &cobra.Command{
+		Use:               "remove <domain>",
+		Short:             i18n.T("Remove a forwarder"),
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeForwarder,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+
+			domain := args[0]
+			newForwarders := make([]config.Forwarder, 0)
+			found := false
+			for _, f := range cfg.Forwarders {
+				if f.Domain != domain {
+					newForwarders = append(newForwarders, f)
+				} else {
+					found = true
+				}
+			}
+
+			if !found {
+				fmt.Fprintln(os.Stderr, i18n.T("Forwarder not found: %s", domain))
+				os.Exit(1)
+			}
+
+			cfg.Forwarders = newForwarders
+			if err := config.Save(cfg); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error saving config: %v", configProblems(err)))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Removed forwarder: %s", domain))
+		},
+	}
+
+	// Rule commands - local allow/block overrides, mirrored to the profile
+	// when the server supports it
+	var ruleLocal bool
+	newRuleCmd := func(action, short string) *cobra.Command {
+		return &cobra.Command{
+			Use:   action + " <domain>",
+			Short: short,
+			Args:  cobra.ExactArgs(1),
+			Run: func(cmd *cobra.Command, args []string) {
+				rule := config.Rule{Domain: config.NormalizeRuleDomain(args[0]), Action: action}
+				if rule.Domain == "" || strings.ContainsAny(rule.Domain, " /:") {
+					fmt.Fprintln(os.Stderr, i18n.T("Invalid domain: %s", args[0]))
+					os.Exit(1)
+				}
+
+				cfg, err := changeRules(func(cfg *config.Config) bool {
+					cfg.SetRule(rule)
+					return true
+				})
+				if err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error saving config: %v", configProblems(err)))
+					os.Exit(1)
+				}
+				fmt.Println(i18n.T("Rule added: %s %s (and its subdomains)", rule.Action, rule.Domain))
+
+				if !ruleLocal && syncRule(cfg, rule, false) {
+					// Note that the rule is on the server too
+					rule.Server = true
+					if _, err := changeRules(func(cfg *config.Config) bool {
+						cfg.SetRule(rule)
+						return true
+					}); err != nil {
+						fmt.Fprintln(os.Stderr, i18n.T("Error saving config: %v", configProblems(err)))
+						os.Exit(1)
+					}
+				}
+			},
+		}
+	}
+	allowCmd := newRuleCmd(config.RuleAllow, i18n.T("Always resolve a domain, even if the profile blocks it"))
+	blockCmd := newRuleCmd(config.RuleBlock, i18n.T("Block a domain on this machine"))
+	allowCmd.Flags().BoolVar(&ruleLocal, "local", false, i18n.T("Only change this machine, not the server profile"))
+	blockCmd.Flags().BoolVar(&ruleLocal, "local", false, i18n.T("Only change this machine, not the server profile"))
+
+	rulesCmd := &cobra.Command{
+		Use:   "rules",
+		Short: i18n.T("Manage local allow/block rules"),
+	}
+
+	rulesListCmd := &cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List local allow/block rules"),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+			if client := daemon.NewClient(); client.IsRunning() {
+				if current, err := client.GetConfig(); err == nil {
+					cfg = current
+				}
+			}
+
+			if output == outputJSON {
+				rules := cfg.Rules
+				if rules == nil {
+					rules = []config.Rule{}
+				}
+				printJSON(rules)
+				return
+			}
+			if len(cfg.Rules) == 0 {
+				fmt.Println(i18n.T("No rules. Add one with: filterdns-client allow <domain> or block <domain>"))
+				return
+			}
+			for _, r := range cfg.Rules {
+				scope := i18n.T("local")
+				if r.Server {
+					scope = i18n.T("server")
+				}
+				fmt.Printf("%-5s  %-6s  %s\n", r.Action, scope, r.Domain)
+			}
+		},
+	}
+
+	rulesRemoveCmd := &cobra.Command{
+		Use:               "remove <domain>",
+		Short:             i18n.T("Remove a local allow/block rule"),
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeRule,
+		Run: func(cmd *cobra.Command, args []string) {
+			domain := config.NormalizeRuleDomain(args[0])
+			var removed *config.Rule
+			cfg, err := changeRules(func(cfg *config.Config) bool {
+				for _, r := range cfg.Rules {
+					if r.Domain == domain {
+						removed = &r
+						break
+					}
+				}
+				return cfg.RemoveRule(domain)
+			})
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error saving config: %v", configProblems(err)))
+				os.Exit(1)
+			}
+			if removed == nil {
+				fmt.Fprintln(os.Stderr, i18n.T("No rule for %s", domain))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Rule removed: %s %s", removed.Action, removed.Domain))
+
+			if !ruleLocal {
+				syncRule(cfg, *removed, true)
+			}
+		},
+	}
+	rulesRemoveCmd.Flags().BoolVar(&ruleLocal, "local", false, i18n.T("Only change this machine, not the server profile"))
+
+	// Profile commands - saved server/profile pairs to switch between
+	profileCmd := &cobra.Command{
+		Use:   "profile",
+		Short: i18n.T("Manage saved profiles and switch between them"),
+	}
+
+	var profileRemote bool
+	profileListCmd := &cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List saved profiles, or with --remote those the server offers"),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+
+			if profileRemote {
+				profiles, err := onboard.ListProfiles(cfg.ServerURL)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+					os.Exit(1)
+				}
+				if output == outputJSON {
+					printJSON(profiles)
+					return
+				}
+				if len(profiles) == 0 {
+					fmt.Println(i18n.T("%s offers no profiles.", config.RedactURL(cfg.ServerURL)))
+					return
+				}
+				for _, p := range profiles {
+					password := ""
+					if p.HasPassword {
+						password = " (password)"
+					}
+					fmt.Printf("%s%s\n", p.Name, password)
+				}
+				return
+			}
+
+			if output == outputJSON {
+				profiles := cfg.Profiles
+				if profiles == nil {
+					profiles = []config.SavedProfile{}
+				}
+				printJSON(profiles)
+				return
+			}
+			if len(cfg.Profiles) == 0 {
+				fmt.Println(i18n.T("No saved profiles. Add one with: filterdns-client profile add <name>"))
+				return
+			}
+			// The daemon knows whether each profile is paused on its server
+			var states []daemon.ProfileState
+			if client := daemon.NewClient(); client.IsRunning() {
+				if status, err := client.Status(); err == nil {
+					states = status.Profiles
+				}
+			}
+			for _, p := range cfg.Profiles {
+				marker := " "
+				if p.Name == cfg.ActiveProfile {
+					marker = "*"
+				}
+				line := i18n.T("%s %-12s %s on %s", marker, p.Name, p.Profile, config.RedactURL(p.ServerURL))
+				i := slices.IndexFunc(states, func(state daemon.ProfileState) bool { return state.Name == p.Name })
+				if i >= 0 {
+					if text := states[i].Describe(); text != "" {
+						line += " (" + text + ")"
+					}
+				}
+				fmt.Println(line)
+			}
+		},
+	}
+	profileListCmd.Flags().BoolVar(&profileRemote, "remote", false, i18n.T("List the profiles offered by the configured server"))
+
+	var addProfile, addServer string
+	var addPasswordStdin bool
+	profileAddCmd := &cobra.Command{
+		Use:   "add <name>",
+		Short: i18n.T("Save a profile to switch to, or update a saved one"),
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				cfg = config.Default()
+			}
+
+			saved := config.SavedProfile{Name: args[0], Profile: addProfile, ServerURL: addServer}
+			if saved.Profile == "" {
+				saved.Profile = saved.Name
+			}
+			if saved.ServerURL == "" {
+				saved.ServerURL = cfg.ServerURL
+			}
+			serverKey, _ := findConfigKey("server")
+			if err := serverKey.set(config.Default(), saved.ServerURL); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+
+			if existing := cfg.FindProfile(saved.Name); existing != nil {
+				*existing = saved
+			} else {
+				cfg.Profiles = append(cfg.Profiles, saved)
+			}
+			if err := config.Save(cfg); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error saving config: %v", configProblems(err)))
+				os.Exit(1)
+			}
+
+			if addPasswordStdin {
+				data, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error reading password: %v", err))
+					os.Exit(1)
+				}
+				if err := config.SetPassword(saved.Profile, strings.TrimRight(string(data), "\r\n")); err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error storing password: %v", err))
+					os.Exit(1)
+				}
+			}
+			fmt.Println(i18n.T("Saved profile %s (%s on %s)", saved.Name, saved.Profile, config.RedactURL(saved.ServerURL)))
+			reloadDaemon()
+		},
+	}
+	profileAddCmd.Flags().StringVar(&addProfile, "profile", "", i18n.T("FilterDNS profile name (default: the name)"))
+	profileAddCmd.Flags().StringVar(&addServer, "server", "", i18n.T("FilterDNS server URL (default: the current server)"))
+	profileAddCmd.Flags().BoolVar(&addPasswordStdin, "password-stdin", false, i18n.T("Read the profile password from standard input"))
+
+	profileRemoveCmd := &cobra.Command{
+		Use:               "remove <name>",
+		Short:             i18n.T("Remove a saved profile"),
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeSavedProfile,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+
+			n := len(cfg.Profiles)
+			cfg.Profiles = slices.DeleteFunc(cfg.Profiles, func(p config.SavedProfile) bool { return p.Name == args[0] })
+			if len(cfg.Profiles) == n {
+				fmt.Fprintln(os.Stderr, i18n.T("Unknown profile: %s", args[0]))
+				os.Exit(1)
+			}
+			if cfg.ActiveProfile == args[0] {
+				// The settings stay in use, just no longer under this name
+				cfg.ActiveProfile = ""
+			}
+
+			if err := config.Save(cfg); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error saving config: %v", configProblems(err)))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Removed profile: %s", args[0]))
+			reloadDaemon()
+		},
+	}
+
+	profileSwitchCmd := &cobra.Command{
+		Use:               "switch <name>",
+		Short:             i18n.T("Make a saved profile the active one"),
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeSavedProfile,
+		Run: func(cmd *cobra.Command, args []string) {
+			client := daemon.NewClient()
+			if client.IsRunning() {
+				status, err := client.SwitchProfile(args[0])
+				if err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+					os.Exit(1)
+				}
+				if output == outputJSON {
+					printJSON(status)
+					return
+				}
+				fmt.Println(i18n.T("Switched to profile %s (%s on %s)", args[0], status.Profile, config.RedactURL(status.ServerURL)))
+				return
+			}
+
+			// No daemon: the next one to start picks it up
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+			saved := cfg.FindProfile(args[0])
+			if saved == nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Unknown profile: %s", args[0]))
+				os.Exit(1)
+			}
+			cfg.UseProfile(saved)
+			if err := config.Save(cfg); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error saving config: %v", configProblems(err)))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Switched to profile %s (%s on %s)", saved.Name, saved.Profile, config.RedactURL(saved.ServerURL)))
+		},
+	}
+
+	// Network commands - settings that change on particular Wi-Fi networks
+	networkCmd := &cobra.Command{
+		Use:   "network",
+		Short: i18n.T("Manage settings that apply on particular Wi-Fi networks"),
+	}
+
+	networkListCmd := &cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List the networks with settings of their own"),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+
+			// Only the daemon knows which Wi-Fi the computer is on
+			var status *daemon.Status
+			if client := daemon.NewClient(); client.IsRunning() {
+				status, _ = client.Status()
+			}
+
+			if output == outputJSON {
+				networks := cfg.Networks
+				if networks == nil {
+					networks = []config.NetworkOverlay{}
+				}
+				printJSON(networks)
+				return
+			}
+			if len(cfg.Networks) == 0 {
+				fmt.Println(i18n.T("No networks with settings of their own. Add one with: filterdns-client network add <name> --ssid <Wi-Fi name>"))
+			}
+			for _, n := range cfg.Networks {
+				marker := " "
+				if status != nil && status.Network == n.Name {
+					marker = "*"
+				}
+				fmt.Println(i18n.T("%s %-12s on %s", marker, n.Name, strings.Join(n.SSIDs, ", ")))
+				if n.Profile != "" {
+					fmt.Println(i18n.T("    profile %s", n.Profile))
+				}
+				for _, f := range n.Forwarders {
+					fmt.Printf("    %s → %s\n", f.Domain, f.Server)
+				}
+				for _, key := range config.Policy(n.Settings).Keys() {
+					fmt.Printf("    %s = %s\n", key, n.Settings[key])
+				}
+			}
+			if status != nil && len(status.WiFi) > 0 {
+				fmt.Println(i18n.T("Connected to Wi-Fi %s", strings.Join(status.WiFi, ", ")))
+			}
+		},
+	}
+
+	var networkSSIDs, networkForwarders, networkSettings []string
+	var networkProfile string
+	networkAddCmd := &cobra.Command{
+		Use:   "add <name>",
+		Short: i18n.T("Add settings for Wi-Fi networks, or replace those of a network"),
+		Long: i18n.T(`Add settings that apply while the computer is on one of the given Wi-Fi
+networks, or replace them for a network added before. The daemon notices
+when the computer joins or leaves one and switches settings by itself.
+
+Example:
+  filterdns-client network add office --ssid CorpWiFi \
+    --forwarder '*.corp=10.0.0.2' --setting search-shortcut=false
+  filterdns-client network add home --ssid Home --profile kids`),
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				cfg = config.Default()
+			}
+
+			n := config.NetworkOverlay{Name: args[0], SSIDs: networkSSIDs, Profile: networkProfile}
+			for _, forwarder := range networkForwarders {
+				domain, server, ok := strings.Cut(forwarder, "=")
+				if !ok {
+					fmt.Fprintln(os.Stderr, i18n.T("Error: --forwarder takes domain=server, got %q", forwarder))
+					os.Exit(1)
+				}
+				n.Forwarders = append(n.Forwarders, config.Forwarder{Domain: domain, Server: server})
+			}
+			for _, setting := range networkSettings {
+				key, value, err := networkSetting(setting)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+					os.Exit(1)
+				}
+				if n.Settings == nil {
+					n.Settings = map[string]json.RawMessage{}
+				}
+				n.Settings[key] = value
+			}
+
+			i := slices.IndexFunc(cfg.Networks, func(existing config.NetworkOverlay) bool { return existing.Name == n.Name })
+			if i >= 0 {
+				cfg.Networks[i] = n
+			} else {
+				cfg.Networks = append(cfg.Networks, n)
+			}
+			if err := config.Save(cfg); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error saving config: %v", configProblems(err)))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Saved network %s (on %s)", n.Name, strings.Join(n.SSIDs, ", ")))
+		},
+	}
+	networkAddCmd.Flags().StringArrayVar(&networkSSIDs, "ssid", nil, i18n.T("Wi-Fi network name it applies on (repeatable)"))
+	networkAddCmd.Flags().StringVar(&networkProfile, "profile", "", i18n.T("Saved profile to use on it"))
+	networkAddCmd.Flags().StringArrayVar(&networkForwarders, "forwarder", nil, i18n.T("Split DNS forwarder to add there, as domain=server (repeatable)"))
+	networkAddCmd.Flags().StringArrayVar(&networkSettings, "setting", nil, i18n.T("Config key to change there, as key=value (repeatable)"))
+	networkAddCmd.MarkFlagRequired("ssid")
+
+	networkRemoveCmd := &cobra.Command{
+		Use:               "remove <name>",
+		Short:             i18n.T("Remove the settings of a network"),
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeNetwork,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+
+			n := len(cfg.Networks)
+			cfg.Networks = slices.DeleteFunc(cfg.Networks, func(network config.NetworkOverlay) bool { return network.Name == args[0] })
+			if len(cfg.Networks) == n {
+				fmt.Fprintln(os.Stderr, i18n.T("Unknown network: %s", args[0]))
+				os.Exit(1)
+			}
+			if err := config.Save(cfg); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error saving config: %v", configProblems(err)))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Removed network: %s", args[0]))
+		},
+	}
+
+	// Schedule commands - filtering changes at set times of the week
+	scheduleCmd := &cobra.Command{
+		Use:   "schedule",
+		Short: i18n.T("Manage times of the week when filtering changes by itself"),
+	}
+
+	scheduleListCmd := &cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List the schedule"),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+
+			if output == outputJSON {
+				schedule := cfg.Schedule
+				if schedule == nil {
+					schedule = []config.ScheduleEntry{}
+				}
+				printJSON(schedule)
+				return
+			}
+			if len(cfg.Schedule) == 0 {
+				fmt.Println(i18n.T("Nothing scheduled. Add an entry with: filterdns-client schedule add <name> --from 15:00 --until 19:00 --profile <name>"))
+				return
+			}
+			for i := range cfg.Schedule {
+				e := &cfg.Schedule[i]
+				marker := " "
+				if !e.ActiveAt(time.Now()).IsZero() {
+					marker = "*"
+				}
+				days := i18n.T("every day")
+				if len(e.Days) > 0 {
+					days = strings.Join(e.Days, ",")
+				}
+				fmt.Println(i18n.T("%s %-12s %s %s-%s: %s", marker, e.Name, days, e.From, e.Until, describeScheduleAction(e)))
+			}
+		},
+	}
+
+	var scheduleDays []string
+	var scheduleFrom, scheduleUntil, scheduleProfile string
+	var scheduleOff, scheduleOn bool
+	scheduleAddCmd := &cobra.Command{
+		Use:   "add <name>",
+		Short: i18n.T("Add a schedule entry, or replace one"),
+		Long: i18n.T(`Add an entry to the schedule, or replace the one with the same name. During
+its time range filtering is paused (--off), turned on (--on) or uses a
+saved profile (--profile). The daemon carries it out even when no GUI is
+running, and undoes it when the time range ends.
+
+Example:
+  filterdns-client schedule add homework --days weekdays \
+    --from 15:00 --until 19:00 --profile homework
+  filterdns-client schedule add night --from 22:00 --until 06:30 --on`),
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				cfg = config.Default()
+			}
+
+			entry := config.ScheduleEntry{Name: args[0], Days: scheduleDays, From: scheduleFrom, Until: scheduleUntil}
+			switch {
+			case scheduleOff:
+				entry.Action = config.ScheduleOff
+			case scheduleOn:
+				entry.Action = config.ScheduleOn
+			case scheduleProfile != "":
+				entry.Action = config.ScheduleProfile
+				entry.Profile = scheduleProfile
+			default:
+				fmt.Fprintln(os.Stderr, i18n.T("Error: one of --off, --on or --profile is needed"))
+				os.Exit(1)
+			}
+
+			i := slices.IndexFunc(cfg.Schedule, func(existing config.ScheduleEntry) bool { return existing.Name == entry.Name })
+			if i >= 0 {
+				cfg.Schedule[i] = entry
+			} else {
+				cfg.Schedule = append(cfg.Schedule, entry)
+			}
+			if err := config.Save(cfg); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error saving config: %v", configProblems(err)))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Scheduled %s: %s-%s, %s", entry.Name, entry.From, entry.Until, describeScheduleAction(&entry)))
+		},
+	}
+	scheduleAddCmd.Flags().StringSliceVar(&scheduleDays, "days", nil, i18n.T("Days it applies on: mon to sun, weekdays or weekends (default: every day)"))
+	scheduleAddCmd.Flags().StringVar(&scheduleFrom, "from", "", i18n.T("Start time, e.g. 15:00"))
+	scheduleAddCmd.Flags().StringVar(&scheduleUntil, "until", "", i18n.T("End time, e.g. 19:00; before the start it is on the next day"))
+	scheduleAddCmd.Flags().BoolVar(&scheduleOff, "off", false, i18n.T("Pause filtering during the time range"))
+	scheduleAddCmd.Flags().BoolVar(&scheduleOn, "on", false, i18n.T("Turn filtering on during the time range"))
+	scheduleAddCmd.Flags().StringVar(&scheduleProfile, "profile", "", i18n.T("Saved profile to use during the time range"))
+	scheduleAddCmd.MarkFlagRequired("from")
+	scheduleAddCmd.MarkFlagRequired("until")
+	scheduleAddCmd.MarkFlagsMutuallyExclusive("off", "on", "profile")
+	scheduleAddCmd.RegisterFlagCompletionFunc("days", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return config.ScheduleDayNames, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	scheduleRemoveCmd := &cobra.Command{
+		Use:               "remove <name>",
+		Short:             i18n.T("Remove a schedule entry"),
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeScheduleEntry,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+
+			n := len(cfg.Schedule)
+			cfg.Schedule = slices.DeleteFunc(cfg.Schedule, func(e config.ScheduleEntry) bool { return e.Name == args[0] })
+			if len(cfg.Schedule) == n {
+				fmt.Fprintln(os.Stderr, i18n.T("Unknown schedule entry: %s", args[0]))
+				os.Exit(1)
+			}
+			if err := config.Save(cfg); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error saving config: %v", configProblems(err)))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Removed schedule entry: %s", args[0]))
+		},
+	}
+
+	// Install command - install as system service
+	var installOwner string
+	installCmd := &cobra.Command{
+		Use:   "install",
+		Short: i18n.T("Install as a system service (requires root)"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if os.Geteuid() != 0 {
+				fmt.Fprintln(os.Stderr, i18n.T("This command requires root privileges. Run with sudo."))
+				os.Exit(1)
+			}
+			seedMachineConfig()
+			if installOwner == "" {
+				installOwner = invokingUser()
+			}
+			recordOwner(installOwner)
+			if err := service.Install(); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Install failed: %v", err))
+				os.Exit(1)
+			}
+		},
+	}
+	installCmd.Flags().StringVar(&installOwner, "owner", "", i18n.T("User who may read the daemon's query history (default: the user running sudo)"))
+
+	// Uninstall command - remove system service
+	var uninstallPurge bool
+	uninstallCmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: i18n.T("Uninstall the system service (requires root)"),
+		Long: `Restores the original system DNS, then stops and removes the system
+service and the installed binaries.
+
+With --purge, everything the client stored is removed as well: the machine
+config and statistics, the config of root and of the user running sudo,
+their saved passwords in the keychain, the DNS backup and the service log
+file.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if os.Geteuid() != 0 {
+				fmt.Fprintln(os.Stderr, i18n.T("This command requires root privileges. Run with sudo."))
+				os.Exit(1)
+			}
+
+			// Nothing may be left pointing at the proxy once it is gone
+			if err := restoreNetwork(); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Warning: %v", err))
+				if resolver, err := system.InspectDNS(); err == nil && resolver.UsesProxy {
+					fmt.Fprintln(os.Stderr, i18n.T("System DNS still points at the local proxy, not uninstalling."))
+					fmt.Fprintln(os.Stderr, i18n.T("Fix it with: sudo filterdns-client dns-reset --force"))
+					os.Exit(1)
+				}
+			} else {
+				fmt.Println(i18n.T("Network settings restored"))
+			}
+
+			if err := service.Uninstall(); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Uninstall failed: %v", err))
+				os.Exit(1)
+			}
+
+			if uninstallPurge {
+				if err := purgeData(); err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Purge incomplete: %v", err))
+					os.Exit(1)
+				}
+				fmt.Println(i18n.T("Configuration, passwords, statistics and backups removed"))
+			}
+		},
+	}
+	uninstallCmd.Flags().BoolVar(&uninstallPurge, "purge", false, i18n.T("Also remove config, saved passwords, statistics, backups and logs"))
+
+	// Update command - replace this binary with the latest signed release
+	var updateCheck, updateForce bool
+	updateCmd := &cobra.Command{
+		Use:   "update",
+		Short: i18n.T("Install the latest signed release and restart the service"),
+		Long: `Checks the release manifest (config key update-url, or the one built in),
+downloads the binary for this platform, verifies its checksum and signature,
+replaces this binary in one step and restarts the system service if it is
+running. Replacing an installed binary usually requires root.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error loading config: %v", err))
+				os.Exit(1)
+			}
+
+			u, err := update.Check(cfg.UpdateURL)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			if updateCheck {
+				if output == outputJSON {
+					printJSON(u)
+					return
+				}
+				if u.Available {
+					fmt.Println(i18n.T("Update available: %s (installed: %s)", u.Latest, u.Current))
+				} else {
+					fmt.Println(i18n.T("Up to date: %s (latest release: %s)", u.Current, u.Latest))
+				}
+				return
+			}
+
+			if !u.Available && !updateForce {
+				if u.Development {
+					fmt.Println(i18n.T("Development build; install release %s with --force", u.Latest))
+				} else {
+					fmt.Println(i18n.T("Up to date: %s", u.Current))
+				}
+				return
+			}
+
+			path, err := u.Install()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Update failed: %v", err))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Updated %s from %s to %s", path, u.Current, u.Latest))
+
+			if state, err := service.Status(); err == nil && state.Running {
+				if err := service.Restart(); err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Failed to restart service: %v", err))
+					os.Exit(1)
+				}
+				fmt.Println(i18n.T("Service restarted"))
+			}
+		},
+	}
+	updateCmd.Flags().BoolVar(&updateCheck, "check", false, i18n.T("Only report whether an update is available"))
+	updateCmd.Flags().BoolVar(&updateForce, "force", false, i18n.T("Install the latest release on a development build"))
+
+	// Provision command - the whole setup in one scriptable step
+	var (
+		provisionServer, provisionProfile string
+		provisionPasswordStdin            bool
+		provisionEnable, provisionInstall bool
+	)
+	provisionCmd := &cobra.Command{
+		Use:   "provision",
+		Short: i18n.T("Set up the client without the browser onboarding (for mass deployment)"),
+		Long: `Writes the server and profile to the config, stores the password in the
+keychain, installs and starts the system service and turns filtering on,
+as far as the flags ask for. Running it again with the same flags
+changes nothing, so it can be used from Ansible, Intune and the like.
+
+  echo "$PASSWORD" | sudo filterdns-client provision --server https://filterdns.example.com \
+      --profile kids --password-stdin --install --enable`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if provisionInstall && os.Geteuid() != 0 {
+				fmt.Fprintln(os.Stderr, i18n.T("--install requires root privileges. Run with sudo."))
+				os.Exit(1)
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				cfg = config.Default()
+			}
+			if provisionServer != "" {
+				key, _ := findConfigKey("server")
+				if err := key.set(cfg, provisionServer); err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+					os.Exit(1)
+				}
+			}
+			if provisionProfile != "" {
+				cfg.Profile = provisionProfile
+			}
+			if cfg.Profile == "" {
+				fmt.Fprintln(os.Stderr, i18n.T("No profile configured; pass --profile."))
+				os.Exit(1)
+			}
+
+			var password string
+			if provisionPasswordStdin {
+				data, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error reading password: %v", err))
+					os.Exit(1)
+				}
+				password = strings.TrimRight(string(data), "\r\n")
+				if password == "" {
+					fmt.Fprintln(os.Stderr, i18n.T("No password on standard input."))
+					os.Exit(1)
+				}
+			}
+
+			if provisionEnable {
+				// Picked up by a daemon that starts after this
+				cfg.Enabled = true
+				cfg.PausedUntil = nil
+			}
+			if err := config.Save(cfg); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error saving config: %v", configProblems(err)))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Configured profile %s on %s", cfg.Profile, config.RedactURL(cfg.ServerURL)))
+
+			if password != "" {
+				if err := config.SetPassword(cfg.Profile, password); err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error storing password: %v", err))
+					os.Exit(1)
+				}
+				fmt.Println(i18n.T("Password stored in the keychain"))
+			}
+
+			client := daemon.NewClient()
+			if provisionInstall {
+				if err := service.Install(); err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Install failed: %v", err))
+					os.Exit(1)
+				}
+				if !client.IsRunning() {
+					if err := service.Start(); err != nil {
+						fmt.Fprintln(os.Stderr, i18n.T("Error starting service: %v", err))
+						os.Exit(1)
+					}
+					waitForDaemon(client, 10*time.Second)
+				}
+			}
+
+			if !client.IsRunning() {
+				if provisionEnable {
+					fmt.Println(i18n.T("Daemon not running; filtering starts when it does."))
+				}
+				return
+			}
+
+			// A daemon that was already running keeps its own state
+			if _, err := client.Reload(); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error reloading daemon: %v", err))
+				os.Exit(1)
+			}
+			status, err := client.Status()
+			if provisionEnable && err == nil && !status.Running {
+				status, err = client.Enable()
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+
+			if output == outputJSON {
+				printJSON(status)
+				return
+			}
+			if status.Running {
+				fmt.Println(i18n.T("DNS filtering enabled for profile: %s", status.Profile))
+			} else {
+				fmt.Println(i18n.T("DNS filtering is off; turn it on with: filterdns-client start"))
+			}
+		},
+	}
+	provisionCmd.Flags().StringVar(&provisionServer, "server", "", i18n.T("FilterDNS server URL"))
+	provisionCmd.Flags().StringVar(&provisionProfile, "profile", "", i18n.T("FilterDNS profile name"))
+	provisionCmd.Flags().BoolVar(&provisionPasswordStdin, "password-stdin", false, i18n.T("Read the profile password from standard input"))
+	provisionCmd.Flags().BoolVar(&provisionEnable, "enable", false, i18n.T("Turn DNS filtering on"))
+	provisionCmd.Flags().BoolVar(&provisionInstall, "install", false, i18n.T("Install and start the system service (requires root)"))
+
+	// Daemon command - run the daemon (used by systemd service)
+	var daemonUser, daemonDebugAddr string
+	daemonCmd := &cobra.Command{
+		Use:   "daemon",
+		Short: i18n.T("Run the daemon (used by system service)"),
+		Run: func(cmd *cobra.Command, args []string) {
+			d := daemon.New()
+			if daemonUser != "" {
+				d.SetUser(daemonUser)
+			}
+			if daemonDebugAddr != "" {
+				d.SetDebugAddr(daemonDebugAddr)
+			}
+			if err := d.Run(); errors.Is(err, daemon.ErrRestart) {
+				// Exit non-zero so the service manager starts the new binary
+				log.Println(err)
+				os.Exit(1)
+			} else if err != nil {
+				log.Fatalf("Daemon failed: %v", err)
+			}
+		},
+	}
+	daemonCmd.Flags().StringVar(&daemonUser, "user", "", i18n.T("Drop root and run as this user after startup (Linux/macOS)"))
+	daemonCmd.Flags().StringVar(&daemonDebugAddr, "debug-addr", "", i18n.T("Serve pprof on this localhost address, e.g. 127.0.0.1:6060"))
+
+	// Stats command - show counters kept across restarts
+	var statsJSON, statsWatch bool
+	var statsPeriod string
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: i18n.T("Show query statistics (today, 7 days, total and daily history)"),
+		Run: func(cmd *cobra.Command, args []string) {
+			client := daemon.NewClient()
+			if !client.IsRunning() {
+				fmt.Fprintln(os.Stderr, i18n.T("Daemon not running."))
+				os.Exit(1)
+			}
+
+			statsJSON = statsJSON || output == outputJSON
+			encoder := json.NewEncoder(os.Stdout)
+			for {
+				report, err := client.Stats(statsPeriod)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+					os.Exit(1)
+				}
+
+				if statsJSON {
+					encoder.Encode(report)
+				} else {
+					if statsWatch {
+						// Clear the screen and move to the top
+						fmt.Print("\033[H\033[2J")
+					}
+					printStats(report, !statsWatch)
+				}
+
+				if !statsWatch {
+					return
+				}
+				time.Sleep(2 * time.Second)
+			}
+		},
+	}
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, i18n.T("Print statistics as JSON (same as --output json)"))
+	statsCmd.Flags().BoolVarP(&statsWatch, "watch", "w", false, i18n.T("Keep updating every 2 seconds"))
+	statsCmd.Flags().StringVarP(&statsPeriod, "period", "p", "", i18n.T("Show history for hour, day, week, month or year"))
+	statsCmd.RegisterFlagCompletionFunc("period", cobra.FixedCompletions([]string{"hour", "day", "week", "month", "year"}, cobra.ShellCompDirectiveNoFileComp))
+
+	// Log command - recent queries, e.g. to find out why a site is blocked
+	var logFollow, logBlockedOnly, logJSON bool
+	var logDomain, logSince string
+	var logLines int
+	logCmd := &cobra.Command{
+		Use:   "log",
+		Short: i18n.T("Show recent DNS queries"),
+		Long: `Shows the most recent DNS queries answered by the daemon.
+
+--since takes a duration such as 10m or a time such as 2024-05-01T12:00:00Z.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			client := daemon.NewClient()
+			if !client.IsRunning() {
+				fmt.Fprintln(os.Stderr, i18n.T("Daemon not running."))
+				os.Exit(1)
+			}
+
+			filter := dns.QueryFilter{
+				BlockedOnly: logBlockedOnly,
+				Domain:      logDomain,
+				Limit:       logLines,
+			}
+			if logSince != "" {
+				since, err := parseSince(logSince)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Invalid --since: %v", err))
+					os.Exit(1)
+				}
+				filter.Since = since
+			}
+
+			logJSON = logJSON || output == outputJSON
+			encoder := json.NewEncoder(os.Stdout)
+			for {
+				entries, err := client.QueryLog(filter)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+					os.Exit(1)
+				}
+
+				for _, entry := range entries {
+					if logJSON {
+						encoder.Encode(entry)
+					} else {
+						printQuery(entry)
+					}
+					filter.After = entry.Seq
+				}
+
+				if !logFollow {
+					return
+				}
+				// Everything newer than what was printed, however much
+				filter.Limit = 0
+				time.Sleep(time.Second)
+			}
+		},
+	}
+	logCmd.Flags().BoolVarP(&logFollow, "follow", "f", false, i18n.T("Keep printing new queries"))
+	logCmd.Flags().BoolVar(&logBlockedOnly, "blocked-only", false, i18n.T("Only show blocked queries"))
+	logCmd.Flags().StringVar(&logDomain, "domain", "", i18n.T("Only show domains containing this text"))
+	logCmd.Flags().StringVar(&logSince, "since", "", i18n.T("Only show queries since a duration ago or a time"))
+	logCmd.Flags().IntVarP(&logLines, "lines", "n", 50, i18n.T("Number of recent queries to show (0 for all kept)"))
+	logCmd.Flags().BoolVar(&logJSON, "json", false, i18n.T("Print queries as JSON lines (same as --output json)"))
+
+	// Top command - full-screen dashboard for terminals without the GUI
+	topCmd := &cobra.Command{
+		Use:   "top",
+		Short: i18n.T("Full-screen dashboard with live queries, counters and controls"),
+		Long: `Shows filtering state, counters and the live query stream in the terminal,
+updated every second. Keys: e turns filtering on or off, p pauses for 15
+minutes or resumes, b shows blocked queries only, c clears the stream and q
+quits.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			client := daemon.NewClient()
+			if !client.IsRunning() {
+				fmt.Fprintln(os.Stderr, i18n.T("Daemon not running."))
+				os.Exit(1)
+			}
+			if err := top.Run(client); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+		},
+	}
+
+	// Query command - a built-in dig through the local proxy
+	queryCmd := &cobra.Command{
+		Use:   "query <domain> [type]",
+		Short: i18n.T("Resolve a name through the local proxy and show how it was answered"),
+		Long: `Resolves a name through the local proxy, exactly as applications do, and
+shows where the answer came from (cache, split DNS forwarder, FilterDNS
+or the search-domain shortcut), whether it was blocked, the records and
+how long it took.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			qtype := "A"
+			if len(args) > 1 {
+				qtype = args[1]
+			}
+
+			// The daemon's query log tells how the query was answered
+			client := daemon.NewClient()
+			running := client.IsRunning()
+			var last uint64
+			if running {
+				if entries, err := client.QueryLog(dns.QueryFilter{Limit: 1}); err == nil && len(entries) > 0 {
+					last = entries[0].Seq
+				}
+			}
+
+			result, err := dns.Lookup(net.JoinHostPort("127.0.0.1", "53"), args[0], qtype)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				if !running {
+					fmt.Fprintln(os.Stderr, i18n.T("Daemon not running; the proxy only answers while filtering is on."))
+				}
+				os.Exit(1)
+			}
+
+			out := queryOutput{LookupResult: result}
+			if running {
+				entries, _ := client.QueryLog(dns.QueryFilter{After: last, Domain: result.Domain})
+				for _, entry := range entries {
+					if entry.Domain == result.Domain && entry.Type == result.Type {
+						out.Source = entry.Source
+						out.Blocked = entry.Blocked
+						out.ProxyDuration = entry.Duration
+						break
+					}
+				}
+			}
+			if out.Source == dns.SourceForwarder {
+				if cfg, err := config.Load(); err == nil {
+					out.Forwarder = dns.NewForwarderMatcher(cfg.Effective().Forwarders).MatchRule(result.Domain)
+				}
+			}
+
+			if output == outputJSON {
+				printJSON(out)
+				return
+			}
+
+			fmt.Println(i18n.T("Query:    %s %s via %s", result.Domain, result.Type, result.Server))
+			fmt.Println(i18n.T("Path:     %s", describePath(out)))
+			status := result.Rcode
+			if out.Blocked {
+				status += " (blocked)"
+			}
+			fmt.Println(i18n.T("Status:   %s", status))
+			if result.EDE != "" {
+				fmt.Println(i18n.T("Reason:   %s", result.EDE))
+			}
+			for i, answer := range result.Answers {
+				label := ""
+				if i == 0 {
+					label = "Answer:"
+				}
+				fmt.Printf("%-9s %s\n", label, answer)
+			}
+			latency := result.Duration.Round(time.Microsecond).String()
+			if out.Source != "" {
+				latency += fmt.Sprintf(" (%s in the proxy)", out.ProxyDuration.Round(time.Microsecond))
+			}
+			fmt.Println(i18n.T("Latency:  %s", latency))
+		},
+	}
+
+	// Cache commands - inspect and flush the proxy's answer cache
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: i18n.T("Inspect or flush the DNS cache"),
+	}
+
+	cacheStatsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: i18n.T("Show cache size and hit rate"),
+		Run: func(cmd *cobra.Command, args []string) {
+			client := daemon.NewClient()
+			if !client.IsRunning() {
+				fmt.Fprintln(os.Stderr, i18n.T("Daemon not running."))
+				os.Exit(1)
+			}
+
+			stats, err := client.CacheStats()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			if output == outputJSON {
+				printJSON(stats)
+				return
+			}
+			fmt.Println(i18n.T("Entries:  %d of %d", stats.Entries, stats.MaxSize))
+			fmt.Println(i18n.T("Memory:   %.1f KiB", float64(stats.Bytes)/1024))
+			fmt.Println(i18n.T("Hit rate: %.1f%% (%d of %d queries)", percent(stats.Hits, stats.Queries), stats.Hits, stats.Queries))
+		},
+	}
+
+	var cacheDomain string
+	cacheDumpCmd := &cobra.Command{
+		Use:   "dump",
+		Short: i18n.T("List cached answers"),
+		Run: func(cmd *cobra.Command, args []string) {
+			client := daemon.NewClient()
+			if !client.IsRunning() {
+				fmt.Fprintln(os.Stderr, i18n.T("Daemon not running."))
+				os.Exit(1)
+			}
+
+			entries, err := client.CacheDump(cacheDomain)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			if output == outputJSON {
+				if entries == nil {
+					entries = []dns.CacheEntry{}
+				}
+				printJSON(entries)
+				return
+			}
+			if len(entries) == 0 {
+				fmt.Println(i18n.T("Nothing cached."))
+				return
+			}
+			for _, entry := range entries {
+				answer := entry.Rcode
+				if len(entry.Answers) > 0 {
+					answer = strings.Join(entry.Answers, ", ")
+				}
+				fmt.Printf("%-40s %-6s %5ds  %s\n", entry.Domain, entry.Type,
+					int(time.Until(entry.ExpiresAt).Seconds()), answer)
+			}
+		},
+	}
+	cacheDumpCmd.Flags().StringVar(&cacheDomain, "domain", "", i18n.T("Only show domains containing this text"))
+
+	cacheFlushCmd := &cobra.Command{
+		Use:   "flush [domain]",
+		Short: i18n.T("Drop cached answers, all or those for domains containing domain"),
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			client := daemon.NewClient()
+			if !client.IsRunning() {
+				fmt.Fprintln(os.Stderr, i18n.T("Daemon not running."))
+				os.Exit(1)
+			}
+
+			var domain string
+			if len(args) > 0 {
+				domain = args[0]
+			}
+			flushed, err := client.FlushCache(domain)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			if output == outputJSON {
+				printJSON(map[string]int{"flushed": flushed})
+				return
+			}
+			fmt.Println(i18n.T("Flushed %d cached answers.", flushed))
+		},
+	}
+
+	// Events command - follow filtering state changes
+	eventsCmd := &cobra.Command{
+		Use:   "events",
+		Short: i18n.T("Print filtering state changes as JSON lines"),
+		Run: func(cmd *cobra.Command, args []string) {
+			client := daemon.NewClient()
+			encoder := json.NewEncoder(os.Stdout)
+			err := client.Subscribe(func(event daemon.StateEvent) bool {
+				return encoder.Encode(event) == nil
+			})
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+		},
+	}
+
+	// Debug bundle command - collect diagnostics for a support ticket
+	var bundleOutput string
+	debugBundleCmd := &cobra.Command{
+		Use:   "debug-bundle",
+		Short: i18n.T("Collect config, logs and resolver state into a tarball for support"),
+		Run: func(cmd *cobra.Command, args []string) {
+			client := daemon.NewClient()
+			if !client.IsRunning() {
+				fmt.Fprintln(os.Stderr, i18n.T("Daemon not running."))
+				os.Exit(1)
+			}
+
+			bundle, err := client.DebugBundle()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+
+			path := bundleOutput
+			if path == "" {
+				path = fmt.Sprintf("filterdns-debug-%s.tar.gz", time.Now().Format("20060102-150405"))
+			}
+			if err := os.WriteFile(path, bundle, 0600); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Debug bundle written to %s", path))
+			fmt.Println(i18n.T("Passwords are not included; server URL credentials are redacted."))
+		},
+	}
+	debugBundleCmd.Flags().StringVarP(&bundleOutput, "output", "o", "", i18n.T("Output file (default: filterdns-debug-<time>.tar.gz)"))
+
+	// Remote control command - what the GUI on another machine asks for
+	remoteControlCmd := &cobra.Command{
+		Use:   "remote-control",
+		Short: i18n.T("Show how a GUI on another computer connects to this daemon"),
+		Run: func(cmd *cobra.Command, args []string) {
+			client := daemon.NewClient()
+			if !client.IsRunning() {
+				fmt.Fprintln(os.Stderr, i18n.T("Daemon not running."))
+				os.Exit(1)
+			}
+
+			info, err := client.ControlInfo()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				fmt.Fprintln(os.Stderr, i18n.T("Turn it on with: filterdns-client config set control-addr :%s", daemon.ControlPort))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Address:     %s", info.Addr))
+			fmt.Println(i18n.T("Token:       %s", info.Token))
+			fmt.Println(i18n.T("Fingerprint: %s", info.Fingerprint))
+			fmt.Println()
+			fmt.Println(i18n.T("Enter these in the GUI's Settings under Daemon, with this computer's name or IP address in front of the port."))
+		},
+	}
+
+	// Privileged half of the daemon, started by "daemon --user"
+	dnsHelperCmd := &cobra.Command{
+		Use:    "dns-helper",
+		Short:  i18n.T("Privileged DNS helper (started by the daemon)"),
+		Hidden: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := privsep.Serve(); err != nil {
+				log.Fatalf("DNS helper failed: %v", err)
+			}
+		},
+	}
+
+	// Purges the invoking user's data as that user, for "uninstall --purge"
+	purgeUserCmd := &cobra.Command{
+		Use:    "purge-user",
+		Short:  i18n.T("Remove this user's config and saved passwords (run by uninstall --purge)"),
+		Hidden: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := config.Purge(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	// Service commands - the daemon's lifecycle under the service manager
+	serviceCmd := &cobra.Command{
+		Use:   "service",
+		Short: i18n.T("Control the system service running the daemon"),
+	}
+
+	serviceStatusCmd := &cobra.Command{
+		Use:   "status",
+		Short: i18n.T("Show whether the service is installed, running and enabled at boot"),
+		Run: func(cmd *cobra.Command, args []string) {
+			state, err := service.Status()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			reachable := daemon.NewClient().IsRunning()
+
+			if output == outputJSON {
+				printJSON(serviceOutput{State: state, DaemonReachable: reachable})
+				return
+			}
+			if !state.Installed {
+				fmt.Println(i18n.T("Service: not installed (install with: sudo filterdns-client install)"))
+			} else {
+				fmt.Println(i18n.T("Service: %s", state.Detail))
+				fmt.Println(i18n.T("Start at boot: %s", yesNo(state.Enabled)))
+			}
+			if reachable {
+				fmt.Println(i18n.T("Daemon: responding"))
+			} else {
+				fmt.Println(i18n.T("Daemon: not responding"))
+			}
+		},
+	}
+
+	serviceStartCmd := &cobra.Command{
+		Use:   "start",
+		Short: i18n.T("Start the system service"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := service.Start(); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Failed to start service: %v", err))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Service started"))
+		},
+	}
+
+	serviceStopCmd := &cobra.Command{
+		Use:   "stop",
+		Short: i18n.T("Stop the system service"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := service.Stop(); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Failed to stop service: %v", err))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Service stopped"))
+		},
+	}
+
+	serviceRestartCmd := &cobra.Command{
+		Use:   "restart",
+		Short: i18n.T("Restart the system service"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := service.Restart(); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Failed to restart service: %v", err))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Service restarted"))
+		},
+	}
+
+	serviceEnableCmd := &cobra.Command{
+		Use:   "enable",
+		Short: i18n.T("Start the system service at boot"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := service.Enable(); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Failed to enable service: %v", err))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Service enabled"))
+		},
+	}
+
+	serviceDisableCmd := &cobra.Command{
+		Use:   "disable",
+		Short: i18n.T("Stop starting the system service at boot"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := service.Disable(); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Failed to disable service: %v", err))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Service disabled (still running until stopped)"))
+		},
+	}
+
+	var logsLines int
+	var logsFollow bool
+	serviceLogsCmd := &cobra.Command{
+		Use:   "logs",
+		Short: i18n.T("Show the service log (journalctl on Linux, the log file on macOS)"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := service.Logs(logsLines, logsFollow); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Failed to read service log: %v", err))
+				os.Exit(1)
+			}
+		},
+	}
+	serviceLogsCmd.Flags().IntVarP(&logsLines, "lines", "n", 50, i18n.T("Number of lines to show"))
+	serviceLogsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, i18n.T("Keep printing new lines"))
+
+	// The flat commands predate the service group; kept for scripts
+	legacyServiceStartCmd := &cobra.Command{
+		Use:        "service-start",
+		Short:      serviceStartCmd.Short,
+		Hidden:     true,
+		Deprecated: `use "service start" instead`,
+		Run:        serviceStartCmd.Run,
+	}
+	legacyServiceStopCmd := &cobra.Command{
+		Use:        "service-stop",
+		Short:      serviceStopCmd.Short,
+		Hidden:     true,
+		Deprecated: `use "service stop" instead`,
+		Run:        serviceStopCmd.Run,
+	}
+
+	// Restore network command - the escape hatch when anything goes wrong
+	restoreNetworkCmd := &cobra.Command{
+		Use:   "restore-network",
+		Short: i18n.T("Stop filtering and restore the original network settings"),
+		Long: `Stops the proxy, restores system DNS from the backup, points anything
+still using the proxy back at automatic DNS and checks that names resolve.
+Works whether or not the daemon is running.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := restoreNetwork(); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Network settings restored, filtering is off"))
+		},
+	}
+
+	// DNS reset command - used by systemd ExecStopPost to restore DNS on service stop
+	var dnsResetForce bool
+	dnsResetCmd := &cobra.Command{
+		Use:   "dns-reset",
+		Short: i18n.T("Reset system DNS to default (used by service on stop)"),
+		Long: `Restores the system DNS settings saved when filtering was turned on
+and removes the firewall rules of block-outside-dns.
+
+With --force, DNS is restored from the backup if there is one and otherwise
+every interface is set back to automatic (DHCP) DNS. Use it when names no
+longer resolve after a crash and the backup is gone; DNS servers set by
+hand have to be entered again afterwards.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Rules a crashed daemon left would keep blocking DNS
+			if err := system.UnblockOutsideDNS(); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Failed to remove DNS firewall rules: %v", err))
+			}
+
+			reset := system.ResetDNS
+			if dnsResetForce {
+				reset = system.ForceResetDNS
+			}
+			if err := reset(); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Failed to reset DNS: %v", err))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("DNS settings restored"))
+		},
+	}
+	dnsResetCmd.Flags().BoolVar(&dnsResetForce, "force", false, i18n.T("Revert to automatic DNS if no backup exists"))
+
+	// DNS commands
+	dnsCmd := &cobra.Command{
+		Use:   "dns",
+		Short: i18n.T("Inspect the system resolver"),
+	}
+
+	dnsShowCmd := &cobra.Command{
+		Use:   "show",
+		Short: i18n.T("Show where system DNS points and what manages it"),
+		Run: func(cmd *cobra.Command, args []string) {
+			resolver, err := system.InspectDNS()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+
+			if output == outputJSON {
+				printJSON(resolver)
+				return
+			}
+			fmt.Println(i18n.T("Backend: %s", resolver.Backend))
+			for _, link := range resolver.Links {
+				servers := strings.Join(link.Servers, ", ")
+				if servers == "" {
+					servers = "automatic"
+				}
+				fmt.Printf("  %s: %s\n", link.Name, servers)
+			}
+			fmt.Println(i18n.T("Local proxy in use: %s", yesNo(resolver.UsesProxy)))
+			fmt.Println(i18n.T("Backup of original settings: %s", yesNo(resolver.Backup)))
+			fmt.Println(i18n.T("DNS outside the proxy blocked: %s", yesNo(resolver.Firewall)))
+			if j := resolver.Interrupted; j != nil {
+				fmt.Println(i18n.T("Interrupted: %s started %s", j.Operation, j.StartedAt.Local().Format("2006-01-02 15:04:05")))
+			}
+
+			if resolver.UsesProxy && !daemon.NewClient().IsRunning() {
+				fmt.Println()
+				fmt.Println(i18n.T("System DNS points at the local proxy, but the daemon is not running."))
+				fmt.Println(i18n.T("Restore it with: sudo filterdns-client dns-reset --force"))
+			}
+		},
+	}
+
+	var conflictsFix, conflictsYes bool
+	dnsConflictsCmd := &cobra.Command{
+		Use:   "conflicts",
+		Short: i18n.T("List other DNS servers that hold port 53 on the loopback"),
+		Long: `Lists DNS servers such as dnsmasq, systemd-resolved's extra stub
+listeners, Unbound or a Docker container that already listen on port 53 of
+the addresses the proxy needs, and what to do about each. Only Linux can
+tell which program holds the port.
+
+With --fix, systemd-resolved and dnsmasq are reconfigured to leave the
+loopback to the proxy and restarted, after asking for each (--yes skips
+the question). Needs root.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := config.Load()
+			if err != nil {
+				cfg = config.Default()
+			}
+			conflicts, err := system.ResolverConflicts(cfg.LoopbackAddrs())
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+				os.Exit(1)
+			}
+
+			if output == outputJSON && !conflictsFix {
+				if conflicts == nil {
+					conflicts = []system.ResolverConflict{}
+				}
+				printJSON(conflicts)
+				return
+			}
+			if len(conflicts) == 0 {
+				fmt.Println(i18n.T("No other DNS server listens on port 53 of %s", strings.Join(cfg.LoopbackAddrs(), ", ")))
+				return
+			}
+			for _, c := range conflicts {
+				fmt.Println(c)
+				fmt.Printf("  %s\n", c.Remedy)
+			}
+			if !conflictsFix {
+				return
+			}
+
+			if os.Geteuid() != 0 {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: fixing conflicts needs root, run it with sudo"))
+				os.Exit(1)
+			}
+			failed := false
+			for _, c := range conflicts {
+				if !c.Fixable {
+					continue
+				}
+				fmt.Println()
+				if !conflictsYes && !confirm(i18n.T("Reconfigure %s and restart it?", c.Process)) {
+					continue
+				}
+				if err := system.FixResolverConflict(c); err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+					failed = true
+					continue
+				}
+				fmt.Println(i18n.T("%s reconfigured", c.Process))
+			}
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+	dnsConflictsCmd.Flags().BoolVar(&conflictsFix, "fix", false, i18n.T("Reconfigure systemd-resolved or dnsmasq to make way for the proxy"))
+	dnsConflictsCmd.Flags().BoolVarP(&conflictsYes, "yes", "y", false, i18n.T("Do not ask before reconfiguring"))
+
+	dnsCmd.AddCommand(dnsShowCmd, dnsConflictsCmd)
+
+	// Autostart command - start the GUI on login
+	autostartCmd := &cobra.Command{
+		Use:       "autostart [on|off]",
+		Short:     i18n.T("Show or change whether the client starts on login"),
+		Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"on", "off"},
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				enabled := system.IsAutostartEnabled()
+				if output == outputJSON {
+					printJSON(map[string]bool{"enabled": enabled})
+					return
+				}
+				fmt.Println(i18n.T("Start on login: %s", yesNo(enabled)))
+				return
+			}
+
+			enabled := args[0] == "on"
+			if err := system.SetAutostart(enabled); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Error: failed to update login items: %v", err))
+				os.Exit(1)
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				cfg = config.Default()
+			}
+			cfg.Autostart = enabled
+			if err := config.Save(cfg); err != nil && !errors.Is(err, config.ErrReadOnly) {
+				fmt.Fprintln(os.Stderr, i18n.T("Error saving config: %v", configProblems(err)))
+				os.Exit(1)
+			}
+			fmt.Println(i18n.T("Start on login: %s", args[0]))
+		},
+	}
+
+	// Onboard command - web-based setup
+	var onboardServer, onboardLink, onboardProfile, onboardPassword string
+	var onboardHeadless, onboardPasswordStdin bool
+	onboardCmd := &cobra.Command{
+		Use:   "onboard",
+		Short: i18n.T("Connect to FilterDNS via web-based setup"),
+		Long: `Opens a browser to complete the FilterDNS setup.
+
+This launches a web-based onboarding flow where you can:
+- Select an existing profile
+- Create a new profile
+- Configure your connection
+
+The configuration is automatically saved when complete.
+
+With --link, setup finishes from a filterdns://onboard link copied from
+the dashboard instead of starting a new flow.
+
+On machines without a browser, --headless prints the URL and a short
+pairing code to enter on another device. With --profile, the web flow is
+skipped and the given profile and password are saved directly.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if (onboardPassword != "" || onboardPasswordStdin) && onboardProfile == "" {
+				fmt.Fprintln(os.Stderr, i18n.T("--password and --password-stdin need --profile"))
+				os.Exit(1)
+			}
+
+			if onboardLink != "" {
+				serverURL, token, err := onboard.ParseDeepLink(onboardLink)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Invalid link: %v", err))
+					os.Exit(1)
+				}
+				fmt.Println(i18n.T("Connecting to %s...", config.RedactURL(serverURL)))
+
+				result, err := onboard.Resume(serverURL, token)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Onboarding failed: %v", err))
+					os.Exit(1)
+				}
+				if err := onboard.SaveResult(result); err != nil {
+					fmt.Fprintln(os.Stderr, i18n.T("Failed to save config: %v", err))
+					os.Exit(1)
+				}
+				fmt.Println(i18n.T("\nSuccess! Connected to profile: %s", result.ProfileName))
+				return
+			}
+
+			serverURL := onboardServer
+			if serverURL == "" {
+				// Try to get from existing config
+				cfg, err := config.Load()
+				if err != nil {
+					cfg = config.Default()
+				}
+				if cfg.ServerURL != "" {
+					serverURL = cfg.ServerURL
+				} else {
+					serverURL = config.DefaultServerURL
+				}
+			}
+
+			var result *onboard.Result
+			var err error
+			switch {
+			case onboardProfile != "":
+				password := onboardPassword
+				if onboardPasswordStdin {
+					data, err := io.ReadAll(os.Stdin)
+					if err != nil {
+						fmt.Fprintln(os.Stderr, i18n.T("Error reading password: %v", err))
+						os.Exit(1)
+					}
+					password = strings.TrimRight(string(data), "\r\n")
+				}
+				result, err = onboard.WithProfile(serverURL, onboardProfile, password)
+			case onboardHeadless:
+				fmt.Println(i18n.T("Connecting to %s...", config.RedactURL(serverURL)))
+				result, err = onboard.RunHeadless(serverURL)
+			default:
+				fmt.Println(i18n.T("Connecting to %s...", config.RedactURL(serverURL)))
+				result, err = onboard.Run(serverURL)
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Onboarding failed: %v", err))
+				os.Exit(1)
+			}
+
+			if err := onboard.SaveResult(result); err != nil {
+				fmt.Fprintln(os.Stderr, i18n.T("Failed to save config: %v", err))
+				os.Exit(1)
+			}
+
+			fmt.Println(i18n.T("\nSuccess! Connected to profile: %s", result.ProfileName))
+			fmt.Println(i18n.T("\nTo start filtering, run: filterdns-client start"))
+			fmt.Println(i18n.T("Or start the GUI app for system tray access."))
+		},
+	}
+	onboardCmd.Flags().StringVarP(&onboardServer, "server", "s", "", i18n.T("FilterDNS server URL (default: from config or http://localhost:8080)"))
+	onboardCmd.Flags().StringVar(&onboardLink, "link", "", i18n.T("Finish setup from a filterdns://onboard link"))
+	onboardCmd.Flags().BoolVar(&onboardHeadless, "headless", false, i18n.T("Print the setup URL and a pairing code instead of opening a browser"))
+	onboardCmd.Flags().StringVar(&onboardProfile, "profile", "", i18n.T("Skip the web flow and use this FilterDNS profile"))
+	onboardCmd.Flags().StringVar(&onboardPassword, "password", "", i18n.T("Password of --profile (visible to other users, prefer --password-stdin)"))
+	onboardCmd.Flags().BoolVar(&onboardPasswordStdin, "password-stdin", false, i18n.T("Read the password of --profile from standard input"))
+	onboardCmd.MarkFlagsMutuallyExclusive("link", "headless", "profile")
+	onboardCmd.MarkFlagsMutuallyExclusive("password", "password-stdin")
+
+	// Pure-config mode for declaratively managed installs (NixOS, home-manager)
+	var configFile string
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Read-only config file managed outside the client (default: $"+config.ConfigEnv+")")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", outputText, i18n.T("Output format: text or json"))
+	rootCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{outputText, outputJSON}, cobra.ShellCompDirectiveNoFileComp))
+	var configSets []string
+	rootCmd.PersistentFlags().StringArrayVar(&configSets, "set", nil, i18n.T("Override a config key without saving it, as key=value (repeatable)"))
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		if configFile != "" {
+			config.SetPureConfig(configFile)
+		}
+		overrides, err := configOverrides(configSets)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+			os.Exit(1)
+		}
+		config.SetOverrides(overrides)
+		config.SetMachineWriter(daemon.SaveMachineConfig)
+		if output != outputText && output != outputJSON {
+			fmt.Fprintln(os.Stderr, i18n.T("Invalid --output %q (text or json)", output))
+			os.Exit(1)
+		}
+	}
+
+	// Build command tree
+	configCmd.AddCommand(configGetCmd, configSetCmd, configUnsetCmd, configShowCmd, configValidateCmd, configExportCmd, configImportCmd)
+	forwarderCmd.AddCommand(forwarderAddCmd, forwarderListCmd, forwarderTestCmd, forwarderRemoveCmd)
+	cacheCmd.AddCommand(cacheStatsCmd, cacheDumpCmd, cacheFlushCmd)
+	rulesCmd.AddCommand(rulesListCmd, rulesRemoveCmd)
+	profileCmd.AddCommand(profileListCmd, profileAddCmd, profileSwitchCmd, profileRemoveCmd)
+	networkCmd.AddCommand(networkListCmd, networkAddCmd, networkRemoveCmd)
+	scheduleCmd.AddCommand(scheduleListCmd, scheduleAddCmd, scheduleRemoveCmd)
+	serviceCmd.AddCommand(serviceStatusCmd, serviceStartCmd, serviceStopCmd, serviceRestartCmd, serviceEnableCmd, serviceDisableCmd, serviceLogsCmd)
+	rootCmd.AddCommand(startCmd, stopCmd, pauseCmd, resumeCmd, statusCmd, reloadCmd, statsCmd, logCmd, topCmd, queryCmd, cacheCmd, eventsCmd, doctorCmd, leaktestCmd, debugBundleCmd, remoteControlCmd, loadtestCmd, configCmd, autostartCmd, forwarderCmd, allowCmd, blockCmd, rulesCmd, profileCmd, networkCmd, scheduleCmd, onboardCmd)
+	rootCmd.AddCommand(installCmd, uninstallCmd, provisionCmd, updateCmd, daemonCmd)
+	rootCmd.AddCommand(serviceCmd, legacyServiceStartCmd, legacyServiceStopCmd, dnsResetCmd, dnsCmd, restoreNetworkCmd, dnsHelperCmd, purgeUserCmd)
+
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// restoreNetwork turns filtering off and restores the original network
+// settings, through the daemon if it works and directly otherwise
+func restoreNetwork() error {
+	// A working daemon stops its proxy and undoes its changes itself
+	client := daemon.NewClient()
+	if client.IsRunning() {
+		_, err := client.RestoreNetwork()
+		if err == nil {
+			return nil
+		}
+		fmt.Fprintln(os.Stderr, i18n.T("Daemon could not restore the network: %v", err))
+	}
+
+	// The daemon is gone or stuck; make sure its proxy is down
+	if err := service.Stop(); err == nil {
+		fmt.Println(i18n.T("Service stopped"))
+	}
+	return system.RestoreNetwork()
+}
+
+// seedMachineConfig starts the machine config from the config of the user
+// who ran sudo, so the daemon carries on with the profile they set up
+// before installing it
+func seedMachineConfig() {
+	name := os.Getenv("SUDO_USER")
+	if name == "" || name == "root" {
+		return
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return
+	}
+	if adopted, err := config.AdoptConfig(config.DirFor(u.HomeDir)); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: %v", err))
+	} else if adopted {
+		fmt.Println(i18n.T("Settings of %s copied to %s", name, config.SystemDir()))
+	}
+}
+
+// invokingUser returns the user who ran sudo or pkexec, "" if unknown
+func invokingUser() string {
+	if name := os.Getenv("SUDO_USER"); name != "" {
+		return name
+	}
+	if uid := os.Getenv("PKEXEC_UID"); uid != "" {
+		if u, err := user.LookupId(uid); err == nil {
+			return u.Username
+		}
+	}
+	return ""
+}
+
+// recordOwner saves the user the service is installed for in the machine
+// config, so the daemon lets them read its query history
+func recordOwner(name string) {
+	if name == "" || name == "root" {
+		return
+	}
+	if err := config.UseSystemConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: %v", err))
+		return
+	}
+	cfg, err := config.Load()
+	if err != nil || cfg.Owner == name {
+		return
+	}
+	cfg.Owner = name
+	if err := config.Save(cfg); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: %v", err))
+	}
+}
+
+// purgeData removes what the client stored on this machine: the config
+// of the user who ran sudo (purged as that user) and of root, the
+// machine config, with their keychain entries, the system state and the
+// service log
+func purgeData() error {
+	var errs []error
+	if name := os.Getenv("SUDO_USER"); name != "" && name != "root" {
+		if u, err := user.Lookup(name); err == nil {
+			if err := purgeAsUser(u); err != nil {
+				// Their passwords stay in their keychain, but the files go
+				errs = append(errs, fmt.Errorf("failed to purge as %s: %w", name, err))
+				config.SetDir(config.DirFor(u.HomeDir))
+				errs = append(errs, config.Purge())
+				config.SetDir("")
+			}
+		}
+	}
+	errs = append(errs, config.Purge())
+	if err := config.UseSystemConfig(); err == nil {
+		errs = append(errs, config.Purge())
+	}
+
+	if err := system.RemoveState(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to remove state directory: %w", err))
+	}
+	if err := service.RemoveLogs(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to remove service log: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// purgeAsUser runs purge-user as u, since their passwords are in their
+// keychain, which root cannot open. The session bus is the one of their
+// login session, which sudo does not pass on.
+func purgeAsUser(u *user.User) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	runtimeDir := "/run/user/" + u.Uid
+	cmd := exec.Command("sudo", "-u", u.Username, "-H", "env",
+		"XDG_RUNTIME_DIR="+runtimeDir,
+		"DBUS_SESSION_BUS_ADDRESS=unix:path="+runtimeDir+"/bus",
+		exe, "purge-user")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Exit codes of start and stop, for scripts
+const (
+	exitFailure     = 1 // Anything not listed below
+	exitNoDaemon    = 2 // The daemon is not running
+	exitAuthFailed  = 3 // The server rejected the profile password
+	exitDNSFailed   = 4 // System DNS or the proxy could not be set up
+	exitNotVerified = 5 // --wait timed out
+	exitNoProfile   = 6 // No profile configured
+)
+
+// errNotVerified is returned by waitForFiltering when it times out
+var errNotVerified = errors.New("not verified")
+
+// exitCode maps an error from start or stop to its exit code
+func exitCode(err error) int {
+	if errors.Is(err, errNotVerified) {
+		return exitNotVerified
+	}
+	var daemonErr *daemon.Error
+	if !errors.As(err, &daemonErr) {
+		return exitFailure
+	}
+	switch daemonErr.Code {
+	case daemon.CodeAuthFailed:
+		return exitAuthFailed
+	case daemon.CodeDNSFailed, daemon.CodeProxyFailed, daemon.CodePortInUse:
+		return exitDNSFailed
+	case daemon.CodeNoProfile:
+		return exitNoProfile
+	default:
+		return exitFailure
+	}
+}
+
+// waitForFiltering polls the daemon until filtering is verifiably on (or
+// off, with system DNS no longer pointing at the proxy). A rejected
+// password ends the wait at once, since waiting will not fix it.
+func waitForFiltering(client *daemon.Client, on bool, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		v, err := client.Verify()
+		if err != nil {
+			return err
+		}
+		switch {
+		case on && v.OK(), !on && !v.Running && !v.SystemDNSOK:
+			return nil
+		case on && v.Code == daemon.CodeAuthFailed:
+			return &daemon.Error{Code: v.Code, Message: v.Error}
+		}
+
+		if time.Now().After(deadline) {
+			reason := "system DNS still points at the proxy"
+			if on {
+				reason = v.Error
+			}
+			return fmt.Errorf("%w after %s: %s", errNotVerified, timeout, reason)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// printJSON prints v as indented JSON for scripts, with secrets redacted
+func printJSON(v any) {
+	data, err := config.RedactJSON(v)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Error: %v", err))
+		os.Exit(1)
+	}
+	fmt.Println(string(data))
+}
+
+// printStats prints a stats report, with daily history if days is set
+func printStats(report *daemon.StatsReport, days bool) {
+	fmt.Println(i18n.T("Today:   %s", countsString(report.Today)))
+	fmt.Println(i18n.T("7 days:  %s", countsString(report.Week)))
+	fmt.Println(i18n.T("Total:   %s (since %s)", countsString(report.Total), report.Since.Format("2006-01-02")))
+
+	fmt.Println()
+	fmt.Println(i18n.T("Uptime:  %s", time.Since(report.StartedAt).Round(time.Second)))
+	fmt.Println(i18n.T("Session: %s, cache hit rate %.1f%%", countsString(report.Session),
+		percent(report.CacheHits, report.Session.Queries)))
+	if l := report.Latency; l != nil {
+		fmt.Println(i18n.T("Latency: %s average, %s median, %s p95 (last %d upstream queries)",
+			l.Average.Round(time.Millisecond), l.Median.Round(time.Millisecond),
+			l.P95.Round(time.Millisecond), l.Samples))
+	}
+
+	if len(report.TopBlocked) > 0 {
+		fmt.Println(i18n.T("\nTop blocked:"))
+		for _, domain := range report.TopBlocked {
+			fmt.Printf("  %8d  %s\n", domain.Count, domain.Domain)
+		}
+	}
+
+	if len(report.History) > 0 {
+		layout := "2006-01-02"
+		if report.Resolution < stats.Daily {
+			layout = "2006-01-02 15:04"
+		}
+		fmt.Println()
+		for _, bucket := range report.History {
+			fmt.Println(i18n.T("  %-16s  %8d queries  %8d blocked", bucket.Start.Format(layout), bucket.Queries, bucket.Blocked))
+		}
+	} else if days && len(report.Days) > 0 {
+		fmt.Println()
+		for _, day := range report.Days {
+			fmt.Println(i18n.T("  %s  %8d queries  %8d blocked", day.Date, day.Queries, day.Blocked))
+		}
+	}
+}
+
+// countsString formats query counts with the blocked share
+func countsString(counts stats.Counts) string {
+	return i18n.T("%d queries, %d blocked (%.1f%%)", counts.Queries, counts.Blocked,
+		percent(counts.Blocked, counts.Queries))
+}
+
+// percent returns part as a percentage of total, or 0 if total is 0
+func percent(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
+
+// printQuery prints a query log entry as one line
+func printQuery(entry dns.QueryLogEntry) {
+	result := entry.Rcode
+	if entry.Blocked {
+		result = "BLOCKED"
+	}
+	fmt.Printf("%s  %-8s %-6s %s  (%s, %s)\n", entry.Time.Format("15:04:05"), result, entry.Type,
+		entry.Domain, entry.Source, entry.Duration.Round(time.Millisecond))
+}
+
+// parseSince reads a --since value: a duration before now, or a time
+func parseSince(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// changeRules applies change to the daemon's config if the daemon is
+// running, so the proxy picks it up at once, or else to the config file.
+// change reports whether it changed anything.
+func changeRules(change func(cfg *config.Config) bool) (*config.Config, error) {
+	client := daemon.NewClient()
+	if client.IsRunning() {
+		cfg, err := client.GetConfig()
+		if err != nil {
+			return nil, err
+		}
+		if !change(cfg) {
+			return cfg, nil
+		}
+		return cfg, client.SetConfig(cfg)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.Default()
+	}
+	if !change(cfg) {
+		return cfg, nil
+	}
+	return cfg, config.Save(cfg)
+}
+
+// syncRule mirrors a rule change to the server profile, if the server
+// has a rules API, and reports whether it did. Failing to do so leaves
+// the local rule in place.
+func syncRule(cfg *config.Config, rule config.Rule, remove bool) bool {
+	mirrored, err := filtersync.MirrorRule(cfg, rule, remove)
+	switch {
+	case err != nil:
+		fmt.Fprintln(os.Stderr, i18n.T("Warning: profile %s on the server not updated: %v", cfg.Profile, err))
+		return false
+	case mirrored:
+		fmt.Println(i18n.T("Profile %s on the server updated too", cfg.Profile))
+	}
+	return mirrored
+}
+
+// saveConfigKey saves cfg after name was changed, applying the autostart
+// setting to the system as well
+func saveConfigKey(cfg *config.Config, name string) {
+	if keyLocked(name) {
+		fmt.Fprintln(os.Stderr, i18n.T("%s is locked by your administrator.", name))
+		os.Exit(1)
+	}
+	if err := config.Save(cfg); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Error saving config: %v", configProblems(err)))
+		os.Exit(1)
+	}
+	if name == "autostart" {
+		if err := system.SetAutostart(cfg.Autostart); err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("Warning: failed to update login items: %v", err))
+		}
+	}
+}
+
+// configKeyHelp lists the config keys for help output
+func configKeyHelp() string {
+	var b strings.Builder
+	for _, key := range configKeys {
+		fmt.Fprintf(&b, "  %-20s %s\n", key.name, key.help)
+	}
+	fmt.Fprintf(&b, "  %-20s %s\n", passwordKey, "Profile password, stored in the system keychain")
+	return b.String()
+}
+
+// reloadDaemon has a running daemon pick up a config change at once,
+// e.g. to start or stop polling the state of a saved profile
+func reloadDaemon() {
+	client := daemon.NewClient()
+	if !client.IsRunning() {
+		return
+	}
+	if _, err := client.Reload(); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("Error reloading daemon: %v", err))
+	}
+}
+
+// completeForwarder completes the domains of configured forwarders
+func completeForwarder(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	domains := make([]string, 0, len(cfg.Forwarders))
+	for _, f := range cfg.Forwarders {
+		domains = append(domains, f.Domain)
+	}
+	return domains, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeRule completes the domains of local rules
+func completeRule(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	domains := make([]string, 0, len(cfg.Rules))
+	for _, r := range cfg.Rules {
+		domains = append(domains, r.Domain)
+	}
+	return domains, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSavedProfile completes the names of saved profiles
+func completeSavedProfile(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names := make([]string, 0, len(cfg.Profiles))
+	for _, p := range cfg.Profiles {
+		names = append(names, p.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeNetwork completes the names of networks with settings of their own
+func completeNetwork(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names := make([]string, 0, len(cfg.Networks))
+	for _, n := range cfg.Networks {
+		names = append(names, n.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeScheduleEntry completes the names of schedule entries
+func completeScheduleEntry(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names := make([]string, 0, len(cfg.Schedule))
+	for _, e := range cfg.Schedule {
+		names = append(names, e.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// describeScheduleAction says what a schedule entry does during its time
+// range
+func describeScheduleAction(e *config.ScheduleEntry) string {
+	switch e.Action {
+	case config.ScheduleOff:
+		return i18n.T("filtering paused")
+	case config.ScheduleOn:
+		return i18n.T("filtering on")
+	case config.ScheduleProfile:
+		return i18n.T("profile %s", e.Profile)
+	}
+	return e.Action
+}
+
+// completeConfigKey completes key names, and true/false for boolean keys
+func completeConfigKey(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return configKeyNames(), cobra.ShellCompDirectiveNoFileComp
+	case 1:
+		if key, err := findConfigKey(args[0]); err == nil && cmd.Name() == "set" {
+			if _, ok := key.get(config.Default()).(bool); ok {
+				return []string{"true", "false"}, cobra.ShellCompDirectiveNoFileComp
+			}
+		}
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// waitForDaemon waits up to timeout for a just started daemon to answer
+func waitForDaemon(client *daemon.Client, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for !client.IsRunning() && time.Now().Before(deadline) {
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// describePath explains where the proxy got a query's answer from
+func describePath(out queryOutput) string {
+	switch out.Source {
+	case dns.SourceCache:
+		return "cache"
+	case dns.SourceUpstream:
+		return "FilterDNS (DNS-over-HTTPS)"
+	case dns.SourceSearch:
+		return "search-domain shortcut (answered locally)"
+	case dns.SourceAllowRule:
+		return "local allow rule (resolved past FilterDNS)"
+	case dns.SourceBlockRule:
+		return "local block rule (answered locally)"
+	case dns.SourceForwarder:
+		if f := out.Forwarder; f != nil {
+			return fmt.Sprintf("split DNS forwarder %s → %s", f.Domain, f.Server)
+		}
+		return "split DNS forwarder"
+	case "":
+		return "unknown (daemon not running or query not logged)"
+	default:
+		return out.Source
+	}
+}
+
+// parsePause reads the arguments of "pause": a duration, or "until" and
+// a time of day, which is the next one after now
+func parsePause(args []string, now time.Time) (time.Duration, error) {
+	if args[0] != "until" {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("expected a duration such as 30m, or 'until 17:00'")
+		}
+		d, err := time.ParseDuration(args[0])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", args[0], err)
+		}
+		if d <= 0 {
+			return 0, fmt.Errorf("pause duration must be positive")
+		}
+		return d, nil
+	}
+
+	if len(args) != 2 {
+		return 0, fmt.Errorf("expected a time after 'until', e.g. 'until 17:00'")
+	}
+	clock, err := time.Parse("15:04", args[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", args[1])
+	}
+	until := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !until.After(now) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until.Sub(now), nil
+}
+
+// remaining formats the time left until t, e.g. "1h25m" or "40s"
+func remaining(until time.Time) string {
+	left := time.Until(until)
+	if left < time.Minute {
+		return max(left, 0).Round(time.Second).String()
+	}
+	s := left.Round(time.Minute).String()
+	return strings.TrimSuffix(s, "0s")
+}
+
+// okString formats a probe result for display
+func okString(ok bool) string {
+	if ok {
+		return i18n.T("ok")
+	}
+	return i18n.T("blocked")
+}
+
+// describeSkew formats a clock skew as how far ahead or behind the
+// server's clock the local one is
+func describeSkew(skew time.Duration) string {
+	if skew < 0 {
+		return i18n.T("%s behind", (-skew).Round(time.Second))
+	}
+	return i18n.T("%s ahead of", skew.Round(time.Second))
+}
+
+// printClock reports a clock check for "doctor"
+func printClock(clock *filtersync.ClockCheck) {
+	if clock.Skew.Abs() > filtersync.MaxClockSkew {
+		fmt.Println(i18n.T("Warning: this computer's clock is %s the server's; TLS to the server may fail until it is set right.",
+			describeSkew(clock.Skew)))
+	} else {
+		fmt.Println(i18n.T("Clock: in step with the server (off by %s)", clock.Skew.Abs().Round(time.Second)))
+	}
+	if !clock.CertValid && clock.NotBefore != nil && clock.NotAfter != nil {
+		fmt.Println(i18n.T("Warning: the server certificate is valid from %s to %s, which does not include this computer's time.",
+			clock.NotBefore.Format(time.RFC3339), clock.NotAfter.Format(time.RFC3339)))
+	}
+}
+
+// yesNo formats a feature flag for display
+func yesNo(ok bool) string {
+	if ok {
+		return i18n.T("yes")
+	}
+	return i18n.T("no")
+}
+
+// confirm asks a yes/no question on the terminal; anything but yes is no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes", "j", "ja":
+		return true
+	}
+	return false
+}
+
+// splitList parses a comma-separated config value; an empty value clears it
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// fromServer marks a setting that comes from the server profile's client
+// settings
+func fromServer(yes bool) string {
+	if !yes {
+		return ""
+	}
+	return i18n.T(" (from server)")
+}
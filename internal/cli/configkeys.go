@@ -0,0 +1,424 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+)
+
+// configKey is a setting reachable through "config get/set/unset"
+type configKey struct {
+	name  string
+	help  string
+	env   string // Environment variable overriding it, if not the one envName derives
+	json  string // Name of the setting in config.json, for the administrator's policy
+	get   func(cfg *config.Config) any
+	set   func(cfg *config.Config, value string) error
+	unset func(cfg *config.Config) // Restores the default
+}
+
+// passwordKey is stored in the keychain rather than config.json, so it
+// is handled separately and can be set or unset but not read back
+const passwordKey = "password"
+
+// configKeys lists the settable keys in the order "config get" shows them
+var configKeys = []configKey{
+	{
+		name:  "profile",
+		help:  "FilterDNS profile name",
+		json:  "profile",
+		get:   func(cfg *config.Config) any { return cfg.Profile },
+		set:   func(cfg *config.Config, v string) error { cfg.Profile = v; return nil },
+		unset: func(cfg *config.Config) { cfg.Profile = "" },
+	},
+	{
+		name: "server",
+		help: "FilterDNS server URL",
+		env:  "FILTERDNS_SERVER_URL",
+		json: "serverUrl",
+		get:  func(cfg *config.Config) any { return cfg.ServerURL },
+		set: func(cfg *config.Config, v string) error {
+			u, err := url.Parse(v)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("server must be an http:// or https:// URL")
+			}
+			cfg.ServerURL = v
+			return nil
+		},
+		unset: func(cfg *config.Config) { cfg.ServerURL = config.DefaultServerURL },
+	},
+	{
+		name:  "autostart",
+		help:  "Start the client on login (true or false)",
+		json:  "autostart",
+		get:   func(cfg *config.Config) any { return cfg.Autostart },
+		set:   boolSetter("autostart", func(cfg *config.Config) *bool { return &cfg.Autostart }),
+		unset: func(cfg *config.Config) { cfg.Autostart = false },
+	},
+	{
+		name: "language",
+		help: "Language of the GUI and CLI: " + strings.Join(i18n.Languages, ", ") + " (empty = the system's)",
+		json: "language",
+		get:  func(cfg *config.Config) any { return cfg.Language },
+		set: func(cfg *config.Config, v string) error {
+			if !slices.Contains(i18n.Languages, v) {
+				return fmt.Errorf("language must be one of %s", strings.Join(i18n.Languages, ", "))
+			}
+			cfg.Language = v
+			return nil
+		},
+		unset: func(cfg *config.Config) { cfg.Language = "" },
+	},
+	{
+		name:  "start-minimized",
+		help:  "Start the GUI in the tray without its window (true or false)",
+		json:  "startMinimized",
+		get:   func(cfg *config.Config) any { return cfg.StartMinimized },
+		set:   boolSetter("start-minimized", func(cfg *config.Config) *bool { return &cfg.StartMinimized }),
+		unset: func(cfg *config.Config) { cfg.StartMinimized = false },
+	},
+	{
+		name:  "close-quits",
+		help:  "Closing the GUI window quits instead of hiding it in the tray (true or false)",
+		json:  "closeQuits",
+		get:   func(cfg *config.Config) any { return cfg.CloseQuits },
+		set:   boolSetter("close-quits", func(cfg *config.Config) *bool { return &cfg.CloseQuits }),
+		unset: func(cfg *config.Config) { cfg.CloseQuits = false },
+	},
+	{
+		name:  "show-on-status-change",
+		help:  "Bring up the GUI window when filtering turns on, off or pauses (true or false)",
+		json:  "showOnStatusChange",
+		get:   func(cfg *config.Config) any { return cfg.ShowOnStatusChange },
+		set:   boolSetter("show-on-status-change", func(cfg *config.Config) *bool { return &cfg.ShowOnStatusChange }),
+		unset: func(cfg *config.Config) { cfg.ShowOnStatusChange = false },
+	},
+	{
+		name:  "interfaces",
+		help:  "Comma-separated interface patterns to filter (empty = all)",
+		json:  "managedInterfaces",
+		get:   func(cfg *config.Config) any { return cfg.ManagedInterfaces },
+		set:   func(cfg *config.Config, v string) error { cfg.ManagedInterfaces = splitList(v); return nil },
+		unset: func(cfg *config.Config) { cfg.ManagedInterfaces = nil },
+	},
+	{
+		name:  "ignore-interfaces",
+		help:  "Comma-separated interface patterns left untouched",
+		json:  "ignoredInterfaces",
+		get:   func(cfg *config.Config) any { return cfg.IgnoredInterfaces },
+		set:   func(cfg *config.Config, v string) error { cfg.IgnoredInterfaces = splitList(v); return nil },
+		unset: func(cfg *config.Config) { cfg.IgnoredInterfaces = nil },
+	},
+	{
+		name:  "verify-every",
+		help:  "Re-check 1 in N answers over a pinned connection (0 = off)",
+		json:  "verifyEvery",
+		get:   func(cfg *config.Config) any { return cfg.VerifyEvery },
+		set:   intSetter("verify-every", func(cfg *config.Config) *int { return &cfg.VerifyEvery }),
+		unset: func(cfg *config.Config) { cfg.VerifyEvery = 0 },
+	},
+	{
+		name:  "search-shortcut",
+		help:  "Answer search-domain expansions of known names locally (true or false)",
+		json:  "searchShortcut",
+		get:   func(cfg *config.Config) any { return cfg.SearchShortcut },
+		set:   boolSetter("search-shortcut", func(cfg *config.Config) *bool { return &cfg.SearchShortcut }),
+		unset: func(cfg *config.Config) { cfg.SearchShortcut = false },
+	},
+	{
+		name:  "ipv4-only",
+		help:  "Listen on 127.0.0.1 only, not also on ::1 (true or false)",
+		json:  "ipv4Only",
+		get:   func(cfg *config.Config) any { return cfg.IPv4Only },
+		set:   boolSetter("ipv4-only", func(cfg *config.Config) *bool { return &cfg.IPv4Only }),
+		unset: func(cfg *config.Config) { cfg.IPv4Only = false },
+	},
+	{
+		name:  "block-outside-dns",
+		help:  "Block DNS that bypasses the proxy with firewall rules while filtering (true or false)",
+		json:  "blockOutsideDns",
+		get:   func(cfg *config.Config) any { return cfg.BlockOutsideDNS },
+		set:   boolSetter("block-outside-dns", func(cfg *config.Config) *bool { return &cfg.BlockOutsideDNS }),
+		unset: func(cfg *config.Config) { cfg.BlockOutsideDNS = false },
+	},
+	{
+		name:  "auto-vpn-forwarders",
+		help:  "Add forwarders for the domains of VPNs that come up (true or false)",
+		json:  "autoVpnForwarders",
+		get:   func(cfg *config.Config) any { return cfg.AutoVPNForwarders },
+		set:   boolSetter("auto-vpn-forwarders", func(cfg *config.Config) *bool { return &cfg.AutoVPNForwarders }),
+		unset: func(cfg *config.Config) { cfg.AutoVPNForwarders = false },
+	},
+	{
+		name:  "cache-size",
+		help:  "Answers kept in the cache (0 = default)",
+		json:  "cacheSize",
+		get:   func(cfg *config.Config) any { return cfg.CacheSize },
+		set:   intSetter("cache-size", func(cfg *config.Config) *int { return &cfg.CacheSize }),
+		unset: func(cfg *config.Config) { cfg.CacheSize = 0 },
+	},
+	{
+		name: "bootstrap-dns",
+		help: "Comma-separated resolver IPs for the server's hostname (empty = built-in)",
+		json: "bootstrapDns",
+		get:  func(cfg *config.Config) any { return cfg.BootstrapDNS },
+		set: func(cfg *config.Config, v string) error {
+			servers := splitList(v)
+			for _, server := range servers {
+				host := server
+				if h, _, err := net.SplitHostPort(server); err == nil {
+					host = h
+				}
+				if net.ParseIP(host) == nil {
+					return fmt.Errorf("bootstrap-dns entries must be IP addresses, got %q", server)
+				}
+			}
+			cfg.BootstrapDNS = servers
+			return nil
+		},
+		unset: func(cfg *config.Config) { cfg.BootstrapDNS = nil },
+	},
+	{
+		name:  "auto-update",
+		help:  "Let the daemon install signed releases by itself (true or false)",
+		json:  "autoUpdate",
+		get:   func(cfg *config.Config) any { return cfg.AutoUpdate },
+		set:   boolSetter("auto-update", func(cfg *config.Config) *bool { return &cfg.AutoUpdate }),
+		unset: func(cfg *config.Config) { cfg.AutoUpdate = false },
+	},
+	{
+		name: "update-url",
+		help: "Release manifest URL (empty = built-in)",
+		json: "updateUrl",
+		get:  func(cfg *config.Config) any { return cfg.UpdateURL },
+		set: func(cfg *config.Config, v string) error {
+			u, err := url.Parse(v)
+			if err != nil || u.Scheme != "https" || u.Host == "" {
+				return fmt.Errorf("update-url must be an https:// URL")
+			}
+			cfg.UpdateURL = v
+			return nil
+		},
+		unset: func(cfg *config.Config) { cfg.UpdateURL = "" },
+	},
+	{
+		name: "control-addr",
+		help: "Address for GUIs on other machines to manage the daemon, e.g. :5380 (empty = off; restart the daemon)",
+		json: "controlAddr",
+		get:  func(cfg *config.Config) any { return cfg.ControlAddr },
+		set: func(cfg *config.Config, v string) error {
+			if _, _, err := net.SplitHostPort(v); err != nil {
+				return fmt.Errorf("control-addr must be host:port or :port, got %q", v)
+			}
+			cfg.ControlAddr = v
+			return nil
+		},
+		unset: func(cfg *config.Config) { cfg.ControlAddr = "" },
+	},
+	{
+		name:  "owner",
+		help:  "Local user the daemon is installed for, who may read its query history (empty = root only)",
+		json:  "owner",
+		get:   func(cfg *config.Config) any { return cfg.Owner },
+		set:   func(cfg *config.Config, v string) error { cfg.Owner = v; return nil },
+		unset: func(cfg *config.Config) { cfg.Owner = "" },
+	},
+	{
+		name: "heartbeat",
+		help: "Comma-separated details each sync reports to the server: " + strings.Join(config.HeartbeatFields, ", ") + " or all (empty = nothing)",
+		json: "heartbeat",
+		get:  func(cfg *config.Config) any { return cfg.Heartbeat },
+		set: func(cfg *config.Config, v string) error {
+			fields := splitList(v)
+			if slices.Equal(fields, []string{"all"}) {
+				fields = slices.Clone(config.HeartbeatFields)
+			}
+			if err := config.ValidateHeartbeat(fields); err != nil {
+				return err
+			}
+			cfg.Heartbeat = fields
+			return nil
+		},
+		unset: func(cfg *config.Config) { cfg.Heartbeat = nil },
+	},
+	{
+		name:  "server-commands",
+		help:  "Carry out commands admins send from the web UI: flush, pause, switch profile, update (true or false)",
+		json:  "serverCommands",
+		get:   func(cfg *config.Config) any { return cfg.ServerCommands },
+		set:   boolSetter("server-commands", func(cfg *config.Config) *bool { return &cfg.ServerCommands }),
+		unset: func(cfg *config.Config) { cfg.ServerCommands = false },
+	},
+	{
+		name: "credential-store",
+		help: "Where profile passwords are kept: " + strings.Join(config.CredentialStores, ", ") + " (empty = the keychain, or an encrypted file without one)",
+		json: "credentialStore",
+		get:  func(cfg *config.Config) any { return cfg.CredentialStore },
+		set: func(cfg *config.Config, v string) error {
+			if !slices.Contains(config.CredentialStores, v) {
+				return fmt.Errorf("credential-store must be one of %s", strings.Join(config.CredentialStores, ", "))
+			}
+			cfg.CredentialStore = v
+			return nil
+		},
+		unset: func(cfg *config.Config) { cfg.CredentialStore = "" },
+	},
+	{
+		name:  "stats-minute-hours",
+		help:  "Hours of per-minute statistics to keep (0 = default)",
+		json:  "statsRetention",
+		get:   func(cfg *config.Config) any { return retentionOf(cfg).MinuteHours },
+		set:   intSetter("stats-minute-hours", func(cfg *config.Config) *int { return &retention(cfg).MinuteHours }),
+		unset: func(cfg *config.Config) { clearRetention(cfg, func(r *config.StatsRetention) { r.MinuteHours = 0 }) },
+	},
+	{
+		name:  "stats-hourly-days",
+		help:  "Days of per-hour statistics to keep (0 = default)",
+		json:  "statsRetention",
+		get:   func(cfg *config.Config) any { return retentionOf(cfg).HourlyDays },
+		set:   intSetter("stats-hourly-days", func(cfg *config.Config) *int { return &retention(cfg).HourlyDays }),
+		unset: func(cfg *config.Config) { clearRetention(cfg, func(r *config.StatsRetention) { r.HourlyDays = 0 }) },
+	},
+	{
+		name:  "stats-daily-days",
+		help:  "Days of per-day statistics to keep (0 = default)",
+		json:  "statsRetention",
+		get:   func(cfg *config.Config) any { return retentionOf(cfg).DailyDays },
+		set:   intSetter("stats-daily-days", func(cfg *config.Config) *int { return &retention(cfg).DailyDays }),
+		unset: func(cfg *config.Config) { clearRetention(cfg, func(r *config.StatsRetention) { r.DailyDays = 0 }) },
+	},
+}
+
+// findConfigKey looks up a key by name
+func findConfigKey(name string) (*configKey, error) {
+	for i := range configKeys {
+		if configKeys[i].name == name {
+			return &configKeys[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown config key: %s (one of %s)", name, strings.Join(configKeyNames(), ", "))
+}
+
+// configKeyNames returns all key names, including the password
+func configKeyNames() []string {
+	names := make([]string, 0, len(configKeys)+1)
+	for _, key := range configKeys {
+		names = append(names, key.name)
+	}
+	return append(names, passwordKey)
+}
+
+// formatConfigValue formats a key's value for display
+func formatConfigValue(value any) string {
+	if list, ok := value.([]string); ok {
+		return strings.Join(list, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// showConfigValue formats a key's value for printing, with credentials
+// in URLs redacted
+func showConfigValue(value any) string {
+	return config.Redact(formatConfigValue(value))
+}
+
+// boolSetter parses true or false into the field returned by field
+func boolSetter(name string, field func(cfg *config.Config) *bool) func(*config.Config, string) error {
+	return func(cfg *config.Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false", name)
+		}
+		*field(cfg) = b
+		return nil
+	}
+}
+
+// intSetter parses a number >= 0 into the field returned by field
+func intSetter(name string, field func(cfg *config.Config) *int) func(*config.Config, string) error {
+	return func(cfg *config.Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("%s must be a number >= 0", name)
+		}
+		*field(cfg) = n
+		return nil
+	}
+}
+
+// retentionOf returns cfg's statistics retention, zero if unset
+func retentionOf(cfg *config.Config) config.StatsRetention {
+	if cfg.StatsRetention == nil {
+		return config.StatsRetention{}
+	}
+	return *cfg.StatsRetention
+}
+
+// retention returns cfg's statistics retention, creating it if unset
+func retention(cfg *config.Config) *config.StatsRetention {
+	if cfg.StatsRetention == nil {
+		cfg.StatsRetention = &config.StatsRetention{}
+	}
+	return cfg.StatsRetention
+}
+
+// clearRetention applies reset to the statistics retention and drops it
+// altogether once nothing is set
+func clearRetention(cfg *config.Config, reset func(r *config.StatsRetention)) {
+	if cfg.StatsRetention == nil {
+		return
+	}
+	reset(cfg.StatsRetention)
+	if *cfg.StatsRetention == (config.StatsRetention{}) {
+		cfg.StatsRetention = nil
+	}
+}
+
+// configProblems formats an error from validating or saving the config,
+// putting each problem Validate found on a line of its own
+func configProblems(err error) string {
+	var v config.ValidationError
+	if !errors.As(err, &v) {
+		return err.Error()
+	}
+	lines := make([]string, len(v))
+	for i, e := range v {
+		lines[i] = "\n  " + e.Error()
+	}
+	return strings.Join(lines, "")
+}
+
+// networkSetting turns key=value, with a key as "config set" takes it,
+// into the setting's name and value in config.json for a network overlay
+func networkSetting(setting string) (string, json.RawMessage, error) {
+	name, value, ok := strings.Cut(setting, "=")
+	if !ok {
+		return "", nil, fmt.Errorf("--setting takes key=value, got %q", setting)
+	}
+	key, err := findConfigKey(name)
+	if err != nil {
+		return "", nil, err
+	}
+	cfg := config.Default()
+	if err := key.set(cfg, value); err != nil {
+		return "", nil, fmt.Errorf("%s: %w", key.name, err)
+	}
+	// The whole field, as omitempty would leave out a value set to zero
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ","); tag == key.json {
+			data, err := json.Marshal(v.Field(i).Interface())
+			return key.json, data, err
+		}
+	}
+	return "", nil, fmt.Errorf("%s cannot be set per network", key.name)
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+)
+
+// envPrefix starts the environment variables overriding config keys
+const envPrefix = "FILTERDNS_"
+
+// overrideHelp explains the overrides in "config set --help"
+const overrideHelp = `Any key can also be overridden without saving it, by an environment
+variable named after it (FILTERDNS_CACHE_SIZE for cache-size,
+FILTERDNS_SERVER_URL for server) or by --set key=value, which wins over
+the environment. An empty value stands for the default.`
+
+// overrideSources maps each overridden key to where its value comes
+// from, for "config get" and the notes of "config set"
+var overrideSources = map[string]string{}
+
+// envName is the environment variable overriding the key, e.g.
+// FILTERDNS_CACHE_SIZE for cache-size
+func (k *configKey) envName() string {
+	if k.env != "" {
+		return k.env
+	}
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(k.name, "-", "_"))
+}
+
+// configOverrides collects the overrides from the environment and from
+// --set key=value flags, which win over the environment. An empty value
+// resets the key to its default.
+func configOverrides(sets []string) ([]config.Override, error) {
+	var overrides []config.Override
+	add := func(key *configKey, source, value string) error {
+		apply := func(cfg *config.Config) error {
+			if value == "" {
+				key.unset(cfg)
+				return nil
+			}
+			return key.set(cfg, value)
+		}
+		if err := apply(config.Default()); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+
+		overrides = append(overrides, config.Override{
+			Source: source,
+			Apply:  apply,
+			Applied: func(cfg *config.Config) bool {
+				want := config.Default()
+				apply(want)
+				return formatConfigValue(key.get(cfg)) == formatConfigValue(key.get(want))
+			},
+			Restore: func(cfg, saved *config.Config) { restoreConfigKey(key, cfg, saved) },
+		})
+		overrideSources[key.name] = source
+		return nil
+	}
+
+	for i := range configKeys {
+		key := &configKeys[i]
+		if value, ok := os.LookupEnv(key.envName()); ok {
+			if err := add(key, key.envName(), value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, set := range sets {
+		name, value, ok := strings.Cut(set, "=")
+		if !ok {
+			return nil, fmt.Errorf("--set takes key=value, got %q", set)
+		}
+		key, err := findConfigKey(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(key, "--set "+name, value); err != nil {
+			return nil, err
+		}
+	}
+	return overrides, nil
+}
+
+// restoreConfigKey copies the key's value from saved into cfg
+func restoreConfigKey(key *configKey, cfg, saved *config.Config) {
+	key.unset(cfg)
+	value := formatConfigValue(key.get(saved))
+	if value != "" && value != formatConfigValue(key.get(cfg)) {
+		key.set(cfg, value)
+	}
+}
+
+// warnOverridden notes that a key just saved does not take effect while
+// something overrides it
+func warnOverridden(name string) {
+	if source := overrideSources[name]; source != "" {
+		fmt.Fprintln(os.Stderr, i18n.T("Note: %s overrides %s until it is removed.", source, name))
+	}
+}
+
+// keyLocked reports whether the administrator's policy locks the key, in
+// which case overrides do not apply to it and it cannot be changed
+func keyLocked(name string) bool {
+	key, err := findConfigKey(name)
+	return err == nil && config.CurrentPolicy().Locked(key.json)
+}
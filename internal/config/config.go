@@ -2,18 +2,34 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
-
-	"github.com/zalando/go-keyring"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
 )
 
 const (
 	appName     = "FilterDNS"
 	configFile  = "config.json"
+	stateFile   = "state.json"
 	keyringName = "filterdns-client"
+
+	credentialsFile    = "credentials.enc"
+	credentialsKeyFile = "credentials.key"
+
+	// ConfigEnv selects a declaratively managed config file, like --config
+	ConfigEnv = "FILTERDNS_CONFIG"
 )
 
+// ErrReadOnly is returned by Save when a change would have to be written
+// to a declaratively managed config file
+var ErrReadOnly = errors.New("config is managed declaratively")
+
 // Build-time variables (set via -ldflags)
 var (
 	// DefaultServerURL is the default FilterDNS server URL.
@@ -29,13 +45,198 @@ type Forwarder struct {
 	Server string `json:"server"` // e.g., "100.100.100.100", "192.168.1.1:53"
 }
 
+// Rule actions
+const (
+	RuleAllow = "allow" // Resolve even if the profile blocks it
+	RuleBlock = "block" // Answer as blocked without asking the server
+)
+
+// Rule is a local allow or block override for a domain and its subdomains
+type Rule struct {
+	Domain string `json:"domain"`           // e.g. "example.com", "*.ads.example.com"
+	Action string `json:"action"`           // RuleAllow or RuleBlock
+	Server bool   `json:"server,omitempty"` // Also on the server profile, for all its devices
+}
+
+// SavedProfile is a named server/profile pair the user can switch to.
+// Its password is stored in the keychain under the profile name.
+type SavedProfile struct {
+	Name      string `json:"name"`      // Display name, e.g. "work", "home"
+	Profile   string `json:"profile"`   // FilterDNS profile name
+	ServerURL string `json:"serverUrl"` // FilterDNS server URL
+}
+
+// StatsRetention limits how long query statistics are kept at each
+// resolution. Zero fields use the defaults.
+type StatsRetention struct {
+	MinuteHours int `json:"minuteHours,omitempty"` // Per-minute counts, in hours (default 24)
+	HourlyDays  int `json:"hourlyDays,omitempty"`  // Per-hour counts, in days (default 30)
+	DailyDays   int `json:"dailyDays,omitempty"`   // Per-day counts, in days (default 365)
+}
+
+// ServerCapabilities records which client API features a FilterDNS
+// server supports, as discovered on first contact
+type ServerCapabilities struct {
+	ServerURL     string    `json:"serverUrl"`               // Server these capabilities were probed from
+	ServerVersion string    `json:"serverVersion,omitempty"` // Version reported by the server, if any
+	Sync          bool      `json:"sync"`                    // /api/client/sync/<profile>
+	Onboarding    bool      `json:"onboarding"`              // /api/client/onboard/*
+	EDE           bool      `json:"ede"`                     // Blocked answers carry Extended DNS Errors
+	Push          bool      `json:"push"`                    // Server can push state changes
+	Rules         bool      `json:"rules,omitempty"`         // /api/client/rules/<profile>
+	Resume        bool      `json:"resume,omitempty"`        // /api/client/resume/<profile>
+	LeakTest      bool      `json:"leakTest,omitempty"`      // /api/client/leaktest/*
+	CheckedAt     time.Time `json:"checkedAt"`
+}
+
 // Config holds the application configuration
 type Config struct {
-	Profile    string      `json:"profile"`    // FilterDNS profile name
-	ServerURL  string      `json:"serverUrl"`  // FilterDNS server URL
-	Enabled    bool        `json:"enabled"`    // Whether filtering is enabled
-	Autostart  bool        `json:"autostart"`  // Start on system boot
-	Forwarders []Forwarder `json:"forwarders"` // Split DNS forwarders
+	Profile     string      `json:"profile"`               // FilterDNS profile name
+	ServerURL   string      `json:"serverUrl"`             // FilterDNS server URL
+	Enabled     bool        `json:"enabled"`               // Whether filtering is enabled
+	PausedUntil *time.Time  `json:"pausedUntil,omitempty"` // Filtering paused locally until this time
+	Autostart   bool        `json:"autostart"`             // Start on system boot
+	Forwarders  []Forwarder `json:"forwarders"`            // Split DNS forwarders
+	Rules       []Rule      `json:"rules,omitempty"`       // Local allow/block overrides
+
+	Profiles      []SavedProfile `json:"profiles,omitempty"`      // Profiles available for switching
+	ActiveProfile string         `json:"activeProfile,omitempty"` // Name of the saved profile in use
+
+	ManagedInterfaces []string `json:"managedInterfaces,omitempty"` // Interface patterns to filter, e.g. "wl*", "en*" (empty = all)
+	IgnoredInterfaces []string `json:"ignoredInterfaces,omitempty"` // Interface patterns left untouched, e.g. "tun*", "wg*"
+
+	Capabilities *ServerCapabilities `json:"capabilities,omitempty"` // Features supported by ServerURL, once probed
+
+	ServerSettings *ManagedSettings `json:"serverSettings,omitempty"` // Client settings from the server profile, as last synced
+	Endpoint       *DNSEndpoint     `json:"endpoint,omitempty"`       // DNS endpoint the server reported, as last synced
+
+	VerifyEvery int `json:"verifyEvery,omitempty"` // Re-check 1 in N answers over a pinned connection (0 = off)
+
+	SearchShortcut bool `json:"searchShortcut,omitempty"` // Answer search-domain expansions of known names locally
+
+	IPv4Only bool `json:"ipv4Only,omitempty"` // Listen on 127.0.0.1 only, not also on ::1
+
+	BlockOutsideDNS bool `json:"blockOutsideDns,omitempty"` // Firewall DNS that bypasses the proxy while filtering
+
+	AutoVPNForwarders bool `json:"autoVpnForwarders,omitempty"` // Add forwarders for the domains of VPNs that come up
+
+	StatsRetention *StatsRetention `json:"statsRetention,omitempty"` // How long statistics history is kept
+
+	CacheSize    int      `json:"cacheSize,omitempty"`    // Answers kept in the proxy's cache (0 = default)
+	BootstrapDNS []string `json:"bootstrapDns,omitempty"` // Resolvers for the server's own hostname, e.g. "1.1.1.1" (empty = built-in)
+
+	AutoUpdate bool   `json:"autoUpdate,omitempty"` // Daemon installs signed releases by itself
+	UpdateURL  string `json:"updateUrl,omitempty"`  // Release manifest to check (empty = built-in)
+
+	ControlAddr string `json:"controlAddr,omitempty"` // TLS control listener for GUIs on other machines, e.g. ":5380" (empty = off)
+
+	Owner string `json:"owner,omitempty"` // Local user the daemon is installed for, who may read its history (empty = root only)
+
+	Heartbeat      []string `json:"heartbeat,omitempty"`      // What syncs report to the server, see HeartbeatFields (empty = nothing)
+	ServerCommands bool     `json:"serverCommands,omitempty"` // Carry out commands admins send from the web UI
+
+	Language string `json:"language,omitempty"` // GUI and CLI language, e.g. "de" (empty = the system's)
+
+	StartMinimized     bool `json:"startMinimized,omitempty"`     // GUI starts in the tray, without its window
+	CloseQuits         bool `json:"closeQuits,omitempty"`         // Closing the window quits the GUI instead of hiding it
+	ShowOnStatusChange bool `json:"showOnStatusChange,omitempty"` // Bring the window up when filtering turns on, off or pauses
+
+	CredentialStore string `json:"credentialStore,omitempty"` // Where passwords are kept: CredentialsKeychain or CredentialsFile (empty = automatic)
+
+	Networks []NetworkOverlay `json:"networks,omitempty"` // Settings that change on particular Wi-Fi networks
+	Schedule []ScheduleEntry  `json:"schedule,omitempty"` // Filtering changes at set times of the week
+}
+
+// CapabilitiesFor returns the recorded capabilities if they were probed
+// from the given server, or nil if the server has not been probed yet
+func (c *Config) CapabilitiesFor(serverURL string) *ServerCapabilities {
+	if c.Capabilities == nil || c.Capabilities.ServerURL != serverURL {
+		return nil
+	}
+	return c.Capabilities
+}
+
+// ManagesInterface reports whether system DNS on the named interface (or
+// network service on macOS) should point at the proxy. Patterns use
+// shell glob syntax; ignored patterns take precedence over managed ones.
+func (c *Config) ManagesInterface(name string) bool {
+	for _, pattern := range c.IgnoredInterfaces {
+		if matchInterface(pattern, name) {
+			return false
+		}
+	}
+	if len(c.ManagedInterfaces) == 0 {
+		return true
+	}
+	for _, pattern := range c.ManagedInterfaces {
+		if matchInterface(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchInterface matches an interface name against a glob pattern,
+// ignoring case since macOS service names are user-visible labels
+func matchInterface(pattern, name string) bool {
+	matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return err == nil && matched
+}
+
+// InterfacePolicyEqual reports whether two configs select the same interfaces
+func (c *Config) InterfacePolicyEqual(other *Config) bool {
+	return slices.Equal(c.ManagedInterfaces, other.ManagedInterfaces) &&
+		slices.Equal(c.IgnoredInterfaces, other.IgnoredInterfaces)
+}
+
+// LoopbackAddrs returns the addresses the proxy listens on and system DNS
+// points at, the required IPv4 loopback first. Some resolvers on Windows
+// and macOS try ::1 first and time out if nothing answers there.
+func (c *Config) LoopbackAddrs() []string {
+	if c.IPv4Only {
+		return []string{"127.0.0.1"}
+	}
+	return []string{"127.0.0.1", "::1"}
+}
+
+// FindProfile returns the saved profile with the given name, or nil
+func (c *Config) FindProfile(name string) *SavedProfile {
+	for i := range c.Profiles {
+		if c.Profiles[i].Name == name {
+			return &c.Profiles[i]
+		}
+	}
+	return nil
+}
+
+// NormalizeRuleDomain lowercases a rule's domain and drops the root dot
+func NormalizeRuleDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+}
+
+// SetRule adds a rule, replacing any existing one for its domain
+func (c *Config) SetRule(rule Rule) {
+	for i := range c.Rules {
+		if c.Rules[i].Domain == rule.Domain {
+			c.Rules[i] = rule
+			return
+		}
+	}
+	c.Rules = append(c.Rules, rule)
+}
+
+// RemoveRule removes the rule for domain and reports whether there was one
+func (c *Config) RemoveRule(domain string) bool {
+	n := len(c.Rules)
+	c.Rules = slices.DeleteFunc(c.Rules, func(r Rule) bool { return r.Domain == domain })
+	return len(c.Rules) != n
+}
+
+// UseProfile makes a saved profile the active one
+func (c *Config) UseProfile(saved *SavedProfile) {
+	c.Profile = saved.Profile
+	c.ServerURL = saved.ServerURL
+	c.ActiveProfile = saved.Name
 }
 
 // Default returns the default configuration
@@ -49,13 +250,77 @@ func Default() *Config {
 	}
 }
 
+// dirOverride replaces the per-user config directory when set
+var dirOverride string
+
+// SetDir makes Load and Save use dir instead of the user's config
+// directory. The daemon uses this when it runs as an unprivileged user.
+func SetDir(dir string) {
+	dirOverride = dir
+}
+
+// DirFor returns the config directory of another user, given their home
+// directory, for use with SetDir
+func DirFor(home string) string {
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", appName)
+	case "windows":
+		return filepath.Join(home, "AppData", "Roaming", appName)
+	default:
+		return filepath.Join(home, ".config", appName)
+	}
+}
+
+// pureConfig is the declaratively managed config file set by SetPureConfig
+var pureConfig string
+
+// SetPureConfig switches to pure-config mode: the configuration is read
+// from path and never written back. Runtime state (enabled, pause,
+// probed server capabilities) goes to a separate state file in the config
+// directory instead, so path can live in a read-only location such as the
+// Nix store.
+func SetPureConfig(path string) {
+	pureConfig = path
+}
+
+// PureConfig returns the declaratively managed config file in use, from
+// SetPureConfig or the FILTERDNS_CONFIG environment variable, or "" if
+// the config is managed by the client itself
+func PureConfig() string {
+	if pureConfig != "" {
+		return pureConfig
+	}
+	return os.Getenv(ConfigEnv)
+}
+
+// state is the part of the configuration that changes at runtime; in
+// pure-config mode it is stored separately from the declared config
+type state struct {
+	Enabled      bool                `json:"enabled"`
+	PausedUntil  *time.Time          `json:"pausedUntil,omitempty"`
+	Capabilities *ServerCapabilities `json:"capabilities,omitempty"`
+
+	ServerSettings *ManagedSettings `json:"serverSettings,omitempty"`
+	Endpoint       *DNSEndpoint     `json:"endpoint,omitempty"`
+}
+
 // configDir returns the configuration directory path
 func configDir() (string, error) {
-	configDir, err := os.UserConfigDir()
-	if err != nil {
-		return "", err
+	if dirOverride != "" {
+		if err := os.MkdirAll(dirOverride, 0755); err != nil {
+			return "", err
+		}
+		return dirOverride, nil
+	}
+
+	dir := SystemDir()
+	if !systemScope {
+		var err error
+		if dir, err = UserDir(); err != nil {
+			return "", err
+		}
 	}
-	dir := filepath.Join(configDir, appName)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return "", err
 	}
@@ -71,21 +336,101 @@ func configPath() (string, error) {
 	return filepath.Join(dir, configFile), nil
 }
 
-// Load reads the configuration from disk
+// statePath returns the full path to the pure-config mode state file
+func statePath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, stateFile), nil
+}
+
+// Path returns the full path to the file Save writes to: the config file,
+// the machine config if Load lays the user's preferences over it, or the
+// state file in pure-config mode
+func Path() (string, error) {
+	if PureConfig() != "" {
+		return statePath()
+	}
+	if machine := machinePath(); machine != "" {
+		return machine, nil
+	}
+	return configPath()
+}
+
+// Load reads the configuration from disk and applies the overrides set
+// with SetOverrides
 func Load() (*Config, error) {
+	cfg, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if err := ApplyOverrides(cfg); err != nil {
+		return nil, err
+	}
+	useCredentialStore(cfg.CredentialStore)
+	return cfg, nil
+}
+
+// load reads the configuration as saved
+func load() (*Config, error) {
+	if pure := PureConfig(); pure != "" {
+		return loadPure(pure)
+	}
+
 	path, err := configPath()
 	if err != nil {
 		return nil, err
 	}
+	if machine := machinePath(); machine != "" {
+		return loadMachine(machine, path)
+	}
+
+	cfg, err := readConfig(path)
+	if os.IsNotExist(err) {
+		return Default(), nil
+	}
+	return cfg, err
+}
 
+// loadPure reads the declared config and overlays the saved state. Unlike
+// the regular config, a missing declared file is an error.
+func loadPure(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return Default(), nil
-		}
 		return nil, err
 	}
+	cfg, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
 
+	statePath, err := statePath()
+	if err != nil {
+		return nil, err
+	}
+	var st state
+	err = readFile(statePath, func(data []byte) error {
+		st = state{}
+		return json.Unmarshal(data, &st)
+	})
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cfg.Enabled = st.Enabled
+	cfg.PausedUntil = st.PausedUntil
+	cfg.Capabilities = st.Capabilities
+	cfg.ServerSettings = st.ServerSettings
+	cfg.Endpoint = st.Endpoint
+
+	return cfg, nil
+}
+
+// parse decodes a config file and fills in defaults
+func parse(data []byte) (*Config, error) {
 	cfg := &Config{}
 	if err := json.Unmarshal(data, cfg); err != nil {
 		return nil, err
@@ -102,40 +447,138 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
-// Save writes the configuration to disk
+// Save writes the configuration to disk. In pure-config mode only the
+// runtime state is written, and any other change fails with ErrReadOnly.
+// Once there is a machine config, only the user's preferences go to
+// their own config; the rest goes to the machine config. A change to a
+// setting the administrator's policy locks fails with ErrLocked, and
+// invalid settings with a ValidationError.
 func Save(cfg *Config) error {
+	if err := CurrentPolicy().check(cfg); err != nil {
+		return err
+	}
+	if err := save(cfg); err != nil {
+		return err
+	}
+	useCredentialStore(cfg.CredentialStore)
+	return nil
+}
+
+// save writes the configuration without the overrides
+func save(cfg *Config) error {
+	cfg, err := withoutOverrides(cfg)
+	if err != nil {
+		return err
+	}
+
+	if pure := PureConfig(); pure != "" {
+		return savePure(pure, cfg)
+	}
+
+	if err := validateChanges(cfg); err != nil {
+		return err
+	}
+
 	path, err := configPath()
 	if err != nil {
 		return err
 	}
+	if machine := machinePath(); machine != "" {
+		return saveMachine(machine, path, cfg)
+	}
 
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0644)
+	return writeFile(path, data)
+}
+
+// validateChanges runs Validate on cfg unless only its runtime state
+// differs from the saved config, so turning filtering on and off is not
+// held up by a problem that was in the file already
+func validateChanges(cfg *Config) error {
+	if saved, err := load(); err == nil && SettingsEqual(cfg, saved) {
+		return nil
+	}
+	return Validate(cfg)
 }
 
-// SetPassword stores the password securely in the OS keychain
-func SetPassword(profile, password string) error {
-	return keyring.Set(keyringName, profile, password)
+// savePure writes the runtime state of cfg to the state file, after
+// checking that the rest of cfg still matches the declared config
+func savePure(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	declared, err := parse(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if !SettingsEqual(cfg, declared) {
+		return fmt.Errorf("%w: edit %s instead", ErrReadOnly, path)
+	}
+
+	statePath, err := statePath()
+	if err != nil {
+		return err
+	}
+	data, err = json.MarshalIndent(state{
+		Enabled:      cfg.Enabled,
+		PausedUntil:  cfg.PausedUntil,
+		Capabilities: cfg.Capabilities,
+
+		ServerSettings: cfg.ServerSettings,
+		Endpoint:       cfg.Endpoint,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFile(statePath, data)
 }
 
-// GetPassword retrieves the password from the OS keychain
-func GetPassword(profile string) (string, error) {
-	password, err := keyring.Get(keyringName, profile)
-	if err == keyring.ErrNotFound {
-		return "", nil
+// SettingsEqual reports whether two configs are the same apart from
+// their runtime state
+func SettingsEqual(a, b *Config) bool {
+	strip := func(cfg *Config) []byte {
+		c := *cfg
+		c.Enabled = false
+		c.PausedUntil = nil
+		c.Capabilities = nil
+		c.ServerSettings = nil
+		c.Endpoint = nil
+		if c.Forwarders == nil {
+			c.Forwarders = []Forwarder{}
+		}
+		data, _ := json.Marshal(&c)
+		return data
 	}
-	return password, err
+	return string(strip(a)) == string(strip(b))
 }
 
-// DeletePassword removes the password from the OS keychain
-func DeletePassword(profile string) error {
-	err := keyring.Delete(keyringName, profile)
-	if err == keyring.ErrNotFound {
-		return nil
+// Purge deletes everything the client keeps for the user: the keychain
+// entries of the profiles the config names, and the config directory with
+// the config, state and statistics in it. A pure config file lives
+// elsewhere and is left alone.
+func Purge() error {
+	var errs []error
+	if cfg, err := Load(); err == nil {
+		for _, profile := range cfg.profileNames() {
+			if err := DeletePassword(profile); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove password for %s: %w", profile, err))
+			}
+		}
+	}
+
+	dir, err := configDir()
+	if err == nil {
+		err = os.RemoveAll(dir)
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to remove config directory: %w", err))
 	}
-	return err
+	return errors.Join(errs...)
 }
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+)
+
+// Credential stores for Config.CredentialStore. Left empty, passwords go
+// to the OS keychain, or to the encrypted file where there is none, e.g.
+// on a headless Linux server without a Secret Service.
+const (
+	CredentialsKeychain = "keychain"
+	CredentialsFile     = "file"
+)
+
+// CredentialStores lists the values Config.CredentialStore takes
+var CredentialStores = []string{CredentialsKeychain, CredentialsFile}
+
+// machineIDFiles hold an ID unique to this installation, mixed into the
+// key of the credentials file so a copy of it is useless elsewhere
+var machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+var (
+	credentialStoreMu  sync.Mutex
+	credentialStoreSet string // Config.CredentialStore as last loaded or saved
+)
+
+// useCredentialStore records the store chosen in a config just loaded or
+// saved, so looking up a password does not read the config again
+func useCredentialStore(store string) {
+	credentialStoreMu.Lock()
+	defer credentialStoreMu.Unlock()
+	credentialStoreSet = store
+}
+
+// credentialStore returns the store chosen in the config, or "" to pick
+// one automatically, also before any config was loaded
+func credentialStore() string {
+	credentialStoreMu.Lock()
+	defer credentialStoreMu.Unlock()
+	return credentialStoreSet
+}
+
+// SetPassword stores the password securely in the OS keychain, or in the
+// encrypted credentials file if there is no keychain
+func SetPassword(profile, password string) error {
+	RegisterSecret(password, SecretRef(profile))
+	switch credentialStore() {
+	case CredentialsFile:
+		return setFilePassword(profile, password)
+	case CredentialsKeychain:
+		return keyring.Set(keyringName, profile, password)
+	}
+
+	err := keyring.Set(keyringName, profile, password)
+	if err == nil {
+		// Do not leave an older copy behind in the file
+		deleteFilePassword(profile)
+		return nil
+	}
+	log.Printf("No keychain (%v), keeping the password for %s in the credentials file", err, profile)
+	return setFilePassword(profile, password)
+}
+
+// GetPassword retrieves the password from the OS keychain or the
+// credentials file, or "" if neither has one. From then on Redact shows
+// it as its SecretRef.
+func GetPassword(profile string) (string, error) {
+	store := credentialStore()
+	if store != CredentialsFile {
+		password, err := keyring.Get(keyringName, profile)
+		switch {
+		case err == nil:
+			RegisterSecret(password, SecretRef(profile))
+			return password, nil
+		case store == CredentialsKeychain && err == keyring.ErrNotFound:
+			return "", nil
+		case store == CredentialsKeychain:
+			return "", err
+		}
+	}
+	return getFilePassword(profile)
+}
+
+// DeletePassword removes the password from the OS keychain and the
+// credentials file
+func DeletePassword(profile string) error {
+	store := credentialStore()
+	if store != CredentialsFile {
+		err := keyring.Delete(keyringName, profile)
+		if err != nil && err != keyring.ErrNotFound && store == CredentialsKeychain {
+			return err
+		}
+	}
+	if store != CredentialsKeychain {
+		return deleteFilePassword(profile)
+	}
+	return nil
+}
+
+// getFilePassword reads a password from the credentials file
+func getFilePassword(profile string) (string, error) {
+	passwords, err := readCredentials()
+	if err != nil {
+		return "", err
+	}
+	RegisterSecret(passwords[profile], SecretRef(profile))
+	return passwords[profile], nil
+}
+
+// setFilePassword stores a password in the credentials file
+func setFilePassword(profile, password string) error {
+	passwords, err := readCredentials()
+	if err != nil {
+		return err
+	}
+	passwords[profile] = password
+	return writeCredentials(passwords)
+}
+
+// deleteFilePassword removes a password from the credentials file
+func deleteFilePassword(profile string) error {
+	passwords, err := readCredentials()
+	if err != nil {
+		return err
+	}
+	if _, ok := passwords[profile]; !ok {
+		return nil
+	}
+	delete(passwords, profile)
+	return writeCredentials(passwords)
+}
+
+// readCredentials decrypts the credentials file, which holds passwords by
+// profile. A missing file holds none.
+func readCredentials() (map[string]string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, credentialsFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := credentialsCipher(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("%s is damaged", path)
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s cannot be decrypted on this machine: %w", path, err)
+	}
+
+	passwords := map[string]string{}
+	if err := json.Unmarshal(plain, &passwords); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return passwords, nil
+}
+
+// writeCredentials encrypts passwords into the credentials file, which
+// only its owner can read
+func writeCredentials(passwords map[string]string) error {
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+	aead, err := credentialsCipher(dir)
+	if err != nil {
+		return err
+	}
+	plain, err := json.Marshal(passwords)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	return writeAtomic(filepath.Join(dir, credentialsFile), aead.Seal(nonce, nonce, plain, nil), 0600)
+}
+
+// credentialsCipher returns the AES-GCM cipher of the credentials file.
+// Its key derives from a random secret kept next to it, readable only by
+// its owner, and the machine ID where there is one.
+func credentialsCipher(dir string) (cipher.AEAD, error) {
+	keyPath := filepath.Join(dir, credentialsKeyFile)
+	secret, err := os.ReadFile(keyPath)
+	if os.IsNotExist(err) {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		if err := writeAtomic(keyPath, secret, 0600); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", keyPath, err)
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	if len(secret) < 32 {
+		return nil, errors.New(keyPath + " is damaged")
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(keyringName + " credentials\x00"))
+	hash.Write(secret)
+	for _, path := range machineIDFiles {
+		if id, err := os.ReadFile(path); err == nil {
+			hash.Write(bytes.TrimSpace(id))
+			break
+		}
+	}
+
+	block, err := aes.NewCipher(hash.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+)
+
+// DNSEndpoint is where the server says a profile's DNS is served, as
+// last synced. It differs from ServerURL once the profile has moved,
+// e.g. to another path or port during a migration.
+type DNSEndpoint struct {
+	ServerURL   string `json:"serverUrl"`             // Server it was synced from
+	Profile     string `json:"profile"`               // Profile it was synced for
+	DoHURL      string `json:"dohUrl,omitempty"`      // e.g. "https://dns2.example.com/dns-query?profile=kids"
+	DoTHostname string `json:"dotHostname,omitempty"` // Recorded only; the proxy speaks DoH
+}
+
+// DefaultDoHURL is the DoH URL of a profile on a server that has not
+// reported another one
+func DefaultDoHURL(serverURL, profile string) string {
+	u := serverURL + "/dns-query"
+	if profile != "" {
+		u += "?profile=" + profile
+	}
+	return u
+}
+
+// ServerEndpoint returns the endpoint synced for the current server and
+// profile, or nil if there is none
+func (c *Config) ServerEndpoint() *DNSEndpoint {
+	e := c.Endpoint
+	if e == nil || e.ServerURL != c.ServerURL || e.Profile != c.Profile {
+		return nil
+	}
+	return e
+}
+
+// DoHURL returns the URL the proxy sends queries to: the one the server
+// reported for the profile, or the default on ServerURL. A reported URL
+// is only followed on the host of an https ServerURL, since queries carry
+// the profile password and a sync must not send them elsewhere.
+func (c *Config) DoHURL() string {
+	if e := c.ServerEndpoint(); e != nil && e.DoHURL != "" && SameSecureHost(c.ServerURL, e.DoHURL) {
+		return e.DoHURL
+	}
+	return DefaultDoHURL(c.ServerURL, c.Profile)
+}
+
+// ValidateDoHURL checks a DoH URL from the server: https, with a host and
+// no fragment
+func ValidateDoHURL(s string) error {
+	u, err := url.Parse(s)
+	switch {
+	case err != nil:
+		return errors.New(i18n.T("invalid DoH URL"))
+	case u.Scheme != "https":
+		return errors.New(i18n.T("DoH URL must start with https://"))
+	case u.Host == "":
+		return errors.New(i18n.T("DoH URL has no host"))
+	case u.Fragment != "":
+		return errors.New(i18n.T("DoH URL must not contain a fragment"))
+	}
+	return nil
+}
+
+// SameSecureHost reports whether both URLs are https and name the same host
+func SameSecureHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Scheme == "https" && ub.Scheme == "https" &&
+		ua.Hostname() != "" && strings.EqualFold(ua.Hostname(), ub.Hostname())
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// exportVersion is the format version written by NewExport
+const exportVersion = 1
+
+// Export is a portable copy of the configuration, for provisioning many
+// machines with the same settings or moving to a new computer. Runtime
+// state (enabled, pause, probed server capabilities) is left out.
+type Export struct {
+	Version int               `json:"version"`
+	Config  json.RawMessage   `json:"config"`
+	Secrets map[string]Secret `json:"secrets,omitempty"` // By FilterDNS profile name
+}
+
+// Secret is a profile password in an export: the password itself, or a
+// reference to the keychain entry that holds it on the importing machine.
+// A reference is written as "keyring:<profile>", the password as
+// {"password": "..."}.
+type Secret struct {
+	Password string `json:"password,omitempty"`
+	Keyring  string `json:"keyring,omitempty"` // Keychain account, usually the profile name
+}
+
+// MarshalJSON writes a reference as its SecretRef
+func (s Secret) MarshalJSON() ([]byte, error) {
+	if s.Password == "" && s.Keyring != "" {
+		return json.Marshal(SecretRef(s.Keyring))
+	}
+	type secret Secret
+	return json.Marshal(secret(s))
+}
+
+// UnmarshalJSON reads a SecretRef, or the object exports before it wrote
+func (s *Secret) UnmarshalJSON(data []byte) error {
+	var ref string
+	if err := json.Unmarshal(data, &ref); err == nil {
+		profile, ok := ParseSecretRef(ref)
+		if !ok {
+			// Not quoting it, in case it is a password
+			return fmt.Errorf(`a secret must be a %s<profile> reference or {"password": "..."}`, SecretRefPrefix)
+		}
+		*s = Secret{Keyring: profile}
+		return nil
+	}
+	type secret Secret
+	if err := json.Unmarshal(data, (*secret)(s)); err != nil {
+		return err
+	}
+	if profile, ok := ParseSecretRef(s.Keyring); ok {
+		s.Keyring = profile
+	}
+	return nil
+}
+
+// NewExport exports cfg. Passwords of the current and saved profiles are
+// written as keychain references unless includeSecrets is set.
+func NewExport(cfg *Config, includeSecrets bool) (*Export, error) {
+	c := *cfg
+	c.Enabled = false
+	c.PausedUntil = nil
+	c.Capabilities = nil
+
+	data, err := json.Marshal(&c)
+	if err != nil {
+		return nil, err
+	}
+	export := &Export{Version: exportVersion, Config: data}
+
+	for _, profile := range cfg.profileNames() {
+		password, err := GetPassword(profile)
+		switch {
+		case err != nil && includeSecrets:
+			return nil, fmt.Errorf("failed to read password for %s: %w", profile, err)
+		case err == nil && password == "":
+			continue
+		}
+
+		if export.Secrets == nil {
+			export.Secrets = make(map[string]Secret)
+		}
+		if includeSecrets {
+			export.Secrets[profile] = Secret{Password: password}
+		} else {
+			// Also when the keychain cannot be read: the reference is
+			// checked on import
+			export.Secrets[profile] = Secret{Keyring: profile}
+		}
+	}
+	return export, nil
+}
+
+// ParseExport reads an export. A plain config.json is accepted as well.
+func ParseExport(data []byte) (*Export, error) {
+	var export Export
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case export.Version == 0 && export.Config == nil:
+		// Plain config file
+		if _, err := parse(data); err != nil {
+			return nil, err
+		}
+		return &Export{Config: data}, nil
+	case export.Version > exportVersion:
+		return nil, fmt.Errorf("export version %d is newer than this client supports", export.Version)
+	case export.Config == nil:
+		return nil, fmt.Errorf("export contains no config")
+	}
+
+	if _, err := parse(export.Config); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return &export, nil
+}
+
+// Apply returns the configuration resulting from importing e on top of
+// current. Without merge the imported config replaces current; with
+// merge only the settings present in the export change, and forwarders,
+// rules and saved profiles are added to the existing ones, replacing those
+// with the same domain or name. Runtime state is always kept from current.
+func (e *Export) Apply(current *Config, merge bool) (*Config, error) {
+	var cfg *Config
+	if merge {
+		c := *current
+		c.Forwarders = append([]Forwarder(nil), current.Forwarders...)
+		c.Rules = append([]Rule(nil), current.Rules...)
+		c.Profiles = append([]SavedProfile(nil), current.Profiles...)
+		if err := json.Unmarshal(e.Config, &c); err != nil {
+			return nil, err
+		}
+		c.Forwarders = mergeForwarders(current.Forwarders, c.Forwarders)
+		c.Rules = mergeRules(current.Rules, c.Rules)
+		c.Profiles = mergeProfiles(current.Profiles, c.Profiles)
+		cfg = &c
+	} else {
+		parsed, err := parse(e.Config)
+		if err != nil {
+			return nil, err
+		}
+		cfg = parsed
+	}
+
+	cfg.Enabled = current.Enabled
+	cfg.PausedUntil = current.PausedUntil
+	cfg.Capabilities = current.Capabilities
+	return cfg, nil
+}
+
+// ImportSecrets stores the passwords contained in e in the keychain and
+// returns the profiles whose referenced keychain entry does not exist or
+// cannot be read
+func (e *Export) ImportSecrets() (missing []string, err error) {
+	for profile, secret := range e.Secrets {
+		if secret.Password != "" {
+			if err := SetPassword(profile, secret.Password); err != nil {
+				return missing, fmt.Errorf("failed to store password for %s: %w", profile, err)
+			}
+			continue
+		}
+		if secret.Keyring == "" {
+			continue
+		}
+
+		password, err := GetPassword(secret.Keyring)
+		if err != nil || password == "" {
+			missing = append(missing, profile)
+			continue
+		}
+		if secret.Keyring != profile {
+			if err := SetPassword(profile, password); err != nil {
+				return missing, fmt.Errorf("failed to store password for %s: %w", profile, err)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// profileNames returns the current and saved FilterDNS profiles, which
+// name their keychain entries
+func (c *Config) profileNames() []string {
+	var names []string
+	add := func(profile string) {
+		if profile != "" && !slices.Contains(names, profile) {
+			names = append(names, profile)
+		}
+	}
+	add(c.Profile)
+	for _, p := range c.Profiles {
+		add(p.Profile)
+	}
+	return names
+}
+
+// mergeForwarders returns the existing forwarders whose domain is not
+// among the imported ones, followed by the imported ones
+func mergeForwarders(existing, imported []Forwarder) []Forwarder {
+	merged := []Forwarder{}
+	for _, f := range existing {
+		if !slices.ContainsFunc(imported, func(i Forwarder) bool { return i.Domain == f.Domain }) {
+			merged = append(merged, f)
+		}
+	}
+	return append(merged, imported...)
+}
+
+// mergeRules returns the existing rules whose domain is not among the
+// imported ones, followed by the imported ones
+func mergeRules(existing, imported []Rule) []Rule {
+	var merged []Rule
+	for _, r := range existing {
+		if !slices.ContainsFunc(imported, func(i Rule) bool { return i.Domain == r.Domain }) {
+			merged = append(merged, r)
+		}
+	}
+	return append(merged, imported...)
+}
+
+// mergeProfiles returns the existing saved profiles whose name is not
+// among the imported ones, followed by the imported ones
+func mergeProfiles(existing, imported []SavedProfile) []SavedProfile {
+	var merged []SavedProfile
+	for _, p := range existing {
+		if !slices.ContainsFunc(imported, func(i SavedProfile) bool { return i.Name == p.Name }) {
+			merged = append(merged, p)
+		}
+	}
+	return append(merged, imported...)
+}
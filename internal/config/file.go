@@ -0,0 +1,91 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// backupSuffix names the copy of the config and state files as they were
+// before the last save, read when the file itself is damaged
+const backupSuffix = ".bak"
+
+// writeFile replaces the file at path with data so that a crash leaves
+// either the old or the new contents, never a mix. The old contents are
+// kept as the backup first, unless they are damaged themselves.
+func writeFile(path string, data []byte) error {
+	if current, err := os.ReadFile(path); err == nil && json.Valid(current) {
+		if err := writeAtomic(path+backupSuffix, current, 0644); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	}
+	return writeAtomic(path, data, 0644)
+}
+
+// writeAtomic writes data to a temporary file with the given permissions,
+// flushes it to disk and renames it over path. Each write gets a file of
+// its own, so the daemon and the CLI or GUI saving at the same time
+// cannot clobber each other's.
+func writeAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	// CreateTemp makes the file 0600
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// Make the rename itself durable; directories cannot be opened for
+	// this on Windows, where the rename is durable already
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// readFile reads the file at path and hands it to decode. If decode
+// fails, e.g. after a crash while an older version wrote the file in
+// place, the backup is decoded instead; the error is returned only if
+// that fails too.
+func readFile(path string, decode func([]byte) error) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	err = decode(data)
+	if err == nil {
+		return nil
+	}
+
+	backup, backupErr := os.ReadFile(path + backupSuffix)
+	if backupErr != nil || decode(backup) != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	log.Printf("Warning: %s is damaged (%v), using its backup %s%s", path, err, path, backupSuffix)
+	return nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+)
+
+// What the daemon may report to the server with each sync, so the
+// dashboard can show which devices are online and healthy. Nothing is
+// reported unless listed in Config.Heartbeat.
+const (
+	HeartbeatName      = "name"      // This computer's hostname
+	HeartbeatVersion   = "version"   // Client version
+	HeartbeatOS        = "os"        // Operating system and architecture
+	HeartbeatFiltering = "filtering" // Whether filtering is on, off or paused
+	HeartbeatCounters  = "counters"  // Queries and blocked queries since the daemon started
+	HeartbeatErrors    = "errors"    // The last problem the daemon ran into
+)
+
+// HeartbeatFields lists the fields in the order they are shown
+var HeartbeatFields = []string{
+	HeartbeatName,
+	HeartbeatVersion,
+	HeartbeatOS,
+	HeartbeatFiltering,
+	HeartbeatCounters,
+	HeartbeatErrors,
+}
+
+// Reports tells whether the heartbeat may include field
+func (c *Config) Reports(field string) bool {
+	return slices.Contains(c.Heartbeat, field)
+}
+
+// ValidateHeartbeat checks that fields only names known heartbeat fields
+func ValidateHeartbeat(fields []string) error {
+	for _, field := range fields {
+		if !slices.Contains(HeartbeatFields, field) {
+			return errors.New(i18n.T("unknown heartbeat field %q", field))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import "slices"
+
+// ManagedSettings are client settings an admin set on the server profile,
+// which the daemon picks up when it syncs. Local settings win over them
+// unless Managed is set.
+type ManagedSettings struct {
+	Managed      bool        `json:"managed,omitempty"`      // The server's settings win over local ones
+	Forwarders   []Forwarder `json:"forwarders,omitempty"`   // Recommended split DNS forwarders
+	BootstrapDNS []string    `json:"bootstrapDns,omitempty"` // Resolvers for the server's own hostname
+	CacheSize    int         `json:"cacheSize,omitempty"`    // Answers kept in the proxy's cache
+}
+
+// Effective returns the config the daemon runs with: c with the server's
+// client settings merged in. Forwarders from both are used, the local one
+// winning for a domain in both; bootstrap resolvers and cache size come
+// from the server only if not set locally. With Managed set the server's
+// values win instead. c itself is returned if there is nothing to merge.
+func (c *Config) Effective() *Config {
+	s := c.ServerSettings
+	if s == nil {
+		return c
+	}
+
+	eff := *c
+	eff.Forwarders = withServerForwarders(c.Forwarders, s.Forwarders, s.Managed)
+	if len(s.BootstrapDNS) > 0 && (s.Managed || len(c.BootstrapDNS) == 0) {
+		eff.BootstrapDNS = s.BootstrapDNS
+	}
+	if s.CacheSize > 0 && (s.Managed || c.CacheSize == 0) {
+		eff.CacheSize = s.CacheSize
+	}
+	return &eff
+}
+
+// ServerForwarder reports whether the forwarder for domain in the
+// effective config is the one from the server
+func (c *Config) ServerForwarder(domain string) bool {
+	s := c.ServerSettings
+	if s == nil || !slices.ContainsFunc(s.Forwarders, func(f Forwarder) bool { return f.Domain == domain }) {
+		return false
+	}
+	return s.Managed || !slices.ContainsFunc(c.Forwarders, func(f Forwarder) bool { return f.Domain == domain })
+}
+
+// withServerForwarders joins the local and server forwarders, keeping the
+// local one for a domain in both unless managed
+func withServerForwarders(local, server []Forwarder, managed bool) []Forwarder {
+	first, second := local, server
+	if managed {
+		first, second = server, local
+	}
+	merged := slices.Clone(first)
+	for _, f := range second {
+		if !slices.ContainsFunc(merged, func(existing Forwarder) bool { return existing.Domain == f.Domain }) {
+			merged = append(merged, f)
+		}
+	}
+	if merged == nil {
+		merged = []Forwarder{}
+	}
+	return merged
+}
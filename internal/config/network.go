@@ -0,0 +1,129 @@
+package config
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+)
+
+// NetworkOverlay changes settings while the computer is connected to one
+// of the given Wi-Fi networks, e.g. a forwarder for the office's internal
+// domains at work or a stricter profile at home. The daemon picks it from
+// the networks it is connected to; Load applies it and Save leaves it out
+// of the file.
+type NetworkOverlay struct {
+	Name       string                     `json:"name"`                 // e.g. "office", "home"
+	SSIDs      []string                   `json:"ssids"`                // Wi-Fi network names it applies on
+	Profile    string                     `json:"profile,omitempty"`    // Saved profile to use there, by name
+	Forwarders []Forwarder                `json:"forwarders,omitempty"` // Added to the configured ones, replacing any for the same domain
+	Settings   map[string]json.RawMessage `json:"settings,omitempty"`   // Other settings as named in config.json, e.g. {"searchShortcut": false}
+}
+
+// networkSettingsExcluded are settings a network overlay cannot change:
+// what the daemon owns at runtime and what picks or defines the overlays
+var networkSettingsExcluded = []string{
+	"enabled", "pausedUntil", "capabilities", "serverSettings", "endpoint",
+	"profiles", "activeProfile", "networks", "profile", "serverUrl", "forwarders",
+}
+
+var (
+	networkMu     sync.Mutex
+	activeNetwork *NetworkOverlay
+)
+
+// SetNetwork makes Load apply the overlay of the network the computer is
+// on, or none if n is nil
+func SetNetwork(n *NetworkOverlay) {
+	networkMu.Lock()
+	defer networkMu.Unlock()
+	activeNetwork = n
+}
+
+// currentNetwork returns the overlay SetNetwork chose, if any
+func currentNetwork() *NetworkOverlay {
+	networkMu.Lock()
+	defer networkMu.Unlock()
+	return activeNetwork
+}
+
+// NetworkFor returns the first overlay for one of the given Wi-Fi
+// networks, or nil if none applies
+func (c *Config) NetworkFor(ssids []string) *NetworkOverlay {
+	for i := range c.Networks {
+		for _, ssid := range c.Networks[i].SSIDs {
+			if slices.Contains(ssids, ssid) {
+				return &c.Networks[i]
+			}
+		}
+	}
+	return nil
+}
+
+// apply changes cfg as the overlay says. A profile that is not saved in
+// cfg is skipped, as are settings an overlay cannot change.
+func (n *NetworkOverlay) apply(cfg *Config) error {
+	if saved := cfg.FindProfile(n.Profile); n.Profile != "" && saved != nil {
+		cfg.UseProfile(saved)
+	}
+	cfg.Forwarders = slices.Clone(cfg.Forwarders)
+	for _, f := range n.Forwarders {
+		i := slices.IndexFunc(cfg.Forwarders, func(existing Forwarder) bool { return existing.Domain == f.Domain })
+		if i >= 0 {
+			cfg.Forwarders[i] = f
+		} else {
+			cfg.Forwarders = append(cfg.Forwarders, f)
+		}
+	}
+	settings := Policy{}
+	for key, value := range n.Settings {
+		if !slices.Contains(networkSettingsExcluded, key) {
+			settings[key] = value
+		}
+	}
+	return settings.apply(cfg)
+}
+
+// restoreProfile puts back the saved profile in cfg if it still uses the
+// saved profile with the given name that an overlay or schedule chose
+func restoreProfile(cfg, saved *Config, name string) {
+	if name != "" && cfg.ActiveProfile == name && saved.ActiveProfile != name {
+		cfg.Profile = saved.Profile
+		cfg.ServerURL = saved.ServerURL
+		cfg.ActiveProfile = saved.ActiveProfile
+	}
+}
+
+// restore undoes the overlay in cfg where it still has the overlay's
+// values, taking them from saved
+func (n *NetworkOverlay) restore(cfg, saved *Config) (*Config, error) {
+	restoreProfile(cfg, saved, n.Profile)
+
+	for _, f := range n.Forwarders {
+		if slices.Contains(saved.Forwarders, f) {
+			continue
+		}
+		i := slices.Index(cfg.Forwarders, f)
+		if i < 0 {
+			continue
+		}
+		cfg.Forwarders = slices.Delete(cfg.Forwarders, i, i+1)
+		for _, previous := range saved.Forwarders {
+			if previous.Domain == f.Domain {
+				cfg.Forwarders = slices.Insert(cfg.Forwarders, i, previous)
+				break
+			}
+		}
+	}
+
+	// Settings changed since the overlay was applied are the caller's
+	var keys []string
+	for key, value := range n.Settings {
+		if (Policy{key: value}).check(cfg) == nil {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return cfg, nil
+	}
+	return withSavedValues(cfg, saved, keys)
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Override replaces a setting in every config Load returns, e.g. from an
+// environment variable or command line flag, without Save writing it to
+// the file
+type Override struct {
+	Source string // Where the value comes from, e.g. "FILTERDNS_PROFILE"
+
+	Apply   func(cfg *Config) error
+	Applied func(cfg *Config) bool   // Whether cfg still has the overriding value
+	Restore func(cfg, saved *Config) // Copies the setting from saved into cfg
+}
+
+// overrides are applied in order, so a later one wins
+var overrides []Override
+
+// SetOverrides makes Load apply overrides to the saved config
+func SetOverrides(o []Override) {
+	overrides = o
+}
+
+// ApplyOverrides applies the overrides to cfg, e.g. to a config received
+// from a client that does not know about them, then the overlay of the
+// network the computer is on, the profile a schedule entry chose and the
+// settings the administrator's policy locks
+func ApplyOverrides(cfg *Config) error {
+	for _, o := range overrides {
+		if err := o.Apply(cfg); err != nil {
+			return fmt.Errorf("%s: %w", o.Source, err)
+		}
+	}
+	if n := currentNetwork(); n != nil {
+		if err := n.apply(cfg); err != nil {
+			return fmt.Errorf("network %s: %w", n.Name, err)
+		}
+	}
+	if name := currentScheduledProfile(); name != "" {
+		if saved := cfg.FindProfile(name); saved != nil {
+			cfg.UseProfile(saved)
+		}
+	}
+	return CurrentPolicy().apply(cfg)
+}
+
+// withoutOverrides returns what Save writes for cfg: overridden settings
+// and those of the network overlay or schedule keep their saved value
+// unless the caller changed them, and locked ones keep it always, so
+// nothing is left behind once the policy is lifted
+func withoutOverrides(cfg *Config) (*Config, error) {
+	policy, network, scheduled := CurrentPolicy(), currentNetwork(), currentScheduledProfile()
+	if len(overrides) == 0 && len(policy) == 0 && network == nil && scheduled == "" {
+		return cfg, nil
+	}
+	saved, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if len(policy) > 0 {
+		if cfg, err = withSavedValues(cfg, saved, policy.Keys()); err != nil {
+			return nil, err
+		}
+	}
+
+	// A deep copy, as restoring may change what pointer fields point to
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	restoreProfile(c, saved, scheduled)
+	if network != nil {
+		if c, err = network.restore(c, saved); err != nil {
+			return nil, err
+		}
+	}
+	for _, o := range overrides {
+		if o.Applied(c) {
+			o.Restore(c, saved)
+		}
+	}
+	return c, nil
+}
+
+// withSavedValues returns a copy of cfg with the settings named by keys,
+// as in config.json, taken from saved
+func withSavedValues(cfg, saved *Config, keys []string) (*Config, error) {
+	fields := func(cfg *Config) (map[string]json.RawMessage, error) {
+		data, err := json.Marshal(cfg)
+		if err != nil {
+			return nil, err
+		}
+		var m map[string]json.RawMessage
+		return m, json.Unmarshal(data, &m)
+	}
+	current, err := fields(cfg)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := fields(saved)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if value, ok := previous[key]; ok {
+			current[key] = value
+		} else {
+			delete(current, key)
+		}
+	}
+
+	data, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	return c, json.Unmarshal(data, c)
+}
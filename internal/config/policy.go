@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy holds the settings an administrator locked through device
+// management (a policy file, Group Policy or a configuration profile), by
+// their name in config.json, e.g. "serverUrl". Load applies it over
+// everything else and Save refuses to change what it locks.
+type Policy map[string]json.RawMessage
+
+// ErrLocked is returned by Save for a change to a setting the policy locks
+var ErrLocked = errors.New("locked by the administrator")
+
+// policyMaxAge is how long a policy that was read is used before it is
+// read again; Load runs often and on macOS reading it runs a command
+const policyMaxAge = 10 * time.Second
+
+var (
+	policyMu     sync.Mutex
+	policyCache  Policy
+	policyReadAt time.Time
+
+	// The last warning about the policy, logged once rather than on every read
+	policyWarning string
+)
+
+// CurrentPolicy returns the settings the administrator locked, if any.
+// Settings the client does not know or that do not fit are left out.
+func CurrentPolicy() Policy {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+
+	if !policyReadAt.IsZero() && time.Since(policyReadAt) < policyMaxAge {
+		return policyCache
+	}
+	policyReadAt = time.Now()
+
+	var warning string
+	p, err := readPolicy()
+	if err != nil {
+		warning = fmt.Sprintf("ignoring the managed configuration: %v", err)
+		p = nil
+	}
+	var ignored []string
+	for key, value := range p {
+		if err := (Policy{key: value}).apply(Default()); err != nil || policyFieldType(key) == nil {
+			ignored = append(ignored, key)
+			delete(p, key)
+		}
+	}
+	if len(ignored) > 0 {
+		sort.Strings(ignored)
+		warning = "ignoring " + strings.Join(ignored, ", ") + " in the managed configuration"
+	}
+	if warning != "" && warning != policyWarning {
+		log.Printf("Warning: %s", warning)
+	}
+	policyWarning = warning
+
+	policyCache = p
+	return p
+}
+
+// Locked reports whether the policy locks the setting with the given name
+// in config.json
+func (p Policy) Locked(key string) bool {
+	_, ok := p[key]
+	return ok
+}
+
+// Keys returns the names of the locked settings, sorted
+func (p Policy) Keys() []string {
+	keys := make([]string, 0, len(p))
+	for key := range p {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// apply sets the locked settings in cfg
+func (p Policy) apply(cfg *Config) error {
+	if len(p) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(map[string]json.RawMessage(p))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, cfg)
+}
+
+// check returns ErrLocked if cfg differs from the policy in a setting it
+// locks
+func (p Policy) check(cfg *Config) error {
+	current, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	var changed []string
+	for _, key := range p.Keys() {
+		c := &Config{}
+		if err := json.Unmarshal(current, c); err != nil {
+			return err
+		}
+		if err := (Policy{key: p[key]}).apply(c); err != nil {
+			return err
+		}
+		if data, _ := json.Marshal(c); string(data) != string(current) {
+			changed = append(changed, key)
+		}
+	}
+	if len(changed) > 0 {
+		return fmt.Errorf("%s: %w", strings.Join(changed, ", "), ErrLocked)
+	}
+	return nil
+}
+
+// policyFieldType returns the type of the Config field with the given
+// name in config.json, or nil if there is none
+func policyFieldType(key string) reflect.Type {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == key {
+			return t.Field(i).Type
+		}
+	}
+	return nil
+}
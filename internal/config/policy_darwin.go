@@ -0,0 +1,31 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// policyPlist is where a configuration profile for the io.filterdns.client
+// domain, as installed by MDM, leaves its settings
+const policyPlist = "/Library/Managed Preferences/io.filterdns.client.plist"
+
+// readPolicy reads the settings of the configuration profile
+func readPolicy() (Policy, error) {
+	if _, err := os.Stat(policyPlist); os.IsNotExist(err) {
+		return nil, nil
+	}
+	out, err := exec.Command("plutil", "-convert", "json", "-o", "-", policyPlist).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", policyPlist, err)
+	}
+	var p Policy
+	if err := json.Unmarshal(out, &p); err != nil {
+		return nil, fmt.Errorf("%s: %w", policyPlist, err)
+	}
+	// Profiles carry bookkeeping of their own
+	delete(p, "PayloadUUID")
+	delete(p, "PayloadType")
+	return p, nil
+}
@@ -0,0 +1,30 @@
+//go:build !darwin && !windows
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// policyFile holds the managed configuration, next to the machine config
+const policyFile = "managed.json"
+
+// readPolicy reads the policy file, e.g. /etc/filterdns/managed.json
+func readPolicy() (Policy, error) {
+	path := filepath.Join(SystemDir(), policyFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
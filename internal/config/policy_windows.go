@@ -0,0 +1,61 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// policyKey holds the managed configuration as set by Group Policy, one
+// value per setting: DWORD for switches and numbers, a string for text
+// and JSON for lists
+const policyKey = `SOFTWARE\Policies\FilterDNS`
+
+// readPolicy reads the values under HKLM\SOFTWARE\Policies\FilterDNS
+func readPolicy() (Policy, error) {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, policyKey, registry.QUERY_VALUE)
+	if err == registry.ErrNotExist {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+
+	names, err := k.ReadValueNames(0)
+	if err != nil {
+		return nil, err
+	}
+	p := Policy{}
+	for _, name := range names {
+		value, err := policyValue(k, name)
+		if err != nil {
+			return nil, fmt.Errorf(`HKLM\%s\%s: %w`, policyKey, name, err)
+		}
+		p[name] = value
+	}
+	return p, nil
+}
+
+// policyValue converts a registry value to JSON for the setting it locks
+func policyValue(k registry.Key, name string) (json.RawMessage, error) {
+	t := policyFieldType(name)
+
+	if n, _, err := k.GetIntegerValue(name); err == nil {
+		if t != nil && t.Kind() == reflect.Bool {
+			return json.Marshal(n != 0)
+		}
+		return json.RawMessage(strconv.FormatUint(n, 10)), nil
+	}
+	s, _, err := k.GetStringValue(name)
+	if err != nil {
+		return nil, err
+	}
+	if t != nil && t.Kind() == reflect.String {
+		return json.Marshal(s)
+	}
+	return json.RawMessage(s), nil
+}
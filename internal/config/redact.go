@@ -0,0 +1,255 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SecretRefPrefix starts a reference to a password in the keychain (or
+// the credentials file), e.g. "keyring:kids" for the password of the
+// profile kids. Exports, debug bundles, logs and printed output carry
+// these instead of the passwords themselves.
+const SecretRefPrefix = "keyring:"
+
+// Redacted stands in for a secret that has no keychain entry to refer
+// to, such as a token or the credentials in a URL
+const Redacted = "[redacted]"
+
+// SecretRef returns the reference to the password of a profile
+func SecretRef(profile string) string {
+	return SecretRefPrefix + profile
+}
+
+// ParseSecretRef returns the profile a reference names, and whether s is
+// a reference at all
+func ParseSecretRef(s string) (string, bool) {
+	profile, ok := strings.CutPrefix(s, SecretRefPrefix)
+	return profile, ok && profile != ""
+}
+
+// minSecretLen keeps short secrets from being replaced wherever they
+// happen to appear in free text; RedactJSON still replaces them when
+// they make up a whole value
+const minSecretLen = 8
+
+var (
+	secretsMu sync.Mutex
+	secrets   = map[string]string{} // Secret value, what Redact puts in its place
+)
+
+// RegisterSecret makes Redact replace value wherever it appears with
+// replacement, e.g. a password with its SecretRef
+func RegisterSecret(value, replacement string) {
+	if value == "" {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secrets[value] = replacement
+}
+
+var (
+	// Credentials before the host of a URL, e.g. "https://user:pw@host"
+	urlCredentials = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)[^/@\s"']+@`)
+
+	// Query parameters and JSON fields that carry secrets
+	secretParam = regexp.MustCompile(`(?i)([?&](?:password|passwd|pass|token|secret|key|auth)=)[^&\s"']*`)
+	secretField = regexp.MustCompile(`(?i)("(?:password|passwd|token|secret)"\s*:\s*")(?:[^"\\]|\\.)*"`)
+	secretKey   = regexp.MustCompile(`(?i)^(?:password|passwd|token|secret)$`)
+)
+
+// Redact removes secrets from text that is printed or logged: the
+// passwords and tokens registered with RegisterSecret, credentials in
+// URLs, secret query parameters and JSON fields such as "password"
+func Redact(text string) string {
+	secretsMu.Lock()
+	values := make([]string, 0, len(secrets))
+	for value := range secrets {
+		values = append(values, value)
+	}
+	// Longest first, in case one secret contains another
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, value := range values {
+		if len(value) >= minSecretLen {
+			text = strings.ReplaceAll(text, value, secrets[value])
+		}
+	}
+	secretsMu.Unlock()
+
+	text = urlCredentials.ReplaceAllString(text, "${1}"+Redacted+"@")
+	text = secretParam.ReplaceAllString(text, "${1}"+Redacted)
+	text = secretField.ReplaceAllStringFunc(text, func(field string) string {
+		prefix := secretField.FindStringSubmatch(field)[1]
+		// Already a reference, which is safe to show
+		if _, ok := ParseSecretRef(strings.TrimSuffix(field[len(prefix):], `"`)); ok {
+			return field
+		}
+		return prefix + Redacted + `"`
+	})
+	return text
+}
+
+// RedactJSON marshals v as indented JSON with its secrets redacted value
+// by value: a string that is a registered secret or sits in a secret
+// field is replaced whole, and others only have the credentials in URLs
+// masked. Unlike Redact on the marshalled text, this never rewrites parts
+// of unrelated values, such as a domain containing a short password.
+func RedactJSON(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var buf bytes.Buffer
+	if err := redactJSONValue(dec, &buf, ""); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// redactJSONValue copies the next value from dec to buf, redacting its
+// strings; key is the field the value belongs to
+func redactJSONValue(dec *json.Decoder, buf *bytes.Buffer, key string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		end := byte('}')
+		if t == '[' {
+			end = ']'
+		}
+		buf.WriteByte(byte(t))
+		for i := 0; dec.More(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			elemKey := key
+			if t == '{' {
+				name, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				elemKey, _ = name.(string)
+				writeJSONString(buf, elemKey)
+				buf.WriteByte(':')
+			}
+			if err := redactJSONValue(dec, buf, elemKey); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		buf.WriteByte(end)
+	case string:
+		writeJSONString(buf, redactValue(key, t))
+	case json.Number:
+		buf.WriteString(t.String())
+	case bool:
+		buf.WriteString(strconv.FormatBool(t))
+	case nil:
+		buf.WriteString("null")
+	}
+	return nil
+}
+
+// writeJSONString writes s as a JSON string
+func writeJSONString(buf *bytes.Buffer, s string) {
+	data, _ := json.Marshal(s)
+	buf.Write(data)
+}
+
+// redactValue redacts one string value of the field key
+func redactValue(key, s string) string {
+	if _, ok := ParseSecretRef(s); ok || s == "" {
+		return s
+	}
+	if secretKey.MatchString(key) {
+		return Redacted
+	}
+	secretsMu.Lock()
+	replacement, ok := secrets[s]
+	secretsMu.Unlock()
+	if ok {
+		return replacement
+	}
+	s = urlCredentials.ReplaceAllString(s, "${1}"+Redacted+"@")
+	return secretParam.ReplaceAllString(s, "${1}"+Redacted)
+}
+
+// RedactURL masks the credentials and query of a URL
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Redact(raw)
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = "redacted"
+	}
+	if u.User == nil {
+		return u.String()
+	}
+	u.User = nil
+	return strings.Replace(u.String(), "://", "://"+Redacted+"@", 1)
+}
+
+// RedactConfig returns a copy of cfg for showing and debug bundles, with
+// the credentials in its server URLs masked. Passwords themselves live in
+// the keychain, not the config.
+func RedactConfig(cfg *Config) *Config {
+	redacted := *cfg
+	redacted.ServerURL = RedactURL(cfg.ServerURL)
+	if cfg.Capabilities != nil {
+		caps := *cfg.Capabilities
+		caps.ServerURL = RedactURL(caps.ServerURL)
+		redacted.Capabilities = &caps
+	}
+	if cfg.Endpoint != nil {
+		endpoint := *cfg.Endpoint
+		endpoint.ServerURL = RedactURL(endpoint.ServerURL)
+		endpoint.DoHURL = Redact(endpoint.DoHURL)
+		redacted.Endpoint = &endpoint
+	}
+	if cfg.UpdateURL != "" {
+		redacted.UpdateURL = RedactURL(cfg.UpdateURL)
+	}
+
+	redacted.Profiles = make([]SavedProfile, len(cfg.Profiles))
+	for i, p := range cfg.Profiles {
+		p.ServerURL = RedactURL(p.ServerURL)
+		redacted.Profiles[i] = p
+	}
+	return &redacted
+}
+
+// redactingWriter passes writes on with secrets redacted
+type redactingWriter struct {
+	w io.Writer
+}
+
+// RedactingWriter returns a writer that redacts what it passes on to w,
+// for log output; the log package writes one line per call
+func RedactingWriter(w io.Writer) io.Writer {
+	return redactingWriter{w}
+}
+
+func (r redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schedule actions
+const (
+	ScheduleOff     = "off"     // Filtering paused during the time range
+	ScheduleOn      = "on"      // Filtering on during the time range, off again after if it was off
+	ScheduleProfile = "profile" // A saved profile in use during the time range
+)
+
+// ScheduleActions lists the values ScheduleEntry.Action takes
+var ScheduleActions = []string{ScheduleOff, ScheduleOn, ScheduleProfile}
+
+// ScheduleEntry changes filtering during a weekly time range, e.g. a
+// strict profile from 15:00 to 19:00 on school days. The daemon carries
+// it out, with or without the GUI running.
+type ScheduleEntry struct {
+	Name    string   `json:"name"`              // e.g. "homework", "night"
+	Days    []string `json:"days,omitempty"`    // "mon" to "sun", "weekdays" or "weekends" (empty = every day)
+	From    string   `json:"from"`              // Start, e.g. "15:00"
+	Until   string   `json:"until"`             // End, e.g. "19:00"; before From it is on the next day
+	Action  string   `json:"action"`            // One of the Schedule* actions
+	Profile string   `json:"profile,omitempty"` // Saved profile for ScheduleProfile, by name
+}
+
+// scheduleDays are the day names ScheduleEntry.Days takes, in the order
+// of time.Weekday
+var scheduleDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ScheduleDayNames lists every value ScheduleEntry.Days takes
+var ScheduleDayNames = append([]string{"weekdays", "weekends"}, scheduleDays...)
+
+// onDay reports whether the entry applies on the given day of the week
+func (e *ScheduleEntry) onDay(day time.Weekday) bool {
+	if len(e.Days) == 0 {
+		return true
+	}
+	for _, name := range e.Days {
+		switch strings.ToLower(name) {
+		case scheduleDays[day]:
+			return true
+		case "weekdays":
+			if day != time.Saturday && day != time.Sunday {
+				return true
+			}
+		case "weekends":
+			if day == time.Saturday || day == time.Sunday {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// clockOn returns the time of day s, such as "15:00", on the date of day
+func clockOn(day time.Time, s string) (time.Time, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, day.Location()), nil
+}
+
+// ActiveAt returns when the entry's current time range ends if now falls
+// in one, or the zero time
+func (e *ScheduleEntry) ActiveAt(now time.Time) time.Time {
+	// A range that started yesterday may run past midnight
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, day := range []time.Time{today.AddDate(0, 0, -1), today} {
+		if !e.onDay(day.Weekday()) {
+			continue
+		}
+		start, err := clockOn(day, e.From)
+		if err != nil {
+			return time.Time{}
+		}
+		end, err := clockOn(day, e.Until)
+		if err != nil {
+			return time.Time{}
+		}
+		if !end.After(start) {
+			end = end.AddDate(0, 0, 1)
+		}
+		if !now.Before(start) && now.Before(end) {
+			return end
+		}
+	}
+	return time.Time{}
+}
+
+// ActiveSchedule returns the first schedule entry in effect at now and
+// when its time range ends, or nil
+func (c *Config) ActiveSchedule(now time.Time) (*ScheduleEntry, time.Time) {
+	for i := range c.Schedule {
+		if end := c.Schedule[i].ActiveAt(now); !end.IsZero() {
+			return &c.Schedule[i], end
+		}
+	}
+	return nil, time.Time{}
+}
+
+var (
+	scheduleMu       sync.Mutex
+	scheduledProfile string
+)
+
+// SetScheduledProfile makes Load use the saved profile with the given
+// name, as a schedule entry says, or stops doing so if it is ""
+func SetScheduledProfile(name string) {
+	scheduleMu.Lock()
+	defer scheduleMu.Unlock()
+	scheduledProfile = name
+}
+
+// currentScheduledProfile returns the profile SetScheduledProfile chose
+func currentScheduledProfile() string {
+	scheduleMu.Lock()
+	defer scheduleMu.Unlock()
+	return scheduledProfile
+}
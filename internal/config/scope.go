@@ -0,0 +1,262 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// The daemon reads the machine config in SystemDir. Everyone else reads
+// it too once it exists, with their own preferences laid over it from
+// the per-user config directory; until then the per-user config is all
+// there is, as before the daemon was installed.
+
+// systemScope is set in the daemon, which uses the machine config only
+var systemScope bool
+
+// machineWriter saves the machine config for users who cannot write it,
+// see SetMachineWriter
+var machineWriter func(cfg *Config) error
+
+// SystemDir returns the directory of the machine config, which the
+// daemon reads and writes. On macOS and Windows it sits next to the
+// daemon's DNS backup rather than in it.
+func SystemDir() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "/Library/Application Support/FilterDNS/config"
+	case "windows":
+		return filepath.Join(os.Getenv("PROGRAMDATA"), appName, "config")
+	default:
+		return "/etc/filterdns"
+	}
+}
+
+// UseSystemConfig makes Load and Save use the machine config in SystemDir
+// alone, as the daemon does. It fails, changing nothing, if SystemDir is
+// not writable, e.g. for a daemon started by hand without root.
+func UseSystemConfig() error {
+	if err := os.MkdirAll(SystemDir(), 0755); err != nil {
+		return err
+	}
+	probe, err := os.CreateTemp(SystemDir(), ".probe")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	systemScope = true
+	return nil
+}
+
+// SetMachineWriter sets how Save changes the machine config when it is
+// not writable by the user, e.g. by handing it to the running daemon
+func SetMachineWriter(write func(cfg *Config) error) {
+	machineWriter = write
+}
+
+// machinePath returns the machine config file when Load lays the user's
+// preferences over it: outside the daemon and pure-config mode, once the
+// file exists
+func machinePath() string {
+	if systemScope || PureConfig() != "" {
+		return ""
+	}
+	path := filepath.Join(SystemDir(), configFile)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// userSettings are the settings each user keeps for themselves, in the
+// per-user config, while the machine config holds everything else
+type userSettings struct {
+	Autostart          bool   `json:"autostart"`
+	Language           string `json:"language,omitempty"`
+	StartMinimized     bool   `json:"startMinimized,omitempty"`
+	CloseQuits         bool   `json:"closeQuits,omitempty"`
+	ShowOnStatusChange bool   `json:"showOnStatusChange,omitempty"`
+}
+
+// CopyUserSettings copies the settings each user keeps for themselves,
+// such as the GUI's language and window behavior, from src to dst
+func CopyUserSettings(dst, src *Config) {
+	dst.Autostart = src.Autostart
+	dst.Language = src.Language
+	dst.StartMinimized = src.StartMinimized
+	dst.CloseQuits = src.CloseQuits
+	dst.ShowOnStatusChange = src.ShowOnStatusChange
+}
+
+// readConfig reads the config file at path, recovering it from its
+// backup if need be
+func readConfig(path string) (*Config, error) {
+	var cfg *Config
+	err := readFile(path, func(data []byte) (err error) {
+		cfg, err = parse(data)
+		return err
+	})
+	return cfg, err
+}
+
+// loadMachine reads the machine config and lays the user's preferences
+// from userPath over it
+func loadMachine(machine, userPath string) (*Config, error) {
+	cfg, err := readConfig(machine)
+	if err != nil {
+		return nil, err
+	}
+	user, err := readConfig(userPath)
+	switch {
+	case err == nil:
+		CopyUserSettings(cfg, user)
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// saveMachine writes the user's preferences in cfg to userPath and the
+// rest to the machine config, if it changed
+func saveMachine(machine, userPath string, cfg *Config) error {
+	if err := saveUserSettings(userPath, cfg); err != nil {
+		return err
+	}
+
+	saved, err := readConfig(machine)
+	if err != nil {
+		return err
+	}
+	c := *cfg
+	CopyUserSettings(&c, saved)
+	if SettingsEqual(&c, saved) {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(&c, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = writeFile(machine, data)
+	if !errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+	if machineWriter != nil {
+		if err = machineWriter(&c); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s can only be changed by the daemon or an administrator: %w", machine, err)
+}
+
+// saveUserSettings writes the user's preferences in cfg to path
+func saveUserSettings(path string, cfg *Config) error {
+	data, err := json.MarshalIndent(userSettings{
+		Autostart:          cfg.Autostart,
+		Language:           cfg.Language,
+		StartMinimized:     cfg.StartMinimized,
+		CloseQuits:         cfg.CloseQuits,
+		ShowOnStatusChange: cfg.ShowOnStatusChange,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// SaveUser saves only the settings each user keeps for themselves, e.g.
+// while the rest belongs to a daemon on another machine
+func SaveUser(cfg *Config) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if machinePath() != "" {
+		return saveUserSettings(path, cfg)
+	}
+
+	// Without a machine config the user's file holds everything
+	current, err := Load()
+	if err != nil {
+		return err
+	}
+	CopyUserSettings(current, cfg)
+	return Save(current)
+}
+
+// AdoptConfig copies the files in dir, such as a config directory used
+// before the machine config existed, into SystemDir, unless the machine
+// config exists already. It reports whether it copied anything.
+func AdoptConfig(dir string) (bool, error) {
+	machine := filepath.Join(SystemDir(), configFile)
+	if _, err := os.Stat(machine); err == nil {
+		return false, nil
+	}
+	if !fullConfig(filepath.Join(dir, configFile)) {
+		return false, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(SystemDir(), 0755); err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || strings.HasSuffix(name, ".tmp") || name == configFile {
+			continue
+		}
+		if err := copyFile(filepath.Join(dir, name), filepath.Join(SystemDir(), name)); err != nil {
+			return false, err
+		}
+	}
+	// The config goes last: once it exists, nothing is adopted again
+	return true, copyFile(filepath.Join(dir, configFile), machine)
+}
+
+// fullConfig reports whether the file at path holds a whole config rather
+// than only the preferences a user lays over the machine config
+func fullConfig(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var keys map[string]json.RawMessage
+	if json.Unmarshal(data, &keys) != nil {
+		return false
+	}
+	_, ok := keys["serverUrl"]
+	return ok
+}
+
+// copyFile copies src to dst, keeping its permissions
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, info.Mode().Perm())
+}
+
+// UserDir returns the per-user config directory of the current user,
+// whatever scope Load and Save use
+func UserDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appName), nil
+}
@@ -0,0 +1,311 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+)
+
+// ValidateServerURL checks that s can serve as a FilterDNS server URL: an
+// http or https URL with a host and nothing after the path
+func ValidateServerURL(s string) error {
+	if s == "" {
+		return errors.New(i18n.T("server URL is empty"))
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("%s: %w", i18n.T("invalid server URL"), err)
+	}
+	switch {
+	case u.Scheme != "http" && u.Scheme != "https":
+		return errors.New(i18n.T("server URL must start with https://"))
+	case u.Host == "":
+		return errors.New(i18n.T("server URL has no host"))
+	case u.RawQuery != "" || u.Fragment != "":
+		return errors.New(i18n.T("server URL must not contain a query or fragment"))
+	}
+	return nil
+}
+
+// ValidateProfile checks a profile name, which ends up unescaped in DoH
+// URLs. An empty name is valid: no profile is set up yet.
+func ValidateProfile(name string) error {
+	for _, r := range name {
+		if !isNameChar(r) && r != '.' {
+			return errors.New(i18n.T("profile name may only contain letters, digits, '-', '_' and '.'"))
+		}
+	}
+	return nil
+}
+
+// ValidateForwarder checks that f names a domain, optionally as
+// "*.domain", and a server IP address with an optional port
+func ValidateForwarder(f Forwarder) error {
+	if err := ValidateDomain(f.Domain); err != nil {
+		return err
+	}
+	return ValidateDNSServer(f.Server)
+}
+
+// ValidateDomain checks a forwarder or rule domain, optionally written
+// as "*.domain"
+func ValidateDomain(domain string) error {
+	name := strings.TrimSuffix(strings.TrimPrefix(domain, "*."), ".")
+	if name == "" {
+		return errors.New(i18n.T("domain is empty"))
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || strings.IndexFunc(label, func(r rune) bool { return !isNameChar(r) }) >= 0 {
+			return errors.New(i18n.T("invalid domain %q", domain))
+		}
+	}
+	return nil
+}
+
+// ValidateDNSServer checks a plain DNS server address: an IP address,
+// or an IP address and port such as "192.168.1.1:53" or "[fd00::1]:53"
+func ValidateDNSServer(server string) error {
+	if net.ParseIP(server) != nil && !strings.Contains(server, ":") {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(server)
+	if err != nil || net.ParseIP(host) == nil {
+		return errors.New(i18n.T("DNS server must be an IP address, e.g. 192.168.1.1 or [fd00::1]:53"))
+	}
+	return validatePort(port)
+}
+
+// validatePort checks that port is a number from 1 to 65535
+func validatePort(port string) error {
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return errors.New(i18n.T("port must be a number from 1 to 65535, got %q", port))
+	}
+	return nil
+}
+
+// isNameChar reports whether r may appear in a profile name or a domain
+// label
+func isNameChar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_'
+}
+
+// FieldError is a problem with one setting, named as in config.json, e.g.
+// "forwarders[1].server"
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists every problem Validate found
+type ValidationError []*FieldError
+
+func (v ValidationError) Error() string {
+	problems := make([]string, len(v))
+	for i, e := range v {
+		problems[i] = e.Error()
+	}
+	return strings.Join(problems, "; ")
+}
+
+// For returns the problems with the setting named field and the settings
+// inside it, e.g. with "forwarders[1]" those of its domain and server
+func (v ValidationError) For(field string) []*FieldError {
+	var found []*FieldError
+	for _, e := range v {
+		if e.Field == field || strings.HasPrefix(e.Field, field+".") || strings.HasPrefix(e.Field, field+"[") {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// Validate checks every setting in cfg and how they fit together. It
+// returns a ValidationError naming each setting that is wrong, or nil.
+func Validate(cfg *Config) error {
+	var v ValidationError
+	check := func(field string, err error) {
+		if err != nil {
+			v = append(v, &FieldError{Field: field, Err: err})
+		}
+	}
+
+	check("serverUrl", ValidateServerURL(cfg.ServerURL))
+	check("profile", ValidateProfile(cfg.Profile))
+
+	domains := map[string]bool{}
+	for i, f := range cfg.Forwarders {
+		field := fmt.Sprintf("forwarders[%d]", i)
+		check(field+".domain", ValidateDomain(f.Domain))
+		check(field+".server", ValidateDNSServer(f.Server))
+		if domain := strings.ToLower(strings.TrimSuffix(f.Domain, ".")); domains[domain] {
+			check(field+".domain", errors.New(i18n.T("%s has another forwarder already", f.Domain)))
+		} else {
+			domains[domain] = true
+		}
+	}
+
+	for i, rule := range cfg.Rules {
+		field := fmt.Sprintf("rules[%d]", i)
+		check(field+".domain", ValidateDomain(rule.Domain))
+		if rule.Action != RuleAllow && rule.Action != RuleBlock {
+			check(field+".action", errors.New(i18n.T("rule action must be %s or %s", RuleAllow, RuleBlock)))
+		}
+	}
+
+	names := map[string]bool{}
+	for i, saved := range cfg.Profiles {
+		field := fmt.Sprintf("profiles[%d]", i)
+		switch {
+		case saved.Name == "":
+			check(field+".name", errors.New(i18n.T("saved profile has no name")))
+		case names[saved.Name]:
+			check(field+".name", errors.New(i18n.T("another saved profile is called %s", saved.Name)))
+		}
+		names[saved.Name] = true
+		check(field+".profile", ValidateProfile(saved.Profile))
+		check(field+".serverUrl", ValidateServerURL(saved.ServerURL))
+	}
+	if cfg.ActiveProfile != "" && cfg.FindProfile(cfg.ActiveProfile) == nil {
+		check("activeProfile", errors.New(i18n.T("no saved profile is called %s", cfg.ActiveProfile)))
+	}
+
+	for _, list := range []struct {
+		field    string
+		patterns []string
+	}{{"managedInterfaces", cfg.ManagedInterfaces}, {"ignoredInterfaces", cfg.IgnoredInterfaces}} {
+		for i, pattern := range list.patterns {
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+				check(fmt.Sprintf("%s[%d]", list.field, i), errors.New(i18n.T("invalid interface pattern %q", pattern)))
+			}
+		}
+	}
+	for i, pattern := range cfg.IgnoredInterfaces {
+		if slices.Contains(cfg.ManagedInterfaces, pattern) {
+			check(fmt.Sprintf("ignoredInterfaces[%d]", i), errors.New(i18n.T("%s is both filtered and ignored", pattern)))
+		}
+	}
+
+	for i, server := range cfg.BootstrapDNS {
+		host := server
+		if h, port, err := net.SplitHostPort(server); err == nil {
+			host = h
+			check(fmt.Sprintf("bootstrapDns[%d]", i), validatePort(port))
+		}
+		if net.ParseIP(host) == nil {
+			check(fmt.Sprintf("bootstrapDns[%d]", i), errors.New(i18n.T("bootstrap resolvers must be IP addresses, got %q", server)))
+		}
+	}
+
+	if cfg.UpdateURL != "" {
+		if u, err := url.Parse(cfg.UpdateURL); err != nil || u.Scheme != "https" || u.Host == "" {
+			check("updateUrl", errors.New(i18n.T("update URL must start with https://")))
+		}
+	}
+	if cfg.ControlAddr != "" {
+		if _, port, err := net.SplitHostPort(cfg.ControlAddr); err != nil {
+			check("controlAddr", errors.New(i18n.T("control address must be host:port or :port, got %q", cfg.ControlAddr)))
+		} else {
+			check("controlAddr", validatePort(port))
+		}
+	}
+
+	check("heartbeat", ValidateHeartbeat(cfg.Heartbeat))
+	if cfg.Language != "" && !slices.Contains(i18n.Languages, cfg.Language) {
+		check("language", errors.New(i18n.T("language must be one of %s", strings.Join(i18n.Languages, ", "))))
+	}
+	if cfg.CredentialStore != "" && !slices.Contains(CredentialStores, cfg.CredentialStore) {
+		check("credentialStore", errors.New(i18n.T("credential store must be one of %s", strings.Join(CredentialStores, ", "))))
+	}
+
+	networks := map[string]bool{}
+	for i, n := range cfg.Networks {
+		field := fmt.Sprintf("networks[%d]", i)
+		switch {
+		case n.Name == "":
+			check(field+".name", errors.New(i18n.T("network has no name")))
+		case networks[n.Name]:
+			check(field+".name", errors.New(i18n.T("another network is called %s", n.Name)))
+		}
+		networks[n.Name] = true
+		if len(n.SSIDs) == 0 || slices.Contains(n.SSIDs, "") {
+			check(field+".ssids", errors.New(i18n.T("network needs the names of its Wi-Fi networks")))
+		}
+		if n.Profile != "" && cfg.FindProfile(n.Profile) == nil {
+			check(field+".profile", errors.New(i18n.T("no saved profile is called %s", n.Profile)))
+		}
+		for j, f := range n.Forwarders {
+			check(fmt.Sprintf("%s.forwarders[%d].domain", field, j), ValidateDomain(f.Domain))
+			check(fmt.Sprintf("%s.forwarders[%d].server", field, j), ValidateDNSServer(f.Server))
+		}
+		for _, key := range Policy(n.Settings).Keys() {
+			switch {
+			case policyFieldType(key) == nil:
+				check(field+".settings."+key, errors.New(i18n.T("unknown setting")))
+			case slices.Contains(networkSettingsExcluded, key):
+				check(field+".settings."+key, errors.New(i18n.T("cannot change by network")))
+			default:
+				check(field+".settings."+key, (Policy{key: n.Settings[key]}).apply(Default()))
+			}
+		}
+	}
+
+	entries := map[string]bool{}
+	for i, e := range cfg.Schedule {
+		field := fmt.Sprintf("schedule[%d]", i)
+		switch {
+		case e.Name == "":
+			check(field+".name", errors.New(i18n.T("schedule entry has no name")))
+		case entries[e.Name]:
+			check(field+".name", errors.New(i18n.T("another schedule entry is called %s", e.Name)))
+		}
+		entries[e.Name] = true
+		for j, day := range e.Days {
+			if !slices.Contains(ScheduleDayNames, strings.ToLower(day)) {
+				check(fmt.Sprintf("%s.days[%d]", field, j), errors.New(i18n.T("day must be one of %s", strings.Join(ScheduleDayNames, ", "))))
+			}
+		}
+		for _, clock := range []struct{ field, value string }{{"from", e.From}, {"until", e.Until}} {
+			if _, err := clockOn(time.Now(), clock.value); err != nil {
+				check(field+"."+clock.field, errors.New(i18n.T("time must be hours and minutes, e.g. 15:00, got %q", clock.value)))
+			}
+		}
+		switch {
+		case !slices.Contains(ScheduleActions, e.Action):
+			check(field+".action", errors.New(i18n.T("action must be one of %s", strings.Join(ScheduleActions, ", "))))
+		case e.Action == ScheduleProfile && cfg.FindProfile(e.Profile) == nil:
+			check(field+".profile", errors.New(i18n.T("no saved profile is called %s", e.Profile)))
+		}
+	}
+
+	negative := errors.New(i18n.T("must not be negative"))
+	if cfg.VerifyEvery < 0 {
+		check("verifyEvery", negative)
+	}
+	if cfg.CacheSize < 0 {
+		check("cacheSize", negative)
+	}
+	if r := cfg.StatsRetention; r != nil && (r.MinuteHours < 0 || r.HourlyDays < 0 || r.DailyDays < 0) {
+		check("statsRetention", negative)
+	}
+
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
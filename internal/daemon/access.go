@@ -0,0 +1,96 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+)
+
+// errNoPeerCredentials is returned where local sockets do not tell who
+// connected, as on Windows
+var errNoPeerCredentials = errors.New("peer credentials are not available on this platform")
+
+// trustedPeer reports whether the client on conn may read browsing
+// history, handle passwords and change protected settings: root, the
+// user the daemon runs as, or the owner it was installed for. Every
+// local user can reach the socket, so the kernel's record of who
+// connected decides. Remote clients proved themselves with the token.
+func (d *Daemon) trustedPeer(conn net.Conn, remote bool) bool {
+	if remote {
+		return true
+	}
+	uid, err := peerUID(conn)
+	if errors.Is(err, errNoPeerCredentials) {
+		// Only the socket's permissions guard it here
+		return true
+	}
+	if err != nil {
+		log.Printf("Failed to identify client: %v", err)
+		return false
+	}
+	if uid == 0 || uid == os.Geteuid() {
+		return true
+	}
+
+	d.mu.RLock()
+	owner := d.config.Owner
+	d.mu.RUnlock()
+	return owner != "" && ownerUID(owner) == uid
+}
+
+// ownerUID looks up the user id of the owner, -1 if there is no such user
+func ownerUID(owner string) int {
+	u, err := user.Lookup(owner)
+	if err != nil {
+		return -1
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return -1
+	}
+	return uid
+}
+
+// errForbidden is the error for a request only trusted clients may make
+// (must be called with lock held)
+func (d *Daemon) errForbidden(what string) error {
+	if d.config.Owner != "" {
+		return withCode(CodeForbidden, fmt.Errorf("%s needs root or %s", what, d.config.Owner))
+	}
+	return withCode(CodeForbidden, fmt.Errorf("%s needs root (set the owner with: sudo filterdns-client config set owner <user>)", what))
+}
+
+// forbidden answers a request only trusted clients may make
+func (d *Daemon) forbidden(what string) Response {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return errorResponse(d.errForbidden(what))
+}
+
+// protectedChanges lists the settings cfg changes that decide what the
+// daemon runs as root or who may read its history, by their config key
+func protectedChanges(old, cfg *config.Config) []string {
+	var changed []string
+	if cfg.UpdateURL != old.UpdateURL {
+		changed = append(changed, "update-url")
+	}
+	if cfg.AutoUpdate != old.AutoUpdate {
+		changed = append(changed, "auto-update")
+	}
+	if cfg.ServerCommands != old.ServerCommands {
+		changed = append(changed, "server-commands")
+	}
+	if cfg.Owner != old.Owner {
+		changed = append(changed, "owner")
+	}
+	if cfg.ControlAddr != old.ControlAddr {
+		changed = append(changed, "control-addr")
+	}
+	return changed
+}
@@ -0,0 +1,31 @@
+package daemon
+
+import "fmt"
+
+// handleCache answers "cache_stats", "cache_dump" and "cache_flush"; only
+// trusted clients may list the cached domains
+func (d *Daemon) handleCache(req Request, trusted bool) Response {
+	if req.Action == "cache_dump" && !trusted {
+		return d.forbidden("Listing cached domains")
+	}
+
+	d.mu.RLock()
+	proxy := d.proxy
+	d.mu.RUnlock()
+
+	if proxy == nil {
+		return Response{Success: false, Error: "filtering is not running, nothing is cached"}
+	}
+
+	switch req.Action {
+	case "cache_stats":
+		stats := proxy.CacheStats()
+		return Response{Success: true, Cache: &stats}
+	case "cache_dump":
+		return Response{Success: true, CacheEntries: proxy.CacheEntries(req.Domain)}
+	case "cache_flush":
+		return Response{Success: true, Flushed: proxy.FlushCache(req.Domain)}
+	default:
+		return Response{Success: false, Error: fmt.Sprintf("unknown cache action %q", req.Action)}
+	}
+}
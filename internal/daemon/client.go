@@ -4,30 +4,75 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/dns"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/update"
 )
 
 // Client communicates with the daemon
 type Client struct {
 	socketPath string
+
+	// A daemon on another machine to use instead of the local socket,
+	// and the streams open to the current one
+	mu       sync.Mutex
+	endpoint *Endpoint
+	streams  map[net.Conn]struct{}
 }
 
 // NewClient creates a new daemon client
 func NewClient() *Client {
-	return &Client{socketPath: SocketPath}
+	return &Client{socketPath: SocketPath, streams: make(map[net.Conn]struct{})}
 }
 
-// send sends a request to the daemon and returns the response
-func (c *Client) send(req Request) (*Response, error) {
+// SetEndpoint switches to the daemon at e, or back to the local one for
+// nil. Open subscriptions end, so their callers reconnect to the new one.
+func (c *Client) SetEndpoint(e *Endpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.endpoint = e
+	for conn := range c.streams {
+		conn.Close()
+	}
+}
+
+// Remote reports whether the client talks to a daemon on another machine
+func (c *Client) Remote() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.endpoint != nil
+}
+
+// dial connects to the current daemon, adding the token req needs there
+func (c *Client) dial(req *Request) (net.Conn, error) {
+	c.mu.Lock()
+	e := c.endpoint
+	c.mu.Unlock()
+
+	if e != nil {
+		req.Token = e.Token
+		return dialEndpoint(e)
+	}
 	conn, err := net.DialTimeout("unix", c.socketPath, 5*time.Second)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to daemon: %w (is it running?)", err)
 	}
+	return conn, nil
+}
+
+// send sends a request to the daemon and returns the response
+func (c *Client) send(req Request) (*Response, error) {
+	conn, err := c.dial(&req)
+	if err != nil {
+		return nil, err
+	}
 	defer conn.Close()
 
-	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	// Leave the daemon its full request timeout before giving up
+	conn.SetDeadline(time.Now().Add(requestTimeout + 5*time.Second))
 
 	encoder := json.NewEncoder(conn)
 	if err := encoder.Encode(req); err != nil {
@@ -67,7 +112,7 @@ func (c *Client) Enable() (*Status, error) {
 		return nil, err
 	}
 	if !resp.Success {
-		return nil, fmt.Errorf(resp.Error)
+		return nil, responseError(resp)
 	}
 	return resp.Status, nil
 }
@@ -78,12 +123,123 @@ func (c *Client) Disable() (*Status, error) {
 	if err != nil {
 		return nil, err
 	}
+	if !resp.Success {
+		return nil, responseError(resp)
+	}
+	return resp.Status, nil
+}
+
+// Pause stops DNS filtering for the given duration
+func (c *Client) Pause(duration time.Duration) (*Status, error) {
+	resp, err := c.send(Request{Action: "pause", Duration: duration.String()})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf(resp.Error)
+	}
+	return resp.Status, nil
+}
+
+// Resume ends a pause and restarts DNS filtering
+func (c *Client) Resume() (*Status, error) {
+	resp, err := c.send(Request{Action: "resume"})
+	if err != nil {
+		return nil, err
+	}
 	if !resp.Success {
 		return nil, fmt.Errorf(resp.Error)
 	}
 	return resp.Status, nil
 }
 
+// RestoreNetwork turns filtering off and undoes all changes to system
+// networking
+func (c *Client) RestoreNetwork() (*Status, error) {
+	resp, err := c.send(Request{Action: "restore_network"})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf(resp.Error)
+	}
+	return resp.Status, nil
+}
+
+// Sync makes the daemon fetch the profile state from the server now
+func (c *Client) Sync() (*Status, error) {
+	resp, err := c.send(Request{Action: "sync"})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf(resp.Error)
+	}
+	return resp.Status, nil
+}
+
+// SwitchProfile makes the saved profile with the given name active
+func (c *Client) SwitchProfile(name string) (*Status, error) {
+	resp, err := c.send(Request{Action: "switch_profile", Profile: name})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf(resp.Error)
+	}
+	return resp.Status, nil
+}
+
+// Verify checks that filtering is in effect end to end
+func (c *Client) Verify() (*Verification, error) {
+	resp, err := c.send(Request{Action: "verify"})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf(resp.Error)
+	}
+	return resp.Verification, nil
+}
+
+// Version returns the daemon's build version, which lags behind the
+// client's after an update until the service restarts
+func (c *Client) Version() (string, error) {
+	resp, err := c.send(Request{Action: "version"})
+	if err != nil {
+		return "", err
+	}
+	if !resp.Success {
+		return "", fmt.Errorf(resp.Error)
+	}
+	return resp.Version, nil
+}
+
+// CheckUpdate compares the daemon's build with the latest release
+func (c *Client) CheckUpdate() (*update.Update, error) {
+	resp, err := c.send(Request{Action: "check_update"})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf(resp.Error)
+	}
+	return resp.Update, nil
+}
+
+// Update has the daemon install the latest release if it is newer, and
+// restart into it shortly after answering
+func (c *Client) Update() (*update.Update, error) {
+	resp, err := c.send(Request{Action: "update"})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf(resp.Error)
+	}
+	return resp.Update, nil
+}
+
 // Status returns the current daemon status
 func (c *Client) Status() (*Status, error) {
 	resp, err := c.send(Request{Action: "status"})
@@ -119,3 +275,193 @@ func (c *Client) SetConfig(cfg *config.Config) error {
 	}
 	return nil
 }
+
+// Reload asks the daemon to re-read its configuration from disk
+func (c *Client) Reload() (*config.Config, error) {
+	resp, err := c.send(Request{Action: "reload"})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf(resp.Error)
+	}
+	return resp.Config, nil
+}
+
+// DebugBundle returns a .tar.gz with the daemon's config, logs, status
+// and resolver state for support tickets
+func (c *Client) DebugBundle() ([]byte, error) {
+	resp, err := c.send(Request{Action: "debug_bundle"})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf(resp.Error)
+	}
+	return resp.Bundle, nil
+}
+
+// Stats returns the counters kept across restarts, with daily history,
+// and the current session's cache, latency and top blocked domains. A
+// period (hour, day, week, month or year) adds its history.
+func (c *Client) Stats(period string) (*StatsReport, error) {
+	resp, err := c.send(Request{Action: "stats", Period: period})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf(resp.Error)
+	}
+	return resp.Stats, nil
+}
+
+// QueryLog returns the recent queries matching filter, oldest first
+func (c *Client) QueryLog(filter dns.QueryFilter) ([]dns.QueryLogEntry, error) {
+	resp, err := c.send(Request{Action: "query_log", QueryFilter: &filter})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf(resp.Error)
+	}
+	return resp.Queries, nil
+}
+
+// CacheStats returns the size of the proxy's cache and its hit counters
+func (c *Client) CacheStats() (*dns.CacheStats, error) {
+	resp, err := c.send(Request{Action: "cache_stats"})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf(resp.Error)
+	}
+	return resp.Cache, nil
+}
+
+// CacheDump returns the cached answers for domains containing domain, or
+// all of them if it is empty
+func (c *Client) CacheDump(domain string) ([]dns.CacheEntry, error) {
+	resp, err := c.send(Request{Action: "cache_dump", Domain: domain})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf(resp.Error)
+	}
+	return resp.CacheEntries, nil
+}
+
+// FlushCache drops the cached answers for domains containing domain, or
+// all of them if it is empty, and returns how many were dropped
+func (c *Client) FlushCache(domain string) (int, error) {
+	resp, err := c.send(Request{Action: "cache_flush", Domain: domain})
+	if err != nil {
+		return 0, err
+	}
+	if !resp.Success {
+		return 0, fmt.Errorf(resp.Error)
+	}
+	return resp.Flushed, nil
+}
+
+// SetPassword stores a profile password in the daemon's keyring, which a
+// daemon on another machine does not share with this one; an empty
+// password deletes it
+func (c *Client) SetPassword(profile, password string) error {
+	resp, err := c.send(Request{Action: "set_password", Profile: profile, Password: password})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// ControlInfo returns what a GUI on another machine needs to connect to
+// the daemon, if its remote control listener is on
+func (c *Client) ControlInfo() (*RemoteControl, error) {
+	resp, err := c.send(Request{Action: "control_info"})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, responseError(resp)
+	}
+	return resp.Control, nil
+}
+
+// Subscribe calls handler with the current filtering state and then with
+// every change, until the daemon goes away or handler returns false
+func (c *Client) Subscribe(handler func(StateEvent) bool) error {
+	conn, decoder, err := c.stream(Request{Action: "subscribe"})
+	if err != nil {
+		return err
+	}
+	defer c.endStream(conn)
+
+	for {
+		var event StateEvent
+		if err := decoder.Decode(&event); err != nil {
+			return fmt.Errorf("event feed closed: %w", err)
+		}
+		if event.State == "" {
+			return fmt.Errorf("subscription refused")
+		}
+		if !handler(event) {
+			return nil
+		}
+	}
+}
+
+// SubscribeQueries calls handler with the logged queries matching filter
+// and then with every new one that matches, until the daemon goes away or
+// handler returns false
+func (c *Client) SubscribeQueries(filter dns.QueryFilter, handler func(dns.QueryLogEntry) bool) error {
+	conn, decoder, err := c.stream(Request{Action: "subscribe_queries", QueryFilter: &filter})
+	if err != nil {
+		return err
+	}
+	defer c.endStream(conn)
+
+	for {
+		var entry dns.QueryLogEntry
+		if err := decoder.Decode(&entry); err != nil {
+			return fmt.Errorf("query feed closed: %w", err)
+		}
+		if entry.Seq == 0 {
+			return fmt.Errorf("subscription refused")
+		}
+		if !handler(entry) {
+			return nil
+		}
+	}
+}
+
+// stream sends a request whose answer is a stream of JSON lines. The
+// caller must hand the connection to endStream when done with it.
+func (c *Client) stream(req Request) (net.Conn, *json.Decoder, error) {
+	conn, err := c.dial(&req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	c.mu.Lock()
+	c.streams[conn] = struct{}{}
+	c.mu.Unlock()
+	return conn, json.NewDecoder(conn), nil
+}
+
+// endStream closes a connection from stream
+func (c *Client) endStream(conn net.Conn) {
+	c.mu.Lock()
+	delete(c.streams, conn)
+	c.mu.Unlock()
+	conn.Close()
+}
@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	filtersync "github.com/zkmkarlsruhe/filterdns-client/internal/sync"
+)
+
+// maxServerPause caps the pauses the server can ask for
+const maxServerPause = 24 * time.Hour
+
+// restartDelay leaves the syncer time to acknowledge an update command,
+// or a client time to read the answer to "update", before the daemon
+// shuts down to restart into the new release
+const restartDelay = 3 * time.Second
+
+// errCommandsOff answers commands while the config does not allow them
+var errCommandsOff = errors.New("commands from the server are off on this device (server-commands)")
+
+// onServerCommand checks and carries out a command an admin sent from
+// the web UI
+func (d *Daemon) onServerCommand(syncer *filtersync.Syncer, cmd filtersync.Command) error {
+	d.mu.RLock()
+	stale := d.syncer != syncer
+	allowed := d.config.ServerCommands
+	releaseURL := d.config.UpdateURL
+	proxy := d.proxy
+	d.mu.RUnlock()
+
+	if stale {
+		return errors.New("profile changed before the command arrived")
+	}
+	if !allowed {
+		return errCommandsOff
+	}
+	log.Printf("Running %s command %s from the server", cmd.Action, cmd.ID)
+
+	switch cmd.Action {
+	case filtersync.CommandFlush:
+		if proxy == nil {
+			return errors.New("filtering is not running, nothing is cached")
+		}
+		log.Printf("Flushed %d cached answers", proxy.FlushCache(""))
+		return nil
+
+	case filtersync.CommandPause:
+		duration := time.Duration(cmd.Minutes) * time.Minute
+		if duration <= 0 || duration > maxServerPause {
+			return fmt.Errorf("pause must be between 1 and %d minutes", int(maxServerPause.Minutes()))
+		}
+		return d.pause(duration)
+
+	case filtersync.CommandSwitchProfile:
+		// Only profiles saved on the device, so the server cannot point
+		// it at a profile or server it does not know
+		return d.switchProfile(cmd.Profile)
+
+	case filtersync.CommandUpdate:
+		_, installed, err := installUpdate(releaseURL)
+		if err != nil {
+			return err
+		}
+		if !installed {
+			return nil
+		}
+		d.mu.Lock()
+		d.updated = true
+		d.mu.Unlock()
+		time.AfterFunc(restartDelay, d.Shutdown)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q", cmd.Action)
+	}
+}
@@ -4,41 +4,190 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
 	"github.com/zkmkarlsruhe/filterdns-client/internal/dns"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/notify"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/privsep"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/stats"
+	filtersync "github.com/zkmkarlsruhe/filterdns-client/internal/sync"
 	"github.com/zkmkarlsruhe/filterdns-client/internal/system"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/update"
 )
 
 const SocketPath = "/var/run/filterdns.sock"
 
+// maxForgetDomains is how many changed domains the daemon drops from the
+// cache one by one; with more it is cheaper to flush it
+const maxForgetDomains = 500
+
+// syncInterval is how often the daemon polls the server for profile state
+// while the server does not push changes
+const syncInterval = 30 * time.Second
+
+const (
+	// readTimeout bounds how long a client may take to send its request
+	readTimeout = 5 * time.Second
+
+	// requestTimeout bounds how long a single command may run before it is
+	// cancelled and the connection is dropped
+	requestTimeout = 30 * time.Second
+
+	// maxConnections caps the number of clients served at the same time
+	maxConnections = 16
+
+	// maxRemoteConnections caps the remote control clients on their own,
+	// so unauthenticated ones cannot crowd out local clients
+	maxRemoteConnections = 4
+
+	// remoteAuthTimeout bounds how long a remote client may take for the
+	// TLS handshake and its request, before its token is checked
+	remoteAuthTimeout = 2 * time.Second
+
+	// queryLogSize is how many recent queries the daemon keeps for "log"
+	queryLogSize = 1000
+)
+
 // Request represents a command from the client
 type Request struct {
-	Action string         `json:"action"`
-	Config *config.Config `json:"config,omitempty"`
+	Action   string         `json:"action"`
+	Config   *config.Config `json:"config,omitempty"`
+	Duration string         `json:"duration,omitempty"` // For "pause", e.g. "15m"
+	Profile  string         `json:"profile,omitempty"`  // For "switch_profile", a saved profile name
+
+	// For "stats", a history period: hour, day, week, month or year
+	Period string `json:"period,omitempty"`
+
+	// For "query_log" and "subscribe_queries", which entries to return
+	QueryFilter *dns.QueryFilter `json:"queryFilter,omitempty"`
+
+	// For "cache_dump" and "cache_flush", only domains containing this
+	Domain string `json:"domain,omitempty"`
+
+	// For "set_password", the password to keep for Profile; empty deletes it
+	Password string `json:"password,omitempty"`
+
+	// Required on the remote control listener, see RemoteControl
+	Token string `json:"token,omitempty"`
 }
 
 // Response represents the daemon's response
 type Response struct {
 	Success bool           `json:"success"`
 	Error   string         `json:"error,omitempty"`
+	Code    string         `json:"code,omitempty"` // One of the Code constants, for some failures
 	Status  *Status        `json:"status,omitempty"`
 	Config  *config.Config `json:"config,omitempty"`
+	Bundle  []byte         `json:"bundle,omitempty"` // For "debug_bundle", a .tar.gz
+	Stats   *StatsReport   `json:"stats,omitempty"`  // For "stats", including daily history
+
+	// For "control_info", how to reach the daemon from another machine
+	Control *RemoteControl `json:"control,omitempty"`
+
+	// For "query_log", oldest first
+	Queries []dns.QueryLogEntry `json:"queries,omitempty"`
+
+	Cache        *dns.CacheStats  `json:"cache,omitempty"`        // For "cache_stats"
+	CacheEntries []dns.CacheEntry `json:"cacheEntries,omitempty"` // For "cache_dump"
+	Flushed      int              `json:"flushed,omitempty"`      // For "cache_flush", entries dropped
+
+	Verification *Verification `json:"verification,omitempty"` // For "verify"
+
+	Version string         `json:"version,omitempty"` // For "version", the daemon's build
+	Update  *update.Update `json:"update,omitempty"`  // For "check_update" and "update"
 }
 
 // Status represents the current daemon status
 type Status struct {
-	Running        bool   `json:"running"`
-	Profile        string `json:"profile"`
-	ServerURL      string `json:"serverUrl"`
-	QueriesTotal   int64  `json:"queriesTotal"`
-	QueriesBlocked int64  `json:"queriesBlocked"`
+	Running        bool       `json:"running"`
+	Profile        string     `json:"profile"`
+	ServerURL      string     `json:"serverUrl"`
+	PausedUntil    *time.Time `json:"pausedUntil,omitempty"`
+	QueriesTotal   int64      `json:"queriesTotal"`
+	QueriesBlocked int64      `json:"queriesBlocked"`
+	CacheHits      int64      `json:"cacheHits"`
+	StartedAt      time.Time  `json:"startedAt"` // When the daemon started
+
+	// Search-domain expansions answered locally, if the shortcut is on
+	SearchShortcuts int64 `json:"searchShortcuts,omitempty"`
+
+	// Firewall rules block DNS that bypasses the proxy
+	OutsideDNSBlocked bool `json:"outsideDnsBlocked,omitempty"`
+
+	// VPNs whose DNS does not get along with the proxy
+	VPNConflicts []VPNConflict `json:"vpnConflicts,omitempty"`
+
+	// Daemon resource usage
+	Goroutines  int    `json:"goroutines"`
+	MemoryBytes uint64 `json:"memoryBytes"`
+
+	// Last upstream connectivity probe, if any
+	Connectivity *dns.Connectivity `json:"connectivity,omitempty"`
+
+	// Profile state on the server, if syncing is active
+	Sync *SyncStatus `json:"sync,omitempty"`
+
+	// Server state of each saved profile, the active one included
+	Profiles []ProfileState `json:"profiles,omitempty"`
+
+	// Last self-test result, if one ran
+	Health *Health `json:"health,omitempty"`
+
+	// Counters kept across restarts: today, last 7 days and total
+	Stats *stats.Summary `json:"stats,omitempty"`
+
+	// Answers re-checked over the pinned connection, if integrity mode is on
+	Integrity *dns.Integrity `json:"integrity,omitempty"`
+
+	// Settings the administrator's policy locks, by their name in config.json
+	Locked []string `json:"locked,omitempty"`
+
+	// Wi-Fi networks connected to, and the network overlay applied for them
+	WiFi    []string `json:"wifi,omitempty"`
+	Network string   `json:"network,omitempty"`
+
+	// Schedule entry in effect, and when its time range ends
+	Schedule      *config.ScheduleEntry `json:"schedule,omitempty"`
+	ScheduleUntil *time.Time            `json:"scheduleUntil,omitempty"`
+}
+
+// SyncStatus reports the server-side state picked up by the syncer
+type SyncStatus struct {
+	FilteringEnabled bool       `json:"filteringEnabled"`
+	PausedUntil      *time.Time `json:"pausedUntil,omitempty"`
+	LastSync         time.Time  `json:"lastSync"`
+	Error            string     `json:"error,omitempty"`
+	ServerVersion    string     `json:"serverVersion,omitempty"` // As of the last successful sync
+	Push             bool       `json:"push,omitempty"`          // Changes arrive as the server pushes them
+	Maintenance      bool       `json:"maintenance,omitempty"`   // The server has the profile in maintenance mode
+
+	// Identifies the profile's block lists as last synced, see
+	// filtersync.SyncResponse.BlocklistVersion
+	BlocklistVersion string `json:"blocklistVersion,omitempty"`
+
+	// While syncs fail: the last one that worked, how many failed since,
+	// whether the network looked down and when the next try is due
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	Failures    int        `json:"failures,omitempty"`
+	Offline     bool       `json:"offline,omitempty"`
+	NextSync    *time.Time `json:"nextSync,omitempty"`
+
+	// Local clock ahead of the server's at the last sync, negative if
+	// behind
+	ClockSkew time.Duration `json:"clockSkew,omitempty"`
 }
 
 // Daemon is the background service that handles DNS filtering
@@ -50,10 +199,95 @@ type Daemon struct {
 	mu       sync.RWMutex
 	ctx      context.Context
 	cancel   context.CancelFunc
+
+	// Pending automatic resume after a pause
+	pauseTimer *time.Timer
+
+	// Firewall rules block DNS that bypasses the proxy
+	outsideDNSBlocked bool
+
+	// Server-side profile state; while the server has filtering paused
+	// the proxy stays down regardless of the local enabled flag
+	syncer            *filtersync.Syncer
+	serverPaused      bool
+	serverPausedUntil *time.Time
+
+	// State-only syncers for the saved profiles not in use, by name
+	profileSyncers map[string]*profileSyncer
+
+	// Result of the periodic self-test
+	health Health
+
+	// VPNs getting in the way of the proxy, and when each took over
+	// system DNS lately
+	vpnConflicts []VPNConflict
+	vpnTakeovers map[string][]time.Time
+
+	// Rate-limits repeated problem reports in the log
+	notifier *notify.Notifier
+
+	// Unprivileged user to switch to after startup, and the root helper
+	// that changes system DNS and binds port 53 on its behalf
+	user   string
+	helper *privsep.Helper
+
+	// Control interface on the system D-Bus (Linux only)
+	bus io.Closer
+
+	// State change feed for "subscribe" connections, and the last
+	// state announced
+	events    eventFeed
+	lastEvent *StateEvent
+
+	// Whether the proxy found the upstream server unreachable
+	upstreamDown bool
+
+	// How far the local clock is off from the server's, while beyond
+	// filtersync.MaxClockSkew
+	clockSkew time.Duration
+
+	// Semaphores limiting concurrently served connections, local and
+	// over the remote control listener
+	conns       chan struct{}
+	remoteConns chan struct{}
+
+	// Persistent counters, and the proxy counts already added to them
+	stats      *stats.Store
+	statsProxy *dns.Proxy
+	statsBase  stats.Counts
+
+	// Recent log output for debug bundles, and the opt-in pprof listener
+	logs      *logRing
+	debugAddr string
+
+	// Recent queries, kept across proxy restarts
+	queryLog *dns.QueryLog
+
+	// Set once an automatic update replaced the binary
+	updated bool
+
+	// TLS listener for GUIs on other machines, if ControlAddr is set
+	control         *RemoteControl
+	controlListener net.Listener
+
+	// Wi-Fi networks last seen, the overlay of settings applied for them,
+	// and the timer letting network changes settle before looking again
+	ssids        []string
+	network      *config.NetworkOverlay
+	networkTimer *time.Timer
+
+	// Schedule entry in effect and when its time range ends, and whether
+	// it turned filtering on
+	schedule         *config.ScheduleEntry
+	scheduleEnd      time.Time
+	scheduleTurnedOn bool
+
+	started time.Time
 }
 
 // New creates a new daemon instance
 func New() *Daemon {
+	useSystemConfig()
 	cfg, err := config.Load()
 	if err != nil {
 		cfg = config.Default()
@@ -65,13 +299,83 @@ func New() *Daemon {
 		config: cfg,
 		ctx:    ctx,
 		cancel: cancel,
+		notifier: notify.New(func(title, message string) {
+			log.Printf("%s: %s", title, message)
+		}, 10*time.Minute, 2),
+		conns:       make(chan struct{}, maxConnections),
+		remoteConns: make(chan struct{}, maxRemoteConnections),
+		logs:        &logRing{},
+		queryLog:    dns.NewQueryLog(queryLogSize),
+		started:     time.Now(),
+	}
+}
+
+// SetUser makes Run drop root and continue as the given user once its
+// sockets are bound. System DNS changes then go through a root helper.
+func (d *Daemon) SetUser(username string) {
+	d.user = username
+}
+
+// dropPrivileges starts the root helper, hands the machine config
+// directory to d.user so the daemon can still save its config, and
+// switches to d.user
+func (d *Daemon) dropPrivileges() error {
+	uid, gid, err := privsep.LookupUser(d.user)
+	if err != nil {
+		return fmt.Errorf("unknown user %s: %w", d.user, err)
 	}
+
+	helper, err := privsep.StartHelper()
+	if err != nil {
+		return err
+	}
+
+	path, err := config.Path()
+	if err != nil {
+		helper.Close()
+		return fmt.Errorf("failed to prepare config directory: %w", err)
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		config.Save(d.config)
+	}
+	dir := filepath.Dir(path)
+	os.Chown(dir, uid, gid)
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			os.Chown(filepath.Join(dir, entry.Name()), uid, gid)
+		}
+	}
+
+	if err := privsep.DropPrivileges(d.user); err != nil {
+		helper.Close()
+		return fmt.Errorf("failed to drop privileges: %w", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Printf("Warning: failed to load %s: %v", path, err)
+	} else {
+		d.config = cfg
+	}
+
+	d.helper = helper
+	log.Printf("Dropped privileges, running as %s", d.user)
+	return nil
 }
 
 // Run starts the daemon
 func (d *Daemon) Run() error {
+	log.SetOutput(config.RedactingWriter(io.MultiWriter(os.Stderr, d.logs)))
 	log.Println("Starting FilterDNS daemon...")
 
+	// Finish a DNS change that a crash interrupted half-way
+	if journal, err := system.RecoverInterruptedOperation(); err != nil {
+		log.Printf("Warning: recovering interrupted DNS %s failed: %v", journal.Operation, err)
+	} else if journal != nil {
+		log.Printf("Recovered from DNS %s interrupted at %s - original settings restored",
+			journal.Operation, journal.StartedAt.Format(time.RFC3339))
+	}
+
 	// Check for crash recovery - restore DNS if we crashed while DNS was modified
 	if err := system.RestoreFromBackupIfNeeded(); err != nil {
 		log.Printf("Warning: crash recovery failed: %v", err)
@@ -79,6 +383,16 @@ func (d *Daemon) Run() error {
 		log.Println("Recovered from previous crash - DNS settings restored")
 	}
 
+	// Firewall rules a crash left behind would keep blocking DNS; they
+	// are added again once filtering is back on
+	if system.OutsideDNSBlocked() {
+		if err := system.UnblockOutsideDNS(); err != nil {
+			log.Printf("Warning: removing leftover DNS firewall rules failed: %v", err)
+		} else {
+			log.Println("Removed DNS firewall rules left by the previous run")
+		}
+	}
+
 	// Remove old socket if exists
 	os.Remove(SocketPath)
 
@@ -96,22 +410,80 @@ func (d *Daemon) Run() error {
 
 	log.Printf("Listening on %s", SocketPath)
 
-	// Auto-start DNS if was enabled
+	if bus, err := d.startDBus(); err != nil {
+		log.Printf("Warning: D-Bus control interface unavailable: %v", err)
+	} else {
+		d.bus = bus
+	}
+
+	if d.debugAddr != "" {
+		if err := d.startDebugListener(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	if d.user != "" {
+		if err := d.dropPrivileges(); err != nil {
+			listener.Close()
+			return err
+		}
+	}
+
+	// After dropping privileges, so the credentials land in the config
+	// directory the daemon keeps
+	if d.config.ControlAddr != "" {
+		if err := d.startControlListener(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	d.mu.Lock()
+	d.startSync()
+	d.syncProfiles()
+	d.mu.Unlock()
+
+	d.openStats()
+
+	go d.runHealthChecks()
+	go d.runStatsFlush()
+	go d.runAutoUpdate()
+	d.checkNetwork()
+	go d.watchNetwork()
+	go d.watchConfig()
+
+	// Auto-start DNS if was enabled, unless a pause is still in effect
 	if d.config.Enabled && d.config.Profile != "" {
-		log.Println("Auto-starting DNS filtering (was enabled)...")
-		if err := d.enable(); err != nil {
-			log.Printf("Warning: auto-start failed: %v", err)
+		if until := d.config.PausedUntil; until != nil && time.Now().Before(*until) {
+			log.Printf("Filtering paused until %s, will resume then", until.Format(time.RFC3339))
+			d.mu.Lock()
+			d.scheduleResume(*until)
+			d.mu.Unlock()
+		} else {
+			log.Println("Auto-starting DNS filtering (was enabled)...")
+			if err := d.enable(d.ctx); err != nil {
+				log.Printf("Warning: auto-start failed: %v", err)
+			}
 		}
 	}
+	go d.runSchedule()
 
-	// Handle shutdown
+	// Handle shutdown and reload
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	go func() {
-		<-sigChan
-		log.Println("Shutting down daemon...")
-		d.Shutdown()
+		for sig := range sigChan {
+			if sig == syscall.SIGHUP {
+				log.Println("Received SIGHUP, reloading config...")
+				if err := d.reload(); err != nil {
+					log.Printf("Reload failed: %v", err)
+				}
+				continue
+			}
+			log.Println("Shutting down daemon...")
+			d.Shutdown()
+			return
+		}
 	}()
 
 	// Accept connections
@@ -120,13 +492,30 @@ func (d *Daemon) Run() error {
 		if err != nil {
 			select {
 			case <-d.ctx.Done():
+				d.mu.RLock()
+				defer d.mu.RUnlock()
+				if d.updated {
+					return ErrRestart
+				}
 				return nil
 			default:
 				log.Printf("Accept error: %v", err)
 				continue
 			}
 		}
-		go d.handleConnection(conn)
+
+		select {
+		case d.conns <- struct{}{}:
+			go func() {
+				defer func() { <-d.conns }()
+				d.handleConnection(conn, false)
+			}()
+		default:
+			log.Printf("Rejecting connection: %d clients already connected", maxConnections)
+			conn.SetDeadline(time.Now().Add(time.Second))
+			json.NewEncoder(conn).Encode(Response{Success: false, Error: "daemon busy, try again"})
+			conn.Close()
+		}
 	}
 }
 
@@ -134,45 +523,146 @@ func (d *Daemon) Run() error {
 func (d *Daemon) Shutdown() {
 	d.cancel()
 
+	d.mu.Lock()
+	if d.pauseTimer != nil {
+		d.pauseTimer.Stop()
+		d.pauseTimer = nil
+	}
+	if d.networkTimer != nil {
+		d.networkTimer.Stop()
+	}
+	d.stopSync()
+	d.stopProfileSyncs()
+	d.mu.Unlock()
+
+	d.flushStats()
+
+	// Stop filtering without going through disable: a locked policy must
+	// not keep the machine on a dead proxy, and the saved state stays
+	// enabled so filtering resumes when the daemon starts again
+	d.mu.Lock()
 	if d.running {
-		d.disable()
+		d.stopFiltering()
 	}
+	d.mu.Unlock()
 
 	if d.listener != nil {
 		d.listener.Close()
 	}
 
+	if d.controlListener != nil {
+		d.controlListener.Close()
+	}
+
+	if d.bus != nil {
+		d.bus.Close()
+	}
+
+	if d.helper != nil {
+		d.helper.Close()
+	}
+
 	os.Remove(SocketPath)
 	log.Println("Daemon stopped")
 }
 
-// handleConnection processes a client connection
-func (d *Daemon) handleConnection(conn net.Conn) {
+// handleConnection processes a client connection; remote ones come from
+// the control listener and must carry its token
+func (d *Daemon) handleConnection(conn net.Conn, remote bool) {
 	defer conn.Close()
 
 	decoder := json.NewDecoder(conn)
 	encoder := json.NewEncoder(conn)
 
+	// A client that never sends its request must not hold the goroutine
+	if remote {
+		conn.SetDeadline(time.Now().Add(remoteAuthTimeout))
+	} else {
+		conn.SetDeadline(time.Now().Add(readTimeout))
+	}
+
 	var req Request
 	if err := decoder.Decode(&req); err != nil {
 		encoder.Encode(Response{Success: false, Error: err.Error()})
 		return
 	}
+	if remote && !d.authorized(req) {
+		log.Printf("Rejected remote command from %s: wrong token", conn.RemoteAddr())
+		rejectRemote(encoder)
+		return
+	}
 
 	log.Printf("Received command: %s", req.Action)
+	trusted := d.trustedPeer(conn, remote)
+
+	switch req.Action {
+	case "subscribe":
+		d.serveSubscription(conn)
+		return
+	case "subscribe_queries":
+		if !trusted {
+			encoder.Encode(d.forbidden("Following queries"))
+			return
+		}
+		var filter dns.QueryFilter
+		if req.QueryFilter != nil {
+			filter = *req.QueryFilter
+		}
+		d.serveQuerySubscription(conn, filter)
+		return
+	}
+
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+	ctx, cancel := context.WithTimeout(d.ctx, requestTimeout)
+	defer cancel()
+
+	// Clients send a single request and then wait, so a read returning
+	// means they hung up and anything still running for them can stop
+	go func() {
+		var buf [1]byte
+		conn.Read(buf[:])
+		cancel()
+	}()
 
 	var resp Response
 
 	switch req.Action {
 	case "enable":
-		if err := d.enable(); err != nil {
-			resp = Response{Success: false, Error: err.Error()}
+		if err := d.enable(ctx); err != nil {
+			resp = errorResponse(err)
 		} else {
 			resp = Response{Success: true, Status: d.getStatus()}
 		}
 
 	case "disable":
 		if err := d.disable(); err != nil {
+			resp = errorResponse(err)
+		} else {
+			resp = Response{Success: true, Status: d.getStatus()}
+		}
+
+	case "verify":
+		resp = Response{Success: true, Verification: d.verify(ctx)}
+
+	case "pause":
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			resp = Response{Success: false, Error: fmt.Sprintf("invalid duration: %v", err)}
+		} else if err := d.pause(duration); err != nil {
+			resp = Response{Success: false, Error: err.Error()}
+		} else {
+			resp = Response{Success: true, Status: d.getStatus()}
+		}
+
+	case "restore_network":
+		if err := d.restoreNetwork(); err != nil {
+			resp = Response{Success: false, Error: err.Error()}
+		} else {
+			resp = Response{Success: true, Status: d.getStatus()}
+		}
+
+	case "resume":
+		if err := d.resume(); err != nil {
 			resp = Response{Success: false, Error: err.Error()}
 		} else {
 			resp = Response{Success: true, Status: d.getStatus()}
@@ -186,8 +676,8 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 
 	case "set_config":
 		if req.Config != nil {
-			if err := d.setConfig(req.Config); err != nil {
-				resp = Response{Success: false, Error: err.Error()}
+			if err := d.setConfig(req.Config, trusted); err != nil {
+				resp = errorResponse(err)
 			} else {
 				resp = Response{Success: true, Config: d.config}
 			}
@@ -195,9 +685,105 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 			resp = Response{Success: false, Error: "no config provided"}
 		}
 
+	case "switch_profile":
+		if err := d.switchProfile(req.Profile); err != nil {
+			resp = Response{Success: false, Error: err.Error()}
+		} else {
+			resp = Response{Success: true, Status: d.getStatus()}
+		}
+
+	case "sync":
+		if err := d.syncNow(); err != nil {
+			resp = Response{Success: false, Error: err.Error()}
+		} else {
+			resp = Response{Success: true, Status: d.getStatus()}
+		}
+
+	case "reload":
+		if err := d.reload(); err != nil {
+			resp = Response{Success: false, Error: err.Error()}
+		} else {
+			resp = Response{Success: true, Config: d.config}
+		}
+
+	case "set_password":
+		if !trusted {
+			resp = d.forbidden("Storing passwords")
+		} else if err := setPassword(req.Profile, req.Password); err != nil {
+			resp = Response{Success: false, Error: err.Error()}
+		} else {
+			d.mu.RLock()
+			if d.proxy != nil {
+				d.proxy.RefreshPassword()
+			}
+			d.mu.RUnlock()
+			resp = Response{Success: true}
+		}
+
+	case "control_info":
+		if !trusted {
+			resp = d.forbidden("Showing the remote control token")
+		} else if d.control == nil {
+			resp = Response{Success: false, Error: "remote control is off; set control-addr and restart the daemon"}
+		} else {
+			resp = Response{Success: true, Control: d.control}
+		}
+
+	case "debug_bundle":
+		if !trusted {
+			resp = d.forbidden("Collecting a debug bundle")
+		} else if bundle, err := d.debugBundle(); err != nil {
+			resp = Response{Success: false, Error: err.Error()}
+		} else {
+			resp = Response{Success: true, Bundle: bundle}
+		}
+
+	case "stats":
+		d.mu.RLock()
+		report, err := d.statsReport(req.Period)
+		d.mu.RUnlock()
+		if err != nil {
+			resp = Response{Success: false, Error: err.Error()}
+		} else {
+			resp = Response{Success: true, Stats: report}
+		}
+
+	case "query_log":
+		var filter dns.QueryFilter
+		if req.QueryFilter != nil {
+			filter = *req.QueryFilter
+		}
+		if !trusted {
+			resp = d.forbidden("Reading the query log")
+		} else {
+			resp = Response{Success: true, Queries: d.queryLog.Entries(filter)}
+		}
+
+	case "cache_stats", "cache_dump", "cache_flush":
+		resp = d.handleCache(req, trusted)
+
 	case "ping":
 		resp = Response{Success: true}
 
+	case "version":
+		resp = Response{Success: true, Version: update.Version}
+
+	case "check_update":
+		if u, err := d.checkUpdate(); err != nil {
+			resp = errorResponse(err)
+		} else {
+			resp = Response{Success: true, Update: u}
+		}
+
+	case "update":
+		if !trusted {
+			resp = d.forbidden("Updating the daemon")
+		} else if u, err := d.updateNow(); err != nil {
+			resp = errorResponse(err)
+		} else {
+			resp = Response{Success: true, Update: u}
+		}
+
 	default:
 		resp = Response{Success: false, Error: "unknown action"}
 	}
@@ -205,112 +791,926 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 	encoder.Encode(resp)
 }
 
-// enable starts DNS filtering
-func (d *Daemon) enable() error {
+// enable starts DNS filtering. Creating the proxy resolves the server
+// through bootstrap DNS, which can take a while on a bad network, so it
+// happens before taking the lock; status requests are not blocked by it
+// and the whole operation gives up when ctx is done.
+func (d *Daemon) enable(ctx context.Context) error {
+	d.mu.RLock()
+	cfg := d.config
+	needProxy := !d.running && cfg.Profile != "" && !d.serverPaused
+	d.mu.RUnlock()
+
+	var proxy *dns.Proxy
+	if needProxy {
+		var err error
+		if proxy, err = newProxy(ctx, cfg.Effective()); err != nil {
+			return fmt.Errorf("enable cancelled: %w", err)
+		}
+	}
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	defer d.publishState()
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("enable cancelled: %w", err)
+	}
+
+	d.clearPause()
 
 	if d.running {
 		return nil
 	}
 
-	if d.config.Profile == "" {
-		return fmt.Errorf("no profile configured")
+	// The config changed while the proxy was being created
+	if d.config != cfg {
+		proxy = nil
 	}
 
-	log.Printf("Enabling DNS filtering for profile: %s", d.config.Profile)
-
-	// Create and start proxy
-	d.proxy = dns.NewProxy(d.config)
-
-	go func() {
-		if err := d.proxy.Start(); err != nil {
-			log.Printf("DNS proxy error: %v", err)
-		}
-	}()
-
-	// Configure system DNS
-	if err := system.SetDNS("127.0.0.1"); err != nil {
-		d.proxy.Stop()
-		d.proxy = nil
-		return fmt.Errorf("failed to set system DNS: %w", err)
+	if err := d.startFiltering(proxy); err != nil {
+		return err
 	}
 
-	d.running = true
 	d.config.Enabled = true
 	config.Save(d.config)
-
-	log.Println("DNS filtering enabled")
 	return nil
 }
 
 // disable stops DNS filtering
 func (d *Daemon) disable() error {
+	if config.CurrentPolicy().Locked("enabled") {
+		return fmt.Errorf("filtering cannot be turned off: %w", config.ErrLocked)
+	}
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	defer d.publishState()
+
+	wasPaused := d.config.PausedUntil != nil
+	d.clearPause()
 
 	if !d.running {
+		if wasPaused {
+			d.config.Enabled = false
+			config.Save(d.config)
+		}
 		return nil
 	}
 
-	log.Println("Disabling DNS filtering...")
-
-	if d.proxy != nil {
-		d.proxy.Stop()
-		d.proxy = nil
-	}
-
-	system.ResetDNS()
+	d.stopFiltering()
 
-	d.running = false
 	d.config.Enabled = false
 	config.Save(d.config)
-
-	log.Println("DNS filtering disabled")
 	return nil
 }
 
-// setConfig updates the configuration
-func (d *Daemon) setConfig(cfg *config.Config) error {
+// restoreNetwork is the panic button: it turns filtering off and undoes
+// every change to system networking, including ones no backup recorded
+func (d *Daemon) restoreNetwork() error {
+	if config.CurrentPolicy().Locked("enabled") {
+		return fmt.Errorf("filtering cannot be turned off: %w", config.ErrLocked)
+	}
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	defer d.publishState()
 
-	needsRestart := d.running && (cfg.Profile != d.config.Profile || cfg.ServerURL != d.config.ServerURL)
+	log.Println("Restoring original network settings...")
 
-	d.config = cfg
-	if err := config.Save(cfg); err != nil {
-		return err
+	d.clearPause()
+	if d.running || d.proxy != nil {
+		d.stopFiltering()
 	}
+	d.config.Enabled = false
+	config.Save(d.config)
 
-	if needsRestart {
-		log.Println("Config changed, restarting proxy...")
-		if d.proxy != nil {
-			d.proxy.Stop()
-		}
-		d.proxy = dns.NewProxy(d.config)
-		go d.proxy.Start()
-	} else if d.proxy != nil {
-		// Just update forwarders
-		d.proxy.UpdateForwarders(cfg.Forwarders)
+	if d.helper != nil {
+		return d.helper.RestoreNetwork()
 	}
-
-	return nil
+	return system.RestoreNetwork()
 }
 
-// getStatus returns the current status
-func (d *Daemon) getStatus() *Status {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
+// pause stops DNS filtering until the given duration has elapsed.
+// The deadline is persisted so the pause survives daemon restarts.
+func (d *Daemon) pause(duration time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	defer d.publishState()
 
-	status := &Status{
-		Running:   d.running,
-		Profile:   d.config.Profile,
-		ServerURL: d.config.ServerURL,
+	if duration <= 0 {
+		return fmt.Errorf("pause duration must be positive")
+	}
+	if config.CurrentPolicy().Locked("enabled") {
+		return fmt.Errorf("filtering cannot be paused: %w", config.ErrLocked)
 	}
 
-	if d.proxy != nil {
+	if d.config.Profile == "" {
+		return fmt.Errorf("no profile configured")
+	}
+
+	d.clearPause()
+
+	if d.running {
+		d.stopFiltering()
+	}
+
+	until := time.Now().Add(duration)
+	d.config.PausedUntil = &until
+	d.config.Enabled = true
+	config.Save(d.config)
+
+	d.scheduleResume(until)
+	log.Printf("DNS filtering paused until %s", until.Format(time.RFC3339))
+	return nil
+}
+
+// resume ends a pause and restarts DNS filtering
+func (d *Daemon) resume() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	defer d.publishState()
+
+	return d.endPause()
+}
+
+// endPause ends a pause and restarts DNS filtering (must be called with
+// lock held)
+func (d *Daemon) endPause() error {
+	d.clearPause()
+
+	if !d.running {
+		if err := d.startFiltering(nil); err != nil {
+			config.Save(d.config)
+			return err
+		}
+	}
+
+	d.config.Enabled = true
+	config.Save(d.config)
+	return nil
+}
+
+// scheduleResume arms the timer that ends a pause (must be called with lock held)
+func (d *Daemon) scheduleResume(until time.Time) {
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(until), func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		// Stop does not catch a timer that already fired and is waiting
+		// for the lock, so a pause cleared or renewed meanwhile must not
+		// end early
+		if d.pauseTimer != timer {
+			return
+		}
+		defer d.publishState()
+
+		log.Println("Pause expired, resuming DNS filtering...")
+		if err := d.endPause(); err != nil {
+			log.Printf("Warning: resume after pause failed: %v", err)
+		}
+	})
+	d.pauseTimer = timer
+}
+
+// clearPause cancels a pending resume and forgets the pause deadline
+// (must be called with lock held)
+func (d *Daemon) clearPause() {
+	if d.pauseTimer != nil {
+		d.pauseTimer.Stop()
+		d.pauseTimer = nil
+	}
+	d.config.PausedUntil = nil
+}
+
+// newProxy creates a proxy for cfg, giving up when ctx is done. The proxy
+// is not started yet, so an abandoned one needs no cleanup.
+func newProxy(ctx context.Context, cfg *config.Config) (*dns.Proxy, error) {
+	created := make(chan *dns.Proxy, 1)
+	go func() {
+		created <- dns.NewProxy(cfg)
+	}()
+
+	select {
+	case proxy := <-created:
+		return proxy, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// startFiltering starts the proxy and points system DNS at it. proxy may
+// be a proxy already created for the current config, or nil.
+// (must be called with lock held)
+func (d *Daemon) startFiltering(proxy *dns.Proxy) error {
+	if d.config.Profile == "" {
+		return withCode(CodeNoProfile, fmt.Errorf("no profile configured"))
+	}
+
+	if d.serverPaused {
+		log.Println("Filtering is paused on the server, leaving system DNS untouched")
+		return nil
+	}
+
+	log.Printf("Enabling DNS filtering for profile: %s", d.config.Profile)
+
+	// Another DNS server on the loopback makes binding fail with a bare
+	// "address already in use"; name it and what to do instead
+	if err := d.checkResolverConflicts(); err != nil {
+		return withCode(CodePortInUse, err)
+	}
+
+	// Create and start proxy
+	if err := d.startProxy(proxy); err != nil {
+		return withCode(CodeProxyFailed, err)
+	}
+
+	// Configure system DNS
+	if err := d.setSystemDNS(); err != nil {
+		d.proxy.Stop()
+		d.proxy = nil
+		return withCode(CodeDNSFailed, fmt.Errorf("failed to set system DNS: %w", err))
+	}
+
+	d.running = true
+
+	if d.config.BlockOutsideDNS {
+		d.blockOutsideDNS()
+	}
+
+	log.Println("DNS filtering enabled")
+	return nil
+}
+
+// checkResolverConflicts returns an error naming the DNS server that
+// already listens on the proxy's IPv4 loopback address, if one does, and
+// logs those on the other addresses, which the proxy can do without
+// (must be called with lock held)
+func (d *Daemon) checkResolverConflicts() error {
+	addrs := d.config.LoopbackAddrs()
+	conflicts, err := system.ResolverConflicts(addrs[:1])
+	if err != nil {
+		log.Printf("Could not check for other DNS servers: %v", err)
+		return nil
+	}
+	if len(conflicts) > 0 {
+		c := conflicts[0]
+		return fmt.Errorf("%s. %s Run 'filterdns-client dns conflicts' for details", c, c.Remedy)
+	}
+
+	others, _ := system.ResolverConflicts(addrs[1:])
+	for _, c := range others {
+		log.Printf("Warning: %s. %s", c, c.Remedy)
+	}
+	return nil
+}
+
+// startProxy starts proxy, or a new one for the current config if it is
+// nil, on sockets bound by the privileged helper if root was dropped
+// (must be called with lock held)
+func (d *Daemon) startProxy(proxy *dns.Proxy) error {
+	if proxy == nil {
+		proxy = dns.NewProxy(d.config.Effective())
+	}
+	proxy.SetQueryLog(d.queryLog)
+	proxy.SetUpstreamListener(func(down bool) { d.upstreamChanged(proxy, down) })
+
+	var err error
+	if d.helper != nil {
+		err = proxy.StartWith(d.helper.BindDNS)
+	} else {
+		err = proxy.Start()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start DNS proxy: %w", err)
+	}
+
+	d.proxy = proxy
+	d.upstreamDown = false
+	return nil
+}
+
+// setSystemDNS points system DNS at the proxy on the managed interfaces
+// (must be called with lock held)
+func (d *Daemon) setSystemDNS() error {
+	servers := []string{"127.0.0.1"}
+	if d.proxy != nil {
+		// Only the addresses the proxy could actually bind
+		servers = d.proxy.Addresses()
+	}
+
+	// VPNs in a stalemate keep their DNS until the user settles it
+	policy := *d.config
+	policy.IgnoredInterfaces = slices.Concat(d.config.IgnoredInterfaces, d.vpnStalemates())
+
+	var err error
+	if d.helper != nil {
+		err = d.helper.SetDNS(servers, policy.ManagedInterfaces, policy.IgnoredInterfaces)
+	} else {
+		err = system.SetDNS(servers, policy.ManagesInterface)
+	}
+	if err != nil {
+		return err
+	}
+
+	d.setSplitDNS()
+	return nil
+}
+
+// addSystemDNSLinks points links that came up since system DNS was set at
+// the proxy, leaving the others as they are (must be called with lock held)
+func (d *Daemon) addSystemDNSLinks(links []string) error {
+	servers := []string{"127.0.0.1"}
+	if d.proxy != nil {
+		servers = d.proxy.Addresses()
+	}
+
+	if d.helper != nil {
+		return d.helper.AddDNSLinks(servers, links)
+	}
+	return system.AddDNSLinks(servers, links)
+}
+
+// setSplitDNS registers the forwarders with the system resolver where it
+// supports split DNS, so apps that bypass the proxy reach them too. The
+// proxy forwards them either way, so failing is only logged.
+// (must be called with lock held)
+func (d *Daemon) setSplitDNS() {
+	var domains []system.SplitDomain
+	for _, f := range d.config.Effective().Forwarders {
+		domains = append(domains, system.SplitDomain{Domain: f.Domain, Server: f.Server})
+	}
+
+	var err error
+	if d.helper != nil {
+		err = d.helper.SetSplitDNS(domains)
+	} else {
+		err = system.SetSplitDNS(domains)
+	}
+	if err != nil {
+		log.Printf("Failed to set split DNS: %v", err)
+	}
+}
+
+// blockOutsideDNS installs or updates the firewall rules that keep other
+// programs from sending DNS past the proxy. The forwarders' servers stay
+// reachable for the system resolver's split DNS. If the rules cannot be
+// installed, filtering goes on without them and the failure is logged.
+// (must be called with lock held)
+func (d *Daemon) blockOutsideDNS() {
+	var forwarders []system.SplitDomain
+	for _, f := range d.config.Effective().Forwarders {
+		forwarders = append(forwarders, system.SplitDomain{Domain: f.Domain, Server: f.Server})
+	}
+
+	var err error
+	if d.helper != nil {
+		err = d.helper.BlockOutsideDNS(forwarders)
+	} else {
+		err = system.BlockOutsideDNS(os.Getuid(), forwarders)
+	}
+	if err != nil {
+		log.Printf("Failed to block DNS outside the proxy: %v", err)
+		return
+	}
+	if !d.outsideDNSBlocked {
+		log.Println("Blocking DNS that bypasses the proxy")
+	}
+	d.outsideDNSBlocked = true
+}
+
+// unblockOutsideDNS removes the firewall rules of blockOutsideDNS
+// (must be called with lock held)
+func (d *Daemon) unblockOutsideDNS() {
+	var err error
+	if d.helper != nil {
+		err = d.helper.UnblockOutsideDNS()
+	} else {
+		err = system.UnblockOutsideDNS()
+	}
+	if err != nil {
+		log.Printf("Failed to remove DNS firewall rules: %v", err)
+		return
+	}
+	d.outsideDNSBlocked = false
+}
+
+// resetSystemDNS restores the original system DNS settings
+// (must be called with lock held)
+func (d *Daemon) resetSystemDNS() error {
+	if d.helper != nil {
+		return d.helper.ResetDNS()
+	}
+	return system.ResetDNS()
+}
+
+// stopFiltering stops the proxy and restores system DNS
+// (must be called with lock held)
+func (d *Daemon) stopFiltering() {
+	log.Println("Disabling DNS filtering...")
+
+	d.recordStats()
+	if d.proxy != nil {
+		d.proxy.Stop()
+		d.proxy = nil
+	}
+
+	if d.outsideDNSBlocked {
+		d.unblockOutsideDNS()
+	}
+	d.resetSystemDNS()
+
+	d.running = false
+	d.vpnConflicts, d.vpnTakeovers = nil, nil
+
+	log.Println("DNS filtering disabled")
+}
+
+// setConfig updates the configuration; only trusted clients may change
+// the protected settings
+func (d *Daemon) setConfig(cfg *config.Config, trusted bool) error {
+	if err := config.Validate(cfg); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if changed := protectedChanges(d.config, cfg); len(changed) > 0 && !trusted {
+		return d.errForbidden("Changing " + strings.Join(changed, ", "))
+	}
+
+	d.keepRuntimeState(cfg)
+	if err := config.Save(cfg); err != nil {
+		return err
+	}
+	// The user may have settled a stalemate with a VPN, give it another go
+	d.vpnTakeovers = nil
+
+	// The daemon's own overrides win over what the client sent
+	if err := config.ApplyOverrides(cfg); err != nil {
+		return err
+	}
+	d.applyConfig(cfg)
+	d.selectNetwork()
+	return nil
+}
+
+// reload re-reads the configuration from disk and applies it
+func (d *Daemon) reload() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.keepRuntimeState(cfg)
+	d.applyConfig(cfg)
+	d.selectNetwork()
+	d.scheduleNetworkCheck()
+	d.publishReload()
+	log.Println("Config reloaded")
+	return nil
+}
+
+// keepRuntimeState copies the fields owned by the daemon (enabled and
+// pause state, probed capabilities, settings and DNS endpoint from the
+// server) into an incoming config (must be called with lock held)
+func (d *Daemon) keepRuntimeState(cfg *config.Config) {
+	cfg.Enabled = d.config.Enabled
+	cfg.PausedUntil = d.config.PausedUntil
+	if cfg.Capabilities == nil {
+		cfg.Capabilities = d.config.Capabilities
+	}
+	cfg.ServerSettings = d.config.ServerSettings
+	cfg.Endpoint = d.config.Endpoint
+}
+
+// applyConfig swaps in a new configuration, switching the proxy upstream
+// only if the server or profile changed (must be called with lock held)
+func (d *Daemon) applyConfig(cfg *config.Config) {
+	// The proxy runs with the server's client settings merged in
+	eff, old := cfg.Effective(), d.config.Effective()
+
+	profileChanged := cfg.Profile != d.config.Profile || cfg.ServerURL != d.config.ServerURL
+	bootstrapChanged := !slices.Equal(eff.BootstrapDNS, old.BootstrapDNS)
+	endpointChanged := cfg.DoHURL() != d.config.DoHURL()
+	upstreamChanged := d.running && (profileChanged || bootstrapChanged || endpointChanged)
+	interfacesChanged := d.running && !cfg.InterfacePolicyEqual(d.config)
+	listenChanged := d.running && !slices.Equal(cfg.LoopbackAddrs(), d.config.LoopbackAddrs())
+
+	d.config = cfg
+
+	if listenChanged && d.proxy != nil {
+		log.Println("Listen addresses changed, restarting proxy...")
+		d.recordStats()
+		d.proxy.Stop()
+		if err := d.startProxy(nil); err != nil {
+			log.Printf("Failed to restart proxy: %v", err)
+		}
+	}
+
+	if interfacesChanged || listenChanged {
+		log.Println("Interface policy or listen addresses changed, reapplying system DNS...")
+		d.resetSystemDNS()
+		if err := d.setSystemDNS(); err != nil {
+			log.Printf("Failed to set system DNS: %v", err)
+		}
+	} else if d.running && !slices.Equal(eff.Forwarders, old.Forwarders) {
+		d.setSplitDNS()
+	}
+
+	// The forwarders are exempt from the firewall rules
+	if d.running && cfg.BlockOutsideDNS && (!d.outsideDNSBlocked || !slices.Equal(eff.Forwarders, old.Forwarders)) {
+		d.blockOutsideDNS()
+	} else if d.outsideDNSBlocked && !cfg.BlockOutsideDNS {
+		d.unblockOutsideDNS()
+	}
+
+	if upstreamChanged && d.proxy != nil {
+		log.Println("Upstream changed, switching proxy to new profile...")
+		d.proxy.SwitchUpstream(eff)
+	} else if d.proxy != nil {
+		// Just update forwarders, rules, integrity mode and the search
+		// shortcut, and pick up a password set since
+		d.proxy.UpdateForwarders(eff.Forwarders)
+		d.proxy.UpdateRules(cfg.Rules)
+		d.proxy.RefreshPassword()
+		d.proxy.SetVerifyEvery(cfg.VerifyEvery)
+		d.proxy.SetSearchShortcut(cfg.SearchShortcut)
+	}
+	if d.proxy != nil {
+		d.proxy.SetCacheSize(eff.CacheSize)
+	}
+
+	if d.stats != nil {
+		d.stats.SetRetention(statsRetention(cfg))
+	}
+
+	if profileChanged {
+		d.startSync()
+	}
+	d.syncProfiles()
+
+	d.publishState()
+}
+
+// startSync (re)starts polling the server for the current profile's state
+// (must be called with lock held)
+func (d *Daemon) startSync() {
+	d.stopSync()
+
+	if d.config.Profile == "" {
+		return
+	}
+
+	caps := d.config.CapabilitiesFor(d.config.ServerURL)
+	if caps == nil {
+		// First contact with this server: find out what it supports
+		// before polling it
+		go d.probeCapabilities(d.config.ServerURL, d.config.Profile)
+		return
+	}
+	if !caps.Sync {
+		log.Println("Server does not support sync, web UI changes will not be picked up")
+		return
+	}
+
+	d.runSyncer()
+}
+
+// runSyncer starts polling the server (must be called with lock held)
+func (d *Daemon) runSyncer() {
+	var syncer *filtersync.Syncer
+	syncer = filtersync.NewSyncer(d.config.ServerURL, d.config.Profile, syncInterval,
+		func(enabled bool, pausedUntil *time.Time) {
+			d.onServerStateChanged(syncer, enabled, pausedUntil)
+		})
+	if caps := d.config.CapabilitiesFor(d.config.ServerURL); caps != nil && caps.Push {
+		syncer.EnablePush()
+	}
+	syncer.OnBlocklist(func(domains []string) { d.onBlocklistChanged(syncer, domains) })
+	syncer.OnSettings(func(settings *config.ManagedSettings) {
+		d.onServerSettings(syncer, settings)
+	})
+	syncer.OnEndpoint(func(dohURL, dotHostname string) {
+		d.onServerEndpoint(syncer, dohURL, dotHostname)
+	})
+	syncer.OnClockSkew(func(skew time.Duration) { d.onClockSkew(syncer, skew) })
+	syncer.ReportHeartbeat(func() *filtersync.Heartbeat { return d.heartbeat(syncer) })
+	syncer.OnCommand(func(cmd filtersync.Command) error { return d.onServerCommand(syncer, cmd) })
+	d.syncer = syncer
+	d.syncer.Start()
+}
+
+// probeCapabilities records which features the server supports and then
+// starts syncing if it can
+func (d *Daemon) probeCapabilities(serverURL, profile string) {
+	caps, err := filtersync.ProbeCapabilities(serverURL, profile)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// The server or profile changed while probing
+	if d.config.ServerURL != serverURL || d.config.Profile != profile || d.syncer != nil {
+		return
+	}
+
+	if err != nil {
+		// Assume a current server rather than never syncing; the next
+		// start probes again
+		log.Printf("Capability probe failed: %v", err)
+		d.runSyncer()
+		return
+	}
+
+	log.Printf("Server capabilities: sync=%v onboarding=%v ede=%v push=%v",
+		caps.Sync, caps.Onboarding, caps.EDE, caps.Push)
+	d.config.Capabilities = caps
+	config.Save(d.config)
+	if d.proxy != nil {
+		d.proxy.SetCapabilities(caps)
+	}
+	d.startSync()
+}
+
+// stopSync stops the syncer and forgets the server state, which belongs
+// to the profile being synced (must be called with lock held)
+func (d *Daemon) stopSync() {
+	if d.syncer != nil {
+		d.syncer.Stop()
+		d.syncer = nil
+	}
+	d.serverPaused = false
+	d.serverPausedUntil = nil
+	d.clockSkew = 0
+}
+
+// onServerStateChanged stops or restarts filtering to follow the profile
+// state set in the web UI
+func (d *Daemon) onServerStateChanged(syncer *filtersync.Syncer, enabled bool, pausedUntil *time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	defer d.publishState()
+
+	// Ignore late results from a syncer that has since been replaced
+	if d.syncer != syncer {
+		return
+	}
+
+	d.serverPaused = !enabled
+	d.serverPausedUntil = pausedUntil
+
+	switch {
+	case !enabled && d.running:
+		if pausedUntil != nil {
+			log.Printf("Filtering paused on the server until %s", pausedUntil.Format(time.RFC3339))
+		} else {
+			log.Println("Filtering paused on the server")
+		}
+		d.stopFiltering()
+
+	case enabled && !d.running && d.config.Enabled && d.config.PausedUntil == nil:
+		log.Println("Filtering resumed on the server")
+		if err := d.startFiltering(nil); err != nil {
+			log.Printf("Warning: failed to resume filtering: %v", err)
+		}
+	}
+}
+
+// onServerSettings applies the client settings from the server profile,
+// which Effective merges with the local ones
+func (d *Daemon) onServerSettings(syncer *filtersync.Syncer, settings *config.ManagedSettings) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.syncer != syncer || reflect.DeepEqual(d.config.ServerSettings, settings) {
+		return
+	}
+
+	cfg := *d.config
+	cfg.ServerSettings = settings
+	if err := config.Save(&cfg); err != nil {
+		log.Printf("Warning: failed to save client settings from the server: %v", err)
+	}
+	log.Println("Client settings on the server changed, applying them")
+	d.applyConfig(&cfg)
+}
+
+// onClockSkew warns while the local clock is too far off from the
+// server's, which breaks TLS once certificates look invalid
+func (d *Daemon) onClockSkew(syncer *filtersync.Syncer, skew time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.syncer != syncer {
+		return
+	}
+	if skew.Abs() <= filtersync.MaxClockSkew {
+		if d.clockSkew != 0 {
+			log.Println("Clock is in step with the server again")
+		}
+		skew = 0
+	} else if d.clockSkew == 0 {
+		log.Printf("Warning: this computer's clock is off by %s from the server's; TLS to the server may fail",
+			skew.Round(time.Second))
+	}
+	d.clockSkew = skew
+	d.publishState()
+}
+
+// onServerEndpoint records where the server serves the profile's DNS,
+// and moves the proxy there without dropping its listeners if it changed
+func (d *Daemon) onServerEndpoint(syncer *filtersync.Syncer, dohURL, dotHostname string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.syncer != syncer {
+		return
+	}
+	if dohURL != "" {
+		if err := config.ValidateDoHURL(dohURL); err != nil {
+			log.Printf("Ignoring DoH URL %q from the server: %v", dohURL, err)
+			dohURL = ""
+		} else if !config.SameSecureHost(d.config.ServerURL, dohURL) {
+			log.Printf("Ignoring DoH URL %q from the server: not on the server's https host", dohURL)
+			dohURL = ""
+		}
+	}
+
+	endpoint := &config.DNSEndpoint{
+		ServerURL:   d.config.ServerURL,
+		Profile:     d.config.Profile,
+		DoHURL:      dohURL,
+		DoTHostname: dotHostname,
+	}
+	if reflect.DeepEqual(d.config.Endpoint, endpoint) {
+		return
+	}
+
+	cfg := *d.config
+	cfg.Endpoint = endpoint
+	if err := config.Save(&cfg); err != nil {
+		log.Printf("Warning: failed to save the DNS endpoint from the server: %v", err)
+	}
+	if cfg.DoHURL() != d.config.DoHURL() {
+		log.Printf("Server moved the profile's DNS to %s", cfg.DoHURL())
+	}
+	d.applyConfig(&cfg)
+}
+
+// watchNetwork has the syncer retry at once when the network changes,
+// instead of waiting out its backoff, e.g. after a captive portal login,
+// and looks again which Wi-Fi the computer is on and which interfaces
+// still need their DNS pointed at the proxy
+func (d *Daemon) watchNetwork() {
+	err := system.WatchNetwork(d.ctx, func() {
+		d.mu.Lock()
+		syncer := d.syncer
+		d.scheduleNetworkCheck()
+		d.mu.Unlock()
+		if syncer != nil {
+			syncer.NetworkChanged()
+		}
+	})
+	if err != nil {
+		log.Printf("Network change notifications unavailable, polling interfaces instead: %v", err)
+	}
+}
+
+// onBlocklistChanged drops the cached answers after the profile's block
+// lists changed on the server, so the change applies at once: only those
+// for the changed domains if the server named them, else all
+func (d *Daemon) onBlocklistChanged(syncer *filtersync.Syncer, domains []string) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.syncer != syncer || d.proxy == nil {
+		return
+	}
+	if domains == nil || len(domains) > maxForgetDomains {
+		n := d.proxy.FlushCache("")
+		log.Printf("Block lists changed on the server, dropped %d cached answers", n)
+		return
+	}
+	n := d.proxy.ForgetDomains(domains)
+	log.Printf("Block lists changed on the server for %d domains, dropped %d cached answers", len(domains), n)
+}
+
+// syncNow fetches the profile state from the server at once, e.g. after
+// a client changed it there
+func (d *Daemon) syncNow() error {
+	d.mu.RLock()
+	syncer := d.syncer
+	d.mu.RUnlock()
+
+	if syncer == nil {
+		return fmt.Errorf("server sync is off")
+	}
+	return syncer.SyncNow()
+}
+
+// switchProfile makes a saved profile the active one
+func (d *Daemon) switchProfile(name string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	saved := d.config.FindProfile(name)
+	if saved == nil {
+		return fmt.Errorf("unknown profile: %s", name)
+	}
+
+	cfg := *d.config
+	cfg.UseProfile(saved)
+
+	if err := config.Save(&cfg); err != nil {
+		return err
+	}
+
+	log.Printf("Switching to profile %s (%s on %s)", saved.Name, saved.Profile, saved.ServerURL)
+	d.applyConfig(&cfg)
+	return nil
+}
+
+// getStatus returns the current status
+func (d *Daemon) getStatus() *Status {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	status := &Status{
+		Running:     d.running,
+		Profile:     d.config.Profile,
+		ServerURL:   d.config.ServerURL,
+		PausedUntil: d.config.PausedUntil,
+		StartedAt:   d.started,
+
+		OutsideDNSBlocked: d.outsideDNSBlocked,
+		VPNConflicts:      slices.Clone(d.vpnConflicts),
+	}
+
+	if d.proxy != nil {
 		status.QueriesTotal, status.QueriesBlocked = d.proxy.GetStats()
+		status.CacheHits = d.proxy.CacheHits()
+		status.SearchShortcuts = d.proxy.SearchShortcuts()
+		status.Connectivity = d.proxy.Connectivity()
+		status.Integrity = d.proxy.Integrity()
 	}
 
+	status.Stats = d.statsSummary(0)
+
+	if !d.health.CheckedAt.IsZero() {
+		health := d.health
+		health.Events = slices.Clone(d.health.Events)
+		status.Health = &health
+	}
+
+	if d.syncer != nil {
+		attempts := d.syncer.Attempts()
+		status.Sync = &SyncStatus{
+			FilteringEnabled: !d.serverPaused,
+			PausedUntil:      d.serverPausedUntil,
+			LastSync:         attempts.Last,
+			Push:             d.syncer.Pushing(),
+		}
+		if skew, ok := d.syncer.ClockSkew(); ok {
+			status.Sync.ClockSkew = skew
+		}
+		if err := attempts.LastErr; err != nil {
+			status.Sync.Error = err.Error()
+			status.Sync.Failures = attempts.Failures
+			status.Sync.Offline = attempts.Offline
+			if !attempts.LastSuccess.IsZero() {
+				status.Sync.LastSuccess = &attempts.LastSuccess
+			}
+			if !attempts.Next.IsZero() {
+				status.Sync.NextSync = &attempts.Next
+			}
+		}
+		if state := d.syncer.GetLastState(); state != nil {
+			status.Sync.ServerVersion = state.ServerVersion
+			status.Sync.Maintenance = state.Profile.MaintenanceMode
+			status.Sync.BlocklistVersion = state.BlocklistVersion()
+		}
+	}
+	status.Profiles = d.profileStates()
+	status.Locked = config.CurrentPolicy().Keys()
+	status.WiFi = d.ssids
+	if d.network != nil {
+		status.Network = d.network.Name
+	}
+	if d.schedule != nil {
+		until := d.scheduleEnd
+		status.Schedule = d.schedule
+		status.ScheduleUntil = &until
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	status.Goroutines = runtime.NumGoroutine()
+	status.MemoryBytes = mem.HeapAlloc
+
 	return status
 }
@@ -0,0 +1,198 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+const (
+	// DBusName is the well-known name the daemon owns on the system bus
+	DBusName = "io.filterdns.Client"
+
+	// DBusPath is the path of the control object
+	DBusPath = dbus.ObjectPath("/io/filterdns/Client")
+
+	// DBusInterface is the interface of the control object
+	DBusInterface = "io.filterdns.Client1"
+
+	// polkitAction guards the methods that change filtering
+	polkitAction = "io.filterdns.client.control"
+)
+
+// dbusControl exposes the daemon's operations on the system bus. Status
+// can be read by anyone; everything that changes filtering is checked
+// with polkit first.
+type dbusControl struct {
+	d    *Daemon
+	conn *dbus.Conn
+}
+
+// dbusService owns the system bus connection and emits StateChanged
+type dbusService struct {
+	conn *dbus.Conn
+}
+
+// Close releases the bus name and closes the connection
+func (s *dbusService) Close() error {
+	return s.conn.Close()
+}
+
+// StateChanged emits the StateChanged signal with the new state, the
+// profile and the end of a pause as a Unix timestamp (0 if not paused)
+func (s *dbusService) StateChanged(event StateEvent) {
+	var pausedUntil int64
+	if event.PausedUntil != nil {
+		pausedUntil = event.PausedUntil.Unix()
+	}
+	if err := s.conn.Emit(DBusPath, DBusInterface+".StateChanged", event.State, event.Profile, pausedUntil); err != nil {
+		log.Printf("D-Bus: failed to emit StateChanged: %v", err)
+	}
+}
+
+// startDBus exports the control object on the system bus. It runs before
+// root is dropped, so the connection keeps root's credentials, which the
+// bus policy and polkit both rely on.
+func (d *Daemon) startDBus() (io.Closer, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to system bus: %w", err)
+	}
+
+	control := &dbusControl{d: d, conn: conn}
+	if err := conn.Export(control, DBusPath, DBusInterface); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	node := &introspect.Node{
+		Name: string(DBusPath),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{
+				Name:    DBusInterface,
+				Methods: introspect.Methods(control),
+				Signals: []introspect.Signal{{
+					Name: "StateChanged",
+					Args: []introspect.Arg{
+						{Name: "state", Type: "s"},
+						{Name: "profile", Type: "s"},
+						{Name: "pausedUntil", Type: "x"},
+					},
+				}},
+			},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), DBusPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reply, err := conn.RequestName(DBusName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to request %s: %w", DBusName, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		conn.Close()
+		return nil, fmt.Errorf("%s is already owned", DBusName)
+	}
+
+	log.Printf("Listening on system D-Bus as %s", DBusName)
+	return &dbusService{conn: conn}, nil
+}
+
+// Enable starts DNS filtering
+func (c *dbusControl) Enable(sender dbus.Sender) *dbus.Error {
+	if err := c.authorize(sender); err != nil {
+		return err
+	}
+	return dbusError(c.d.enable(c.d.ctx))
+}
+
+// Disable stops DNS filtering
+func (c *dbusControl) Disable(sender dbus.Sender) *dbus.Error {
+	if err := c.authorize(sender); err != nil {
+		return err
+	}
+	return dbusError(c.d.disable())
+}
+
+// Pause stops DNS filtering for a duration such as "15m"
+func (c *dbusControl) Pause(duration string, sender dbus.Sender) *dbus.Error {
+	if err := c.authorize(sender); err != nil {
+		return err
+	}
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return dbusError(fmt.Errorf("invalid duration: %w", err))
+	}
+	return dbusError(c.d.pause(d))
+}
+
+// Resume ends a pause
+func (c *dbusControl) Resume(sender dbus.Sender) *dbus.Error {
+	if err := c.authorize(sender); err != nil {
+		return err
+	}
+	return dbusError(c.d.resume())
+}
+
+// GetStatus returns whether filtering is running, the profile, the end of
+// a pause as a Unix timestamp (0 if not paused) and the query counters
+func (c *dbusControl) GetStatus() (running bool, profile string, pausedUntil int64, queries int64, blocked int64, err *dbus.Error) {
+	status := c.d.getStatus()
+	if status.PausedUntil != nil {
+		pausedUntil = status.PausedUntil.Unix()
+	}
+	return status.Running, status.Profile, pausedUntil, status.QueriesTotal, status.QueriesBlocked, nil
+}
+
+// polkitSubject identifies the caller to polkit by its bus name
+type polkitSubject struct {
+	Kind    string
+	Details map[string]dbus.Variant
+}
+
+// polkitResult is polkit's answer to CheckAuthorization
+type polkitResult struct {
+	IsAuthorized bool
+	IsChallenge  bool
+	Details      map[string]string
+}
+
+// authorize asks polkit whether sender may control filtering, letting it
+// prompt for a password if the policy requires one
+func (c *dbusControl) authorize(sender dbus.Sender) *dbus.Error {
+	subject := polkitSubject{
+		Kind:    "system-bus-name",
+		Details: map[string]dbus.Variant{"name": dbus.MakeVariant(string(sender))},
+	}
+
+	const allowUserInteraction = uint32(1)
+
+	var result polkitResult
+	authority := c.conn.Object("org.freedesktop.PolicyKit1", "/org/freedesktop/PolicyKit1/Authority")
+	err := authority.Call("org.freedesktop.PolicyKit1.Authority.CheckAuthorization", 0,
+		subject, polkitAction, map[string]string{}, allowUserInteraction, "").Store(&result)
+	if err != nil {
+		log.Printf("D-Bus: polkit check for %s failed: %v", sender, err)
+		return dbus.MakeFailedError(fmt.Errorf("authorization check failed: %w", err))
+	}
+	if !result.IsAuthorized {
+		return dbus.NewError(DBusInterface+".Error.NotAuthorized", []interface{}{"not authorized to control filtering"})
+	}
+	return nil
+}
+
+// dbusError converts an operation's error into a D-Bus error
+func dbusError(err error) *dbus.Error {
+	if err == nil {
+		return nil
+	}
+	return dbus.MakeFailedError(err)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package daemon
+
+import "io"
+
+// startDBus does nothing: the D-Bus control interface is Linux-only
+func (d *Daemon) startDBus() (io.Closer, error) {
+	return nil, nil
+}
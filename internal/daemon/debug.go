@@ -0,0 +1,184 @@
+package daemon
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/system"
+)
+
+// maxLogLines is how many recent log lines the daemon keeps for debug bundles
+const maxLogLines = 1000
+
+// logRing keeps the most recent log lines in memory
+type logRing struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+// Write implements io.Writer; the log package writes one line per call
+func (r *logRing) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lines = append(r.lines, string(p))
+	if len(r.lines) > maxLogLines {
+		r.lines = r.lines[len(r.lines)-maxLogLines:]
+	}
+	return len(p), nil
+}
+
+// String returns the kept lines
+func (r *logRing) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return strings.Join(r.lines, "")
+}
+
+// SetDebugAddr makes Run serve net/http/pprof on addr, which must be a
+// loopback address
+func (d *Daemon) SetDebugAddr(addr string) {
+	d.debugAddr = addr
+}
+
+// startDebugListener serves the pprof handlers on d.debugAddr
+func (d *Daemon) startDebugListener() error {
+	host, _, err := net.SplitHostPort(d.debugAddr)
+	if err != nil {
+		return fmt.Errorf("invalid debug address: %w", err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("debug address must be on localhost, got %s", d.debugAddr)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	l, err := net.Listen("tcp", d.debugAddr)
+	if err != nil {
+		return fmt.Errorf("failed to start debug listener: %w", err)
+	}
+
+	server := &http.Server{Handler: mux}
+	go func() {
+		<-d.ctx.Done()
+		server.Close()
+	}()
+	go func() {
+		if err := server.Serve(l); err != http.ErrServerClosed {
+			log.Printf("Debug listener error: %v", err)
+		}
+	}()
+
+	log.Printf("Serving pprof on http://%s/debug/pprof/", l.Addr())
+	return nil
+}
+
+// debugBundle collects what support needs to look into a problem into a
+// .tar.gz: the config, recent logs and status with secrets redacted,
+// the resolver state and the DNS backup
+func (d *Daemon) debugBundle() ([]byte, error) {
+	d.mu.RLock()
+	cfg := config.RedactConfig(d.config)
+	d.mu.RUnlock()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: time.Now(),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	addJSON := func(name string, v any) error {
+		data, err := config.RedactJSON(v)
+		if err != nil {
+			return err
+		}
+		return add(name, data)
+	}
+
+	info := fmt.Sprintf("time: %s\nos: %s/%s\ngo: %s\nuid: %d\n",
+		time.Now().Format(time.RFC3339), runtime.GOOS, runtime.GOARCH, runtime.Version(), os.Getuid())
+
+	files := []func() error{
+		func() error { return add("info.txt", []byte(info)) },
+		func() error { return addJSON("config.json", cfg) },
+		func() error { return addJSON("status.json", d.getStatus()) },
+		func() error { return add("daemon.log", []byte(config.Redact(d.logs.String()))) },
+		func() error { return add("resolver.txt", []byte(resolverState())) },
+		func() error {
+			backup, err := system.LoadBackup()
+			if err != nil {
+				return add("dns-backup.error", []byte(err.Error()))
+			}
+			return addJSON("dns-backup.json", backup)
+		},
+		func() error {
+			journal, err := system.LoadJournal()
+			if err != nil {
+				return add("dns-journal.error", []byte(err.Error()))
+			}
+			return addJSON("dns-journal.json", journal)
+		},
+	}
+	for _, addFile := range files {
+		if err := addFile(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// resolverState describes the system resolver configuration
+func resolverState() string {
+	var b strings.Builder
+
+	servers, err := system.GetCurrentDNS()
+	if err != nil {
+		fmt.Fprintf(&b, "current DNS: error: %v\n", err)
+	} else {
+		fmt.Fprintf(&b, "current DNS: %s\n", strings.Join(servers, ", "))
+	}
+
+	if runtime.GOOS != "windows" {
+		if data, err := os.ReadFile("/etc/resolv.conf"); err == nil {
+			fmt.Fprintf(&b, "\n/etc/resolv.conf:\n%s", data)
+		}
+	}
+
+	return b.String()
+}
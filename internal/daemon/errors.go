@@ -0,0 +1,61 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error codes in Response.Code and Verification.Code, so clients can tell
+// failures apart without parsing messages
+const (
+	CodeNoProfile      = "no_profile"      // No profile configured
+	CodeProxyFailed    = "proxy_failed"    // The local proxy could not start or does not answer
+	CodePortInUse      = "port_in_use"     // Another DNS server listens where the proxy has to
+	CodeDNSFailed      = "dns_failed"      // System DNS could not be pointed at the proxy
+	CodeAuthFailed     = "auth_failed"     // The server rejected the profile password
+	CodeUpstreamFailed = "upstream_failed" // The server could not be reached
+	CodeNotRunning     = "not_running"     // Filtering is off
+	CodeUnauthorized   = "unauthorized"    // A remote client sent a wrong token
+	CodeForbidden      = "forbidden"       // The local user may not do this, see trustedPeer
+)
+
+// codedError is an error with one of the codes above
+type codedError struct {
+	code string
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+// withCode attaches code to err
+func withCode(code string, err error) error {
+	return &codedError{code: code, err: err}
+}
+
+// errorResponse is the failed Response for err, with its code if it has one
+func errorResponse(err error) Response {
+	resp := Response{Success: false, Error: err.Error()}
+	var coded *codedError
+	if errors.As(err, &coded) {
+		resp.Code = coded.code
+	}
+	return resp
+}
+
+// Error is a failed request as seen by a Client. Code is empty for
+// failures without one of the codes above.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// responseError returns the error carried by a failed response
+func responseError(resp *Response) error {
+	if resp.Code == "" {
+		return fmt.Errorf("%s", resp.Error)
+	}
+	return &Error{Code: resp.Code, Message: resp.Error}
+}
@@ -0,0 +1,288 @@
+package daemon
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/dns"
+)
+
+const (
+	// maxSubscribers caps event feed connections, leaving the rest of
+	// maxConnections for regular requests
+	maxSubscribers = 8
+
+	// subscriberBuffer is how many events a slow subscriber may lag
+	// behind before it misses some
+	subscriberBuffer = 16
+)
+
+// Filtering states reported in StateEvent
+const (
+	StateEnabled      = "enabled"
+	StateDisabled     = "disabled"
+	StatePaused       = "paused"        // Paused locally until PausedUntil
+	StateServerPaused = "server_paused" // Enabled locally, paused on the server
+)
+
+// StateEvent is published whenever filtering turns on or off, is paused
+// or switches profile, and when the upstream stops or resumes answering
+type StateEvent struct {
+	State        string     `json:"state"`
+	Profile      string     `json:"profile"`
+	PausedUntil  *time.Time `json:"pausedUntil,omitempty"`
+	UpstreamDown bool       `json:"upstreamDown,omitempty"` // FilterDNS server not answering
+	Time         time.Time  `json:"time"`
+
+	// Set while the local clock is off from the server's by more than
+	// sync.MaxClockSkew, ahead if positive
+	ClockSkew time.Duration `json:"clockSkew,omitempty"`
+
+	// Set when the daemon applied a changed config file, so clients can
+	// reload the copy they edit
+	ConfigReloaded bool `json:"configReloaded,omitempty"`
+}
+
+// stateListener is told about every published state, e.g. to forward it
+// to a platform event system
+type stateListener interface {
+	StateChanged(event StateEvent)
+}
+
+// eventFeed fans state events out to "subscribe" connections, and counts
+// "subscribe_queries" connections against the same limit
+type eventFeed struct {
+	mu            sync.Mutex
+	subscribers   map[chan StateEvent]struct{}
+	queryWatchers int
+}
+
+// subscribe registers a subscriber, or returns nil if there are too many
+func (f *eventFeed) subscribe() chan StateEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.subscribers)+f.queryWatchers >= maxSubscribers {
+		return nil
+	}
+	if f.subscribers == nil {
+		f.subscribers = make(map[chan StateEvent]struct{})
+	}
+	ch := make(chan StateEvent, subscriberBuffer)
+	f.subscribers[ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe removes a subscriber
+func (f *eventFeed) unsubscribe(ch chan StateEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.subscribers, ch)
+}
+
+// watchQueries takes a subscriber slot for a query feed, reporting
+// whether one was free
+func (f *eventFeed) watchQueries() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.subscribers)+f.queryWatchers >= maxSubscribers {
+		return false
+	}
+	f.queryWatchers++
+	return true
+}
+
+// unwatchQueries gives back the slot of a query feed
+func (f *eventFeed) unwatchQueries() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queryWatchers--
+}
+
+// publish sends an event to all subscribers without waiting for any
+func (f *eventFeed) publish(event StateEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Subscriber is not reading; it will see the next event
+		}
+	}
+}
+
+// stateEvent describes the current filtering state (must be called with lock held)
+func (d *Daemon) stateEvent() StateEvent {
+	event := StateEvent{
+		State:   StateDisabled,
+		Profile: d.config.Profile,
+		Time:    time.Now(),
+
+		ClockSkew: d.clockSkew,
+	}
+
+	switch {
+	case d.running:
+		event.State = StateEnabled
+		event.UpstreamDown = d.upstreamDown
+	case d.config.PausedUntil != nil:
+		event.State = StatePaused
+		event.PausedUntil = d.config.PausedUntil
+	case d.serverPaused && d.config.Enabled:
+		event.State = StateServerPaused
+		event.PausedUntil = d.serverPausedUntil
+	}
+	return event
+}
+
+// publishState announces the filtering state to subscribers and the
+// platform if it changed since the last announcement
+// (must be called with lock held)
+func (d *Daemon) publishState() {
+	event := d.stateEvent()
+	if last := d.lastEvent; last != nil && last.State == event.State && last.Profile == event.Profile &&
+		samePause(last.PausedUntil, event.PausedUntil) && last.UpstreamDown == event.UpstreamDown &&
+		(last.ClockSkew == 0) == (event.ClockSkew == 0) {
+		return
+	}
+	d.lastEvent = &event
+
+	d.events.publish(event)
+	if listener, ok := d.bus.(stateListener); ok {
+		listener.StateChanged(event)
+	}
+	postPlatformEvent(event)
+}
+
+// publishReload announces that the config was reloaded from disk, along
+// with the current state (must be called with lock held)
+func (d *Daemon) publishReload() {
+	event := d.stateEvent()
+	event.ConfigReloaded = true
+	d.lastEvent = &event
+	d.events.publish(event)
+}
+
+// upstreamChanged records whether the proxy's upstream answers and
+// announces the change
+func (d *Daemon) upstreamChanged(proxy *dns.Proxy, down bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// A replaced proxy's upstream no longer matters
+	if d.proxy != proxy {
+		return
+	}
+	d.upstreamDown = down
+	d.publishState()
+}
+
+// samePause compares two optional pause deadlines
+func samePause(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// serveSubscription streams state events to conn as JSON lines, starting
+// with the current state, until the client hangs up or the daemon stops
+func (d *Daemon) serveSubscription(conn net.Conn) {
+	ch := d.events.subscribe()
+	if ch == nil {
+		json.NewEncoder(conn).Encode(Response{Success: false, Error: "too many subscribers"})
+		return
+	}
+	defer d.events.unsubscribe(ch)
+
+	// Subscriptions are long-lived, unlike regular requests
+	conn.SetDeadline(time.Time{})
+
+	d.mu.RLock()
+	current := d.stateEvent()
+	d.mu.RUnlock()
+
+	// Subscribers never send anything after the request, so a read
+	// returning means they hung up
+	hangup := make(chan struct{})
+	go func() {
+		var buf [1]byte
+		conn.Read(buf[:])
+		close(hangup)
+	}()
+
+	encoder := json.NewEncoder(conn)
+	if err := encoder.Encode(current); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-hangup:
+			return
+		case event := <-ch:
+			if err := encoder.Encode(event); err != nil {
+				log.Printf("Dropping event subscriber: %v", err)
+				return
+			}
+		}
+	}
+}
+
+// serveQuerySubscription streams the query log to conn as JSON lines: the
+// entries selected by filter, then every new query it selects, until the
+// client hangs up or the daemon stops. Queries arriving faster than the
+// client reads are skipped.
+func (d *Daemon) serveQuerySubscription(conn net.Conn, filter dns.QueryFilter) {
+	if !d.events.watchQueries() {
+		json.NewEncoder(conn).Encode(Response{Success: false, Error: "too many subscribers"})
+		return
+	}
+	defer d.events.unwatchQueries()
+
+	// Watch before reading the backlog, so nothing falls in between
+	live, stop := d.queryLog.Watch()
+	defer stop()
+
+	conn.SetDeadline(time.Time{})
+
+	hangup := make(chan struct{})
+	go func() {
+		var buf [1]byte
+		conn.Read(buf[:])
+		close(hangup)
+	}()
+
+	encoder := json.NewEncoder(conn)
+	for _, entry := range d.queryLog.Entries(filter) {
+		if err := encoder.Encode(entry); err != nil {
+			return
+		}
+		filter.After = entry.Seq
+	}
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-hangup:
+			return
+		case entry := <-live:
+			if !filter.Matches(entry) {
+				continue
+			}
+			if err := encoder.Encode(entry); err != nil {
+				log.Printf("Dropping query subscriber: %v", err)
+				return
+			}
+		}
+	}
+}
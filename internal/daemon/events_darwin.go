@@ -0,0 +1,29 @@
+package daemon
+
+import (
+	"log"
+	"os/exec"
+)
+
+// darwinNotification is posted through notify(3) on every state change.
+// Its state value is 1 while filtering is enabled and 0 otherwise, so
+// subscribers can read it with notify_get_state.
+const darwinNotification = "io.filterdns.client.state"
+
+// postPlatformEvent posts the Darwin notification for event
+func postPlatformEvent(event StateEvent) {
+	value := "0"
+	if event.State == StateEnabled {
+		value = "1"
+	}
+
+	go func() {
+		if err := exec.Command("notifyutil", "-s", darwinNotification, value).Run(); err != nil {
+			log.Printf("Failed to set %s state: %v", darwinNotification, err)
+			return
+		}
+		if err := exec.Command("notifyutil", "-p", darwinNotification).Run(); err != nil {
+			log.Printf("Failed to post %s: %v", darwinNotification, err)
+		}
+	}()
+}
@@ -0,0 +1,7 @@
+//go:build !darwin
+
+package daemon
+
+// postPlatformEvent does nothing here: Linux gets the D-Bus StateChanged
+// signal and other platforms rely on the event feed
+func postPlatformEvent(event StateEvent) {}
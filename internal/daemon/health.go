@@ -0,0 +1,175 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/system"
+)
+
+const (
+	// healthInterval is how often the daemon checks its own health
+	healthInterval = time.Minute
+
+	// canaryDomain is resolved through the local proxy on every check
+	canaryDomain = "example.com."
+
+	// maxHealthEvents caps the event history kept in Health
+	maxHealthEvents = 20
+)
+
+// Health is the result of the daemon's periodic self-test
+type Health struct {
+	OK          bool          `json:"ok"`
+	ProxyOK     bool          `json:"proxyOk"`     // Canary resolved through 127.0.0.1:53
+	SystemDNSOK bool          `json:"systemDnsOk"` // System resolver still points at the proxy
+	Hijacked    bool          `json:"hijacked"`    // Port 53 answered, but not by our proxy
+	Repairs     int           `json:"repairs"`     // Auto-repairs attempted since start
+	CheckedAt   time.Time     `json:"checkedAt"`
+	Events      []HealthEvent `json:"events,omitempty"`
+}
+
+// HealthEvent records a failed check or a repair attempt
+type HealthEvent struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// runHealthChecks periodically self-tests while filtering is running
+func (d *Daemon) runHealthChecks() {
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.checkHealth()
+		}
+	}
+}
+
+// checkHealth resolves the canary through the proxy, verifies system DNS
+// and repairs what it can
+func (d *Daemon) checkHealth() {
+	d.mu.RLock()
+	proxy := d.proxy
+	running := d.running
+	d.mu.RUnlock()
+
+	if !running || proxy == nil {
+		return
+	}
+
+	// Every query the proxy sees is counted, so an answer that leaves the
+	// counter untouched came from someone else listening on port 53
+	before, _ := proxy.GetStats()
+	answered := queryCanary()
+	after, _ := proxy.GetStats()
+
+	servers, err := system.GetCurrentDNS()
+	systemOK := err == nil && slices.Contains(servers, "127.0.0.1")
+
+	vpns, err := system.DetectVPNs()
+	if err != nil {
+		log.Printf("Failed to look for VPNs: %v", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Filtering was stopped or restarted while we were checking
+	if d.proxy != proxy {
+		return
+	}
+
+	h := &d.health
+	h.CheckedAt = time.Now()
+	h.ProxyOK = answered && after > before
+	h.Hijacked = answered && after == before
+
+	// A VPN that took over system DNS is pointed back at the proxy like
+	// any other change, unless it keeps taking it back
+	var takenBy []string
+	var stalemate bool
+	for _, c := range d.checkVPNs(vpns, true) {
+		switch c.Kind {
+		case VPNTakeover:
+			takenBy = append(takenBy, fmt.Sprintf("%s on %s", c.VPN, c.Interface))
+		case VPNStalemate:
+			stalemate = true
+		}
+	}
+
+	h.SystemDNSOK = systemOK && len(takenBy) == 0 && !stalemate
+	h.OK = h.ProxyOK && h.SystemDNSOK
+
+	if h.OK {
+		return
+	}
+
+	switch {
+	case h.Hijacked:
+		d.healthEvent("Port 53 is answered by another process, restarting proxy")
+		d.restartProxy()
+	case !h.ProxyOK:
+		d.healthEvent("Local proxy did not answer the canary query, restarting proxy")
+		d.restartProxy()
+	}
+
+	switch {
+	case len(takenBy) > 0:
+		d.healthEvent("System DNS was taken over by " + strings.Join(takenBy, ", ") + ", pointing it back at the proxy")
+	case stalemate:
+		// Reported when it began, see checkVPNs
+		return
+	case h.SystemDNSOK:
+		return
+	default:
+		d.healthEvent("System DNS no longer points at the proxy, re-applying")
+	}
+	h.Repairs++
+	d.resetSystemDNS()
+	if err := d.setSystemDNS(); err != nil {
+		d.healthEvent("Failed to re-apply system DNS: " + err.Error())
+	}
+}
+
+// restartProxy replaces the running proxy with a fresh one
+// (must be called with lock held)
+func (d *Daemon) restartProxy() {
+	d.health.Repairs++
+
+	d.recordStats()
+	d.proxy.Stop()
+	if err := d.startProxy(nil); err != nil {
+		d.healthEvent("Failed to restart proxy: " + err.Error())
+	}
+}
+
+// healthEvent logs a health problem and keeps it for Status. Repeats of
+// the same problem are rate-limited in the log but always recorded.
+// (must be called with lock held)
+func (d *Daemon) healthEvent(message string) {
+	d.notifier.Notify("Health check", message)
+
+	d.health.Events = append(d.health.Events, HealthEvent{Time: time.Now(), Message: message})
+	if len(d.health.Events) > maxHealthEvents {
+		d.health.Events = d.health.Events[len(d.health.Events)-maxHealthEvents:]
+	}
+}
+
+// queryCanary resolves the canary domain through the local proxy
+func queryCanary() bool {
+	msg := new(dns.Msg)
+	msg.SetQuestion(canaryDomain, dns.TypeA)
+
+	client := &dns.Client{Timeout: 3 * time.Second}
+	resp, _, err := client.Exchange(msg, "127.0.0.1:53")
+	return err == nil && resp != nil
+}
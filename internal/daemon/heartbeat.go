@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	filtersync "github.com/zkmkarlsruhe/filterdns-client/internal/sync"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/update"
+)
+
+// heartbeat collects what the config allows syncer to report to the
+// server, or nil if it allows nothing
+func (d *Daemon) heartbeat(syncer *filtersync.Syncer) *filtersync.Heartbeat {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	cfg := d.config
+	if len(cfg.Heartbeat) == 0 {
+		return nil
+	}
+
+	hb := &filtersync.Heartbeat{}
+	if cfg.Reports(config.HeartbeatName) {
+		hb.Name, _ = os.Hostname()
+	}
+	if cfg.Reports(config.HeartbeatVersion) {
+		hb.Version = update.Version
+	}
+	if cfg.Reports(config.HeartbeatOS) {
+		hb.OS = runtime.GOOS + "/" + runtime.GOARCH
+	}
+	if cfg.Reports(config.HeartbeatFiltering) {
+		switch {
+		case d.running:
+			hb.Filtering = filtersync.FilteringOn
+		case d.serverPaused || (cfg.PausedUntil != nil && cfg.PausedUntil.After(time.Now())):
+			hb.Filtering = filtersync.FilteringPaused
+		default:
+			hb.Filtering = filtersync.FilteringOff
+		}
+	}
+	if cfg.Reports(config.HeartbeatCounters) && d.proxy != nil {
+		total, blocked := d.proxy.GetStats()
+		hb.Counters = &filtersync.HeartbeatCounters{Queries: total, Blocked: blocked}
+	}
+	if cfg.Reports(config.HeartbeatErrors) {
+		hb.LastError = d.lastProblem(syncer)
+	}
+	return hb
+}
+
+// lastProblem is the newest failed health check if DNS is unhealthy, else
+// why the previous sync failed, or "" if all is well (must be called with
+// lock held)
+func (d *Daemon) lastProblem(syncer *filtersync.Syncer) string {
+	if events := d.health.Events; !d.health.OK && !d.health.CheckedAt.IsZero() && len(events) > 0 {
+		return events[len(events)-1].Message
+	}
+	if _, err := syncer.LastAttempt(); err != nil {
+		return err.Error()
+	}
+	return ""
+}
@@ -0,0 +1,121 @@
+package daemon
+
+import (
+	"log"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/system"
+)
+
+// networkSettle is how long network changes have to stop before the
+// daemon looks again which Wi-Fi it is on; joining one takes a few
+// changes in a row
+const networkSettle = 3 * time.Second
+
+// scheduleNetworkCheck looks which Wi-Fi the computer is on and for new
+// interfaces once network changes have settled (must be called with lock
+// held)
+func (d *Daemon) scheduleNetworkCheck() {
+	if d.networkTimer != nil {
+		d.networkTimer.Stop()
+	}
+	d.networkTimer = time.AfterFunc(networkSettle, d.checkNetwork)
+}
+
+// checkNetwork finds the Wi-Fi networks the computer is on and applies
+// the settings configured for them, and points interfaces that came up
+// since, e.g. USB tethering or a VPN, at the proxy, noting VPNs whose
+// DNS gets in its way
+func (d *Daemon) checkNetwork() {
+	ssids := system.WiFiNetworks()
+
+	d.mu.RLock()
+	running, include := d.running, d.config.ManagesInterface
+	d.mu.RUnlock()
+	var unfiltered []string
+	var vpns []system.VPN
+	if running {
+		var err error
+		if unfiltered, err = system.UnfilteredLinks(include); err != nil {
+			log.Printf("Failed to check which interfaces use the proxy: %v", err)
+		}
+		// Before the proxy replaces a new VPN's servers, which tell
+		// where its domains go
+		if vpns, err = system.DetectVPNs(); err != nil {
+			log.Printf("Failed to look for VPNs: %v", err)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.ctx.Err() != nil {
+		return
+	}
+	if !reflect.DeepEqual(ssids, d.ssids) {
+		if len(ssids) > 0 {
+			log.Printf("Connected to Wi-Fi %s", strings.Join(ssids, ", "))
+		} else if len(d.ssids) > 0 {
+			log.Println("No longer on Wi-Fi")
+		}
+	}
+	d.ssids = ssids
+	d.selectNetwork()
+
+	if !d.running {
+		return
+	}
+	d.checkVPNs(vpns, false)
+	unfiltered = slices.DeleteFunc(unfiltered, func(link string) bool {
+		return slices.Contains(d.vpnStalemates(), link)
+	})
+	if len(unfiltered) > 0 {
+		log.Printf("New network interfaces %s, pointing their DNS at the proxy", strings.Join(unfiltered, ", "))
+		if err := d.addSystemDNSLinks(unfiltered); err != nil {
+			log.Printf("Failed to set system DNS: %v", err)
+		}
+	}
+}
+
+// selectNetwork switches to the overlay the config has for the Wi-Fi the
+// computer is on, if it is not the one in use, reloading the config with
+// it (must be called with lock held)
+func (d *Daemon) selectNetwork() {
+	var n *config.NetworkOverlay
+	if found := d.config.NetworkFor(d.ssids); found != nil {
+		overlay := *found
+		n = &overlay
+	}
+	if reflect.DeepEqual(n, d.network) {
+		return
+	}
+
+	if n != nil {
+		log.Printf("Using the settings for network %s", n.Name)
+	} else {
+		log.Printf("Left network %s, using the usual settings", d.network.Name)
+	}
+	d.network = n
+	config.SetNetwork(n)
+
+	if err := d.reapplyConfig(); err != nil {
+		log.Printf("Warning: failed to apply the network's settings: %v", err)
+	}
+}
+
+// reapplyConfig loads the config again and applies it, after what Load
+// lays over the saved one changed (must be called with lock held)
+func (d *Daemon) reapplyConfig() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	d.keepRuntimeState(cfg)
+	d.applyConfig(cfg)
+	d.publishReload()
+	return nil
+}
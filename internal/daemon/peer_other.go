@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package daemon
+
+import "net"
+
+// peerUID cannot tell who is on the other end of a local socket here;
+// access to it is governed by the socket file's permissions alone
+func peerUID(conn net.Conn) (int, error) {
+	return -1, errNoPeerCredentials
+}
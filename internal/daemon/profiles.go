@@ -0,0 +1,130 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+	filtersync "github.com/zkmkarlsruhe/filterdns-client/internal/sync"
+)
+
+// profileSyncInterval is how often the daemon polls the state of saved
+// profiles that are not in use; it only shows in the profile switcher
+const profileSyncInterval = 5 * time.Minute
+
+// ProfileState is the server-side state of a saved profile, so switchers
+// can show which ones are paused before switching
+type ProfileState struct {
+	Name             string     `json:"name"`
+	Active           bool       `json:"active,omitempty"` // The profile the daemon filters with
+	Synced           bool       `json:"synced,omitempty"` // The state below came from the server
+	FilteringEnabled bool       `json:"filteringEnabled"` // As of the last sync
+	PausedUntil      *time.Time `json:"pausedUntil,omitempty"`
+	Maintenance      bool       `json:"maintenance,omitempty"`
+	Error            string     `json:"error,omitempty"` // Why the last sync failed, if it did
+}
+
+// Describe says whether the profile filters, is paused or in maintenance,
+// as of the last sync, in the user's language; "" if it is not known
+func (s *ProfileState) Describe() string {
+	switch {
+	case s == nil:
+		return ""
+	case !s.Synced && s.Error != "":
+		return i18n.T("server unreachable")
+	case !s.Synced:
+		return ""
+	case s.Maintenance:
+		return i18n.T("maintenance")
+	case !s.FilteringEnabled && s.PausedUntil != nil:
+		return i18n.T("paused until %s", s.PausedUntil.Local().Format("15:04"))
+	case !s.FilteringEnabled:
+		return i18n.T("paused")
+	}
+	return i18n.T("filtering")
+}
+
+// profileSyncer polls the state of a saved profile that is not in use
+type profileSyncer struct {
+	saved  config.SavedProfile
+	syncer *filtersync.Syncer
+}
+
+// activeProfile reports whether saved is the profile the daemon filters with
+func (d *Daemon) activeProfile(saved config.SavedProfile) bool {
+	return saved.Profile == d.config.Profile && saved.ServerURL == d.config.ServerURL
+}
+
+// syncProfiles starts a state-only syncer for every saved profile but the
+// active one, which the main syncer covers, and stops those no longer
+// needed (must be called with lock held)
+func (d *Daemon) syncProfiles() {
+	wanted := make(map[string]config.SavedProfile)
+	for _, saved := range d.config.Profiles {
+		if saved.Profile != "" && !d.activeProfile(saved) {
+			wanted[saved.Name] = saved
+		}
+	}
+
+	for name, ps := range d.profileSyncers {
+		if saved, ok := wanted[name]; !ok || saved != ps.saved {
+			ps.syncer.Stop()
+			delete(d.profileSyncers, name)
+		}
+	}
+	for name, saved := range wanted {
+		if _, ok := d.profileSyncers[name]; ok {
+			continue
+		}
+		if caps := d.config.CapabilitiesFor(saved.ServerURL); caps != nil && !caps.Sync {
+			continue
+		}
+		if d.profileSyncers == nil {
+			d.profileSyncers = make(map[string]*profileSyncer)
+		}
+		// No callbacks: the state is only read for the status
+		syncer := filtersync.NewSyncer(saved.ServerURL, saved.Profile, profileSyncInterval, nil)
+		syncer.StateOnly()
+		d.profileSyncers[name] = &profileSyncer{saved: saved, syncer: syncer}
+		syncer.Start()
+	}
+}
+
+// stopProfileSyncs stops polling the saved profiles (must be called with
+// lock held)
+func (d *Daemon) stopProfileSyncs() {
+	for name, ps := range d.profileSyncers {
+		ps.syncer.Stop()
+		delete(d.profileSyncers, name)
+	}
+}
+
+// profileStates reports the server state of each saved profile, in the
+// order they were saved (must be called with lock held)
+func (d *Daemon) profileStates() []ProfileState {
+	var states []ProfileState
+	for _, saved := range d.config.Profiles {
+		state := ProfileState{Name: saved.Name, Active: d.activeProfile(saved)}
+
+		syncer := d.syncer
+		if !state.Active {
+			syncer = nil
+			if ps := d.profileSyncers[saved.Name]; ps != nil {
+				syncer = ps.syncer
+			}
+		}
+		if syncer != nil {
+			if last := syncer.GetLastState(); last != nil {
+				state.Synced = true
+				state.FilteringEnabled = last.Profile.FilteringEnabled
+				state.PausedUntil = last.PausedUntil()
+				state.Maintenance = last.Profile.MaintenanceMode
+			}
+			if _, err := syncer.LastAttempt(); err != nil {
+				state.Error = err.Error()
+			}
+		}
+		states = append(states, state)
+	}
+	return states
+}
@@ -0,0 +1,226 @@
+package daemon
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+)
+
+// ControlPort is the TCP port suggested for the remote control listener
+const ControlPort = "5380"
+
+// Files in the config directory holding the remote control credentials:
+// a self-signed certificate that clients pin, and the token they send
+const (
+	controlCertFile  = "control.pem"
+	controlTokenFile = "control.token"
+)
+
+// RemoteControl is what a GUI on another machine needs to manage this
+// daemon through its TCP listener
+type RemoteControl struct {
+	Addr        string `json:"addr"`
+	Token       string `json:"token"`
+	Fingerprint string `json:"fingerprint"` // SHA-256 of the certificate, which clients pin
+}
+
+// Endpoint is a daemon reached over TCP instead of the local socket, e.g.
+// on the family Raspberry Pi or through an SSH tunnel
+type Endpoint struct {
+	Addr        string `json:"addr"`        // host:port
+	Token       string `json:"token"`       // As shown by "remote-control" on the daemon's machine
+	Fingerprint string `json:"fingerprint"` // Certificate fingerprint to pin
+}
+
+// errUnauthorized answers remote requests with a wrong or missing token
+var errUnauthorized = errors.New("unauthorized: wrong remote control token")
+
+// startControlListener serves the control API on d.config.ControlAddr
+// over TLS, for GUIs on other machines. Each request must carry the
+// token; the certificate and token are created on first use.
+func (d *Daemon) startControlListener() error {
+	addr := d.config.ControlAddr
+	cert, token, err := loadControlCredentials()
+	if err != nil {
+		return fmt.Errorf("remote control credentials: %w", err)
+	}
+	config.RegisterSecret(token, config.Redacted)
+
+	listener, err := tls.Listen("tcp", addr, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to listen for remote control on %s: %w", addr, err)
+	}
+	d.control = &RemoteControl{Addr: addr, Token: token, Fingerprint: certFingerprint(cert.Certificate[0])}
+	d.controlListener = listener
+	log.Printf("Remote control listening on %s (certificate %s)", listener.Addr(), d.control.Fingerprint)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if d.ctx.Err() == nil {
+					log.Printf("Remote control accept error: %v", err)
+				}
+				return
+			}
+			select {
+			case d.remoteConns <- struct{}{}:
+				go func() {
+					defer func() { <-d.remoteConns }()
+					d.handleConnection(conn, true)
+				}()
+			default:
+				log.Printf("Rejecting remote connection from %s: %d clients already connected", conn.RemoteAddr(), maxRemoteConnections)
+				conn.Close()
+			}
+		}
+	}()
+	return nil
+}
+
+// authorized checks a remote request's token in constant time
+func (d *Daemon) authorized(req Request) bool {
+	return d.control != nil && subtle.ConstantTimeCompare([]byte(req.Token), []byte(d.control.Token)) == 1
+}
+
+// loadControlCredentials reads the remote control certificate and token
+// from the config directory, creating them if missing
+func loadControlCredentials() (tls.Certificate, string, error) {
+	path, err := config.Path()
+	if err != nil {
+		return tls.Certificate{}, "", err
+	}
+	dir := filepath.Dir(path)
+	certPath, tokenPath := filepath.Join(dir, controlCertFile), filepath.Join(dir, controlTokenFile)
+
+	if pemData, err := os.ReadFile(certPath); err == nil {
+		token, err := os.ReadFile(tokenPath)
+		if err != nil {
+			return tls.Certificate{}, "", err
+		}
+		cert, err := tls.X509KeyPair(pemData, pemData)
+		return cert, strings.TrimSpace(string(token)), err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, "", err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return tls.Certificate{}, "", err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "filterdns-daemon"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(20, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, "", err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, "", err
+	}
+	pemData := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})...)
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return tls.Certificate{}, "", err
+	}
+	token := hex.EncodeToString(secret)
+
+	if err := os.WriteFile(tokenPath, []byte(token+"\n"), 0600); err != nil {
+		return tls.Certificate{}, "", err
+	}
+	if err := os.WriteFile(certPath, pemData, 0600); err != nil {
+		return tls.Certificate{}, "", err
+	}
+	log.Printf("Created remote control credentials in %s", dir)
+
+	cert, err := tls.X509KeyPair(pemData, pemData)
+	return cert, token, err
+}
+
+// certFingerprint formats the SHA-256 of a DER certificate like
+// "ab:cd:...", as clients compare it
+func certFingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = hex.EncodeToString([]byte{b})
+	}
+	return strings.Join(parts, ":")
+}
+
+// dialEndpoint connects to a remote daemon over TLS, accepting only the
+// certificate with the pinned fingerprint
+func dialEndpoint(e *Endpoint) (net.Conn, error) {
+	want := strings.ToLower(strings.TrimSpace(e.Fingerprint))
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: 5 * time.Second},
+		Config: &tls.Config{
+			// The certificate is self-signed; the pin replaces the CA check
+			InsecureSkipVerify: true,
+			MinVersion:         tls.VersionTLS12,
+			VerifyConnection: func(state tls.ConnectionState) error {
+				if len(state.PeerCertificates) == 0 {
+					return errors.New("daemon sent no certificate")
+				}
+				if got := certFingerprint(state.PeerCertificates[0].Raw); got != want {
+					return fmt.Errorf("daemon certificate %s does not match the expected %s", got, want)
+				}
+				return nil
+			},
+		},
+	}
+	conn, err := dialer.Dial("tcp", e.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to daemon at %s: %w", e.Addr, err)
+	}
+	return conn, nil
+}
+
+// rejectRemote answers a remote connection whose token is wrong
+func rejectRemote(encoder *json.Encoder) {
+	encoder.Encode(Response{Success: false, Error: errUnauthorized.Error(), Code: CodeUnauthorized})
+}
+
+// setPassword keeps a profile password in the daemon's keyring, for GUIs
+// on other machines that cannot reach it; an empty password deletes it
+func setPassword(profile, password string) error {
+	if profile == "" {
+		return errors.New("no profile given")
+	}
+	if password == "" {
+		return config.DeletePassword(profile)
+	}
+	return config.SetPassword(profile, password)
+}
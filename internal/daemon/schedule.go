@@ -0,0 +1,137 @@
+package daemon
+
+import (
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+)
+
+// scheduleInterval is how often the daemon checks the schedule, so time
+// ranges start and end within this of their minute
+const scheduleInterval = 15 * time.Second
+
+// runSchedule carries out the schedule entries as their time ranges start
+// and end, until the daemon stops
+func (d *Daemon) runSchedule() {
+	d.checkSchedule()
+
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.checkSchedule()
+		}
+	}
+}
+
+// checkSchedule ends the schedule entry in effect if its time range is
+// over and starts the one whose time range began
+func (d *Daemon) checkSchedule() {
+	d.mu.Lock()
+	var entry *config.ScheduleEntry
+	found, end := d.config.ActiveSchedule(time.Now())
+	if found != nil {
+		copied := *found
+		entry = &copied
+	}
+	previous, previousEnd := d.schedule, d.scheduleEnd
+	if reflect.DeepEqual(entry, previous) && end.Equal(previousEnd) {
+		d.mu.Unlock()
+		return
+	}
+	d.schedule, d.scheduleEnd = entry, end
+	turnedOn := d.scheduleTurnedOn
+	d.scheduleTurnedOn = false
+	d.mu.Unlock()
+
+	// The same entry again right away, e.g. all day on consecutive days,
+	// carries on without ending
+	if previous != nil && !reflect.DeepEqual(entry, previous) {
+		log.Printf("Schedule %s ended", previous.Name)
+		d.endSchedule(previous, previousEnd, turnedOn)
+	}
+	if entry != nil {
+		log.Printf("Schedule %s started, until %s", entry.Name, end.Format("Mon 15:04"))
+		d.startSchedule(entry, end, turnedOn && reflect.DeepEqual(entry, previous))
+	}
+}
+
+// startSchedule carries out an entry whose time range began. turnedOn
+// carries over from the same entry's previous time range.
+func (d *Daemon) startSchedule(entry *config.ScheduleEntry, end time.Time, turnedOn bool) {
+	d.mu.RLock()
+	enabled, paused := d.config.Enabled, d.config.PausedUntil != nil
+	d.mu.RUnlock()
+
+	switch entry.Action {
+	case config.ScheduleOff:
+		if !enabled {
+			return
+		}
+		if err := d.pause(time.Until(end)); err != nil {
+			log.Printf("Warning: schedule %s could not pause filtering: %v", entry.Name, err)
+		}
+
+	case config.ScheduleOn:
+		if enabled && !paused {
+			d.mu.Lock()
+			d.scheduleTurnedOn = turnedOn
+			d.mu.Unlock()
+			return
+		}
+		if err := d.resume(); err != nil {
+			log.Printf("Warning: schedule %s could not turn filtering on: %v", entry.Name, err)
+			return
+		}
+		d.mu.Lock()
+		d.scheduleTurnedOn = turnedOn || !enabled
+		d.mu.Unlock()
+
+	case config.ScheduleProfile:
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		config.SetScheduledProfile(entry.Profile)
+		if err := d.reapplyConfig(); err != nil {
+			log.Printf("Warning: schedule %s could not switch profiles: %v", entry.Name, err)
+		}
+	}
+}
+
+// endSchedule undoes what an entry did once its time range is over or
+// it was removed: a pause it began ends, filtering it turned on goes off
+// again and its profile gives way to the saved one
+func (d *Daemon) endSchedule(entry *config.ScheduleEntry, end time.Time, turnedOn bool) {
+	switch entry.Action {
+	case config.ScheduleOff:
+		d.mu.RLock()
+		until := d.config.PausedUntil
+		d.mu.RUnlock()
+		// Not if filtering was paused by hand for longer since
+		if until != nil && until.Sub(end).Abs() < time.Minute {
+			if err := d.resume(); err != nil {
+				log.Printf("Warning: resume after schedule %s failed: %v", entry.Name, err)
+			}
+		}
+
+	case config.ScheduleOn:
+		if !turnedOn {
+			return
+		}
+		if err := d.disable(); err != nil {
+			log.Printf("Warning: schedule %s could not turn filtering off again: %v", entry.Name, err)
+		}
+
+	case config.ScheduleProfile:
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		config.SetScheduledProfile("")
+		if err := d.reapplyConfig(); err != nil {
+			log.Printf("Warning: failed to return to the saved profile after schedule %s: %v", entry.Name, err)
+		}
+	}
+}
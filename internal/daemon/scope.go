@@ -0,0 +1,51 @@
+package daemon
+
+import (
+	"errors"
+	"log"
+	"path/filepath"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/system"
+)
+
+// useSystemConfig switches to the machine config, which the CLI and GUI
+// of every user read too, adopting the config an older daemon kept in
+// root's config directory or, with dropped privileges, in the state
+// directory. A daemon that cannot write the machine config, e.g. one run
+// by hand without root, keeps using the config of the user running it.
+func useSystemConfig() {
+	if config.PureConfig() != "" {
+		return
+	}
+	if err := config.UseSystemConfig(); err != nil {
+		log.Printf("Using the per-user config, %s is not writable: %v", config.SystemDir(), err)
+		return
+	}
+
+	legacy := []string{filepath.Join(system.StateDir(), "config")}
+	if dir, err := config.UserDir(); err == nil {
+		legacy = append(legacy, dir)
+	}
+	for _, dir := range legacy {
+		adopted, err := config.AdoptConfig(dir)
+		if err != nil {
+			log.Printf("Warning: failed to copy the config in %s to %s: %v", dir, config.SystemDir(), err)
+		}
+		if adopted {
+			log.Printf("Copied the config in %s to %s", dir, config.SystemDir())
+			return
+		}
+	}
+}
+
+// SaveMachineConfig hands cfg to the daemon on this computer to save as
+// the machine config, for users who cannot write it themselves; see
+// config.SetMachineWriter
+func SaveMachineConfig(cfg *config.Config) error {
+	client := NewClient()
+	if !client.IsRunning() {
+		return errors.New("the daemon is not running")
+	}
+	return client.SetConfig(cfg)
+}
@@ -0,0 +1,199 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/dns"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/stats"
+)
+
+const (
+	// statsInterval is how often the proxy's counters are written to disk
+	statsInterval = time.Minute
+
+	// statsFile is kept next to the daemon's config, which is writable
+	// even after dropping root
+	statsFile = "stats.json"
+
+	// statsDays is how many days of history the "stats" action returns
+	statsDays = 30
+
+	// topBlocked is how many of the most blocked domains "stats" returns
+	topBlocked = 10
+)
+
+// StatsReport is the answer to "stats": the persistent counters plus what
+// the running daemon knows about its current session
+type StatsReport struct {
+	*stats.Summary
+
+	// Counted by the current proxy, which restarts with filtering
+	Session   stats.Counts `json:"session"`
+	CacheHits int64        `json:"cacheHits"`
+
+	StartedAt  time.Time         `json:"startedAt"`
+	Latency    *dns.Latency      `json:"latency,omitempty"` // Of recent upstream queries
+	TopBlocked []dns.DomainCount `json:"topBlocked,omitempty"`
+
+	// For a requested period, its history at the matching resolution
+	Resolution time.Duration  `json:"resolution,omitempty"`
+	History    []stats.Bucket `json:"history,omitempty"`
+}
+
+// openStats opens the persistent counter store. A damaged store is moved
+// aside and counting starts over.
+func (d *Daemon) openStats() {
+	configPath, err := config.Path()
+	if err != nil {
+		log.Printf("Warning: statistics will not be kept: %v", err)
+		return
+	}
+	path := filepath.Join(filepath.Dir(configPath), statsFile)
+
+	store, err := stats.Open(path)
+	if err != nil {
+		log.Printf("Warning: failed to load statistics, starting over: %v", err)
+		os.Rename(path, path+".bad")
+		if store, err = stats.Open(path); err != nil {
+			return
+		}
+	}
+
+	d.mu.Lock()
+	store.SetRetention(statsRetention(d.config))
+	d.stats = store
+	d.mu.Unlock()
+}
+
+// statsRetention returns the configured statistics retention, with
+// defaults for what is not set
+func statsRetention(cfg *config.Config) stats.Retention {
+	retention := stats.DefaultRetention
+	if r := cfg.StatsRetention; r != nil {
+		if r.MinuteHours > 0 {
+			retention.Minutes = time.Duration(r.MinuteHours) * time.Hour
+		}
+		if r.HourlyDays > 0 {
+			retention.Hours = time.Duration(r.HourlyDays) * stats.Daily
+		}
+		if r.DailyDays > 0 {
+			retention.Days = time.Duration(r.DailyDays) * stats.Daily
+		}
+	}
+	return retention
+}
+
+// runStatsFlush periodically moves the proxy's counters into the store
+func (d *Daemon) runStatsFlush() {
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.flushStats()
+		}
+	}
+}
+
+// flushStats records the counters and writes the store to disk
+func (d *Daemon) flushStats() {
+	d.mu.Lock()
+	d.recordStats()
+	store := d.stats
+	d.mu.Unlock()
+
+	if store == nil {
+		return
+	}
+	if err := store.Save(); err != nil {
+		log.Printf("Warning: failed to save statistics: %v", err)
+	}
+}
+
+// recordStats adds what the proxy counted since the last call to the
+// store. Call it before the proxy is stopped or replaced, or its last
+// counts are lost. (must be called with lock held)
+func (d *Daemon) recordStats() {
+	if d.stats == nil {
+		return
+	}
+	d.stats.Add(d.pendingStats(), time.Now())
+
+	d.statsProxy = d.proxy
+	d.statsBase = stats.Counts{}
+	if d.proxy != nil {
+		d.statsBase.Queries, d.statsBase.Blocked = d.proxy.GetStats()
+	}
+}
+
+// pendingStats returns what the proxy counted since the last recordStats
+// (must be called with lock held)
+func (d *Daemon) pendingStats() stats.Counts {
+	if d.proxy == nil || d.proxy != d.statsProxy {
+		return countsOf(d.proxy)
+	}
+
+	current := countsOf(d.proxy)
+	return stats.Counts{
+		Queries: current.Queries - d.statsBase.Queries,
+		Blocked: current.Blocked - d.statsBase.Blocked,
+	}
+}
+
+// statsSummary returns the persistent counters including what has not
+// been written yet, or nil if statistics are not kept
+// (must be called with lock held)
+func (d *Daemon) statsSummary(days int) *stats.Summary {
+	if d.stats == nil {
+		return nil
+	}
+	return d.stats.Summary(d.pendingStats(), days)
+}
+
+// statsReport collects everything "stats" returns, with history for the
+// named period if one is given, or nil if statistics are not kept
+// (must be called with lock held)
+func (d *Daemon) statsReport(period string) (*StatsReport, error) {
+	summary := d.statsSummary(statsDays)
+	if summary == nil {
+		return nil, fmt.Errorf("statistics are not available")
+	}
+
+	report := &StatsReport{
+		Summary:    summary,
+		Session:    countsOf(d.proxy),
+		StartedAt:  d.started,
+		Latency:    d.queryLog.Latency(),
+		TopBlocked: d.queryLog.TopBlocked(topBlocked),
+	}
+	if d.proxy != nil {
+		report.CacheHits = d.proxy.CacheHits()
+	}
+
+	if period != "" {
+		resolution, since, err := stats.Period(period, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		report.Resolution = resolution
+		report.History = d.stats.History(d.pendingStats(), resolution, since)
+	}
+	return report, nil
+}
+
+// countsOf returns a proxy's counters, which start at zero for every proxy
+func countsOf(proxy *dns.Proxy) stats.Counts {
+	if proxy == nil {
+		return stats.Counts{}
+	}
+	total, blocked := proxy.GetStats()
+	return stats.Counts{Queries: total, Blocked: blocked}
+}
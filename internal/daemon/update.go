@@ -0,0 +1,115 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/update"
+)
+
+// updateInterval is how often the daemon looks for a release when
+// automatic updates are on; each daemon adds up to an hour on top so a
+// fleet does not hit the release server all at once
+const updateInterval = 6 * time.Hour
+
+// ErrRestart is returned by Run after an automatic update replaced the
+// binary. The caller should exit non-zero so the service manager starts
+// the new one.
+var ErrRestart = errors.New("restarting into updated binary")
+
+// runAutoUpdate periodically installs newer releases while the config
+// asks for it
+func (d *Daemon) runAutoUpdate() {
+	timer := time.NewTimer(updateInterval + rand.N(time.Hour))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-timer.C:
+			if d.autoUpdate() {
+				return
+			}
+			timer.Reset(updateInterval + rand.N(time.Hour))
+		}
+	}
+}
+
+// autoUpdate installs a newer release if there is one and shuts the
+// daemon down to restart into it. It reports whether it did.
+func (d *Daemon) autoUpdate() bool {
+	d.mu.RLock()
+	enabled := d.config.AutoUpdate
+	releaseURL := d.config.UpdateURL
+	d.mu.RUnlock()
+
+	if !enabled {
+		return false
+	}
+
+	_, installed, err := installUpdate(releaseURL)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	if !installed {
+		return false
+	}
+
+	d.mu.Lock()
+	d.updated = true
+	d.mu.Unlock()
+	d.Shutdown()
+	return true
+}
+
+// installUpdate installs the release from releaseURL if it is newer, and
+// reports whether it did; the daemon must then restart into it
+func installUpdate(releaseURL string) (*update.Update, bool, error) {
+	u, err := update.Check(releaseURL)
+	if err != nil {
+		return nil, false, fmt.Errorf("update check failed: %w", err)
+	}
+	if !u.Available {
+		return u, false, nil
+	}
+
+	path, err := u.Install()
+	if err != nil {
+		return u, false, fmt.Errorf("update to %s failed: %w", u.Latest, err)
+	}
+	log.Printf("Updated %s from %s to %s, restarting", path, u.Current, u.Latest)
+	return u, true, nil
+}
+
+// checkUpdate compares the daemon's build with the latest release, for
+// clients that offer to update it; theirs may be another build, or run
+// on another machine
+func (d *Daemon) checkUpdate() (*update.Update, error) {
+	d.mu.RLock()
+	releaseURL := d.config.UpdateURL
+	d.mu.RUnlock()
+
+	return update.Check(releaseURL)
+}
+
+// updateNow installs a newer release at a client's request, and restarts
+// into it once the client has its answer
+func (d *Daemon) updateNow() (*update.Update, error) {
+	d.mu.RLock()
+	releaseURL := d.config.UpdateURL
+	d.mu.RUnlock()
+
+	u, installed, err := installUpdate(releaseURL)
+	if err != nil || !installed {
+		return u, err
+	}
+	d.mu.Lock()
+	d.updated = true
+	d.mu.Unlock()
+	time.AfterFunc(restartDelay, d.Shutdown)
+	return u, nil
+}
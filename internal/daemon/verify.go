@@ -0,0 +1,68 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/dns"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/system"
+)
+
+// Verification is the result of checking, on request, that filtering is
+// in effect end to end
+type Verification struct {
+	Running     bool   `json:"running"`         // Filtering is on
+	ProxyOK     bool   `json:"proxyOk"`         // Canary resolved through 127.0.0.1:53
+	SystemDNSOK bool   `json:"systemDnsOk"`     // System resolver points at the proxy
+	UpstreamOK  bool   `json:"upstreamOk"`      // FilterDNS answered with the profile's password
+	Code        string `json:"code,omitempty"`  // Why filtering is not in effect, if it is not
+	Error       string `json:"error,omitempty"` // Details for Code
+}
+
+// OK reports whether filtering is verifiably in effect
+func (v *Verification) OK() bool {
+	return v.Running && v.ProxyOK && v.SystemDNSOK && v.UpstreamOK
+}
+
+// verify checks the proxy, system DNS and the upstream
+func (d *Daemon) verify(ctx context.Context) *Verification {
+	d.mu.RLock()
+	proxy := d.proxy
+	running := d.running
+	d.mu.RUnlock()
+
+	servers, err := system.GetCurrentDNS()
+	v := &Verification{
+		Running:     running && proxy != nil,
+		SystemDNSOK: err == nil && slices.Contains(servers, "127.0.0.1"),
+	}
+	if !v.Running {
+		v.Code, v.Error = CodeNotRunning, "filtering is off"
+		return v
+	}
+
+	v.ProxyOK = queryCanary()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := proxy.CheckUpstream(ctx, canaryDomain); err != nil {
+		v.Code, v.Error = CodeUpstreamFailed, err.Error()
+		if errors.Is(err, dns.ErrUnauthorized) {
+			v.Code = CodeAuthFailed
+		}
+	} else {
+		v.UpstreamOK = true
+	}
+
+	// Upstream problems come first: they will not fix themselves by waiting
+	switch {
+	case v.Code != "":
+	case !v.ProxyOK:
+		v.Code, v.Error = CodeProxyFailed, "the local proxy did not answer"
+	case !v.SystemDNSOK:
+		v.Code, v.Error = CodeDNSFailed, "system DNS does not point at the proxy"
+	}
+	return v
+}
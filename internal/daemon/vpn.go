@@ -0,0 +1,236 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/system"
+)
+
+// Kinds of VPNConflict
+const (
+	// The VPN's domains have no forwarder, so the proxy sends them to the
+	// profile, which cannot resolve them
+	VPNMissingForwarders = "forwarders"
+
+	// The VPN pointed system DNS at its own servers, past the proxy; the
+	// daemon points it back
+	VPNTakeover = "takeover"
+
+	// The VPN keeps taking system DNS back, so the daemon stopped pointing
+	// it at the proxy until the user resolves the conflict
+	VPNStalemate = "stalemate"
+)
+
+const (
+	// vpnTakeoverLimit takeovers by one VPN within vpnTakeoverWindow make
+	// a stalemate: its client reasserts its DNS, and fighting it only
+	// makes name resolution flap
+	vpnTakeoverLimit  = 3
+	vpnTakeoverWindow = 15 * time.Minute
+)
+
+// VPNConflict is a VPN whose DNS does not get along with the proxy, with
+// what would resolve it
+type VPNConflict struct {
+	Interface string    `json:"interface"`
+	VPN       string    `json:"vpn"`  // One of the system.VPN* kinds
+	Kind      string    `json:"kind"` // One of the VPN* conflict kinds
+	Domains   []string  `json:"domains,omitempty"`
+	Servers   []string  `json:"servers,omitempty"`
+	Since     time.Time `json:"since"`
+
+	// Forwarders that would send the VPN's domains to its servers, of
+	// those still missing
+	Forwarders []config.Forwarder `json:"forwarders,omitempty"`
+
+	// What the user can do about it, for VPNTakeover and VPNStalemate
+	Remedy string `json:"remedy,omitempty"`
+}
+
+// VPNForwarders suggests a forwarder to the VPN's first DNS server for
+// each of its domains, skipping those already forwarded
+func VPNForwarders(vpn system.VPN, existing []config.Forwarder) []config.Forwarder {
+	if len(vpn.Servers) == 0 {
+		return nil
+	}
+	var forwarders []config.Forwarder
+	for _, domain := range vpn.Domains {
+		if slices.ContainsFunc(existing, func(f config.Forwarder) bool { return f.Domain == domain }) {
+			continue
+		}
+		if config.ValidateDomain(domain) != nil {
+			continue
+		}
+		forwarders = append(forwarders, config.Forwarder{Domain: domain, Server: vpn.Servers[0]})
+	}
+	return forwarders
+}
+
+// VPNRemedy tells how to keep a VPN of the kind from taking over system
+// DNS, as a format taking its interface, so clients can translate it
+func VPNRemedy(kind string) string {
+	switch kind {
+	case system.VPNTailscale:
+		return "Run \"tailscale set --accept-dns=false\" and add a forwarder for the tailnet, or ignore %s"
+	case system.VPNWireGuard:
+		return "Remove the DNS line from the tunnel's configuration and add forwarders for its domains, or ignore %s"
+	case system.VPNOther:
+		return "Add forwarders for the VPN's internal domains and keep its client from setting DNS " +
+			"(for OpenVPN: pull-filter ignore \"dhcp-option DNS\"), or ignore %s"
+	}
+	return "Keep the VPN from setting DNS and add forwarders for its domains, or ignore %s"
+}
+
+// checkVPNs classifies how the active VPNs get in the way of the proxy,
+// adds the forwarders they lack if the config asks for it, and, if count
+// is set, counts the takeovers of system DNS towards a stalemate. It
+// returns the conflicts found. (must be called with lock held)
+func (d *Daemon) checkVPNs(vpns []system.VPN, count bool) []VPNConflict {
+	if !d.running {
+		d.vpnConflicts, d.vpnTakeovers = nil, nil
+		return nil
+	}
+
+	d.addVPNForwarders(vpns)
+	existing := d.config.Effective().Forwarders
+
+	now := time.Now()
+	var conflicts []VPNConflict
+	for _, vpn := range vpns {
+		if !d.config.ManagesInterface(vpn.Interface) {
+			continue
+		}
+		conflict := VPNConflict{
+			Interface: vpn.Interface,
+			VPN:       vpn.Kind,
+			Domains:   vpn.Domains,
+			Servers:   vpn.Servers,
+			Since:     now,
+
+			Forwarders: VPNForwarders(vpn, existing),
+		}
+		prev := d.vpnConflict(vpn.Interface)
+		remedy := fmt.Sprintf(VPNRemedy(vpn.Kind), vpn.Interface)
+
+		takeovers := d.vpnTakeovers[vpn.Interface]
+		switch {
+		case len(takeovers) >= vpnTakeoverLimit && vpn.TakesAllDNS:
+			conflict.Kind = VPNStalemate
+			conflict.Remedy = remedy
+		case vpn.TakesAllDNS && !count:
+			conflict.Kind = VPNTakeover
+			conflict.Remedy = remedy
+		case vpn.TakesAllDNS:
+			takeovers = slices.DeleteFunc(takeovers, func(t time.Time) bool { return now.Sub(t) > vpnTakeoverWindow })
+			takeovers = append(takeovers, now)
+			if d.vpnTakeovers == nil {
+				d.vpnTakeovers = make(map[string][]time.Time)
+			}
+			d.vpnTakeovers[vpn.Interface] = takeovers
+
+			conflict.Kind = VPNTakeover
+			conflict.Remedy = remedy
+			if len(takeovers) >= vpnTakeoverLimit {
+				conflict.Kind = VPNStalemate
+				d.healthEvent(fmt.Sprintf("%s on %s keeps taking over system DNS, no longer pointing it back at the proxy. %s",
+					vpn.Kind, vpn.Interface, conflict.Remedy))
+			}
+		default:
+			// The VPN let go of system DNS, which ends a stalemate
+			if len(takeovers) >= vpnTakeoverLimit {
+				delete(d.vpnTakeovers, vpn.Interface)
+			}
+
+			// Once pointed at the proxy, the interface no longer shows
+			// the VPN's servers, so keep suggesting what was found before
+			if len(vpn.Servers) == 0 && prev != nil {
+				conflict.Forwarders = slices.DeleteFunc(slices.Clone(prev.Forwarders), func(f config.Forwarder) bool {
+					return slices.ContainsFunc(existing, func(e config.Forwarder) bool { return e.Domain == f.Domain })
+				})
+				conflict.Servers = prev.Servers
+			}
+			if len(conflict.Forwarders) == 0 {
+				continue
+			}
+			conflict.Kind = VPNMissingForwarders
+		}
+
+		if prev != nil && prev.Kind == conflict.Kind {
+			conflict.Since = prev.Since
+		} else if conflict.Kind == VPNMissingForwarders {
+			log.Printf("%s on %s has no forwarders for %s", vpn.Kind, vpn.Interface, strings.Join(vpn.Domains, ", "))
+		}
+		conflicts = append(conflicts, conflict)
+	}
+
+	// A VPN that went away takes its takeovers along
+	for iface := range d.vpnTakeovers {
+		if !slices.ContainsFunc(vpns, func(vpn system.VPN) bool { return vpn.Interface == iface }) {
+			delete(d.vpnTakeovers, iface)
+		}
+	}
+	d.vpnConflicts = conflicts
+	return conflicts
+}
+
+// vpnConflict returns the last conflict found for an interface, if any
+// (must be called with lock held)
+func (d *Daemon) vpnConflict(iface string) *VPNConflict {
+	for i := range d.vpnConflicts {
+		if d.vpnConflicts[i].Interface == iface {
+			return &d.vpnConflicts[i]
+		}
+	}
+	return nil
+}
+
+// addVPNForwarders saves forwarders for the domains of the VPNs that
+// lack them, if the config turns that on and no policy locks the
+// forwarders (must be called with lock held)
+func (d *Daemon) addVPNForwarders(vpns []system.VPN) {
+	if !d.config.AutoVPNForwarders || config.CurrentPolicy().Locked("forwarders") {
+		return
+	}
+
+	var add []config.Forwarder
+	var domains []string
+	for _, vpn := range vpns {
+		if !d.config.ManagesInterface(vpn.Interface) {
+			continue
+		}
+		for _, f := range VPNForwarders(vpn, slices.Concat(d.config.Effective().Forwarders, add)) {
+			add = append(add, f)
+			domains = append(domains, f.Domain)
+		}
+	}
+	if len(add) == 0 {
+		return
+	}
+
+	cfg := *d.config
+	cfg.Forwarders = slices.Concat(d.config.Forwarders, add)
+	if err := config.Save(&cfg); err != nil {
+		log.Printf("Failed to add forwarders for VPN domains: %v", err)
+		return
+	}
+	log.Printf("Added forwarders for VPN domains %s", strings.Join(domains, ", "))
+	d.applyConfig(&cfg)
+	d.publishReload()
+}
+
+// vpnStalemates lists the interfaces of VPNs the daemon stopped pointing
+// back at the proxy (must be called with lock held)
+func (d *Daemon) vpnStalemates() []string {
+	var ifaces []string
+	for _, c := range d.vpnConflicts {
+		if c.Kind == VPNStalemate {
+			ifaces = append(ifaces, c.Interface)
+		}
+	}
+	return ifaces
+}
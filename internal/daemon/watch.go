@@ -0,0 +1,96 @@
+package daemon
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+)
+
+// configSettle is how long the config file must stay unchanged before it
+// is read, so a save made of several writes is read once, complete
+const configSettle = 500 * time.Millisecond
+
+// watchConfig applies changes to the config file as they are saved, by
+// the GUI, the CLI or configuration management. Its directory is watched
+// rather than the file, which atomic saves replace.
+func (d *Daemon) watchConfig() {
+	path := config.PureConfig()
+	if path == "" {
+		var err error
+		if path, err = config.Path(); err != nil {
+			log.Printf("Not watching the config file: %v", err)
+			return
+		}
+	}
+	path, _ = filepath.Abs(path)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Not watching the config file: %v", err)
+		return
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		log.Printf("Not watching the config file: %v", err)
+		return
+	}
+
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) == path && !event.Has(fsnotify.Chmod) {
+				settle = time.After(configSettle)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Config file watch error: %v", err)
+		case <-settle:
+			settle = nil
+			d.configFileChanged(path)
+		}
+	}
+}
+
+// configFileChanged applies the config file at path if its settings
+// differ from the ones in use; the daemon's own saves match them
+func (d *Daemon) configFileChanged(path string) {
+	// An editor may still be writing it; the backup is no substitute
+	data, err := os.ReadFile(path)
+	if err != nil || !json.Valid(data) {
+		log.Printf("Config file changed but cannot be read yet, waiting for the next change")
+		return
+	}
+	cfg, err := config.Load()
+	if err == nil {
+		err = config.Validate(cfg)
+	}
+	if err != nil {
+		log.Printf("Ignoring changed config file: %v", err)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.keepRuntimeState(cfg)
+	if config.SettingsEqual(cfg, d.config) {
+		return
+	}
+	log.Println("Config file changed, applying it")
+	d.applyConfig(cfg)
+	d.selectNetwork()
+	d.publishReload()
+}
@@ -1,6 +1,8 @@
 package dns
 
 import (
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -94,6 +96,30 @@ func (c *Cache) Set(domain string, qtype uint16, msg *dns.Msg) {
 	}
 }
 
+// SetMaxSize changes the number of entries kept, evicting the oldest
+// ones if there are more
+func (c *Cache) SetMaxSize(maxSize int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxSize = maxSize
+	excess := len(c.entries) - maxSize
+	if excess <= 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return c.entries[a].expiresAt.Compare(c.entries[b].expiresAt)
+	})
+	for _, key := range keys[:excess] {
+		delete(c.entries, key)
+	}
+}
+
 // evictOldest removes the oldest entry (must be called with lock held)
 func (c *Cache) evictOldest() {
 	var oldestKey string
@@ -128,6 +154,114 @@ func (c *Cache) cleanup() {
 	}
 }
 
+// CacheStats describes the cache's contents and, once filled in by the
+// proxy, how often it answered
+type CacheStats struct {
+	Entries int   `json:"entries"`
+	MaxSize int   `json:"maxSize"`
+	Bytes   int   `json:"bytes"` // Approximate memory held by cached answers
+	Hits    int64 `json:"hits"`
+	Queries int64 `json:"queries"`
+}
+
+// CacheEntry is a cached answer as shown by "cache dump"
+type CacheEntry struct {
+	Domain    string    `json:"domain"`
+	Type      string    `json:"type"`
+	Rcode     string    `json:"rcode"`
+	Answers   []string  `json:"answers,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Stats returns the number of entries, the limit and the approximate size
+func (c *Cache) Stats() CacheStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats := CacheStats{Entries: len(c.entries), MaxSize: c.maxSize}
+	for key, entry := range c.entries {
+		stats.Bytes += len(key) + entry.msg.Len()
+	}
+	return stats
+}
+
+// Entries returns the unexpired entries whose domain contains domain (all
+// if it is empty), sorted by domain and type
+func (c *Cache) Entries(domain string) []CacheEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	domain = strings.ToLower(domain)
+	now := time.Now()
+
+	var entries []CacheEntry
+	for key, entry := range c.entries {
+		name, qtype := splitCacheKey(key)
+		if now.After(entry.expiresAt) || !strings.Contains(name, domain) {
+			continue
+		}
+
+		e := CacheEntry{
+			Domain:    name,
+			Type:      qtype,
+			Rcode:     dns.RcodeToString[entry.msg.Rcode],
+			ExpiresAt: entry.expiresAt,
+		}
+		for _, rr := range entry.msg.Answer {
+			e.Answers = append(e.Answers, strings.TrimPrefix(rr.String(), rr.Header().String()))
+		}
+		entries = append(entries, e)
+	}
+
+	slices.SortFunc(entries, func(a, b CacheEntry) int {
+		if n := strings.Compare(a.Domain, b.Domain); n != 0 {
+			return n
+		}
+		return strings.Compare(a.Type, b.Type)
+	})
+	return entries
+}
+
+// Remove drops the entries whose domain contains domain and returns how
+// many there were
+func (c *Cache) Remove(domain string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	domain = strings.ToLower(domain)
+	removed := 0
+	for key := range c.entries {
+		if name, _ := splitCacheKey(key); strings.Contains(name, domain) {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// RemoveZone drops the entries for domain and its subdomains and returns
+// how many there were
+func (c *Cache) RemoveZone(domain string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	zone := dns.Fqdn(strings.ToLower(domain))
+	removed := 0
+	for key := range c.entries {
+		if name, _ := splitCacheKey(key); dns.IsSubDomain(zone, name) {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// splitCacheKey splits a cache key into domain and query type
+func splitCacheKey(key string) (domain, qtype string) {
+	i := strings.LastIndexByte(key, ':')
+	return key[:i], key[i+1:]
+}
+
 // Clear removes all entries from the cache
 func (c *Cache) Clear() {
 	c.mu.Lock()
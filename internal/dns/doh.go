@@ -4,37 +4,84 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/miekg/dns"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
 )
 
+// ErrUnauthorized is returned by queries the server rejects because the
+// profile password is missing or wrong
+var ErrUnauthorized = errors.New("server rejected the profile password")
+
 // Bootstrap DNS servers used to resolve the DoH server hostname
-var bootstrapDNS = []string{
+var defaultBootstrapDNS = []string{
 	"1.1.1.1:53", // Cloudflare
 	"8.8.8.8:53", // Google
 	"9.9.9.9:53", // Quad9
 }
 
+var (
+	bootstrapMu  sync.Mutex
+	bootstrapDNS = defaultBootstrapDNS
+)
+
+// SetBootstrapDNS replaces the bootstrap servers, given as IP addresses
+// with an optional port; an empty list restores the built-in ones
+func SetBootstrapDNS(servers []string) {
+	addrs := make([]string, 0, len(servers))
+	for _, server := range servers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		addrs = append(addrs, server)
+	}
+	if len(addrs) == 0 {
+		addrs = defaultBootstrapDNS
+	}
+
+	bootstrapMu.Lock()
+	defer bootstrapMu.Unlock()
+	bootstrapDNS = addrs
+}
+
+// bootstrapServers returns the current bootstrap servers
+func bootstrapServers() []string {
+	bootstrapMu.Lock()
+	defer bootstrapMu.Unlock()
+	return bootstrapDNS
+}
+
 // DoHClient is a DNS-over-HTTPS client for FilterDNS
 type DoHClient struct {
-	serverURL  string
-	profile    string
-	httpClient *http.Client
-	serverIP   string // Resolved IP of the DoH server
+	queryURL     string // DoH URL of the profile, without the dns parameter
+	httpClient   *http.Client
+	serverIP     string // Resolved IP of the DoH server
+	bootstrapNet string // "udp", or "tcp" when the network blocks UDP/53
+	mu           sync.Mutex
 }
 
-// NewDoHClient creates a new DoH client
+// NewDoHClient creates a new DoH client for a profile on a server
 func NewDoHClient(serverURL, profile string) *DoHClient {
+	return NewDoHClientURL(config.DefaultDoHURL(serverURL, profile))
+}
+
+// NewDoHClientURL creates a DoH client for a full DoH URL, such as one
+// the server reported for the profile, e.g.
+// "https://dns.example.com/dns-query?profile=kids"
+func NewDoHClientURL(queryURL string) *DoHClient {
 	client := &DoHClient{
-		serverURL: serverURL,
-		profile:   profile,
+		queryURL:     queryURL,
+		bootstrapNet: "udp",
 	}
 
 	// Resolve the DoH server's IP using bootstrap DNS
@@ -46,20 +93,37 @@ func NewDoHClient(serverURL, profile string) *DoHClient {
 		Transport: &http.Transport{
 			DialContext: client.dialContext,
 		},
+		CheckRedirect: checkRedirect,
 	}
 
 	return client
 }
 
+// checkRedirect follows redirects like the default policy, but drops the
+// profile password when one leads to another host, so it never leaves
+// the server it was meant for
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if !strings.EqualFold(req.URL.Hostname(), via[0].URL.Hostname()) || req.URL.Scheme != "https" {
+		req.Header.Del("X-FilterDNS-Password")
+	}
+	return nil
+}
+
 // resolveServerIP resolves the DoH server hostname using bootstrap DNS
 func (c *DoHClient) resolveServerIP() {
-	parsed, err := url.Parse(c.serverURL)
+	parsed, err := url.Parse(c.queryURL)
 	if err != nil {
 		return
 	}
 
 	hostname := parsed.Hostname()
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	// Check if it's already an IP
 	if ip := net.ParseIP(hostname); ip != nil {
 		c.serverIP = ip.String()
@@ -67,11 +131,11 @@ func (c *DoHClient) resolveServerIP() {
 	}
 
 	// Resolve using bootstrap DNS
-	for _, bootstrap := range bootstrapDNS {
-		ip, err := resolveWithDNS(hostname, bootstrap)
+	for _, bootstrap := range bootstrapServers() {
+		ip, err := resolveWithDNS(hostname, bootstrap, c.bootstrapNet)
 		if err == nil && ip != "" {
 			c.serverIP = ip
-			log.Printf("Resolved %s to %s using bootstrap DNS %s", hostname, ip, bootstrap)
+			log.Printf("Resolved %s to %s using bootstrap DNS %s/%s", hostname, ip, bootstrap, c.bootstrapNet)
 			return
 		}
 	}
@@ -80,9 +144,9 @@ func (c *DoHClient) resolveServerIP() {
 }
 
 // resolveWithDNS resolves a hostname using a specific DNS server
-func resolveWithDNS(hostname, dnsServer string) (string, error) {
+func resolveWithDNS(hostname, dnsServer, network string) (string, error) {
 	client := &dns.Client{
-		Net:     "udp",
+		Net:     network,
 		Timeout: 5 * time.Second,
 	}
 
@@ -105,13 +169,17 @@ func resolveWithDNS(hostname, dnsServer string) (string, error) {
 
 // dialContext is a custom dialer that uses the pre-resolved IP
 func (c *DoHClient) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	c.mu.Lock()
+	serverIP := c.serverIP
+	c.mu.Unlock()
+
 	// If we have a resolved IP, use it
-	if c.serverIP != "" {
+	if serverIP != "" {
 		host, port, err := net.SplitHostPort(addr)
 		if err == nil {
-			parsed, _ := url.Parse(c.serverURL)
+			parsed, _ := url.Parse(c.queryURL)
 			if parsed != nil && host == parsed.Hostname() {
-				addr = net.JoinHostPort(c.serverIP, port)
+				addr = net.JoinHostPort(serverIP, port)
 			}
 		}
 	}
@@ -132,10 +200,11 @@ func (c *DoHClient) Query(ctx context.Context, msg *dns.Msg, password string) (*
 
 	// Build the DoH URL
 	// FilterDNS expects: /dns-query?profile=<name>
-	url := fmt.Sprintf("%s/dns-query?dns=%s", c.serverURL, base64.RawURLEncoding.EncodeToString(packed))
-	if c.profile != "" {
-		url = fmt.Sprintf("%s&profile=%s", url, c.profile)
+	sep := "?"
+	if strings.Contains(c.queryURL, "?") {
+		sep = "&"
 	}
+	url := c.queryURL + sep + "dns=" + base64.RawURLEncoding.EncodeToString(packed)
 
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -157,6 +226,9 @@ func (c *DoHClient) Query(ctx context.Context, msg *dns.Msg, password string) (*
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("DoH server returned %d: %w", resp.StatusCode, ErrUnauthorized)
+	}
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("DoH server returned %d: %s", resp.StatusCode, string(body))
@@ -185,14 +257,8 @@ func (c *DoHClient) QueryPOST(ctx context.Context, msg *dns.Msg, password string
 		return nil, fmt.Errorf("failed to pack DNS message: %w", err)
 	}
 
-	// Build the DoH URL
-	url := fmt.Sprintf("%s/dns-query", c.serverURL)
-	if c.profile != "" {
-		url = fmt.Sprintf("%s?profile=%s", url, c.profile)
-	}
-
 	// Create request
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(packed))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.queryURL, bytes.NewReader(packed))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -212,6 +278,9 @@ func (c *DoHClient) QueryPOST(ctx context.Context, msg *dns.Msg, password string
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("DoH server returned %d: %w", resp.StatusCode, ErrUnauthorized)
+	}
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("DoH server returned %d: %s", resp.StatusCode, string(body))
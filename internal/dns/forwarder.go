@@ -1,8 +1,11 @@
 package dns
 
 import (
+	"net"
 	"strings"
+	"time"
 
+	"github.com/miekg/dns"
 	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
 )
 
@@ -12,9 +15,10 @@ type ForwarderMatcher struct {
 }
 
 type forwarderRule struct {
-	pattern string // The domain pattern (e.g., "ts.net", "*.internal")
-	server  string // The DNS server to forward to
-	isWild  bool   // Whether the pattern starts with *
+	forwarder config.Forwarder // The rule as configured
+	pattern   string           // The domain pattern (e.g., "ts.net", "*.internal")
+	server    string           // The DNS server to forward to
+	isWild    bool             // Whether the pattern starts with *
 }
 
 // NewForwarderMatcher creates a new forwarder matcher
@@ -29,9 +33,10 @@ func NewForwarderMatcher(forwarders []config.Forwarder) *ForwarderMatcher {
 		}
 
 		rules = append(rules, forwarderRule{
-			pattern: domain,
-			server:  f.Server,
-			isWild:  isWild,
+			forwarder: f,
+			pattern:   domain,
+			server:    f.Server,
+			isWild:    isWild,
 		})
 	}
 	return &ForwarderMatcher{rules: rules}
@@ -39,21 +44,90 @@ func NewForwarderMatcher(forwarders []config.Forwarder) *ForwarderMatcher {
 
 // Match returns the DNS server to forward to for a given domain, or "" if no match
 func (m *ForwarderMatcher) Match(domain string) string {
+	if rule := m.MatchRule(domain); rule != nil {
+		return rule.Server
+	}
+	return ""
+}
+
+// MatchRule returns the first forwarder rule matching a domain, or nil
+func (m *ForwarderMatcher) MatchRule(domain string) *config.Forwarder {
 	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
 
 	for _, rule := range m.rules {
 		if rule.isWild {
 			// Wildcard match: *.example.com matches foo.example.com and bar.foo.example.com
 			if domain == rule.pattern || strings.HasSuffix(domain, "."+rule.pattern) {
-				return rule.server
+				return &rule.forwarder
 			}
 		} else {
 			// Exact match or suffix match
 			if domain == rule.pattern || strings.HasSuffix(domain, "."+rule.pattern) {
-				return rule.server
+				return &rule.forwarder
 			}
 		}
 	}
 
-	return ""
+	return nil
+}
+
+// exchangeForwarder sends r to a forwarder's server, on port 53 unless
+// the server names another
+func exchangeForwarder(r *dns.Msg, server string) (*dns.Msg, time.Duration, error) {
+	// Ensure server has a port
+	if !strings.Contains(server, ":") {
+		server = net.JoinHostPort(server, "53")
+	}
+
+	client := &dns.Client{
+		Net:     "udp",
+		Timeout: 5 * time.Second,
+	}
+	return client.Exchange(r, server)
+}
+
+// ForwarderTest is the result of TestForwarder
+type ForwarderTest struct {
+	Domain   string            `json:"domain"`
+	Type     string            `json:"type"`
+	Rule     *config.Forwarder `json:"rule,omitempty"` // nil if the domain goes to FilterDNS
+	Rcode    string            `json:"rcode,omitempty"`
+	Answers  []string          `json:"answers,omitempty"`
+	Duration time.Duration     `json:"duration,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// TestForwarder finds the rule among forwarders that domain would be
+// sent to and queries its server directly for a record type such as "A",
+// bypassing the proxy and cache
+func TestForwarder(forwarders []config.Forwarder, domain, qtypeName string) (*ForwarderTest, error) {
+	qtype, err := parseType(qtypeName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ForwarderTest{
+		Domain: strings.ToLower(strings.TrimSuffix(domain, ".")),
+		Type:   dns.TypeToString[qtype],
+		Rule:   NewForwarderMatcher(forwarders).MatchRule(domain),
+	}
+	if result.Rule == nil {
+		return result, nil
+	}
+
+	r := new(dns.Msg)
+	r.SetQuestion(dns.Fqdn(result.Domain), qtype)
+	r.RecursionDesired = true
+
+	resp, rtt, err := exchangeForwarder(r, result.Rule.Server)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.Duration = rtt
+	result.Rcode = dns.RcodeToString[resp.Rcode]
+	for _, rr := range resp.Answer {
+		result.Answers = append(result.Answers, strings.TrimPrefix(rr.String(), rr.Header().String()))
+	}
+	return result, nil
 }
@@ -0,0 +1,186 @@
+package dns
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+)
+
+// Integrity summarizes the answers re-checked by the verifier
+type Integrity struct {
+	Checked    int64              `json:"checked"`
+	Mismatches int64              `json:"mismatches"`
+	Last       *IntegrityMismatch `json:"last,omitempty"`
+}
+
+// IntegrityMismatch is an answer the verification channel disagreed with
+type IntegrityMismatch struct {
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	Upstream []string  `json:"upstream"` // What the proxy served
+	Verified []string  `json:"verified"` // What the pinned channel returned
+	At       time.Time `json:"at"`
+}
+
+// Verifier re-resolves a sample of answers over a second, independent
+// DoH connection to the server: its address is bootstrapped over TCP and
+// its TLS certificate is pinned on first use, so local tampering with
+// either the regular connection or the bootstrap is likely to show up as
+// disagreeing answers
+type Verifier struct {
+	client *DoHClient
+	every  int64
+	seen   atomic.Int64
+
+	mu        sync.Mutex
+	pin       []byte // SHA-256 of the server's public key
+	integrity Integrity
+}
+
+// NewVerifier creates a verifier that re-checks one in every answers
+func NewVerifier(cfg *config.Config, every int) *Verifier {
+	v := &Verifier{every: int64(every)}
+
+	client := &DoHClient{
+		queryURL:     cfg.DoHURL(),
+		bootstrapNet: "tcp",
+	}
+	client.resolveServerIP()
+	client.httpClient = &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:     client.dialContext,
+			TLSClientConfig: &tls.Config{VerifyConnection: v.verifyPin},
+		},
+		CheckRedirect: checkRedirect,
+	}
+	v.client = client
+
+	return v
+}
+
+// Sample reports whether the next answer should be verified
+func (v *Verifier) Sample() bool {
+	return v.seen.Add(1)%v.every == 0
+}
+
+// Check re-resolves query and compares the result with the answer the
+// proxy served. It blocks, so callers run it in the background.
+func (v *Verifier) Check(query, served *dns.Msg, password string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	verified, err := v.client.Query(ctx, query, password)
+	if err != nil {
+		log.Printf("Integrity check failed: %v", err)
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.integrity.Checked++
+	if answersAgree(served, verified) {
+		return
+	}
+
+	q := query.Question[0]
+	mismatch := &IntegrityMismatch{
+		Name:     q.Name,
+		Type:     dns.TypeToString[q.Qtype],
+		Upstream: answerSummary(served),
+		Verified: answerSummary(verified),
+		At:       time.Now(),
+	}
+	v.integrity.Mismatches++
+	v.integrity.Last = mismatch
+
+	log.Printf("Integrity mismatch for %s %s: served %v, verified %v - the network may be tampering with DNS",
+		mismatch.Name, mismatch.Type, mismatch.Upstream, mismatch.Verified)
+}
+
+// Integrity returns a snapshot of the verification results
+func (v *Verifier) Integrity() *Integrity {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	integrity := v.integrity
+	return &integrity
+}
+
+// verifyPin pins the server's public key on the first handshake and
+// rejects any later connection presenting a different one
+func (v *Verifier) verifyPin(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return fmt.Errorf("no server certificate")
+	}
+	leaf := cs.PeerCertificates[0]
+	sum := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.pin == nil {
+		v.pin = sum[:]
+		return nil
+	}
+	if !slices.Equal(v.pin, sum[:]) {
+		v.integrity.Mismatches++
+		log.Printf("Integrity: server key for %s changed since first use - possible TLS interception",
+			leaf.Subject.CommonName)
+		return fmt.Errorf("server public key does not match pin")
+	}
+	return nil
+}
+
+// answersAgree compares two answers loosely enough to tolerate load
+// balancing: the rcodes must match and, if both carry addresses, at least
+// one address must be shared
+func answersAgree(a, b *dns.Msg) bool {
+	if a.Rcode != b.Rcode {
+		return false
+	}
+
+	addrsA, addrsB := answerAddrs(a), answerAddrs(b)
+	if len(addrsA) == 0 || len(addrsB) == 0 {
+		return true
+	}
+	for _, addr := range addrsA {
+		if slices.Contains(addrsB, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// answerAddrs returns the A and AAAA addresses in a response
+func answerAddrs(msg *dns.Msg) []string {
+	var addrs []string
+	for _, rr := range msg.Answer {
+		switch rr := rr.(type) {
+		case *dns.A:
+			addrs = append(addrs, rr.A.String())
+		case *dns.AAAA:
+			addrs = append(addrs, rr.AAAA.String())
+		}
+	}
+	return addrs
+}
+
+// answerSummary describes a response for logs and status
+func answerSummary(msg *dns.Msg) []string {
+	if addrs := answerAddrs(msg); len(addrs) > 0 {
+		return addrs
+	}
+	return []string{dns.RcodeToString[msg.Rcode]}
+}
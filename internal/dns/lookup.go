@@ -0,0 +1,77 @@
+package dns
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// LookupResult is the answer to a query sent by Lookup
+type LookupResult struct {
+	Server   string        `json:"server"`
+	Domain   string        `json:"domain"`
+	Type     string        `json:"type"`
+	Rcode    string        `json:"rcode"`
+	Answers  []string      `json:"answers,omitempty"`
+	EDE      string        `json:"ede,omitempty"` // Extended DNS Error, e.g. "Blocked: ads"
+	Duration time.Duration `json:"duration"`
+}
+
+// Lookup queries server ("host:port") for a record type such as "A" the
+// way a stub resolver would: over UDP, retrying over TCP if the answer
+// is truncated
+func Lookup(server, domain, qtypeName string) (*LookupResult, error) {
+	qtype, err := parseType(qtypeName)
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(dns.Msg)
+	r.SetQuestion(dns.Fqdn(strings.ToLower(domain)), qtype)
+	r.RecursionDesired = true
+	r.SetEdns0(dns.DefaultMsgSize, false)
+
+	start := time.Now()
+	client := &dns.Client{Net: "udp", Timeout: 5 * time.Second}
+	resp, _, err := client.Exchange(r, server)
+	if err == nil && resp.Truncated {
+		client.Net = "tcp"
+		resp, _, err = client.Exchange(r, server)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LookupResult{
+		Server:   server,
+		Domain:   strings.TrimSuffix(r.Question[0].Name, "."),
+		Type:     dns.TypeToString[qtype],
+		Rcode:    dns.RcodeToString[resp.Rcode],
+		Duration: time.Since(start),
+	}
+	for _, rr := range resp.Answer {
+		result.Answers = append(result.Answers, strings.TrimPrefix(rr.String(), rr.Header().String()))
+	}
+	if opt := resp.IsEdns0(); opt != nil {
+		for _, option := range opt.Option {
+			if e, ok := option.(*dns.EDNS0_EDE); ok {
+				result.EDE = dns.ExtendedErrorCodeToString[e.InfoCode]
+				if e.ExtraText != "" {
+					result.EDE += ": " + e.ExtraText
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// parseType reads a record type name such as "A" or "aaaa"
+func parseType(name string) (uint16, error) {
+	qtype, ok := dns.StringToType[strings.ToUpper(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown record type: %s", name)
+	}
+	return qtype, nil
+}
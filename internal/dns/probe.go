@@ -0,0 +1,148 @@
+package dns
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const probeTimeout = 3 * time.Second
+
+// Connectivity records which upstream paths the current network allows
+type Connectivity struct {
+	UDP53     bool      `json:"udp53"`     // Plain DNS to the bootstrap resolvers
+	TCP853    bool      `json:"tcp853"`    // DNS-over-TLS to the server
+	TCP443    bool      `json:"tcp443"`    // DNS-over-HTTPS (HTTP/2) to the server
+	UDP443    bool      `json:"udp443"`    // DNS-over-HTTP/3 (QUIC) to the server
+	Preferred string    `json:"preferred"` // Suggested transport, "" if nothing works
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Probe checks which transports can reach the DoH server and the
+// bootstrap resolvers from the current network
+func (c *DoHClient) Probe() *Connectivity {
+	parsed, err := url.Parse(c.queryURL)
+	if err != nil {
+		return &Connectivity{CheckedAt: time.Now()}
+	}
+	hostname := parsed.Hostname()
+
+	c.mu.Lock()
+	host := c.serverIP
+	c.mu.Unlock()
+	if host == "" {
+		host = hostname
+	}
+
+	result := &Connectivity{}
+	var wg sync.WaitGroup
+	probe := func(ok *bool, fn func() bool) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			*ok = fn()
+		}()
+	}
+
+	probe(&result.UDP53, probeUDP53)
+	probe(&result.TCP853, func() bool { return probeTLS(host, "853", hostname, nil) })
+	probe(&result.TCP443, func() bool { return probeTLS(host, "443", hostname, []string{"h2", "http/1.1"}) })
+	probe(&result.UDP443, func() bool { return probeQUIC(host) })
+	wg.Wait()
+
+	switch {
+	case result.TCP443:
+		result.Preferred = "doh"
+	case result.TCP853:
+		result.Preferred = "dot"
+	case result.UDP443:
+		result.Preferred = "doh3"
+	case result.UDP53:
+		result.Preferred = "dns"
+	}
+	result.CheckedAt = time.Now()
+
+	// Bootstrap over TCP if the network drops outgoing UDP/53
+	c.mu.Lock()
+	if result.UDP53 {
+		c.bootstrapNet = "udp"
+	} else {
+		c.bootstrapNet = "tcp"
+	}
+	unresolved := c.serverIP == ""
+	c.mu.Unlock()
+
+	if unresolved {
+		c.resolveServerIP()
+	}
+
+	return result
+}
+
+// probeUDP53 checks whether any bootstrap resolver answers over UDP
+func probeUDP53() bool {
+	client := &dns.Client{Net: "udp", Timeout: probeTimeout}
+	msg := new(dns.Msg)
+	msg.SetQuestion(".", dns.TypeNS)
+
+	for _, server := range bootstrapServers() {
+		if _, _, err := client.Exchange(msg, server); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// probeTLS checks whether a TLS handshake with the server completes
+func probeTLS(host, port, serverName string, nextProtos []string) bool {
+	dialer := &net.Dialer{Timeout: probeTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, port), &tls.Config{
+		ServerName: serverName,
+		NextProtos: nextProtos,
+	})
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// probeQUIC checks whether UDP/443 reaches the server by sending a QUIC
+// packet with an unsupported version and waiting for the server's
+// version negotiation reply (RFC 9000, section 6)
+func probeQUIC(host string) bool {
+	conn, err := net.DialTimeout("udp", net.JoinHostPort(host, "443"), probeTimeout)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	// Long header, reserved version 0x?a?a?a?a, 8-byte connection IDs,
+	// padded to the 1200 byte minimum so servers are willing to respond
+	packet := make([]byte, 1200)
+	packet[0] = 0xc0
+	copy(packet[1:5], []byte{0x1a, 0x2a, 0x3a, 0x4a})
+	packet[5] = 8
+	rand.Read(packet[6:14])
+	packet[14] = 8
+	rand.Read(packet[15:23])
+
+	conn.SetDeadline(time.Now().Add(probeTimeout))
+	if _, err := conn.Write(packet); err != nil {
+		return false
+	}
+
+	reply := make([]byte, 1500)
+	n, err := conn.Read(reply)
+	if err != nil || n < 5 {
+		return false
+	}
+
+	// Version negotiation packets have the long header bit set and version 0
+	return reply[0]&0x80 != 0 && reply[1] == 0 && reply[2] == 0 && reply[3] == 0 && reply[4] == 0
+}
@@ -2,6 +2,7 @@ package dns
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net"
 	"strings"
@@ -10,14 +11,17 @@ import (
 
 	"github.com/miekg/dns"
 	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/notify"
 )
 
 // Proxy is a local DNS proxy that forwards queries to FilterDNS or split DNS servers
 type Proxy struct {
 	config     *config.Config
-	server     *dns.Server
+	servers    []*dns.Server // UDP and TCP per loopback address
+	addrs      []string      // Loopback addresses actually listened on
 	dohClient  *DoHClient
 	forwarders *ForwarderMatcher
+	rules      *RuleMatcher
 	cache      *Cache
 	mu         sync.RWMutex
 	ctx        context.Context
@@ -26,53 +30,175 @@ type Proxy struct {
 	// Stats
 	queriesTotal   int64
 	queriesBlocked int64
+	cacheHits      int64
+
+	// Whether the server marks blocked answers with Extended DNS Errors
+	ede bool
+
+	// The profile's password, read when the upstream is set rather than
+	// on every query
+	password string
+
+	// Re-checks a sample of answers when integrity mode is on
+	verifier *Verifier
+
+	// Answers search-domain expansions locally when enabled
+	search *SearchShortcut
+
+	// Records answered queries, if set
+	queryLog *QueryLog
+
+	// Keeps a flapping upstream from flooding the log
+	failureLog *notify.Notifier
+
+	// Upstream health
+	onUpstream   func(down bool) // Told when the upstream fails or recovers
+	upstreamSeq  uint64          // Transitions passed to onUpstream so far
+	dohFailures  int
+	lastProbe    time.Time
+	connectivity *Connectivity
+
+	// Serializes onUpstream calls; the last transition delivered, so an
+	// older one that lost the race is dropped
+	upstreamMu   sync.Mutex
+	upstreamSent uint64
 }
 
+const (
+	// probeAfterFailures is the number of consecutive DoH failures that
+	// triggers a connectivity probe
+	probeAfterFailures = 3
+
+	// probeInterval limits how often failures can trigger a probe
+	probeInterval = 5 * time.Minute
+
+	// DefaultCacheSize is how many answers are cached unless configured
+	DefaultCacheSize = 10000
+)
+
 // NewProxy creates a new DNS proxy
 func NewProxy(cfg *config.Config) *Proxy {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	SetBootstrapDNS(cfg.BootstrapDNS)
 	p := &Proxy{
 		config:     cfg,
-		dohClient:  NewDoHClient(cfg.ServerURL, cfg.Profile),
+		dohClient:  NewDoHClientURL(cfg.DoHURL()),
 		forwarders: NewForwarderMatcher(cfg.Forwarders),
-		cache:      NewCache(5*time.Minute, 10000),
+		rules:      NewRuleMatcher(cfg.Rules),
+		cache:      NewCache(5*time.Minute, cacheSize(cfg)),
+		ede:        supportsEDE(cfg),
+		password:   profilePassword(cfg.Profile),
+		failureLog: notify.New(logNotification, time.Minute, 3),
 		ctx:        ctx,
 		cancel:     cancel,
 	}
 
+	if cfg.VerifyEvery > 0 {
+		p.verifier = NewVerifier(cfg, cfg.VerifyEvery)
+	}
+	if cfg.SearchShortcut {
+		p.search = NewSearchShortcut()
+	}
+
 	return p
 }
 
-// Start starts the DNS proxy server
+// BindFunc binds UDP and TCP sockets on a loopback address such as
+// "127.0.0.1:53"
+type BindFunc func(addr string) (net.PacketConn, net.Listener, error)
+
+// Start binds the proxy's sockets and serves them in the background
 func (p *Proxy) Start() error {
-	p.server = &dns.Server{
-		Addr:    "127.0.0.1:53",
-		Net:     "udp",
-		Handler: dns.HandlerFunc(p.handleQuery),
-	}
+	return p.StartWith(bindLoopback)
+}
 
-	// Also listen on TCP
-	go func() {
-		tcpServer := &dns.Server{
-			Addr:    "127.0.0.1:53",
-			Net:     "tcp",
-			Handler: dns.HandlerFunc(p.handleQuery),
-		}
-		if err := tcpServer.ListenAndServe(); err != nil {
-			log.Printf("TCP server error: %v", err)
+// StartWith serves on sockets from bind, e.g. ones bound by the privileged
+// helper after the daemon dropped root. The IPv4 loopback is required;
+// ::1 is skipped with a warning where IPv6 loopback is unavailable.
+func (p *Proxy) StartWith(bind BindFunc) error {
+	p.mu.RLock()
+	addrs := p.config.LoopbackAddrs()
+	p.mu.RUnlock()
+
+	for i, ip := range addrs {
+		addr := net.JoinHostPort(ip, "53")
+		pc, l, err := bind(addr)
+		if err != nil {
+			if i == 0 {
+				p.Stop()
+				return fmt.Errorf("failed to bind %s: %w", addr, err)
+			}
+			log.Printf("Warning: not listening on %s: %v", addr, err)
+			continue
 		}
-	}()
+		p.serve(ip, pc, l)
+	}
+	return nil
+}
 
-	log.Printf("DNS proxy listening on 127.0.0.1:53")
-	return p.server.ListenAndServe()
+// bindLoopback binds UDP and TCP sockets on addr
+func bindLoopback(addr string) (net.PacketConn, net.Listener, error) {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		pc.Close()
+		return nil, nil, err
+	}
+	return pc, l, nil
+}
+
+// serve answers queries arriving on pc and l, which are bound to ip
+func (p *Proxy) serve(ip string, pc net.PacketConn, l net.Listener) {
+	servers := []*dns.Server{
+		{PacketConn: pc, Handler: dns.HandlerFunc(p.handleQuery)},
+		{Listener: l, Handler: dns.HandlerFunc(p.handleQuery)},
+	}
+
+	p.mu.Lock()
+	p.servers = append(p.servers, servers...)
+	p.addrs = append(p.addrs, ip)
+	p.mu.Unlock()
+
+	for _, server := range servers {
+		go func(server *dns.Server) {
+			if err := server.ActivateAndServe(); err != nil {
+				log.Printf("DNS proxy error on %s: %v", ip, err)
+			}
+		}(server)
+	}
+
+	log.Printf("DNS proxy listening on %s", net.JoinHostPort(ip, "53"))
+}
+
+// Addresses returns the loopback addresses the proxy listens on
+func (p *Proxy) Addresses() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]string(nil), p.addrs...)
 }
 
 // Stop stops the DNS proxy server
 func (p *Proxy) Stop() {
 	p.cancel()
-	if p.server != nil {
-		p.server.Shutdown()
+
+	p.mu.RLock()
+	servers := p.servers
+	p.mu.RUnlock()
+
+	for _, server := range servers {
+		if err := server.Shutdown(); err != nil {
+			// Not activated yet; close the socket so it cannot start
+			if server.PacketConn != nil {
+				server.PacketConn.Close()
+			}
+			if server.Listener != nil {
+				server.Listener.Close()
+			}
+		}
 	}
 }
 
@@ -88,15 +214,60 @@ func (p *Proxy) handleQuery(w dns.ResponseWriter, r *dns.Msg) {
 	q := r.Question[0]
 	qname := strings.ToLower(q.Name)
 
+	p.mu.RLock()
+	forwarders := p.forwarders
+	rules := p.rules
+	search := p.search
+	queryLog := p.queryLog
+	ede := p.ede
+	p.mu.RUnlock()
+
+	source := SourceUpstream
+	if queryLog != nil {
+		lw := &loggingWriter{ResponseWriter: w}
+		w = lw
+		start := time.Now()
+		defer func() {
+			queryLog.Add(newQueryLogEntry(q, lw.resp, source, ede, start))
+		}()
+	}
+
+	// Local rules override the cache, which may hold the profile's answer
+	if rule := rules.Match(qname); rule != nil {
+		switch rule.Action {
+		case config.RuleBlock:
+			p.queriesBlocked++
+			source = SourceBlockRule
+			w.WriteMsg(blockedByRule(r))
+			return
+		case config.RuleAllow:
+			source = SourceAllowRule
+			p.resolveAllowed(w, r)
+			return
+		}
+	}
+
 	// Check cache first
 	if cached := p.cache.Get(qname, q.Qtype); cached != nil {
+		p.cacheHits++
+		source = SourceCache
 		cached.Id = r.Id
 		w.WriteMsg(cached)
 		return
 	}
 
+	// Answer search-domain expansions of known names locally
+	if search != nil {
+		if resp := search.Answer(r); resp != nil {
+			source = SourceSearch
+			w.WriteMsg(resp)
+			return
+		}
+	}
+
 	// Check if this domain should be forwarded to a split DNS server
-	if forwarder := p.forwarders.Match(qname); forwarder != "" {
+	if forwarder := forwarders.Match(qname); forwarder != "" {
+		source = SourceForwarder
 		p.forwardToServer(w, r, forwarder)
 		return
 	}
@@ -105,20 +276,61 @@ func (p *Proxy) handleQuery(w dns.ResponseWriter, r *dns.Msg) {
 	p.forwardToDoH(w, r)
 }
 
+// newQueryLogEntry describes a query and the response written for it.
+// Only answers that passed through FilterDNS or a block rule can be
+// blocked.
+func newQueryLogEntry(q dns.Question, resp *dns.Msg, source string, ede bool, start time.Time) QueryLogEntry {
+	entry := QueryLogEntry{
+		Time:     start,
+		Domain:   strings.TrimSuffix(strings.ToLower(q.Name), "."),
+		Type:     dns.TypeToString[q.Qtype],
+		Source:   source,
+		Duration: time.Since(start),
+	}
+	if resp != nil {
+		entry.Rcode = dns.RcodeToString[resp.Rcode]
+		entry.Blocked = source == SourceBlockRule ||
+			(source == SourceUpstream || source == SourceCache) && isBlockedResponse(resp, ede)
+	}
+	return entry
+}
+
+// observeSearch lets the search shortcut learn from an upstream answer
+func (p *Proxy) observeSearch(r, resp *dns.Msg) {
+	p.mu.RLock()
+	search := p.search
+	p.mu.RUnlock()
+
+	if search != nil && len(r.Question) > 0 {
+		search.Observe(r, resp)
+	}
+}
+
 // forwardToDoH forwards the query to FilterDNS via DNS-over-HTTPS
 func (p *Proxy) forwardToDoH(w dns.ResponseWriter, r *dns.Msg) {
 	ctx, cancel := context.WithTimeout(p.ctx, 5*time.Second)
 	defer cancel()
 
-	// Get password if needed
-	password, _ := config.GetPassword(p.config.Profile)
+	p.mu.RLock()
+	dohClient := p.dohClient
+	password := p.password
+	ede := p.ede
+	verifier := p.verifier
+	p.mu.RUnlock()
 
-	resp, err := p.dohClient.Query(ctx, r, password)
+	resp, err := dohClient.Query(ctx, r, password)
 	if err != nil {
-		log.Printf("DoH query failed: %v", err)
+		p.failureLog.Notify("DoH query failed", err.Error())
+		p.recordUpstreamFailure()
 		dns.HandleFailed(w, r)
 		return
 	}
+	p.recordUpstreamSuccess()
+
+	if verifier != nil && verifier.Sample() {
+		go verifier.Check(r.Copy(), resp.Copy(), password)
+	}
+	p.observeSearch(r, resp)
 
 	// Cache the response
 	if len(r.Question) > 0 {
@@ -127,7 +339,7 @@ func (p *Proxy) forwardToDoH(w dns.ResponseWriter, r *dns.Msg) {
 	}
 
 	// Check if response indicates blocking
-	if isBlockedResponse(resp) {
+	if isBlockedResponse(resp, ede) {
 		p.queriesBlocked++
 	}
 
@@ -136,22 +348,13 @@ func (p *Proxy) forwardToDoH(w dns.ResponseWriter, r *dns.Msg) {
 
 // forwardToServer forwards the query to a traditional DNS server
 func (p *Proxy) forwardToServer(w dns.ResponseWriter, r *dns.Msg, server string) {
-	// Ensure server has a port
-	if !strings.Contains(server, ":") {
-		server = net.JoinHostPort(server, "53")
-	}
-
-	client := &dns.Client{
-		Net:     "udp",
-		Timeout: 5 * time.Second,
-	}
-
-	resp, _, err := client.Exchange(r, server)
+	resp, _, err := exchangeForwarder(r, server)
 	if err != nil {
 		log.Printf("Forward to %s failed: %v", server, err)
 		dns.HandleFailed(w, r)
 		return
 	}
+	p.observeSearch(r, resp)
 
 	// Cache the response
 	if len(r.Question) > 0 {
@@ -162,6 +365,24 @@ func (p *Proxy) forwardToServer(w dns.ResponseWriter, r *dns.Msg, server string)
 	w.WriteMsg(resp)
 }
 
+// resolveAllowed answers a query allowed by a local rule
+func (p *Proxy) resolveAllowed(w dns.ResponseWriter, r *dns.Msg) {
+	resp, err := resolveAllowed(r)
+	if err != nil {
+		p.failureLog.Notify("Allowed query failed", err.Error())
+		dns.HandleFailed(w, r)
+		return
+	}
+	w.WriteMsg(resp)
+}
+
+// UpdateRules updates the local allow/block rules
+func (p *Proxy) UpdateRules(rules []config.Rule) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rules = NewRuleMatcher(rules)
+}
+
 // UpdateForwarders updates the split DNS forwarders
 func (p *Proxy) UpdateForwarders(forwarders []config.Forwarder) {
 	p.mu.Lock()
@@ -169,13 +390,335 @@ func (p *Proxy) UpdateForwarders(forwarders []config.Forwarder) {
 	p.forwarders = NewForwarderMatcher(forwarders)
 }
 
+// SwitchUpstream points the proxy at a new server and profile without
+// dropping the listeners
+func (p *Proxy) SwitchUpstream(cfg *config.Config) {
+	// Bootstrap resolution may block, so do it before taking the lock
+	SetBootstrapDNS(cfg.BootstrapDNS)
+	dohClient := NewDoHClientURL(cfg.DoHURL())
+	var verifier *Verifier
+	if cfg.VerifyEvery > 0 {
+		verifier = NewVerifier(cfg, cfg.VerifyEvery)
+	}
+	password := profilePassword(cfg.Profile)
+
+	p.mu.Lock()
+	p.config = cfg
+	p.dohClient = dohClient
+	p.password = password
+	p.forwarders = NewForwarderMatcher(cfg.Forwarders)
+	p.rules = NewRuleMatcher(cfg.Rules)
+	p.ede = supportsEDE(cfg)
+	p.verifier = verifier
+	if !cfg.SearchShortcut {
+		p.search = nil
+	} else if p.search == nil {
+		p.search = NewSearchShortcut()
+	}
+	if p.dohFailures >= probeAfterFailures {
+		// The new upstream gets a fresh start
+		p.notifyUpstream(false)
+	}
+	p.dohFailures = 0
+	p.connectivity = nil
+	p.mu.Unlock()
+
+	// Answers from the old profile may have been filtered differently
+	p.cache.Clear()
+}
+
+// RefreshPassword re-reads the profile's password, e.g. after it was
+// stored or changed
+func (p *Proxy) RefreshPassword() {
+	p.mu.RLock()
+	profile := p.config.Profile
+	p.mu.RUnlock()
+
+	password := profilePassword(profile)
+
+	p.mu.Lock()
+	if p.config.Profile == profile {
+		p.password = password
+	}
+	p.mu.Unlock()
+}
+
+// profilePassword returns the password stored for profile, "" if there is
+// none or it cannot be read
+func profilePassword(profile string) string {
+	password, _ := config.GetPassword(profile)
+	return password
+}
+
+// SetVerifyEvery turns integrity mode on (re-checking one in every
+// answers) or off (every <= 0)
+func (p *Proxy) SetVerifyEvery(every int) {
+	p.mu.RLock()
+	current := p.verifier
+	cfg := p.config
+	p.mu.RUnlock()
+
+	if every <= 0 {
+		p.mu.Lock()
+		p.verifier = nil
+		p.mu.Unlock()
+		return
+	}
+	if current != nil && current.every == int64(every) {
+		return
+	}
+
+	// Bootstrap resolution may block, so do it before taking the lock
+	verifier := NewVerifier(cfg, every)
+	p.mu.Lock()
+	p.verifier = verifier
+	p.mu.Unlock()
+}
+
+// SetCacheSize changes how many answers are cached (size <= 0 for the
+// default), evicting the oldest if the cache is now too big
+func (p *Proxy) SetCacheSize(size int) {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	p.cache.SetMaxSize(size)
+}
+
+// cacheSize returns the configured cache size or the default
+func cacheSize(cfg *config.Config) int {
+	if cfg.CacheSize > 0 {
+		return cfg.CacheSize
+	}
+	return DefaultCacheSize
+}
+
+// SetSearchShortcut turns the search-domain shortcut on or off
+func (p *Proxy) SetSearchShortcut(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !enabled {
+		p.search = nil
+	} else if p.search == nil {
+		p.search = NewSearchShortcut()
+	}
+}
+
+// SetQueryLog makes the proxy record answered queries in queryLog, or
+// stop recording if it is nil
+func (p *Proxy) SetQueryLog(queryLog *QueryLog) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queryLog = queryLog
+}
+
+// SetUpstreamListener makes the proxy call fn, in a goroutine of its own,
+// when the upstream stops answering (probeAfterFailures failures in a row)
+// and when it answers again
+func (p *Proxy) SetUpstreamListener(fn func(down bool)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onUpstream = fn
+}
+
+// SearchShortcuts returns the number of search-domain expansions answered
+// locally, or 0 when the shortcut is off
+func (p *Proxy) SearchShortcuts() int64 {
+	p.mu.RLock()
+	search := p.search
+	p.mu.RUnlock()
+
+	if search == nil {
+		return 0
+	}
+	return search.Suppressed()
+}
+
+// Integrity returns the integrity check results, or nil when integrity
+// mode is off
+func (p *Proxy) Integrity() *Integrity {
+	p.mu.RLock()
+	verifier := p.verifier
+	p.mu.RUnlock()
+
+	if verifier == nil {
+		return nil
+	}
+	return verifier.Integrity()
+}
+
+// SetCapabilities applies newly probed server capabilities
+func (p *Proxy) SetCapabilities(caps *config.ServerCapabilities) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ede = caps != nil && caps.ServerURL == p.config.ServerURL && caps.EDE
+}
+
+// supportsEDE reports whether the configured server is known to send
+// Extended DNS Errors
+func supportsEDE(cfg *config.Config) bool {
+	caps := cfg.CapabilitiesFor(cfg.ServerURL)
+	return caps != nil && caps.EDE
+}
+
+// recordUpstreamFailure counts a failed DoH query and probes the network
+// once failures keep piling up
+func (p *Proxy) recordUpstreamFailure() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.dohFailures++
+	if p.dohFailures == probeAfterFailures {
+		p.notifyUpstream(true)
+	}
+	if p.dohFailures < probeAfterFailures || time.Since(p.lastProbe) < probeInterval {
+		return
+	}
+	p.lastProbe = time.Now()
+
+	go p.ProbeUpstream()
+}
+
+// recordUpstreamSuccess resets the consecutive failure count
+func (p *Proxy) recordUpstreamSuccess() {
+	p.mu.Lock()
+	if p.dohFailures >= probeAfterFailures {
+		p.notifyUpstream(false)
+	}
+	p.dohFailures = 0
+	p.mu.Unlock()
+}
+
+// notifyUpstream tells the listener the upstream went down or came back,
+// without blocking the query that noticed. Transitions arrive in order:
+// one overtaken by a later one is dropped. (must be called with p.mu
+// held)
+func (p *Proxy) notifyUpstream(down bool) {
+	fn := p.onUpstream
+	if fn == nil {
+		return
+	}
+	p.upstreamSeq++
+	seq := p.upstreamSeq
+	go func() {
+		p.upstreamMu.Lock()
+		defer p.upstreamMu.Unlock()
+		if seq <= p.upstreamSent {
+			return
+		}
+		p.upstreamSent = seq
+		fn(down)
+	}()
+}
+
+// CheckUpstream resolves domain through FilterDNS with the
+// profile's password, bypassing the cache. Errors wrap ErrUnauthorized
+// if the server rejected the password.
+func (p *Proxy) CheckUpstream(ctx context.Context, domain string) error {
+	p.mu.RLock()
+	dohClient := p.dohClient
+	password := p.password
+	p.mu.RUnlock()
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(domain), dns.TypeA)
+	_, err := dohClient.Query(ctx, msg, password)
+	return err
+}
+
+// ProbeUpstream checks which upstream transports the network allows and
+// records the result
+func (p *Proxy) ProbeUpstream() *Connectivity {
+	p.mu.RLock()
+	dohClient := p.dohClient
+	p.mu.RUnlock()
+
+	log.Println("Probing upstream connectivity...")
+	result := dohClient.Probe()
+	log.Printf("Upstream connectivity: udp/53=%v tcp/853=%v tcp/443=%v udp/443=%v (preferred: %s)",
+		result.UDP53, result.TCP853, result.TCP443, result.UDP443, result.Preferred)
+
+	p.mu.Lock()
+	p.connectivity = result
+	p.mu.Unlock()
+
+	return result
+}
+
+// Connectivity returns the last upstream probe result, or nil if none ran
+func (p *Proxy) Connectivity() *Connectivity {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.connectivity
+}
+
 // GetStats returns current proxy statistics
 func (p *Proxy) GetStats() (total, blocked int64) {
 	return p.queriesTotal, p.queriesBlocked
 }
 
-// isBlockedResponse checks if a DNS response indicates a blocked domain
-func isBlockedResponse(resp *dns.Msg) bool {
+// CacheHits returns the number of queries answered from the cache
+func (p *Proxy) CacheHits() int64 {
+	return p.cacheHits
+}
+
+// CacheStats returns the cache's size and how often it answered
+func (p *Proxy) CacheStats() CacheStats {
+	stats := p.cache.Stats()
+	stats.Hits = p.cacheHits
+	stats.Queries = p.queriesTotal
+	return stats
+}
+
+// CacheEntries returns the cached answers for domains containing domain,
+// or all of them if it is empty
+func (p *Proxy) CacheEntries(domain string) []CacheEntry {
+	return p.cache.Entries(domain)
+}
+
+// FlushCache drops the cached answers for domains containing domain, or
+// all of them if it is empty, and returns how many were dropped
+func (p *Proxy) FlushCache(domain string) int {
+	if domain == "" {
+		n := p.cache.Size()
+		p.cache.Clear()
+		return n
+	}
+	return p.cache.Remove(domain)
+}
+
+// ForgetDomains drops the cached answers for the given domains and their
+// subdomains, e.g. after their verdict changed on the server, and returns
+// how many were dropped
+func (p *Proxy) ForgetDomains(domains []string) int {
+	n := 0
+	for _, domain := range domains {
+		n += p.cache.RemoveZone(domain)
+	}
+	return n
+}
+
+// logNotification is a notify.SendFunc that writes to the log
+func logNotification(title, message string) {
+	log.Printf("%s: %s", title, message)
+}
+
+// isBlockedResponse checks if a DNS response indicates a blocked domain.
+// Servers that send Extended DNS Errors are trusted to mark every blocked
+// answer, so a plain NXDOMAIN from them is a real one.
+func isBlockedResponse(resp *dns.Msg, ede bool) bool {
+	if ede {
+		if opt := resp.IsEdns0(); opt != nil {
+			for _, option := range opt.Option {
+				if e, ok := option.(*dns.EDNS0_EDE); ok &&
+					(e.InfoCode == dns.ExtendedErrorCodeBlocked || e.InfoCode == dns.ExtendedErrorCodeFiltered) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
 	if resp.Rcode == dns.RcodeNameError {
 		return true
 	}
@@ -0,0 +1,227 @@
+package dns
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Where a logged query was answered from
+const (
+	SourceUpstream  = "upstream"  // FilterDNS over DoH
+	SourceCache     = "cache"     // Local response cache
+	SourceForwarder = "forwarder" // Split DNS server
+	SourceSearch    = "search"    // Search-domain shortcut
+	SourceAllowRule = "allowed"   // Local allow rule, resolved past FilterDNS
+	SourceBlockRule = "blocked"   // Local block rule
+)
+
+// QueryLogEntry is one answered query
+type QueryLogEntry struct {
+	Seq      uint64        `json:"seq"` // Increases by one per query, for following the log
+	Time     time.Time     `json:"time"`
+	Domain   string        `json:"domain"`
+	Type     string        `json:"type"`
+	Rcode    string        `json:"rcode"`
+	Blocked  bool          `json:"blocked"`
+	Source   string        `json:"source"`
+	Duration time.Duration `json:"duration"`
+}
+
+// QueryFilter selects entries from the query log. The zero value selects
+// everything.
+type QueryFilter struct {
+	After       uint64    `json:"after,omitempty"` // Only entries with a larger Seq
+	Since       time.Time `json:"since,omitempty"`
+	BlockedOnly bool      `json:"blockedOnly,omitempty"`
+	Domain      string    `json:"domain,omitempty"` // Substring of the domain
+	Limit       int       `json:"limit,omitempty"`  // Most recent entries only
+}
+
+// Matches reports whether filter selects entry. Limit is not considered.
+func (f QueryFilter) Matches(entry QueryLogEntry) bool {
+	if entry.Seq <= f.After || entry.Time.Before(f.Since) {
+		return false
+	}
+	if f.BlockedOnly && !entry.Blocked {
+		return false
+	}
+	return f.Domain == "" || strings.Contains(entry.Domain, strings.ToLower(f.Domain))
+}
+
+const (
+	// maxBlockedDomains bounds the domains counted for TopBlocked
+	maxBlockedDomains = 10000
+
+	// watcherBuffer is how many entries a watcher may lag behind before
+	// it misses some
+	watcherBuffer = 256
+)
+
+// DomainCount is how often a domain was blocked
+type DomainCount struct {
+	Domain string `json:"domain"`
+	Count  int64  `json:"count"`
+}
+
+// Latency summarizes upstream response times of the queries in the log
+type Latency struct {
+	Samples int           `json:"samples"`
+	Average time.Duration `json:"average"`
+	Median  time.Duration `json:"median"`
+	P95     time.Duration `json:"p95"`
+}
+
+// QueryLog keeps the most recent queries in memory. It outlives proxies,
+// so the log is not lost when filtering restarts.
+type QueryLog struct {
+	mu      sync.Mutex
+	entries []QueryLogEntry // Ring buffer, next holds the oldest once full
+	next    int
+	seq     uint64
+	blocked map[string]int64 // Blocked domains since the log was created
+
+	watchers map[chan QueryLogEntry]struct{}
+}
+
+// NewQueryLog creates a query log keeping up to size entries
+func NewQueryLog(size int) *QueryLog {
+	return &QueryLog{
+		entries: make([]QueryLogEntry, 0, size),
+		blocked: make(map[string]int64),
+	}
+}
+
+// Add appends an entry, assigning its Seq
+func (l *QueryLog) Add(entry QueryLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seq++
+	entry.Seq = l.seq
+
+	if entry.Blocked {
+		if _, ok := l.blocked[entry.Domain]; !ok && len(l.blocked) >= maxBlockedDomains {
+			l.blocked = make(map[string]int64)
+		}
+		l.blocked[entry.Domain]++
+	}
+
+	for ch := range l.watchers {
+		select {
+		case ch <- entry:
+		default:
+			// Watcher is not reading; it misses this entry
+		}
+	}
+
+	if len(l.entries) < cap(l.entries) {
+		l.entries = append(l.entries, entry)
+		return
+	}
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+}
+
+// Watch returns a channel receiving every entry added from now on, and a
+// function to call when done watching
+func (l *QueryLog) Watch() (<-chan QueryLogEntry, func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.watchers == nil {
+		l.watchers = make(map[chan QueryLogEntry]struct{})
+	}
+	ch := make(chan QueryLogEntry, watcherBuffer)
+	l.watchers[ch] = struct{}{}
+
+	return ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.watchers, ch)
+	}
+}
+
+// Entries returns the entries matching filter, oldest first
+func (l *QueryLog) Entries(filter QueryFilter) []QueryLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var result []QueryLogEntry
+	for i := range l.entries {
+		if entry := l.entries[(l.next+i)%len(l.entries)]; filter.Matches(entry) {
+			result = append(result, entry)
+		}
+	}
+
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[len(result)-filter.Limit:]
+	}
+	return result
+}
+
+// TopBlocked returns the n most often blocked domains, most blocked first
+func (l *QueryLog) TopBlocked(n int) []DomainCount {
+	l.mu.Lock()
+	counts := make([]DomainCount, 0, len(l.blocked))
+	for domain, count := range l.blocked {
+		counts = append(counts, DomainCount{Domain: domain, Count: count})
+	}
+	l.mu.Unlock()
+
+	slices.SortFunc(counts, func(a, b DomainCount) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Domain, b.Domain)
+	})
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// Latency summarizes how long the upstream took to answer the queries
+// still in the log, or returns nil if none went upstream
+func (l *QueryLog) Latency() *Latency {
+	l.mu.Lock()
+	var durations []time.Duration
+	for _, entry := range l.entries {
+		if entry.Source == SourceUpstream {
+			durations = append(durations, entry.Duration)
+		}
+	}
+	l.mu.Unlock()
+
+	if len(durations) == 0 {
+		return nil
+	}
+	slices.Sort(durations)
+
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return &Latency{
+		Samples: len(durations),
+		Average: total / time.Duration(len(durations)),
+		Median:  durations[len(durations)/2],
+		P95:     durations[len(durations)*95/100],
+	}
+}
+
+// loggingWriter remembers the response written for a query
+type loggingWriter struct {
+	dns.ResponseWriter
+	resp *dns.Msg
+}
+
+// WriteMsg records the response and writes it
+func (w *loggingWriter) WriteMsg(m *dns.Msg) error {
+	w.resp = m
+	return w.ResponseWriter.WriteMsg(m)
+}
@@ -0,0 +1,82 @@
+package dns
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+)
+
+// RuleMatcher matches domain names against local allow/block rules
+type RuleMatcher struct {
+	rules []localRule
+}
+
+type localRule struct {
+	rule    config.Rule // The rule as configured
+	pattern string      // Domain without "*." prefix
+}
+
+// NewRuleMatcher creates a new rule matcher
+func NewRuleMatcher(rules []config.Rule) *RuleMatcher {
+	m := &RuleMatcher{rules: make([]localRule, 0, len(rules))}
+	for _, r := range rules {
+		domain := strings.ToLower(strings.TrimSuffix(r.Domain, "."))
+		m.rules = append(m.rules, localRule{rule: r, pattern: strings.TrimPrefix(domain, "*.")})
+	}
+	return m
+}
+
+// Match returns the rule for a domain, or nil. Rules cover subdomains,
+// and the most specific one wins, so "allow cdn.example.com" can punch a
+// hole into "block example.com".
+func (m *RuleMatcher) Match(domain string) *config.Rule {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	var best *localRule
+	for i := range m.rules {
+		rule := &m.rules[i]
+		if domain != rule.pattern && !strings.HasSuffix(domain, "."+rule.pattern) {
+			continue
+		}
+		if best == nil || len(rule.pattern) > len(best.pattern) {
+			best = rule
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return &best.rule
+}
+
+// blockedByRule answers r as blocked: NXDOMAIN, marked with an Extended
+// DNS Error for clients that asked with EDNS0
+func blockedByRule(r *dns.Msg) *dns.Msg {
+	resp := new(dns.Msg)
+	resp.SetRcode(r, dns.RcodeNameError)
+	resp.RecursionAvailable = true
+	if r.IsEdns0() != nil {
+		resp.SetEdns0(dns.DefaultMsgSize, false)
+		opt := resp.IsEdns0()
+		opt.Option = append(opt.Option, &dns.EDNS0_EDE{
+			InfoCode:  dns.ExtendedErrorCodeBlocked,
+			ExtraText: "blocked by local rule",
+		})
+	}
+	return resp
+}
+
+// resolveAllowed resolves an allowed name past FilterDNS, through the
+// bootstrap resolvers
+func resolveAllowed(r *dns.Msg) (*dns.Msg, error) {
+	var lastErr error
+	for _, server := range bootstrapServers() {
+		resp, _, err := exchangeForwarder(r, server)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("no bootstrap resolver answered: %w", lastErr)
+}
@@ -0,0 +1,153 @@
+package dns
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// expansionWindow is how soon after an NXDOMAIN for "name.suffix" a
+	// successful query for "name" marks suffix as a search domain
+	expansionWindow = 10 * time.Second
+
+	// searchSuffixTTL is how long a learned search domain is trusted
+	// before expansions go upstream again to re-learn it
+	searchSuffixTTL = time.Hour
+
+	// resolvedTTL is how long a name that resolved counts as known
+	resolvedTTL = time.Hour
+
+	// maxSearchNames bounds the names remembered by the shortcut
+	maxSearchNames = 10000
+)
+
+// SearchShortcut answers search-domain expansions of names that are
+// already fully qualified locally.
+//
+// With a long search list (and, worse, a high ndots), resolving
+// "api.github.com" first tries "api.github.com.corp.example.com",
+// "api.github.com.example.com" and so on, each of which goes upstream and
+// comes back NXDOMAIN. The shortcut learns search domains from that
+// pattern - NXDOMAIN for "name.suffix" shortly followed by a successful
+// "name" - and from then on answers "name.suffix" with NXDOMAIN itself
+// whenever "name" is known to resolve on its own. Expansions of names it
+// has not seen resolve, such as internal hosts, still go upstream.
+type SearchShortcut struct {
+	mu         sync.Mutex
+	expansions map[string]map[string]time.Time // name -> suffix -> time of NXDOMAIN for name.suffix
+	resolved   map[string]time.Time            // names that recently resolved
+	suffixes   map[string]time.Time            // learned search domains -> when learned
+	suppressed int64
+}
+
+// NewSearchShortcut creates an empty search shortcut
+func NewSearchShortcut() *SearchShortcut {
+	return &SearchShortcut{
+		expansions: make(map[string]map[string]time.Time),
+		resolved:   make(map[string]time.Time),
+		suffixes:   make(map[string]time.Time),
+	}
+}
+
+// Answer returns a local NXDOMAIN for r if it is the expansion of a known
+// name with a learned search domain, or nil if r should go upstream
+func (s *SearchShortcut) Answer(r *dns.Msg) *dns.Msg {
+	qname := strings.ToLower(r.Question[0].Name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, sp := range splits(qname) {
+		learned, ok := s.suffixes[sp.suffix]
+		if !ok || now.Sub(learned) > searchSuffixTTL {
+			continue
+		}
+		if at, ok := s.resolved[sp.name]; ok && now.Sub(at) < resolvedTTL {
+			s.suppressed++
+
+			resp := new(dns.Msg)
+			resp.SetRcode(r, dns.RcodeNameError)
+			resp.RecursionAvailable = true
+			return resp
+		}
+	}
+	return nil
+}
+
+// Observe learns from an upstream answer to r
+func (s *SearchShortcut) Observe(r, resp *dns.Msg) {
+	qname := strings.ToLower(r.Question[0].Name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	switch resp.Rcode {
+	case dns.RcodeNameError:
+		if len(s.expansions) >= maxSearchNames {
+			s.expansions = make(map[string]map[string]time.Time)
+		}
+		for _, sp := range splits(qname) {
+			if s.expansions[sp.name] == nil {
+				s.expansions[sp.name] = make(map[string]time.Time)
+			}
+			s.expansions[sp.name][sp.suffix] = now
+		}
+
+	case dns.RcodeSuccess:
+		if !qualified(qname) {
+			return
+		}
+		if len(s.resolved) >= maxSearchNames {
+			s.resolved = make(map[string]time.Time)
+		}
+		s.resolved[qname] = now
+
+		for suffix, at := range s.expansions[qname] {
+			if now.Sub(at) < expansionWindow {
+				s.suffixes[suffix] = now
+			}
+		}
+		delete(s.expansions, qname)
+	}
+}
+
+// Suppressed returns the number of expansions answered locally
+func (s *SearchShortcut) Suppressed() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.suppressed
+}
+
+// split is a query name read as a name with a search domain appended
+type split struct {
+	name   string
+	suffix string
+}
+
+// splits returns every way to read qname as a fully qualified name with a
+// search domain appended, e.g. "api.github.com.corp.example." gives
+// ("api.github.com.", "corp.example.") and ("api.github.com.corp.", "example.")
+func splits(qname string) []split {
+	var result []split
+	for i := 0; i < len(qname)-1; i++ {
+		if qname[i] != '.' {
+			continue
+		}
+		if name := qname[:i+1]; qualified(name) {
+			result = append(result, split{name: name, suffix: qname[i+1:]})
+		}
+	}
+	return result
+}
+
+// qualified reports whether name has at least two labels, i.e. looks like
+// a name that was already complete before a search domain was appended
+func qualified(name string) bool {
+	return strings.Count(name, ".") >= 2
+}
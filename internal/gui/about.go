@@ -0,0 +1,153 @@
+package gui
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"runtime"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/daemon"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/update"
+)
+
+// aboutContent describes this build, the daemon and the server, and
+// offers updates and a debug bundle for support
+func (g *GUI) aboutContent() fyne.CanvasObject {
+	title := widget.NewLabel(i18n.T("FilterDNS Client"))
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	g.daemonVersion = widget.NewLabel(i18n.T("Unknown"))
+	g.serverVersion = widget.NewLabel(i18n.T("Unknown"))
+
+	info := widget.NewForm(
+		widget.NewFormItem(i18n.T("Version"), widget.NewLabel(update.Version)),
+		widget.NewFormItem(i18n.T("Daemon version"), g.daemonVersion),
+		widget.NewFormItem(i18n.T("Server version"), g.serverVersion),
+		widget.NewFormItem(i18n.T("Platform"), widget.NewLabel(runtime.GOOS+"/"+runtime.GOARCH)),
+	)
+	if path, err := config.Path(); err == nil {
+		info.Append(i18n.T("Settings"), widget.NewLabel(path))
+	}
+
+	g.updateBtn = widget.NewButton(i18n.T("Check for Updates"), g.checkForUpdates)
+	g.bundleBtn = widget.NewButton(i18n.T("Save Debug Bundle..."), g.saveDebugBundle)
+
+	project, _ := url.Parse(projectURL)
+	return container.NewVBox(
+		title,
+		widget.NewLabel(i18n.T("Keeps this device's DNS lookups going through your FilterDNS profile.")),
+		info,
+		container.NewHBox(g.updateBtn, g.bundleBtn),
+		widget.NewHyperlink(i18n.T("Source code and issues"), project),
+	)
+}
+
+// refreshDaemonVersion asks the daemon which build it runs. Call it off
+// the UI thread.
+func (g *GUI) refreshDaemonVersion() {
+	version, err := g.client.Version()
+	if err != nil {
+		// Daemons from before the version request
+		log.Printf("Failed to get daemon version: %v", err)
+		version = i18n.T("Unknown")
+	}
+	g.do(func() { g.daemonVersion.SetText(version) })
+}
+
+// updateServerVersion shows the server's version from the last sync,
+// keeping the one shown while syncing fails (must be called on the UI
+// thread)
+func (g *GUI) updateServerVersion(sync *daemon.SyncStatus) {
+	switch {
+	case sync == nil:
+		g.serverVersion.SetText(i18n.T("Unknown"))
+	case sync.ServerVersion != "":
+		g.serverVersion.SetText(sync.ServerVersion)
+	}
+}
+
+// checkForUpdates asks the daemon whether there is a release newer than
+// its build, and offers to have it install the release
+func (g *GUI) checkForUpdates() {
+	g.busy(g.updateBtn, i18n.T("Checking..."), func() {
+		u, err := g.client.CheckUpdate()
+		if err != nil {
+			g.showError(i18n.T("Update check failed: %v", err))
+			return
+		}
+		if !u.Available {
+			if u.Development {
+				g.showInfo(i18n.T("Development build; the latest release is %s", u.Latest))
+			} else {
+				g.showInfo(i18n.T("Up to date: %s", u.Current))
+			}
+			return
+		}
+
+		g.do(func() {
+			dialog.ShowConfirm(i18n.T("Update available"),
+				i18n.T("Install FilterDNS %s? This replaces %s and restarts the service.", u.Latest, u.Current),
+				func(ok bool) {
+					if ok {
+						g.installUpdate()
+					}
+				}, g.window)
+		})
+	})
+}
+
+// installUpdate has the daemon install the release checkForUpdates
+// found; it restarts into it, and the reconnect shows the new version
+func (g *GUI) installUpdate() {
+	g.busy(g.updateBtn, i18n.T("Updating..."), func() {
+		u, err := g.client.Update()
+		if err != nil {
+			log.Printf("Update failed: %v", err)
+			g.showError(i18n.T("Update failed: %v", err))
+			return
+		}
+		if !u.Available {
+			g.showInfo(i18n.T("Up to date: %s", u.Current))
+			return
+		}
+		g.showInfo(i18n.T("Updated to %s", u.Latest))
+	})
+}
+
+// saveDebugBundle asks where to save a debug bundle from the daemon, as
+// the debug-bundle command writes it
+func (g *GUI) saveDebugBundle() {
+	save := dialog.NewFileSave(func(w fyne.URIWriteCloser, err error) {
+		if err != nil {
+			g.showError(i18n.T("Failed to save: %v", err))
+			return
+		}
+		if w == nil {
+			return // Cancelled
+		}
+
+		g.busy(g.bundleBtn, i18n.T("Collecting..."), func() {
+			defer w.Close()
+
+			bundle, err := g.client.DebugBundle()
+			if err != nil {
+				g.showError(i18n.T("Could not collect diagnostics: %v", err))
+				return
+			}
+			if _, err := w.Write(bundle); err != nil {
+				g.showError(i18n.T("Failed to save: %v", err))
+				return
+			}
+			g.showInfo(i18n.T("Debug bundle written to %s", w.URI().Path()))
+		})
+	}, g.window)
+	save.SetFileName(fmt.Sprintf("filterdns-debug-%s.tar.gz", time.Now().Format("20060102-150405")))
+	save.Show()
+}
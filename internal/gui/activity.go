@@ -0,0 +1,263 @@
+package gui
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/dns"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+)
+
+const (
+	// activityLimit caps the queries kept for the Activity tab
+	activityLimit = 1000
+
+	// activityRefresh is how often the table catches up with new queries,
+	// so a burst of lookups does not redraw it for every one
+	activityRefresh = 500 * time.Millisecond
+
+	// activityRetry is how long to wait before subscribing again after
+	// the daemon went away
+	activityRetry = 5 * time.Second
+)
+
+// activityColumns are the table headers, in order, translated when shown
+var activityColumns = []string{"Time", "Domain", "Type", "Result", "Source", "Latency"}
+
+// activity is the Activity tab: a live, searchable table of the queries
+// the daemon answered, fed by its query subscription
+type activity struct {
+	g *GUI
+
+	mu      sync.Mutex
+	entries []dns.QueryLogEntry // Oldest first
+	rows    []dns.QueryLogEntry // Shown in the table, newest first
+	changed bool                // Entries arrived since the table was updated
+	problem string              // Why the query feed is down, if it is
+
+	search      *widget.Entry
+	blockedOnly *widget.Check
+	table       *widget.Table
+	status      *widget.Label
+}
+
+// activityCell is a table cell offering the row's actions on right-click
+type activityCell struct {
+	widget.Label
+	onSecondary func(*fyne.PointEvent)
+}
+
+func newActivityCell() *activityCell {
+	cell := &activityCell{}
+	cell.Truncation = fyne.TextTruncateEllipsis
+	cell.ExtendBaseWidget(cell)
+	return cell
+}
+
+// TappedSecondary shows the row's actions
+func (c *activityCell) TappedSecondary(e *fyne.PointEvent) {
+	if c.onSecondary != nil {
+		c.onSecondary(e)
+	}
+}
+
+// activityContent builds the Activity tab and starts following queries
+func (g *GUI) activityContent() fyne.CanvasObject {
+	a := &activity{g: g}
+	g.activity = a
+
+	a.search = widget.NewEntry()
+	a.search.SetPlaceHolder(i18n.T("Search domains"))
+	a.search.OnChanged = func(string) { a.update() }
+
+	a.blockedOnly = widget.NewCheck(i18n.T("Blocked only"), func(bool) { a.update() })
+	a.status = widget.NewLabel("")
+
+	clearBtn := widget.NewButton(i18n.T("Clear"), func() {
+		a.mu.Lock()
+		a.entries = nil
+		a.mu.Unlock()
+		a.update()
+	})
+
+	a.table = widget.NewTable(a.size, func() fyne.CanvasObject { return newActivityCell() }, a.updateCell)
+	a.table.ShowHeaderRow = true
+	a.table.CreateHeader = func() fyne.CanvasObject { return widget.NewLabel("") }
+	a.table.UpdateHeader = func(id widget.TableCellID, template fyne.CanvasObject) {
+		label := template.(*widget.Label)
+		label.TextStyle = fyne.TextStyle{Bold: true}
+		label.SetText(i18n.T(activityColumns[id.Col]))
+	}
+	// Space on a row selects it, which offers the right-click actions to
+	// keyboard users too
+	a.table.OnSelected = func(id widget.TableCellID) {
+		a.table.Unselect(id)
+		a.mu.Lock()
+		if id.Row >= len(a.rows) {
+			a.mu.Unlock()
+			return
+		}
+		domain := a.rows[id.Row].Domain
+		a.mu.Unlock()
+
+		pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(a.table)
+		widget.ShowPopUpMenuAtPosition(a.rowMenu(domain), g.window.Canvas(), pos.AddXY(theme.Padding()*4, theme.Padding()*4))
+	}
+	for col, width := range []float32{80, 320, 60, 90, 90, 70} {
+		a.table.SetColumnWidth(col, width)
+	}
+
+	go a.follow()
+	go a.refreshLoop()
+
+	top := container.NewBorder(nil, nil, nil, container.NewHBox(a.blockedOnly, clearBtn), a.search)
+	return container.NewBorder(top, a.status, nil, nil, a.table)
+}
+
+// follow subscribes to the daemon's queries, subscribing again whenever
+// the daemon goes away
+func (a *activity) follow() {
+	for {
+		running := a.g.client.IsRunning()
+		after := uint64(0)
+		a.mu.Lock()
+		if len(a.entries) > 0 {
+			after = a.entries[len(a.entries)-1].Seq
+		}
+		if running {
+			a.problem = ""
+			a.changed = true
+		}
+		a.mu.Unlock()
+
+		err := a.g.client.SubscribeQueries(dns.QueryFilter{After: after, Limit: activityLimit}, func(entry dns.QueryLogEntry) bool {
+			a.g.notes.query(entry)
+			a.mu.Lock()
+			a.entries = append(a.entries, entry)
+			if len(a.entries) > activityLimit {
+				a.entries = a.entries[len(a.entries)-activityLimit:]
+			}
+			a.changed = true
+			a.mu.Unlock()
+			return true
+		})
+		if running {
+			log.Printf("Query feed ended: %v", err)
+		}
+		a.mu.Lock()
+		a.problem = i18n.T("Not connected to the daemon, retrying...")
+		a.changed = true
+		a.mu.Unlock()
+		time.Sleep(activityRetry)
+	}
+}
+
+// refreshLoop updates the table with queries that arrived since the last
+// update
+func (a *activity) refreshLoop() {
+	ticker := time.NewTicker(activityRefresh)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		a.mu.Lock()
+		changed := a.changed
+		a.mu.Unlock()
+		if changed {
+			a.g.do(a.update)
+		}
+	}
+}
+
+// update recomputes the shown rows from the search and the blocked-only
+// switch, newest first
+func (a *activity) update() {
+	search := strings.ToLower(strings.TrimSpace(a.search.Text))
+	blockedOnly := a.blockedOnly.Checked
+
+	a.mu.Lock()
+	a.rows = a.rows[:0]
+	for i := len(a.entries) - 1; i >= 0; i-- {
+		entry := a.entries[i]
+		if blockedOnly && !entry.Blocked {
+			continue
+		}
+		if search != "" && !strings.Contains(entry.Domain, search) {
+			continue
+		}
+		a.rows = append(a.rows, entry)
+	}
+	a.changed = false
+	status := i18n.T("%d of %d recent queries", len(a.rows), len(a.entries))
+	if a.problem != "" {
+		status = a.problem
+	}
+	a.mu.Unlock()
+
+	a.status.SetText(status)
+	a.table.Refresh()
+}
+
+// size reports the table dimensions
+func (a *activity) size() (int, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.rows), len(activityColumns)
+}
+
+// updateCell fills a cell from its row's query
+func (a *activity) updateCell(id widget.TableCellID, template fyne.CanvasObject) {
+	cell := template.(*activityCell)
+
+	a.mu.Lock()
+	if id.Row >= len(a.rows) {
+		a.mu.Unlock()
+		cell.SetText("")
+		cell.onSecondary = nil
+		return
+	}
+	entry := a.rows[id.Row]
+	a.mu.Unlock()
+
+	var text string
+	switch id.Col {
+	case 0:
+		text = entry.Time.Local().Format("15:04:05")
+	case 1:
+		text = entry.Domain
+	case 2:
+		text = entry.Type
+	case 3:
+		text = i18n.T("allowed")
+		if entry.Blocked {
+			text = i18n.T("blocked")
+		} else if entry.Rcode != "NOERROR" {
+			text = strings.ToLower(entry.Rcode)
+		}
+	case 4:
+		text = entry.Source
+	case 5:
+		text = fmt.Sprintf("%d ms", entry.Duration.Milliseconds())
+	}
+	cell.SetText(text)
+
+	domain := entry.Domain
+	cell.onSecondary = func(e *fyne.PointEvent) {
+		widget.ShowPopUpMenuAtPosition(a.rowMenu(domain), a.g.window.Canvas(), e.AbsolutePosition)
+	}
+}
+
+// rowMenu is the actions for a row's domain
+func (a *activity) rowMenu(domain string) *fyne.Menu {
+	items := append(a.g.ruleMenuItems(domain),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem(i18n.T("Copy domain"), func() { a.g.window.Clipboard().SetContent(domain) }),
+	)
+	return fyne.NewMenu("", items...)
+}
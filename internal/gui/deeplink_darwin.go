@@ -0,0 +1,34 @@
+package gui
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Foundation -framework CoreServices
+
+void registerURLHandler(void);
+*/
+import "C"
+
+// deepLinks carries URLs from the Apple Event handler to the GUI
+var deepLinks = make(chan string, 4)
+
+// listenForDeepLinks installs a handler for the GetURL Apple Event: on
+// macOS, links arrive as events instead of command line arguments, both
+// at launch and while the app is running
+func listenForDeepLinks(handle func(link string)) {
+	C.registerURLHandler()
+
+	go func() {
+		for link := range deepLinks {
+			handle(link)
+		}
+	}()
+}
+
+//export goHandleDeepLink
+func goHandleDeepLink(url *C.char) {
+	select {
+	case deepLinks <- C.GoString(url):
+	default:
+		// Several links at once; the user only needs one
+	}
+}
@@ -0,0 +1,31 @@
+#import <Foundation/Foundation.h>
+#import <CoreServices/CoreServices.h>
+
+#include "_cgo_export.h"
+
+@interface FilterDNSURLHandler : NSObject
+- (void)handleGetURL:(NSAppleEventDescriptor *)event withReplyEvent:(NSAppleEventDescriptor *)reply;
+@end
+
+@implementation FilterDNSURLHandler
+- (void)handleGetURL:(NSAppleEventDescriptor *)event withReplyEvent:(NSAppleEventDescriptor *)reply {
+	NSString *url = [[event paramDescriptorForKeyword:keyDirectObject] stringValue];
+	if (url != nil) {
+		goHandleDeepLink((char *)[url UTF8String]);
+	}
+}
+@end
+
+// registerURLHandler routes kAEGetURL events, sent when a filterdns://
+// link is opened, to goHandleDeepLink
+void registerURLHandler(void) {
+	static FilterDNSURLHandler *handler;
+	if (handler != nil) {
+		return;
+	}
+	handler = [[FilterDNSURLHandler alloc] init];
+	[[NSAppleEventManager sharedAppleEventManager] setEventHandler:handler
+		andSelector:@selector(handleGetURL:withReplyEvent:)
+		forEventClass:kInternetEventClass
+		andEventID:kAEGetURL];
+}
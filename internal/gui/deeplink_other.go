@@ -0,0 +1,7 @@
+//go:build !darwin
+
+package gui
+
+// listenForDeepLinks does nothing here: on Linux and Windows the OS starts
+// the app with the link as its argument (see RunDeepLink)
+func listenForDeepLinks(handle func(link string)) {}
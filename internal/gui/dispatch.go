@@ -0,0 +1,84 @@
+package gui
+
+import (
+	"sync"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// dispatcher applies widget updates one at a time, in the order they were
+// queued, so the goroutines talking to the daemon and the server never
+// change the window at the same time. Fyne 2.4 has no fyne.Do; this is
+// where it would go.
+type dispatcher struct {
+	mu    sync.Mutex
+	queue []func()
+	wake  chan struct{}
+}
+
+func newDispatcher() *dispatcher {
+	d := &dispatcher{wake: make(chan struct{}, 1)}
+	go d.run()
+	return d
+}
+
+// do queues fn and returns at once. fn may queue further updates.
+func (d *dispatcher) do(fn func()) {
+	d.mu.Lock()
+	d.queue = append(d.queue, fn)
+	d.mu.Unlock()
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run applies queued updates until the process exits
+func (d *dispatcher) run() {
+	for range d.wake {
+		for {
+			d.mu.Lock()
+			if len(d.queue) == 0 {
+				d.mu.Unlock()
+				break
+			}
+			fn := d.queue[0]
+			d.queue = d.queue[1:]
+			d.mu.Unlock()
+
+			fn()
+		}
+	}
+}
+
+// do queues a widget update; see dispatcher
+func (g *GUI) do(fn func()) {
+	g.ui.do(fn)
+}
+
+// busy runs work in the background, with btn disabled and labelled label
+// until it is done. The button comes back as it was unless work updated it
+// in the meantime.
+func (g *GUI) busy(btn *widget.Button, label string, work func()) {
+	if btn == nil {
+		go work()
+		return
+	}
+
+	g.do(func() {
+		text := btn.Text
+		btn.Disable()
+		btn.SetText(label)
+
+		go func() {
+			defer g.do(func() {
+				if btn.Text == label {
+					btn.SetText(text)
+					btn.Enable()
+				}
+			})
+			work()
+		}()
+	})
+}
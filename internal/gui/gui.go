@@ -1,21 +1,48 @@
 package gui
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
 	"github.com/zkmkarlsruhe/filterdns-client/internal/daemon"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/leaktest"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/notify"
 	"github.com/zkmkarlsruhe/filterdns-client/internal/onboard"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/stats"
 	filtersync "github.com/zkmkarlsruhe/filterdns-client/internal/sync"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/system"
+)
+
+const (
+	// statusInterval is how often the window and tray re-read the daemon
+	// status, which keeps the counters current, counts a pause down and
+	// catches sync and health problems that are not announced as state
+	// changes
+	statusInterval = 10 * time.Second
+
+	// stateRetry is how long to wait before subscribing to state changes
+	// again after the daemon went away
+	stateRetry = 5 * time.Second
+
+	// projectURL is where the source code lives, linked from the About tab
+	projectURL = "https://github.com/zkmkarlsruhe/filterdns-client"
 )
 
 // GUI holds the application GUI state
@@ -23,26 +50,95 @@ type GUI struct {
 	app    fyne.App
 	window fyne.Window
 	client *daemon.Client
-	syncer *filtersync.Syncer
+
+	// Applies widget updates from background work in order
+	ui *dispatcher
+
+	// Whether the daemon answered the last status refresh, and ever did
+	connected     atomic.Bool
+	everConnected atomic.Bool
+
+	// Collapses bursts of error notifications, e.g. while the upstream flaps
+	errors *notify.Notifier
+
+	// Desktop notifications for blocked queries and state changes
+	notes *notifications
 
 	// Local config copy for editing
 	config *config.Config
 
-	// Server state from sync
-	serverFilteringEnabled bool
-	serverPausedUntil      *time.Time
+	// Main content, and the first-run setup screen shown in its place
+	main  fyne.CanvasObject
+	setup *setup
 
 	// Widgets that need updating
 	statusLabel     *widget.Label
 	statusIcon      *widget.Icon
 	toggleBtn       *widget.Button
 	daemonStatus    *widget.Label
-	profileEntry    *widget.Entry
+	setupBtn        *widget.Button
+	profileEntry    *widget.SelectEntry
+	profileHint     *widget.Label
+	profilesBtn     *widget.Button
 	passwordEntry   *widget.Entry
 	serverEntry     *widget.Entry
+	testBtn         *widget.Button
+	connectionLabel *widget.Label
 	autostartCheck  *widget.Check
 	forwarderList   *fyne.Container
+	ruleList        *fyne.Container
+	savedProfiles   *fyne.Container
+	serverBlockBtn  *widget.Button
 	serverSyncLabel *widget.Label
+	serverPause     *fyne.Container
+	serverPauseText *widget.Label
+	serverResumeBtn *widget.Button
+	vpnConflictBox  *fyne.Container
+	statsLabel      *widget.Label
+	serviceBtn      *widget.Button
+	serviceLabel    *widget.Label
+	leakBtn         *widget.Button
+	leakLabel       *widget.Label
+	heartbeatChecks map[string]*widget.Check
+	commandsCheck   *widget.Check
+	saveBtn         *widget.Button
+	saveError       *widget.Label
+	statusBar       *widget.Label
+	daemonVersion   *widget.Label
+	serverVersion   *widget.Label
+	updateBtn       *widget.Button
+	bundleBtn       *widget.Button
+
+	// The Activity tab, for its keyboard shortcut
+	activity *activity
+
+	// Errors shown in the open error dialog, if there is one
+	errorDialog dialog.Dialog
+	errorLog    []string
+
+	// The onboarding URL as a QR code, while onboarding runs
+	pairing dialog.Dialog
+
+	// What the server said about its profiles, for the profile selector
+	profileList profileList
+
+	// Server state of the saved profiles, as the daemon last reported it
+	profileStates []daemon.ProfileState
+
+	// VPN conflicts the banners show, as the daemon last reported them
+	vpnConflicts []daemon.VPNConflict
+
+	// Settings the administrator's policy locks, by their name in
+	// config.json, and the note saying so
+	locked     []string
+	lockedNote *widget.Label
+
+	// System tray, rebuilt when the filtering state changes
+	desk       desktop.App
+	tray       *fyne.Menu
+	trayMu     sync.Mutex
+	trayStatus *daemon.Status // Last known status, nil without a daemon
+	trayKey    string         // What the tray shows, to skip needless rebuilds
 }
 
 // New creates a new GUI instance
@@ -53,68 +149,45 @@ func New(app fyne.App, window fyne.Window) *GUI {
 		cfg = config.Default()
 	}
 
+	// Everything built from here on is in the user's language
+	i18n.Init(cfg.Language)
+
 	g := &GUI{
-		app:                    app,
-		window:                 window,
-		client:                 daemon.NewClient(),
-		config:                 cfg,
-		serverFilteringEnabled: true,
+		app:    app,
+		window: window,
+		client: daemon.NewClient(),
+		ui:     newDispatcher(),
+		config: cfg,
 	}
-
-	// Start sync if profile is configured
-	if cfg.Profile != "" {
-		g.startSync()
+	if e := g.savedEndpoint(); e != nil {
+		// Its config is loaded once it answers
+		g.client.SetEndpoint(e)
+	} else {
+		// The daemon reports its policy once it answers
+		g.locked = config.CurrentPolicy().Keys()
 	}
-
+	g.errors = notify.New(g.presentError, time.Minute, 2)
+	g.notes = newNotifications(g)
+	g.applyTheme()
 	return g
 }
 
-// startSync starts the server sync loop
-func (g *GUI) startSync() {
-	if g.syncer != nil {
-		g.syncer.Stop()
-	}
-
-	syncer, err := filtersync.SyncFromConfig(g.onServerStateChanged)
-	if err != nil {
-		log.Printf("Failed to start sync: %v", err)
-		return
-	}
-
-	g.syncer = syncer
-	g.syncer.Start()
-	log.Println("Server sync started")
-}
-
-// onServerStateChanged is called when the server state changes
-func (g *GUI) onServerStateChanged(enabled bool, pausedUntil *time.Time) {
-	g.serverFilteringEnabled = enabled
-	g.serverPausedUntil = pausedUntil
-
-	// Update UI on main thread
-	if g.serverSyncLabel != nil {
-		if !enabled && pausedUntil != nil {
-			g.serverSyncLabel.SetText(fmt.Sprintf("Server: Paused until %s", pausedUntil.Format("15:04")))
-		} else if !enabled {
-			g.serverSyncLabel.SetText("Server: Filtering paused")
-		} else {
-			g.serverSyncLabel.SetText("Server: Filtering active")
-		}
-	}
-}
-
 // Content returns the main content container
 func (g *GUI) Content() fyne.CanvasObject {
 	// Daemon connection status
-	g.daemonStatus = widget.NewLabel("Checking daemon...")
+	g.daemonStatus = widget.NewLabel(i18n.T("Checking daemon..."))
 	g.daemonStatus.TextStyle = fyne.TextStyle{Italic: true}
 
+	// Way back to the setup screen while something is missing
+	g.setupBtn = widget.NewButton(i18n.T("Set Up FilterDNS..."), g.showSetup)
+	g.setupBtn.Hide()
+
 	// Status section
 	g.statusIcon = widget.NewIcon(theme.MediaStopIcon())
-	g.statusLabel = widget.NewLabel("Unknown")
+	g.statusLabel = widget.NewLabel(i18n.T("Unknown"))
 	g.statusLabel.TextStyle = fyne.TextStyle{Bold: true}
 
-	g.toggleBtn = widget.NewButton("Enable", g.toggle)
+	g.toggleBtn = widget.NewButton(i18n.T("Enable"), g.toggle)
 	g.toggleBtn.Importance = widget.HighImportance
 
 	statusBox := container.NewHBox(
@@ -124,98 +197,225 @@ func (g *GUI) Content() fyne.CanvasObject {
 		g.toggleBtn,
 	)
 
-	statusCard := widget.NewCard("Status", "", container.NewVBox(
+	// Server-side state, as synced by the daemon
+	g.serverSyncLabel = widget.NewLabel("")
+
+	// Counters kept by the daemon across restarts
+	g.statsLabel = widget.NewLabel("")
+
+	// Whether lookups really reach the profile
+	g.leakBtn = widget.NewButton(i18n.T("Leak Test"), g.runLeakTest)
+	g.leakLabel = widget.NewLabel("")
+
+	statusCard := widget.NewCard(i18n.T("Status"), "", container.NewVBox(
 		g.daemonStatus,
+		g.setupBtn,
+		g.serverPauseContent(),
+		g.vpnConflictContent(),
 		statusBox,
+		g.serverSyncLabel,
+		g.statsLabel,
+		container.NewHBox(g.leakBtn, g.leakLabel),
 	))
 
 	// Profile section
-	g.profileEntry = widget.NewEntry()
-	g.profileEntry.SetPlaceHolder("my-profile-name")
-	g.profileEntry.SetText(g.config.Profile)
+	g.serverEntry = widget.NewEntry()
+	g.serverEntry.SetPlaceHolder("https://filterdns.example.com")
+	g.serverEntry.SetText(g.config.ServerURL)
+	g.serverEntry.Validator = serverURLValidator
+
+	// Checks the server and credentials in the form, before saving them
+	g.testBtn = widget.NewButton(i18n.T("Test"), g.testConnection)
+	g.connectionLabel = widget.NewLabel("")
 
 	g.passwordEntry = widget.NewPasswordEntry()
-	g.passwordEntry.SetPlaceHolder("Password (if protected)")
-	if pwd, _ := config.GetPassword(g.config.Profile); pwd != "" {
+	g.passwordEntry.SetPlaceHolder(i18n.T("Password (if protected)"))
+	if g.client.Remote() {
+		g.passwordEntry.SetPlaceHolder(i18n.T("Password (kept on the other computer)"))
+	} else if pwd, _ := config.GetPassword(g.config.Profile); pwd != "" {
 		g.passwordEntry.SetText(pwd)
 	}
 
-	g.serverEntry = widget.NewEntry()
-	g.serverEntry.SetPlaceHolder("https://filterdns.example.com")
-	g.serverEntry.SetText(g.config.ServerURL)
-
 	profileForm := container.NewVBox(
-		widget.NewLabel("Profile Name"),
-		g.profileEntry,
-		widget.NewLabel("Password"),
+		widget.NewLabel(i18n.T("Profile Name")),
+		g.profileSelector(),
+		widget.NewLabel(i18n.T("Password")),
 		g.passwordEntry,
-		widget.NewLabel("Server URL"),
-		g.serverEntry,
+		widget.NewLabel(i18n.T("Server URL")),
+		container.NewBorder(nil, nil, nil, g.testBtn, g.serverEntry),
+		g.connectionLabel,
+		g.lockedNoteLabel(),
 	)
+	g.applyLocks()
 
-	profileCard := widget.NewCard("Profile", "", profileForm)
+	profileCard := widget.NewCard(i18n.T("Profile"), "", profileForm)
 
 	// Forwarders section
 	g.forwarderList = container.NewVBox()
 	g.refreshForwarderList()
 
-	addForwarderBtn := widget.NewButton("Add Forwarder", g.showAddForwarderDialog)
+	addForwarderBtn := widget.NewButton(i18n.T("Add Forwarder"), g.showAddForwarderDialog)
 	addForwarderBtn.Importance = widget.MediumImportance
 
-	tailscaleBtn := widget.NewButton("Add Tailscale", func() {
+	tailscaleBtn := widget.NewButton(i18n.T("Add Tailscale"), func() {
 		g.addForwarder("ts.net", "100.100.100.100")
 	})
 
-	forwarderButtons := container.NewHBox(addForwarderBtn, tailscaleBtn)
+	// Suggests forwarders for the VPNs that are up
+	var detectBtn *widget.Button
+	detectBtn = widget.NewButton(i18n.T("Detect VPNs"), func() { g.detectVPNs(detectBtn) })
+
+	forwarderButtons := container.NewHBox(addForwarderBtn, tailscaleBtn, detectBtn)
+
+	// The list scrolls, so many forwarders do not push the buttons away
+	forwarderScroll := container.NewVScroll(g.forwarderList)
+	forwarderScroll.SetMinSize(fyne.NewSize(0, 120))
 
-	forwarderContent := container.NewVBox(
-		widget.NewLabel("Forward specific domains to other DNS servers"),
-		g.forwarderList,
+	forwarderContent := container.NewBorder(
+		widget.NewLabel(i18n.T("Forward specific domains to other DNS servers")),
 		forwarderButtons,
+		nil, nil,
+		forwarderScroll,
 	)
 
-	forwarderCard := widget.NewCard("Split DNS", "For VPN/Tailscale compatibility", forwarderContent)
+	forwarderCard := widget.NewCard(i18n.T("Split DNS"), i18n.T("For VPN/Tailscale compatibility"), forwarderContent)
 
 	// Settings section
-	g.autostartCheck = widget.NewCheck("Start on login", g.onAutostartChanged)
-	g.autostartCheck.Checked = g.config.Autostart
+	g.autostartCheck = widget.NewCheck(i18n.T("Start on login"), nil)
+	g.autostartCheck.Checked = system.IsAutostartEnabled()
+	g.autostartCheck.OnChanged = g.onAutostartChanged
 
-	dashboardBtn := widget.NewButton("Open Dashboard", g.openDashboard)
+	dashboardBtn := widget.NewButton(i18n.T("Open Dashboard"), g.openDashboard)
+
+	// Background service, installable without a terminal
+	g.serviceLabel = widget.NewLabel("")
+	g.serviceBtn = widget.NewButton(i18n.T("Install Background Service"), func() { g.installService(g.serviceBtn) })
+	g.serviceBtn.Hide()
+	go g.refreshServiceState()
 
 	settingsContent := container.NewVBox(
 		g.autostartCheck,
+		g.windowChecks(),
+		g.languageSelector(),
+		g.themeSelector(),
 		dashboardBtn,
+		container.NewHBox(g.serviceBtn, g.serviceLabel),
 	)
 
-	settingsCard := widget.NewCard("Settings", "", settingsContent)
+	settingsCard := widget.NewCard(i18n.T("Settings"), "", settingsContent)
+
+	// Save button, below the tabs as it saves the edits made on all of them
+	g.saveBtn = widget.NewButton(i18n.T("Save"), g.save)
+	g.saveBtn.Importance = widget.HighImportance
+	g.saveError = widget.NewLabel("")
+	g.saveError.Wrapping = fyne.TextWrapWord
+	g.saveError.Importance = widget.DangerImportance
+	g.saveError.Hide()
+
+	// Confirms what worked, without a dialog or notification
+	g.statusBar = widget.NewLabel("")
+	g.statusBar.TextStyle = fyne.TextStyle{Italic: true}
+
+	// Main layout. The tabs scroll; the status one sets the smallest the
+	// window can get.
+	statusScroll := container.NewVScroll(statusCard)
+	statusScroll.SetMinSize(fyne.NewSize(420, 360))
+
+	tabs := container.NewAppTabs(
+		container.NewTabItem(i18n.T("Status"), container.NewPadded(statusScroll)),
+		container.NewTabItem(i18n.T("Activity"), container.NewPadded(g.activityContent())),
+		container.NewTabItem(i18n.T("Rules"), container.NewPadded(g.rulesContent())),
+		container.NewTabItem(i18n.T("Split DNS"), container.NewPadded(forwarderCard)),
+		container.NewTabItem(i18n.T("Settings"), container.NewPadded(container.NewVScroll(container.NewVBox(
+			profileCard,
+			g.savedProfilesContent(),
+			settingsCard,
+			g.daemonContent(),
+			g.heartbeatContent(),
+			g.notificationsContent(),
+		)))),
+		container.NewTabItem(i18n.T("About"), container.NewPadded(container.NewVScroll(g.aboutContent()))),
+	)
 
-	// Save button
-	saveBtn := widget.NewButton("Save", g.save)
-	saveBtn.Importance = widget.HighImportance
+	g.addShortcuts(tabs)
 
-	// Main layout
-	content := container.NewVBox(
-		statusCard,
-		profileCard,
-		forwarderCard,
-		settingsCard,
-		layout.NewSpacer(),
-		saveBtn,
-	)
+	// Keep the status current from now on
+	go g.followState()
 
-	// Initial status check
-	go g.refreshStatus()
+	saveBar := container.NewBorder(g.saveError, nil, nil, g.saveBtn, g.statusBar)
+	g.main = container.NewBorder(nil, container.NewPadded(saveBar), nil, nil, tabs)
 
-	return container.NewPadded(content)
+	content := container.NewStack(g.main, g.setupContent())
+	if g.needsSetup() {
+		g.showSetup()
+	}
+	return content
 }
 
-// SetupSystemTray configures the system tray icon and menu
+// SetupSystemTray configures the system tray icon and menu, and keeps them
+// in step with the profile and the daemon's filtering state
 func (g *GUI) SetupSystemTray(desk desktop.App) {
 	log.Println("Setting up system tray...")
 
+	g.trayMu.Lock()
+	g.desk = desk
+	g.tray = fyne.NewMenu("FilterDNS")
+	g.trayMu.Unlock()
+
+	g.updateTray()
+	desk.SetSystemTrayMenu(g.tray)
+
+	// Swap the tray icon when the system switches to or from dark mode
+	changes := make(chan fyne.Settings)
+	g.app.Settings().AddChangeListener(changes)
+	go func() {
+		for range changes {
+			g.updateTray()
+		}
+	}()
+	log.Println("System tray setup complete")
+}
+
+// updateTray rebuilds the tray menu and icon from the config and the last
+// known status, if what they show changed
+func (g *GUI) updateTray() {
+	g.trayMu.Lock()
+	defer g.trayMu.Unlock()
+
+	if g.tray == nil {
+		return
+	}
+
+	items := g.trayItems()
+	icon := trayIcon(g.trayStatus, g.darkMenuBar())
+	key := icon.Name() + "\n" + menuKey(items)
+	if key == g.trayKey {
+		return
+	}
+	g.trayKey = key
+
+	g.tray.Items = items
+	g.tray.Refresh()
+	g.desk.SetSystemTrayIcon(icon)
+}
+
+// setTrayState records the daemon status, nil if the daemon is not
+// running, and updates the tray
+func (g *GUI) setTrayState(status *daemon.Status) {
+	g.trayMu.Lock()
+	g.trayStatus = status
+	g.trayMu.Unlock()
+
+	g.updateTray()
+}
+
+// trayItems builds the tray menu (must be called with trayMu held)
+func (g *GUI) trayItems() []*fyne.MenuItem {
+	status := g.trayStatus
+
 	// Build menu items
 	menuItems := []*fyne.MenuItem{
-		fyne.NewMenuItem("Show", func() {
+		fyne.NewMenuItem(i18n.T("Show"), func() {
 			g.window.Show()
 		}),
 		fyne.NewMenuItemSeparator(),
@@ -223,33 +423,201 @@ func (g *GUI) SetupSystemTray(desk desktop.App) {
 
 	// Add connect option if no profile configured
 	if g.config.Profile == "" {
-		menuItems = append(menuItems, fyne.NewMenuItem("Connect to FilterDNS", g.startOnboarding))
+		menuItems = append(menuItems, fyne.NewMenuItem(i18n.T("Connect to FilterDNS"), g.startOnboarding))
 		menuItems = append(menuItems, fyne.NewMenuItemSeparator())
 	} else {
-		// Show profile name and enable/disable options
+		// Show profile name, state and the controls that apply to it
+		menuItems = append(menuItems, disabledItem(i18n.T("Profile: %s", g.config.Profile)))
+
+		switch {
+		case status == nil:
+			menuItems = append(menuItems, disabledItem(i18n.T("Daemon not running")))
+		case status.PausedUntil != nil:
+			menuItems = append(menuItems,
+				disabledItem(i18n.T("Paused, %s left", pauseRemaining(*status.PausedUntil))),
+				fyne.NewMenuItem(i18n.T("Resume Filtering"), g.resume),
+			)
+		case status.Running:
+			pauseItem := fyne.NewMenuItem(i18n.T("Pause Filtering"), nil)
+			pauseItem.ChildMenu = fyne.NewMenu("",
+				fyne.NewMenuItem(i18n.T("For 5 Minutes"), func() { g.pause(5 * time.Minute) }),
+				fyne.NewMenuItem(i18n.T("For 30 Minutes"), func() { g.pause(30 * time.Minute) }),
+				fyne.NewMenuItem(i18n.T("For 1 Hour"), func() { g.pause(time.Hour) }),
+				fyne.NewMenuItem(i18n.T("Until Tomorrow"), func() { g.pause(time.Until(tomorrow(time.Now()))) }),
+			)
+			menuItems = append(menuItems,
+				disabledItem(i18n.T("Filtering on")),
+				fyne.NewMenuItem(i18n.T("Disable Filtering"), g.disable),
+				pauseItem,
+			)
+		default:
+			menuItems = append(menuItems,
+				disabledItem(i18n.T("Filtering off")),
+				fyne.NewMenuItem(i18n.T("Enable Filtering"), g.enable),
+			)
+		}
+		if status != nil {
+			if server := serverState(status.Sync); server != "" {
+				menuItems = append(menuItems, disabledItem(server))
+			}
+		}
+
 		menuItems = append(menuItems,
-			fyne.NewMenuItem(fmt.Sprintf("Profile: %s", g.config.Profile), nil),
-			fyne.NewMenuItem("Enable Filtering", func() {
-				g.enable()
-			}),
-			fyne.NewMenuItem("Disable Filtering", func() {
-				g.disable()
-			}),
 			fyne.NewMenuItemSeparator(),
-			fyne.NewMenuItem("Open Dashboard", g.openDashboard),
-			fyne.NewMenuItem("Change Profile...", g.startOnboarding),
+			fyne.NewMenuItem(i18n.T("Open Dashboard"), g.openDashboard),
+		)
+		if item := g.switchMenuItem(); item != nil {
+			menuItems = append(menuItems, item)
+		}
+		menuItems = append(menuItems,
+			fyne.NewMenuItem(i18n.T("Change Profile..."), g.startOnboarding),
 			fyne.NewMenuItemSeparator(),
 		)
 	}
 
-	menuItems = append(menuItems, fyne.NewMenuItem("Quit", func() {
+	// The daemon refuses it while the administrator locks filtering on
+	restoreItem := fyne.NewMenuItem(i18n.T("Restore Network Settings..."), g.restoreNetwork)
+	restoreItem.Disabled = g.isLocked("enabled")
+	menuItems = append(menuItems, restoreItem)
+	menuItems = append(menuItems, fyne.NewMenuItemSeparator())
+
+	menuItems = append(menuItems, fyne.NewMenuItem(i18n.T("Quit"), func() {
 		g.app.Quit()
 	}))
+	return menuItems
+}
 
-	menu := fyne.NewMenu("FilterDNS", menuItems...)
-	desk.SetSystemTrayMenu(menu)
-	desk.SetSystemTrayIcon(AppIcon())
-	log.Println("System tray setup complete")
+// trayIcon picks the tray icon for the daemon status, nil if the daemon
+// is not running, with lighter greys on a dark menu bar
+func trayIcon(status *daemon.Status, dark bool) fyne.Resource {
+	switch {
+	case status == nil && dark:
+		return NoDaemonDarkIcon()
+	case status == nil:
+		return NoDaemonIcon()
+	case status.PausedUntil != nil:
+		return PausedIcon()
+	case status.Running && (status.Health != nil && !status.Health.OK || status.Sync != nil && status.Sync.Error != ""):
+		return ErrorIcon()
+	case status.Running && status.Sync != nil && !status.Sync.FilteringEnabled:
+		// Paused on the server
+		return PausedIcon()
+	case status.Running:
+		return AppIcon()
+	case dark:
+		return DisabledDarkIcon()
+	default:
+		return DisabledIcon()
+	}
+}
+
+// disabledItem is a menu item that only shows information
+func disabledItem(label string) *fyne.MenuItem {
+	item := fyne.NewMenuItem(label, nil)
+	item.Disabled = true
+	return item
+}
+
+// menuKey describes what a menu shows, for telling whether it changed
+func menuKey(items []*fyne.MenuItem) string {
+	var b strings.Builder
+	for _, item := range items {
+		switch {
+		case item.IsSeparator:
+			b.WriteString("-")
+		case item.Disabled:
+			b.WriteString("(" + item.Label + ")")
+		default:
+			b.WriteString(item.Label)
+		}
+		if item.Checked {
+			b.WriteString("*")
+		}
+		if item.ChildMenu != nil {
+			b.WriteString("[" + menuKey(item.ChildMenu.Items) + "]")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// followState refreshes the status whenever the daemon announces a
+// state change, from here or anywhere else, and every statusInterval. It
+// subscribes again whenever the daemon goes away, so a daemon started or
+// restarted after the GUI is picked up.
+func (g *GUI) followState() {
+	g.refreshStatus()
+
+	go func() {
+		ticker := time.NewTicker(statusInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			g.refreshStatus()
+		}
+	}()
+
+	for {
+		err := g.client.Subscribe(func(event daemon.StateEvent) bool {
+			g.notes.state(event)
+			if event.ConfigReloaded {
+				g.reloadConfig()
+			}
+			g.refreshStatus()
+			return true
+		})
+		if g.connected.Load() {
+			log.Printf("State feed ended: %v", err)
+		}
+		g.notes.reset()
+
+		g.refreshStatus()
+		time.Sleep(stateRetry)
+	}
+}
+
+// pause stops filtering for d via the daemon
+func (g *GUI) pause(d time.Duration) {
+	go func() {
+		status, err := g.client.Pause(d)
+		if err != nil {
+			log.Printf("Pause failed: %v", err)
+			g.showError(i18n.T("Failed to pause: %v", err))
+			return
+		}
+		g.updateStatusDisplay(status)
+	}()
+}
+
+// resume ends a pause via the daemon
+func (g *GUI) resume() {
+	go func() {
+		status, err := g.client.Resume()
+		if err != nil {
+			log.Printf("Resume failed: %v", err)
+			g.showError(i18n.T("Failed to resume: %v", err))
+			return
+		}
+		g.updateStatusDisplay(status)
+	}()
+}
+
+// tomorrow returns the start of the day after now
+func tomorrow(now time.Time) time.Time {
+	y, m, d := now.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+}
+
+// pauseRemaining formats the time left until a pause ends, rounded up to
+// whole minutes
+func pauseRemaining(until time.Time) string {
+	left := time.Until(until).Round(time.Minute)
+	if left < time.Minute {
+		left = time.Minute
+	}
+	if left < time.Hour {
+		return i18n.T("%d min", int(left.Minutes()))
+	}
+	return i18n.T("%d h %d min", int(left.Hours()), int(left.Minutes())%60)
 }
 
 // startOnboarding launches the web-based onboarding flow
@@ -263,61 +631,131 @@ func (g *GUI) startOnboarding() {
 
 	// Run onboarding in background
 	go func() {
-		result, err := onboard.Run(serverURL)
-		if err != nil {
-			log.Printf("Onboarding failed: %v", err)
-			g.showError(fmt.Sprintf("Onboarding failed: %v", err))
-			return
-		}
+		result, err := onboard.RunShowing(serverURL, func(onboardURL string) {
+			g.do(func() { g.showPairing(onboardURL) })
+		})
+		g.do(g.hidePairing)
+		g.finishOnboarding(result, err)
+	}()
+}
 
-		if err := onboard.SaveResult(result); err != nil {
-			log.Printf("Failed to save config: %v", err)
-			g.showError(fmt.Sprintf("Failed to save: %v", err))
-			return
-		}
+// handleDeepLink completes onboarding from a filterdns://onboard link,
+// once the user confirmed the server it points at
+func (g *GUI) handleDeepLink(link string) {
+	log.Println("Opening deep link...")
 
-		// Reload config
-		cfg, _ := config.Load()
-		g.config = cfg
+	serverURL, token, err := onboard.ParseDeepLink(link)
+	if err != nil {
+		log.Printf("Deep link rejected: %v", err)
+		g.showError(i18n.T("Cannot open link: %v", err))
+		return
+	}
 
-		// Update UI
+	g.window.Show()
+	dialog.ShowConfirm(i18n.T("Set up this device"),
+		i18n.T("Connect this device to the FilterDNS server at %s?", serverURL),
+		func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			go func() {
+				result, err := onboard.Resume(serverURL, token)
+				g.finishOnboarding(result, err)
+			}()
+		}, g.window)
+}
+
+// finishOnboarding saves a completed onboarding and hands the new config
+// to the daemon
+func (g *GUI) finishOnboarding(result *onboard.Result, err error) {
+	if err != nil {
+		log.Printf("Onboarding failed: %v", err)
+		g.showError(i18n.T("Onboarding failed: %v", err))
+		return
+	}
+
+	if err := onboard.SaveResult(result); err != nil {
+		log.Printf("Failed to save config: %v", err)
+		g.showError(i18n.T("Failed to save: %v", err))
+		return
+	}
+
+	// Reload config
+	cfg, _ := config.Load()
+
+	// Update UI
+	g.do(func() {
+		g.config = cfg
 		if g.profileEntry != nil {
 			g.profileEntry.SetText(cfg.Profile)
 		}
-
-		// Restart sync with new profile
-		g.startSync()
-
-		// Update daemon config
-		if g.client.IsRunning() {
-			g.client.SetConfig(cfg)
+		if g.serverEntry != nil {
+			g.serverEntry.SetText(cfg.ServerURL)
+			g.refreshProfiles()
 		}
+		g.refreshRuleList()
+		g.refreshSavedProfiles()
+		g.updateTray()
+	})
 
-		g.showInfo(fmt.Sprintf("Connected to profile: %s", result.ProfileName))
-		log.Printf("Onboarding completed: %s", result.ProfileName)
-	}()
+	// Update daemon config
+	if g.client.IsRunning() {
+		g.client.SetConfig(cfg)
+	}
+
+	g.showInfo(i18n.T("Connected to profile: %s", result.ProfileName))
+	log.Printf("Onboarding completed: %s", result.ProfileName)
 }
 
 // Shutdown cleans up resources
 func (g *GUI) Shutdown() {
-	// Stop syncer
-	if g.syncer != nil {
-		g.syncer.Stop()
-	}
+	// Nothing to do: server sync now runs in the daemon
 }
 
-// refreshStatus updates the status from the daemon
+// refreshStatus updates the status from the daemon. It talks to the
+// daemon, so call it off the UI thread.
 func (g *GUI) refreshStatus() {
 	if !g.client.IsRunning() {
-		g.daemonStatus.SetText("⚠ Daemon not running (sudo systemctl start filterdns)")
-		g.statusLabel.SetText("No daemon")
-		g.statusIcon.SetResource(theme.ErrorIcon())
-		g.toggleBtn.Disable()
+		g.connected.Store(false)
+		reconnecting := g.everConnected.Load()
+		g.do(func() {
+			if reconnecting {
+				g.daemonStatus.SetText(i18n.T("⚠ Lost the FilterDNS service, reconnecting…"))
+			} else {
+				g.daemonStatus.SetText(i18n.T("⚠ The FilterDNS service is not running"))
+			}
+			g.setupBtn.Show()
+			g.statusLabel.SetText(i18n.T("No daemon"))
+			g.statusIcon.SetResource(theme.ErrorIcon())
+			g.toggleBtn.SetText(i18n.T("Enable"))
+			g.toggleBtn.Disable()
+			g.daemonVersion.SetText(i18n.T("Not running"))
+			g.updateServerPause(nil)
+			g.updateVPNConflicts(nil)
+		})
+		g.setTrayState(nil)
 		return
 	}
 
-	g.daemonStatus.SetText("✓ Connected to daemon")
-	g.toggleBtn.Enable()
+	if !g.connected.Swap(true) {
+		// A restarted daemon may be another build, e.g. after an update
+		g.refreshDaemonVersion()
+		if g.client.Remote() {
+			g.loadRemoteConfig()
+		}
+		if g.everConnected.Swap(true) {
+			g.showInfo(i18n.T("Reconnected to the FilterDNS service"))
+		}
+	}
+	g.do(func() {
+		if g.config.Profile == "" {
+			g.daemonStatus.SetText(i18n.T("No profile connected yet"))
+			g.setupBtn.Show()
+		} else {
+			g.daemonStatus.SetText(i18n.T("✓ Connected to daemon"))
+			g.setupBtn.Hide()
+		}
+	})
 
 	status, err := g.client.Status()
 	if err != nil {
@@ -330,172 +768,455 @@ func (g *GUI) refreshStatus() {
 
 // updateStatusDisplay updates the UI with status
 func (g *GUI) updateStatusDisplay(status *daemon.Status) {
-	if status.Running {
-		g.statusLabel.SetText(fmt.Sprintf("Enabled (%d queries, %d blocked)", status.QueriesTotal, status.QueriesBlocked))
-		g.statusIcon.SetResource(theme.MediaPlayIcon())
-		g.toggleBtn.SetText("Disable")
-		g.toggleBtn.Importance = widget.DangerImportance
-	} else {
-		g.statusLabel.SetText("Disabled")
-		g.statusIcon.SetResource(theme.MediaStopIcon())
-		g.toggleBtn.SetText("Enable")
-		g.toggleBtn.Importance = widget.HighImportance
+	g.do(func() {
+		if status.Running {
+			g.statusLabel.SetText(i18n.T("Enabled (%d queries, %d blocked)", status.QueriesTotal, status.QueriesBlocked))
+			g.statusIcon.SetResource(theme.MediaPlayIcon())
+			g.toggleBtn.SetText(i18n.T("Disable"))
+			g.toggleBtn.Importance = widget.DangerImportance
+		} else if status.PausedUntil != nil {
+			g.statusLabel.SetText(i18n.T("Paused until %s", status.PausedUntil.Local().Format("15:04")))
+			g.statusIcon.SetResource(theme.MediaPauseIcon())
+			g.toggleBtn.SetText(i18n.T("Enable"))
+			g.toggleBtn.Importance = widget.HighImportance
+		} else {
+			g.statusLabel.SetText(i18n.T("Disabled"))
+			g.statusIcon.SetResource(theme.MediaStopIcon())
+			g.toggleBtn.SetText(i18n.T("Enable"))
+			g.toggleBtn.Importance = widget.HighImportance
+		}
+		g.toggleBtn.Enable()
+		if status.Running && g.isLocked("enabled") {
+			g.toggleBtn.Disable()
+		}
+		g.toggleBtn.Refresh()
+		g.setLocked(status.Locked)
+
+		g.updateSyncDisplay(status.Sync)
+		g.updateServerPause(status.Sync)
+		g.updateVPNConflicts(status.VPNConflicts)
+		g.updateStatsDisplay(status.Stats)
+		g.updateServerVersion(status.Sync)
+		if !reflect.DeepEqual(g.profileStates, status.Profiles) {
+			g.profileStates = status.Profiles
+			g.refreshSavedProfiles()
+		}
+	})
+	g.setTrayState(status)
+}
+
+// updateStatsDisplay shows the counters kept across restarts
+func (g *GUI) updateStatsDisplay(summary *stats.Summary) {
+	if g.statsLabel == nil {
+		return
 	}
-	g.toggleBtn.Refresh()
+
+	if summary == nil {
+		g.statsLabel.SetText("")
+		return
+	}
+	g.statsLabel.SetText(i18n.T("Today: %d blocked of %d · 7 days: %d blocked of %d · Total: %d blocked",
+		summary.Today.Blocked, summary.Today.Queries, summary.Week.Blocked, summary.Week.Queries, summary.Total.Blocked))
 }
 
-// toggle enables or disables filtering
-func (g *GUI) toggle() {
-	status, err := g.client.Status()
-	if err != nil {
-		g.showError(fmt.Sprintf("Failed to get status: %v", err))
+// updateSyncDisplay shows the server-side state reported by the daemon
+func (g *GUI) updateSyncDisplay(sync *daemon.SyncStatus) {
+	if g.serverSyncLabel == nil {
 		return
 	}
+	text := serverState(sync)
+	if sync != nil && sync.ClockSkew.Abs() > filtersync.MaxClockSkew {
+		text += "\n" + clockWarning(sync.ClockSkew)
+	}
+	g.serverSyncLabel.SetText(text)
+}
+
+// clockWarning says how far this computer's clock is off from the
+// server's, ahead if skew is positive
+func clockWarning(skew time.Duration) string {
+	if skew < 0 {
+		return i18n.T("This computer's clock is %s behind the server's, so secure connections may fail", (-skew).Round(time.Minute))
+	}
+	return i18n.T("This computer's clock is %s ahead of the server's, so secure connections may fail", skew.Round(time.Minute))
+}
 
-	if status.Running {
-		g.disable()
-	} else {
-		g.enable()
+// serverState describes the server-side state, or is empty if the daemon
+// does not sync
+func serverState(sync *daemon.SyncStatus) string {
+	switch {
+	case sync == nil:
+		return ""
+	case sync.Error != "" && sync.Offline && sync.NextSync != nil:
+		return i18n.T("Server: Offline, trying again at %s", sync.NextSync.Format("15:04"))
+	case sync.Error != "":
+		return i18n.T("Server: Sync failed")
+	case sync.FilteringEnabled:
+		return i18n.T("Server: Filtering active")
+	case sync.PausedUntil != nil:
+		return i18n.T("Server: Paused until %s", sync.PausedUntil.Format("15:04"))
+	default:
+		return i18n.T("Server: Filtering paused")
 	}
 }
 
+// toggle enables or disables filtering
+func (g *GUI) toggle() {
+	g.busy(g.toggleBtn, i18n.T("Working..."), func() {
+		status, err := g.client.Status()
+		if err != nil {
+			g.showError(i18n.T("Failed to get status: %v", err))
+			return
+		}
+		g.setFiltering(!status.Running)
+	})
+}
+
 // enable starts DNS filtering via daemon
 func (g *GUI) enable() {
-	log.Println("Requesting enable from daemon...")
-	status, err := g.client.Enable()
-	if err != nil {
-		log.Printf("Enable failed: %v", err)
-		g.showError(fmt.Sprintf("Failed to enable: %v", err))
-		return
-	}
-	g.updateStatusDisplay(status)
-	g.showInfo("DNS filtering enabled")
+	g.busy(g.toggleBtn, i18n.T("Enabling..."), func() { g.setFiltering(true) })
 }
 
 // disable stops DNS filtering via daemon
 func (g *GUI) disable() {
-	log.Println("Requesting disable from daemon...")
-	status, err := g.client.Disable()
-	if err != nil {
+	g.busy(g.toggleBtn, i18n.T("Disabling..."), func() { g.setFiltering(false) })
+}
+
+// setFiltering turns DNS filtering on or off via the daemon, blocking
+// until it answered
+func (g *GUI) setFiltering(on bool) {
+	var status *daemon.Status
+	var err error
+	if on {
+		log.Println("Requesting enable from daemon...")
+		status, err = g.client.Enable()
+	} else {
+		log.Println("Requesting disable from daemon...")
+		status, err = g.client.Disable()
+	}
+
+	switch {
+	case err != nil && on:
+		log.Printf("Enable failed: %v", err)
+		g.showError(i18n.T("Failed to enable: %v", err))
+	case err != nil:
 		log.Printf("Disable failed: %v", err)
-		g.showError(fmt.Sprintf("Failed to disable: %v", err))
-		return
+		g.showError(i18n.T("Failed to disable: %v", err))
+	case on:
+		g.updateStatusDisplay(status)
+		g.showInfo(i18n.T("DNS filtering enabled"))
+	default:
+		g.updateStatusDisplay(status)
+		g.showInfo(i18n.T("DNS filtering disabled"))
 	}
-	g.updateStatusDisplay(status)
-	g.showInfo("DNS filtering disabled")
 }
 
 // save saves the configuration to the daemon
 func (g *GUI) save() {
-	g.config.Profile = g.profileEntry.Text
-	g.config.ServerURL = g.serverEntry.Text
+	// Show what is wrong rather than saving a config that cannot work
+	if !g.validateSettings() {
+		return
+	}
 
-	// Save password to keyring (local)
-	if g.passwordEntry.Text != "" {
-		if err := config.SetPassword(g.config.Profile, g.passwordEntry.Text); err != nil {
-			g.showError(fmt.Sprintf("Failed to save password: %v", err))
-			return
-		}
+	// Catch typos in the profile name before the daemon is pointed at it
+	if err := g.checkProfile(g.serverEntry.Text, g.profileEntry.Text); err != nil {
+		g.setSaveError(err.Error())
+		return
 	}
 
-	// Send config to daemon
-	if g.client.IsRunning() {
-		if err := g.client.SetConfig(g.config); err != nil {
-			g.showError(fmt.Sprintf("Failed to update daemon: %v", err))
+	g.config.Profile = strings.TrimSpace(g.profileEntry.Text)
+	g.config.ServerURL = strings.TrimSpace(g.serverEntry.Text)
+	password := g.passwordEntry.Text
+
+	// Work on a copy, so edits made while saving wait for the next save
+	cfg := *g.config
+
+	g.busy(g.saveBtn, i18n.T("Saving..."), func() {
+		remote := g.client.Remote()
+		running := g.client.IsRunning()
+		if remote && !running {
+			g.showError(i18n.T("Failed to update daemon: %v", errRemoteDown()))
 			return
 		}
-	}
 
-	// Also save locally
-	if err := config.Save(g.config); err != nil {
-		g.showError(fmt.Sprintf("Failed to save config: %v", err))
-		return
-	}
+		// Save password to the keyring the daemon reads
+		if password != "" {
+			if err := g.setPassword(cfg.Profile, password); err != nil {
+				g.showError(i18n.T("Failed to save password: %v", err))
+				return
+			}
+		}
 
-	g.showInfo("Settings saved")
-	g.refreshStatus()
+		// Send config to daemon
+		if running {
+			if err := g.client.SetConfig(&cfg); err != nil {
+				g.showError(i18n.T("Failed to update daemon: %v", err))
+				return
+			}
+		}
+
+		// Also save locally; for a remote daemon only what concerns this
+		// computer's window
+		save := config.Save
+		if remote {
+			save = config.SaveUser
+		}
+		if err := save(&cfg); err != nil {
+			g.showError(i18n.T("Failed to save config: %v", err))
+			return
+		}
+
+		// A new profile or server may take rules or not
+		g.do(g.refreshRuleList)
+		g.showInfo(i18n.T("Settings saved"))
+		g.refreshStatus()
+	})
 }
 
 // refreshForwarderList updates the forwarder list display
 func (g *GUI) refreshForwarderList() {
 	g.forwarderList.RemoveAll()
 
-	if len(g.config.Forwarders) == 0 {
-		g.forwarderList.Add(widget.NewLabel("No forwarders configured"))
+	if len(g.config.Effective().Forwarders) == 0 {
+		g.forwarderList.Add(widget.NewLabel(i18n.T("No forwarders configured")))
 		return
 	}
 
-	for _, fwd := range g.config.Forwarders {
+	problems := validationProblems(g.config)
+	for i, fwd := range g.config.Forwarders {
 		fwd := fwd // capture
 		row := container.NewHBox(
 			widget.NewLabel(fwd.Domain),
 			widget.NewLabel("→"),
 			widget.NewLabel(fwd.Server),
 			layout.NewSpacer(),
-			widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
-				g.removeForwarder(fwd.Domain)
-			}),
 		)
+		if g.config.ServerForwarder(fwd.Domain) {
+			row.Add(serverNote(i18n.T("Overridden by server")))
+		}
+		for _, problem := range problems.For(fmt.Sprintf("forwarders[%d]", i)) {
+			row.Add(problemNote(problem.Err.Error()))
+		}
+		row.Add(widget.NewButtonWithIcon(i18n.T("Edit"), theme.DocumentCreateIcon(), func() {
+			g.showForwarderDialog(&fwd)
+		}))
+		row.Add(widget.NewButtonWithIcon(i18n.T("Remove"), theme.DeleteIcon(), func() {
+			g.removeForwarder(fwd.Domain)
+		}))
 		g.forwarderList.Add(row)
 	}
+
+	// Set by an admin on the server profile, so not editable here
+	if s := g.config.ServerSettings; s != nil {
+		for _, fwd := range s.Forwarders {
+			if !g.config.ServerForwarder(fwd.Domain) {
+				continue
+			}
+			g.forwarderList.Add(container.NewHBox(
+				widget.NewLabel(fwd.Domain),
+				widget.NewLabel("→"),
+				widget.NewLabel(fwd.Server),
+				layout.NewSpacer(),
+				serverNote(i18n.T("From server")),
+			))
+		}
+	}
+}
+
+// serverNote labels a setting that comes from the server profile
+func serverNote(text string) *widget.Label {
+	note := widget.NewLabel(text)
+	note.TextStyle = fyne.TextStyle{Italic: true}
+	note.Importance = widget.LowImportance
+	return note
 }
 
 // showAddForwarderDialog shows a dialog to add a new forwarder
 func (g *GUI) showAddForwarderDialog() {
+	g.showForwarderDialog(nil)
+}
+
+// showForwarderDialog shows a dialog to add a forwarder, or to edit fwd if
+// it is set. The dialog only confirms once both fields are valid.
+func (g *GUI) showForwarderDialog(fwd *config.Forwarder) {
 	domainEntry := widget.NewEntry()
 	domainEntry.SetPlaceHolder("*.example.com")
+	domainEntry.Validator = func(s string) error { return config.ValidateDomain(strings.TrimSpace(s)) }
 
 	serverEntry := widget.NewEntry()
 	serverEntry.SetPlaceHolder("192.168.1.1")
+	serverEntry.Validator = func(s string) error { return config.ValidateDNSServer(strings.TrimSpace(s)) }
 
-	form := widget.NewForm(
-		widget.NewFormItem("Domain", domainEntry),
-		widget.NewFormItem("DNS Server", serverEntry),
-	)
+	title, confirm := i18n.T("Add Split DNS Forwarder"), i18n.T("Add")
+	if fwd != nil {
+		title, confirm = i18n.T("Edit Split DNS Forwarder"), i18n.T("Save")
+		domainEntry.SetText(fwd.Domain)
+		serverEntry.SetText(fwd.Server)
+	}
 
-	dialog := widget.NewModalPopUp(
-		container.NewVBox(
-			widget.NewLabel("Add Split DNS Forwarder"),
-			form,
-			container.NewHBox(
-				layout.NewSpacer(),
-				widget.NewButton("Cancel", func() {}),
-				widget.NewButton("Add", func() {
-					if domainEntry.Text != "" && serverEntry.Text != "" {
-						g.addForwarder(domainEntry.Text, serverEntry.Text)
-					}
-				}),
-			),
-		),
-		g.window.Canvas(),
-	)
-	dialog.Show()
+	items := []*widget.FormItem{
+		widget.NewFormItem(i18n.T("Domain"), domainEntry),
+		widget.NewFormItem(i18n.T("DNS Server"), serverEntry),
+	}
+	d := dialog.NewForm(title, confirm, i18n.T("Cancel"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		f := config.Forwarder{
+			Domain: strings.TrimSpace(domainEntry.Text),
+			Server: strings.TrimSpace(serverEntry.Text),
+		}
+		if fwd != nil {
+			g.editForwarder(fwd.Domain, f)
+		} else {
+			g.addForwarder(f.Domain, f.Server)
+		}
+	}, g.window)
+	d.Resize(fyne.NewSize(400, 0))
+	d.Show()
 }
 
-// addForwarder adds a new forwarder
+// addForwarder adds a new forwarder, replacing one for the same domain
 func (g *GUI) addForwarder(domain, server string) {
-	g.config.Forwarders = append(g.config.Forwarders, config.Forwarder{
-		Domain: domain,
-		Server: server,
-	})
-	g.refreshForwarderList()
+	f := config.Forwarder{Domain: domain, Server: server}
+	g.changeForwarders(func(forwarders []config.Forwarder) []config.Forwarder {
+		forwarders = slices.DeleteFunc(forwarders, func(existing config.Forwarder) bool {
+			return existing.Domain == domain
+		})
+		return append(forwarders, f)
+	}, i18n.T("Forwarding %s to %s", domain, server))
+}
+
+// editForwarder replaces the forwarder for domain with f, in place
+func (g *GUI) editForwarder(domain string, f config.Forwarder) {
+	g.changeForwarders(func(forwarders []config.Forwarder) []config.Forwarder {
+		forwarders = slices.DeleteFunc(forwarders, func(existing config.Forwarder) bool {
+			return existing.Domain == f.Domain && existing.Domain != domain
+		})
+		i := slices.IndexFunc(forwarders, func(existing config.Forwarder) bool { return existing.Domain == domain })
+		if i < 0 {
+			return append(forwarders, f)
+		}
+		forwarders[i] = f
+		return forwarders
+	}, i18n.T("Forwarding %s to %s", f.Domain, f.Server))
 }
 
 // removeForwarder removes a forwarder
 func (g *GUI) removeForwarder(domain string) {
-	newForwarders := make([]config.Forwarder, 0)
-	for _, f := range g.config.Forwarders {
-		if f.Domain != domain {
-			newForwarders = append(newForwarders, f)
+	g.changeForwarders(func(forwarders []config.Forwarder) []config.Forwarder {
+		return slices.DeleteFunc(forwarders, func(f config.Forwarder) bool { return f.Domain == domain })
+	}, i18n.T("Removed forwarder for %s", domain))
+}
+
+// changeForwarders applies change to the forwarders, through the daemon if
+// it runs so split DNS follows at once, and shows the result
+func (g *GUI) changeForwarders(change func([]config.Forwarder) []config.Forwarder, done string) {
+	go func() {
+		var cfg *config.Config
+		var err error
+		if g.client.IsRunning() {
+			if cfg, err = g.client.GetConfig(); err == nil {
+				cfg.Forwarders = change(slices.Clone(cfg.Forwarders))
+				err = g.client.SetConfig(cfg)
+			}
+		} else if g.client.Remote() {
+			err = errRemoteDown()
+		} else {
+			if cfg, err = config.Load(); err == nil {
+				cfg.Forwarders = change(slices.Clone(cfg.Forwarders))
+				err = config.Save(cfg)
+			}
 		}
-	}
-	g.config.Forwarders = newForwarders
-	g.refreshForwarderList()
+		if err != nil {
+			log.Printf("Failed to update forwarders: %v", err)
+			g.showError(i18n.T("Failed to update forwarders: %v", err))
+			return
+		}
+
+		// Keep the settings being edited from undoing the change on save
+		g.do(func() {
+			g.config.Forwarders = cfg.Forwarders
+			g.config.ServerSettings = cfg.ServerSettings
+			g.refreshForwarderList()
+		})
+		g.showInfo(done)
+	}()
 }
 
-// onAutostartChanged handles autostart checkbox changes
+// onAutostartChanged adds or removes the login item, putting the checkbox
+// back if that fails
 func (g *GUI) onAutostartChanged(checked bool) {
-	g.config.Autostart = checked
+	g.autostartCheck.Disable()
+	go func() {
+		err := system.SetAutostart(checked)
+		if err != nil {
+			log.Printf("Failed to update login items: %v", err)
+			g.showError(i18n.T("Failed to change start on login: %v", err))
+		}
+
+		g.do(func() {
+			if err != nil {
+				// Without OnChanged, so this does not try again
+				g.autostartCheck.OnChanged = nil
+				g.autostartCheck.SetChecked(system.IsAutostartEnabled())
+				g.autostartCheck.OnChanged = g.onAutostartChanged
+			} else {
+				g.config.Autostart = checked
+			}
+			g.autostartCheck.Enable()
+		})
+	}()
+}
+
+// runLeakTest checks that lookups reach the server through the profile and
+// shows the result in the status card
+func (g *GUI) runLeakTest() {
+	if g.config.Profile == "" {
+		g.leakLabel.SetText(i18n.T("Connect to a profile first"))
+		return
+	}
+
+	serverURL, profile := g.config.ServerURL, g.config.Profile
+	g.leakLabel.SetText("")
+
+	g.busy(g.leakBtn, i18n.T("Testing..."), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		result, err := leaktest.Run(ctx, serverURL, profile)
+		if err != nil {
+			log.Printf("Leak test failed: %v", err)
+			g.do(func() { g.leakLabel.SetText(i18n.T("Leak test failed: %v", err)) })
+			return
+		}
+
+		g.do(func() { g.leakLabel.SetText(result.Summary()) })
+		if result.Leaked > 0 {
+			g.showError(result.Summary())
+		}
+	})
+}
+
+// restoreNetwork asks the daemon to turn filtering off and undo all its
+// changes to system networking, after the user confirmed
+func (g *GUI) restoreNetwork() {
+	g.window.Show()
+	dialog.ShowConfirm(i18n.T("Restore network settings"),
+		i18n.T("Turn filtering off and put back the network settings from before FilterDNS was installed?"),
+		func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			go func() {
+				if _, err := g.client.RestoreNetwork(); err != nil {
+					log.Printf("Restoring network failed: %v", err)
+					g.showError(i18n.T("Restoring network failed: %v. Run \"sudo filterdns-client restore-network\" instead.", err))
+					return
+				}
+				g.showInfo(i18n.T("Network settings restored, filtering is off"))
+				g.refreshStatus()
+			}()
+		}, g.window)
 }
 
 // openDashboard opens the FilterDNS web dashboard
@@ -513,18 +1234,21 @@ func (g *GUI) openDashboard() {
 	g.app.OpenURL(u)
 }
 
-// showError displays an error notification
+// showError displays an error dialog and notification, suppressing
+// bursts of similar errors
 func (g *GUI) showError(msg string) {
-	fyne.CurrentApp().SendNotification(&fyne.Notification{
-		Title:   "FilterDNS Error",
-		Content: msg,
-	})
+	g.errors.Notify(i18n.T("FilterDNS Error"), msg)
 }
 
-// showInfo displays an info notification
+// showInfo confirms a success in the status bar
 func (g *GUI) showInfo(msg string) {
+	g.showStatusMessage(msg)
+}
+
+// sendNotification shows a desktop notification
+func sendNotification(title, message string) {
 	fyne.CurrentApp().SendNotification(&fyne.Notification{
-		Title:   "FilterDNS",
-		Content: msg,
+		Title:   title,
+		Content: message,
 	})
 }
@@ -0,0 +1,68 @@
+package gui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+)
+
+// heartbeatLabels describes each heartbeat field for its checkbox
+var heartbeatLabels = map[string]string{
+	config.HeartbeatName:      "This computer's name",
+	config.HeartbeatVersion:   "FilterDNS version",
+	config.HeartbeatOS:        "Operating system",
+	config.HeartbeatFiltering: "Whether filtering is on, off or paused",
+	config.HeartbeatCounters:  "Number of queries and blocked queries",
+	config.HeartbeatErrors:    "The last problem, e.g. a failed health check",
+}
+
+// heartbeatContent builds the card choosing what the daemon reports to
+// the server when it syncs, and whether it follows commands from it.
+// Nothing is checked until the user opts in; changes are saved with the
+// Save button.
+func (g *GUI) heartbeatContent() fyne.CanvasObject {
+	g.heartbeatChecks = make(map[string]*widget.Check)
+	content := container.NewVBox()
+	for _, field := range config.HeartbeatFields {
+		c := widget.NewCheck(i18n.T(heartbeatLabels[field]), func(on bool) { g.setHeartbeat(field, on) })
+		g.heartbeatChecks[field] = c
+		content.Add(c)
+	}
+
+	// Not a heartbeat field, but the other half of what the dashboard
+	// can do with this device
+	g.commandsCheck = widget.NewCheck(i18n.T("Let admins flush, pause, switch profile or update from the dashboard"),
+		func(on bool) { g.config.ServerCommands = on })
+	content.Add(widget.NewSeparator())
+	content.Add(g.commandsCheck)
+	g.refreshHeartbeat()
+
+	return widget.NewCard(i18n.T("Device Status"),
+		i18n.T("Shared with the server when syncing, so the dashboard shows whether this device is online and healthy"),
+		content)
+}
+
+// setHeartbeat adds field to or removes it from the heartbeat, keeping
+// the order of config.HeartbeatFields
+func (g *GUI) setHeartbeat(field string, on bool) {
+	var fields []string
+	for _, f := range config.HeartbeatFields {
+		if f == field && on || f != field && g.config.Reports(f) {
+			fields = append(fields, f)
+		}
+	}
+	g.config.Heartbeat = fields
+}
+
+// refreshHeartbeat checks the boxes of the fields the config reports,
+// and whether it takes commands (must be called on the UI thread)
+func (g *GUI) refreshHeartbeat() {
+	for field, c := range g.heartbeatChecks {
+		c.Checked = g.config.Reports(field)
+		c.Refresh()
+	}
+	g.commandsCheck.Checked = g.config.ServerCommands
+	g.commandsCheck.Refresh()
+}
@@ -9,6 +9,37 @@ func AppIcon() fyne.Resource {
 	return fyne.NewStaticResource("icon.png", iconData)
 }
 
+// PausedIcon returns the tray icon shown while filtering is paused
+func PausedIcon() fyne.Resource {
+	return fyne.NewStaticResource("icon-paused.png", pausedIconData)
+}
+
+// DisabledIcon returns the tray icon shown while filtering is off
+func DisabledIcon() fyne.Resource {
+	return fyne.NewStaticResource("icon-disabled.png", disabledIconData)
+}
+
+// ErrorIcon returns the tray icon shown while filtering is on but failing
+// its health checks or out of sync with the server
+func ErrorIcon() fyne.Resource {
+	return fyne.NewStaticResource("icon-error.png", errorIconData)
+}
+
+// NoDaemonIcon returns the tray icon shown while the daemon is not running
+func NoDaemonIcon() fyne.Resource {
+	return fyne.NewStaticResource("icon-no-daemon.png", noDaemonIconData)
+}
+
+// DisabledDarkIcon is DisabledIcon for dark menu bars
+func DisabledDarkIcon() fyne.Resource {
+	return fyne.NewStaticResource("icon-disabled-dark.png", disabledDarkIconData)
+}
+
+// NoDaemonDarkIcon is NoDaemonIcon for dark menu bars
+func NoDaemonDarkIcon() fyne.Resource {
+	return fyne.NewStaticResource("icon-no-daemon-dark.png", noDaemonDarkIconData)
+}
+
 // Valid 16x16 green PNG icon
 var iconData = []byte{
 	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d,
@@ -19,3 +50,73 @@ var iconData = []byte{
 	0x98, 0x4a, 0x10, 0x19, 0xf9, 0x5f, 0x60, 0x00, 0x00, 0x00, 0x00, 0x49,
 	0x45, 0x4e, 0x44, 0xae, 0x42, 0x60, 0x82,
 }
+
+// Valid 16x16 amber PNG icon
+var pausedIconData = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d,
+	0x49, 0x48, 0x44, 0x52, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x10,
+	0x08, 0x02, 0x00, 0x00, 0x00, 0x90, 0x91, 0x68, 0x36, 0x00, 0x00, 0x00,
+	0x16, 0x49, 0x44, 0x41, 0x54, 0x78, 0xda, 0x63, 0xf8, 0xba, 0x4c, 0x99,
+	0x24, 0xc4, 0x30, 0xaa, 0x61, 0x54, 0xc3, 0xf0, 0xd5, 0x00, 0x00, 0xed,
+	0x15, 0xbe, 0x10, 0x9c, 0x5c, 0x85, 0xd6, 0x00, 0x00, 0x00, 0x00, 0x49,
+	0x45, 0x4e, 0x44, 0xae, 0x42, 0x60, 0x82,
+}
+
+// Valid 16x16 grey PNG icon
+var disabledIconData = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d,
+	0x49, 0x48, 0x44, 0x52, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x10,
+	0x08, 0x02, 0x00, 0x00, 0x00, 0x90, 0x91, 0x68, 0x36, 0x00, 0x00, 0x00,
+	0x1b, 0x49, 0x44, 0x41, 0x54, 0x78, 0xda, 0x62, 0x99, 0x37, 0x6f, 0x1e,
+	0x03, 0x29, 0x80, 0x89, 0x81, 0x44, 0x30, 0xaa, 0x61, 0x54, 0xc3, 0xd0,
+	0xd1, 0x00, 0x18, 0x00, 0xe5, 0x77, 0x01, 0xfd, 0x45, 0x1c, 0x8f, 0xc3,
+	0x00, 0x00, 0x00, 0x00, 0x49, 0x45, 0x4e, 0x44, 0xae, 0x42, 0x60, 0x82,
+}
+
+// Valid 16x16 red PNG icon
+var errorIconData = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d,
+	0x49, 0x48, 0x44, 0x52, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x10,
+	0x08, 0x02, 0x00, 0x00, 0x00, 0x90, 0x91, 0x68, 0x36, 0x00, 0x00, 0x00,
+	0x1b, 0x49, 0x44, 0x41, 0x54, 0x78, 0xda, 0x62, 0xb9, 0xac, 0xaf, 0xcf,
+	0x40, 0x0a, 0x60, 0x62, 0x20, 0x11, 0x8c, 0x6a, 0x18, 0xd5, 0x30, 0x74,
+	0x34, 0x00, 0x06, 0x00, 0xe1, 0xbf, 0x01, 0x54, 0x3d, 0xfb, 0x42, 0xc1,
+	0x00, 0x00, 0x00, 0x00, 0x49, 0x45, 0x4e, 0x44, 0xae, 0x42, 0x60, 0x82,
+}
+
+// Valid 16x16 grey outline PNG icon
+var noDaemonIconData = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d,
+	0x49, 0x48, 0x44, 0x52, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x10,
+	0x08, 0x06, 0x00, 0x00, 0x00, 0x1f, 0xf3, 0xff, 0x61, 0x00, 0x00, 0x00,
+	0x37, 0x49, 0x44, 0x41, 0x54, 0x78, 0xda, 0x62, 0x99, 0x37, 0x6f, 0xde,
+	0x7f, 0x06, 0x0a, 0x00, 0x13, 0x03, 0x85, 0x80, 0x85, 0x81, 0x81, 0x81,
+	0x81, 0x81, 0x81, 0x81, 0x21, 0x29, 0x29, 0x89, 0x91, 0x14, 0x8d, 0x30,
+	0x97, 0x53, 0xec, 0x82, 0x51, 0x03, 0x46, 0x0d, 0x18, 0x1c, 0x06, 0xb0,
+	0xa0, 0xa7, 0x6d, 0xba, 0xbb, 0x00, 0x30, 0x00, 0xbd, 0xd0, 0x09, 0xd9,
+	0x05, 0xa1, 0x63, 0x74, 0x00, 0x00, 0x00, 0x00, 0x49, 0x45, 0x4e, 0x44,
+	0xae, 0x42, 0x60, 0x82,
+}
+
+// Valid 16x16 light grey PNG icon
+var disabledDarkIconData = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d,
+	0x49, 0x48, 0x44, 0x52, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x10,
+	0x08, 0x02, 0x00, 0x00, 0x00, 0x90, 0x91, 0x68, 0x36, 0x00, 0x00, 0x00,
+	0x14, 0x49, 0x44, 0x41, 0x54, 0x78, 0xda, 0x63, 0xb8, 0x42, 0x22, 0x60,
+	0x18, 0xd5, 0x30, 0xaa, 0x61, 0xf8, 0x6a, 0x00, 0x00, 0x1c, 0x22, 0x7c,
+	0x1f, 0xfe, 0xd2, 0x93, 0xa4, 0x00, 0x00, 0x00, 0x00, 0x49, 0x45, 0x4e,
+	0x44, 0xae, 0x42, 0x60, 0x82,
+}
+
+// Valid 16x16 light grey outline PNG icon
+var noDaemonDarkIconData = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d,
+	0x49, 0x48, 0x44, 0x52, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x10,
+	0x08, 0x06, 0x00, 0x00, 0x00, 0x1f, 0xf3, 0xff, 0x61, 0x00, 0x00, 0x00,
+	0x20, 0x49, 0x44, 0x41, 0x54, 0x78, 0xda, 0x63, 0xb8, 0x72, 0xe5, 0xca,
+	0x7f, 0x4a, 0x30, 0x03, 0xd5, 0x0c, 0x60, 0x20, 0x11, 0x8c, 0x1a, 0x30,
+	0x6a, 0xc0, 0x70, 0x35, 0x60, 0xc0, 0x72, 0x23, 0x00, 0xe4, 0xb1, 0x85,
+	0xe0, 0x32, 0x08, 0xb6, 0xd7, 0x00, 0x00, 0x00, 0x00, 0x49, 0x45, 0x4e,
+	0x44, 0xae, 0x42, 0x60, 0x82,
+}
@@ -0,0 +1,46 @@
+package gui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// activityTab is the Activity tab's position
+const activityTab = 1
+
+// tabKeys select the tabs by position with Ctrl (Cmd on macOS) and a
+// digit, as the tab bar cannot take the keyboard focus
+var tabKeys = []fyne.KeyName{fyne.Key1, fyne.Key2, fyne.Key3, fyne.Key4, fyne.Key5, fyne.Key6, fyne.Key7, fyne.Key8, fyne.Key9}
+
+// addShortcuts adds the window's keyboard shortcuts: Ctrl+E toggles
+// filtering, Ctrl+L searches the activity, Ctrl+S saves and Ctrl+1 to
+// Ctrl+9 pick a tab. The rest is reachable with Tab and Space. Fyne
+// hands shortcuts to a focused text field instead, so they work outside
+// of those.
+func (g *GUI) addShortcuts(tabs *container.AppTabs) {
+	canvas := g.window.Canvas()
+	add := func(key fyne.KeyName, action func()) {
+		canvas.AddShortcut(&desktop.CustomShortcut{KeyName: key, Modifier: fyne.KeyModifierShortcutDefault},
+			func(fyne.Shortcut) { action() })
+	}
+
+	add(fyne.KeyE, func() {
+		if !g.toggleBtn.Disabled() {
+			g.toggle()
+		}
+	})
+	add(fyne.KeyL, func() {
+		tabs.SelectIndex(activityTab)
+		canvas.Focus(g.activity.search)
+	})
+	add(fyne.KeyS, func() {
+		if !g.saveBtn.Disabled() {
+			g.save()
+		}
+	})
+	for i := range tabs.Items[:min(len(tabs.Items), len(tabKeys))] {
+		i := i // capture
+		add(tabKeys[i], func() { tabs.SelectIndex(i) })
+	}
+}
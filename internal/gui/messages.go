@@ -0,0 +1,131 @@
+package gui
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/update"
+)
+
+const (
+	// statusBarTimeout is how long a success message stays in the status
+	// bar
+	statusBarTimeout = 10 * time.Second
+
+	// errorDialogLimit caps the errors collected in the open error dialog
+	errorDialogLimit = 5
+)
+
+// presentError shows an error in a dialog, collecting errors that arrive
+// while it is open, and as a desktop notification in case the window is
+// hidden in the tray. It is the send function of g.errors, which keeps
+// bursts of similar errors from reaching it.
+func (g *GUI) presentError(title, message string) {
+	sendNotification(title, message)
+
+	g.do(func() {
+		g.errorLog = append(g.errorLog, fmt.Sprintf("%s  %s", time.Now().Format("15:04:05"), message))
+		if len(g.errorLog) > errorDialogLimit {
+			g.errorLog = g.errorLog[len(g.errorLog)-errorDialogLimit:]
+		}
+
+		if g.errorDialog != nil {
+			g.errorDialog.Hide()
+		}
+
+		details := widget.NewLabel(strings.Join(g.errorLog, "\n"))
+		details.Wrapping = fyne.TextWrapWord
+
+		errors := append([]string(nil), g.errorLog...)
+		copyBtn := widget.NewButton(i18n.T("Copy Diagnostics"), nil)
+		copyBtn.OnTapped = func() {
+			g.busy(copyBtn, i18n.T("Copying..."), func() {
+				text := g.diagnostics(errors)
+				g.do(func() {
+					g.window.Clipboard().SetContent(text)
+					g.showInfo(i18n.T("Diagnostics copied to the clipboard"))
+				})
+			})
+		}
+
+		d := dialog.NewCustom(title, i18n.T("Close"), container.NewVBox(details, copyBtn), g.window)
+		d.SetOnClosed(func() {
+			if g.errorDialog == d {
+				g.errorDialog = nil
+				g.errorLog = nil
+			}
+		})
+		d.Resize(fyne.NewSize(420, 0))
+		g.errorDialog = d
+		d.Show()
+	})
+}
+
+// diagnostics describes this client and the daemon's state along with
+// errors, for pasting into a support request
+func (g *GUI) diagnostics(errors []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "FilterDNS Client %s (%s/%s)\n", update.Version, runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "Time: %s\n", time.Now().Format(time.RFC3339))
+
+	status, err := g.client.Status()
+	if err != nil {
+		fmt.Fprintf(&b, "Daemon: not reachable (%v)\n", err)
+	} else {
+		fmt.Fprintf(&b, "Daemon: running since %s\n", status.StartedAt.Format(time.RFC3339))
+		fmt.Fprintf(&b, "Profile: %s on %s\n", status.Profile, status.ServerURL)
+		fmt.Fprintf(&b, "Filtering: running=%v paused=%v\n", status.Running, status.PausedUntil != nil)
+		if c := status.Connectivity; c != nil {
+			fmt.Fprintf(&b, "Connectivity: udp/53=%v tcp/853=%v tcp/443=%v udp/443=%v preferred=%s\n",
+				c.UDP53, c.TCP853, c.TCP443, c.UDP443, c.Preferred)
+		}
+		if h := status.Health; h != nil {
+			fmt.Fprintf(&b, "Health: ok=%v proxy=%v systemDNS=%v hijacked=%v repairs=%d\n",
+				h.OK, h.ProxyOK, h.SystemDNSOK, h.Hijacked, h.Repairs)
+		}
+		if s := status.Sync; s != nil && s.Error != "" {
+			fmt.Fprintf(&b, "Sync: %s\n", s.Error)
+		}
+	}
+
+	b.WriteString("\nErrors:\n")
+	for _, e := range errors {
+		b.WriteString(e + "\n")
+	}
+	return b.String()
+}
+
+// showStatusMessage shows msg in the status bar below the tabs until
+// statusBarTimeout passes or another message replaces it
+func (g *GUI) showStatusMessage(msg string) {
+	text := fmt.Sprintf("%s  %s", time.Now().Format("15:04"), msg)
+	g.do(func() {
+		g.statusBar.SetText(text)
+	})
+
+	time.AfterFunc(statusBarTimeout, func() {
+		g.do(func() {
+			if g.statusBar.Text == text {
+				g.statusBar.SetText("")
+			}
+		})
+	})
+}
+
+// setSaveError shows why the settings cannot be saved, or hides the
+// message if msg is empty
+func (g *GUI) setSaveError(msg string) {
+	g.saveError.SetText(msg)
+	if msg == "" {
+		g.saveError.Hide()
+	} else {
+		g.saveError.Show()
+	}
+}
@@ -0,0 +1,205 @@
+package gui
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/daemon"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/dns"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+)
+
+// Notification preferences, kept in the app preferences as they belong to
+// the user at this desktop rather than to the daemon
+const (
+	notifyBlockedKey  = "notifyBlocked"  // A query was blocked
+	notifyServerKey   = "notifyServer"   // The server paused or resumed filtering
+	notifyUpstreamKey = "notifyUpstream" // The server stopped or resumed answering
+	notifyClockKey    = "notifyClock"    // The clock got too far off from the server's
+	quietFromKey      = "quietFrom"      // Start of quiet hours, "HH:MM"
+	quietToKey        = "quietTo"        // End of quiet hours, "HH:MM"
+)
+
+// blockedBatch is how long blocked queries are collected into one
+// notification, so a page full of trackers does not raise one each
+const blockedBatch = 5 * time.Second
+
+// notifications raises desktop notifications for what the daemon reports,
+// as far as the user asked for them
+type notifications struct {
+	g       *GUI
+	started time.Time // Blocked queries from before are history, not news
+
+	mu      sync.Mutex
+	blocked []string           // Domains blocked in the current batch
+	last    *daemon.StateEvent // Previous state, to tell what changed
+}
+
+func newNotifications(g *GUI) *notifications {
+	return &notifications{g: g, started: time.Now()}
+}
+
+// enabled reports whether notifications of a kind are wanted right now
+func (n *notifications) enabled(key string, fallback bool) bool {
+	prefs := n.g.app.Preferences()
+	if !prefs.BoolWithFallback(key, fallback) {
+		return false
+	}
+	return !inQuietHours(time.Now(), prefs.String(quietFromKey), prefs.String(quietToKey))
+}
+
+// query notes a query from the daemon's feed, notifying of blocked ones
+// at most once per blockedBatch
+func (n *notifications) query(entry dns.QueryLogEntry) {
+	if !entry.Blocked || entry.Time.Before(n.started) || !n.enabled(notifyBlockedKey, false) {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, domain := range n.blocked {
+		if domain == entry.Domain {
+			return
+		}
+	}
+	n.blocked = append(n.blocked, entry.Domain)
+	if len(n.blocked) == 1 {
+		time.AfterFunc(blockedBatch, n.sendBlocked)
+	}
+}
+
+// sendBlocked ends a batch of blocked queries
+func (n *notifications) sendBlocked() {
+	n.mu.Lock()
+	domains := n.blocked
+	n.blocked = nil
+	n.mu.Unlock()
+
+	msg := i18n.T("Blocked %s", strings.TrimSuffix(domains[0], "."))
+	if len(domains) > 1 {
+		msg += i18n.T(" and %d other domains", len(domains)-1)
+	}
+	sendNotification("FilterDNS", msg)
+}
+
+// state notes a state event from the daemon, notifying of the server
+// pausing or resuming filtering, of the upstream going away or coming
+// back and of the clock going wrong, and bringing up the window on any
+// change in filtering if asked to
+func (n *notifications) state(event daemon.StateEvent) {
+	n.mu.Lock()
+	last := n.last
+	n.last = &event
+	n.mu.Unlock()
+
+	// The first event describes how things are, not a change
+	if last == nil {
+		return
+	}
+
+	if last.State != event.State && n.g.config.ShowOnStatusChange {
+		n.g.do(n.g.window.Show)
+	}
+
+	wasPaused, paused := last.State == daemon.StateServerPaused, event.State == daemon.StateServerPaused
+	if wasPaused != paused && n.enabled(notifyServerKey, true) {
+		if paused {
+			sendNotification("FilterDNS", i18n.T("The server paused filtering for this profile"))
+		} else if event.State == daemon.StateEnabled {
+			sendNotification("FilterDNS", i18n.T("The server resumed filtering"))
+		}
+	}
+
+	if last.UpstreamDown != event.UpstreamDown && n.enabled(notifyUpstreamKey, true) {
+		if event.UpstreamDown {
+			sendNotification("FilterDNS", i18n.T("The FilterDNS server is not answering"))
+		} else {
+			sendNotification("FilterDNS", i18n.T("The FilterDNS server is answering again"))
+		}
+	}
+
+	if last.ClockSkew == 0 && event.ClockSkew != 0 && n.enabled(notifyClockKey, true) {
+		sendNotification("FilterDNS", clockWarning(event.ClockSkew))
+	}
+}
+
+// reset forgets the last state, e.g. when the daemon went away, so the
+// state found on reconnecting is not taken for a change
+func (n *notifications) reset() {
+	n.mu.Lock()
+	n.last = nil
+	n.mu.Unlock()
+}
+
+// inQuietHours reports whether now falls between from and to, both
+// "HH:MM", wrapping past midnight if to is earlier than from. Quiet hours
+// are off unless both are set.
+func inQuietHours(now time.Time, from, to string) bool {
+	start, err1 := time.Parse("15:04", from)
+	end, err2 := time.Parse("15:04", to)
+	if err1 != nil || err2 != nil || start.Equal(end) {
+		return false
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	first, last := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	if first < last {
+		return minute >= first && minute < last
+	}
+	return minute >= first || minute < last
+}
+
+// quietTimeValidator accepts an empty field or a time of day
+func quietTimeValidator(s string) error {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil
+	}
+	if _, err := time.Parse("15:04", s); err != nil {
+		return errors.New(i18n.T("use a time like 22:00"))
+	}
+	return nil
+}
+
+// notificationsContent builds the Notifications settings card. Changes
+// apply at once, like the start on login checkbox.
+func (g *GUI) notificationsContent() fyne.CanvasObject {
+	prefs := g.app.Preferences()
+
+	check := func(label, key string, fallback bool) *widget.Check {
+		c := widget.NewCheck(label, func(on bool) { prefs.SetBool(key, on) })
+		c.Checked = prefs.BoolWithFallback(key, fallback)
+		return c
+	}
+
+	quietEntry := func(key string) *widget.Entry {
+		e := widget.NewEntry()
+		e.SetPlaceHolder("HH:MM")
+		e.SetText(prefs.String(key))
+		e.Validator = quietTimeValidator
+		e.OnChanged = func(s string) {
+			if quietTimeValidator(s) == nil {
+				prefs.SetString(key, strings.TrimSpace(s))
+			}
+		}
+		return e
+	}
+
+	content := container.NewVBox(
+		check(i18n.T("When a domain is blocked"), notifyBlockedKey, false),
+		check(i18n.T("When the server pauses or resumes filtering"), notifyServerKey, true),
+		check(i18n.T("When the FilterDNS server stops or resumes answering"), notifyUpstreamKey, true),
+		check(i18n.T("When this computer's clock is off from the server's"), notifyClockKey, true),
+		container.NewHBox(
+			widget.NewLabel(i18n.T("Quiet hours from")),
+			container.NewGridWrap(fyne.NewSize(80, 36), quietEntry(quietFromKey)),
+			widget.NewLabel(i18n.T("to")),
+			container.NewGridWrap(fyne.NewSize(80, 36), quietEntry(quietToKey)),
+		),
+	)
+	return widget.NewCard(i18n.T("Notifications"), i18n.T("Desktop notifications, none during quiet hours"), content)
+}
@@ -0,0 +1,56 @@
+package gui
+
+import (
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/qr"
+)
+
+// showPairing shows the onboarding URL as a QR code, so the setup can be
+// finished on a phone when there is no browser here or the machine is
+// out of reach. It closes by itself once onboarding ends. (must be called
+// on the UI thread)
+func (g *GUI) showPairing(onboardURL string) {
+	code, err := qr.Encode(onboardURL)
+	if err != nil {
+		log.Printf("Failed to encode onboarding URL: %v", err)
+		return
+	}
+
+	img := canvas.NewImageFromImage(code.Image(8))
+	img.FillMode = canvas.ImageFillContain
+	img.ScaleMode = canvas.ImageScalePixels
+	img.SetMinSize(fyne.NewSize(240, 240))
+
+	link := widget.NewLabel(onboardURL)
+	link.Wrapping = fyne.TextWrapBreak
+	copyBtn := widget.NewButton(i18n.T("Copy Link"), func() {
+		g.window.Clipboard().SetContent(onboardURL)
+		g.showInfo(i18n.T("Link copied"))
+	})
+
+	g.hidePairing()
+	g.pairing = dialog.NewCustom(i18n.T("Finish Setup on Another Device"), i18n.T("Close"), container.NewVBox(
+		widget.NewLabel(i18n.T("Complete the setup in the browser that opened, or scan this code with your phone:")),
+		img,
+		link,
+		container.NewCenter(copyBtn),
+	), g.window)
+	g.pairing.Resize(fyne.NewSize(420, 0))
+	g.pairing.Show()
+}
+
+// hidePairing closes the QR code dialog, if it is open (must be called on
+// the UI thread)
+func (g *GUI) hidePairing() {
+	if g.pairing != nil {
+		g.pairing.Hide()
+		g.pairing = nil
+	}
+}
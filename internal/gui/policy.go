@@ -0,0 +1,54 @@
+package gui
+
+import (
+	"slices"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+)
+
+// isLocked reports whether the administrator's policy locks the setting
+// with the given name in config.json
+func (g *GUI) isLocked(key string) bool {
+	return slices.Contains(g.locked, key)
+}
+
+// setLocked greys out the settings the daemon reports as locked (must be
+// called on the UI thread)
+func (g *GUI) setLocked(keys []string) {
+	if slices.Equal(g.locked, keys) {
+		return
+	}
+	g.locked = keys
+	g.applyLocks()
+	g.refreshSavedProfiles()
+}
+
+// lockedNoteLabel builds the note shown while settings are locked
+func (g *GUI) lockedNoteLabel() fyne.CanvasObject {
+	g.lockedNote = widget.NewLabel(i18n.T("Some settings are locked by your administrator."))
+	g.lockedNote.Importance = widget.LowImportance
+	g.lockedNote.Wrapping = fyne.TextWrapWord
+	return g.lockedNote
+}
+
+// applyLocks greys out the fields of locked settings and shows the note
+// if there are any (must be called on the UI thread)
+func (g *GUI) applyLocks() {
+	lock := func(w fyne.Disableable, key string) {
+		if g.isLocked(key) {
+			w.Disable()
+		} else {
+			w.Enable()
+		}
+	}
+	lock(g.serverEntry, "serverUrl")
+	lock(g.profileEntry, "profile")
+
+	if len(g.locked) > 0 {
+		g.lockedNote.Show()
+	} else {
+		g.lockedNote.Hide()
+	}
+}
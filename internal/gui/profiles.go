@@ -0,0 +1,117 @@
+package gui
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/onboard"
+)
+
+// profileList is what the server said about its profiles, for the
+// profile selector
+type profileList struct {
+	serverURL string                // Server the list came from
+	profiles  []onboard.ProfileInfo // Nil if the server does not list them
+}
+
+// profileSelector builds the profile field: a dropdown of the profiles
+// the server offers that still takes a typed name, so servers that do not
+// list their profiles keep working
+func (g *GUI) profileSelector() fyne.CanvasObject {
+	g.profileEntry = widget.NewSelectEntry(nil)
+	g.profileEntry.SetPlaceHolder("my-profile-name")
+	g.profileEntry.SetText(g.config.Profile)
+	g.profileEntry.OnChanged = func(string) { g.updateProfileHint() }
+	g.profileEntry.Validator = profileValidator
+
+	g.profileHint = widget.NewLabel("")
+	g.profileHint.Wrapping = fyne.TextWrapWord
+
+	g.profilesBtn = widget.NewButtonWithIcon(i18n.T("Refresh"), theme.ViewRefreshIcon(), g.refreshProfiles)
+
+	g.refreshProfiles()
+
+	return container.NewVBox(
+		container.NewBorder(nil, nil, nil, g.profilesBtn, g.profileEntry),
+		g.profileHint,
+	)
+}
+
+// refreshProfiles fetches the profile list from the server in the Server
+// URL field
+func (g *GUI) refreshProfiles() {
+	serverURL := strings.TrimSpace(g.serverEntry.Text)
+	if serverURL == "" {
+		serverURL = g.config.ServerURL
+	}
+	if serverURL == "" {
+		return
+	}
+
+	g.busy(g.profilesBtn, i18n.T("Loading..."), func() {
+		profiles, err := onboard.ListProfiles(serverURL)
+		if err != nil {
+			log.Printf("Failed to list profiles: %v", err)
+		}
+
+		g.do(func() {
+			g.profileList = profileList{serverURL: serverURL, profiles: profiles}
+
+			names := make([]string, 0, len(profiles))
+			for _, p := range profiles {
+				names = append(names, p.Name)
+			}
+			g.profileEntry.SetOptions(names)
+
+			switch {
+			case err != nil:
+				g.profileHint.SetText(i18n.T("Could not list profiles (%v), type the name instead", err))
+			case len(profiles) == 0:
+				g.profileHint.SetText(i18n.T("The server offers no profiles"))
+			default:
+				g.updateProfileHint()
+			}
+		})
+	})
+}
+
+// updateProfileHint says whether the profile in the field exists on the
+// server and needs a password
+func (g *GUI) updateProfileHint() {
+	if g.profileList.profiles == nil {
+		return
+	}
+
+	name := strings.TrimSpace(g.profileEntry.Text)
+	i := slices.IndexFunc(g.profileList.profiles, func(p onboard.ProfileInfo) bool { return p.Name == name })
+	switch {
+	case name == "":
+		g.profileHint.SetText(i18n.T("Choose one of %d profiles", len(g.profileList.profiles)))
+	case i < 0:
+		g.profileHint.SetText(i18n.T("The server has no profile %q", name))
+	case g.profileList.profiles[i].HasPassword:
+		g.profileHint.SetText(i18n.T("This profile needs a password"))
+	default:
+		g.profileHint.SetText("")
+	}
+}
+
+// checkProfile rejects a profile the server is known not to have
+func (g *GUI) checkProfile(serverURL, profile string) error {
+	list := g.profileList
+	serverURL, profile = strings.TrimSpace(serverURL), strings.TrimSpace(profile)
+	if list.profiles == nil || list.serverURL != serverURL || profile == "" {
+		return nil
+	}
+	if !slices.ContainsFunc(list.profiles, func(p onboard.ProfileInfo) bool { return p.Name == profile }) {
+		return errors.New(i18n.T("server has no profile %s", profile))
+	}
+	return nil
+}
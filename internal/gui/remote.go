@@ -0,0 +1,244 @@
+package gui
+
+import (
+	"errors"
+	"log"
+	"net"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/daemon"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+)
+
+// Preferences naming the daemon on another machine the GUI manages, if
+// any. Its token is kept in the keychain under remoteTokenKey.
+const (
+	remoteAddrKey        = "remoteDaemonAddr"
+	remoteFingerprintKey = "remoteDaemonFingerprint"
+)
+
+// remoteTokenKey is the keychain entry for the token of the daemon at
+// addr; profile names cannot contain the colon, so it cannot clash
+func remoteTokenKey(addr string) string {
+	return "remote-daemon:" + addr
+}
+
+// savedEndpoint is the remote daemon chosen in Settings, or nil for the
+// local one
+func (g *GUI) savedEndpoint() *daemon.Endpoint {
+	prefs := g.app.Preferences()
+	addr := prefs.String(remoteAddrKey)
+	if addr == "" {
+		return nil
+	}
+	token, err := config.GetPassword(remoteTokenKey(addr))
+	if err != nil {
+		log.Printf("Failed to read remote daemon token: %v", err)
+	}
+	return &daemon.Endpoint{Addr: addr, Token: token, Fingerprint: prefs.String(remoteFingerprintKey)}
+}
+
+// daemonContent builds the Daemon settings card, which picks the daemon
+// on this computer or one on another, e.g. a Raspberry Pi filtering the
+// whole home network
+func (g *GUI) daemonContent() fyne.CanvasObject {
+	local, remote := i18n.T("This computer"), i18n.T("Another computer")
+
+	addrEntry := widget.NewEntry()
+	addrEntry.SetPlaceHolder("raspberrypi.local:" + daemon.ControlPort)
+	addrEntry.Validator = func(s string) error {
+		if _, _, err := net.SplitHostPort(strings.TrimSpace(s)); err != nil {
+			return errors.New(i18n.T("enter the address as host:port"))
+		}
+		return nil
+	}
+	tokenEntry := widget.NewPasswordEntry()
+	fingerprintEntry := widget.NewEntry()
+	fingerprintEntry.SetPlaceHolder("ab:cd:ef:...")
+
+	if e := g.savedEndpoint(); e != nil {
+		addrEntry.SetText(e.Addr)
+		tokenEntry.SetText(e.Token)
+		fingerprintEntry.SetText(e.Fingerprint)
+	}
+
+	var connectBtn *widget.Button
+	connectBtn = widget.NewButton(i18n.T("Connect"), func() {
+		if addrEntry.Validate() != nil {
+			return
+		}
+		g.connectRemote(connectBtn, &daemon.Endpoint{
+			Addr:        strings.TrimSpace(addrEntry.Text),
+			Token:       strings.TrimSpace(tokenEntry.Text),
+			Fingerprint: strings.TrimSpace(fingerprintEntry.Text),
+		})
+	})
+
+	hint := widget.NewLabel(i18n.T("Run \"filterdns-client remote-control\" on the other computer to see these. Through an SSH tunnel, use localhost and the forwarded port."))
+	hint.Wrapping = fyne.TextWrapWord
+
+	remoteForm := container.NewVBox(
+		widget.NewForm(
+			widget.NewFormItem(i18n.T("Address"), addrEntry),
+			widget.NewFormItem(i18n.T("Token"), tokenEntry),
+			widget.NewFormItem(i18n.T("Fingerprint"), fingerprintEntry),
+		),
+		hint,
+		container.NewHBox(connectBtn),
+	)
+
+	choice := widget.NewRadioGroup([]string{local, remote}, nil)
+	choice.Horizontal = true
+	if g.client.Remote() {
+		choice.Selected = remote
+	} else {
+		choice.Selected = local
+		remoteForm.Hide()
+	}
+	choice.Required = true
+	choice.OnChanged = func(selected string) {
+		if selected == remote {
+			remoteForm.Show()
+			return
+		}
+		remoteForm.Hide()
+		if g.client.Remote() {
+			g.connectLocal()
+		}
+	}
+
+	return widget.NewCard(i18n.T("Daemon"), i18n.T("The FilterDNS service this window manages"),
+		container.NewVBox(choice, remoteForm))
+}
+
+// connectRemote checks that the daemon at e answers and accepts the
+// token, then manages it instead of the current one
+func (g *GUI) connectRemote(btn *widget.Button, e *daemon.Endpoint) {
+	g.busy(btn, i18n.T("Connecting..."), func() {
+		probe := daemon.NewClient()
+		probe.SetEndpoint(e)
+		if err := probe.Ping(); err != nil {
+			log.Printf("Remote daemon check failed: %v", err)
+			g.showError(i18n.T("Could not connect to the daemon at %s: %v", e.Addr, err))
+			return
+		}
+
+		if err := config.SetPassword(remoteTokenKey(e.Addr), e.Token); err != nil {
+			g.showError(i18n.T("Failed to save the token: %v", err))
+			return
+		}
+		prefs := g.app.Preferences()
+		prefs.SetString(remoteAddrKey, e.Addr)
+		prefs.SetString(remoteFingerprintKey, e.Fingerprint)
+
+		g.client.SetEndpoint(e)
+		g.connected.Store(false)
+		g.showInfo(i18n.T("Managing the daemon at %s", e.Addr))
+		g.refreshStatus()
+	})
+}
+
+// connectLocal goes back to the daemon on this computer and its config
+func (g *GUI) connectLocal() {
+	prefs := g.app.Preferences()
+	if addr := prefs.String(remoteAddrKey); addr != "" {
+		if err := config.DeletePassword(remoteTokenKey(addr)); err != nil {
+			log.Printf("Failed to delete remote daemon token: %v", err)
+		}
+	}
+	prefs.RemoveValue(remoteAddrKey)
+	prefs.RemoveValue(remoteFingerprintKey)
+	g.client.SetEndpoint(nil)
+	g.connected.Store(false)
+
+	go func() {
+		cfg, err := config.Load()
+		if err != nil {
+			cfg = config.Default()
+		}
+		password, _ := config.GetPassword(cfg.Profile)
+		g.do(func() { g.useConfig(cfg, password) })
+		g.showInfo(i18n.T("Managing the daemon on this computer"))
+		g.refreshStatus()
+	}()
+}
+
+// loadRemoteConfig edits the remote daemon's config from now on. The
+// profile password stays in its keychain, so the field starts empty.
+func (g *GUI) loadRemoteConfig() {
+	cfg, err := g.client.GetConfig()
+	if err != nil {
+		log.Printf("Failed to load the remote daemon's config: %v", err)
+		return
+	}
+	g.do(func() {
+		config.CopyUserSettings(cfg, g.config)
+		g.useConfig(cfg, "")
+	})
+}
+
+// reloadConfig edits the daemon's config from now on after it applied a
+// config file changed elsewhere, so this window cannot save over it. The
+// password field stays unless the profile changed.
+func (g *GUI) reloadConfig() {
+	cfg, err := g.client.GetConfig()
+	if err != nil {
+		log.Printf("Failed to load the daemon's new config: %v", err)
+		return
+	}
+	// A remote daemon keeps its passwords to itself
+	remote := g.client.Remote()
+	var stored string
+	if !remote {
+		stored, _ = config.GetPassword(cfg.Profile)
+	}
+	g.do(func() {
+		password := g.passwordEntry.Text
+		if cfg.Profile != g.config.Profile || cfg.ServerURL != g.config.ServerURL {
+			password = stored
+		}
+		if remote {
+			config.CopyUserSettings(cfg, g.config)
+		}
+		g.useConfig(cfg, password)
+	})
+	g.showInfo(i18n.T("Settings were changed elsewhere and have been reloaded"))
+}
+
+// useConfig replaces the config being edited and the widgets showing it
+// (must be called on the UI thread)
+func (g *GUI) useConfig(cfg *config.Config, password string) {
+	g.config = cfg
+	g.profileEntry.SetText(cfg.Profile)
+	g.serverEntry.SetText(cfg.ServerURL)
+	g.passwordEntry.SetText(password)
+	g.refreshProfiles()
+	g.refreshForwarderList()
+	g.refreshRuleList()
+	g.refreshSavedProfiles()
+	g.refreshHeartbeat()
+	g.updateTray()
+}
+
+// errRemoteDown is returned instead of falling back to the local config
+// file while a remote daemon does not answer
+func errRemoteDown() error {
+	return errors.New(i18n.T("the daemon on the other computer does not answer"))
+}
+
+// setPassword keeps a profile password where the managed daemon reads
+// it: this computer's keychain, or the remote daemon's. An empty password
+// deletes it.
+func (g *GUI) setPassword(profile, password string) error {
+	if g.client.Remote() {
+		return g.client.SetPassword(profile, password)
+	}
+	if password == "" {
+		return config.DeletePassword(profile)
+	}
+	return config.SetPassword(profile, password)
+}
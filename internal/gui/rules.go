@@ -0,0 +1,216 @@
+package gui
+
+import (
+	"errors"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+	filtersync "github.com/zkmkarlsruhe/filterdns-client/internal/sync"
+)
+
+// rulesContent builds the Rules tab: a domain entry with buttons to allow
+// or block it, and the rules there are
+func (g *GUI) rulesContent() fyne.CanvasObject {
+	domainEntry := widget.NewEntry()
+	domainEntry.SetPlaceHolder("ads.example.com")
+	domainEntry.Validator = func(s string) error { return config.ValidateDomain(config.NormalizeRuleDomain(s)) }
+
+	add := func(action string, server bool) func() {
+		return func() {
+			if domainEntry.Validate() != nil {
+				return
+			}
+			g.setRule(config.Rule{Domain: config.NormalizeRuleDomain(domainEntry.Text), Action: action, Server: server})
+			domainEntry.SetText("")
+		}
+	}
+	allowBtn := widget.NewButton(i18n.T("Allow here"), add(config.RuleAllow, false))
+	blockBtn := widget.NewButton(i18n.T("Block here"), add(config.RuleBlock, false))
+	g.serverBlockBtn = widget.NewButton(i18n.T("Block for the profile"), add(config.RuleBlock, true))
+
+	g.ruleList = container.NewVBox()
+	g.refreshRuleList()
+
+	content := container.NewBorder(
+		container.NewVBox(
+			domainEntry,
+			container.NewHBox(allowBtn, blockBtn, g.serverBlockBtn),
+			widget.NewSeparator(),
+		),
+		nil, nil, nil,
+		container.NewVScroll(g.ruleList),
+	)
+	return widget.NewCard(i18n.T("Rules"),
+		i18n.T("Allow or block domains and their subdomains on this device, or block them for every device using the profile"),
+		content)
+}
+
+// refreshRuleList shows the rules, saying which are on the server too
+// (must be called on the UI thread)
+func (g *GUI) refreshRuleList() {
+	if g.ruleList == nil {
+		return
+	}
+	if g.serverRules() {
+		g.serverBlockBtn.Enable()
+	} else {
+		g.serverBlockBtn.Disable()
+	}
+
+	g.ruleList.RemoveAll()
+	if len(g.config.Rules) == 0 {
+		g.ruleList.Add(widget.NewLabel(i18n.T("No rules yet; right-click a query in the Activity tab to add one")))
+		return
+	}
+
+	for _, rule := range g.config.Rules {
+		rule := rule // capture
+		action := i18n.T("allowed")
+		if rule.Action == config.RuleBlock {
+			action = i18n.T("blocked")
+		}
+		scope := i18n.T("this device")
+		if rule.Server {
+			scope = i18n.T("whole profile")
+		}
+		g.ruleList.Add(container.NewHBox(
+			widget.NewLabel(rule.Domain),
+			layout.NewSpacer(),
+			widget.NewLabel(action),
+			widget.NewLabel(scope),
+			widget.NewButtonWithIcon(i18n.T("Remove"), theme.DeleteIcon(), func() {
+				g.removeRule(rule)
+			}),
+		))
+	}
+}
+
+// serverRules reports whether rules can go to the server profile (must be
+// called on the UI thread)
+func (g *GUI) serverRules() bool {
+	caps := g.config.CapabilitiesFor(g.config.ServerURL)
+	return caps != nil && caps.Rules && g.config.Profile != ""
+}
+
+// ruleMenuItems are the quick actions for a domain, e.g. in the Activity
+// tab's context menu
+func (g *GUI) ruleMenuItems(domain string) []*fyne.MenuItem {
+	domain = config.NormalizeRuleDomain(domain)
+	items := []*fyne.MenuItem{
+		fyne.NewMenuItem(i18n.T("Allow %s here", domain), func() {
+			g.setRule(config.Rule{Domain: domain, Action: config.RuleAllow})
+		}),
+		fyne.NewMenuItem(i18n.T("Block %s here", domain), func() {
+			g.setRule(config.Rule{Domain: domain, Action: config.RuleBlock})
+		}),
+	}
+	if g.serverRules() {
+		items = append(items, fyne.NewMenuItem(i18n.T("Block %s for the profile", domain), func() {
+			g.setRule(config.Rule{Domain: domain, Action: config.RuleBlock, Server: true})
+		}))
+	}
+	return items
+}
+
+// setRule adds an allow or block rule, through the daemon if it runs so
+// the proxy applies it at once. A rule for the server goes there first
+// and is only kept if the server took it.
+func (g *GUI) setRule(rule config.Rule) {
+	go func() {
+		cfg, err := g.changeRules(func(cfg *config.Config) error {
+			if rule.Server {
+				mirrored, err := filtersync.MirrorRule(cfg, rule, false)
+				if err != nil {
+					return err
+				}
+				if !mirrored {
+					return errors.New(i18n.T("the server takes no rules for this profile"))
+				}
+			}
+			cfg.SetRule(rule)
+			return nil
+		})
+		if err != nil {
+			log.Printf("Failed to add rule: %v", err)
+			g.showError(i18n.T("Failed to add a rule for %s: %v", rule.Domain, err))
+			return
+		}
+
+		// Keep the settings being edited from undoing the rule on save
+		g.do(func() {
+			g.config.Rules = cfg.Rules
+			g.refreshRuleList()
+		})
+
+		switch {
+		case rule.Server:
+			g.showInfo(i18n.T("%s is now blocked for profile %s", rule.Domain, cfg.Profile))
+		case rule.Action == config.RuleAllow:
+			g.showInfo(i18n.T("%s is now allowed", rule.Domain))
+		default:
+			g.showInfo(i18n.T("%s is now blocked", rule.Domain))
+		}
+	}()
+}
+
+// removeRule removes a rule, from the server profile too if it is there
+func (g *GUI) removeRule(rule config.Rule) {
+	go func() {
+		cfg, err := g.changeRules(func(cfg *config.Config) error {
+			cfg.RemoveRule(rule.Domain)
+			return nil
+		})
+		if err != nil {
+			log.Printf("Failed to remove rule: %v", err)
+			g.showError(i18n.T("Failed to remove the rule for %s: %v", rule.Domain, err))
+			return
+		}
+		g.do(func() {
+			g.config.Rules = cfg.Rules
+			g.refreshRuleList()
+		})
+
+		if rule.Server {
+			if _, err := filtersync.MirrorRule(cfg, rule, true); err != nil {
+				log.Printf("Failed to update server profile: %v", err)
+				g.showError(i18n.T("Rule removed on this device, but not from the server profile: %v", err))
+				return
+			}
+		}
+		g.showInfo(i18n.T("Rule removed: %s %s", rule.Action, rule.Domain))
+	}()
+}
+
+// changeRules applies change to the daemon's config if the daemon is
+// running, so the proxy picks it up at once, or else to the config file.
+// Call it off the UI thread.
+func (g *GUI) changeRules(change func(cfg *config.Config) error) (*config.Config, error) {
+	if g.client.IsRunning() {
+		cfg, err := g.client.GetConfig()
+		if err != nil {
+			return nil, err
+		}
+		if err := change(cfg); err != nil {
+			return nil, err
+		}
+		return cfg, g.client.SetConfig(cfg)
+	}
+	if g.client.Remote() {
+		return nil, errRemoteDown()
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := change(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, config.Save(cfg)
+}
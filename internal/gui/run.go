@@ -0,0 +1,86 @@
+package gui
+
+import (
+	"log"
+	"os"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/driver/desktop"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/onboard"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/system"
+)
+
+// Run creates the Fyne application and blocks until the user quits
+func Run() {
+	run("")
+}
+
+// RunDeepLink is Run for a start through a filterdns:// link, which the
+// OS passes as the only argument
+func RunDeepLink(link string) {
+	run(link)
+}
+
+// run starts the GUI, handling link once the window is up if it is set
+func run(link string) {
+	log.SetOutput(config.RedactingWriter(os.Stderr))
+	log.Println("Starting FilterDNS Client (GUI mode)")
+
+	// Create Fyne application
+	a := app.NewWithID("io.filterdns.client")
+	a.SetIcon(AppIcon())
+	log.Println("Fyne app created")
+
+	// Create main window
+	w := a.NewWindow("FilterDNS")
+	// Resizable; the content's minimum size keeps it usable when small
+	w.Resize(fyne.NewSize(560, 600))
+	log.Println("Window created")
+
+	// Create the GUI
+	g := New(a, w)
+	w.SetContent(g.Content())
+	log.Println("GUI initialized")
+
+	// Setup system tray if supported
+	if desk, ok := a.(desktop.App); ok {
+		log.Println("Desktop app detected, setting up system tray...")
+		g.SetupSystemTray(desk)
+		log.Println("System tray setup complete")
+	} else {
+		log.Println("WARNING: Desktop features not available (no system tray)")
+	}
+
+	// Hide window on close (keep in tray), unless set to quit
+	w.SetCloseIntercept(g.closeWindow)
+
+	// Show window on start, unless set to start in the tray
+	if g.startMinimized() {
+		log.Println("Starting minimized to tray")
+	} else {
+		log.Println("Showing window...")
+		w.Show()
+	}
+
+	// Make filterdns:// links from the dashboard open this app
+	go func() {
+		if err := system.RegisterURLScheme(onboard.DeepLinkScheme); err != nil {
+			log.Printf("Could not register %s:// links: %v", onboard.DeepLinkScheme, err)
+		}
+	}()
+	listenForDeepLinks(g.handleDeepLink)
+	if link != "" {
+		g.handleDeepLink(link)
+	}
+
+	// Run the app
+	log.Println("Running Fyne main loop...")
+	a.Run()
+
+	// Cleanup on exit
+	log.Println("Shutting down...")
+	g.Shutdown()
+	log.Println("Goodbye")
+}
@@ -0,0 +1,88 @@
+package gui
+
+import (
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/daemon"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+	filtersync "github.com/zkmkarlsruhe/filterdns-client/internal/sync"
+)
+
+// serverPauseContent builds the banner shown while the profile is paused
+// in the web UI, which otherwise looks like the client stopped working
+func (g *GUI) serverPauseContent() fyne.CanvasObject {
+	g.serverPauseText = widget.NewLabel("")
+	g.serverPauseText.TextStyle = fyne.TextStyle{Bold: true}
+	g.serverPauseText.Importance = widget.WarningImportance
+	g.serverPauseText.Wrapping = fyne.TextWrapWord
+
+	g.serverResumeBtn = widget.NewButton(i18n.T("Resume Now"), g.resumeOnServer)
+	g.serverResumeBtn.Importance = widget.WarningImportance
+
+	g.serverPause = container.NewBorder(nil, nil,
+		widget.NewIcon(theme.WarningIcon()),
+		container.NewVBox(layout.NewSpacer(), g.serverResumeBtn, layout.NewSpacer()),
+		g.serverPauseText)
+	g.serverPause.Hide()
+	return g.serverPause
+}
+
+// updateServerPause shows the banner, with the time left, while the
+// server has the profile paused (must be called on the UI thread)
+func (g *GUI) updateServerPause(sync *daemon.SyncStatus) {
+	if g.serverPause == nil {
+		return
+	}
+	if sync == nil || sync.Error != "" || sync.FilteringEnabled {
+		g.serverPause.Hide()
+		return
+	}
+
+	if sync.PausedUntil != nil {
+		g.serverPauseText.SetText(i18n.T("Filtering for profile %s is paused from the web dashboard, %s left",
+			g.config.Profile, pauseRemaining(*sync.PausedUntil)))
+	} else {
+		g.serverPauseText.SetText(i18n.T("Filtering for profile %s is paused from the web dashboard",
+			g.config.Profile))
+	}
+
+	// Servers without the resume API leave it to the web UI
+	if caps := g.config.CapabilitiesFor(g.config.ServerURL); caps != nil && caps.Resume {
+		g.serverResumeBtn.Show()
+	} else {
+		g.serverResumeBtn.Hide()
+	}
+	g.serverPause.Show()
+}
+
+// resumeOnServer turns filtering back on for the profile on the server,
+// for all its devices, and has the daemon pick that up at once
+func (g *GUI) resumeOnServer() {
+	serverURL, profile := g.config.ServerURL, g.config.Profile
+	g.busy(g.serverResumeBtn, i18n.T("Resuming..."), func() {
+		password, _ := config.GetPassword(profile)
+		if err := filtersync.ResumeProfile(serverURL, profile, password); err != nil {
+			log.Printf("Failed to resume on server: %v", err)
+			g.showError(i18n.T("Could not resume profile %s: %v", profile, err))
+			return
+		}
+		g.showInfo(i18n.T("Filtering resumed for profile %s", profile))
+
+		if !g.client.IsRunning() {
+			return
+		}
+		status, err := g.client.Sync()
+		if err != nil {
+			// The next regular sync catches up
+			log.Printf("Failed to sync after resuming: %v", err)
+			return
+		}
+		g.updateStatusDisplay(status)
+	})
+}
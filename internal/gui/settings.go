@@ -0,0 +1,217 @@
+package gui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/miekg/dns"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	filterdns "github.com/zkmkarlsruhe/filterdns-client/internal/dns"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/service"
+)
+
+// testDomain is looked up through the server by the connection test
+const testDomain = "example.com."
+
+// validateSettings checks the profile fields, showing what is wrong next
+// to them, and reports whether they can be saved
+func (g *GUI) validateSettings() bool {
+	var problems []string
+	for _, entry := range []fyne.Validatable{g.serverEntry, g.profileEntry} {
+		if err := entry.Validate(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	// The entries above show their own problems
+	cfg := *g.config
+	cfg.ServerURL = strings.TrimSpace(g.serverEntry.Text)
+	cfg.Profile = strings.TrimSpace(g.profileEntry.Text)
+	for _, problem := range validationProblems(&cfg) {
+		if problem.Field != "serverUrl" && problem.Field != "profile" {
+			problems = append(problems, describeProblem(&cfg, problem))
+		}
+	}
+
+	g.setSaveError(strings.Join(problems, "\n"))
+	return len(problems) == 0
+}
+
+// testConnection checks that the server in the form answers, serves
+// DNS-over-HTTPS and accepts the profile's password, and shows the result
+// under the server URL
+func (g *GUI) testConnection() {
+	serverURL := strings.TrimSpace(g.serverEntry.Text)
+	profile := strings.TrimSpace(g.profileEntry.Text)
+	password := g.passwordEntry.Text
+
+	if err := config.ValidateServerURL(serverURL); err != nil {
+		g.connectionLabel.SetText("✗ " + err.Error())
+		return
+	}
+	g.connectionLabel.SetText("")
+
+	g.busy(g.testBtn, i18n.T("Testing..."), func() {
+		result := checkConnection(serverURL, profile, password)
+		g.do(func() { g.connectionLabel.SetText(result) })
+	})
+}
+
+// checkConnection runs the connection test and describes its outcome,
+// one line per step, stopping at the first that fails
+func checkConnection(serverURL, profile, password string) string {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(serverURL)
+	if err != nil {
+		return i18n.T("✗ Server not reachable: %v", err)
+	}
+	resp.Body.Close()
+	lines := []string{i18n.T("✓ Server reachable")}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(testDomain, dns.TypeA)
+	_, err = filterdns.NewDoHClient(serverURL, profile).Query(ctx, msg, password)
+	switch {
+	case errors.Is(err, filterdns.ErrUnauthorized):
+		lines = append(lines, i18n.T("✓ DNS-over-HTTPS answers"), i18n.T("✗ Server rejected the profile password"))
+	case err != nil:
+		lines = append(lines, i18n.T("✗ DNS-over-HTTPS failed: %v", err))
+	case profile == "":
+		lines = append(lines, i18n.T("✓ DNS-over-HTTPS answers"))
+	default:
+		lines = append(lines, i18n.T("✓ DNS-over-HTTPS answers"), i18n.T("✓ Profile %s accepted", profile))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// installService installs and starts the background service with
+// administrator rights, showing progress on btn
+func (g *GUI) installService(btn *widget.Button) {
+	g.busy(btn, i18n.T("Installing..."), func() {
+		if err := service.InstallElevated(); err != nil {
+			log.Printf("Service install failed: %v", err)
+			g.showError(i18n.T("Could not install the service: %v", err))
+			return
+		}
+		g.showInfo(i18n.T("Background service installed"))
+		g.refreshServiceState()
+		g.refreshStatus()
+		if g.setup.visible.Load() {
+			g.setup.refresh()
+		}
+	})
+}
+
+// refreshServiceState shows whether the background service is installed,
+// offering to install it if not. It runs system tools, so call it off the
+// UI thread.
+func (g *GUI) refreshServiceState() {
+	running := g.client.IsRunning()
+	state, err := service.Status()
+
+	g.do(func() {
+		switch {
+		case running:
+			g.serviceLabel.SetText(i18n.T("Background service running"))
+			g.serviceBtn.Hide()
+		case err == nil && state.Installed:
+			g.serviceLabel.SetText(i18n.T("Background service installed, but not running"))
+			g.serviceBtn.Hide()
+		default:
+			g.serviceLabel.SetText("")
+			g.serviceBtn.Show()
+		}
+	})
+}
+
+// validationProblems returns what config.Validate finds wrong with cfg
+func validationProblems(cfg *config.Config) config.ValidationError {
+	var v config.ValidationError
+	errors.As(config.Validate(cfg), &v)
+	return v
+}
+
+// describeProblem names the forwarder, rule or saved profile a problem
+// is with, rather than its place in config.json
+func describeProblem(cfg *config.Config, problem *config.FieldError) string {
+	var i int
+	switch {
+	case scanIndex(problem.Field, "forwarders[%d]", &i) && i < len(cfg.Forwarders):
+		return i18n.T("Forwarder for %s: %v", cfg.Forwarders[i].Domain, problem.Err)
+	case scanIndex(problem.Field, "rules[%d]", &i) && i < len(cfg.Rules):
+		return i18n.T("Rule for %s: %v", cfg.Rules[i].Domain, problem.Err)
+	case scanIndex(problem.Field, "profiles[%d]", &i) && i < len(cfg.Profiles):
+		return i18n.T("Saved profile %s: %v", cfg.Profiles[i].Name, problem.Err)
+	}
+	return problem.Error()
+}
+
+// scanIndex reads the list index from a field name such as
+// "forwarders[2].server"
+func scanIndex(field, format string, i *int) bool {
+	n, _ := fmt.Sscanf(field, format, i)
+	return n == 1
+}
+
+// problemNote is a row's marker for a problem with its setting
+func problemNote(text string) *widget.Label {
+	note := widget.NewLabel("⚠ " + text)
+	note.Importance = widget.DangerImportance
+	return note
+}
+
+// serverURLValidator adapts config.ValidateServerURL to an entry
+func serverURLValidator(s string) error {
+	return config.ValidateServerURL(strings.TrimSpace(s))
+}
+
+// profileValidator adapts config.ValidateProfile to an entry
+func profileValidator(s string) error {
+	return config.ValidateProfile(strings.TrimSpace(s))
+}
+
+// languageSelector builds the language picker. The choice is saved with
+// the other settings and applies from the next start, as the window is
+// built in one language.
+func (g *GUI) languageSelector() fyne.CanvasObject {
+	options := []string{i18n.T("System default")}
+	for _, lang := range i18n.Languages {
+		options = append(options, i18n.Name(lang))
+	}
+
+	sel := widget.NewSelect(options, nil)
+	if i := slices.Index(i18n.Languages, g.config.Language); i >= 0 {
+		sel.SetSelectedIndex(i + 1)
+	} else {
+		sel.SetSelectedIndex(0)
+	}
+
+	hint := widget.NewLabel("")
+	hint.Hide()
+	sel.OnChanged = func(string) {
+		g.config.Language = ""
+		if i := sel.SelectedIndex(); i > 0 {
+			g.config.Language = i18n.Languages[i-1]
+		}
+		hint.SetText(i18n.T("Save and restart FilterDNS to switch the language"))
+		hint.Show()
+	}
+
+	return container.NewVBox(
+		container.NewBorder(nil, nil, widget.NewLabel(i18n.T("Language")), nil, sel),
+		hint,
+	)
+}
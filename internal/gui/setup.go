@@ -0,0 +1,163 @@
+package gui
+
+import (
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+)
+
+const (
+	// setupDoneKey is the app preference set once the user finished or
+	// skipped the setup screen, so it is not shown on every start
+	setupDoneKey = "setupDone"
+
+	// setupPoll is how often the setup screen checks its steps while shown
+	setupPoll = 2 * time.Second
+)
+
+// setup is the guided first-run screen: install the service, connect a
+// profile, turn filtering on
+type setup struct {
+	g       *GUI
+	screen  *fyne.Container
+	visible atomic.Bool
+
+	service, profile, filtering *setupStep
+	doneBtn                     *widget.Button
+}
+
+// setupStep is one step of the setup screen
+type setupStep struct {
+	icon   *widget.Icon
+	button *widget.Button
+}
+
+func newSetupStep(text, action string, tapped func()) (*setupStep, fyne.CanvasObject) {
+	step := &setupStep{
+		icon:   widget.NewIcon(theme.RadioButtonIcon()),
+		button: widget.NewButton(action, tapped),
+	}
+	label := widget.NewLabel(text)
+	label.Wrapping = fyne.TextWrapWord
+	return step, container.NewBorder(nil, nil, step.icon, step.button, label)
+}
+
+// set shows whether the step is done, and whether it can be started
+func (s *setupStep) set(done, ready bool) {
+	if done {
+		s.icon.SetResource(theme.ConfirmIcon())
+		s.button.Hide()
+		return
+	}
+	s.icon.SetResource(theme.RadioButtonIcon())
+	s.button.Show()
+	if ready {
+		s.button.Enable()
+	} else {
+		s.button.Disable()
+	}
+}
+
+// setupContent builds the setup screen, hidden until showSetup
+func (g *GUI) setupContent() fyne.CanvasObject {
+	s := &setup{g: g}
+	g.setup = s
+
+	title := widget.NewLabel(i18n.T("Welcome to FilterDNS"))
+	title.TextStyle = fyne.TextStyle{Bold: true}
+	intro := widget.NewLabel(i18n.T("Three steps and every DNS lookup on this computer goes through your FilterDNS profile."))
+	intro.Wrapping = fyne.TextWrapWord
+
+	var serviceRow, profileRow, filteringRow fyne.CanvasObject
+	s.service, serviceRow = newSetupStep(i18n.T("Install the FilterDNS service, which needs administrator rights"),
+		i18n.T("Install"), func() { g.installService(s.service.button) })
+	s.profile, profileRow = newSetupStep(i18n.T("Connect this computer to your FilterDNS profile"),
+		i18n.T("Connect"), g.startOnboarding)
+	s.filtering, filteringRow = newSetupStep(i18n.T("Turn on filtering"), i18n.T("Enable"), g.enable)
+
+	s.doneBtn = widget.NewButton(i18n.T("Done"), g.finishSetup)
+	s.doneBtn.Importance = widget.HighImportance
+	s.doneBtn.Disable()
+
+	s.screen = container.NewVBox(
+		title,
+		intro,
+		widget.NewSeparator(),
+		serviceRow,
+		profileRow,
+		filteringRow,
+		layout.NewSpacer(),
+		container.NewHBox(
+			widget.NewButton(i18n.T("Skip to Settings"), g.finishSetup),
+			layout.NewSpacer(),
+			s.doneBtn,
+		),
+	)
+	s.screen.Hide()
+	return container.NewPadded(s.screen)
+}
+
+// needsSetup reports whether to open with the setup screen: on first
+// start, as long as the daemon or the profile is missing
+func (g *GUI) needsSetup() bool {
+	if g.app.Preferences().Bool(setupDoneKey) {
+		return false
+	}
+	return g.config.Profile == "" || !g.client.IsRunning()
+}
+
+// showSetup replaces the main window content with the setup screen
+func (g *GUI) showSetup() {
+	g.main.Hide()
+	g.setup.screen.Show()
+	if !g.setup.visible.Swap(true) {
+		go g.setup.follow()
+	}
+}
+
+// finishSetup leaves the setup screen for good
+func (g *GUI) finishSetup() {
+	g.app.Preferences().SetBool(setupDoneKey, true)
+	g.setup.visible.Store(false)
+	g.setup.screen.Hide()
+	g.main.Show()
+	go g.refreshStatus()
+}
+
+// follow updates the steps while the screen is shown
+func (s *setup) follow() {
+	for s.visible.Load() {
+		s.refresh()
+		time.Sleep(setupPoll)
+	}
+}
+
+// refresh checks which steps are done
+func (s *setup) refresh() {
+	running := s.g.client.IsRunning()
+	filtering := false
+	if running {
+		if status, err := s.g.client.Status(); err == nil {
+			filtering = status.Running
+		}
+	}
+
+	s.g.do(func() {
+		connected := s.g.config.Profile != ""
+		s.service.set(running, true)
+		s.profile.set(connected, true)
+		s.filtering.set(filtering, running && connected)
+		if running && connected && filtering {
+			s.doneBtn.Enable()
+		} else {
+			s.doneBtn.Disable()
+		}
+	})
+}
@@ -0,0 +1,309 @@
+package gui
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/daemon"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+)
+
+// savedProfilesContent builds the Settings card for the saved profiles
+// there are to switch between, as the profile commands keep them
+func (g *GUI) savedProfilesContent() fyne.CanvasObject {
+	g.savedProfiles = container.NewVBox()
+	g.refreshSavedProfiles()
+
+	addBtn := widget.NewButton(i18n.T("Add Profile"), func() { g.showSavedProfileDialog(nil) })
+	return widget.NewCard(i18n.T("Saved Profiles"),
+		i18n.T("Switch between profiles, e.g. for home and work"),
+		container.NewVBox(g.savedProfiles, container.NewHBox(addBtn)))
+}
+
+// refreshSavedProfiles shows the saved profiles, marking the active one
+// (must be called on the UI thread)
+func (g *GUI) refreshSavedProfiles() {
+	if g.savedProfiles == nil {
+		return
+	}
+
+	g.savedProfiles.RemoveAll()
+	if len(g.config.Profiles) == 0 {
+		g.savedProfiles.Add(widget.NewLabel(i18n.T("No saved profiles yet")))
+		return
+	}
+
+	for _, saved := range g.config.Profiles {
+		saved := saved // capture
+		name := widget.NewLabel(saved.Name)
+		state := widget.NewLabel(g.profileState(saved.Name).Describe())
+		state.Importance = widget.LowImportance
+		useBtn := widget.NewButton(i18n.T("Use"), func() { g.switchProfile(saved.Name) })
+		if saved.Name == g.config.ActiveProfile {
+			name.TextStyle = fyne.TextStyle{Bold: true}
+			useBtn.Disable()
+		} else if g.isLocked("profile") || g.isLocked("serverUrl") {
+			useBtn.Disable()
+		}
+		g.savedProfiles.Add(container.NewHBox(
+			name,
+			widget.NewLabel(i18n.T("%s on %s", saved.Profile, saved.ServerURL)),
+			state,
+			layout.NewSpacer(),
+			useBtn,
+			widget.NewButtonWithIcon(i18n.T("Edit"), theme.DocumentCreateIcon(), func() { g.showSavedProfileDialog(&saved) }),
+			widget.NewButtonWithIcon(i18n.T("Remove"), theme.DeleteIcon(), func() { g.removeSavedProfile(saved.Name) }),
+		))
+	}
+}
+
+// switchMenuItem is the tray's profile submenu, nil unless there are
+// profiles to switch between (must be called with trayMu held)
+func (g *GUI) switchMenuItem() *fyne.MenuItem {
+	if len(g.config.Profiles) < 2 {
+		return nil
+	}
+
+	var states []daemon.ProfileState
+	if g.trayStatus != nil {
+		states = g.trayStatus.Profiles
+	}
+
+	menu := fyne.NewMenu("")
+	for _, saved := range g.config.Profiles {
+		name := saved.Name
+		label := name
+		if text := findProfileState(states, name).Describe(); text != "" {
+			label = i18n.T("%s (%s)", name, text)
+		}
+		item := fyne.NewMenuItem(label, func() { g.switchProfile(name) })
+		item.Checked = name == g.config.ActiveProfile
+		menu.Items = append(menu.Items, item)
+	}
+	item := fyne.NewMenuItem(i18n.T("Switch Profile"), nil)
+	item.ChildMenu = menu
+	return item
+}
+
+// profileState is what the daemon last reported about the saved profile
+// name, if anything (must be called on the UI thread)
+func (g *GUI) profileState(name string) *daemon.ProfileState {
+	return findProfileState(g.profileStates, name)
+}
+
+// findProfileState picks the state of the saved profile name from states
+func findProfileState(states []daemon.ProfileState, name string) *daemon.ProfileState {
+	i := slices.IndexFunc(states, func(state daemon.ProfileState) bool { return state.Name == name })
+	if i < 0 {
+		return nil
+	}
+	return &states[i]
+}
+
+// showSavedProfileDialog asks for a profile to save, or for changes to
+// saved if it is set. The password goes to the keychain; left empty it
+// keeps the stored one unless asked to forget it.
+func (g *GUI) showSavedProfileDialog(saved *config.SavedProfile) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder(i18n.T("e.g. home or work"))
+	nameEntry.Validator = func(s string) error {
+		name := strings.TrimSpace(s)
+		switch {
+		case name == "":
+			return errors.New(i18n.T("enter a name"))
+		case (saved == nil || name != saved.Name) && g.config.FindProfile(name) != nil:
+			return errors.New(i18n.T("a profile named %s is saved already", name))
+		}
+		return nil
+	}
+
+	profileEntry := widget.NewEntry()
+	profileEntry.SetPlaceHolder("my-profile-name")
+	profileEntry.Validator = profileValidator
+
+	serverEntry := widget.NewEntry()
+	serverEntry.SetPlaceHolder("https://filterdns.example.com")
+	serverEntry.Validator = serverURLValidator
+
+	passwordEntry := widget.NewPasswordEntry()
+	forgetCheck := widget.NewCheck(i18n.T("Forget the saved password"), nil)
+
+	title, confirm := i18n.T("Add Profile"), i18n.T("Add")
+	if saved != nil {
+		title, confirm = i18n.T("Edit Profile"), i18n.T("Save")
+		nameEntry.SetText(saved.Name)
+		profileEntry.SetText(saved.Profile)
+		serverEntry.SetText(saved.ServerURL)
+		passwordEntry.SetPlaceHolder(i18n.T("Unchanged"))
+	} else {
+		profileEntry.SetText(g.config.Profile)
+		serverEntry.SetText(g.config.ServerURL)
+		passwordEntry.SetPlaceHolder(i18n.T("Password (if protected)"))
+		forgetCheck.Hide()
+	}
+
+	items := []*widget.FormItem{
+		widget.NewFormItem(i18n.T("Name"), nameEntry),
+		widget.NewFormItem(i18n.T("Profile Name"), profileEntry),
+		widget.NewFormItem(i18n.T("Server URL"), serverEntry),
+		widget.NewFormItem(i18n.T("Password"), passwordEntry),
+		widget.NewFormItem("", forgetCheck),
+	}
+	d := dialog.NewForm(title, confirm, i18n.T("Cancel"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		p := config.SavedProfile{
+			Name:      strings.TrimSpace(nameEntry.Text),
+			Profile:   strings.TrimSpace(profileEntry.Text),
+			ServerURL: strings.TrimSpace(serverEntry.Text),
+		}
+		old := ""
+		if saved != nil {
+			old = saved.Name
+		}
+		g.saveProfile(old, p, passwordEntry.Text, forgetCheck.Checked)
+	}, g.window)
+	d.Resize(fyne.NewSize(450, 0))
+	d.Show()
+}
+
+// saveProfile stores p in place of the saved profile named old, or as a
+// new one if old is empty, and its password in the keychain
+func (g *GUI) saveProfile(old string, p config.SavedProfile, password string, forget bool) {
+	go func() {
+		switch {
+		case password != "":
+			if err := g.setPassword(p.Profile, password); err != nil {
+				g.showError(i18n.T("Failed to save password: %v", err))
+				return
+			}
+		case forget:
+			if err := g.setPassword(p.Profile, ""); err != nil {
+				log.Printf("Failed to delete password: %v", err)
+			}
+		}
+
+		g.changeProfiles(func(cfg *config.Config) {
+			i := slices.IndexFunc(cfg.Profiles, func(existing config.SavedProfile) bool { return existing.Name == old })
+			if old == "" || i < 0 {
+				cfg.Profiles = append(cfg.Profiles, p)
+				return
+			}
+			cfg.Profiles[i] = p
+			if cfg.ActiveProfile == old {
+				cfg.ActiveProfile = p.Name
+			}
+		}, i18n.T("Saved profile %s (%s on %s)", p.Name, p.Profile, p.ServerURL))
+	}()
+}
+
+// removeSavedProfile forgets a saved profile after asking. The settings in
+// use stay, just no longer under its name.
+func (g *GUI) removeSavedProfile(name string) {
+	dialog.ShowConfirm(i18n.T("Remove Profile"), i18n.T("Remove the saved profile %s?", name), func(ok bool) {
+		if !ok {
+			return
+		}
+		go g.changeProfiles(func(cfg *config.Config) {
+			cfg.Profiles = slices.DeleteFunc(cfg.Profiles, func(p config.SavedProfile) bool { return p.Name == name })
+			if cfg.ActiveProfile == name {
+				cfg.ActiveProfile = ""
+			}
+		}, i18n.T("Removed profile: %s", name))
+	}, g.window)
+}
+
+// changeProfiles applies change to the saved profiles, in the daemon's
+// config if it runs or else in the config file, and shows the result.
+// Call it off the UI thread.
+func (g *GUI) changeProfiles(change func(cfg *config.Config), done string) {
+	cfg, err := g.changeRules(func(cfg *config.Config) error {
+		cfg.Profiles = slices.Clone(cfg.Profiles)
+		change(cfg)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to update profiles: %v", err)
+		g.showError(i18n.T("Failed to update profiles: %v", err))
+		return
+	}
+
+	// Keep the settings being edited from undoing the change on save
+	g.do(func() {
+		g.config.Profiles = cfg.Profiles
+		g.config.ActiveProfile = cfg.ActiveProfile
+		g.refreshSavedProfiles()
+		g.updateTray()
+	})
+	g.showInfo(done)
+}
+
+// switchProfile makes a saved profile the active one, through the daemon
+// if it runs so filtering follows at once, or else in the config file
+func (g *GUI) switchProfile(name string) {
+	go func() {
+		var saved config.SavedProfile
+		if g.client.IsRunning() {
+			status, err := g.client.SwitchProfile(name)
+			if err != nil {
+				log.Printf("Failed to switch profile: %v", err)
+				g.showError(i18n.T("Failed to switch profile: %v", err))
+				return
+			}
+			saved = config.SavedProfile{Name: name, Profile: status.Profile, ServerURL: status.ServerURL}
+		} else if g.client.Remote() {
+			g.showError(i18n.T("Failed to switch profile: %v", errRemoteDown()))
+			return
+		} else {
+			cfg, err := config.Load()
+			if err == nil {
+				if p := cfg.FindProfile(name); p != nil {
+					saved = *p
+					cfg.UseProfile(p)
+					err = config.Save(cfg)
+				} else {
+					err = errors.New(i18n.T("unknown profile: %s", name))
+				}
+			}
+			if err != nil {
+				log.Printf("Failed to switch profile: %v", err)
+				g.showError(i18n.T("Failed to switch profile: %v", err))
+				return
+			}
+		}
+		// A remote daemon keeps its passwords to itself
+		var password string
+		if !g.client.Remote() {
+			password, _ = config.GetPassword(saved.Profile)
+		}
+
+		g.do(func() {
+			g.config.UseProfile(&saved)
+			if g.profileEntry != nil {
+				g.profileEntry.SetText(saved.Profile)
+			}
+			if g.serverEntry != nil {
+				g.serverEntry.SetText(saved.ServerURL)
+				g.refreshProfiles()
+			}
+			if g.passwordEntry != nil {
+				g.passwordEntry.SetText(password)
+			}
+			g.refreshSavedProfiles()
+			g.refreshRuleList()
+			g.updateTray()
+		})
+		g.showInfo(i18n.T("Switched to profile %s (%s on %s)", saved.Name, saved.Profile, saved.ServerURL))
+		g.refreshStatus()
+	}()
+}
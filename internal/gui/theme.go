@@ -0,0 +1,115 @@
+package gui
+
+import (
+	"image/color"
+	"runtime"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+)
+
+// themeKey holds the appearance picked in Settings, kept in the app
+// preferences like the notification settings
+const themeKey = "theme"
+
+// Appearances to pick from
+const (
+	themeSystem = "" // Follow the system's dark mode
+	themeLight  = "light"
+	themeDark   = "dark"
+)
+
+// Colours of the FilterDNS green, as in the app and tray icons
+var (
+	brandColor     = color.NRGBA{R: 0x10, G: 0xb9, B: 0x81, A: 0xff}
+	brandDarkColor = color.NRGBA{R: 0x04, G: 0x78, B: 0x57, A: 0xff} // Links on light backgrounds
+)
+
+// appTheme is Fyne's default theme in FilterDNS colours and a little more
+// compact, light or dark as picked or else as the system is
+type appTheme struct {
+	appearance string
+}
+
+var _ fyne.Theme = appTheme{}
+
+func (t appTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	switch t.appearance {
+	case themeLight:
+		variant = theme.VariantLight
+	case themeDark:
+		variant = theme.VariantDark
+	}
+
+	switch name {
+	case theme.ColorNamePrimary, theme.ColorNameSuccess:
+		return brandColor
+	case theme.ColorNameHyperlink:
+		if variant == theme.VariantLight {
+			return brandDarkColor
+		}
+		return brandColor
+	case theme.ColorNameFocus:
+		return color.NRGBA{R: brandColor.R, G: brandColor.G, B: brandColor.B, A: 0x7f}
+	case theme.ColorNameSelection:
+		return color.NRGBA{R: brandColor.R, G: brandColor.G, B: brandColor.B, A: 0x3f}
+	}
+	return theme.DefaultTheme().Color(name, variant)
+}
+
+func (t appTheme) Font(style fyne.TextStyle) fyne.Resource {
+	return theme.DefaultTheme().Font(style)
+}
+
+func (t appTheme) Icon(name fyne.ThemeIconName) fyne.Resource {
+	return theme.DefaultTheme().Icon(name)
+}
+
+func (t appTheme) Size(name fyne.ThemeSizeName) float32 {
+	switch name {
+	case theme.SizeNameInnerPadding:
+		return 6
+	case theme.SizeNameText:
+		return 13
+	case theme.SizeNameHeadingText:
+		return 20
+	case theme.SizeNameSubHeadingText:
+		return 16
+	}
+	return theme.DefaultTheme().Size(name)
+}
+
+// applyTheme switches the app to the appearance in the preferences
+func (g *GUI) applyTheme() {
+	g.app.Settings().SetTheme(appTheme{appearance: g.app.Preferences().String(themeKey)})
+}
+
+// darkMenuBar reports whether the tray icon sits on a dark menu bar, which
+// on macOS comes with the system's dark mode. The window's appearance
+// setting does not change the menu bar.
+func (g *GUI) darkMenuBar() bool {
+	return runtime.GOOS == "darwin" && g.app.Settings().ThemeVariant() == theme.VariantDark
+}
+
+// themeSelector builds the appearance picker, which applies at once
+func (g *GUI) themeSelector() fyne.CanvasObject {
+	appearances := []string{themeSystem, themeLight, themeDark}
+	options := []string{i18n.T("Match the system"), i18n.T("Light"), i18n.T("Dark")}
+
+	sel := widget.NewSelect(options, nil)
+	sel.SetSelectedIndex(0)
+	for i, appearance := range appearances {
+		if appearance == g.app.Preferences().String(themeKey) {
+			sel.SetSelectedIndex(i)
+		}
+	}
+	sel.OnChanged = func(string) {
+		g.app.Preferences().SetString(themeKey, appearances[sel.SelectedIndex()])
+		g.applyTheme()
+	}
+
+	return container.NewBorder(nil, nil, widget.NewLabel(i18n.T("Appearance")), nil, sel)
+}
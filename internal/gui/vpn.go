@@ -0,0 +1,98 @@
+package gui
+
+import (
+	"log"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/daemon"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/system"
+)
+
+// detectVPNs looks for VPN and Tailscale interfaces and offers forwarders
+// for the domains their DNS servers resolve, so the user need not know
+// the suffixes and addresses
+func (g *GUI) detectVPNs(btn *widget.Button) {
+	g.busy(btn, i18n.T("Detecting..."), func() {
+		vpns, err := system.DetectVPNs()
+		if err != nil {
+			log.Printf("VPN detection failed: %v", err)
+			g.showError(i18n.T("Could not look for VPNs: %v", err))
+			return
+		}
+		if len(vpns) == 0 {
+			g.showInfo(i18n.T("No active VPN found"))
+			return
+		}
+		g.do(func() { g.showVPNForwarders(vpns) })
+	})
+}
+
+// showVPNForwarders lists the VPNs found with a checkbox for each
+// suggested forwarder, and adds the ones left checked (must be called on
+// the UI thread)
+func (g *GUI) showVPNForwarders(vpns []system.VPN) {
+	type suggestion struct {
+		check     *widget.Check
+		forwarder config.Forwarder
+	}
+	var suggestions []suggestion
+
+	list := container.NewVBox()
+	for _, vpn := range vpns {
+		title := widget.NewLabel(i18n.T("%s on %s", vpn.Kind, vpn.Interface))
+		title.TextStyle = fyne.TextStyle{Bold: true}
+		list.Add(title)
+
+		forwarders := daemon.VPNForwarders(vpn, g.config.Forwarders)
+		switch {
+		case len(forwarders) > 0:
+			for _, f := range forwarders {
+				check := widget.NewCheck(i18n.T("%s → %s", f.Domain, f.Server), nil)
+				check.SetChecked(true)
+				suggestions = append(suggestions, suggestion{check, f})
+				list.Add(check)
+			}
+		case len(vpn.Domains) > 0 && len(vpn.Servers) > 0:
+			list.Add(widget.NewLabel(i18n.T("Already forwarded: %s", strings.Join(vpn.Domains, ", "))))
+		default:
+			list.Add(widget.NewLabel(i18n.T("No DNS domain found; add its forwarder by hand if it has one")))
+		}
+	}
+
+	if len(suggestions) == 0 {
+		dialog.ShowCustom(i18n.T("Detected VPNs"), i18n.T("Close"), list, g.window)
+		return
+	}
+	dialog.ShowCustomConfirm(i18n.T("Detected VPNs"), i18n.T("Add"), i18n.T("Cancel"), list, func(ok bool) {
+		if !ok {
+			return
+		}
+		var add []config.Forwarder
+		var domains []string
+		for _, s := range suggestions {
+			if s.check.Checked {
+				add = append(add, s.forwarder)
+				domains = append(domains, s.forwarder.Domain)
+			}
+		}
+		if len(add) == 0 {
+			return
+		}
+		g.changeForwarders(func(forwarders []config.Forwarder) []config.Forwarder {
+			for _, f := range add {
+				forwarders = slices.DeleteFunc(forwarders, func(existing config.Forwarder) bool {
+					return existing.Domain == f.Domain
+				})
+				forwarders = append(forwarders, f)
+			}
+			return forwarders
+		}, i18n.T("Added forwarders for %s", strings.Join(domains, ", ")))
+	}, g.window)
+}
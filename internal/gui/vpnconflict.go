@@ -0,0 +1,121 @@
+package gui
+
+import (
+	"log"
+	"reflect"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/daemon"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+)
+
+// vpnConflictContent builds the banners shown for VPNs whose DNS does
+// not get along with the proxy, which otherwise fight it unnoticed
+func (g *GUI) vpnConflictContent() fyne.CanvasObject {
+	g.vpnConflictBox = container.NewVBox()
+	g.vpnConflictBox.Hide()
+	return g.vpnConflictBox
+}
+
+// updateVPNConflicts shows a banner for each conflict the daemon found,
+// with buttons for what settles it (must be called on the UI thread)
+func (g *GUI) updateVPNConflicts(conflicts []daemon.VPNConflict) {
+	if g.vpnConflictBox == nil || reflect.DeepEqual(conflicts, g.vpnConflicts) {
+		return
+	}
+	g.vpnConflicts = conflicts
+
+	g.vpnConflictBox.RemoveAll()
+	for _, c := range conflicts {
+		g.vpnConflictBox.Add(g.vpnConflictBanner(c))
+	}
+	if len(conflicts) > 0 {
+		g.vpnConflictBox.Show()
+	} else {
+		g.vpnConflictBox.Hide()
+	}
+}
+
+// vpnConflictBanner explains one conflict and offers to add the missing
+// forwarders or to ignore the VPN's interface
+func (g *GUI) vpnConflictBanner(c daemon.VPNConflict) fyne.CanvasObject {
+	var text string
+	switch c.Kind {
+	case daemon.VPNMissingForwarders:
+		var domains []string
+		for _, f := range c.Forwarders {
+			domains = append(domains, f.Domain)
+		}
+		text = i18n.T("%s on %s: its domains %s are sent to the profile, which cannot resolve them",
+			c.VPN, c.Interface, strings.Join(domains, ", "))
+	case daemon.VPNTakeover:
+		text = i18n.T("%s on %s took over system DNS, so lookups went past the filter. It was pointed back at the proxy.",
+			c.VPN, c.Interface) + "\n" + i18n.T(daemon.VPNRemedy(c.VPN), c.Interface)
+	case daemon.VPNStalemate:
+		text = i18n.T("%s on %s keeps taking over system DNS, so it is left alone and lookups go past the filter.",
+			c.VPN, c.Interface) + "\n" + i18n.T(daemon.VPNRemedy(c.VPN), c.Interface)
+	}
+	label := widget.NewLabel(text)
+	label.TextStyle = fyne.TextStyle{Bold: true}
+	label.Importance = widget.WarningImportance
+	label.Wrapping = fyne.TextWrapWord
+
+	buttons := container.NewVBox(layout.NewSpacer())
+	if len(c.Forwarders) > 0 && !g.isLocked("forwarders") {
+		btn := widget.NewButton(i18n.T("Add Forwarders"), func() { g.addConflictForwarders(c.Forwarders) })
+		btn.Importance = widget.WarningImportance
+		buttons.Add(btn)
+	}
+	if c.Kind != daemon.VPNMissingForwarders && !g.isLocked("ignoredInterfaces") {
+		buttons.Add(widget.NewButton(i18n.T("Ignore %s", c.Interface), func() { g.ignoreInterface(c.Interface) }))
+	}
+	buttons.Add(layout.NewSpacer())
+
+	return container.NewBorder(nil, nil, widget.NewIcon(theme.WarningIcon()), buttons, label)
+}
+
+// addConflictForwarders adds the forwarders a conflict suggests, as the
+// Split DNS tab's VPN detection would
+func (g *GUI) addConflictForwarders(add []config.Forwarder) {
+	var domains []string
+	for _, f := range add {
+		domains = append(domains, f.Domain)
+	}
+	g.changeForwarders(func(forwarders []config.Forwarder) []config.Forwarder {
+		for _, f := range add {
+			if !slices.ContainsFunc(forwarders, func(existing config.Forwarder) bool { return existing.Domain == f.Domain }) {
+				forwarders = append(forwarders, f)
+			}
+		}
+		return forwarders
+	}, i18n.T("Added forwarders for %s", strings.Join(domains, ", ")))
+}
+
+// ignoreInterface leaves an interface's DNS to the VPN that owns it,
+// which settles the conflict at the cost of not filtering its lookups
+func (g *GUI) ignoreInterface(iface string) {
+	go func() {
+		cfg, err := g.client.GetConfig()
+		if err == nil {
+			if !slices.Contains(cfg.IgnoredInterfaces, iface) {
+				cfg.IgnoredInterfaces = append(cfg.IgnoredInterfaces, iface)
+			}
+			err = g.client.SetConfig(cfg)
+		}
+		if err != nil {
+			log.Printf("Failed to ignore interface %s: %v", iface, err)
+			g.showError(i18n.T("Could not ignore %s: %v", iface, err))
+			return
+		}
+
+		g.do(func() { g.config.IgnoredInterfaces = cfg.IgnoredInterfaces })
+		g.showInfo(i18n.T("%s is ignored now and keeps the VPN's DNS", iface))
+	}()
+}
@@ -0,0 +1,64 @@
+package gui
+
+import (
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/i18n"
+)
+
+// closeHintKey records in the app preferences that the user was told the
+// app keeps running after closing its window
+const closeHintKey = "closeHintShown"
+
+// hasTray reports whether the app got a system tray to bring the window
+// back from
+func (g *GUI) hasTray() bool {
+	g.trayMu.Lock()
+	defer g.trayMu.Unlock()
+	return g.desk != nil
+}
+
+// startMinimized reports whether to start in the tray without showing the
+// window. The setup screen and systems without a tray always get it.
+func (g *GUI) startMinimized() bool {
+	return g.config.StartMinimized && g.hasTray() && !g.needsSetup()
+}
+
+// closeWindow handles the window's close button: it hides the window in
+// the tray, telling the user so the first time, or quits if asked to or
+// if there is no tray to get the window back from
+func (g *GUI) closeWindow() {
+	if g.config.CloseQuits || !g.hasTray() {
+		log.Println("Window closed, quitting")
+		g.app.Quit()
+		return
+	}
+
+	log.Println("Window hidden (still running in tray)")
+	g.window.Hide()
+
+	prefs := g.app.Preferences()
+	if !prefs.Bool(closeHintKey) {
+		prefs.SetBool(closeHintKey, true)
+		sendNotification("FilterDNS", i18n.T("FilterDNS keeps running in the system tray; quit it from the tray menu"))
+	}
+}
+
+// windowChecks builds the window behaviour settings, which take effect at
+// once and are saved with the other settings
+func (g *GUI) windowChecks() fyne.CanvasObject {
+	check := func(label string, field func(cfg *config.Config) *bool) *widget.Check {
+		c := widget.NewCheck(label, func(on bool) { *field(g.config) = on })
+		c.Checked = *field(g.config)
+		return c
+	}
+	return container.NewVBox(
+		check(i18n.T("Start minimized to the tray"), func(cfg *config.Config) *bool { return &cfg.StartMinimized }),
+		check(i18n.T("Closing the window quits FilterDNS"), func(cfg *config.Config) *bool { return &cfg.CloseQuits }),
+		check(i18n.T("Show the window when filtering turns on, off or pauses"), func(cfg *config.Config) *bool { return &cfg.ShowOnStatusChange }),
+	)
+}
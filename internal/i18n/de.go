@@ -0,0 +1,726 @@
+package i18n
+
+// german translates into German, keyed by the English message
+var german = map[string]string{
+	"FilterDNS desktop client":                                       "FilterDNS-Desktop-Client",
+	"Start DNS filtering (via daemon)":                               "DNS-Filterung starten (über den Dienst)",
+	"Daemon not running. Start with: sudo systemctl start filterdns": "Dienst läuft nicht. Starten mit: sudo systemctl start filterdns",
+	"Error: %v":                                     "Fehler: %v",
+	"DNS filtering enabled for profile: %s":         "DNS-Filterung eingeschaltet für Profil: %s",
+	"Stop DNS filtering (via daemon)":               "DNS-Filterung beenden (über den Dienst)",
+	"Daemon not running.":                           "Dienst läuft nicht.",
+	"DNS filtering disabled.":                       "DNS-Filterung ausgeschaltet.",
+	"Wait until the change is verifiably in effect": "Warten, bis die Änderung nachweislich wirkt",
+	"How long --wait waits":                         "Wie lange --wait wartet",
+	"Print nothing on success":                      "Bei Erfolg nichts ausgeben",
+	"Pause DNS filtering for a while (e.g. 'pause 30m', 'pause until 17:00')":    "DNS-Filterung eine Weile pausieren (z. B. 'pause 30m', 'pause until 17:00')",
+	"DNS filtering paused until %s (%s left).":                                   "DNS-Filterung pausiert bis %s (noch %s).",
+	"DNS filtering paused.":                                                      "DNS-Filterung pausiert.",
+	"End a pause and turn DNS filtering back on":                                 "Pause beenden und DNS-Filterung wieder einschalten",
+	"DNS filtering resumed for profile: %s":                                      "DNS-Filterung fortgesetzt für Profil: %s",
+	"Show current status":                                                        "Aktuellen Status anzeigen",
+	"Profile:    %s":                                                             "Profil:     %s",
+	"Server:     %s":                                                             "Server:     %s",
+	"Daemon:     not running":                                                    "Dienst:     läuft nicht",
+	"Daemon:     error (%v)":                                                     "Dienst:     Fehler (%v)",
+	"Filtering:  enabled (%d queries, %d blocked)":                               "Filterung:  an (%d Anfragen, %d blockiert)",
+	"Filtering:  paused until %s (%s left)":                                      "Filterung:  pausiert bis %s (noch %s)",
+	"Filtering:  disabled":                                                       "Filterung:  aus",
+	"Stats:      today %d queries (%d blocked), 7 days %d (%d blocked)":          "Statistik:  heute %d Anfragen (%d blockiert), 7 Tage %d (%d blockiert)",
+	"Search:     %d search-domain expansions answered locally":                   "Suche:      %d Suchdomain-Erweiterungen lokal beantwortet",
+	"Integrity:  %d checked, %d mismatches":                                      "Integrität: %d geprüft, %d Abweichungen",
+	"            last: %s %s served %v, verified %v (%s)":                        "            zuletzt: %s %s lieferte %v, geprüft %v (%s)",
+	"Health:     ok (checked %s)":                                                "Zustand:    ok (geprüft %s)",
+	"Health:     degraded (proxy answering: %s, system DNS set: %s, %d repairs)": "Zustand:    beeinträchtigt (Proxy antwortet: %s, System-DNS gesetzt: %s, %d Reparaturen)",
+	"            last: %s (%s)":                                                  "            zuletzt: %s (%s)",
+	"Sync:       failed (%s)":                                                    "Abgleich:   fehlgeschlagen (%s)",
+	"Sync:       filtering active on server (synced %s)":                         "Abgleich:   Filterung auf dem Server aktiv (abgeglichen %s)",
+	"Sync:       paused on server until %s":                                      "Abgleich:   auf dem Server pausiert bis %s",
+	"Sync:       paused on server":                                               "Abgleich:   auf dem Server pausiert",
+	"Network:    udp/53 %s, tcp/853 %s, tcp/443 %s, udp/443 %s (checked %s)":     "Netzwerk:   udp/53 %s, tcp/853 %s, tcp/443 %s, udp/443 %s (geprüft %s)",
+	"Forwarders:": "Weiterleitungen:",
+	"Reload configuration in the running daemon":                                     "Konfiguration im laufenden Dienst neu laden",
+	"Configuration reloaded.":                                                        "Konfiguration neu geladen.",
+	"Check which upstream transports this network allows":                            "Prüfen, welche Verbindungen zum Server dieses Netzwerk zulässt",
+	"Probing %s...\n":                                                                "Prüfe %s...\n",
+	"  udp/53  (plain DNS)       %s":                                                 "  udp/53  (einfaches DNS)   %s",
+	"  tcp/853 (DNS-over-TLS)    %s":                                                 "  tcp/853 (DNS-over-TLS)    %s",
+	"  tcp/443 (DNS-over-HTTPS)  %s":                                                 "  tcp/443 (DNS-over-HTTPS)  %s",
+	"  udp/443 (DNS-over-HTTP/3) %s":                                                 "  udp/443 (DNS-over-HTTP/3) %s",
+	"No upstream transport works from this network.":                                 "Aus diesem Netzwerk funktioniert keine Verbindung zum Server.",
+	"Suggested transport: %s":                                                        "Empfohlene Verbindung: %s",
+	"Warning: DNS-over-HTTPS is blocked; filtering will not work on this network.":   "Warnung: DNS-over-HTTPS ist gesperrt; in diesem Netzwerk funktioniert die Filterung nicht.",
+	"Server features: unknown (%v)":                                                  "Serverfunktionen: unbekannt (%v)",
+	"Server features (%s): sync %s, onboarding %s, EDE %s, push %s, leak test %s":    "Serverfunktionen (%s): Abgleich %s, Einrichtung %s, EDE %s, Push %s, Lecktest %s",
+	"Check that DNS lookups reach FilterDNS and not another resolver":                "Prüfen, dass DNS-Anfragen FilterDNS erreichen und keinen anderen Resolver",
+	"No profile configured.":                                                         "Kein Profil eingerichtet.",
+	"Testing lookups against %s...":                                                  "Teste Anfragen gegen %s...",
+	"Print the result as JSON (same as --output json)":                               "Ergebnis als JSON ausgeben (wie --output json)",
+	"Generate load against the local proxy and report performance":                   "Last auf den lokalen Proxy erzeugen und die Leistung melden",
+	"Error loading domains: %v":                                                      "Fehler beim Laden der Domains: %v",
+	"Sending %d qps to %s for %s...":                                                 "Sende %d Anfragen/s an %s für %s...",
+	"Queries:    %d sent, %d ok, %d failed, %d dropped (%.0f qps achieved)":          "Anfragen:   %d gesendet, %d ok, %d fehlgeschlagen, %d verworfen (%.0f Anfragen/s erreicht)",
+	"Error rate: %.2f%%":                                                             "Fehlerrate: %.2f%%",
+	"Latency:    p50 %s, p90 %s, p99 %s, max %s":                                     "Latenz:     p50 %s, p90 %s, p99 %s, max %s",
+	"Cache:      %.1f%% hit rate (%d of %d)":                                         "Cache:      %.1f%% Trefferquote (%d von %d)",
+	"Goroutines: %d → %d":                                                            "Goroutinen: %d → %d",
+	"Memory:     %.1f MiB → %.1f MiB":                                                "Speicher:   %.1f MiB → %.1f MiB",
+	"(daemon not reachable - cache and resource stats unavailable)":                  "(Dienst nicht erreichbar – keine Cache- und Ressourcenwerte)",
+	"Target queries per second":                                                      "Angestrebte Anfragen pro Sekunde",
+	"How long to generate load":                                                      "Wie lange Last erzeugt wird",
+	"Maximum in-flight queries":                                                      "Höchstzahl gleichzeitiger Anfragen",
+	"File with one domain per line (default: built-in list)":                         "Datei mit einer Domain pro Zeile (Standard: eingebaute Liste)",
+	"Proxy address to test":                                                          "Zu testende Proxy-Adresse",
+	"Manage configuration":                                                           "Konfiguration verwalten",
+	"Set a configuration value":                                                      "Einen Konfigurationswert setzen",
+	"Error storing password: %v":                                                     "Fehler beim Speichern des Passworts: %v",
+	"Password stored securely.":                                                      "Passwort sicher gespeichert.",
+	"Set %s = %s":                                                                    "%s = %s gesetzt",
+	"Show one configuration value, or all of them":                                   "Einen oder alle Konfigurationswerte anzeigen",
+	"Error loading config: %v":                                                       "Fehler beim Laden der Konfiguration: %v",
+	"The password is kept in the system keychain and cannot be shown.":               "Das Passwort liegt im Schlüsselbund des Systems und kann nicht angezeigt werden.",
+	"Reset a configuration value to its default":                                     "Einen Konfigurationswert auf den Standard zurücksetzen",
+	"Error removing password: %v":                                                    "Fehler beim Entfernen des Passworts: %v",
+	"Password removed.":                                                              "Passwort entfernt.",
+	"Unset %s (now %s)":                                                              "%s zurückgesetzt (jetzt %s)",
+	"Print the configuration for 'config import' on another machine":                 "Konfiguration für 'config import' auf einem anderen Rechner ausgeben",
+	"Warning: the export contains passwords; keep it safe.":                          "Warnung: Der Export enthält Passwörter; sicher aufbewahren.",
+	"Include profile passwords instead of keychain references":                       "Profilpasswörter statt Verweisen auf den Schlüsselbund aufnehmen",
+	"Replace the configuration with an export ('-' reads standard input)":            "Konfiguration durch einen Export ersetzen ('-' liest die Standardeingabe)",
+	"Invalid export: %v":                                                             "Ungültiger Export: %v",
+	"Error saving config: %v":                                                        "Fehler beim Speichern der Konfiguration: %v",
+	"Warning: no password in the keychain for %s; set it with 'config set password'": "Warnung: Kein Passwort für %s im Schlüsselbund; mit 'config set password' setzen",
+	"Imported configuration for profile: %s":                                         "Konfiguration importiert für Profil: %s",
+	"Run 'filterdns-client reload' to apply it to the running daemon.":               "'filterdns-client reload' übernimmt sie in den laufenden Dienst.",
+	"Merge into the existing configuration instead of replacing it":                  "In die bestehende Konfiguration einfügen, statt sie zu ersetzen",
+	"Show current configuration":                                                     "Aktuelle Konfiguration anzeigen",
+	"Config:    %s (read-only)":                                                      "Konfig.:   %s (schreibgeschützt)",
+	"Profile:   %s":                                                                  "Profil:    %s",
+	"Server:    %s":                                                                  "Server:    %s",
+	"Autostart: %v":                                                                  "Autostart: %v",
+	"Interfaces: %s":                                                                 "Schnittstellen: %s",
+	"Ignored interfaces: %s":                                                         "Ignorierte Schnittstellen: %s",
+	"Cache size: %d":                                                                 "Cache-Größe: %d",
+	"Bootstrap DNS: %s":                                                              "Bootstrap-DNS: %s",
+	"Automatic updates: on":                                                          "Automatische Updates: an",
+	"Rules:":                                                                         "Regeln:",
+	"Manage DNS forwarders (split DNS)":                                              "DNS-Weiterleitungen verwalten (Split-DNS)",
+	"Add a forwarder (e.g., 'add ts.net 100.100.100.100')":                           "Weiterleitung hinzufügen (z. B. 'add ts.net 100.100.100.100')",
+	"Added forwarder: %s → %s":                                                       "Weiterleitung hinzugefügt: %s → %s",
+	"List all forwarders":                                                            "Alle Weiterleitungen auflisten",
+	"No forwarders configured.":                                                      "Keine Weiterleitungen eingerichtet.",
+	"Show which forwarder a domain goes to and query it directly":                    "Zeigen, an welche Weiterleitung eine Domain geht, und sie direkt abfragen",
+	"No forwarder matches %s; it is resolved through FilterDNS.":                     "Keine Weiterleitung passt zu %s; sie wird über FilterDNS aufgelöst.",
+	"Rule:    %s → %s":                                                               "Regel:   %s → %s",
+	"Query:   %s %s":                                                                 "Anfrage: %s %s",
+	"Answer:  failed (%s)":                                                           "Antwort: fehlgeschlagen (%s)",
+	"Answer:  %s, %s (%s)":                                                           "Antwort: %s, %s (%s)",
+	"Answer:  %s, no records (%s)":                                                   "Antwort: %s, keine Einträge (%s)",
+	"Try an unsaved rule forwarding to this server":                                  "Eine ungespeicherte Regel mit Weiterleitung an diesen Server ausprobieren",
+	"Pattern of the unsaved rule, e.g. '*.corp' (default: the domain)":               "Muster der ungespeicherten Regel, z. B. '*.corp' (Standard: die Domain)",
+	"Record type to query":                                                           "Abzufragender Eintragstyp",
+	"Remove a forwarder":                                                             "Weiterleitung entfernen",
+	"Forwarder not found: %s":                                                        "Weiterleitung nicht gefunden: %s",
+	"Removed forwarder: %s":                                                          "Weiterleitung entfernt: %s",
+	"Invalid domain: %s":                                                             "Ungültige Domain: %s",
+	"Rule added: %s %s (and its subdomains)":                                         "Regel hinzugefügt: %s %s (samt Subdomains)",
+	"Only change this machine, not the server profile":                               "Nur diesen Rechner ändern, nicht das Serverprofil",
+	"Manage local allow/block rules":                                                 "Lokale Erlauben-/Blockieren-Regeln verwalten",
+	"List local allow/block rules":                                                   "Lokale Erlauben-/Blockieren-Regeln auflisten",
+	"No rules. Add one with: filterdns-client allow <domain> or block <domain>":      "Keine Regeln. Hinzufügen mit: filterdns-client allow <domain> oder block <domain>",
+	"Remove a local allow/block rule":                                                "Lokale Erlauben-/Blockieren-Regel entfernen",
+	"No rule for %s":                                                                 "Keine Regel für %s",
+	"Rule removed: %s %s":                                                            "Regel entfernt: %s %s",
+	"Manage saved profiles and switch between them":                                  "Gespeicherte Profile verwalten und zwischen ihnen wechseln",
+	"List saved profiles, or with --remote those the server offers":                  "Gespeicherte Profile auflisten, mit --remote die des Servers",
+	"%s offers no profiles.":                                                         "%s bietet keine Profile an.",
+	"No saved profiles. Add one with: filterdns-client profile add <name>":           "Keine gespeicherten Profile. Hinzufügen mit: filterdns-client profile add <name>",
+	"%s %-12s %s on %s":                                                              "%s %-12s %s auf %s",
+	"List the profiles offered by the configured server":                             "Die Profile des eingerichteten Servers auflisten",
+	"Save a profile to switch to, or update a saved one":                             "Ein Profil zum Wechseln speichern oder ein gespeichertes ändern",
+	"Error reading password: %v":                                                     "Fehler beim Lesen des Passworts: %v",
+	"Saved profile %s (%s on %s)":                                                    "Profil %s gespeichert (%s auf %s)",
+	"FilterDNS profile name (default: the name)":                                     "FilterDNS-Profilname (Standard: der Name)",
+	"FilterDNS server URL (default: the current server)":                             "FilterDNS-Server-URL (Standard: der aktuelle Server)",
+	"Read the profile password from standard input":                                  "Profilpasswort von der Standardeingabe lesen",
+	"Remove a saved profile":                                                         "Gespeichertes Profil entfernen",
+	"Unknown profile: %s":                                                            "Unbekanntes Profil: %s",
+	"Removed profile: %s":                                                            "Profil entfernt: %s",
+	"Make a saved profile the active one":                                            "Ein gespeichertes Profil aktivieren",
+	"Switched to profile %s (%s on %s)":                                              "Zu Profil %s gewechselt (%s auf %s)",
+	"Install as a system service (requires root)":                                    "Als Systemdienst installieren (braucht root)",
+	"This command requires root privileges. Run with sudo.":                          "Dieser Befehl braucht root-Rechte. Mit sudo ausführen.",
+	"Install failed: %v":                                                             "Installation fehlgeschlagen: %v",
+	"Uninstall the system service (requires root)":                                   "Systemdienst deinstallieren (braucht root)",
+	"Warning: %v":                                                                    "Warnung: %v",
+	"System DNS still points at the local proxy, not uninstalling.":                  "System-DNS zeigt noch auf den lokalen Proxy, Deinstallation abgebrochen.",
+	"Fix it with: sudo filterdns-client dns-reset --force":                           "Beheben mit: sudo filterdns-client dns-reset --force",
+	"Network settings restored":                                                      "Netzwerkeinstellungen wiederhergestellt",
+	"Uninstall failed: %v":                                                           "Deinstallation fehlgeschlagen: %v",
+	"Purge incomplete: %v":                                                           "Nicht alles entfernt: %v",
+	"Configuration, passwords, statistics and backups removed":                       "Konfiguration, Passwörter, Statistik und Sicherungen entfernt",
+	"Also remove config, saved passwords, statistics, backups and logs":              "Auch Konfiguration, gespeicherte Passwörter, Statistik, Sicherungen und Logs entfernen",
+	"Install the latest signed release and restart the service":                      "Neueste signierte Version installieren und den Dienst neu starten",
+	"Update available: %s (installed: %s)":                                           "Update verfügbar: %s (installiert: %s)",
+	"Up to date: %s (latest release: %s)":                                            "Aktuell: %s (neueste Version: %s)",
+	"Development build; install release %s with --force":                             "Entwicklungsversion; Version %s mit --force installieren",
+	"Up to date: %s":                                                                 "Aktuell: %s",
+	"Update failed: %v":                                                              "Update fehlgeschlagen: %v",
+	"Updated %s from %s to %s":                                                       "%s von %s auf %s aktualisiert",
+	"Failed to restart service: %v":                                                  "Dienst konnte nicht neu gestartet werden: %v",
+	"Service restarted":                                                              "Dienst neu gestartet",
+	"Only report whether an update is available":                                     "Nur melden, ob ein Update verfügbar ist",
+	"Install the latest release on a development build":                              "Neueste Version auf einem Entwicklungsbuild installieren",
+	"Set up the client without the browser onboarding (for mass deployment)":         "Client ohne Einrichtung im Browser einrichten (für Massenverteilung)",
+	"--install requires root privileges. Run with sudo.":                             "--install braucht root-Rechte. Mit sudo ausführen.",
+	"No profile configured; pass --profile.":                                         "Kein Profil eingerichtet; --profile angeben.",
+	"No password on standard input.":                                                 "Kein Passwort auf der Standardeingabe.",
+	"Configured profile %s on %s":                                                    "Profil %s auf %s eingerichtet",
+	"Password stored in the keychain":                                                "Passwort im Schlüsselbund gespeichert",
+	"Error starting service: %v":                                                     "Fehler beim Starten des Dienstes: %v",
+	"Daemon not running; filtering starts when it does.":                             "Dienst läuft nicht; die Filterung beginnt, sobald er läuft.",
+	"Error reloading daemon: %v":                                                     "Fehler beim Neuladen des Dienstes: %v",
+	"DNS filtering is off; turn it on with: filterdns-client start":                  "DNS-Filterung ist aus; einschalten mit: filterdns-client start",
+	"FilterDNS server URL":                                                           "FilterDNS-Server-URL",
+	"FilterDNS profile name":                                                         "FilterDNS-Profilname",
+	"Turn DNS filtering on":                                                          "DNS-Filterung einschalten",
+	"Install and start the system service (requires root)":                           "Systemdienst installieren und starten (braucht root)",
+	"Run the daemon (used by system service)":                                        "Dienst ausführen (vom Systemdienst benutzt)",
+	"Drop root and run as this user after startup (Linux/macOS)":                     "Nach dem Start root-Rechte abgeben und als dieser Benutzer laufen (Linux/macOS)",
+	"Serve pprof on this localhost address, e.g. 127.0.0.1:6060":                     "pprof auf dieser localhost-Adresse anbieten, z. B. 127.0.0.1:6060",
+	"Show query statistics (today, 7 days, total and daily history)":                 "Anfragestatistik anzeigen (heute, 7 Tage, gesamt und Tagesverlauf)",
+	"Print statistics as JSON (same as --output json)":                               "Statistik als JSON ausgeben (wie --output json)",
+	"Keep updating every 2 seconds":                                                  "Alle 2 Sekunden aktualisieren",
+	"Show history for hour, day, week, month or year":                                "Verlauf für hour, day, week, month oder year anzeigen",
+	"Show recent DNS queries":                                                        "Letzte DNS-Anfragen anzeigen",
+	"Invalid --since: %v":                                                            "Ungültiges --since: %v",
+	"Keep printing new queries":                                                      "Neue Anfragen fortlaufend ausgeben",
+	"Only show blocked queries":                                                      "Nur blockierte Anfragen anzeigen",
+	"Only show domains containing this text":                                         "Nur Domains mit diesem Text anzeigen",
+	"Only show queries since a duration ago or a time":                               "Nur Anfragen seit einer Dauer oder Uhrzeit anzeigen",
+	"Number of recent queries to show (0 for all kept)":                              "Anzahl der letzten Anfragen (0 für alle gespeicherten)",
+	"Print queries as JSON lines (same as --output json)":                            "Anfragen als JSON-Zeilen ausgeben (wie --output json)",
+	"Full-screen dashboard with live queries, counters and controls":                 "Vollbild-Übersicht mit laufenden Anfragen, Zählern und Steuerung",
+	"Resolve a name through the local proxy and show how it was answered":            "Einen Namen über den lokalen Proxy auflösen und zeigen, wie er beantwortet wurde",
+	"Daemon not running; the proxy only answers while filtering is on.":              "Dienst läuft nicht; der Proxy antwortet nur bei eingeschalteter Filterung.",
+	"Query:    %s %s via %s":                                                         "Anfrage:  %s %s über %s",
+	"Path:     %s":                                                                   "Weg:      %s",
+	"Status:   %s":                                                                   "Status:   %s",
+	"Reason:   %s":                                                                   "Grund:    %s",
+	"Latency:  %s":                                                                   "Latenz:   %s",
+	"Inspect or flush the DNS cache":                                                 "DNS-Cache ansehen oder leeren",
+	"Show cache size and hit rate":                                                   "Cache-Größe und Trefferquote anzeigen",
+	"Entries:  %d of %d":                                                             "Einträge: %d von %d",
+	"Memory:   %.1f KiB":                                                             "Speicher: %.1f KiB",
+	"Hit rate: %.1f%% (%d of %d queries)":                                            "Treffer:  %.1f%% (%d von %d Anfragen)",
+	"List cached answers":                                                            "Zwischengespeicherte Antworten auflisten",
+	"Nothing cached.":                                                                "Nichts zwischengespeichert.",
+	"Drop cached answers, all or those for domains containing domain":                "Zwischengespeicherte Antworten verwerfen, alle oder die für Domains mit domain",
+	"Flushed %d cached answers.":                                                     "%d zwischengespeicherte Antworten verworfen.",
+	"Print filtering state changes as JSON lines":                                    "Änderungen des Filterzustands als JSON-Zeilen ausgeben",
+	"Collect config, logs and resolver state into a tarball for support":             "Konfiguration, Logs und Resolver-Zustand für den Support in ein Archiv packen",
+	"Debug bundle written to %s":                                                     "Diagnosepaket geschrieben nach %s",
+	"Passwords are not included; server URL credentials are redacted.":               "Passwörter sind nicht enthalten; Zugangsdaten in Server-URLs sind geschwärzt.",
+	"Output file (default: filterdns-debug-<time>.tar.gz)":                           "Ausgabedatei (Standard: filterdns-debug-<zeit>.tar.gz)",
+	"Privileged DNS helper (started by the daemon)":                                  "Privilegierter DNS-Helfer (vom Dienst gestartet)",
+	"Control the system service running the daemon":                                  "Den Systemdienst steuern, der den Dienst ausführt",
+	"Show whether the service is installed, running and enabled at boot":             "Zeigen, ob der Dienst installiert ist, läuft und beim Hochfahren startet",
+	"Service: not installed (install with: sudo filterdns-client install)":           "Dienst: nicht installiert (installieren mit: sudo filterdns-client install)",
+	"Service: %s":                                    "Dienst: %s",
+	"Start at boot: %s":                              "Start beim Hochfahren: %s",
+	"Daemon: responding":                             "Dienst: antwortet",
+	"Daemon: not responding":                         "Dienst: antwortet nicht",
+	"Start the system service":                       "Systemdienst starten",
+	"Failed to start service: %v":                    "Dienst konnte nicht gestartet werden: %v",
+	"Service started":                                "Dienst gestartet",
+	"Stop the system service":                        "Systemdienst beenden",
+	"Failed to stop service: %v":                     "Dienst konnte nicht beendet werden: %v",
+	"Service stopped":                                "Dienst beendet",
+	"Restart the system service":                     "Systemdienst neu starten",
+	"Start the system service at boot":               "Systemdienst beim Hochfahren starten",
+	"Failed to enable service: %v":                   "Dienst konnte nicht aktiviert werden: %v",
+	"Service enabled":                                "Dienst aktiviert",
+	"Stop starting the system service at boot":       "Systemdienst nicht mehr beim Hochfahren starten",
+	"Failed to disable service: %v":                  "Dienst konnte nicht deaktiviert werden: %v",
+	"Service disabled (still running until stopped)": "Dienst deaktiviert (läuft weiter, bis er beendet wird)",
+	"Show the service log (journalctl on Linux, the log file on macOS)": "Log des Dienstes anzeigen (journalctl unter Linux, die Logdatei unter macOS)",
+	"Failed to read service log: %v":                                    "Log des Dienstes konnte nicht gelesen werden: %v",
+	"Number of lines to show":                                           "Anzahl der anzuzeigenden Zeilen",
+	"Keep printing new lines":                                           "Neue Zeilen fortlaufend ausgeben",
+	"Stop filtering and restore the original network settings":          "Filterung beenden und die ursprünglichen Netzwerkeinstellungen wiederherstellen",
+	"Network settings restored, filtering is off":                       "Netzwerkeinstellungen wiederhergestellt, die Filterung ist aus",
+	"Reset system DNS to default (used by service on stop)":             "System-DNS auf den Standard zurücksetzen (vom Dienst beim Beenden benutzt)",
+	"Failed to reset DNS: %v":                                           "DNS konnte nicht zurückgesetzt werden: %v",
+	"DNS settings restored":                                             "DNS-Einstellungen wiederhergestellt",
+	"Revert to automatic DNS if no backup exists":                       "Ohne Sicherung auf automatisches DNS zurückstellen",
+	"Inspect the system resolver":                                       "Den System-Resolver untersuchen",
+	"Show where system DNS points and what manages it":                  "Zeigen, wohin das System-DNS zeigt und was es verwaltet",
+	"Backend: %s":                     "Verwaltung: %s",
+	"Local proxy in use: %s":          "Lokaler Proxy in Gebrauch: %s",
+	"Backup of original settings: %s": "Sicherung der ursprünglichen Einstellungen: %s",
+	"Interrupted: %s started %s":      "Unterbrochen: %s, begonnen %s",
+	"System DNS points at the local proxy, but the daemon is not running.": "System-DNS zeigt auf den lokalen Proxy, aber der Dienst läuft nicht.",
+	"Restore it with: sudo filterdns-client dns-reset --force":             "Wiederherstellen mit: sudo filterdns-client dns-reset --force",
+	"Show or change whether the client starts on login":                    "Anzeigen oder ändern, ob der Client bei der Anmeldung startet",
+	"Start on login: %s":                                                      "Start bei der Anmeldung: %s",
+	"Error: failed to update login items: %v":                                 "Fehler: Anmeldeobjekte konnten nicht geändert werden: %v",
+	"Connect to FilterDNS via web-based setup":                                "Mit FilterDNS über die Einrichtung im Browser verbinden",
+	"--password and --password-stdin need --profile":                          "--password und --password-stdin brauchen --profile",
+	"Invalid link: %v":                                                        "Ungültiger Link: %v",
+	"Connecting to %s...":                                                     "Verbinde mit %s...",
+	"Onboarding failed: %v":                                                   "Einrichtung fehlgeschlagen: %v",
+	"Failed to save config: %v":                                               "Konfiguration konnte nicht gespeichert werden: %v",
+	"\nSuccess! Connected to profile: %s":                                     "\nGeschafft! Verbunden mit Profil: %s",
+	"\nTo start filtering, run: filterdns-client start":                       "\nFilterung starten mit: filterdns-client start",
+	"Or start the GUI app for system tray access.":                            "Oder die Desktop-App für das Symbol im Infobereich starten.",
+	"FilterDNS server URL (default: from config or http://localhost:8080)":    "FilterDNS-Server-URL (Standard: aus der Konfiguration oder http://localhost:8080)",
+	"Finish setup from a filterdns://onboard link":                            "Einrichtung über einen filterdns://onboard-Link abschließen",
+	"Print the setup URL and a pairing code instead of opening a browser":     "Einrichtungs-URL und Kopplungscode ausgeben, statt einen Browser zu öffnen",
+	"Skip the web flow and use this FilterDNS profile":                        "Einrichtung im Browser überspringen und dieses FilterDNS-Profil verwenden",
+	"Password of --profile (visible to other users, prefer --password-stdin)": "Passwort von --profile (für andere Benutzer sichtbar, besser --password-stdin)",
+	"Read the password of --profile from standard input":                      "Passwort von --profile von der Standardeingabe lesen",
+	"Output format: text or json":                                             "Ausgabeformat: text oder json",
+	"Invalid --output %q (text or json)":                                      "Ungültiges --output %q (text oder json)",
+	"Daemon could not restore the network: %v":                                "Dienst konnte das Netzwerk nicht wiederherstellen: %v",
+	"Today:   %s":                        "Heute:   %s",
+	"7 days:  %s":                        "7 Tage:  %s",
+	"Total:   %s (since %s)":             "Gesamt:  %s (seit %s)",
+	"Uptime:  %s":                        "Laufzeit: %s",
+	"Session: %s, cache hit rate %.1f%%": "Sitzung: %s, Cache-Trefferquote %.1f%%",
+	"Latency: %s average, %s median, %s p95 (last %d upstream queries)": "Latenz:  %s Mittel, %s Median, %s p95 (letzte %d Anfragen an den Server)",
+	"\nTop blocked:":                                    "\nAm häufigsten blockiert:",
+	"  %-16s  %8d queries  %8d blocked":                 "  %-16s  %8d Anfragen  %8d blockiert",
+	"  %s  %8d queries  %8d blocked":                    "  %s  %8d Anfragen  %8d blockiert",
+	"%d queries, %d blocked (%.1f%%)":                   "%d Anfragen, %d blockiert (%.1f%%)",
+	"Warning: profile %s on the server not updated: %v": "Warnung: Profil %s auf dem Server nicht geändert: %v",
+	"Profile %s on the server updated too":              "Profil %s auf dem Server ebenfalls geändert",
+	"Warning: failed to update login items: %v":         "Warnung: Anmeldeobjekte konnten nicht geändert werden: %v",
+	"ok":                                  "ok",
+	"blocked":                             "blockiert",
+	"yes":                                 "ja",
+	"no":                                  "nein",
+	"server URL is empty":                 "Server-URL ist leer",
+	"invalid server URL":                  "ungültige Server-URL",
+	"server URL must start with https://": "Server-URL muss mit https:// beginnen",
+	"server URL has no host":              "Server-URL enthält keinen Host",
+	"server URL must not contain a query or fragment":                 "Server-URL darf keine Abfrage und kein Fragment enthalten",
+	"profile name may only contain letters, digits, '-', '_' and '.'": "Profilname darf nur Buchstaben, Ziffern, '-', '_' und '.' enthalten",
+	"domain is empty":   "Domain ist leer",
+	"invalid domain %q": "ungültige Domain %q",
+	"DNS server must be an IP address, e.g. 192.168.1.1 or [fd00::1]:53": "DNS-Server muss eine IP-Adresse sein, z. B. 192.168.1.1 oder [fd00::1]:53",
+	"Search domains": "Domains suchen",
+	"Blocked only":   "Nur blockierte",
+	"Clear":          "Leeren",
+	"Not connected to the daemon, retrying...": "Keine Verbindung zum Dienst, neuer Versuch...",
+	"%d of %d recent queries":                  "%d von %d letzten Anfragen",
+	"allowed":                                  "erlaubt",
+	"Copy domain":                              "Domain kopieren",
+	"Failed to add a rule for %s: %v":          "Regel für %s konnte nicht angelegt werden: %v",
+	"%s is now allowed":                        "%s ist jetzt erlaubt",
+	"%s is now blocked":                        "%s ist jetzt blockiert",
+	"Checking daemon...":                       "Prüfe Dienst...",
+	"Set Up FilterDNS...":                      "FilterDNS einrichten...",
+	"Unknown":                                  "Unbekannt",
+	"Enable":                                   "Einschalten",
+	"Leak Test":                                "Lecktest",
+	"Status":                                   "Status",
+	"Test":                                     "Testen",
+	"Password (if protected)":                  "Passwort (falls geschützt)",
+	"Profile Name":                             "Profilname",
+	"Password":                                 "Passwort",
+	"Server URL":                               "Server-URL",
+	"Profile":                                  "Profil",
+	"Add Forwarder":                            "Weiterleitung hinzufügen",
+	"Add Tailscale":                            "Tailscale hinzufügen",
+	"Forward specific domains to other DNS servers": "Bestimmte Domains an andere DNS-Server weiterleiten",
+	"Split DNS":                       "Split-DNS",
+	"For VPN/Tailscale compatibility": "Für VPN und Tailscale",
+	"Start on login":                  "Bei der Anmeldung starten",
+	"Open Dashboard":                  "Dashboard öffnen",
+	"Install Background Service":      "Hintergrunddienst installieren",
+	"Settings":                        "Einstellungen",
+	"Save":                            "Speichern",
+	"Activity":                        "Aktivität",
+	"About":                           "Über",
+	"FilterDNS Client":                "FilterDNS-Client",
+	"Version":                         "Version",
+	"Platform":                        "Plattform",
+	"Keeps this device's DNS lookups going through your FilterDNS profile.": "Leitet die DNS-Anfragen dieses Geräts über Ihr FilterDNS-Profil.",
+	"Source code and issues":      "Quellcode und Fehlermeldungen",
+	"Show":                        "Anzeigen",
+	"Connect to FilterDNS":        "Mit FilterDNS verbinden",
+	"Profile: %s":                 "Profil: %s",
+	"Daemon not running":          "Dienst läuft nicht",
+	"Paused, %s left":             "Pausiert, noch %s",
+	"Resume Filtering":            "Filterung fortsetzen",
+	"Pause Filtering":             "Filterung pausieren",
+	"For 5 Minutes":               "Für 5 Minuten",
+	"For 30 Minutes":              "Für 30 Minuten",
+	"For 1 Hour":                  "Für 1 Stunde",
+	"Until Tomorrow":              "Bis morgen",
+	"Filtering on":                "Filterung an",
+	"Disable Filtering":           "Filterung ausschalten",
+	"Filtering off":               "Filterung aus",
+	"Enable Filtering":            "Filterung einschalten",
+	"Change Profile...":           "Profil wechseln...",
+	"Restore Network Settings...": "Netzwerkeinstellungen wiederherstellen...",
+	"Quit":                        "Beenden",
+	"Failed to pause: %v":         "Pausieren fehlgeschlagen: %v",
+	"Failed to resume: %v":        "Fortsetzen fehlgeschlagen: %v",
+	"%d min":                      "%d Min.",
+	"%d h %d min":                 "%d Std. %d Min.",
+	"Cannot open link: %v":        "Link kann nicht geöffnet werden: %v",
+	"Set up this device":          "Dieses Gerät einrichten",
+	"Connect this device to the FilterDNS server at %s?": "Dieses Gerät mit dem FilterDNS-Server %s verbinden?",
+	"Failed to save: %v":                          "Speichern fehlgeschlagen: %v",
+	"Connected to profile: %s":                    "Verbunden mit Profil: %s",
+	"⚠ Lost the FilterDNS service, reconnecting…": "⚠ Verbindung zum FilterDNS-Dienst verloren, verbinde neu…",
+	"⚠ The FilterDNS service is not running":      "⚠ Der FilterDNS-Dienst läuft nicht",
+	"No daemon":                            "Kein Dienst",
+	"Reconnected to the FilterDNS service": "Wieder mit dem FilterDNS-Dienst verbunden",
+	"No profile connected yet":             "Noch kein Profil verbunden",
+	"✓ Connected to daemon":                "✓ Mit dem Dienst verbunden",
+	"Enabled (%d queries, %d blocked)":     "An (%d Anfragen, %d blockiert)",
+	"Disable":                              "Ausschalten",
+	"Paused until %s":                      "Pausiert bis %s",
+	"Disabled":                             "Aus",
+	"Today: %d blocked of %d · 7 days: %d blocked of %d · Total: %d blocked": "Heute: %d von %d blockiert · 7 Tage: %d von %d blockiert · Gesamt: %d blockiert",
+	"Server: Sync failed":                 "Server: Abgleich fehlgeschlagen",
+	"Server: Filtering active":            "Server: Filterung aktiv",
+	"Server: Paused until %s":             "Server: Pausiert bis %s",
+	"Server: Filtering paused":            "Server: Filterung pausiert",
+	"Working...":                          "Einen Moment...",
+	"Failed to get status: %v":            "Status konnte nicht abgefragt werden: %v",
+	"Enabling...":                         "Schalte ein...",
+	"Disabling...":                        "Schalte aus...",
+	"Failed to enable: %v":                "Einschalten fehlgeschlagen: %v",
+	"Failed to disable: %v":               "Ausschalten fehlgeschlagen: %v",
+	"DNS filtering enabled":               "DNS-Filterung eingeschaltet",
+	"DNS filtering disabled":              "DNS-Filterung ausgeschaltet",
+	"Saving...":                           "Speichere...",
+	"Failed to save password: %v":         "Passwort konnte nicht gespeichert werden: %v",
+	"Failed to update daemon: %v":         "Dienst konnte nicht aktualisiert werden: %v",
+	"Settings saved":                      "Einstellungen gespeichert",
+	"No forwarders configured":            "Keine Weiterleitungen eingerichtet",
+	"Add Split DNS Forwarder":             "Split-DNS-Weiterleitung hinzufügen",
+	"Add":                                 "Hinzufügen",
+	"Edit Split DNS Forwarder":            "Split-DNS-Weiterleitung bearbeiten",
+	"Domain":                              "Domain",
+	"DNS Server":                          "DNS-Server",
+	"Cancel":                              "Abbrechen",
+	"Forwarding %s to %s":                 "%s wird an %s weitergeleitet",
+	"Removed forwarder for %s":            "Weiterleitung für %s entfernt",
+	"Failed to update forwarders: %v":     "Weiterleitungen konnten nicht geändert werden: %v",
+	"Failed to change start on login: %v": "Start bei der Anmeldung konnte nicht geändert werden: %v",
+	"Connect to a profile first":          "Zuerst mit einem Profil verbinden",
+	"Testing...":                          "Teste...",
+	"Leak test failed: %v":                "Lecktest fehlgeschlagen: %v",
+	"Restore network settings":            "Netzwerkeinstellungen wiederherstellen",
+	"Turn filtering off and put back the network settings from before FilterDNS was installed?": "Filterung ausschalten und die Netzwerkeinstellungen von vor der Installation von FilterDNS wiederherstellen?",
+	"Restoring network failed: %v. Run \"sudo filterdns-client restore-network\" instead.":      "Wiederherstellen des Netzwerks fehlgeschlagen: %v. Stattdessen \"sudo filterdns-client restore-network\" ausführen.",
+	"FilterDNS Error":                     "FilterDNS-Fehler",
+	"Copy Diagnostics":                    "Diagnose kopieren",
+	"Copying...":                          "Kopiere...",
+	"Diagnostics copied to the clipboard": "Diagnose in die Zwischenablage kopiert",
+	"Close":                               "Schließen",
+	"Blocked %s":                          "%s blockiert",
+	" and %d other domains":               " und %d weitere Domains",
+	"The server paused filtering for this profile":         "Der Server hat die Filterung für dieses Profil pausiert",
+	"The server resumed filtering":                         "Der Server hat die Filterung fortgesetzt",
+	"The FilterDNS server is not answering":                "Der FilterDNS-Server antwortet nicht",
+	"The FilterDNS server is answering again":              "Der FilterDNS-Server antwortet wieder",
+	"use a time like 22:00":                                "Uhrzeit wie 22:00 angeben",
+	"When a domain is blocked":                             "Wenn eine Domain blockiert wird",
+	"When the server pauses or resumes filtering":          "Wenn der Server die Filterung pausiert oder fortsetzt",
+	"When the FilterDNS server stops or resumes answering": "Wenn der FilterDNS-Server nicht mehr oder wieder antwortet",
+	"Quiet hours from":                                     "Ruhezeit von",
+	"to":                                                   "bis",
+	"Notifications":                                        "Benachrichtigungen",
+	"Desktop notifications, none during quiet hours":       "Desktop-Benachrichtigungen, keine während der Ruhezeit",
+	"Could not list profiles (%v), type the name instead":  "Profile konnten nicht abgerufen werden (%v), bitte den Namen eintippen",
+	"The server offers no profiles":                        "Der Server bietet keine Profile an",
+	"Choose one of %d profiles":                            "Eines von %d Profilen wählen",
+	"The server has no profile %q":                         "Der Server hat kein Profil %q",
+	"This profile needs a password":                        "Dieses Profil braucht ein Passwort",
+	"server has no profile %s":                             "Server hat kein Profil %s",
+	"✗ Server not reachable: %v":                           "✗ Server nicht erreichbar: %v",
+	"✓ Server reachable":                                   "✓ Server erreichbar",
+	"✓ DNS-over-HTTPS answers":                             "✓ DNS-over-HTTPS antwortet",
+	"✗ Server rejected the profile password":               "✗ Server hat das Profilpasswort abgelehnt",
+	"✗ DNS-over-HTTPS failed: %v":                          "✗ DNS-over-HTTPS fehlgeschlagen: %v",
+	"✓ Profile %s accepted":                                "✓ Profil %s angenommen",
+	"Installing...":                                        "Installiere...",
+	"Could not install the service: %v":                    "Dienst konnte nicht installiert werden: %v",
+	"Background service installed":                         "Hintergrunddienst installiert",
+	"Background service running":                           "Hintergrunddienst läuft",
+	"Background service installed, but not running":        "Hintergrunddienst installiert, läuft aber nicht",
+	"System default":                                       "Systemstandard",
+	"Save and restart FilterDNS to switch the language":    "Zum Wechseln der Sprache speichern und FilterDNS neu starten",
+	"Language":             "Sprache",
+	"Welcome to FilterDNS": "Willkommen bei FilterDNS",
+	"Three steps and every DNS lookup on this computer goes through your FilterDNS profile.": "Drei Schritte, und jede DNS-Anfrage dieses Computers läuft über Ihr FilterDNS-Profil.",
+	"Install the FilterDNS service, which needs administrator rights":                        "Den FilterDNS-Dienst installieren, wofür Administratorrechte nötig sind",
+	"Install": "Installieren",
+	"Connect this computer to your FilterDNS profile": "Diesen Computer mit Ihrem FilterDNS-Profil verbinden",
+	"Connect":           "Verbinden",
+	"Turn on filtering": "Filterung einschalten",
+	"Done":              "Fertig",
+	"Skip to Settings":  "Weiter zu den Einstellungen",
+	"Always resolve a domain, even if the profile blocks it": "Eine Domain immer auflösen, auch wenn das Profil sie blockiert",
+	"Block a domain on this machine":                         "Eine Domain auf diesem Rechner blockieren",
+	"Match the system":                                       "Wie das System",
+	"Light":                                                  "Hell",
+	"Dark":                                                   "Dunkel",
+	"Appearance":                                             "Erscheinungsbild",
+	"Not running":                                            "Läuft nicht",
+	"Daemon version":                                         "Dienstversion",
+	"Server version":                                         "Serverversion",
+	"Check for Updates":                                      "Nach Updates suchen",
+	"Save Debug Bundle...":                                   "Diagnosepaket speichern...",
+	"Checking...":                                            "Suche...",
+	"Update check failed: %v":                                "Suche nach Updates fehlgeschlagen: %v",
+	"Development build; the latest release is %s": "Entwicklungsversion; die neueste Version ist %s",
+	"Update available": "Update verfügbar",
+	"Install FilterDNS %s? This replaces %s and restarts the service.": "FilterDNS %s installieren? Das ersetzt %s und startet den Dienst neu.",
+	"Updating...":                       "Aktualisiere...",
+	"Updated to %s":                     "Auf %s aktualisiert",
+	"Collecting...":                     "Sammle...",
+	"Could not collect diagnostics: %v": "Diagnose konnte nicht gesammelt werden: %v",
+	"local":                             "lokal",
+	"server":                            "Server",
+	"Allow here":                        "Hier erlauben",
+	"Block here":                        "Hier blockieren",
+	"Block for the profile":             "Für das Profil blockieren",
+	"Rules":                             "Regeln",
+	"Allow or block domains and their subdomains on this device, or block them for every device using the profile": "Domains samt Subdomains auf diesem Gerät erlauben oder blockieren, oder für alle Geräte mit diesem Profil blockieren",
+	"No rules yet; right-click a query in the Activity tab to add one":                                             "Noch keine Regeln; per Rechtsklick auf eine Anfrage im Tab Aktivität hinzufügen",
+	"this device":              "dieses Gerät",
+	"whole profile":            "ganzes Profil",
+	"Allow %s here":            "%s hier erlauben",
+	"Block %s here":            "%s hier blockieren",
+	"Block %s for the profile": "%s für das Profil blockieren",
+	"the server takes no rules for this profile":                             "der Server nimmt für dieses Profil keine Regeln an",
+	"%s is now blocked for profile %s":                                       "%s ist jetzt für Profil %s blockiert",
+	"Failed to remove the rule for %s: %v":                                   "Regel für %s konnte nicht entfernt werden: %v",
+	"Rule removed on this device, but not from the server profile: %v":       "Regel auf diesem Gerät entfernt, aber nicht aus dem Serverprofil: %v",
+	"FilterDNS keeps running in the system tray; quit it from the tray menu": "FilterDNS läuft im Infobereich weiter; beenden über das Menü dort",
+	"Start minimized to the tray":                                            "Minimiert im Infobereich starten",
+	"Closing the window quits FilterDNS":                                     "Schließen des Fensters beendet FilterDNS",
+	"Show the window when filtering turns on, off or pauses":                 "Fenster zeigen, wenn die Filterung an- oder ausgeht oder pausiert",
+	"%s on %s":                      "%s auf %s",
+	"Add Profile":                   "Profil hinzufügen",
+	"Edit Profile":                  "Profil bearbeiten",
+	"Failed to switch profile: %v":  "Profil konnte nicht gewechselt werden: %v",
+	"Failed to update profiles: %v": "Profile konnten nicht aktualisiert werden: %v",
+	"Forget the saved password":     "Gespeichertes Passwort vergessen",
+	"Name":                          "Name",
+	"No saved profiles yet":         "Noch keine gespeicherten Profile",
+	"Remove Profile":                "Profil entfernen",
+	"Remove the saved profile %s?":  "Das gespeicherte Profil %s entfernen?",
+	"Saved Profiles":                "Gespeicherte Profile",
+	"Switch Profile":                "Profil wechseln",
+	"Switch between profiles, e.g. for home and work": "Zwischen Profilen wechseln, z. B. für Zuhause und Arbeit",
+	"Unchanged":                           "Unverändert",
+	"Use":                                 "Verwenden",
+	"a profile named %s is saved already": "ein Profil namens %s ist bereits gespeichert",
+	"e.g. home or work":                   "z. B. zuhause oder arbeit",
+	"enter a name":                        "Namen eingeben",
+	"unknown profile: %s":                 "unbekanntes Profil: %s",
+	"Copy Link":                           "Link kopieren",
+	"Link copied":                         "Link kopiert",
+	"Finish Setup on Another Device":      "Einrichtung auf einem anderen Gerät abschließen",
+	"Complete the setup in the browser that opened, or scan this code with your phone:": "Schließe die Einrichtung im geöffneten Browser ab oder scanne diesen Code mit deinem Handy:",
+	"Detect VPNs":                 "VPNs erkennen",
+	"Detecting...":                "Suche...",
+	"Could not look for VPNs: %v": "VPNs konnten nicht gesucht werden: %v",
+	"No active VPN found":         "Kein aktives VPN gefunden",
+	"%s → %s":                     "%s → %s",
+	"Already forwarded: %s":       "Bereits weitergeleitet: %s",
+	"No DNS domain found; add its forwarder by hand if it has one": "Keine DNS-Domain gefunden; füge die Weiterleitung bei Bedarf von Hand hinzu",
+	"Detected VPNs":           "Erkannte VPNs",
+	"Added forwarders for %s": "Weiterleitungen für %s hinzugefügt",
+	"Resume Now":              "Jetzt fortsetzen",
+	"Filtering for profile %s is paused from the web dashboard, %s left": "Der Filter für Profil %s wurde im Web-Dashboard pausiert, noch %s",
+	"Filtering for profile %s is paused from the web dashboard":          "Der Filter für Profil %s wurde im Web-Dashboard pausiert",
+	"Resuming...":                      "Setze fort...",
+	"Could not resume profile %s: %v":  "Profil %s konnte nicht fortgesetzt werden: %v",
+	"Filtering resumed for profile %s": "Filter für Profil %s fortgesetzt",
+	"Edit":                             "Bearbeiten",
+	"Remove":                           "Entfernen",
+	"Refresh":                          "Aktualisieren",
+	"Loading...":                       "Lade...",
+	"Show how a GUI on another computer connects to this daemon":    "Zeigen, wie sich eine GUI auf einem anderen Computer mit diesem Dienst verbindet",
+	"Turn it on with: filterdns-client config set control-addr :%s": "Einschalten mit: filterdns-client config set control-addr :%s",
+	"Address:     %s": "Adresse:     %s",
+	"Token:       %s": "Token:       %s",
+	"Fingerprint: %s": "Fingerabdruck: %s",
+	"Enter these in the GUI's Settings under Daemon, with this computer's name or IP address in front of the port.": "Gib diese in den Einstellungen der GUI unter Dienst ein, mit dem Namen oder der IP-Adresse dieses Computers vor dem Port.",
+	"Password (kept on the other computer)": "Passwort (auf dem anderen Computer gespeichert)",
+	"This computer":                         "Dieser Computer",
+	"Another computer":                      "Anderer Computer",
+	"enter the address as host:port":        "gib die Adresse als Host:Port ein",
+	"Run \"filterdns-client remote-control\" on the other computer to see these. Through an SSH tunnel, use localhost and the forwarded port.": "Führe \"filterdns-client remote-control\" auf dem anderen Computer aus, um diese Angaben zu sehen. Über einen SSH-Tunnel nimm localhost und den weitergeleiteten Port.",
+	"Address":     "Adresse",
+	"Token":       "Token",
+	"Fingerprint": "Fingerabdruck",
+	"Daemon":      "Dienst",
+	"The FilterDNS service this window manages": "Der FilterDNS-Dienst, den dieses Fenster verwaltet",
+	"Connecting...": "Verbinde...",
+	"Could not connect to the daemon at %s: %v":                           "Keine Verbindung zum Dienst auf %s: %v",
+	"Failed to save the token: %v":                                        "Token konnte nicht gespeichert werden: %v",
+	"Managing the daemon at %s":                                           "Verwalte den Dienst auf %s",
+	"Managing the daemon on this computer":                                "Verwalte den Dienst auf diesem Computer",
+	"the daemon on the other computer does not answer":                    "der Dienst auf dem anderen Computer antwortet nicht",
+	"            live updates from server":                                "            Live-Aktualisierung vom Server",
+	"            the network looks offline":                               "            das Netzwerk scheint offline zu sein",
+	"            last worked %s, %d attempts since":                       "            zuletzt erfolgreich %s, seitdem %d Versuche",
+	"            next try at %s":                                          "            nächster Versuch um %s",
+	"Server: Offline, trying again at %s":                                 "Server: Offline, nächster Versuch um %s",
+	"Managed:   the server profile's client settings win over local ones": "Verwaltet: die Client-Einstellungen des Serverprofils haben Vorrang vor lokalen",
+	" (from server)":                                                      " (vom Server)",
+	"Overridden by server":                                                "Vom Server überschrieben",
+	"From server":                                                         "Vom Server",
+	"unknown heartbeat field %q":                                          "unbekanntes Heartbeat-Feld %q",
+	"This computer's name":                                                "Name dieses Computers",
+	"FilterDNS version":                                                   "FilterDNS-Version",
+	"Operating system":                                                    "Betriebssystem",
+	"Whether filtering is on, off or paused":                              "Ob die Filterung an, aus oder pausiert ist",
+	"Number of queries and blocked queries":                               "Anzahl der Anfragen und blockierten Anfragen",
+	"The last problem, e.g. a failed health check":                        "Das letzte Problem, z. B. eine fehlgeschlagene Prüfung",
+	"Device Status":                                                       "Gerätestatus",
+	"Shared with the server when syncing, so the dashboard shows whether this device is online and healthy": "Wird beim Synchronisieren an den Server übermittelt, damit das Dashboard zeigt, ob dieses Gerät online ist und funktioniert",
+	"Reported to the server: %s":                                           "An den Server gemeldet: %s",
+	"Commands from the server: on":                                         "Befehle vom Server: an",
+	"Let admins flush, pause, switch profile or update from the dashboard": "Admins dürfen über das Dashboard den Cache leeren, pausieren, das Profil wechseln oder aktualisieren",
+	"invalid DoH URL":                                                      "ungültige DoH-URL",
+	"DoH URL must start with https://":                                     "DoH-URL muss mit https:// beginnen",
+	"DoH URL has no host":                                                  "DoH-URL hat keinen Host",
+	"DoH URL must not contain a fragment":                                  "DoH-URL darf kein Fragment enthalten",
+	"DNS:       %s":                                                        "DNS:       %s",
+	"            warning: this computer's clock is %s the server's":        "            Warnung: die Uhr dieses Computers geht gegenüber der des Servers %s",
+	"%s behind":           "%s nach",
+	"%s ahead of":         "%s vor",
+	"Clock: unknown (%v)": "Uhr: unbekannt (%v)",
+	"Warning: this computer's clock is %s the server's; TLS to the server may fail until it is set right.": "Warnung: die Uhr dieses Computers geht gegenüber der des Servers %s; TLS zum Server kann fehlschlagen, bis sie richtig gestellt ist.",
+	"Clock: in step with the server (off by %s)":                                                           "Uhr: stimmt mit dem Server überein (Abweichung %s)",
+	"Warning: the server certificate is valid from %s to %s, which does not include this computer's time.": "Warnung: das Serverzertifikat ist von %s bis %s gültig, was die Uhrzeit dieses Computers nicht einschließt.",
+	"This computer's clock is %s behind the server's, so secure connections may fail":                      "Die Uhr dieses Computers geht gegenüber der des Servers %s nach, sichere Verbindungen können daher fehlschlagen",
+	"This computer's clock is %s ahead of the server's, so secure connections may fail":                    "Die Uhr dieses Computers geht gegenüber der des Servers %s vor, sichere Verbindungen können daher fehlschlagen",
+	"When this computer's clock is off from the server's":                                                  "Wenn die Uhr dieses Computers von der des Servers abweicht",
+	"server unreachable": "Server nicht erreichbar",
+	"maintenance":        "Wartung",
+	"paused until %s":    "pausiert bis %s",
+	"paused":             "pausiert",
+	"filtering":          "filtert",
+	"%s (%s)":            "%s (%s)",
+	"Settings were changed elsewhere and have been reloaded":             "Die Einstellungen wurden anderswo geändert und neu geladen",
+	"Note: %s overrides %s until it is removed.":                         "Hinweis: %s überschreibt %s, bis es entfernt wird.",
+	"Override a config key without saving it, as key=value (repeatable)": "Einen Konfigurationsschlüssel überschreiben, ohne ihn zu speichern, als Schlüssel=Wert (mehrfach möglich)",
+	"Settings of %s copied to %s":                                        "Einstellungen von %s nach %s kopiert",
+	"%s is locked by your administrator.":                                "%s ist von deiner Administration gesperrt.",
+	"locked by your administrator":                                       "von deiner Administration gesperrt",
+	"Some settings are locked by your administrator.":                    "Einige Einstellungen sind von deiner Administration gesperrt.",
+	"port must be a number from 1 to 65535, got %q":                      "Port muss eine Zahl von 1 bis 65535 sein, nicht %q",
+	"%s has another forwarder already":                                   "%s hat schon einen anderen Forwarder",
+	"rule action must be %s or %s":                                       "Regelaktion muss %s oder %s sein",
+	"saved profile has no name":                                          "gespeichertes Profil hat keinen Namen",
+	"another saved profile is called %s":                                 "ein anderes gespeichertes Profil heißt schon %s",
+	"no saved profile is called %s":                                      "kein gespeichertes Profil heißt %s",
+	"invalid interface pattern %q":                                       "ungültiges Schnittstellenmuster %q",
+	"%s is both filtered and ignored":                                    "%s wird zugleich gefiltert und ignoriert",
+	"bootstrap resolvers must be IP addresses, got %q":                   "Bootstrap-Resolver müssen IP-Adressen sein, nicht %q",
+	"update URL must start with https://":                                "Update-URL muss mit https:// beginnen",
+	"control address must be host:port or :port, got %q":                 "Steuerungsadresse muss host:port oder :port sein, nicht %q",
+	"language must be one of %s":                                         "Sprache muss eine von %s sein",
+	"credential store must be one of %s":                                 "Passwortspeicher muss einer von %s sein",
+	"must not be negative":                                               "darf nicht negativ sein",
+	"Check the configuration for invalid or conflicting settings":        "Konfiguration auf ungültige oder widersprüchliche Einstellungen prüfen",
+	"The configuration has problems:%s":                                  "Die Konfiguration hat Probleme:%s",
+	"The configuration is valid":                                         "Die Konfiguration ist gültig",
+	"Forwarder for %s: %v":                                               "Forwarder für %s: %v",
+	"Rule for %s: %v":                                                    "Regel für %s: %v",
+	"Saved profile %s: %v":                                               "Gespeichertes Profil %s: %v",
+	"network has no name":                                                "Netzwerk hat keinen Namen",
+	"another network is called %s":                                       "ein anderes Netzwerk heißt schon %s",
+	"network needs the names of its Wi-Fi networks":                      "Netzwerk braucht die Namen seiner WLANs",
+	"unknown setting":                                                    "unbekannte Einstellung",
+	"cannot change by network":                                           "kann nicht je Netzwerk geändert werden",
+	"Manage settings that apply on particular Wi-Fi networks":            "Einstellungen für bestimmte WLANs verwalten",
+	"List the networks with settings of their own":                       "Netzwerke mit eigenen Einstellungen auflisten",
+	"No networks with settings of their own. Add one with: filterdns-client network add <name> --ssid <Wi-Fi name>": "Keine Netzwerke mit eigenen Einstellungen. Füge eins hinzu mit: filterdns-client network add <Name> --ssid <WLAN-Name>",
+	"%s %-12s on %s":        "%s %-12s in %s",
+	"    profile %s":        "    Profil %s",
+	"Connected to Wi-Fi %s": "Verbunden mit WLAN %s",
+	"Add settings for Wi-Fi networks, or replace those of a network": "Einstellungen für WLANs hinzufügen oder die eines Netzwerks ersetzen",
+	"Add settings that apply while the computer is on one of the given Wi-Fi\nnetworks, or replace them for a network added before. The daemon notices\nwhen the computer joins or leaves one and switches settings by itself.\n\nExample:\n  filterdns-client network add office --ssid CorpWiFi \\\n    --forwarder '*.corp=10.0.0.2' --setting search-shortcut=false\n  filterdns-client network add home --ssid Home --profile kids": "Fügt Einstellungen hinzu, die gelten, solange der Computer mit einem der\nangegebenen WLANs verbunden ist, oder ersetzt die eines schon angelegten\nNetzwerks. Der Daemon merkt, wenn der Computer einem beitritt oder es\nverlässt, und wechselt die Einstellungen selbst.\n\nBeispiel:\n  filterdns-client network add office --ssid CorpWiFi \\\n    --forwarder '*.corp=10.0.0.2' --setting search-shortcut=false\n  filterdns-client network add home --ssid Home --profile kids",
+	"Error: --forwarder takes domain=server, got %q":                  "Fehler: --forwarder erwartet Domain=Server, nicht %q",
+	"Saved network %s (on %s)":                                        "Netzwerk %s gespeichert (in %s)",
+	"Wi-Fi network name it applies on (repeatable)":                   "Name eines WLANs, in dem sie gelten (wiederholbar)",
+	"Saved profile to use on it":                                      "Gespeichertes Profil, das dort verwendet wird",
+	"Split DNS forwarder to add there, as domain=server (repeatable)": "Split-DNS-Forwarder, der dort hinzukommt, als Domain=Server (wiederholbar)",
+	"Config key to change there, as key=value (repeatable)":           "Konfigurationsschlüssel, der dort geändert wird, als Schlüssel=Wert (wiederholbar)",
+	"Remove the settings of a network":                                "Einstellungen eines Netzwerks entfernen",
+	"Unknown network: %s":                                             "Unbekanntes Netzwerk: %s",
+	"Removed network: %s":                                             "Netzwerk entfernt: %s",
+	"Wi-Fi:      %s, using the settings for network %s":               "WLAN:       %s, mit den Einstellungen für Netzwerk %s",
+	"Wi-Fi:      %s":                                                  "WLAN:       %s",
+	"schedule entry has no name":                                      "Zeitplan-Eintrag hat keinen Namen",
+	"another schedule entry is called %s":                             "ein anderer Zeitplan-Eintrag heißt schon %s",
+	"day must be one of %s":                                           "Tag muss einer von %s sein",
+	"time must be hours and minutes, e.g. 15:00, got %q":              "Uhrzeit muss aus Stunden und Minuten bestehen, z. B. 15:00, nicht %q",
+	"action must be one of %s":                                        "Aktion muss eine von %s sein",
+	"Manage times of the week when filtering changes by itself":       "Zeiten der Woche verwalten, zu denen sich die Filterung selbst ändert",
+	"List the schedule":                                               "Zeitplan anzeigen",
+	"Nothing scheduled. Add an entry with: filterdns-client schedule add <name> --from 15:00 --until 19:00 --profile <name>": "Nichts geplant. Füge einen Eintrag hinzu mit: filterdns-client schedule add <Name> --from 15:00 --until 19:00 --profile <Name>",
+	"every day":                            "jeden Tag",
+	"%s %-12s %s %s-%s: %s":                "%s %-12s %s %s–%s: %s",
+	"Add a schedule entry, or replace one": "Zeitplan-Eintrag hinzufügen oder ersetzen",
+	"Add an entry to the schedule, or replace the one with the same name. During\nits time range filtering is paused (--off), turned on (--on) or uses a\nsaved profile (--profile). The daemon carries it out even when no GUI is\nrunning, and undoes it when the time range ends.\n\nExample:\n  filterdns-client schedule add homework --days weekdays \\\n    --from 15:00 --until 19:00 --profile homework\n  filterdns-client schedule add night --from 22:00 --until 06:30 --on": "Fügt dem Zeitplan einen Eintrag hinzu oder ersetzt den gleichnamigen. In\nseinem Zeitraum ist die Filterung pausiert (--off), eingeschaltet (--on)\noder nutzt ein gespeichertes Profil (--profile). Der Daemon führt ihn auch\nohne laufende GUI aus und macht ihn am Ende des Zeitraums rückgängig.\n\nBeispiel:\n  filterdns-client schedule add homework --days weekdays \\\n    --from 15:00 --until 19:00 --profile homework\n  filterdns-client schedule add night --from 22:00 --until 06:30 --on",
+	"Error: one of --off, --on or --profile is needed":                                           "Fehler: --off, --on oder --profile wird gebraucht",
+	"Scheduled %s: %s-%s, %s":                                                                    "%s geplant: %s–%s, %s",
+	"Days it applies on: mon to sun, weekdays or weekends (default: every day)":                  "Tage, an denen er gilt: mon bis sun, weekdays oder weekends (Standard: jeden Tag)",
+	"Start time, e.g. 15:00":                                                                     "Beginn, z. B. 15:00",
+	"End time, e.g. 19:00; before the start it is on the next day":                               "Ende, z. B. 19:00; vor dem Beginn liegt es am nächsten Tag",
+	"Pause filtering during the time range":                                                      "Filterung im Zeitraum pausieren",
+	"Turn filtering on during the time range":                                                    "Filterung im Zeitraum einschalten",
+	"Saved profile to use during the time range":                                                 "Gespeichertes Profil, das im Zeitraum verwendet wird",
+	"Remove a schedule entry":                                                                    "Zeitplan-Eintrag entfernen",
+	"Unknown schedule entry: %s":                                                                 "Unbekannter Zeitplan-Eintrag: %s",
+	"Removed schedule entry: %s":                                                                 "Zeitplan-Eintrag entfernt: %s",
+	"filtering paused":                                                                           "Filterung pausiert",
+	"filtering on":                                                                               "Filterung an",
+	"profile %s":                                                                                 "Profil %s",
+	"Schedule:   %s until %s (%s)":                                                               "Zeitplan:   %s bis %s (%s)",
+	"List other DNS servers that hold port 53 on the loopback":                                   "Andere DNS-Server auflisten, die Port 53 auf dem Loopback belegen",
+	"No other DNS server listens on port 53 of %s":                                               "Kein anderer DNS-Server lauscht auf Port 53 von %s",
+	"Error: fixing conflicts needs root, run it with sudo":                                       "Fehler: Zum Beheben von Konflikten sind Root-Rechte nötig, starte den Befehl mit sudo",
+	"Reconfigure %s and restart it?":                                                             "%s umkonfigurieren und neu starten?",
+	"%s reconfigured":                                                                            "%s umkonfiguriert",
+	"Reconfigure systemd-resolved or dnsmasq to make way for the proxy":                          "systemd-resolved oder dnsmasq umkonfigurieren, um Platz für den Proxy zu machen",
+	"Do not ask before reconfiguring":                                                            "Vor dem Umkonfigurieren nicht nachfragen",
+	"DNS outside the proxy blocked: %s":                                                          "DNS am Proxy vorbei blockiert: %s",
+	"Failed to remove DNS firewall rules: %v":                                                    "DNS-Firewallregeln konnten nicht entfernt werden: %v",
+	"Firewall:   DNS outside the proxy blocked":                                                  "Firewall:   DNS am Proxy vorbei blockiert",
+	"VPN:        %s on %s has no forwarders for its domains":                                     "VPN:        %s auf %s hat keine Weiterleitungen für seine Domains",
+	"            add with: filterdns-client forwarder add %s %s":                                 "            hinzufügen mit: filterdns-client forwarder add %s %s",
+	"VPN:        %s on %s took over system DNS, pointed back at the proxy":                       "VPN:        %s auf %s hat das System-DNS übernommen, wieder auf den Proxy gesetzt",
+	"VPN:        %s on %s keeps taking over system DNS, left alone":                              "VPN:        %s auf %s übernimmt das System-DNS immer wieder, wird in Ruhe gelassen",
+	"Run \"tailscale set --accept-dns=false\" and add a forwarder for the tailnet, or ignore %s": "Führe \"tailscale set --accept-dns=false\" aus und füge eine Weiterleitung für das Tailnet hinzu, oder ignoriere %s",
+	"Remove the DNS line from the tunnel's configuration and add forwarders for its domains, or ignore %s":                                                   "Entferne die DNS-Zeile aus der Konfiguration des Tunnels und füge Weiterleitungen für seine Domains hinzu, oder ignoriere %s",
+	"Add forwarders for the VPN's internal domains and keep its client from setting DNS (for OpenVPN: pull-filter ignore \"dhcp-option DNS\"), or ignore %s": "Füge Weiterleitungen für die internen Domains des VPN hinzu und hindere seinen Client daran, DNS zu setzen (bei OpenVPN: pull-filter ignore \"dhcp-option DNS\"), oder ignoriere %s",
+	"Keep the VPN from setting DNS and add forwarders for its domains, or ignore %s":                                                                         "Hindere das VPN daran, DNS zu setzen, und füge Weiterleitungen für seine Domains hinzu, oder ignoriere %s",
+	"%s on %s: its domains %s are sent to the profile, which cannot resolve them":                                                                            "%s auf %s: Seine Domains %s gehen an das Profil, das sie nicht auflösen kann",
+	"%s on %s took over system DNS, so lookups went past the filter. It was pointed back at the proxy.":                                                      "%s auf %s hat das System-DNS übernommen, sodass Anfragen am Filter vorbeigingen. Es wurde wieder auf den Proxy gesetzt.",
+	"%s on %s keeps taking over system DNS, so it is left alone and lookups go past the filter.":                                                             "%s auf %s übernimmt das System-DNS immer wieder, wird daher in Ruhe gelassen, und Anfragen gehen am Filter vorbei.",
+	"Add Forwarders":          "Weiterleitungen hinzufügen",
+	"Ignore %s":               "%s ignorieren",
+	"Could not ignore %s: %v": "%s konnte nicht ignoriert werden: %v",
+	"%s is ignored now and keeps the VPN's DNS":                                     "%s wird jetzt ignoriert und behält das DNS des VPN",
+	"User who may read the daemon's query history (default: the user running sudo)": "Benutzer, der den Abfrageverlauf des Daemons lesen darf (Standard: der Benutzer, der sudo ausführt)",
+	"Remove this user's config and saved passwords (run by uninstall --purge)":      "Entfernt die Konfiguration und gespeicherten Passwörter dieses Benutzers (von uninstall --purge aufgerufen)",
+	"Time":    "Zeit",
+	"Type":    "Typ",
+	"Result":  "Ergebnis",
+	"Source":  "Quelle",
+	"Latency": "Latenz",
+}
@@ -0,0 +1,123 @@
+// Package i18n translates the text shown to users of the GUI and CLI.
+//
+// Messages are looked up by their English text, so code reads as before
+// and a message without a translation still shows, in English. Log lines,
+// JSON output and the daemon's own messages are not translated: they end
+// up in support requests and scripts.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Supported languages, as ISO 639-1 codes
+const (
+	English = "en"
+	German  = "de"
+)
+
+// Languages lists the supported languages, English first
+var Languages = []string{English, German}
+
+// names are the languages' names in themselves, for language pickers
+var names = map[string]string{
+	English: "English",
+	German:  "Deutsch",
+}
+
+// catalogs map English messages to their translations
+var catalogs = map[string]map[string]string{
+	German: german,
+}
+
+// current is the language in use
+var current atomic.Value
+
+// Init picks the language: override if it names a supported language,
+// otherwise the system's. Call it before building any user-facing text.
+func Init(override string) {
+	if lang := normalize(override); lang != "" {
+		SetLanguage(lang)
+		return
+	}
+	SetLanguage(SystemLanguage())
+}
+
+// SetLanguage switches to lang, falling back to English if it is not
+// supported
+func SetLanguage(lang string) {
+	if _, ok := names[lang]; !ok {
+		lang = English
+	}
+	current.Store(lang)
+}
+
+// Language returns the language in use
+func Language() string {
+	if lang, ok := current.Load().(string); ok {
+		return lang
+	}
+	return English
+}
+
+// Name returns a language's name in itself, e.g. "Deutsch"
+func Name(lang string) string {
+	if name, ok := names[lang]; ok {
+		return name
+	}
+	return lang
+}
+
+// T translates msg into the current language and, given args, formats
+// it like fmt.Sprintf
+func T(msg string, args ...any) string {
+	if translated, ok := catalogs[Language()][msg]; ok {
+		msg = translated
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// SystemLanguage returns the supported language closest to the user's
+// locale, or English
+func SystemLanguage() string {
+	// The POSIX variables win where set, also on macOS and Windows, so a
+	// terminal can be switched without changing the system
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			if lang := normalize(value); lang != "" {
+				return lang
+			}
+			if value != "C" && value != "POSIX" {
+				return English
+			}
+		}
+	}
+	if lang := normalize(platformLocale()); lang != "" {
+		return lang
+	}
+	return English
+}
+
+// normalize maps a locale such as "de_DE.UTF-8", "de-AT" or "German" to
+// a supported language, or "" if it names none
+func normalize(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "_-.@"); i >= 0 {
+		locale = locale[:i]
+	}
+	for _, lang := range Languages {
+		if locale == lang || locale == strings.ToLower(names[lang]) {
+			return lang
+		}
+	}
+	if locale == "german" {
+		return German
+	}
+	return ""
+}
@@ -0,0 +1,16 @@
+package i18n
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// platformLocale returns the locale chosen in System Settings, which apps
+// started from Finder see no LANG for
+func platformLocale() string {
+	out, err := exec.Command("defaults", "read", "-g", "AppleLocale").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
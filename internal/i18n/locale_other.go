@@ -0,0 +1,8 @@
+//go:build !darwin && !windows
+
+package i18n
+
+// platformLocale has nothing to add to the POSIX locale variables
+func platformLocale() string {
+	return ""
+}
@@ -0,0 +1,12 @@
+package i18n
+
+import "golang.org/x/sys/windows"
+
+// platformLocale returns the user's preferred display language
+func platformLocale() string {
+	langs, err := windows.GetUserPreferredUILanguages(windows.MUI_LANGUAGE_NAME)
+	if err != nil || len(langs) == 0 {
+		return ""
+	}
+	return langs[0]
+}
@@ -0,0 +1,214 @@
+// Package leaktest checks that DNS lookups on this machine actually reach
+// FilterDNS through the configured profile.
+//
+// The server hands out a unique test domain it is authoritative for. The
+// client looks up a few random names below it through the system
+// resolver, then asks the server where each name arrived: over DoH for the
+// expected profile, or from some other resolver (a VPN, the ISP, a
+// hard-coded public resolver) that bypassed the client.
+package leaktest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// queries is how many names are looked up
+	queries = 5
+
+	// settle is how long the server gets to record the last lookups
+	settle = 2 * time.Second
+)
+
+// StartResponse from /api/client/leaktest/start
+type StartResponse struct {
+	ID     string `json:"id"`
+	Domain string `json:"domain"` // Names below it are recorded for ID
+}
+
+// Sighting is one lookup the server recorded
+type Sighting struct {
+	Name     string `json:"name"`
+	Source   string `json:"source"`             // "doh" or "resolver"
+	Profile  string `json:"profile,omitempty"`  // For "doh", the profile it came through
+	Resolver string `json:"resolver,omitempty"` // For "resolver", the resolver's address
+}
+
+// ResultsResponse from /api/client/leaktest/<id>
+type ResultsResponse struct {
+	Sightings []Sighting `json:"sightings"`
+}
+
+// Result summarizes a leak test
+type Result struct {
+	Queries   int      `json:"queries"`
+	Filtered  int      `json:"filtered"`            // Arrived through the expected profile
+	Leaked    int      `json:"leaked"`              // Arrived some other way
+	Missing   int      `json:"missing"`             // Never arrived
+	Resolvers []string `json:"resolvers,omitempty"` // Resolvers or profiles that leaked
+}
+
+// OK reports whether every lookup went through the expected profile
+func (r *Result) OK() bool {
+	return r.Filtered == r.Queries
+}
+
+// Summary describes the result in one sentence
+func (r *Result) Summary() string {
+	switch {
+	case r.OK():
+		return fmt.Sprintf("No leak: all %d lookups went through FilterDNS", r.Queries)
+	case r.Leaked > 0:
+		return fmt.Sprintf("Leak: %d of %d lookups bypassed the profile (via %s)",
+			r.Leaked, r.Queries, strings.Join(r.Resolvers, ", "))
+	default:
+		return fmt.Sprintf("Inconclusive: %d of %d lookups never reached the server", r.Missing, r.Queries)
+	}
+}
+
+// Run performs a leak test against the server for profile
+func Run(ctx context.Context, serverURL, profile string) (*Result, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	test, err := start(ctx, client, serverURL, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, queries)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s.%s", randomLabel(), strings.TrimSuffix(test.Domain, "."))
+
+		// The answer does not matter, only where the lookup went
+		lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		net.DefaultResolver.LookupHost(lookupCtx, names[i])
+		cancel()
+	}
+
+	select {
+	case <-time.After(settle):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	sightings, err := results(ctx, client, serverURL, test.ID)
+	if err != nil {
+		return nil, err
+	}
+	return evaluate(names, sightings, profile), nil
+}
+
+// evaluate sorts each looked-up name into filtered, leaked or missing
+func evaluate(names []string, sightings []Sighting, profile string) *Result {
+	result := &Result{Queries: len(names)}
+
+	for _, name := range names {
+		filtered, leaked := false, false
+		for _, s := range sightings {
+			if !strings.EqualFold(strings.TrimSuffix(s.Name, "."), name) {
+				continue
+			}
+			if s.Source == "doh" && s.Profile == profile {
+				filtered = true
+				continue
+			}
+
+			leaked = true
+			via := s.Resolver
+			if s.Source == "doh" {
+				via = "profile " + s.Profile
+			}
+			if via != "" && !slices.Contains(result.Resolvers, via) {
+				result.Resolvers = append(result.Resolvers, via)
+			}
+		}
+
+		// A lookup that also went elsewhere leaked, even if it was filtered
+		switch {
+		case leaked:
+			result.Leaked++
+		case filtered:
+			result.Filtered++
+		default:
+			result.Missing++
+		}
+	}
+
+	return result
+}
+
+// start asks the server for a test domain
+func start(ctx context.Context, client *http.Client, serverURL, profile string) (*StartResponse, error) {
+	body, _ := json.Marshal(map[string]string{"profile": profile})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, serverURL+"/api/client/leaktest/start",
+		strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("server does not support leak tests")
+	default:
+		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	var test StartResponse
+	if err := json.NewDecoder(resp.Body).Decode(&test); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if test.ID == "" || test.Domain == "" {
+		return nil, fmt.Errorf("server returned no test domain")
+	}
+	return &test, nil
+}
+
+// results fetches what the server recorded for a test
+func results(ctx context.Context, client *http.Client, serverURL, id string) ([]Sighting, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/api/client/leaktest/%s", serverURL, url.PathEscape(id)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	var results ResultsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return results.Sightings, nil
+}
+
+// randomLabel returns a DNS label no cache can have seen before
+func randomLabel() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return "lt-" + hex.EncodeToString(b[:])
+}
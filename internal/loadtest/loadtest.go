@@ -0,0 +1,193 @@
+// Package loadtest generates DNS load against the local proxy.
+//
+// It is used to validate performance changes and to size small
+// deployments (e.g. Raspberry Pi) by reporting latency, error rate and
+// how the daemon's cache, goroutines and memory behave under load.
+package loadtest
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Default domains used when no domain file is given
+var defaultDomains = []string{
+	"example.com",
+	"wikipedia.org",
+	"github.com",
+	"google.com",
+	"cloudflare.com",
+	"kit.edu",
+	"zkm.de",
+	"mozilla.org",
+}
+
+// Options configures a load test run
+type Options struct {
+	Server      string        // Proxy address, e.g. "127.0.0.1:53"
+	QPS         int           // Target queries per second
+	Duration    time.Duration // How long to generate load
+	Concurrency int           // Maximum in-flight queries
+	Timeout     time.Duration // Per-query timeout
+	Domains     []string      // Domains to query (round-robin)
+}
+
+// Result summarizes a load test run
+type Result struct {
+	Sent      int64         // Queries sent
+	Succeeded int64         // Queries answered
+	Failed    int64         // Timeouts, network errors and SERVFAIL
+	Dropped   int64         // Queries skipped because all workers were busy
+	Elapsed   time.Duration // Actual run time
+
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// AchievedQPS returns the rate at which queries were actually sent
+func (r *Result) AchievedQPS() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Sent) / r.Elapsed.Seconds()
+}
+
+// ErrorRate returns the fraction of sent queries that failed
+func (r *Result) ErrorRate() float64 {
+	if r.Sent == 0 {
+		return 0
+	}
+	return float64(r.Failed) / float64(r.Sent)
+}
+
+// LoadDomains reads one domain per line, skipping blanks and # comments
+func LoadDomains(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var domains []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no domains in %s", path)
+	}
+	return domains, nil
+}
+
+// Run sends queries at the configured rate and collects the results
+func Run(opts Options) (*Result, error) {
+	if opts.QPS <= 0 {
+		return nil, fmt.Errorf("qps must be positive")
+	}
+	if opts.Duration <= 0 {
+		return nil, fmt.Errorf("duration must be positive")
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 100
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Second
+	}
+	if len(opts.Domains) == 0 {
+		opts.Domains = defaultDomains
+	}
+
+	client := &dns.Client{
+		Net:     "udp",
+		Timeout: opts.Timeout,
+	}
+
+	result := &Result{}
+	var latencies []time.Duration
+	var mu sync.Mutex
+
+	jobs := make(chan string, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for domain := range jobs {
+				msg := new(dns.Msg)
+				msg.SetQuestion(dns.Fqdn(domain), dns.TypeA)
+
+				start := time.Now()
+				resp, _, err := client.Exchange(msg, opts.Server)
+				latency := time.Since(start)
+
+				mu.Lock()
+				if err != nil || resp.Rcode == dns.RcodeServerFailure {
+					result.Failed++
+				} else {
+					result.Succeeded++
+					latencies = append(latencies, latency)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Schedule queries evenly; a ticker is too coarse at high rates
+	interval := time.Second / time.Duration(opts.QPS)
+	start := time.Now()
+	deadline := start.Add(opts.Duration)
+	next := start
+
+	for i := 0; time.Now().Before(deadline); i++ {
+		if wait := time.Until(next); wait > 0 {
+			time.Sleep(wait)
+		}
+		next = next.Add(interval)
+
+		select {
+		case jobs <- opts.Domains[i%len(opts.Domains)]:
+			result.Sent++
+		default:
+			result.Dropped++
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	result.Elapsed = time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.P50 = percentile(latencies, 0.50)
+	result.P90 = percentile(latencies, 0.90)
+	result.P99 = percentile(latencies, 0.99)
+	if len(latencies) > 0 {
+		result.Max = latencies[len(latencies)-1]
+	}
+
+	return result, nil
+}
+
+// percentile returns the p-th percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx]
+}
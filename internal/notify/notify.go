@@ -0,0 +1,104 @@
+// Package notify rate-limits and deduplicates user-facing notifications.
+//
+// When the upstream flaps, every failed query would otherwise turn into
+// its own notification (GUI) or log line (daemon). A Notifier lets the
+// first few similar messages through, swallows the rest of the burst and
+// reports them once as "N similar errors suppressed" when it is over.
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// SendFunc delivers a notification, e.g. as a desktop notification or a
+// log line
+type SendFunc func(title, message string)
+
+// Notifier suppresses bursts of similar notifications
+type Notifier struct {
+	send   SendFunc
+	window time.Duration
+	burst  int
+
+	mu     sync.Mutex
+	groups map[string]*group
+}
+
+// group tracks similar notifications within the current window
+type group struct {
+	title      string
+	sent       int
+	suppressed int
+}
+
+// New creates a notifier that lets at most burst similar notifications
+// through per window
+func New(send SendFunc, window time.Duration, burst int) *Notifier {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Notifier{
+		send:   send,
+		window: window,
+		burst:  burst,
+		groups: make(map[string]*group),
+	}
+}
+
+// Notify sends a notification unless too many similar ones went out
+// recently. Messages that only differ in numbers (status codes, counts,
+// addresses) count as similar.
+func (n *Notifier) Notify(title, message string) {
+	key := title + "\x00" + similarityKey(message)
+
+	n.mu.Lock()
+	g, ok := n.groups[key]
+	if !ok {
+		g = &group{title: title}
+		n.groups[key] = g
+		time.AfterFunc(n.window, func() { n.flush(key) })
+	}
+
+	if g.sent >= n.burst {
+		g.suppressed++
+		n.mu.Unlock()
+		return
+	}
+	g.sent++
+	n.mu.Unlock()
+
+	n.send(title, message)
+}
+
+// flush ends a window and reports what was suppressed in it
+func (n *Notifier) flush(key string) {
+	n.mu.Lock()
+	g := n.groups[key]
+	delete(n.groups, key)
+	n.mu.Unlock()
+
+	if g == nil || g.suppressed == 0 {
+		return
+	}
+
+	noun := "errors"
+	if g.suppressed == 1 {
+		noun = "error"
+	}
+	n.send(g.title, fmt.Sprintf("%d similar %s suppressed", g.suppressed, noun))
+}
+
+// similarityKey normalizes a message so that variants differing only in
+// digits map to the same key
+func similarityKey(message string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return '#'
+		}
+		return r
+	}, message)
+}
@@ -7,6 +7,12 @@
 // 4. Browser calls /api/client/onboard/complete
 // 5. Client polls /api/client/onboard/poll until completed
 // 6. Client saves profile config
+//
+// The dashboard can also start the flow itself and hand the token to the
+// client through a deep link (filterdns://onboard?server=...&token=...),
+// in which case the client only does steps 5 and 6. Headless clients skip
+// step 2 and print the URL as text and as a QR code, plus a short pairing
+// code where the server offers one, for step 3 to happen on another device.
 package onboard
 
 import (
@@ -16,10 +22,13 @@ import (
 	"net/url"
 	"os/exec"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/qr"
+	filtersync "github.com/zkmkarlsruhe/filterdns-client/internal/sync"
 )
 
 // Result contains the onboarding result
@@ -34,6 +43,11 @@ type StartOnboardingResponse struct {
 	Token      string `json:"token"`
 	OnboardURL string `json:"onboard_url"`
 	ExpiresAt  string `json:"expires_at"`
+
+	// Offered for headless sessions: a short code to enter at PairURL on
+	// another device, instead of typing the long OnboardURL
+	PairingCode string `json:"pairing_code,omitempty"`
+	PairURL     string `json:"pair_url,omitempty"`
 }
 
 // PollResponse from /api/client/onboard/poll
@@ -56,22 +70,72 @@ type ProfileInfo struct {
 
 // Run starts the web-based onboarding flow
 func Run(serverURL string) (*Result, error) {
+	return run(serverURL, false, nil)
+}
+
+// RunShowing starts the web-based onboarding flow like Run and passes the
+// onboarding URL to show once the server issued it, e.g. to put it on
+// screen as a QR code for finishing the setup on a phone
+func RunShowing(serverURL string, show func(onboardURL string)) (*Result, error) {
+	return run(serverURL, false, show)
+}
+
+// RunHeadless starts the onboarding flow for machines without a browser:
+// instead of opening one, it prints the onboarding URL, also as a QR code,
+// and, if the server offers one, a short pairing code to enter on another
+// device
+func RunHeadless(serverURL string) (*Result, error) {
+	return run(serverURL, true, nil)
+}
+
+// WithProfile skips the web flow for a profile whose name and password
+// are already known. If the server lists its profiles, the name is checked
+// against them.
+func WithProfile(serverURL, profile, password string) (*Result, error) {
+	if profiles, err := ListProfiles(serverURL); err == nil {
+		i := slices.IndexFunc(profiles, func(p ProfileInfo) bool { return p.Name == profile })
+		switch {
+		case i < 0:
+			return nil, fmt.Errorf("server has no profile %s", profile)
+		case profiles[i].HasPassword && password == "":
+			return nil, fmt.Errorf("profile %s requires a password", profile)
+		}
+	}
+
+	return &Result{ProfileName: profile, Password: password, ServerURL: serverURL}, nil
+}
+
+func run(serverURL string, headless bool, show func(onboardURL string)) (*Result, error) {
+	// Older or stripped-down servers may not offer web onboarding. If the
+	// probe itself fails, let the start request report the problem.
+	if caps, err := filtersync.ProbeCapabilities(serverURL, ""); err == nil && !caps.Onboarding {
+		return nil, fmt.Errorf("server does not support web onboarding, configure the profile with 'config set' instead")
+	}
+
 	// Step 1: Start onboarding session
-	startResp, err := startOnboarding(serverURL)
+	startResp, err := startOnboarding(serverURL, headless)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start onboarding: %w", err)
 	}
+	if show != nil {
+		show(startResp.OnboardURL)
+	}
 
 	// Step 2: Open browser (continue even if it fails)
-	if err := openBrowser(startResp.OnboardURL); err != nil {
-		fmt.Printf("\nCould not open browser automatically.\n")
-		fmt.Printf("Please open this URL in your browser:\n\n")
-		fmt.Printf("  %s\n\n", startResp.OnboardURL)
+	if headless {
+		printPairing(startResp)
+		fmt.Println("Complete the setup on the other device...")
 	} else {
-		fmt.Println("Browser opened.")
+		if err := openBrowser(startResp.OnboardURL); err != nil {
+			fmt.Printf("\nCould not open browser automatically.\n")
+			fmt.Printf("Please open this URL in your browser:\n\n")
+			fmt.Printf("  %s\n\n", startResp.OnboardURL)
+			printQR(startResp.OnboardURL)
+		} else {
+			fmt.Println("Browser opened.")
+		}
+		fmt.Println("Complete the setup in your browser...")
 	}
-
-	fmt.Println("Complete the setup in your browser...")
 	fmt.Println("Waiting for completion...")
 
 	// Step 3: Poll for completion
@@ -84,14 +148,119 @@ func Run(serverURL string) (*Result, error) {
 	return result, nil
 }
 
-func startOnboarding(serverURL string) (*StartOnboardingResponse, error) {
+// DeepLinkScheme is the URL scheme the client registers with the OS
+const DeepLinkScheme = "filterdns"
+
+// IsDeepLink reports whether arg is a filterdns:// URL, as passed by the
+// OS when the user follows a deep link
+func IsDeepLink(arg string) bool {
+	return strings.HasPrefix(strings.ToLower(arg), DeepLinkScheme+"://")
+}
+
+// ParseDeepLink extracts the server URL and onboarding token from a
+// filterdns://onboard link
+func ParseDeepLink(link string) (serverURL, token string, err error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid link: %w", err)
+	}
+	if !strings.EqualFold(u.Scheme, DeepLinkScheme) || u.Host != "onboard" {
+		return "", "", fmt.Errorf("unsupported link: %s", link)
+	}
+
+	query := u.Query()
+	serverURL = strings.TrimSuffix(query.Get("server"), "/")
+	token = query.Get("token")
+
+	server, err := url.Parse(serverURL)
+	if err != nil || (server.Scheme != "https" && server.Scheme != "http") || server.Host == "" {
+		return "", "", fmt.Errorf("link has an invalid server URL: %q", serverURL)
+	}
+	if token == "" {
+		return "", "", fmt.Errorf("link has no onboarding token")
+	}
+
+	return serverURL, token, nil
+}
+
+// Resume completes an onboarding session that the dashboard started,
+// e.g. from a deep link
+func Resume(serverURL, token string) (*Result, error) {
+	result, err := pollForCompletion(serverURL, token)
+	if err != nil {
+		return nil, err
+	}
+
+	result.ServerURL = serverURL
+	return result, nil
+}
+
+// ProfilesResponse from /api/client/profiles
+type ProfilesResponse struct {
+	Profiles []ProfileInfo `json:"profiles"`
+}
+
+// ListProfiles asks the server which profiles clients can use
+func ListProfiles(serverURL string) ([]ProfileInfo, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	resp, err := client.Get(serverURL + "/api/client/profiles")
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("server does not list profiles, see the dashboard instead")
+	default:
+		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	var profiles ProfilesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return profiles.Profiles, nil
+}
+
+// printPairing tells the user how to continue on another device
+func printPairing(start *StartOnboardingResponse) {
+	if start.PairingCode != "" && start.PairURL != "" {
+		fmt.Printf("\nOn another device, open:\n\n")
+		fmt.Printf("  %s\n\n", start.PairURL)
+		fmt.Printf("and enter the code:\n\n")
+		fmt.Printf("  %s\n\n", start.PairingCode)
+		fmt.Printf("Or open this URL directly:\n\n")
+	} else {
+		fmt.Printf("\nOn another device, open this URL:\n\n")
+	}
+	fmt.Printf("  %s\n\n", start.OnboardURL)
+	printQR(start.OnboardURL)
+}
+
+// printQR prints link as a QR code, for opening it with a phone's camera
+func printQR(link string) {
+	code, err := qr.Encode(link)
+	if err != nil {
+		return
+	}
+	fmt.Printf("Or scan this code with your phone:\n\n%s\n", code.Terminal())
+}
+
+func startOnboarding(serverURL string, headless bool) (*StartOnboardingResponse, error) {
 	client := &http.Client{Timeout: 10 * time.Second}
 
-	// Send empty JSON body (required by server)
+	// The server requires a JSON body, even an empty one
+	body := "{}"
+	if headless {
+		body = `{"headless":true}`
+	}
 	resp, err := client.Post(
 		serverURL+"/api/client/onboard/start",
 		"application/json",
-		strings.NewReader("{}"),
+		strings.NewReader(body),
 	)
 	if err != nil {
 		return nil, err
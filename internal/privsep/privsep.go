@@ -0,0 +1,444 @@
+//go:build !windows
+
+// Package privsep splits the daemon into an unprivileged main process and
+// a small root helper.
+//
+// The daemon starts the helper (the same binary, run as "dns-helper")
+// while it is still root, then drops to an unprivileged user. From then on
+// the DoH, HTTP and IPC code paths run without root, and the helper only
+// does the things that need it: change system DNS, restore it, add and
+// remove the firewall rules against DNS leaks, and bind the proxy's
+// port-53 sockets, which it hands back over a Unix socket.
+package privsep
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/system"
+)
+
+// maxMessage bounds a single request or response datagram
+const maxMessage = 64 * 1024
+
+// request is sent from the daemon to the helper
+type request struct {
+	Action  string   `json:"action"` // "set_dns", "add_dns_links", "set_split_dns", "reset_dns", "restore_network", "block_outside_dns", "unblock_outside_dns" or "bind"
+	Servers []string `json:"servers,omitempty"`
+	Managed []string `json:"managed,omitempty"`
+	Ignored []string `json:"ignored,omitempty"`
+	Links   []string `json:"links,omitempty"` // For "add_dns_links"
+	Addr    string   `json:"addr,omitempty"`
+
+	// For "set_split_dns" and "block_outside_dns"
+	Domains []system.SplitDomain `json:"domains,omitempty"`
+
+	// For "block_outside_dns", the daemon's user
+	UID int `json:"uid,omitempty"`
+}
+
+// response is sent back; "bind" responses carry the UDP and TCP socket
+// descriptors as ancillary data
+type response struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Helper is the daemon's handle on the privileged helper process
+type Helper struct {
+	cmd   *exec.Cmd
+	conn  *net.UnixConn
+	stdin io.WriteCloser
+	mu    sync.Mutex
+}
+
+// StartHelper launches the privileged helper. It must be called while the
+// daemon still runs as root.
+func StartHelper() (*Helper, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find executable: %w", err)
+	}
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_DGRAM, 0)
+	if err != nil {
+		return nil, fmt.Errorf("socketpair failed: %w", err)
+	}
+	local := os.NewFile(uintptr(fds[0]), "privsep-daemon")
+	remote := os.NewFile(uintptr(fds[1]), "privsep-helper")
+	defer remote.Close()
+
+	fileConn, err := net.FileConn(local)
+	local.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(exe, "dns-helper")
+	cmd.ExtraFiles = []*os.File{remote} // fd 3 in the helper
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	// The helper exits when this pipe closes, i.e. when the daemon dies
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		fileConn.Close()
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		fileConn.Close()
+		return nil, fmt.Errorf("failed to start helper: %w", err)
+	}
+
+	return &Helper{
+		cmd:   cmd,
+		conn:  fileConn.(*net.UnixConn),
+		stdin: stdin,
+	}, nil
+}
+
+// SetDNS points system DNS at servers on the interfaces selected by the
+// managed/ignored patterns (see config.Config.ManagesInterface)
+func (h *Helper) SetDNS(servers []string, managed, ignored []string) error {
+	_, err := h.call(request{Action: "set_dns", Servers: servers, Managed: managed, Ignored: ignored})
+	return err
+}
+
+// AddDNSLinks points more links at servers (see system.AddDNSLinks)
+func (h *Helper) AddDNSLinks(servers, links []string) error {
+	_, err := h.call(request{Action: "add_dns_links", Servers: servers, Links: links})
+	return err
+}
+
+// SetSplitDNS registers split DNS domains with the system resolver
+// (see system.SetSplitDNS)
+func (h *Helper) SetSplitDNS(domains []system.SplitDomain) error {
+	_, err := h.call(request{Action: "set_split_dns", Domains: domains})
+	return err
+}
+
+// ResetDNS restores the original system DNS settings
+func (h *Helper) ResetDNS() error {
+	_, err := h.call(request{Action: "reset_dns"})
+	return err
+}
+
+// RestoreNetwork undoes all changes to system networking
+// (see system.RestoreNetwork)
+func (h *Helper) RestoreNetwork() error {
+	_, err := h.call(request{Action: "restore_network"})
+	return err
+}
+
+// BlockOutsideDNS blocks DNS from every user but the daemon's, except to
+// the forwarders' servers (see system.BlockOutsideDNS)
+func (h *Helper) BlockOutsideDNS(forwarders []system.SplitDomain) error {
+	_, err := h.call(request{Action: "block_outside_dns", UID: os.Getuid(), Domains: forwarders})
+	return err
+}
+
+// UnblockOutsideDNS removes the rules BlockOutsideDNS installed
+func (h *Helper) UnblockOutsideDNS() error {
+	_, err := h.call(request{Action: "unblock_outside_dns"})
+	return err
+}
+
+// BindDNS has the helper bind UDP and TCP sockets on addr, which must be
+// a loopback address on port 53
+func (h *Helper) BindDNS(addr string) (net.PacketConn, net.Listener, error) {
+	files, err := h.call(request{Action: "bind", Addr: addr})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	if len(files) != 2 {
+		return nil, nil, fmt.Errorf("helper returned %d sockets, want 2", len(files))
+	}
+
+	pc, err := net.FilePacketConn(files[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	l, err := net.FileListener(files[1])
+	if err != nil {
+		pc.Close()
+		return nil, nil, err
+	}
+	return pc, l, nil
+}
+
+// Close stops the helper
+func (h *Helper) Close() error {
+	h.stdin.Close()
+	h.conn.Close()
+	return h.cmd.Wait()
+}
+
+// call sends one request and waits for its response
+func (h *Helper) call(req request) ([]*os.File, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := h.conn.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to send to helper: %w", err)
+	}
+
+	buf := make([]byte, maxMessage)
+	oob := make([]byte, syscall.CmsgSpace(2*4))
+	n, oobn, _, _, err := h.conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from helper: %w", err)
+	}
+
+	files, err := parseRights(oob[:oobn])
+	if err != nil {
+		return nil, err
+	}
+
+	var resp response
+	if err := json.Unmarshal(buf[:n], &resp); err != nil {
+		for _, f := range files {
+			f.Close()
+		}
+		return nil, fmt.Errorf("invalid helper response: %w", err)
+	}
+	if !resp.Success {
+		for _, f := range files {
+			f.Close()
+		}
+		return nil, fmt.Errorf("helper: %s", resp.Error)
+	}
+	return files, nil
+}
+
+// parseRights extracts passed file descriptors from ancillary data
+func parseRights(oob []byte) ([]*os.File, error) {
+	if len(oob) == 0 {
+		return nil, nil
+	}
+
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*os.File
+	for _, msg := range msgs {
+		fds, err := syscall.ParseUnixRights(&msg)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			files = append(files, os.NewFile(uintptr(fd), "privsep-socket"))
+		}
+	}
+	return files, nil
+}
+
+// Serve runs the helper side: it answers requests on fd 3 until the
+// daemon goes away. Called by the hidden "dns-helper" command.
+func Serve() error {
+	f := os.NewFile(3, "privsep-helper")
+	fileConn, err := net.FileConn(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("helper socket missing: %w", err)
+	}
+	conn := fileConn.(*net.UnixConn)
+
+	// Exit as soon as the daemon closes our stdin (or dies)
+	go func() {
+		io.Copy(io.Discard, os.Stdin)
+		os.Exit(0)
+	}()
+
+	buf := make([]byte, maxMessage)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return err
+		}
+
+		var req request
+		var resp response
+		var files []*os.File
+
+		if err := json.Unmarshal(buf[:n], &req); err != nil {
+			resp.Error = fmt.Sprintf("invalid request: %v", err)
+		} else {
+			files, err = handle(req)
+			if err != nil {
+				resp.Error = err.Error()
+			} else {
+				resp.Success = true
+			}
+		}
+
+		var rights []byte
+		if len(files) > 0 {
+			fds := make([]int, len(files))
+			for i, f := range files {
+				fds[i] = int(f.Fd())
+			}
+			rights = syscall.UnixRights(fds...)
+		}
+
+		data, _ := json.Marshal(resp)
+		if _, _, err := conn.WriteMsgUnix(data, rights, nil); err != nil {
+			log.Printf("Helper: failed to reply: %v", err)
+		}
+
+		// The daemon received its own copies of the sockets
+		for _, f := range files {
+			f.Close()
+		}
+	}
+}
+
+// handle performs one privileged action, returning the sockets to pass
+// back for "bind"
+func handle(req request) ([]*os.File, error) {
+	switch req.Action {
+	case "set_dns":
+		if err := checkServers(req.Servers); err != nil {
+			return nil, err
+		}
+		policy := &config.Config{ManagedInterfaces: req.Managed, IgnoredInterfaces: req.Ignored}
+		return nil, system.SetDNS(req.Servers, policy.ManagesInterface)
+
+	case "add_dns_links":
+		if err := checkServers(req.Servers); err != nil {
+			return nil, err
+		}
+		return nil, system.AddDNSLinks(req.Servers, req.Links)
+
+	case "set_split_dns":
+		return nil, system.SetSplitDNS(req.Domains)
+
+	case "reset_dns":
+		return nil, system.ResetDNS()
+
+	case "restore_network":
+		return nil, system.RestoreNetwork()
+
+	case "block_outside_dns":
+		return nil, system.BlockOutsideDNS(req.UID, req.Domains)
+
+	case "unblock_outside_dns":
+		return nil, system.UnblockOutsideDNS()
+
+	case "bind":
+		return bindDNS(req.Addr)
+
+	default:
+		return nil, fmt.Errorf("unknown action: %s", req.Action)
+	}
+}
+
+// checkServers makes sure the daemon asked for DNS servers that are IP
+// addresses
+func checkServers(servers []string) error {
+	if len(servers) == 0 {
+		return fmt.Errorf("no DNS servers given")
+	}
+	for _, server := range servers {
+		if net.ParseIP(server) == nil {
+			return fmt.Errorf("invalid DNS server: %q", server)
+		}
+	}
+	return nil
+}
+
+// bindDNS binds UDP and TCP on a loopback port-53 address and returns
+// the sockets as files
+func bindDNS(addr string) ([]*os.File, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() || port != "53" {
+		return nil, fmt.Errorf("refusing to bind %s", addr)
+	}
+
+	// File() duplicates the descriptors, so the listeners themselves can
+	// be closed right away
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer pc.Close()
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	udpFile, err := pc.(*net.UDPConn).File()
+	if err != nil {
+		return nil, err
+	}
+
+	tcpFile, err := l.(*net.TCPListener).File()
+	if err != nil {
+		udpFile.Close()
+		return nil, err
+	}
+
+	return []*os.File{udpFile, tcpFile}, nil
+}
+
+// DropPrivileges switches the process to the given user and its primary
+// group, dropping all supplementary groups
+func DropPrivileges(username string) error {
+	uid, gid, err := LookupUser(username)
+	if err != nil {
+		return err
+	}
+
+	if err := syscall.Setgroups([]int{}); err != nil {
+		return fmt.Errorf("setgroups failed: %w", err)
+	}
+	if err := syscall.Setgid(gid); err != nil {
+		return fmt.Errorf("setgid failed: %w", err)
+	}
+	if err := syscall.Setuid(uid); err != nil {
+		return fmt.Errorf("setuid failed: %w", err)
+	}
+	return nil
+}
+
+// LookupUser returns the numeric uid and gid of a user
+func LookupUser(username string) (uid, gid int, err error) {
+	u, err := user.Lookup(username)
+	if err != nil {
+		return 0, 0, err
+	}
+	if uid, err = strconv.Atoi(u.Uid); err != nil {
+		return 0, 0, err
+	}
+	if gid, err = strconv.Atoi(u.Gid); err != nil {
+		return 0, 0, err
+	}
+	return uid, gid, nil
+}
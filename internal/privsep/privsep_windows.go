@@ -0,0 +1,82 @@
+//go:build windows
+
+package privsep
+
+import (
+	"errors"
+	"net"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/system"
+)
+
+// errUnsupported is returned by every function on Windows, where the
+// daemon runs as a service account and keeps its privileges
+var errUnsupported = errors.New("privilege separation is not supported on Windows")
+
+// Helper is the daemon's handle on the privileged helper process
+type Helper struct{}
+
+// StartHelper launches the privileged helper
+func StartHelper() (*Helper, error) {
+	return nil, errUnsupported
+}
+
+// SetDNS points system DNS at servers on the selected interfaces
+func (h *Helper) SetDNS(servers []string, managed, ignored []string) error {
+	return errUnsupported
+}
+
+// AddDNSLinks points more links at servers
+func (h *Helper) AddDNSLinks(servers, links []string) error {
+	return errUnsupported
+}
+
+// SetSplitDNS registers split DNS domains with the system resolver
+func (h *Helper) SetSplitDNS(domains []system.SplitDomain) error {
+	return errUnsupported
+}
+
+// ResetDNS restores the original system DNS settings
+func (h *Helper) ResetDNS() error {
+	return errUnsupported
+}
+
+// RestoreNetwork undoes all changes to system networking
+func (h *Helper) RestoreNetwork() error {
+	return errUnsupported
+}
+
+// BlockOutsideDNS blocks DNS from everything but the daemon
+func (h *Helper) BlockOutsideDNS(forwarders []system.SplitDomain) error {
+	return errUnsupported
+}
+
+// UnblockOutsideDNS removes the rules BlockOutsideDNS installed
+func (h *Helper) UnblockOutsideDNS() error {
+	return errUnsupported
+}
+
+// BindDNS has the helper bind UDP and TCP sockets on addr
+func (h *Helper) BindDNS(addr string) (net.PacketConn, net.Listener, error) {
+	return nil, nil, errUnsupported
+}
+
+// Close stops the helper
+func (h *Helper) Close() error {
+	return nil
+}
+
+// Serve runs the helper side
+func Serve() error {
+	return errUnsupported
+}
+
+// DropPrivileges switches the process to the given user
+func DropPrivileges(username string) error {
+	return errUnsupported
+}
+
+// LookupUser returns the numeric uid and gid of a user
+func LookupUser(username string) (uid, gid int, err error) {
+	return 0, 0, errUnsupported
+}
@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"os/user"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"text/template"
 )
 
@@ -18,6 +21,7 @@ Wants=nss-lookup.target
 [Service]
 Type=simple
 ExecStart={{.ExecPath}} daemon
+ExecReload=/bin/kill -HUP $MAINPID
 ExecStopPost={{.ExecPath}} dns-reset
 Restart=on-failure
 RestartSec=5
@@ -26,6 +30,45 @@ RestartSec=5
 WantedBy=multi-user.target
 `
 
+// dbusPolicy lets the daemon own its name on the system bus and anyone
+// call it; methods that change filtering are checked with polkit
+const dbusPolicy = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-BUS Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <policy user="root">
+    <allow own="io.filterdns.Client"/>
+  </policy>
+  <policy context="default">
+    <allow send_destination="io.filterdns.Client"/>
+  </policy>
+</busconfig>
+`
+
+// polkitPolicy lets active local users control filtering and asks
+// everyone else for admin credentials
+const polkitPolicy = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE policyconfig PUBLIC "-//freedesktop//DTD PolicyKit Policy Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/PolicyKit/1/policyconfig.dtd">
+<policyconfig>
+  <vendor>FilterDNS</vendor>
+  <action id="io.filterdns.client.control">
+    <description>Control DNS filtering</description>
+    <message>Authentication is required to change DNS filtering</message>
+    <defaults>
+      <allow_any>auth_admin</allow_any>
+      <allow_inactive>auth_admin</allow_inactive>
+      <allow_active>yes</allow_active>
+    </defaults>
+  </action>
+</policyconfig>
+`
+
+const (
+	dbusPolicyPath   = "/etc/dbus-1/system.d/io.filterdns.Client.conf"
+	polkitPolicyPath = "/usr/share/polkit-1/actions/io.filterdns.client.policy"
+)
+
 const launchdPlist = `<?xml version="1.0" encoding="UTF-8"?>
 <!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
 <plist version="1.0">
@@ -41,12 +84,21 @@ const launchdPlist = `<?xml version="1.0" encoding="UTF-8"?>
     <true/>
     <key>KeepAlive</key>
     <true/>
+    <key>StandardOutPath</key>
+    <string>{{.LogPath}}</string>
+    <key>StandardErrorPath</key>
+    <string>{{.LogPath}}</string>
 </dict>
 </plist>
 `
 
+// darwinLogPath receives the daemon's output on macOS, where launchd
+// would otherwise discard it
+const darwinLogPath = "/Library/Logs/filterdns-client.log"
+
 type Config struct {
 	ExecPath string
+	LogPath  string
 }
 
 // Install installs the service
@@ -101,48 +153,235 @@ func Stop() error {
 	}
 }
 
-// Status returns the service status
-func Status() (string, error) {
+// Restart stops and starts the service
+func Restart() error {
 	switch runtime.GOOS {
 	case "linux":
-		out, err := exec.Command("systemctl", "is-active", "filterdns-client").Output()
-		if err != nil {
-			return "not installed", nil
+		return runCmd("systemctl", "restart", "filterdns-client")
+	case "darwin":
+		return runCmd("launchctl", "kickstart", "-k", "system/io.filterdns.client")
+	default:
+		return fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+	}
+}
+
+// Enable makes the service start at boot
+func Enable() error {
+	switch runtime.GOOS {
+	case "linux":
+		return runCmd("systemctl", "enable", "filterdns-client")
+	case "darwin":
+		return runCmd("launchctl", "enable", "system/io.filterdns.client")
+	default:
+		return fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+	}
+}
+
+// Disable keeps the service from starting at boot; it keeps running until
+// stopped
+func Disable() error {
+	switch runtime.GOOS {
+	case "linux":
+		return runCmd("systemctl", "disable", "filterdns-client")
+	case "darwin":
+		return runCmd("launchctl", "disable", "system/io.filterdns.client")
+	default:
+		return fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+	}
+}
+
+// Logs prints the last lines of the service log, and with follow keeps
+// printing new ones until interrupted
+func Logs(lines int, follow bool) error {
+	n := strconv.Itoa(lines)
+	switch runtime.GOOS {
+	case "linux":
+		args := []string{"-u", "filterdns-client", "-n", n, "--no-pager"}
+		if follow {
+			args = append(args, "-f")
 		}
-		return string(out), nil
+		return runCmd("journalctl", args...)
 	case "darwin":
-		out, err := exec.Command("launchctl", "list", "io.filterdns.client").Output()
-		if err != nil {
-			return "not installed", nil
+		if _, err := os.Stat(darwinLogPath); err != nil {
+			// Installed before the plist redirected output; only the
+			// unified log has anything
+			if follow {
+				return runCmd("log", "stream", "--predicate", `process == "filterdnsd" OR process == "filterdns-client"`)
+			}
+			return runCmd("log", "show", "--last", "1h", "--predicate", `process == "filterdnsd" OR process == "filterdns-client"`)
+		}
+		args := []string{"-n", n}
+		if follow {
+			args = append(args, "-F")
 		}
-		return string(out), nil
+		return runCmd("tail", append(args, darwinLogPath)...)
 	default:
-		return "", fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+		return fmt.Errorf("unsupported OS: %s", runtime.GOOS)
 	}
 }
 
-func installLinux() error {
-	// Get current executable path
-	exe, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
+// RemoveLogs deletes the service's own log file on macOS. The systemd
+// journal cannot be cleared per unit and is left to its own rotation.
+func RemoveLogs() error {
+	if runtime.GOOS != "darwin" {
+		return nil
 	}
-	exe, err = filepath.EvalSymlinks(exe)
+	err := os.Remove(darwinLogPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// State describes the service as the service manager sees it
+// InstallElevated installs and starts the service from a desktop session,
+// asking for administrator rights through the system's own prompt
+// (polkit, the macOS password dialog, UAC). The binary the service will
+// run does the work through its install and service start commands.
+func InstallElevated() error {
+	install := []string{"install"}
+	// The prompts on macOS and Windows do not tell who asked
+	if u, err := user.Current(); err == nil && runtime.GOOS != "windows" {
+		install = append(install, "--owner", u.Username)
+	}
+	if err := runElevated(install, []string{"service", "start"}); err != nil {
+		return fmt.Errorf("install failed: %w", err)
+	}
+	return nil
+}
+
+// runElevated runs the daemon binary once per command, one after the
+// other, with administrator rights. On Windows only the first runs, as
+// the install command there also starts the service.
+func runElevated(commands ...[]string) error {
+	exe, err := daemonBinary()
 	if err != nil {
-		return fmt.Errorf("failed to resolve symlinks: %w", err)
+		return err
+	}
+	// The GUI-only binary has no CLI to run
+	if strings.HasPrefix(filepath.Base(exe), guiBinaryName) {
+		return fmt.Errorf("%s not found next to %s", daemonBinaryName, exe)
 	}
 
-	// Copy binary to /usr/bin
-	destPath := "/usr/bin/filterdns-client"
-	if exe != destPath {
-		input, err := os.ReadFile(exe)
+	var scripts []string
+	for _, args := range commands {
+		line := shellQuote(exe)
+		for _, arg := range args {
+			line += " " + shellQuote(arg)
+		}
+		scripts = append(scripts, line)
+	}
+	script := strings.Join(scripts, " && ")
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux":
+		cmd = exec.Command("pkexec", "sh", "-c", script)
+	case "darwin":
+		cmd = exec.Command("osascript", "-e",
+			fmt.Sprintf("do shell script %s with administrator privileges", appleScriptQuote(script)))
+	case "windows":
+		// UAC prompt; the elevated process has no console to report to,
+		// so only its exit code comes back
+		var args []string
+		for _, arg := range commands[0] {
+			args = append(args, powerShellQuote(arg))
+		}
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command",
+			fmt.Sprintf("$p = Start-Process -FilePath %s -ArgumentList %s -Verb RunAs -Wait -PassThru; exit $p.ExitCode",
+				powerShellQuote(exe), strings.Join(args, ",")))
+	default:
+		return fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if detail := strings.TrimSpace(string(out)); detail != "" {
+			return fmt.Errorf("%w: %s", err, detail)
+		}
+		return err
+	}
+	return nil
+}
+
+// shellQuote quotes s for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// powerShellQuote quotes s as a PowerShell string literal
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// appleScriptQuote quotes s as an AppleScript string
+func appleScriptQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+type State struct {
+	Installed bool   `json:"installed"`
+	Running   bool   `json:"running"`
+	Enabled   bool   `json:"enabled"` // Starts at boot
+	Detail    string `json:"detail,omitempty"`
+}
+
+// Status returns the service status
+func Status() (*State, error) {
+	switch runtime.GOOS {
+	case "linux":
+		out, err := exec.Command("systemctl", "show", "filterdns-client",
+			"-p", "LoadState", "-p", "ActiveState", "-p", "SubState", "-p", "UnitFileState").Output()
 		if err != nil {
-			return fmt.Errorf("failed to read binary: %w", err)
+			return nil, fmt.Errorf("systemctl show failed: %w", err)
 		}
-		if err := os.WriteFile(destPath, input, 0755); err != nil {
-			return fmt.Errorf("failed to copy binary to %s: %w", destPath, err)
+		props := make(map[string]string)
+		for _, line := range strings.Split(string(out), "\n") {
+			if key, value, ok := strings.Cut(line, "="); ok {
+				props[key] = value
+			}
 		}
-		fmt.Printf("Installed binary to %s\n", destPath)
+		if props["LoadState"] == "not-found" {
+			return &State{}, nil
+		}
+		return &State{
+			Installed: true,
+			Running:   props["ActiveState"] == "active",
+			Enabled:   props["UnitFileState"] == "enabled",
+			Detail:    props["ActiveState"] + " (" + props["SubState"] + ")",
+		}, nil
+	case "darwin":
+		if _, err := os.Stat("/Library/LaunchDaemons/io.filterdns.client.plist"); err != nil {
+			return &State{}, nil
+		}
+		state := &State{Installed: true, Enabled: true, Detail: "not loaded"}
+		if out, err := exec.Command("launchctl", "print", "system/io.filterdns.client").Output(); err == nil {
+			for _, line := range strings.Split(string(out), "\n") {
+				if value, ok := strings.CutPrefix(strings.TrimSpace(line), "state = "); ok {
+					state.Running = value == "running"
+					state.Detail = value
+					break
+				}
+			}
+		}
+		if out, err := exec.Command("launchctl", "print-disabled", "system").Output(); err == nil {
+			for _, line := range strings.Split(string(out), "\n") {
+				line = strings.TrimSpace(line)
+				if strings.HasPrefix(line, `"io.filterdns.client" =>`) {
+					state.Enabled = !strings.HasSuffix(line, "disabled") && !strings.HasSuffix(line, "true")
+				}
+			}
+		}
+		return state, nil
+	default:
+		return nil, fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+	}
+}
+
+func installLinux() error {
+	// Copy binary to /usr/bin
+	destPath, err := installBinary("/usr/bin")
+	if err != nil {
+		return err
 	}
 
 	// Create systemd unit file
@@ -163,6 +402,14 @@ func installLinux() error {
 	}
 	fmt.Printf("Created systemd unit at %s\n", unitPath)
 
+	// D-Bus control interface; the daemon runs without it if these are missing
+	if err := os.WriteFile(dbusPolicyPath, []byte(dbusPolicy), 0644); err != nil {
+		fmt.Printf("Warning: failed to install D-Bus policy: %v\n", err)
+	}
+	if err := os.WriteFile(polkitPolicyPath, []byte(polkitPolicy), 0644); err != nil {
+		fmt.Printf("Warning: failed to install polkit policy: %v\n", err)
+	}
+
 	// Reload systemd and enable service
 	if err := runCmd("systemctl", "daemon-reload"); err != nil {
 		return err
@@ -180,33 +427,20 @@ func uninstallLinux() error {
 	runCmd("systemctl", "stop", "filterdns-client")
 	runCmd("systemctl", "disable", "filterdns-client")
 	os.Remove("/etc/systemd/system/filterdns-client.service")
+	os.Remove(dbusPolicyPath)
+	os.Remove(polkitPolicyPath)
 	runCmd("systemctl", "daemon-reload")
+	os.Remove("/usr/bin/" + daemonBinaryName)
 	os.Remove("/usr/bin/filterdns-client")
 	fmt.Println("Service uninstalled")
 	return nil
 }
 
 func installDarwin() error {
-	exe, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
-	}
-	exe, err = filepath.EvalSymlinks(exe)
-	if err != nil {
-		return fmt.Errorf("failed to resolve symlinks: %w", err)
-	}
-
 	// Copy binary to /usr/local/bin
-	destPath := "/usr/local/bin/filterdns-client"
-	if exe != destPath {
-		input, err := os.ReadFile(exe)
-		if err != nil {
-			return fmt.Errorf("failed to read binary: %w", err)
-		}
-		if err := os.WriteFile(destPath, input, 0755); err != nil {
-			return fmt.Errorf("failed to copy binary: %w", err)
-		}
-		fmt.Printf("Installed binary to %s\n", destPath)
+	destPath, err := installBinary("/usr/local/bin")
+	if err != nil {
+		return err
 	}
 
 	// Create launchd plist
@@ -222,7 +456,7 @@ func installDarwin() error {
 		return fmt.Errorf("failed to parse template: %w", err)
 	}
 
-	if err := tmpl.Execute(f, Config{ExecPath: destPath}); err != nil {
+	if err := tmpl.Execute(f, Config{ExecPath: destPath, LogPath: darwinLogPath}); err != nil {
 		return fmt.Errorf("failed to write plist: %w", err)
 	}
 	fmt.Printf("Created launchd plist at %s\n", plistPath)
@@ -235,6 +469,7 @@ func installDarwin() error {
 func uninstallDarwin() error {
 	runCmd("launchctl", "unload", "/Library/LaunchDaemons/io.filterdns.client.plist")
 	os.Remove("/Library/LaunchDaemons/io.filterdns.client.plist")
+	os.Remove("/usr/local/bin/" + daemonBinaryName)
 	os.Remove("/usr/local/bin/filterdns-client")
 	fmt.Println("Service uninstalled")
 	return nil
@@ -248,6 +483,60 @@ func uninstallWindows() error {
 	return fmt.Errorf("Windows service uninstallation not yet implemented")
 }
 
+// daemonBinaryName is the GUI-free daemon/CLI binary built from cmd/filterdnsd
+const daemonBinaryName = "filterdnsd"
+
+// guiBinaryName is the daemon-free GUI binary built from cmd/filterdns-gui
+const guiBinaryName = "filterdns-gui"
+
+// daemonBinary returns the binary the service should run: filterdnsd if we
+// are it or it sits next to us, otherwise the current (all-in-one) binary
+func daemonBinary() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to get executable path: %w", err)
+	}
+	exe, err = filepath.EvalSymlinks(exe)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve symlinks: %w", err)
+	}
+
+	if filepath.Base(exe) == daemonBinaryName {
+		return exe, nil
+	}
+	sibling := filepath.Join(filepath.Dir(exe), daemonBinaryName)
+	if _, err := os.Stat(sibling); err == nil {
+		return sibling, nil
+	}
+	return exe, nil
+}
+
+// installBinary copies the daemon binary into destDir and returns its new path
+func installBinary(destDir string) (string, error) {
+	exe, err := daemonBinary()
+	if err != nil {
+		return "", err
+	}
+
+	name := daemonBinaryName
+	if filepath.Base(exe) != daemonBinaryName {
+		name = "filterdns-client"
+	}
+	destPath := filepath.Join(destDir, name)
+
+	if exe != destPath {
+		input, err := os.ReadFile(exe)
+		if err != nil {
+			return "", fmt.Errorf("failed to read binary: %w", err)
+		}
+		if err := os.WriteFile(destPath, input, 0755); err != nil {
+			return "", fmt.Errorf("failed to copy binary to %s: %w", destPath, err)
+		}
+		fmt.Printf("Installed binary to %s\n", destPath)
+	}
+	return destPath, nil
+}
+
 func runCmd(name string, args ...string) error {
 	cmd := exec.Command(name, args...)
 	cmd.Stdout = os.Stdout
@@ -0,0 +1,311 @@
+// Package stats keeps query and block counters across daemon restarts.
+//
+// The proxy's own counters start from zero with every proxy; the daemon
+// periodically adds what they gained to a Store, which keeps a running
+// total in a small JSON file. History is kept round-robin style at three
+// resolutions - per minute, per hour and per day - each for a limited
+// time, so the file stays small however long the daemon runs.
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Resolutions of the kept history
+const (
+	Minutely = time.Minute
+	Hourly   = time.Hour
+	Daily    = 24 * time.Hour
+)
+
+// Retention is how long each resolution of history is kept
+type Retention struct {
+	Minutes time.Duration
+	Hours   time.Duration
+	Days    time.Duration
+}
+
+// DefaultRetention keeps minutes for a day, hours for a month and days
+// for a year
+var DefaultRetention = Retention{
+	Minutes: 24 * time.Hour,
+	Hours:   30 * Daily,
+	Days:    365 * Daily,
+}
+
+// dateFormat is the layout of Day.Date
+const dateFormat = "2006-01-02"
+
+// Counts are query and block counters
+type Counts struct {
+	Queries int64 `json:"queries"`
+	Blocked int64 `json:"blocked"`
+}
+
+// Add returns the sum of two counts
+func (c Counts) Add(other Counts) Counts {
+	return Counts{Queries: c.Queries + other.Queries, Blocked: c.Blocked + other.Blocked}
+}
+
+// Day holds the counts of one local calendar day
+type Day struct {
+	Date string `json:"date"` // e.g. "2024-05-01"
+	Counts
+}
+
+// Bucket holds the counts of one minute, hour or day of history
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Counts
+}
+
+// Summary breaks the counters down for display
+type Summary struct {
+	Today Counts    `json:"today"`
+	Week  Counts    `json:"week"` // Today and the 6 days before
+	Total Counts    `json:"total"`
+	Since time.Time `json:"since"` // When counting started
+	Days  []Day     `json:"days,omitempty"`
+}
+
+// Store is the on-disk counter store
+type Store struct {
+	path string
+
+	mu        sync.Mutex
+	data      storeData
+	dirty     bool
+	retention Retention
+}
+
+// storeData is the file format. All history is oldest first.
+type storeData struct {
+	Total   Counts    `json:"total"`
+	Since   time.Time `json:"since"`
+	Days    []Day     `json:"days"`
+	Minutes []Bucket  `json:"minutes,omitempty"`
+	Hours   []Bucket  `json:"hours,omitempty"`
+}
+
+// Open loads the store at path, starting empty if it does not exist
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, retention: DefaultRetention}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		s.data.Since = time.Now()
+		return s, nil
+	}
+
+	if err := json.Unmarshal(data, &s.data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Add counts queries and blocked queries at the given time
+func (s *Store) Add(counts Counts, at time.Time) {
+	if counts == (Counts{}) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Total = s.data.Total.Add(counts)
+
+	s.data.Minutes = addBucket(s.data.Minutes, at.Truncate(Minutely), counts)
+	s.data.Hours = addBucket(s.data.Hours, at.Truncate(Hourly), counts)
+
+	date := at.Format(dateFormat)
+	if n := len(s.data.Days); n > 0 && s.data.Days[n-1].Date == date {
+		s.data.Days[n-1].Counts = s.data.Days[n-1].Counts.Add(counts)
+	} else {
+		s.data.Days = append(s.data.Days, Day{Date: date, Counts: counts})
+	}
+
+	s.prune(at)
+	s.dirty = true
+}
+
+// SetRetention changes how long history is kept. Shorter retention
+// drops older history the next time counts are added.
+func (s *Store) SetRetention(retention Retention) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retention = retention
+}
+
+// addBucket adds counts to the bucket starting at start, which is the
+// last one or a new one
+func addBucket(buckets []Bucket, start time.Time, counts Counts) []Bucket {
+	if n := len(buckets); n > 0 && buckets[n-1].Start.Equal(start) {
+		buckets[n-1].Counts = buckets[n-1].Counts.Add(counts)
+		return buckets
+	}
+	return append(buckets, Bucket{Start: start, Counts: counts})
+}
+
+// prune drops history older than the retention (must be called with lock held)
+func (s *Store) prune(now time.Time) {
+	s.data.Minutes = dropBefore(s.data.Minutes, now.Add(-s.retention.Minutes))
+	s.data.Hours = dropBefore(s.data.Hours, now.Add(-s.retention.Hours))
+
+	// Dates sort lexically
+	oldest := now.AddDate(0, 0, -int(s.retention.Days/Daily)).Format(dateFormat)
+	for len(s.data.Days) > 0 && s.data.Days[0].Date <= oldest {
+		s.data.Days = s.data.Days[1:]
+	}
+}
+
+// dropBefore drops the buckets that start before t
+func dropBefore(buckets []Bucket, t time.Time) []Bucket {
+	for len(buckets) > 0 && buckets[0].Start.Before(t) {
+		buckets = buckets[1:]
+	}
+	return buckets
+}
+
+// Summary returns today's, this week's and the total counts, plus the
+// given number of most recent days. pending are counts not added yet,
+// which are attributed to today.
+func (s *Store) Summary(pending Counts, days int) *Summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	today := now.Format(dateFormat)
+	weekStart := now.AddDate(0, 0, -6).Format(dateFormat)
+
+	summary := &Summary{
+		Today: pending,
+		Week:  pending,
+		Total: s.data.Total.Add(pending),
+		Since: s.data.Since,
+	}
+
+	for _, day := range s.data.Days {
+		if day.Date == today {
+			summary.Today = summary.Today.Add(day.Counts)
+		}
+		// Dates sort lexically
+		if day.Date >= weekStart {
+			summary.Week = summary.Week.Add(day.Counts)
+		}
+	}
+
+	if days > 0 {
+		start := max(len(s.data.Days)-days, 0)
+		summary.Days = append([]Day(nil), s.data.Days[start:]...)
+		if pending != (Counts{}) {
+			if n := len(summary.Days); n > 0 && summary.Days[n-1].Date == today {
+				summary.Days[n-1].Counts = summary.Days[n-1].Counts.Add(pending)
+			} else {
+				summary.Days = append(summary.Days, Day{Date: today, Counts: pending})
+			}
+		}
+	}
+
+	return summary
+}
+
+// Period returns the resolution and start of a named display period:
+// "hour" and "day" by minute and hour, "week", "month" and "year" by day
+func Period(name string, now time.Time) (resolution time.Duration, since time.Time, err error) {
+	switch name {
+	case "hour":
+		return Minutely, now.Add(-time.Hour), nil
+	case "day":
+		return Hourly, now.Add(-24 * time.Hour), nil
+	case "week":
+		return Daily, now.AddDate(0, 0, -6), nil
+	case "month":
+		return Daily, now.AddDate(0, 0, -29), nil
+	case "year":
+		return Daily, now.AddDate(0, 0, -364), nil
+	default:
+		return 0, time.Time{}, fmt.Errorf("unknown period %q (hour, day, week, month or year)", name)
+	}
+}
+
+// History returns the buckets of the given resolution (Minutely, Hourly
+// or Daily) from the one containing since on, oldest first. pending are
+// counts not added yet, which are attributed to the current bucket.
+func (s *Store) History(pending Counts, resolution time.Duration, since time.Time) []Bucket {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var buckets []Bucket
+	var current time.Time
+
+	switch resolution {
+	case Minutely:
+		buckets = append(buckets, s.data.Minutes...)
+		current = now.Truncate(Minutely)
+		since = since.Truncate(Minutely)
+	case Hourly:
+		buckets = append(buckets, s.data.Hours...)
+		current = now.Truncate(Hourly)
+		since = since.Truncate(Hourly)
+	default:
+		for _, day := range s.data.Days {
+			start, err := time.ParseInLocation(dateFormat, day.Date, time.Local)
+			if err == nil {
+				buckets = append(buckets, Bucket{Start: start, Counts: day.Counts})
+			}
+		}
+		current = startOfDay(now)
+		since = startOfDay(since)
+	}
+
+	buckets = dropBefore(buckets, since)
+	if pending != (Counts{}) {
+		buckets = addBucket(buckets, current, pending)
+	}
+	return buckets
+}
+
+// startOfDay returns local midnight of t's day
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// Save writes the store to disk if anything changed since the last save
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write and rename so a crash never leaves a truncated file behind
+	tmp := s.path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+
+	s.dirty = false
+	return nil
+}
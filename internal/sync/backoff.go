@@ -0,0 +1,130 @@
+package sync
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+)
+
+const (
+	// maxBackoff caps the wait between polls after failures. Offline, the
+	// syncer waits this long straight away, as NetworkChanged wakes it
+	// once the network is back.
+	maxBackoff = 10 * time.Minute
+
+	// networkSettle is how long to wait after a network change before
+	// syncing, as interfaces come up in bursts
+	networkSettle = 2 * time.Second
+
+	// failureLogEvery limits how often the same sync error is logged
+	failureLogEvery = time.Hour
+)
+
+// Attempts summarises recent syncs for status displays
+type Attempts struct {
+	Last        time.Time // When the last sync finished
+	LastErr     error     // Why it failed, if it did
+	LastSuccess time.Time // Zero until a sync worked
+	Next        time.Time // When the next poll is due
+	Failures    int       // Failed syncs in a row
+	Offline     bool      // The last failure looked like no network at all
+}
+
+// Attempts returns how recent syncs went and when the next one is due
+func (s *Syncer) Attempts() Attempts {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Attempts{
+		Last:        s.lastAttempt,
+		LastErr:     s.lastErr,
+		LastSuccess: s.lastSuccess,
+		Next:        s.next,
+		Failures:    s.failures,
+		Offline:     s.offline,
+	}
+}
+
+// NetworkChanged has the syncer try again shortly, without waiting out
+// its backoff if it was offline, e.g. once Wi-Fi reconnected
+func (s *Syncer) NetworkChanged() {
+	for _, wake := range []chan struct{}{s.wake, s.wakePush} {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// record notes the outcome of a sync (must be called with s.mu held)
+func (s *Syncer) record(err error) {
+	s.lastAttempt = time.Now()
+	s.lastErr = err
+	if err == nil {
+		if s.failures > 0 {
+			log.Printf("Sync working again after %d failed attempts", s.failures)
+		}
+		s.lastSuccess = s.lastAttempt
+		s.failures = 0
+		s.offline = false
+		s.loggedErr = ""
+		return
+	}
+	s.failures++
+	s.offline = isOffline(err)
+}
+
+// delay is how long to wait before the next poll: the interval while
+// syncs work, growing exponentially to maxBackoff while they fail, each
+// with jitter so clients do not poll in step (must be called with s.mu
+// held)
+func (s *Syncer) delay() time.Duration {
+	switch {
+	case s.failures == 0:
+		// ±10%
+		return s.interval - s.interval/10 + time.Duration(rand.Int63n(int64(s.interval/5)+1))
+	case s.offline:
+		return maxBackoff
+	}
+	d := s.interval << min(s.failures, 10)
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	}
+	// Anywhere between half and all of it
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// logFailure logs a failed poll when it is the first, the error changed
+// or the last report is a while ago, so a captive portal does not fill
+// the log
+func (s *Syncer) logFailure(err error) {
+	s.mu.Lock()
+	msg := err.Error()
+	repeat := msg == s.loggedErr && time.Since(s.loggedAt) < failureLogEvery
+	if !repeat {
+		s.loggedErr, s.loggedAt = msg, time.Now()
+	}
+	failures, offline := s.failures, s.offline
+	s.mu.Unlock()
+
+	switch {
+	case repeat:
+	case offline:
+		log.Printf("Sync failed, network looks offline (%d attempts): %v", failures, err)
+	default:
+		log.Printf("Sync failed (%d attempts): %v", failures, err)
+	}
+}
+
+// isOffline reports whether err means there is no network to reach the
+// server over: its name does not resolve or no route leads there. A
+// server that refuses or answers wrongly is down, not offline.
+func isOffline(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) ||
+		errors.Is(err, syscall.ENETUNREACH) ||
+		errors.Is(err, syscall.EHOSTUNREACH) ||
+		errors.Is(err, syscall.ENETDOWN)
+}
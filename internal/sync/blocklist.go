@@ -0,0 +1,48 @@
+package sync
+
+import "strconv"
+
+// BlocklistChanges lists the domains whose verdict changed between two
+// versions of a profile's block lists, for servers that track them
+type BlocklistChanges struct {
+	Since   string   `json:"since"`   // The blocklist_version the changes apply to
+	Domains []string `json:"domains"` // Blocked or unblocked since then
+}
+
+// ChangeCallback is called when the profile's block lists change on the
+// server, so cached answers can be dropped: those for domains, or all of
+// them if domains is nil
+type ChangeCallback func(domains []string)
+
+// OnBlocklist has the syncer report changes to the profile's block lists
+// to callback, as seen in the sync response or announced by a pushed
+// event. Call it before Start.
+func (s *Syncer) OnBlocklist(callback ChangeCallback) {
+	s.onBlocklist = callback
+}
+
+// BlocklistVersion identifies the state of the profile's block lists:
+// blocklist_version if the server sends one, else the number of lists
+func (r *SyncResponse) BlocklistVersion() string {
+	if r.Profile.BlocklistVersion != "" {
+		return r.Profile.BlocklistVersion
+	}
+	return "count:" + strconv.Itoa(r.Profile.BlocklistCount)
+}
+
+// blocklistChange compares the block lists in two sync responses. It
+// reports whether they changed and, if the server said which domains
+// changed since prev's version, those domains.
+func blocklistChange(prev, cur *SyncResponse) (changed bool, domains []string) {
+	if prev == nil || prev.BlocklistVersion() == cur.BlocklistVersion() {
+		return false, nil
+	}
+	if c := cur.Profile.BlocklistChanges; c != nil && c.Since != "" && c.Since == prev.Profile.BlocklistVersion {
+		// An empty list still means only a few domains changed: none
+		domains = c.Domains
+		if domains == nil {
+			domains = []string{}
+		}
+	}
+	return true, domains
+}
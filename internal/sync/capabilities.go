@@ -0,0 +1,88 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+)
+
+// CapabilitiesResponse from /api/client/capabilities
+type CapabilitiesResponse struct {
+	ServerVersion string   `json:"server_version"`
+	Features      []string `json:"features"` // e.g. "sync", "onboarding", "ede", "push", "leaktest", "rules", "resume"
+}
+
+// ProbeCapabilities asks the server which client API features it supports.
+// Servers that predate the capabilities document are probed endpoint by
+// endpoint; profile is used to check the sync endpoint and may be empty.
+func ProbeCapabilities(serverURL, profile string) (*config.ServerCapabilities, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	resp, err := client.Get(serverURL + "/api/client/capabilities")
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	caps := &config.ServerCapabilities{
+		ServerURL: serverURL,
+		CheckedAt: time.Now(),
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var capsResp CapabilitiesResponse
+		if err := json.NewDecoder(resp.Body).Decode(&capsResp); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		caps.ServerVersion = capsResp.ServerVersion
+		for _, feature := range capsResp.Features {
+			switch feature {
+			case "sync":
+				caps.Sync = true
+			case "onboarding":
+				caps.Onboarding = true
+			case "ede":
+				caps.EDE = true
+			case "push":
+				caps.Push = true
+			case "leaktest":
+				caps.LeakTest = true
+			case "rules":
+				caps.Rules = true
+			case "resume":
+				caps.Resume = true
+			}
+		}
+
+	case http.StatusNotFound:
+		// Older server: onboarding has always been there, EDE and push
+		// have not, and sync depends on the version
+		caps.Onboarding = true
+		if profile != "" {
+			caps.Sync, err = endpointExists(client, fmt.Sprintf("%s/api/client/sync/%s", serverURL, profile))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	return caps, nil
+}
+
+// endpointExists reports whether a GET on url is answered with anything
+// other than 404
+func endpointExists(client *http.Client, url string) (bool, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return false, fmt.Errorf("request failed: %w", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode != http.StatusNotFound, nil
+}
@@ -0,0 +1,153 @@
+package sync
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// MaxClockSkew is how far the local clock may be off from the server's
+// before status and notifications warn. Much further off, certificates
+// look not yet or no longer valid, which breaks TLS to the DoH server.
+const MaxClockSkew = 5 * time.Minute
+
+// SkewCallback is called with the clock skew measured by each sync
+type SkewCallback func(skew time.Duration)
+
+// ClockCheck is what MeasureClock found out about the local clock
+type ClockCheck struct {
+	Skew      time.Duration `json:"skew"`                // Local clock ahead of the server's, negative if behind
+	CertValid bool          `json:"certValid"`           // Server certificate valid at local time
+	NotBefore *time.Time    `json:"notBefore,omitempty"` // Server certificate validity, for https servers
+	NotAfter  *time.Time    `json:"notAfter,omitempty"`
+}
+
+// OnClockSkew has the syncer report how far the local clock is off from
+// the server's after each sync. Call it before Start.
+func (s *Syncer) OnClockSkew(callback SkewCallback) {
+	s.onSkew = callback
+}
+
+// ClockSkew returns how far the local clock was ahead of the server's at
+// the last sync, negative if behind, and whether that is known
+func (s *Syncer) ClockSkew() (time.Duration, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.skew, s.skewKnown
+}
+
+// recordSkew notes the clock skew seen in a sync response: against
+// synced_at if the response carried it, else its Date header
+func (s *Syncer) recordSkew(resp *http.Response, syncedAt string, sent, received time.Time) {
+	server, err := time.Parse(time.RFC3339, syncedAt)
+	if err != nil {
+		if server, err = http.ParseTime(resp.Header.Get("Date")); err != nil {
+			return
+		}
+	}
+	s.setSkew(skewOf(server, sent, received))
+}
+
+// setSkew notes a measured clock skew and reports it
+func (s *Syncer) setSkew(skew time.Duration) {
+	s.mu.Lock()
+	s.skew, s.skewKnown = skew, true
+	s.mu.Unlock()
+
+	if s.onSkew != nil {
+		s.onSkew(skew)
+	}
+}
+
+// measureSkew finds out how far the clock is off after a sync failed on
+// a certificate that looks expired or not yet valid: the failed sync got
+// no answer to measure against, and a wrong clock is the likely cause
+func (s *Syncer) measureSkew(err error) {
+	if !certTimeInvalid(err) {
+		return
+	}
+	check, err := MeasureClock(s.serverURL)
+	if err != nil {
+		return
+	}
+	s.setSkew(check.Skew)
+}
+
+// skewOf is how far the local clock is ahead of serverTime, taking the
+// server to have answered halfway through the request
+func skewOf(serverTime, sent, received time.Time) time.Duration {
+	return sent.Add(received.Sub(sent) / 2).Sub(serverTime)
+}
+
+// clockHint explains a certificate the local clock sees as expired or
+// not yet valid, which more often means a wrong clock than a bad server
+func clockHint(err error) error {
+	if certTimeInvalid(err) {
+		return fmt.Errorf("%w (this computer's clock says %s; check that it is right)",
+			err, time.Now().Format(time.RFC3339))
+	}
+	return err
+}
+
+// certTimeInvalid reports whether err is a certificate that is expired or
+// not yet valid at local time
+func certTimeInvalid(err error) bool {
+	var invalid x509.CertificateInvalidError
+	return errors.As(err, &invalid) && invalid.Reason == x509.Expired
+}
+
+// MeasureClock compares the local clock with the server's and checks the
+// server certificate's validity at local time. The certificate chain and
+// name are verified as of a time within its validity, so a wrong clock
+// does not stop the check itself.
+func MeasureClock(serverURL string) (*ClockCheck, error) {
+	check := &ClockCheck{CertValid: true}
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			// Verified in VerifyConnection, ignoring the clock
+			InsecureSkipVerify: true,
+			VerifyConnection: func(state tls.ConnectionState) error {
+				certs := state.PeerCertificates
+				if len(certs) == 0 {
+					return errors.New("server sent no certificate")
+				}
+				leaf := certs[0]
+				opts := x509.VerifyOptions{
+					DNSName:       state.ServerName,
+					Intermediates: x509.NewCertPool(),
+					CurrentTime:   leaf.NotBefore.Add(leaf.NotAfter.Sub(leaf.NotBefore) / 2),
+				}
+				for _, cert := range certs[1:] {
+					opts.Intermediates.AddCert(cert)
+				}
+				if _, err := leaf.Verify(opts); err != nil {
+					return err
+				}
+
+				now := time.Now()
+				check.NotBefore, check.NotAfter = &leaf.NotBefore, &leaf.NotAfter
+				check.CertValid = now.After(leaf.NotBefore) && now.Before(leaf.NotAfter)
+				return nil
+			},
+		}},
+	}
+
+	sent := time.Now()
+	resp, err := client.Get(serverURL + "/api/client/capabilities")
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	received := time.Now()
+	resp.Body.Close()
+
+	server, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return nil, errors.New("server sent no Date header")
+	}
+	check.Skew = skewOf(server, sent, received)
+	return check, nil
+}
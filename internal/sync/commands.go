@@ -0,0 +1,119 @@
+package sync
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Actions of a Command
+const (
+	CommandFlush         = "flush_cache"    // Drop cached answers
+	CommandPause         = "pause"          // Pause filtering for Minutes
+	CommandSwitchProfile = "switch_profile" // Switch to the saved profile named Profile
+	CommandUpdate        = "update"         // Install the latest signed release
+)
+
+// maxDoneCommands bounds how many command IDs the syncer remembers, so
+// commands the server sends again before seeing the ack run only once
+const maxDoneCommands = 256
+
+// Command is an action an admin queued in the web UI for the profile's
+// devices, delivered in SyncResponse
+type Command struct {
+	ID      string `json:"id"`
+	Action  string `json:"action"`
+	Minutes int    `json:"minutes,omitempty"` // For CommandPause
+	Profile string `json:"profile,omitempty"` // For CommandSwitchProfile
+}
+
+// CommandFunc carries out a command from the server; the error is
+// reported back to it
+type CommandFunc func(cmd Command) error
+
+// commandAck is posted to /api/client/commands/<profile>/<id> once a
+// command ran
+type commandAck struct {
+	Status string `json:"status"` // "done" or "failed"
+	Error  string `json:"error,omitempty"`
+}
+
+// OnCommand has the syncer hand commands from the server to fn. Without
+// it, commands are acknowledged as failed. Call it before Start.
+func (s *Syncer) OnCommand(fn CommandFunc) {
+	s.onCommand = fn
+}
+
+// runCommands carries out the commands not seen before, in order, and
+// acknowledges each
+func (s *Syncer) runCommands(commands []Command) {
+	for _, cmd := range commands {
+		if cmd.ID == "" || !s.markDone(cmd.ID) {
+			continue
+		}
+
+		var err error
+		switch {
+		case !strings.HasPrefix(s.serverURL, "https://"):
+			// Anyone on the path could have queued it
+			err = errors.New("commands are only taken from https servers")
+		case s.onCommand != nil:
+			err = s.onCommand(cmd)
+		default:
+			err = fmt.Errorf("this client does not take commands")
+		}
+		if err != nil {
+			log.Printf("Command %s (%s) from the server failed: %v", cmd.ID, cmd.Action, err)
+		}
+		if err := s.ack(cmd, err); err != nil {
+			log.Printf("Failed to acknowledge command %s: %v", cmd.ID, err)
+		}
+	}
+}
+
+// markDone records a command ID, and reports whether it was new
+func (s *Syncer) markDone(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.Contains(s.doneCommands, id) {
+		return false
+	}
+	s.doneCommands = append(s.doneCommands, id)
+	if len(s.doneCommands) > maxDoneCommands {
+		s.doneCommands = s.doneCommands[1:]
+	}
+	return true
+}
+
+// ack tells the server how a command went
+func (s *Syncer) ack(cmd Command, cmdErr error) error {
+	ack := commandAck{Status: "done"}
+	if cmdErr != nil {
+		ack = commandAck{Status: "failed", Error: cmdErr.Error()}
+	}
+	body, err := json.Marshal(ack)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	url := fmt.Sprintf("%s/api/client/commands/%s/%s", s.serverURL, s.profileName, neturl.PathEscape(cmd.ID))
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"strings"
+)
+
+// deltaType is the Content-Type of a sync response that is a JSON Merge
+// Patch (RFC 7396) against the state the request named in If-None-Match
+const deltaType = "application/merge-patch+json"
+
+// syncBase is the last full sync state with the validators the server
+// sent for it, so unchanged state costs a 304 and changed state can come
+// as a delta
+type syncBase struct {
+	etag         string
+	lastModified string
+	doc          map[string]any
+}
+
+// conditional makes a GET req conditional on the last full state, and
+// returns that state for a delta to apply to. A POST carrying a heartbeat
+// stays unconditional: servers answer a matching one with 412, and
+// the heartbeat must get through either way.
+func (s *Syncer) conditional(req *http.Request) *syncBase {
+	s.mu.RLock()
+	base := s.base
+	s.mu.RUnlock()
+
+	req.Header.Set("Accept", "application/json")
+	if base == nil || req.Method != http.MethodGet {
+		return nil
+	}
+	if base.etag != "" {
+		req.Header.Set("If-None-Match", base.etag)
+		req.Header.Set("Accept", "application/json, "+deltaType)
+	}
+	if base.lastModified != "" {
+		req.Header.Set("If-Modified-Since", base.lastModified)
+	}
+	return base
+}
+
+// decodeSync reads a 200 sync response, applying it to base if it is a
+// delta, and keeps the result as the base of the next request
+func (s *Syncer) decodeSync(resp *http.Response, base *syncBase) (*SyncResponse, error) {
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	if isDelta(resp) {
+		if base == nil {
+			return nil, errors.New("server sent a delta without a base state")
+		}
+		doc = mergePatch(maps.Clone(base.doc), doc)
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	var syncResp SyncResponse
+	if err := json.Unmarshal(data, &syncResp); err != nil {
+		return nil, fmt.Errorf("delta does not apply: %w", err)
+	}
+
+	s.mu.Lock()
+	s.base = &syncBase{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		doc:          doc,
+	}
+	s.mu.Unlock()
+	return &syncResp, nil
+}
+
+// isDelta reports whether a sync response is a merge patch
+func isDelta(resp *http.Response) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), deltaType)
+}
+
+// mergePatch applies a JSON Merge Patch to target: members set to null
+// are removed, objects are merged recursively and anything else replaces
+// the old value. Nested objects of target are copied before changing them.
+func mergePatch(target, patch map[string]any) map[string]any {
+	if target == nil {
+		target = map[string]any{}
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		sub, isObject := value.(map[string]any)
+		old, wasObject := target[key].(map[string]any)
+		if isObject && wasObject {
+			target[key] = mergePatch(maps.Clone(old), sub)
+		} else if isObject {
+			target[key] = mergePatch(nil, sub)
+		} else {
+			target[key] = value
+		}
+	}
+	return target
+}
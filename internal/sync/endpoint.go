@@ -0,0 +1,12 @@
+package sync
+
+// EndpointCallback is called with the DNS endpoint from SyncResponse.DNS
+// on the first sync and whenever it changes
+type EndpointCallback func(dohURL, dotHostname string)
+
+// OnEndpoint has the syncer report where the server serves the profile's
+// DNS to callback, so the proxy can follow it when it moves. Call it
+// before Start.
+func (s *Syncer) OnEndpoint(callback EndpointCallback) {
+	s.onEndpoint = callback
+}
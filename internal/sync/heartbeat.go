@@ -0,0 +1,91 @@
+package sync
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// Heartbeat is sent in the body of sync requests, so the dashboard can
+// show which devices are online and healthy. Only what the user agreed to
+// share is set; the rest is left out.
+type Heartbeat struct {
+	Name      string             `json:"name,omitempty"`           // Hostname
+	Version   string             `json:"client_version,omitempty"` // e.g. "1.4.0"
+	OS        string             `json:"os,omitempty"`             // e.g. "linux/arm64"
+	Filtering string             `json:"filtering,omitempty"`      // FilteringOn, FilteringOff or FilteringPaused
+	Counters  *HeartbeatCounters `json:"counters,omitempty"`
+	LastError string             `json:"last_error,omitempty"`
+}
+
+// HeartbeatCounters are the daemon's query counters since it started
+type HeartbeatCounters struct {
+	Queries int64 `json:"queries"`
+	Blocked int64 `json:"blocked"`
+}
+
+// Filtering states in Heartbeat
+const (
+	FilteringOn     = "on"
+	FilteringOff    = "off"
+	FilteringPaused = "paused"
+)
+
+// HeartbeatFunc returns the heartbeat for the next sync, or nil to send
+// none
+type HeartbeatFunc func() *Heartbeat
+
+// ReportHeartbeat has every sync send the heartbeat from fn. Servers that
+// do not take one keep being synced without it. Call it before Start.
+func (s *Syncer) ReportHeartbeat(fn HeartbeatFunc) {
+	s.heartbeat = fn
+}
+
+// syncRequest builds the sync request: a GET, or a POST carrying the
+// heartbeat if there is one to send
+func (s *Syncer) syncRequest(url string) (*http.Request, error) {
+	var hb *Heartbeat
+	if s.heartbeat != nil && !s.stateOnly && !s.noHeartbeat.Load() {
+		hb = s.heartbeat()
+	}
+	if hb == nil || *hb == (Heartbeat{}) {
+		return http.NewRequest(http.MethodGet, url, nil)
+	}
+
+	body, err := json.Marshal(hb)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// send makes the sync request, a GET conditional on the last state, and
+// repeats it without the heartbeat if the server does not take one. It
+// returns the state a delta in the response applies to.
+func (s *Syncer) send(client *http.Client, url string) (*http.Response, *syncBase, error) {
+	req, err := s.syncRequest(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	base := s.conditional(req)
+	resp, err := client.Do(req)
+	if err != nil || req.Method != http.MethodPost || resp.StatusCode != http.StatusMethodNotAllowed {
+		return resp, base, err
+	}
+	resp.Body.Close()
+
+	log.Println("Server does not take heartbeats, syncing without them")
+	s.noHeartbeat.Store(true)
+	if req, err = http.NewRequest(http.MethodGet, url, nil); err != nil {
+		return nil, nil, err
+	}
+	base = s.conditional(req)
+	resp, err = client.Do(req)
+	return resp, base, err
+}
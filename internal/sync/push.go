@@ -0,0 +1,196 @@
+package sync
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Events the server pushes on /api/client/events/<profile>, as
+// Server-Sent Events. Anything else is taken as a state change.
+const (
+	EventState     = "state"     // Filtering paused or resumed
+	EventBlocklist = "blocklist" // Block lists or rules of the profile changed
+	EventCommand   = "command"   // An admin queued a command, fetched with the state
+)
+
+const (
+	// pushRetryMin and pushRetryMax bound the wait before reconnecting
+	// the push channel; it doubles with every failed attempt, plus up to
+	// half of it again as jitter
+	pushRetryMin = time.Second
+	pushRetryMax = time.Minute
+
+	// pushIdle is how long the push channel may stay silent before it is
+	// taken for dead. Servers send a comment line at least every 30s.
+	pushIdle = 90 * time.Second
+
+	// pushPollInterval is how often the syncer still polls while the
+	// push channel is up, in case an event got lost
+	pushPollInterval = 5 * time.Minute
+)
+
+// ErrPushUnsupported is returned when the server has no event stream for
+// the profile
+var ErrPushUnsupported = errors.New("server has no event stream for this profile")
+
+// EnablePush makes the syncer follow the server's event stream, falling
+// back to polling while it is down. Call it before Start.
+func (s *Syncer) EnablePush() {
+	s.push = true
+}
+
+// Pushing reports whether the push channel is connected
+func (s *Syncer) Pushing() bool {
+	return s.pushing.Load()
+}
+
+// listen keeps the push channel connected until the syncer stops or the
+// server turns out not to have one
+func (s *Syncer) listen() {
+	retry := pushRetryMin
+	var lastErr string
+	for {
+		connected, err := s.stream()
+		if s.ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, ErrPushUnsupported) {
+			log.Printf("Push channel unavailable, polling every %s: %v", s.interval, err)
+			return
+		}
+		if connected {
+			retry = pushRetryMin
+			lastErr = ""
+		}
+		// Report each new problem once, not every retry
+		if err.Error() != lastErr {
+			log.Printf("Push channel down, polling until it is back: %v", err)
+			lastErr = err.Error()
+		}
+
+		// Offline, only a network change is worth a quick retry
+		if isOffline(err) {
+			retry = pushRetryMax
+		}
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.wakePush:
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(networkSettle):
+			}
+			retry = pushRetryMin
+			continue
+		case <-time.After(retry + time.Duration(rand.Int63n(int64(retry/2)+1))):
+		}
+		retry = min(2*retry, pushRetryMax)
+	}
+}
+
+// stream reads the event stream until it ends, and reports whether it got
+// connected at all
+func (s *Syncer) stream() (bool, error) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	url := fmt.Sprintf("%s/api/client/events/%s", s.serverURL, s.profileName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// No overall timeout: the response never ends by itself
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return false, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, ErrPushUnsupported
+	case resp.StatusCode != http.StatusOK:
+		return false, fmt.Errorf("server returned status %d", resp.StatusCode)
+	case !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream"):
+		return false, ErrPushUnsupported
+	}
+
+	s.pushing.Store(true)
+	defer s.pushing.Store(false)
+	log.Println("Push channel connected")
+
+	// Catch up on whatever happened while it was down
+	if err := s.doSync(); err != nil {
+		log.Printf("Sync failed: %v", err)
+	}
+
+	// A connection that went away without closing sends nothing more
+	idle := time.AfterFunc(pushIdle, cancel)
+	defer idle.Stop()
+
+	var event string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		idle.Reset(pushIdle)
+
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// A blank line ends the event
+			if event != "" {
+				s.handleEvent(event)
+			}
+			event = ""
+		case strings.HasPrefix(line, ":"):
+			// Comment, sent to keep the connection alive
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			switch field {
+			case "event":
+				event = strings.TrimPrefix(value, " ")
+			case "data":
+				if event == "" {
+					event = "message"
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return true, err
+	}
+	return true, errors.New("server closed the stream")
+}
+
+// handleEvent acts on a pushed event: every event fetches the profile
+// state, and block list changes the sync did not pick up drop all cached
+// answers
+func (s *Syncer) handleEvent(event string) {
+	var before string
+	if last := s.GetLastState(); last != nil {
+		before = last.BlocklistVersion()
+	}
+
+	err := s.doSync()
+	if err != nil {
+		log.Printf("Sync after %q event failed: %v", event, err)
+	}
+
+	if event != EventBlocklist || s.onBlocklist == nil {
+		return
+	}
+	// A server without blocklist_version may not change anything the
+	// sync compares
+	if last := s.GetLastState(); err != nil || last == nil || last.BlocklistVersion() == before {
+		s.onBlocklist(nil)
+	}
+}
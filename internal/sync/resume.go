@@ -0,0 +1,39 @@
+package sync
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ResumeProfile turns filtering back on for a profile paused in the web
+// UI, for every device using it. The daemon follows on its next sync.
+func ResumeProfile(serverURL, profile, password string) error {
+	req, err := http.NewRequest(http.MethodPost,
+		fmt.Sprintf("%s/api/client/resume/%s", serverURL, url.PathEscape(profile)), nil)
+	if err != nil {
+		return err
+	}
+	if password != "" {
+		req.Header.Set("X-FilterDNS-Password", password)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("server rejected the profile password")
+	case http.StatusNotFound:
+		return fmt.Errorf("server cannot resume profiles from clients; resume it in the web UI")
+	default:
+		return fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,93 @@
+package sync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+)
+
+// RuleRequest to /api/client/rules/<profile>
+type RuleRequest struct {
+	Domain string `json:"domain"`
+	Action string `json:"action"` // "allow" or "block"
+}
+
+// PushRule adds or replaces an allow/block rule on the server profile,
+// so it applies to every device using the profile
+func PushRule(serverURL, profile, password string, rule config.Rule) error {
+	body, err := json.Marshal(RuleRequest{Domain: rule.Domain, Action: rule.Action})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, rulesURL(serverURL, profile, ""), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doRuleRequest(req, password)
+}
+
+// MirrorRule applies a local rule change to the current profile on the
+// server, if the server has a rules API, and reports whether it did
+func MirrorRule(cfg *config.Config, rule config.Rule, remove bool) (bool, error) {
+	caps := cfg.CapabilitiesFor(cfg.ServerURL)
+	if caps == nil || !caps.Rules || cfg.Profile == "" {
+		return false, nil
+	}
+
+	password, _ := config.GetPassword(cfg.Profile)
+	if remove {
+		return true, DeleteRule(cfg.ServerURL, cfg.Profile, password, rule.Domain)
+	}
+	return true, PushRule(cfg.ServerURL, cfg.Profile, password, rule)
+}
+
+// DeleteRule removes the rule for domain from the server profile
+func DeleteRule(serverURL, profile, password, domain string) error {
+	req, err := http.NewRequest(http.MethodDelete, rulesURL(serverURL, profile, domain), nil)
+	if err != nil {
+		return err
+	}
+	return doRuleRequest(req, password)
+}
+
+// rulesURL returns the rules endpoint of a profile, or of one of its
+// rules if domain is set
+func rulesURL(serverURL, profile, domain string) string {
+	u := fmt.Sprintf("%s/api/client/rules/%s", serverURL, url.PathEscape(profile))
+	if domain != "" {
+		u += "/" + url.PathEscape(domain)
+	}
+	return u
+}
+
+// doRuleRequest sends a rules API request authenticated with the
+// profile password
+func doRuleRequest(req *http.Request, password string) error {
+	if password != "" {
+		req.Header.Set("X-FilterDNS-Password", password)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("server rejected the profile password")
+	case http.StatusNotFound:
+		return fmt.Errorf("server has no rules endpoint for this profile")
+	default:
+		return fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+}
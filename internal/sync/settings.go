@@ -0,0 +1,67 @@
+package sync
+
+import (
+	"log"
+	"net"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
+)
+
+// ClientSettings in SyncResponse: client behaviour an admin set on the
+// profile, for all its devices
+type ClientSettings struct {
+	Managed      bool               `json:"managed"` // Override the devices' own settings
+	Forwarders   []config.Forwarder `json:"forwarders,omitempty"`
+	BootstrapDNS []string           `json:"bootstrap_dns,omitempty"`
+	CacheSize    int                `json:"cache_size,omitempty"`
+}
+
+// maxCacheSize caps the cache size the server can set, so a typo in the
+// web UI cannot have the proxy use up the device's memory
+const maxCacheSize = 100000
+
+// SettingsCallback is called when the client settings on the server
+// change, with nil once the server sends none
+type SettingsCallback func(settings *config.ManagedSettings)
+
+// OnSettings has the syncer report the server's client settings to
+// callback, on the first sync and whenever they change. Call it before
+// Start.
+func (s *Syncer) OnSettings(callback SettingsCallback) {
+	s.onSettings = callback
+}
+
+// managedSettings converts the settings for the config, dropping values
+// the client cannot use
+func (c *ClientSettings) managedSettings() *config.ManagedSettings {
+	if c == nil {
+		return nil
+	}
+
+	m := &config.ManagedSettings{Managed: c.Managed}
+	for _, f := range c.Forwarders {
+		if err := config.ValidateForwarder(f); err != nil {
+			log.Printf("Ignoring forwarder %s from the server: %v", f.Domain, err)
+			continue
+		}
+		m.Forwarders = append(m.Forwarders, f)
+	}
+	for _, server := range c.BootstrapDNS {
+		host := server
+		if h, _, err := net.SplitHostPort(server); err == nil {
+			host = h
+		}
+		if net.ParseIP(host) == nil {
+			log.Printf("Ignoring bootstrap resolver %q from the server: not an IP address", server)
+			continue
+		}
+		m.BootstrapDNS = append(m.BootstrapDNS, server)
+	}
+	if c.CacheSize > maxCacheSize {
+		log.Printf("Limiting the cache size %d from the server to %d", c.CacheSize, maxCacheSize)
+		m.CacheSize = maxCacheSize
+	} else if c.CacheSize > 0 {
+		m.CacheSize = c.CacheSize
+	}
+	return m
+}
@@ -1,19 +1,19 @@
 // Package sync handles syncing profile state from the server.
 //
-// This allows the desktop client to reflect changes made in the web UI,
-// such as pausing/resuming filtering.
+// The daemon runs a Syncer so that changes made in the web UI, such as
+// pausing/resuming filtering, take effect on headless machines too.
 package sync
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/zkmkarlsruhe/filterdns-client/internal/config"
 )
 
 // SyncResponse from /api/client/sync/<profile>
@@ -25,6 +25,11 @@ type SyncResponse struct {
 		PausedUntil      *string `json:"paused_until,omitempty"`
 		MaintenanceMode  bool    `json:"maintenance_mode"`
 		BlocklistCount   int     `json:"blocklist_count"`
+
+		// Changes whenever the profile's lists or rules do, on servers
+		// that track it; see BlocklistVersion
+		BlocklistVersion string            `json:"blocklist_version,omitempty"`
+		BlocklistChanges *BlocklistChanges `json:"blocklist_changes,omitempty"`
 	} `json:"profile"`
 	DNS struct {
 		Endpoint    string `json:"endpoint"`
@@ -33,20 +38,82 @@ type SyncResponse struct {
 	} `json:"dns"`
 	ServerVersion string `json:"server_version"`
 	SyncedAt      string `json:"synced_at"`
+
+	// Set by servers that manage client settings, see ClientSettings
+	Client *ClientSettings `json:"client,omitempty"`
+
+	// Queued by admins in the web UI until the device acknowledges them
+	Commands []Command `json:"commands,omitempty"`
 }
 
+// PausedUntil is when filtering resumes on the server, or nil if it is
+// not paused for a set time
+func (r *SyncResponse) PausedUntil() *time.Time {
+	if r.Profile.PausedUntil == nil {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, *r.Profile.PausedUntil)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// ErrSyncUnsupported is returned when the server has no sync endpoint
+var ErrSyncUnsupported = errors.New("server has no sync endpoint for this profile")
+
 // StateCallback is called when the server state changes
 type StateCallback func(enabled bool, pausedUntil *time.Time)
 
-// Syncer periodically syncs with the server
+// Syncer periodically syncs with the server, and at once on the events
+// it pushes if EnablePush was called
 type Syncer struct {
 	serverURL   string
 	profileName string
 	interval    time.Duration
 	callback    StateCallback
 
-	lastState *SyncResponse
-	mu        sync.RWMutex
+	push        bool
+	pushing     atomic.Bool
+	onBlocklist ChangeCallback
+	onSettings  SettingsCallback
+	onEndpoint  EndpointCallback
+	onSkew      SkewCallback
+	heartbeat   HeartbeatFunc
+	noHeartbeat atomic.Bool // The server answered a heartbeat with 405
+	onCommand   CommandFunc
+	stateOnly   bool // Only read the state; see StateOnly
+
+	// Serializes syncs from the poll loop, pushed events and SyncNow, so
+	// their responses and callbacks do not interleave
+	syncMu sync.Mutex
+
+	lastState   *SyncResponse
+	lastAttempt time.Time
+	lastErr     error
+	mu          sync.RWMutex
+
+	// IDs of the commands already run, oldest first
+	doneCommands []string
+
+	// Last full state, for conditional and delta syncs
+	base *syncBase
+
+	// Local clock ahead of the server's at the last sync
+	skew      time.Duration
+	skewKnown bool
+
+	// Backoff after failures, see backoff.go
+	lastSuccess time.Time
+	failures    int
+	offline     bool
+	next        time.Time
+	loggedErr   string
+	loggedAt    time.Time
+
+	// Wake the poll loop and the push channel after network changes
+	wake     chan struct{}
+	wakePush chan struct{}
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -60,6 +127,8 @@ func NewSyncer(serverURL, profileName string, interval time.Duration, callback S
 		profileName: profileName,
 		interval:    interval,
 		callback:    callback,
+		wake:        make(chan struct{}, 1),
+		wakePush:    make(chan struct{}, 1),
 		ctx:         ctx,
 		cancel:      cancel,
 	}
@@ -75,6 +144,14 @@ func (s *Syncer) Stop() {
 	s.cancel()
 }
 
+// StateOnly has the syncer only read the profile's state, for a profile
+// the device does not filter with: it sends no heartbeat, and leaves the
+// server's commands to the devices using the profile instead of running
+// or acknowledging them. Call it before Start.
+func (s *Syncer) StateOnly() {
+	s.stateOnly = true
+}
+
 // GetLastState returns the last synced state
 func (s *Syncer) GetLastState() *SyncResponse {
 	s.mu.RLock()
@@ -82,6 +159,19 @@ func (s *Syncer) GetLastState() *SyncResponse {
 	return s.lastState
 }
 
+// LastAttempt returns when the last sync finished and why it failed, if it did
+func (s *Syncer) LastAttempt() (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastAttempt, s.lastErr
+}
+
+// sinceLastAttempt is how long ago the last sync finished
+func (s *Syncer) sinceLastAttempt() time.Duration {
+	last, _ := s.LastAttempt()
+	return time.Since(last)
+}
+
 // SyncNow performs an immediate sync
 func (s *Syncer) SyncNow() error {
 	return s.doSync()
@@ -89,77 +179,164 @@ func (s *Syncer) SyncNow() error {
 
 func (s *Syncer) run() {
 	// Initial sync
-	if err := s.doSync(); err != nil {
+	if err := s.doSync(); errors.Is(err, ErrSyncUnsupported) {
+		log.Printf("Stopping sync: %v", err)
+		return
+	} else if err != nil {
 		log.Printf("Initial sync failed: %v", err)
 	}
 
-	ticker := time.NewTicker(s.interval)
-	defer ticker.Stop()
+	if s.push {
+		go s.listen()
+	}
+
+	timer := time.NewTimer(s.schedule())
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-s.ctx.Done():
 			return
-		case <-ticker.C:
-			if err := s.doSync(); err != nil {
-				log.Printf("Sync failed: %v", err)
+		case <-s.wake:
+			if wait, ok := s.wakeUp(); ok {
+				resetTimer(timer, wait)
 			}
+			continue
+		case <-timer.C:
+		}
+
+		// Pushed events keep the state current; poll only now and then
+		if s.Pushing() && s.sinceLastAttempt() < pushPollInterval {
+			timer.Reset(s.schedule())
+			continue
+		}
+		if err := s.doSync(); errors.Is(err, ErrSyncUnsupported) {
+			log.Printf("Stopping sync: %v", err)
+			return
+		} else if err != nil {
+			s.logFailure(err)
 		}
+		timer.Reset(s.schedule())
+	}
+}
+
+// wakeUp decides how to follow a network change, and returns when to
+// poll and whether that moves the poll. While offline, the change may
+// have brought the network back, so the backoff starts over; syncs that
+// failed for other reasons keep theirs. Changes come in bursts, so a
+// poll already due within networkSettle stays put.
+func (s *Syncer) wakeUp() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failures > 0 && !s.offline {
+		return 0, false
 	}
+	if time.Until(s.next) <= networkSettle {
+		return 0, false
+	}
+	s.failures = 0
+	s.next = time.Now().Add(networkSettle)
+	return networkSettle, true
+}
+
+// resetTimer stops timer, drains a tick it already sent, and resets it,
+// so a stale tick cannot trigger a poll right away
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
+
+// schedule picks when to poll next and returns how long that is from now
+func (s *Syncer) schedule() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.delay()
+	s.next = time.Now().Add(d)
+	return d
 }
 
-func (s *Syncer) doSync() error {
+func (s *Syncer) doSync() (err error) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.record(err)
+		s.mu.Unlock()
+	}()
+
 	client := &http.Client{Timeout: 10 * time.Second}
 	url := fmt.Sprintf("%s/api/client/sync/%s", s.serverURL, s.profileName)
 
-	resp, err := client.Get(url)
+	sent := time.Now()
+	resp, base, err := s.send(client, url)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		s.measureSkew(err)
+		return fmt.Errorf("request failed: %w", clockHint(err))
 	}
 	defer resp.Body.Close()
+	received := time.Now()
 
-	if resp.StatusCode != http.StatusOK {
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		// Nothing changed since the last full state
+		s.recordSkew(resp, "", sent, received)
+		return nil
+	case http.StatusNotFound:
+		return ErrSyncUnsupported
+	case http.StatusOK:
+	default:
 		return fmt.Errorf("server returned status %d", resp.StatusCode)
 	}
 
-	var syncResp SyncResponse
-	if err := json.NewDecoder(resp.Body).Decode(&syncResp); err != nil {
+	syncResp, err := s.decodeSync(resp, base)
+	if err != nil {
+		// Start over with a full state next time
+		s.mu.Lock()
+		s.base = nil
+		s.mu.Unlock()
 		return fmt.Errorf("failed to parse response: %w", err)
 	}
 
+	// A delta's synced_at, if any, may be left over from its base
+	syncedAt := syncResp.SyncedAt
+	if isDelta(resp) {
+		syncedAt = ""
+	}
+	s.recordSkew(resp, syncedAt, sent, received)
+
 	// Check if state changed
 	s.mu.Lock()
 	stateChanged := s.lastState == nil ||
 		s.lastState.Profile.FilteringEnabled != syncResp.Profile.FilteringEnabled ||
 		s.lastState.Profile.PausedUntil != syncResp.Profile.PausedUntil
-	s.lastState = &syncResp
+	settingsChanged := s.lastState == nil || !reflect.DeepEqual(s.lastState.Client, syncResp.Client)
+	endpointChanged := s.lastState == nil || s.lastState.DNS != syncResp.DNS
+	blocklistChanged, changedDomains := blocklistChange(s.lastState, syncResp)
+	s.lastState = syncResp
 	s.mu.Unlock()
 
 	// Notify callback if state changed
 	if stateChanged && s.callback != nil {
-		var pausedUntil *time.Time
-		if syncResp.Profile.PausedUntil != nil {
-			t, err := time.Parse(time.RFC3339, *syncResp.Profile.PausedUntil)
-			if err == nil {
-				pausedUntil = &t
-			}
-		}
-		s.callback(syncResp.Profile.FilteringEnabled, pausedUntil)
+		s.callback(syncResp.Profile.FilteringEnabled, syncResp.PausedUntil())
 	}
-
-	return nil
-}
-
-// SyncFromConfig creates a syncer from the current config
-func SyncFromConfig(callback StateCallback) (*Syncer, error) {
-	cfg, err := config.Load()
-	if err != nil {
-		return nil, fmt.Errorf("failed to load config: %w", err)
+	if settingsChanged && s.onSettings != nil {
+		s.onSettings(syncResp.Client.managedSettings())
 	}
-
-	if cfg.Profile == "" {
-		return nil, fmt.Errorf("no profile configured")
+	if endpointChanged && s.onEndpoint != nil {
+		s.onEndpoint(syncResp.DNS.DoHURL, syncResp.DNS.DoTHostname)
+	}
+	if blocklistChanged && s.onBlocklist != nil {
+		s.onBlocklist(changedDomains)
+	}
+	if !s.stateOnly {
+		s.runCommands(syncResp.Commands)
 	}
 
-	return NewSyncer(cfg.ServerURL, cfg.Profile, 30*time.Second, callback), nil
+	return nil
 }
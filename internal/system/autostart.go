@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/emersion/go-autostart"
 )
@@ -32,9 +33,21 @@ func IsAutostartEnabled() bool {
 	return app.IsEnabled()
 }
 
-// getExecutablePath returns the path to the current executable
+// getExecutablePath returns the path to the GUI: the current executable,
+// or the GUI next to it when run as the GUI-free filterdnsd
 func getExecutablePath() []string {
 	exe, err := os.Executable()
+	if err == nil && strings.TrimSuffix(filepath.Base(exe), ".exe") == "filterdnsd" {
+		for _, name := range []string{"filterdns-gui", "filterdns-client"} {
+			if runtime.GOOS == "windows" {
+				name += ".exe"
+			}
+			sibling := filepath.Join(filepath.Dir(exe), name)
+			if _, err := os.Stat(sibling); err == nil {
+				return []string{sibling}
+			}
+		}
+	}
 	if err != nil {
 		// Fallback to common install locations
 		switch runtime.GOOS {
@@ -30,34 +30,97 @@ type DNSBackup struct {
 // LinuxDNSBackup stores Linux-specific DNS backup
 type LinuxDNSBackup struct {
 	// Which DNS system was in use
-	System string `json:"system"` // "systemd-resolved", "networkmanager", "resolvconf"
+	System string `json:"system"` // "systemd-resolved", "networkmanager", "openresolv", "netconfig", "resolvconf"
 
 	// For NetworkManager: original connection settings
-	ConnectionName   string   `json:"connection_name,omitempty"`
-	OriginalDNS      []string `json:"original_dns,omitempty"`
-	IgnoreAutoDNS    bool     `json:"ignore_auto_dns,omitempty"`
+	ConnectionName string   `json:"connection_name,omitempty"`
+	OriginalDNS    []string `json:"original_dns,omitempty"`
+	IgnoreAutoDNS  bool     `json:"ignore_auto_dns,omitempty"`
+
+	// For NetworkManager: every connection we modified
+	Connections []NMConnectionBackup `json:"connections,omitempty"`
 
 	// For systemd-resolved: interface name
 	Interface string `json:"interface,omitempty"`
 
+	// For systemd-resolved: every interface we modified
+	Interfaces []string `json:"interfaces,omitempty"`
+
 	// For resolv.conf: we use file backup, but track that we modified it
 	ResolvConfModified bool `json:"resolvconf_modified,omitempty"`
+
+	// For SUSE netconfig: the original DNS policy and static servers
+	NetconfigPolicy  string `json:"netconfig_policy,omitempty"`
+	NetconfigServers string `json:"netconfig_servers,omitempty"`
+}
+
+// NMConnectionBackup stores the original DNS settings of one
+// NetworkManager connection
+type NMConnectionBackup struct {
+	Name          string   `json:"name"`
+	Device        string   `json:"device,omitempty"`
+	OriginalDNS   []string `json:"original_dns,omitempty"`
+	IgnoreAutoDNS bool     `json:"ignore_auto_dns,omitempty"`
+
+	// Search domains set on the connection (ipv4.dns-search); older
+	// backups did not save them
+	DNSSearch      []string `json:"dns_search,omitempty"`
+	DNSSearchSaved bool     `json:"dns_search_saved,omitempty"`
+
+	// IPv6 DNS of the connection (ipv6.dns, ipv6.ignore-auto-dns); older
+	// backups did not touch or save them
+	OriginalDNS6   []string `json:"original_dns6,omitempty"`
+	IgnoreAutoDNS6 bool     `json:"ignore_auto_dns6,omitempty"`
+	DNS6Saved      bool     `json:"dns6_saved,omitempty"`
 }
 
 // DarwinDNSBackup stores macOS-specific DNS backup
 type DarwinDNSBackup struct {
 	// Map of network service name to original DNS servers
 	Services map[string][]string `json:"services"`
+
+	// Services we modified; older backups leave this empty
+	Modified []string `json:"modified,omitempty"`
+
+	// Search domains of the services we modified; older backups leave
+	// this empty
+	SearchDomains map[string][]string `json:"searchDomains,omitempty"`
+
+	// Search domains right after DNS was set, so restoring leaves those
+	// changed since by the user or DHCP alone
+	SearchDomainsSet map[string][]string `json:"searchDomainsSet,omitempty"`
 }
 
 // WindowsDNSBackup stores Windows-specific DNS backup
 type WindowsDNSBackup struct {
 	// Map of interface index to original DNS servers
 	Interfaces map[int][]string `json:"interfaces"`
+
+	// Interfaces we modified; older backups leave this empty
+	Modified []int `json:"modified,omitempty"`
+
+	// Original IPv6 DNS servers, if IPv6 DNS was set as well
+	IPv6Interfaces map[int][]string `json:"ipv6Interfaces,omitempty"`
+
+	// Windows' own DNS over HTTPS was prohibited, and the DoHPolicy value
+	// before (nil if there was none)
+	DoHProhibited bool    `json:"dohProhibited,omitempty"`
+	DoHPolicy     *uint32 `json:"dohPolicy,omitempty"`
+
+	// Connection-specific DNS suffixes of the interfaces we modified and
+	// the global suffix search list; older backups leave these empty
+	Suffixes         map[int]string `json:"suffixes,omitempty"`
+	SuffixSearchList []string       `json:"suffixSearchList,omitempty"`
+	SuffixesSaved    bool           `json:"suffixesSaved,omitempty"`
+
+	// The same right after DNS was set, so restoring leaves those changed
+	// since by the user or DHCP alone
+	SuffixesSet         map[int]string `json:"suffixesSet,omitempty"`
+	SuffixSearchListSet []string       `json:"suffixSearchListSet,omitempty"`
 }
 
-// backupFilePath returns the path to the backup file
-func backupFilePath() string {
+// StateDir returns the system-wide directory for daemon state
+func StateDir() string {
 	var dir string
 
 	switch runtime.GOOS {
@@ -72,7 +135,12 @@ func backupFilePath() string {
 	// Ensure directory exists
 	os.MkdirAll(dir, 0755)
 
-	return filepath.Join(dir, "dns-backup.json")
+	return dir
+}
+
+// backupFilePath returns the path to the backup file
+func backupFilePath() string {
+	return filepath.Join(StateDir(), "dns-backup.json")
 }
 
 // SaveBackup persists the DNS backup to disk
@@ -115,6 +183,13 @@ func ClearBackup() error {
 	return err
 }
 
+// RemoveState deletes the system state directory: the DNS backup and
+// journal, and the config of a daemon that dropped root. Restore DNS
+// before calling this.
+func RemoveState() error {
+	return os.RemoveAll(StateDir())
+}
+
 // HasPendingRestore checks if there's a backup that needs to be restored
 // (e.g., after a crash). Call this on startup.
 func HasPendingRestore() bool {
@@ -140,3 +215,109 @@ func RestoreFromBackupIfNeeded() error {
 	// Clear the backup file
 	return ClearBackup()
 }
+
+// Journal records a system DNS change in progress. It is written before
+// anything is touched and removed once the change completed, so a journal
+// found at startup means the previous run died half-way.
+type Journal struct {
+	Operation string    `json:"operation"` // "enable" or "disable"
+	Server    string    `json:"server,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+const (
+	opEnable  = "enable"
+	opDisable = "disable"
+)
+
+// journalFilePath returns the path to the journal file
+func journalFilePath() string {
+	return filepath.Join(StateDir(), "dns-journal.json")
+}
+
+// beginOperation durably records intent before system DNS is modified
+func beginOperation(operation, server string) error {
+	journal := &Journal{
+		Operation: operation,
+		Server:    server,
+		StartedAt: time.Now(),
+	}
+
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename, so the journal is either the
+	// old one or the complete new one after a crash
+	path := journalFilePath()
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// endOperation marks the operation in the journal as complete
+func endOperation() error {
+	err := os.Remove(journalFilePath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// LoadJournal returns the interrupted operation, or nil if there is none
+func LoadJournal() (*Journal, error) {
+	data, err := os.ReadFile(journalFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var journal Journal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return nil, err
+	}
+	return &journal, nil
+}
+
+// RecoverInterruptedOperation finishes an enable or disable that was cut
+// short by a crash and returns it, or nil if nothing was pending. An
+// interrupted enable is rolled back and an interrupted disable completed;
+// both end with the original settings restored. If no backup was written,
+// the enable died before touching anything and there is nothing to undo.
+// Call this on startup, before RestoreFromBackupIfNeeded.
+func RecoverInterruptedOperation() (*Journal, error) {
+	journal, err := LoadJournal()
+	if err != nil {
+		// A torn journal is still evidence of an interrupted change
+		journal = &Journal{Operation: opEnable}
+	}
+	if journal == nil {
+		return nil, nil
+	}
+
+	if HasPendingRestore() {
+		if err := resetDNS(); err != nil {
+			return journal, err
+		}
+		ClearBackup()
+	}
+
+	return journal, endOperation()
+}
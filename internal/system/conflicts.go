@@ -0,0 +1,58 @@
+package system
+
+import (
+	"fmt"
+	"net"
+)
+
+// ResolverConflict is another DNS server listening where the proxy has to,
+// on port 53 of a loopback address or of all addresses
+type ResolverConflict struct {
+	Addr    string `json:"addr"`              // Where it listens, e.g. "0.0.0.0:53"
+	Process string `json:"process,omitempty"` // Its program, e.g. "dnsmasq"; empty if unknown
+	PID     int    `json:"pid,omitempty"`
+	Remedy  string `json:"remedy"`  // What to do about it
+	Fixable bool   `json:"fixable"` // FixResolverConflict can reconfigure it to make way
+}
+
+func (c ResolverConflict) String() string {
+	owner := "another program"
+	if c.Process != "" {
+		owner = c.Process
+		if c.PID != 0 {
+			owner = fmt.Sprintf("%s (PID %d)", c.Process, c.PID)
+		}
+	}
+	return fmt.Sprintf("%s is in use by %s", c.Addr, owner)
+}
+
+// ResolverConflicts lists the DNS servers that already listen on port 53
+// of the given addresses, which the proxy could then not bind. Only Linux
+// can tell; elsewhere the list is empty and binding fails as it would.
+// Implementation is platform-specific
+func ResolverConflicts(addrs []string) ([]ResolverConflict, error) {
+	return resolverConflicts(addrs)
+}
+
+// FixResolverConflict reconfigures a conflicting resolver, if Fixable, so
+// that it leaves the loopback to the proxy, and restarts it. Needs root.
+// Implementation is platform-specific
+func FixResolverConflict(c ResolverConflict) error {
+	if !c.Fixable {
+		return fmt.Errorf("%s cannot be reconfigured automatically: %s", c.Process, c.Remedy)
+	}
+	return fixResolverConflict(c)
+}
+
+// listenerConflicts reports whether a socket bound to listener takes the
+// port away from addr: the same address, or the wildcard of its family.
+// An IPv6 wildcard socket usually accepts IPv4 too.
+func listenerConflicts(listener, addr net.IP) bool {
+	if listener.Equal(addr) {
+		return true
+	}
+	if listener.Equal(net.IPv6unspecified) {
+		return true
+	}
+	return listener.Equal(net.IPv4zero) && addr.To4() != nil
+}
@@ -0,0 +1,242 @@
+//go:build linux
+
+package system
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// dnsListener is a socket bound to port 53
+type dnsListener struct {
+	ip    net.IP
+	inode string
+}
+
+// knownResolvers maps program names, as in /proc/<pid>/comm (at most 15
+// characters), to what to do about them
+var knownResolvers = map[string]struct {
+	remedy  string
+	fixable bool
+}{
+	"systemd-resolve": {"Its extra stub listeners (DNSStubListenerExtra) take the address. FilterDNS can turn them off in a drop-in and restart systemd-resolved.", true},
+	"dnsmasq":         {"FilterDNS can have dnsmasq leave the loopback interface (except-interface=lo, bind-interfaces) and restart it. It keeps serving other interfaces and forwards to FilterDNS through resolv.conf.", true},
+	"unbound":         {"Make Unbound listen on another address (interface: in unbound.conf), forwarding to 127.0.0.1 with a forward-zone if you want to keep it, or stop it.", false},
+	"named":           {"Make BIND listen on another address (listen-on in named.conf), or stop it.", false},
+	"pdns_recursor":   {"Make the PowerDNS Recursor listen on another address (local-address), or stop it.", false},
+	"docker-proxy":    {"A Docker container publishes port 53. Publish it on another address or port (docker ps --filter publish=53 shows which).", false},
+	"coredns":         {"Make CoreDNS listen on another address (bind plugin), or stop it.", false},
+}
+
+// resolverConflicts finds port-53 sockets that take the given addresses
+// and the programs they belong to, from /proc
+func resolverConflicts(addrs []string) ([]ResolverConflict, error) {
+	listeners, err := dnsListeners()
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []ResolverConflict
+	seen := make(map[string]bool)
+	for _, l := range listeners {
+		for _, addr := range addrs {
+			ip := net.ParseIP(addr)
+			if ip == nil || !listenerConflicts(l.ip, ip) {
+				continue
+			}
+			// TCP and UDP sockets of one server are one conflict
+			key := l.ip.String()
+			if seen[key] {
+				break
+			}
+			seen[key] = true
+
+			pid, process := socketOwner(l.inode)
+			if pid == os.Getpid() || isOwnExecutable(pid) {
+				break
+			}
+			c := ResolverConflict{
+				Addr:    net.JoinHostPort(l.ip.String(), "53"),
+				Process: process,
+				PID:     pid,
+				Remedy:  "Stop the program listening on port 53. If it only takes ::1, 'filterdns-client config set ipv4-only true' lets FilterDNS do without that address.",
+			}
+			if known, ok := knownResolvers[process]; ok {
+				c.Remedy, c.Fixable = known.remedy, known.fixable
+			}
+			if process == "systemd-resolve" {
+				c.Process = "systemd-resolved"
+			}
+			conflicts = append(conflicts, c)
+			break
+		}
+	}
+	return conflicts, nil
+}
+
+// dnsListeners reads the TCP sockets listening on port 53 and the UDP
+// sockets bound to it
+func dnsListeners() ([]dnsListener, error) {
+	var listeners []dnsListener
+	for _, table := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		file, err := os.Open(filepath.Join("/proc/net", table))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // No IPv6
+			}
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Scan() // Skip header
+		for scanner.Scan() {
+			// "sl local_address rem_address st ... inode", addresses as
+			// hex "0100007F:0035"
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 10 {
+				continue
+			}
+			host, port, found := strings.Cut(fields[1], ":")
+			if !found || port != "0035" {
+				continue
+			}
+			// TCP: LISTEN only, not connections from port 53
+			if strings.HasPrefix(table, "tcp") && fields[3] != "0A" {
+				continue
+			}
+			if ip := procNetIP(host); ip != nil {
+				listeners = append(listeners, dnsListener{ip: ip, inode: fields[9]})
+			}
+		}
+		file.Close()
+	}
+	return listeners, nil
+}
+
+// procNetIP decodes an address from /proc/net, written as 32-bit words
+// in host (little endian) byte order
+func procNetIP(s string) net.IP {
+	b, err := hex.DecodeString(s)
+	if err != nil || (len(b) != net.IPv4len && len(b) != net.IPv6len) {
+		return nil
+	}
+	ip := make(net.IP, len(b))
+	for i := 0; i < len(b); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = b[i+3], b[i+2], b[i+1], b[i]
+	}
+	return ip
+}
+
+// socketOwner finds the process holding the socket with the given inode.
+// Other users' descriptors can only be read as root; then the name of a
+// known resolver that is running is the best guess, without a PID.
+func socketOwner(inode string) (int, string) {
+	target := "socket:[" + inode + "]"
+	procs, _ := filepath.Glob("/proc/[0-9]*")
+	for _, proc := range procs {
+		fds, _ := filepath.Glob(filepath.Join(proc, "fd", "*"))
+		for _, fd := range fds {
+			if link, err := os.Readlink(fd); err == nil && link == target {
+				pid, _ := strconv.Atoi(filepath.Base(proc))
+				return pid, processName(pid)
+			}
+		}
+	}
+
+	for _, proc := range procs {
+		pid, _ := strconv.Atoi(filepath.Base(proc))
+		if _, ok := knownResolvers[processName(pid)]; ok {
+			return 0, processName(pid)
+		}
+	}
+	return 0, ""
+}
+
+// processName returns the program name of a process
+func processName(pid int) string {
+	comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(comm))
+}
+
+// isOwnExecutable reports whether a process runs this program, such as
+// the privileged helper
+func isOwnExecutable(pid int) bool {
+	if pid == 0 {
+		return false
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return false
+	}
+	exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	return err == nil && exe == self
+}
+
+// Drop-ins that make a resolver leave the loopback to the proxy
+const (
+	resolvedDropIn = "/etc/systemd/resolved.conf.d/filterdns.conf"
+	dnsmasqDropIn  = "/etc/dnsmasq.d/filterdns.conf"
+)
+
+// fixResolverConflict writes a drop-in for systemd-resolved or dnsmasq
+// and restarts it
+func fixResolverConflict(c ResolverConflict) error {
+	var path, content, service string
+	switch c.Process {
+	case "systemd-resolved":
+		path, service = resolvedDropIn, "systemd-resolved"
+		content = "# Written by FilterDNS: extra stub listeners would take its address\n" +
+			"[Resolve]\nDNSStubListenerExtra=\n"
+	case "dnsmasq":
+		if _, err := os.Stat(filepath.Dir(dnsmasqDropIn)); err != nil {
+			return fmt.Errorf("dnsmasq has no %s to add settings to; add except-interface=lo and bind-interfaces to its configuration by hand", filepath.Dir(dnsmasqDropIn))
+		}
+		path, service = dnsmasqDropIn, "dnsmasq"
+		content = "# Written by FilterDNS: leave the loopback to it\n" +
+			"except-interface=lo\nbind-interfaces\n"
+	default:
+		return fmt.Errorf("%s cannot be reconfigured automatically: %s", c.Process, c.Remedy)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return restartService(service)
+}
+
+// restartService restarts a system service with systemd, or OpenRC or
+// SysV init where there is no systemd
+func restartService(name string) error {
+	var cmd *exec.Cmd
+	switch {
+	case commandExists("systemctl"):
+		cmd = exec.Command("systemctl", "restart", name)
+	case commandExists("rc-service"):
+		cmd = exec.Command("rc-service", name, "restart")
+	default:
+		cmd = exec.Command("service", name, "restart")
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to restart %s: %s: %w", name, strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// commandExists reports whether a command is on the PATH
+func commandExists(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package system
+
+import "errors"
+
+// resolverConflicts finds nothing: there is no portable way to list who
+// holds port 53, so binding reports the conflict
+func resolverConflicts(addrs []string) ([]ResolverConflict, error) {
+	return nil, nil
+}
+
+// fixResolverConflict is never reached, as no conflict is Fixable here
+func fixResolverConflict(c ResolverConflict) error {
+	return errors.New("not supported on this platform")
+}
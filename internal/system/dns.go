@@ -1,15 +1,107 @@
 package system
 
-// SetDNS sets the system DNS server
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"strings"
+)
+
+// InterfaceFilter reports whether DNS on the named interface (or network
+// service on macOS) should be managed. A nil filter manages all of them.
+type InterfaceFilter func(name string) bool
+
+// includes reports whether the filter selects the named interface
+func (f InterfaceFilter) includes(name string) bool {
+	return f == nil || f(name)
+}
+
+// SetDNS sets the system DNS servers, in order of preference, on every
+// interface selected by include. IPv6 servers are skipped where the
+// platform cannot set them.
 // Implementation is platform-specific
-func SetDNS(server string) error {
-	return setDNS(server)
+func SetDNS(servers []string, include InterfaceFilter) error {
+	if err := beginOperation(opEnable, strings.Join(servers, ", ")); err != nil {
+		return fmt.Errorf("failed to write DNS journal: %w", err)
+	}
+
+	if err := setDNS(servers, include); err != nil {
+		// Undo whatever was applied before the failure
+		if HasPendingRestore() {
+			resetDNS()
+		}
+		endOperation()
+		return err
+	}
+
+	return endOperation()
+}
+
+// splitFamilies separates IPv4 and IPv6 servers, keeping their order
+func splitFamilies(servers []string) (ipv4, ipv6 []string) {
+	for _, server := range servers {
+		if ip := net.ParseIP(server); ip != nil && ip.To4() == nil {
+			ipv6 = append(ipv6, server)
+		} else {
+			ipv4 = append(ipv4, server)
+		}
+	}
+	return ipv4, ipv6
 }
 
 // ResetDNS restores the original system DNS settings
 // Implementation is platform-specific
 func ResetDNS() error {
-	return resetDNS()
+	// Restoring DNS matters more than the journal, e.g. on a full disk
+	if err := beginOperation(opDisable, ""); err != nil {
+		log.Printf("Warning: failed to write DNS journal: %v", err)
+	}
+
+	// On failure the journal stays, so the next start finishes the job
+	if err := resetDNS(); err != nil {
+		return err
+	}
+
+	return endOperation()
+}
+
+// UnfilteredLinks returns the interfaces (network services on macOS)
+// selected by include that do not use the local proxy, such as those
+// that came up after DNS was set
+// Implementation is platform-specific
+func UnfilteredLinks(include InterfaceFilter) ([]string, error) {
+	return unfilteredLinks(include)
+}
+
+// AddDNSLinks points the links UnfilteredLinks returned at servers too,
+// adding their original settings to the backup, and leaves the links
+// already using them alone
+// Implementation is platform-specific
+func AddDNSLinks(servers, links []string) error {
+	if len(links) == 0 {
+		return nil
+	}
+	if err := beginOperation(opEnable, strings.Join(servers, ", ")); err != nil {
+		return fmt.Errorf("failed to write DNS journal: %w", err)
+	}
+
+	// The backup covers whatever was applied before a failure, and the
+	// links set before keep filtering
+	if err := addDNSLinks(servers, links); err != nil {
+		endOperation()
+		return err
+	}
+
+	return endOperation()
+}
+
+// linkFilter selects exactly the given links
+func linkFilter(links []string) InterfaceFilter {
+	return func(name string) bool {
+		return slices.Contains(links, name)
+	}
 }
 
 // GetCurrentDNS returns the current system DNS servers
@@ -17,3 +109,68 @@ func ResetDNS() error {
 func GetCurrentDNS() ([]string, error) {
 	return getCurrentDNS()
 }
+
+// Resolver describes where the system resolver currently points
+type Resolver struct {
+	Backend     string         `json:"backend"` // What manages system DNS, e.g. "systemd-resolved"
+	Links       []ResolverLink `json:"links"`
+	UsesProxy   bool           `json:"usesProxy"`             // Some link still points at the local proxy
+	Backup      bool           `json:"backup"`                // A backup of the original settings exists
+	Firewall    bool           `json:"firewall"`              // Firewall rules block DNS outside the proxy
+	Interrupted *Journal       `json:"interrupted,omitempty"` // A change cut short by a crash
+}
+
+// ResolverLink is the DNS configuration of one interface, network
+// service or connection
+type ResolverLink struct {
+	Name    string   `json:"name"`
+	Servers []string `json:"servers"`
+}
+
+// InspectDNS reports which servers the system resolver uses and which
+// backend manages them
+// Implementation is platform-specific
+func InspectDNS() (*Resolver, error) {
+	backend, links, err := inspectDNS()
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := &Resolver{
+		Backend:  backend,
+		Links:    links,
+		Backup:   HasPendingRestore(),
+		Firewall: OutsideDNSBlocked(),
+	}
+	for _, link := range links {
+		if usesProxy(link.Servers) {
+			resolver.UsesProxy = true
+		}
+	}
+	resolver.Interrupted, _ = LoadJournal()
+	return resolver, nil
+}
+
+// ForceResetDNS restores the original DNS settings from the backup if
+// there is one, and otherwise points every interface back at automatic
+// (DHCP) DNS. It is the way out for systems left without working
+// resolution after a crash lost the backup; DNS servers set by hand are
+// lost on that path.
+func ForceResetDNS() error {
+	var errs []error
+	if HasPendingRestore() {
+		err := ResetDNS()
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("restoring DNS from backup: %w", err))
+	}
+
+	if err := revertDNS(); err != nil {
+		errs = append(errs, fmt.Errorf("reverting to automatic DNS: %w", err))
+	}
+
+	ClearBackup()
+	endOperation()
+	return errors.Join(errs...)
+}
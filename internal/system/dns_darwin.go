@@ -5,20 +5,33 @@ package system
 import (
 	"fmt"
 	"os/exec"
+	"slices"
 	"strings"
 )
 
-// setDNS sets the system DNS server on macOS
-func setDNS(server string) error {
-	services, err := listNetworkServices()
+// setDNS sets the system DNS servers on macOS
+func setDNS(servers []string, include InterfaceFilter) error {
+	all, err := listNetworkServices()
 	if err != nil {
 		return err
 	}
 
+	var services []string
+	for _, service := range all {
+		if include.includes(service) {
+			services = append(services, service)
+		}
+	}
+	if len(services) == 0 {
+		return fmt.Errorf("no network services match the interface policy")
+	}
+
 	// Create persistent backup before modifying
 	backup := &DNSBackup{
 		Darwin: &DarwinDNSBackup{
-			Services: make(map[string][]string),
+			Services:      make(map[string][]string),
+			Modified:      services,
+			SearchDomains: make(map[string][]string),
 		},
 	}
 
@@ -28,6 +41,9 @@ func setDNS(server string) error {
 		if len(current) > 0 {
 			backup.Darwin.Services[service] = current
 		}
+		if search, err := getSearchDomainsForService(service); err == nil && len(search) > 0 {
+			backup.Darwin.SearchDomains[service] = search
+		}
 	}
 
 	// Save backup to disk BEFORE modifying DNS
@@ -37,12 +53,23 @@ func setDNS(server string) error {
 
 	// Now modify DNS
 	for _, service := range services {
-		cmd := exec.Command("networksetup", "-setdnsservers", service, server)
+		args := append([]string{"-setdnsservers", service}, servers...)
+		cmd := exec.Command("networksetup", args...)
 		if output, err := cmd.CombinedOutput(); err != nil {
 			return fmt.Errorf("failed to set DNS for %s: %s: %w", service, string(output), err)
 		}
 	}
 
+	// Note the search domains as set, so restoring only puts back those
+	// that setting DNS changed; a failed save leaves them as they are
+	backup.Darwin.SearchDomainsSet = make(map[string][]string)
+	for _, service := range services {
+		if search, err := getSearchDomainsForService(service); err == nil && len(search) > 0 {
+			backup.Darwin.SearchDomainsSet[service] = search
+		}
+	}
+	SaveBackup(backup)
+
 	// Flush DNS cache
 	exec.Command("dscacheutil", "-flushcache").Run()
 	exec.Command("killall", "-HUP", "mDNSResponder").Run()
@@ -50,6 +77,66 @@ func setDNS(server string) error {
 	return nil
 }
 
+// addDNSLinks points more network services at servers on macOS, adding
+// them to the backup
+func addDNSLinks(servers, services []string) error {
+	backup, err := LoadBackup()
+	if err != nil {
+		return fmt.Errorf("failed to load DNS backup: %w", err)
+	}
+	if backup == nil || backup.Darwin == nil {
+		// Nothing set before to add to
+		return setDNS(servers, linkFilter(services))
+	}
+	if backup.Darwin.Services == nil {
+		backup.Darwin.Services = make(map[string][]string)
+	}
+	if backup.Darwin.SearchDomains == nil {
+		backup.Darwin.SearchDomains = make(map[string][]string)
+	}
+	if backup.Darwin.SearchDomainsSet == nil {
+		backup.Darwin.SearchDomainsSet = make(map[string][]string)
+	}
+
+	for _, service := range services {
+		// A service set before keeps its original settings
+		if slices.Contains(backup.Darwin.Modified, service) {
+			continue
+		}
+		current, _ := getDNSForService(service)
+		if len(current) > 0 {
+			backup.Darwin.Services[service] = current
+		}
+		if search, err := getSearchDomainsForService(service); err == nil && len(search) > 0 {
+			backup.Darwin.SearchDomains[service] = search
+		}
+		// Older backups restore every service anyway
+		if len(backup.Darwin.Modified) > 0 {
+			backup.Darwin.Modified = append(backup.Darwin.Modified, service)
+		}
+	}
+	if err := SaveBackup(backup); err != nil {
+		return fmt.Errorf("failed to save DNS backup: %w", err)
+	}
+
+	for _, service := range services {
+		args := append([]string{"-setdnsservers", service}, servers...)
+		cmd := exec.Command("networksetup", args...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to set DNS for %s: %s: %w", service, string(output), err)
+		}
+		if search, err := getSearchDomainsForService(service); err == nil && len(search) > 0 {
+			backup.Darwin.SearchDomainsSet[service] = search
+		}
+	}
+	SaveBackup(backup)
+
+	exec.Command("dscacheutil", "-flushcache").Run()
+	exec.Command("killall", "-HUP", "mDNSResponder").Run()
+
+	return nil
+}
+
 // resetDNS restores the original system DNS settings on macOS
 func resetDNS() error {
 	// Load backup from disk
@@ -63,6 +150,12 @@ func resetDNS() error {
 		return err
 	}
 
+	// Only touch the services we changed, so excluded ones (e.g. VPNs)
+	// keep their own DNS
+	if backup != nil && backup.Darwin != nil && len(backup.Darwin.Modified) > 0 {
+		services = backup.Darwin.Modified
+	}
+
 	for _, service := range services {
 		var args []string
 
@@ -82,11 +175,26 @@ func resetDNS() error {
 
 		cmd := exec.Command("networksetup", args...)
 		cmd.Run() // Ignore errors for individual services
+
+		// Put back search domains that setting DNS changed, unless they
+		// were changed again since
+		if backup != nil && backup.Darwin != nil && backup.Darwin.SearchDomains != nil {
+			original := backup.Darwin.SearchDomains[service]
+			current, err := getSearchDomainsForService(service)
+			if err == nil && !slices.Equal(current, original) && slices.Equal(current, backup.Darwin.SearchDomainsSet[service]) {
+				if len(original) == 0 {
+					original = []string{"empty"}
+				}
+				exec.Command("networksetup", append([]string{"-setsearchdomains", service}, original...)...).Run()
+			}
+		}
 	}
 
 	// Clear backup file after successful restore
 	ClearBackup()
 
+	clearSplitDNS()
+
 	// Flush DNS cache
 	exec.Command("dscacheutil", "-flushcache").Run()
 	exec.Command("killall", "-HUP", "mDNSResponder").Run()
@@ -94,6 +202,93 @@ func resetDNS() error {
 	return nil
 }
 
+// unfilteredLinks returns the network services include selects whose DNS
+// servers are not the local proxy
+func unfilteredLinks(include InterfaceFilter) ([]string, error) {
+	services, err := listNetworkServices()
+	if err != nil {
+		return nil, err
+	}
+
+	var links []string
+	for _, service := range services {
+		if !include.includes(service) {
+			continue
+		}
+		if current, _ := getDNSForService(service); !usesProxy(current) {
+			links = append(links, service)
+		}
+	}
+	return links, nil
+}
+
+// restoreLeftovers sets every network service still using the local
+// proxy back to automatic DNS
+func restoreLeftovers() error {
+	services, err := listNetworkServices()
+	if err != nil {
+		return err
+	}
+
+	for _, service := range services {
+		if current, _ := getDNSForService(service); usesProxy(current) {
+			cmd := exec.Command("networksetup", "-setdnsservers", service, "empty")
+			if output, err := cmd.CombinedOutput(); err != nil {
+				return fmt.Errorf("failed to reset DNS for %s: %s: %w", service, string(output), err)
+			}
+		}
+	}
+
+	if err := clearSplitDNS(); err != nil {
+		return err
+	}
+
+	exec.Command("dscacheutil", "-flushcache").Run()
+	exec.Command("killall", "-HUP", "mDNSResponder").Run()
+	return nil
+}
+
+// revertDNS sets every network service back to automatic DNS, whatever
+// it is set to
+func revertDNS() error {
+	services, err := listNetworkServices()
+	if err != nil {
+		return err
+	}
+
+	for _, service := range services {
+		cmd := exec.Command("networksetup", "-setdnsservers", service, "empty")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to reset DNS for %s: %s: %w", service, string(output), err)
+		}
+	}
+
+	if err := clearSplitDNS(); err != nil {
+		return err
+	}
+
+	exec.Command("dscacheutil", "-flushcache").Run()
+	exec.Command("killall", "-HUP", "mDNSResponder").Run()
+	return nil
+}
+
+// inspectDNS returns the DNS servers set on each network service, and
+// those of the split DNS resolver files we wrote. An empty list means the
+// service uses the servers obtained by DHCP.
+func inspectDNS() (string, []ResolverLink, error) {
+	services, err := listNetworkServices()
+	if err != nil {
+		return "networksetup", nil, err
+	}
+
+	var links []ResolverLink
+	for _, service := range services {
+		servers, _ := getDNSForService(service)
+		links = append(links, ResolverLink{Name: service, Servers: servers})
+	}
+	return "networksetup", append(links, splitDNSLinks()...), nil
+}
+
 // getCurrentDNS returns the current system DNS servers on macOS
 func getCurrentDNS() ([]string, error) {
 	services, err := listNetworkServices()
@@ -165,3 +360,27 @@ func getDNSForService(service string) ([]string, error) {
 
 	return servers, nil
 }
+
+// getSearchDomainsForService returns the search domains set on a network
+// service
+func getSearchDomainsForService(service string) ([]string, error) {
+	output, err := exec.Command("networksetup", "-getsearchdomains", service).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	result := strings.TrimSpace(string(output))
+	if strings.Contains(result, "There aren't any Search Domains") {
+		return nil, nil
+	}
+
+	var domains []string
+	for _, line := range strings.Split(result, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			domains = append(domains, line)
+		}
+	}
+
+	return domains, nil
+}
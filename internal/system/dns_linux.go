@@ -5,9 +5,11 @@ package system
 import (
 	"bufio"
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -16,19 +18,30 @@ const (
 	resolvConfBackup = "/etc/resolv.conf.filterdns.bak"
 )
 
-// setDNS sets the system DNS server on Linux
-func setDNS(server string) error {
+// setDNS sets the system DNS servers on Linux
+func setDNS(servers []string, include InterfaceFilter) error {
 	// Detect which DNS management system is in use
 	if isSystemdResolved() {
-		return setDNSSystemdResolved(server)
+		return setDNSSystemdResolved(servers, include)
 	}
 
 	if isNetworkManager() {
-		return setDNSNetworkManager(server)
+		ipv4, ipv6 := splitFamilies(servers)
+		return setDNSNetworkManager(ipv4, ipv6, include)
+	}
+
+	// The rest generate one resolv.conf for all interfaces, so
+	// per-interface policies cannot be honoured here
+	if isOpenresolv() {
+		return setDNSOpenresolv(servers)
+	}
+
+	if isNetconfig() {
+		return setDNSNetconfig(servers)
 	}
 
 	// Fallback: directly modify /etc/resolv.conf
-	return setDNSResolvConf(server)
+	return setDNSResolvConf(servers)
 }
 
 // resetDNS restores the original system DNS settings
@@ -41,11 +54,48 @@ func resetDNS() error {
 		return resetDNSNetworkManager()
 	}
 
+	if isOpenresolv() {
+		return resetDNSOpenresolv()
+	}
+
+	if isNetconfig() {
+		return resetDNSNetconfig()
+	}
+
 	return resetDNSResolvConf()
 }
 
+// addDNSLinks points more interfaces (NetworkManager devices) at servers
+// on Linux
+func addDNSLinks(servers, links []string) error {
+	backup, err := LoadBackup()
+	if err != nil {
+		return fmt.Errorf("failed to load DNS backup: %w", err)
+	}
+	if backup == nil || backup.Linux == nil {
+		// Nothing set before to add to
+		return setDNS(servers, linkFilter(links))
+	}
+
+	switch backup.Linux.System {
+	case "systemd-resolved":
+		return addDNSSystemdResolved(backup, servers, links)
+	case "networkmanager":
+		ipv4, ipv6 := splitFamilies(servers)
+		return addDNSNetworkManager(backup, ipv4, ipv6, links)
+	}
+	// resolv.conf applies to every interface alike
+	return nil
+}
+
 // getCurrentDNS returns the current system DNS servers
 func getCurrentDNS() ([]string, error) {
+	// resolv.conf only lists the local stub when systemd-resolved is in
+	// charge, so ask it for the per-link servers instead
+	if isSystemdResolved() {
+		return getSystemdResolvedDNS()
+	}
+
 	file, err := os.Open(resolvConf)
 	if err != nil {
 		return nil, err
@@ -65,6 +115,260 @@ func getCurrentDNS() ([]string, error) {
 	return servers, scanner.Err()
 }
 
+// unfilteredLinks returns the interfaces include selects that are up but
+// not pointed at the local proxy, or the NetworkManager devices of such
+// connections
+func unfilteredLinks(include InterfaceFilter) ([]string, error) {
+	if isSystemdResolved() {
+		interfaces, err := getActiveInterfaces()
+		if err != nil {
+			return nil, err
+		}
+		r, err := connectResolved()
+		if err != nil {
+			return nil, err
+		}
+		var links []string
+		for _, iface := range interfaces {
+			if !include.includes(iface) {
+				continue
+			}
+			if servers, err := r.linkServers(iface); err == nil && !usesProxy(servers) {
+				links = append(links, iface)
+			}
+		}
+		return links, nil
+	}
+
+	if isNetworkManager() {
+		connections, err := networkManagerDevices(include)
+		if err != nil {
+			return nil, err
+		}
+		var links []string
+		for _, conn := range connections {
+			if current, _ := getNetworkManagerDNS(conn.Name, "ipv4"); !usesProxy(current) {
+				links = append(links, conn.Device)
+			}
+		}
+		return links, nil
+	}
+
+	// resolv.conf applies to every interface alike
+	return nil, nil
+}
+
+// restoreLeftovers points every link or connection still using the local
+// proxy back at automatic DNS, and puts back a resolv.conf we overwrote
+func restoreLeftovers() error {
+	if isSystemdResolved() {
+		r, err := connectResolved()
+		if err != nil {
+			return err
+		}
+		links, err := r.links()
+		if err != nil {
+			return err
+		}
+		for _, link := range links {
+			if link.Name == resolvedGlobal || !usesProxy(link.Servers) {
+				continue
+			}
+			if err := r.revertLink(link.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if isNetworkManager() {
+		names, err := networkManagerConnections(false)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if current, _ := getNetworkManagerDNS(name, "ipv4"); usesProxy(current) {
+				if err := restoreNetworkManagerConnection(NMConnectionBackup{Name: name, DNS6Saved: true}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if isOpenresolv() {
+		return resetDNSOpenresolv()
+	}
+
+	if isNetconfig() {
+		if netconfigUsesProxy() {
+			return resetDNSNetconfig()
+		}
+		return nil
+	}
+
+	// A leftover copy of the original means resolv.conf is still ours
+	if _, err := os.Stat(resolvConfBackup); err == nil {
+		return resetDNSResolvConf()
+	}
+	return releaseDhcpcd()
+}
+
+// revertDNS points every link or active connection back at automatic
+// DNS, whatever it is set to
+func revertDNS() error {
+	if isSystemdResolved() {
+		r, err := connectResolved()
+		if err != nil {
+			return err
+		}
+		links, err := r.links()
+		if err != nil {
+			return err
+		}
+		for _, link := range links {
+			// Global servers come from resolved.conf, which we never touch
+			if link.Name == resolvedGlobal {
+				continue
+			}
+			if err := r.revertLink(link.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if isNetworkManager() {
+		names, err := networkManagerConnections(true)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := restoreNetworkManagerConnection(NMConnectionBackup{Name: name, DNS6Saved: true}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if isOpenresolv() {
+		return resetDNSOpenresolv()
+	}
+
+	if isNetconfig() {
+		return resetDNSNetconfig()
+	}
+
+	if _, err := os.Stat(resolvConfBackup); err == nil {
+		return resetDNSResolvConf()
+	}
+	releaseDhcpcd()
+	// Without a copy there is no automatic setting to go back to
+	if current, _ := getCurrentDNS(); usesProxy(current) {
+		return fmt.Errorf("%s points at the local proxy and no copy of the original exists; edit it by hand", resolvConf)
+	}
+	return nil
+}
+
+// inspectDNS returns the DNS backend in use and its servers per link
+func inspectDNS() (string, []ResolverLink, error) {
+	if isSystemdResolved() {
+		links, err := resolvedLinks()
+		return "systemd-resolved", links, err
+	}
+
+	if isNetworkManager() {
+		names, err := networkManagerConnections(true)
+		if err != nil {
+			return "NetworkManager", nil, err
+		}
+		var links []ResolverLink
+		for _, name := range names {
+			links = append(links, ResolverLink{Name: name, Servers: getNetworkManagerActiveDNS(name)})
+		}
+		return "NetworkManager", links, nil
+	}
+
+	backend := "resolv.conf"
+	if isOpenresolv() {
+		backend = "openresolv"
+	} else if isNetconfig() {
+		backend = "netconfig"
+	}
+	servers, err := getCurrentDNS()
+	if err != nil {
+		return backend, nil, err
+	}
+	return backend, []ResolverLink{{Name: resolvConf, Servers: servers}}, nil
+}
+
+// resolvedGlobal names the global entry of resolvedLinks
+const resolvedGlobal = "Global"
+
+// resolvedLinks returns the global and per-link DNS servers reported by
+// systemd-resolved
+func resolvedLinks() ([]ResolverLink, error) {
+	r, err := connectResolved()
+	if err != nil {
+		return nil, err
+	}
+	return r.links()
+}
+
+// networkManagerConnections returns the names of all NetworkManager
+// connections, or only the active ones
+func networkManagerConnections(active bool) ([]string, error) {
+	args := []string{"-t", "-f", "NAME", "connection", "show"}
+	if active {
+		args = append(args, "--active")
+	}
+	output, err := exec.Command("nmcli", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list connections: %w", err)
+	}
+
+	var names []string
+	for _, name := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if name = strings.ReplaceAll(name, "\\:", ":"); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// getNetworkManagerActiveDNS returns the DNS servers an active connection
+// uses, whether set by hand or obtained by DHCP
+func getNetworkManagerActiveDNS(connName string) []string {
+	output, err := exec.Command("nmcli", "-t", "-f", "IP4.DNS,IP6.DNS", "connection", "show", connName).Output()
+	if err != nil {
+		return nil
+	}
+
+	// Lines look like "IP4.DNS[1]:192.168.1.1"; IPv6 colons are escaped
+	var servers []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if _, server, found := strings.Cut(line, ":"); found && server != "" {
+			servers = append(servers, strings.ReplaceAll(server, "\\:", ":"))
+		}
+	}
+	return servers
+}
+
+// getSystemdResolvedDNS returns the global and per-link DNS servers
+// reported by systemd-resolved
+func getSystemdResolvedDNS() ([]string, error) {
+	links, err := resolvedLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	var servers []string
+	for _, link := range links {
+		servers = append(servers, link.Servers...)
+	}
+	return servers, nil
+}
+
 // isSystemdResolved checks if systemd-resolved is managing DNS
 func isSystemdResolved() bool {
 	// Check if /etc/resolv.conf is a symlink to systemd-resolved
@@ -89,57 +393,113 @@ func isNetworkManager() bool {
 }
 
 // setDNSSystemdResolved configures DNS via systemd-resolved
-func setDNSSystemdResolved(server string) error {
-	// Get the default interface
-	iface, err := getDefaultInterface()
+func setDNSSystemdResolved(servers []string, include InterfaceFilter) error {
+	all, err := getActiveInterfaces()
 	if err != nil {
-		return fmt.Errorf("failed to get default interface: %w", err)
+		return fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+
+	var interfaces []string
+	for _, iface := range all {
+		if include.includes(iface) {
+			interfaces = append(interfaces, iface)
+		}
+	}
+	if len(interfaces) == 0 {
+		return fmt.Errorf("no network interfaces match the interface policy")
 	}
 
 	// Create persistent backup
 	backup := &DNSBackup{
 		Linux: &LinuxDNSBackup{
-			System:    "systemd-resolved",
-			Interface: iface,
+			System:     "systemd-resolved",
+			Interfaces: interfaces,
 		},
 	}
 	if err := SaveBackup(backup); err != nil {
 		return fmt.Errorf("failed to save DNS backup: %w", err)
 	}
 
-	// Use resolvectl to set DNS for the interface
-	cmd := exec.Command("resolvectl", "dns", iface, server)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("resolvectl failed: %s: %w", string(output), err)
+	r, err := connectResolved()
+	if err != nil {
+		return err
+	}
+	for _, iface := range interfaces {
+		if err := r.filterLink(iface, servers); err != nil {
+			return err
+		}
 	}
 
-	// Set this interface as the default route for DNS
-	cmd = exec.Command("resolvectl", "default-route", iface, "true")
-	cmd.Run() // Ignore errors, not all versions support this
+	return nil
+}
+
+// addDNSSystemdResolved points more interfaces at servers, adding them to
+// the backup
+func addDNSSystemdResolved(backup *DNSBackup, servers, interfaces []string) error {
+	if len(backup.Linux.Interfaces) == 0 && backup.Linux.Interface != "" {
+		// Backup written by an older version
+		backup.Linux.Interfaces = []string{backup.Linux.Interface}
+	}
+	for _, iface := range interfaces {
+		if !slices.Contains(backup.Linux.Interfaces, iface) {
+			backup.Linux.Interfaces = append(backup.Linux.Interfaces, iface)
+		}
+	}
+	if err := SaveBackup(backup); err != nil {
+		return fmt.Errorf("failed to save DNS backup: %w", err)
+	}
 
+	r, err := connectResolved()
+	if err != nil {
+		return err
+	}
+	for _, iface := range interfaces {
+		if err := r.filterLink(iface, servers); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // resetDNSSystemdResolved restores DNS via systemd-resolved
 func resetDNSSystemdResolved() error {
-	// Load backup to get interface name
+	// Load backup to get interface names
 	backup, _ := LoadBackup()
 
-	var iface string
-	if backup != nil && backup.Linux != nil && backup.Linux.Interface != "" {
-		iface = backup.Linux.Interface
-	} else {
-		var err error
-		iface, err = getDefaultInterface()
+	var interfaces []string
+	if backup != nil && backup.Linux != nil {
+		interfaces = backup.Linux.Interfaces
+		if len(interfaces) == 0 && backup.Linux.Interface != "" {
+			// Backup written by an older version
+			interfaces = []string{backup.Linux.Interface}
+		}
+	}
+	if len(interfaces) == 0 {
+		iface, err := getDefaultInterface()
 		if err != nil {
 			return err
 		}
+		interfaces = []string{iface}
 	}
 
-	// Revert to DHCP-provided DNS
-	cmd := exec.Command("resolvectl", "revert", iface)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("resolvectl revert failed: %s: %w", string(output), err)
+	r, err := connectResolved()
+	if err != nil {
+		return err
+	}
+
+	// Revert to DHCP-provided DNS, domains, DNSSEC and DNS over TLS
+	var firstErr error
+	for _, iface := range interfaces {
+		if _, err := net.InterfaceByName(iface); err != nil {
+			// Gone since, and resolved forgot its settings with it
+			continue
+		}
+		if err := r.revertLink(iface); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return firstErr
 	}
 
 	// Clear backup
@@ -148,69 +508,171 @@ func resetDNSSystemdResolved() error {
 	return nil
 }
 
-// setDNSNetworkManager configures DNS via NetworkManager
-func setDNSNetworkManager(server string) error {
-	// Get the active connection
-	cmd := exec.Command("nmcli", "-t", "-f", "NAME,DEVICE,STATE", "connection", "show", "--active")
-	output, err := cmd.Output()
+// setDNSNetworkManager configures DNS via NetworkManager. Resolvers from
+// DHCP, DHCPv6 and router advertisements are ignored for both families,
+// so a dual-stack link cannot bypass the proxy over IPv6.
+func setDNSNetworkManager(ipv4, ipv6 []string, include InterfaceFilter) error {
+	active, err := networkManagerDevices(include)
 	if err != nil {
-		return fmt.Errorf("failed to get active connection: %w", err)
+		return err
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	if len(lines) == 0 {
-		return fmt.Errorf("no active network connection")
+	var connections []NMConnectionBackup
+	for _, conn := range active {
+		connections = append(connections, backupNetworkManagerConnection(conn))
 	}
-
-	// Parse the first active connection
-	parts := strings.Split(lines[0], ":")
-	if len(parts) < 1 {
-		return fmt.Errorf("failed to parse connection info")
+	if len(connections) == 0 {
+		return fmt.Errorf("no active network connection matches the interface policy")
 	}
-	connName := parts[0]
-
-	// Get current DNS settings for backup
-	currentDNS, ignoreAutoDNS := getNetworkManagerDNS(connName)
 
 	// Create persistent backup BEFORE modifying
 	backup := &DNSBackup{
 		Linux: &LinuxDNSBackup{
-			System:           "networkmanager",
-			ConnectionName:   connName,
-			OriginalDNS:      currentDNS,
-			IgnoreAutoDNS:    ignoreAutoDNS,
+			System:      "networkmanager",
+			Connections: connections,
 		},
 	}
 	if err := SaveBackup(backup); err != nil {
 		return fmt.Errorf("failed to save DNS backup: %w", err)
 	}
 
+	for _, conn := range connections {
+		if err := filterNetworkManagerConnection(conn, ipv4, ipv6); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addDNSNetworkManager points the connections of more devices at the
+// proxy, adding them to the backup; the connections already filtering
+// stay up
+func addDNSNetworkManager(backup *DNSBackup, ipv4, ipv6, devices []string) error {
+	active, err := networkManagerDevices(linkFilter(devices))
+	if err != nil {
+		return err
+	}
+
+	if len(backup.Linux.Connections) == 0 && backup.Linux.ConnectionName != "" {
+		// Backup written by an older version
+		backup.Linux.Connections = []NMConnectionBackup{{
+			Name:          backup.Linux.ConnectionName,
+			OriginalDNS:   backup.Linux.OriginalDNS,
+			IgnoreAutoDNS: backup.Linux.IgnoreAutoDNS,
+		}}
+	}
+
+	var connections []NMConnectionBackup
+	for _, conn := range active {
+		// A connection set before keeps its original settings
+		i := slices.IndexFunc(backup.Linux.Connections, func(saved NMConnectionBackup) bool {
+			return saved.Name == conn.Name
+		})
+		if i < 0 {
+			backup.Linux.Connections = append(backup.Linux.Connections, backupNetworkManagerConnection(conn))
+			i = len(backup.Linux.Connections) - 1
+		}
+		connections = append(connections, backup.Linux.Connections[i])
+	}
+
+	if err := SaveBackup(backup); err != nil {
+		return fmt.Errorf("failed to save DNS backup: %w", err)
+	}
+
+	for _, conn := range connections {
+		if err := filterNetworkManagerConnection(conn, ipv4, ipv6); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backupNetworkManagerConnection returns the current DNS settings of an
+// active connection with Name and Device set
+func backupNetworkManagerConnection(conn NMConnectionBackup) NMConnectionBackup {
+	currentDNS, ignoreAutoDNS := getNetworkManagerDNS(conn.Name, "ipv4")
+	currentDNS6, ignoreAutoDNS6 := getNetworkManagerDNS(conn.Name, "ipv6")
+	return NMConnectionBackup{
+		Name:           conn.Name,
+		Device:         conn.Device,
+		OriginalDNS:    currentDNS,
+		IgnoreAutoDNS:  ignoreAutoDNS,
+		DNSSearch:      getNetworkManagerSearch(conn.Name),
+		DNSSearchSaved: true,
+		OriginalDNS6:   currentDNS6,
+		IgnoreAutoDNS6: ignoreAutoDNS6,
+		DNS6Saved:      true,
+	}
+}
+
+// filterNetworkManagerConnection points a connection at the proxy and
+// reactivates it
+func filterNetworkManagerConnection(conn NMConnectionBackup, ipv4, ipv6 []string) error {
+	// Ignoring DHCP's DNS drops its search domains too, so keep them
+	// along with those set on the connection
+	search := slices.Clone(conn.DNSSearch)
+	for _, domain := range getNetworkManagerActiveDomains(conn.Name) {
+		if !slices.Contains(search, domain) {
+			search = append(search, domain)
+		}
+	}
+
 	// Set DNS for the connection
-	cmd = exec.Command("nmcli", "connection", "modify", connName,
-		"ipv4.dns", server,
-		"ipv4.ignore-auto-dns", "yes")
+	cmd := exec.Command("nmcli", "connection", "modify", conn.Name,
+		"ipv4.dns", strings.Join(ipv4, ","),
+		"ipv4.ignore-auto-dns", "yes",
+		"ipv4.dns-search", strings.Join(search, ","),
+		"ipv6.dns", strings.Join(ipv6, ","),
+		"ipv6.ignore-auto-dns", "yes")
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("nmcli modify failed: %s: %w", string(output), err)
+		return fmt.Errorf("nmcli modify failed on %s: %s: %w", conn.Name, string(output), err)
 	}
 
 	// Reactivate the connection
-	cmd = exec.Command("nmcli", "connection", "up", connName)
+	cmd = exec.Command("nmcli", "connection", "up", conn.Name)
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("nmcli up failed: %s: %w", string(output), err)
+		return fmt.Errorf("nmcli up failed on %s: %s: %w", conn.Name, string(output), err)
 	}
-
 	return nil
 }
 
-// getNetworkManagerDNS gets current DNS settings for a connection
-func getNetworkManagerDNS(connName string) (dns []string, ignoreAuto bool) {
+// networkManagerDevices returns the active NetworkManager connections
+// whose devices include selects, with Name and Device set
+func networkManagerDevices(include InterfaceFilter) ([]NMConnectionBackup, error) {
+	output, err := exec.Command("nmcli", "-t", "-f", "NAME,DEVICE", "connection", "show", "--active").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active connection: %w", err)
+	}
+
+	var connections []NMConnectionBackup
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		// The device is the last field; connection names may contain
+		// escaped colons
+		sep := strings.LastIndex(line, ":")
+		if sep < 0 {
+			continue
+		}
+		name := strings.ReplaceAll(line[:sep], "\\:", ":")
+		device := line[sep+1:]
+		if device == "" || device == "lo" || !include.includes(device) {
+			continue
+		}
+		connections = append(connections, NMConnectionBackup{Name: name, Device: device})
+	}
+	return connections, nil
+}
+
+// getNetworkManagerDNS gets current DNS settings for a connection; family
+// is "ipv4" or "ipv6"
+func getNetworkManagerDNS(connName, family string) (dns []string, ignoreAuto bool) {
 	// Get DNS servers
-	cmd := exec.Command("nmcli", "-t", "-f", "ipv4.dns", "connection", "show", connName)
+	cmd := exec.Command("nmcli", "-t", "-f", family+".dns", "connection", "show", connName)
 	output, err := cmd.Output()
 	if err == nil {
 		line := strings.TrimSpace(string(output))
-		if strings.HasPrefix(line, "ipv4.dns:") {
-			dnsStr := strings.TrimPrefix(line, "ipv4.dns:")
+		if strings.HasPrefix(line, family+".dns:") {
+			dnsStr := strings.TrimPrefix(line, family+".dns:")
 			if dnsStr != "" && dnsStr != "--" {
 				dns = strings.Split(dnsStr, ",")
 			}
@@ -218,7 +680,7 @@ func getNetworkManagerDNS(connName string) (dns []string, ignoreAuto bool) {
 	}
 
 	// Get ignore-auto-dns setting
-	cmd = exec.Command("nmcli", "-t", "-f", "ipv4.ignore-auto-dns", "connection", "show", connName)
+	cmd = exec.Command("nmcli", "-t", "-f", family+".ignore-auto-dns", "connection", "show", connName)
 	output, err = cmd.Output()
 	if err == nil {
 		line := strings.TrimSpace(string(output))
@@ -230,6 +692,37 @@ func getNetworkManagerDNS(connName string) (dns []string, ignoreAuto bool) {
 	return dns, ignoreAuto
 }
 
+// getNetworkManagerSearch returns the search domains set on a connection
+func getNetworkManagerSearch(connName string) []string {
+	output, err := exec.Command("nmcli", "-t", "-f", "ipv4.dns-search", "connection", "show", connName).Output()
+	if err != nil {
+		return nil
+	}
+	list := strings.TrimPrefix(strings.TrimSpace(string(output)), "ipv4.dns-search:")
+	if list == "" || list == "--" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+// getNetworkManagerActiveDomains returns the search domains an active
+// connection uses, whether set by hand or obtained by DHCP
+func getNetworkManagerActiveDomains(connName string) []string {
+	output, err := exec.Command("nmcli", "-t", "-f", "IP4.DOMAIN", "connection", "show", connName).Output()
+	if err != nil {
+		return nil
+	}
+
+	// Lines look like "IP4.DOMAIN[1]:corp.example.com"
+	var domains []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if _, domain, found := strings.Cut(line, ":"); found && domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
 // resetDNSNetworkManager restores DNS via NetworkManager
 func resetDNSNetworkManager() error {
 	// Load backup
@@ -238,18 +731,21 @@ func resetDNSNetworkManager() error {
 		return fmt.Errorf("failed to load DNS backup: %w", err)
 	}
 
-	var connName string
-	var originalDNS []string
-	var ignoreAutoDNS bool
-
+	var connections []NMConnectionBackup
 	if backup != nil && backup.Linux != nil {
-		connName = backup.Linux.ConnectionName
-		originalDNS = backup.Linux.OriginalDNS
-		ignoreAutoDNS = backup.Linux.IgnoreAutoDNS
+		connections = backup.Linux.Connections
+		if len(connections) == 0 && backup.Linux.ConnectionName != "" {
+			// Backup written by an older version
+			connections = []NMConnectionBackup{{
+				Name:          backup.Linux.ConnectionName,
+				OriginalDNS:   backup.Linux.OriginalDNS,
+				IgnoreAutoDNS: backup.Linux.IgnoreAutoDNS,
+			}}
+		}
 	}
 
 	// If no backup, get current active connection
-	if connName == "" {
+	if len(connections) == 0 {
 		cmd := exec.Command("nmcli", "-t", "-f", "NAME", "connection", "show", "--active")
 		output, err := cmd.Output()
 		if err != nil {
@@ -257,50 +753,71 @@ func resetDNSNetworkManager() error {
 			return nil
 		}
 		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-		if len(lines) == 0 {
+		if len(lines) == 0 || lines[0] == "" {
 			ClearBackup()
 			return nil
 		}
-		connName = lines[0]
+		connections = []NMConnectionBackup{{Name: lines[0], DNS6Saved: true}}
 	}
 
-	// Restore original settings
-	var dnsValue string
-	var ignoreAutoValue string
-
-	if len(originalDNS) > 0 {
-		// Restore original static DNS
-		dnsValue = strings.Join(originalDNS, ",")
-		if ignoreAutoDNS {
-			ignoreAutoValue = "yes"
-		} else {
-			ignoreAutoValue = "no"
+	var firstErr error
+	for _, conn := range connections {
+		if err := restoreNetworkManagerConnection(conn); err != nil && firstErr == nil {
+			firstErr = err
 		}
-	} else {
-		// No original DNS, restore to auto (DHCP)
-		dnsValue = ""
-		ignoreAutoValue = "no"
+	}
+	if firstErr != nil {
+		return firstErr
 	}
 
-	cmd := exec.Command("nmcli", "connection", "modify", connName,
+	// Clear backup
+	ClearBackup()
+
+	return nil
+}
+
+// restoreNetworkManagerConnection puts back the original DNS settings
+// of a single connection
+func restoreNetworkManagerConnection(conn NMConnectionBackup) error {
+	dnsValue, ignoreAutoValue := networkManagerDNSValues(conn.OriginalDNS, conn.IgnoreAutoDNS)
+	args := []string{"connection", "modify", conn.Name,
 		"ipv4.dns", dnsValue,
-		"ipv4.ignore-auto-dns", ignoreAutoValue)
+		"ipv4.ignore-auto-dns", ignoreAutoValue}
+	if conn.DNSSearchSaved {
+		args = append(args, "ipv4.dns-search", strings.Join(conn.DNSSearch, ","))
+	}
+	if conn.DNS6Saved {
+		dnsValue, ignoreAutoValue = networkManagerDNSValues(conn.OriginalDNS6, conn.IgnoreAutoDNS6)
+		args = append(args,
+			"ipv6.dns", dnsValue,
+			"ipv6.ignore-auto-dns", ignoreAutoValue)
+	}
+	cmd := exec.Command("nmcli", args...)
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("nmcli modify failed: %s: %w", string(output), err)
+		return fmt.Errorf("nmcli modify failed on %s: %s: %w", conn.Name, string(output), err)
 	}
 
 	// Reactivate
-	cmd = exec.Command("nmcli", "connection", "up", connName)
+	cmd = exec.Command("nmcli", "connection", "up", conn.Name)
 	cmd.Run()
 
-	// Clear backup
-	ClearBackup()
-
 	return nil
 }
 
+// networkManagerDNSValues returns the dns and ignore-auto-dns values that
+// put back original servers, or automatic DNS if there were none
+func networkManagerDNSValues(original []string, ignoreAuto bool) (dns, ignoreAutoValue string) {
+	if len(original) == 0 {
+		return "", "no"
+	}
+	if ignoreAuto {
+		return strings.Join(original, ","), "yes"
+	}
+	return strings.Join(original, ","), "no"
+}
+
 // setDNSResolvConf directly modifies /etc/resolv.conf
-func setDNSResolvConf(server string) error {
+func setDNSResolvConf(servers []string) error {
 	// Backup the original file (only if no backup exists)
 	if _, err := os.Stat(resolvConfBackup); os.IsNotExist(err) {
 		input, err := os.ReadFile(resolvConf)
@@ -311,6 +828,17 @@ func setDNSResolvConf(server string) error {
 			return fmt.Errorf("failed to backup resolv.conf: %w", err)
 		}
 	}
+	original, err := os.ReadFile(resolvConfBackup)
+	if err != nil {
+		return fmt.Errorf("failed to read resolv.conf backup: %w", err)
+	}
+
+	// dhcpcd would put its own servers back on the next lease
+	if isDhcpcd() {
+		if err := holdDhcpcd(); err != nil {
+			return fmt.Errorf("failed to keep dhcpcd from rewriting resolv.conf: %w", err)
+		}
+	}
 
 	// Also create JSON backup for consistency
 	backup := &DNSBackup{
@@ -321,8 +849,13 @@ func setDNSResolvConf(server string) error {
 	}
 	SaveBackup(backup)
 
-	// Write new resolv.conf
-	content := fmt.Sprintf("# Generated by FilterDNS Client\nnameserver %s\n", server)
+	// Write new resolv.conf, keeping the original's search domains and
+	// options such as ndots
+	content := "# Generated by FilterDNS Client\n"
+	for _, server := range servers {
+		content += fmt.Sprintf("nameserver %s\n", server)
+	}
+	content += resolvConfSettings(string(original))
 	if err := os.WriteFile(resolvConf, []byte(content), 0644); err != nil {
 		return fmt.Errorf("failed to write resolv.conf: %w", err)
 	}
@@ -330,8 +863,28 @@ func setDNSResolvConf(server string) error {
 	return nil
 }
 
+// resolvConfSettings returns the lines of a resolv.conf other than its
+// nameservers and comments: search, domain, sortlist and options
+func resolvConfSettings(content string) string {
+	var settings string
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "search", "domain", "sortlist", "options":
+			settings += strings.TrimSpace(line) + "\n"
+		}
+	}
+	return settings
+}
+
 // resetDNSResolvConf restores the original /etc/resolv.conf
 func resetDNSResolvConf() error {
+	// After the restored copy, so a new lease can update it
+	defer releaseDhcpcd()
+
 	if _, err := os.Stat(resolvConfBackup); os.IsNotExist(err) {
 		ClearBackup()
 		return nil // No backup to restore
@@ -351,6 +904,24 @@ func resetDNSResolvConf() error {
 	return nil
 }
 
+// getActiveInterfaces returns the names of all interfaces that are up,
+// excluding loopback
+func getActiveInterfaces() ([]string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		names = append(names, iface.Name)
+	}
+	return names, nil
+}
+
 // getDefaultInterface returns the name of the default network interface
 func getDefaultInterface() (string, error) {
 	// Parse /proc/net/route to find default gateway interface
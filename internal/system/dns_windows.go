@@ -4,31 +4,76 @@ package system
 
 import (
 	"fmt"
+	"net"
 	"os/exec"
+	"slices"
 	"strconv"
 	"strings"
 )
 
-// setDNS sets the system DNS server on Windows
-func setDNS(server string) error {
-	interfaces, err := getInterfaces()
+// netInterface is a network adapter as listed by netsh
+type netInterface struct {
+	Index int
+	Name  string
+}
+
+// setDNS sets the system DNS servers on Windows
+func setDNS(servers []string, include InterfaceFilter) error {
+	ipv4, ipv6 := splitFamilies(servers)
+
+	all, err := getInterfaces()
 	if err != nil {
 		return err
 	}
 
+	var interfaces []int
+	for _, iface := range all {
+		if include.includes(iface.Name) {
+			interfaces = append(interfaces, iface.Index)
+		}
+	}
+	if len(interfaces) == 0 {
+		return fmt.Errorf("no network interfaces match the interface policy")
+	}
+
 	// Create persistent backup before modifying
 	backup := &DNSBackup{
 		Windows: &WindowsDNSBackup{
 			Interfaces: make(map[int][]string),
+			Modified:   interfaces,
 		},
 	}
 
+	if len(ipv6) > 0 {
+		backup.Windows.IPv6Interfaces = make(map[int][]string)
+	}
+
+	if suffixes, searchList, err := dnsSuffixes(); err == nil {
+		backup.Windows.Suffixes = make(map[int]string)
+		for _, iface := range interfaces {
+			if suffix := suffixes[iface]; suffix != "" {
+				backup.Windows.Suffixes[iface] = suffix
+			}
+		}
+		backup.Windows.SuffixSearchList = searchList
+		backup.Windows.SuffixesSaved = true
+	}
+
+	// Windows 11 upgrades DNS to known DoH servers by itself, which
+	// would send queries past the proxy
+	backup.Windows.DoHPolicy = dohPolicy()
+	backup.Windows.DoHProhibited = true
+
 	for _, iface := range interfaces {
 		// Get and store current DNS
 		current, _ := getDNSForInterface(iface)
 		if len(current) > 0 {
 			backup.Windows.Interfaces[iface] = current
 		}
+		if len(ipv6) > 0 {
+			current, _ := getIPv6DNSForInterface(iface)
+			backup.Windows.IPv6Interfaces[iface] = current
+		}
 	}
 
 	// Save backup to disk BEFORE modifying DNS
@@ -38,22 +83,123 @@ func setDNS(server string) error {
 
 	// Now modify DNS
 	for _, iface := range interfaces {
-		cmd := exec.Command("netsh", "interface", "ipv4", "set", "dnsservers",
-			fmt.Sprintf("name=%d", iface),
-			"source=static",
-			fmt.Sprintf("address=%s", server),
-			"validate=no")
-		if output, err := cmd.CombinedOutput(); err != nil {
-			return fmt.Errorf("failed to set DNS for interface %d: %s: %w", iface, string(output), err)
+		if err := setInterfaceDNS("ipv4", iface, ipv4); err != nil {
+			return fmt.Errorf("failed to set DNS for interface %d: %w", iface, err)
+		}
+
+		// Adapters with IPv6 disabled refuse this; IPv4 alone still works
+		if len(ipv6) > 0 {
+			setInterfaceDNS("ipv6", iface, ipv6)
 		}
 	}
 
+	// Note the suffixes as set, so restoring only puts back those that
+	// setting DNS changed; a failed save leaves them as they are
+	if suffixes, searchList, err := dnsSuffixes(); err == nil && backup.Windows.SuffixesSaved {
+		backup.Windows.SuffixesSet = make(map[int]string)
+		for _, iface := range interfaces {
+			if suffix := suffixes[iface]; suffix != "" {
+				backup.Windows.SuffixesSet[iface] = suffix
+			}
+		}
+		backup.Windows.SuffixSearchListSet = searchList
+		SaveBackup(backup)
+	}
+
+	if err := prohibitDoH(); err != nil {
+		return fmt.Errorf("failed to turn off DNS over HTTPS: %w", err)
+	}
+
 	// Flush DNS cache
 	exec.Command("ipconfig", "/flushdns").Run()
 
 	return nil
 }
 
+// addDNSLinks points more interfaces at servers on Windows, adding them
+// to the backup
+func addDNSLinks(servers, links []string) error {
+	backup, err := LoadBackup()
+	if err != nil {
+		return fmt.Errorf("failed to load DNS backup: %w", err)
+	}
+	if backup == nil || backup.Windows == nil || len(backup.Windows.Modified) == 0 {
+		// Nothing set before to add to, or an older backup that restores
+		// every interface
+		return setDNS(servers, linkFilter(links))
+	}
+	ipv4, ipv6 := splitFamilies(servers)
+
+	all, err := getInterfaces()
+	if err != nil {
+		return err
+	}
+	var interfaces []int
+	for _, iface := range all {
+		if slices.Contains(links, iface.Name) {
+			interfaces = append(interfaces, iface.Index)
+		}
+	}
+
+	if backup.Windows.Interfaces == nil {
+		backup.Windows.Interfaces = make(map[int][]string)
+	}
+	if len(ipv6) > 0 && backup.Windows.IPv6Interfaces == nil {
+		backup.Windows.IPv6Interfaces = make(map[int][]string)
+	}
+	if backup.Windows.Suffixes == nil {
+		backup.Windows.Suffixes = make(map[int]string)
+	}
+	suffixes, _, suffixErr := dnsSuffixes()
+	for _, iface := range interfaces {
+		// An interface set before keeps its original settings
+		if slices.Contains(backup.Windows.Modified, iface) {
+			continue
+		}
+		backup.Windows.Modified = append(backup.Windows.Modified, iface)
+		if current, _ := getDNSForInterface(iface); len(current) > 0 {
+			backup.Windows.Interfaces[iface] = current
+		}
+		if len(ipv6) > 0 {
+			current, _ := getIPv6DNSForInterface(iface)
+			backup.Windows.IPv6Interfaces[iface] = current
+		}
+		if suffixErr == nil && backup.Windows.SuffixesSaved {
+			if suffix := suffixes[iface]; suffix != "" {
+				backup.Windows.Suffixes[iface] = suffix
+			}
+		}
+	}
+	if err := SaveBackup(backup); err != nil {
+		return fmt.Errorf("failed to save DNS backup: %w", err)
+	}
+
+	for _, iface := range interfaces {
+		if err := setInterfaceDNS("ipv4", iface, ipv4); err != nil {
+			return fmt.Errorf("failed to set DNS for interface %d: %w", iface, err)
+		}
+		if len(ipv6) > 0 {
+			setInterfaceDNS("ipv6", iface, ipv6)
+		}
+	}
+
+	if suffixes, _, err := dnsSuffixes(); err == nil && backup.Windows.SuffixesSaved {
+		if backup.Windows.SuffixesSet == nil {
+			backup.Windows.SuffixesSet = make(map[int]string)
+		}
+		for _, iface := range interfaces {
+			if suffix := suffixes[iface]; suffix != "" {
+				backup.Windows.SuffixesSet[iface] = suffix
+			}
+		}
+		SaveBackup(backup)
+	}
+
+	exec.Command("ipconfig", "/flushdns").Run()
+
+	return nil
+}
+
 // resetDNS restores the original system DNS settings on Windows
 func resetDNS() error {
 	// Load backup from disk
@@ -62,11 +208,22 @@ func resetDNS() error {
 		return fmt.Errorf("failed to load DNS backup: %w", err)
 	}
 
-	interfaces, err := getInterfaces()
+	all, err := getInterfaces()
 	if err != nil {
 		return err
 	}
 
+	var interfaces []int
+	for _, iface := range all {
+		interfaces = append(interfaces, iface.Index)
+	}
+
+	// Only touch the interfaces we changed, so excluded ones (e.g. VPNs)
+	// keep their own DNS
+	if backup != nil && backup.Windows != nil && len(backup.Windows.Modified) > 0 {
+		interfaces = backup.Windows.Modified
+	}
+
 	for _, iface := range interfaces {
 		// Check if we have a backup for this interface
 		if backup != nil && backup.Windows != nil {
@@ -103,6 +260,27 @@ func resetDNS() error {
 		}
 	}
 
+	// Put back IPv6 DNS if it was set too
+	if backup != nil && backup.Windows != nil {
+		for iface, original := range backup.Windows.IPv6Interfaces {
+			if len(original) > 0 {
+				setInterfaceDNS("ipv6", iface, original)
+			} else {
+				exec.Command("netsh", "interface", "ipv6", "set", "dnsservers",
+					fmt.Sprintf("name=%d", iface),
+					"source=dhcp").Run()
+			}
+		}
+	}
+
+	if backup != nil && backup.Windows != nil && backup.Windows.SuffixesSaved {
+		restoreDNSSuffixes(backup.Windows, interfaces)
+	}
+	if backup != nil && backup.Windows != nil && backup.Windows.DoHProhibited {
+		restoreDoH(backup.Windows.DoHPolicy)
+	}
+	clearSplitDNS()
+
 	// Clear backup file after successful restore
 	ClearBackup()
 
@@ -112,6 +290,99 @@ func resetDNS() error {
 	return nil
 }
 
+// unfilteredLinks returns the connected interfaces include selects whose
+// IPv4 DNS servers are not the local proxy
+func unfilteredLinks(include InterfaceFilter) ([]string, error) {
+	interfaces, err := getInterfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var links []string
+	for _, iface := range interfaces {
+		if !include.includes(iface.Name) {
+			continue
+		}
+		if current, _ := getDNSForInterface(iface.Index); !usesProxy(current) {
+			links = append(links, iface.Name)
+		}
+	}
+	return links, nil
+}
+
+// restoreLeftovers sets every interface still using the local proxy back
+// to DHCP-provided DNS
+func restoreLeftovers() error {
+	interfaces, err := getInterfaces()
+	if err != nil {
+		return err
+	}
+
+	for _, iface := range interfaces {
+		if current, _ := getDNSForInterface(iface.Index); usesProxy(current) {
+			cmd := exec.Command("netsh", "interface", "ipv4", "set", "dnsservers",
+				fmt.Sprintf("name=%d", iface.Index), "source=dhcp")
+			if output, err := cmd.CombinedOutput(); err != nil {
+				return fmt.Errorf("failed to reset DNS for %s: %s: %w", iface.Name, string(output), err)
+			}
+		}
+		if current, _ := getIPv6DNSForInterface(iface.Index); usesProxy(current) {
+			exec.Command("netsh", "interface", "ipv6", "set", "dnsservers",
+				fmt.Sprintf("name=%d", iface.Index), "source=dhcp").Run()
+		}
+	}
+
+	if err := clearSplitDNS(); err != nil {
+		return err
+	}
+
+	exec.Command("ipconfig", "/flushdns").Run()
+	return nil
+}
+
+// revertDNS sets every connected interface back to DHCP-provided DNS,
+// whatever it is set to
+func revertDNS() error {
+	interfaces, err := getInterfaces()
+	if err != nil {
+		return err
+	}
+
+	for _, iface := range interfaces {
+		cmd := exec.Command("netsh", "interface", "ipv4", "set", "dnsservers",
+			fmt.Sprintf("name=%d", iface.Index), "source=dhcp")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to reset DNS for %s: %s: %w", iface.Name, string(output), err)
+		}
+		exec.Command("netsh", "interface", "ipv6", "set", "dnsservers",
+			fmt.Sprintf("name=%d", iface.Index), "source=dhcp").Run()
+	}
+
+	if err := clearSplitDNS(); err != nil {
+		return err
+	}
+
+	exec.Command("ipconfig", "/flushdns").Run()
+	return nil
+}
+
+// inspectDNS returns the DNS servers of each connected interface, and
+// those of the NRPT rules we added for split DNS
+func inspectDNS() (string, []ResolverLink, error) {
+	interfaces, err := getInterfaces()
+	if err != nil {
+		return "netsh", nil, err
+	}
+
+	var links []ResolverLink
+	for _, iface := range interfaces {
+		servers, _ := getDNSForInterface(iface.Index)
+		ipv6, _ := getIPv6DNSForInterface(iface.Index)
+		links = append(links, ResolverLink{Name: iface.Name, Servers: append(servers, ipv6...)})
+	}
+	return "netsh", append(links, splitDNSLinks()...), nil
+}
+
 // getCurrentDNS returns the current system DNS servers on Windows
 func getCurrentDNS() ([]string, error) {
 	interfaces, err := getInterfaces()
@@ -123,7 +394,7 @@ func getCurrentDNS() ([]string, error) {
 	var servers []string
 
 	for _, iface := range interfaces {
-		dns, err := getDNSForInterface(iface)
+		dns, err := getDNSForInterface(iface.Index)
 		if err != nil {
 			continue
 		}
@@ -138,19 +409,19 @@ func getCurrentDNS() ([]string, error) {
 	return servers, nil
 }
 
-// getInterfaces returns interface indices for active network adapters
-func getInterfaces() ([]int, error) {
+// getInterfaces returns the active network adapters
+func getInterfaces() ([]netInterface, error) {
 	cmd := exec.Command("netsh", "interface", "ipv4", "show", "interfaces")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, err
 	}
 
-	var interfaces []int
+	var interfaces []netInterface
 	lines := strings.Split(string(output), "\n")
 	for _, line := range lines {
 		fields := strings.Fields(line)
-		if len(fields) < 4 {
+		if len(fields) < 5 {
 			continue
 		}
 
@@ -163,12 +434,120 @@ func getInterfaces() ([]int, error) {
 		if err != nil {
 			continue
 		}
-		interfaces = append(interfaces, idx)
+		interfaces = append(interfaces, netInterface{
+			Index: idx,
+			Name:  strings.Join(fields[4:], " "),
+		})
 	}
 
 	return interfaces, nil
 }
 
+// setInterfaceDNS sets static DNS servers of one address family ("ipv4"
+// or "ipv6") on an interface
+func setInterfaceDNS(family string, iface int, servers []string) error {
+	for i, server := range servers {
+		args := []string{"interface", family, "add", "dnsservers",
+			fmt.Sprintf("name=%d", iface),
+			fmt.Sprintf("address=%s", server),
+			"validate=no"}
+		if i == 0 {
+			args = []string{"interface", family, "set", "dnsservers",
+				fmt.Sprintf("name=%d", iface),
+				"source=static",
+				fmt.Sprintf("address=%s", server),
+				"validate=no"}
+		}
+		if output, err := exec.Command("netsh", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %w", string(output), err)
+		}
+	}
+	return nil
+}
+
+// dnsSuffixes returns the connection-specific DNS suffix of each
+// interface by index, and the global suffix search list
+func dnsSuffixes() (map[int]string, []string, error) {
+	script := `Get-DnsClient | ForEach-Object { "$($_.InterfaceIndex) $($_.ConnectionSpecificSuffix)" }; ` +
+		`"search " + ((Get-DnsClientGlobalSetting).SuffixSearchList -join ' ')`
+	output, err := powershell(script)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	suffixes := make(map[int]string)
+	var searchList []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "search" {
+			searchList = fields[1:]
+		} else if idx, err := strconv.Atoi(fields[0]); err == nil && len(fields) > 1 {
+			suffixes[idx] = fields[1]
+		}
+	}
+	return suffixes, searchList, nil
+}
+
+// restoreDNSSuffixes puts back the DNS suffixes of the given interfaces
+// and the global suffix search list where setting DNS changed them, and
+// leaves those changed again since alone
+func restoreDNSSuffixes(backup *WindowsDNSBackup, interfaces []int) error {
+	suffixes, searchList, err := dnsSuffixes()
+	if err != nil {
+		return err
+	}
+
+	var script []string
+	for _, iface := range interfaces {
+		if original := backup.Suffixes[iface]; suffixes[iface] != original && suffixes[iface] == backup.SuffixesSet[iface] {
+			script = append(script, fmt.Sprintf("Set-DnsClient -InterfaceIndex %d -ConnectionSpecificSuffix %s", iface, psQuote(original)))
+		}
+	}
+	if !slices.Equal(searchList, backup.SuffixSearchList) && slices.Equal(searchList, backup.SuffixSearchListSet) {
+		quoted := []string{}
+		for _, suffix := range backup.SuffixSearchList {
+			quoted = append(quoted, psQuote(suffix))
+		}
+		script = append(script, fmt.Sprintf("Set-DnsClientGlobalSetting -SuffixSearchList @(%s)", strings.Join(quoted, ",")))
+	}
+	if len(script) == 0 {
+		return nil
+	}
+	_, err = powershell(strings.Join(script, "; "))
+	return err
+}
+
+// psQuote quotes s as a PowerShell string literal
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// getIPv6DNSForInterface returns the IPv6 DNS servers for an interface
+func getIPv6DNSForInterface(iface int) ([]string, error) {
+	cmd := exec.Command("netsh", "interface", "ipv6", "show", "dnsservers", fmt.Sprintf("name=%d", iface))
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var servers []string
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		// The first server shares its line with the label
+		if ip := net.ParseIP(fields[len(fields)-1]); ip != nil && ip.To4() == nil {
+			servers = append(servers, fields[len(fields)-1])
+		}
+	}
+
+	return servers, nil
+}
+
 // getDNSForInterface returns the DNS servers for a specific interface
 func getDNSForInterface(iface int) ([]string, error) {
 	cmd := exec.Command("netsh", "interface", "ipv4", "show", "dnsservers", fmt.Sprintf("name=%d", iface))
@@ -0,0 +1,171 @@
+package system
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// knownDoHServers are public resolvers that browsers and apps use for DNS
+// over HTTPS (and QUIC) on port 443, past the system resolver. Blocking
+// them there makes those apps fall back to system DNS.
+var knownDoHServers = []string{
+	// Cloudflare, also its malware and family filters
+	"1.1.1.1", "1.0.0.1", "1.1.1.2", "1.0.0.2", "1.1.1.3", "1.0.0.3",
+	"2606:4700:4700::1111", "2606:4700:4700::1001",
+	"2606:4700:4700::1112", "2606:4700:4700::1002",
+	"2606:4700:4700::1113", "2606:4700:4700::1003",
+	// Google
+	"8.8.8.8", "8.8.4.4", "2001:4860:4860::8888", "2001:4860:4860::8844",
+	// Quad9
+	"9.9.9.9", "149.112.112.112", "9.9.9.11", "149.112.112.11",
+	"2620:fe::fe", "2620:fe::9", "2620:fe::11", "2620:fe::fe:11",
+	// OpenDNS
+	"208.67.222.222", "208.67.220.220", "2620:119:35::35", "2620:119:53::53",
+	// AdGuard
+	"94.140.14.14", "94.140.15.15", "2a10:50c0::ad1:ff", "2a10:50c0::ad2:ff",
+	// NextDNS
+	"45.90.28.0", "45.90.30.0", "2a07:a8c0::", "2a07:a8c1::",
+	// Mullvad
+	"194.242.2.2", "2a07:e340::2",
+}
+
+// blockedDNSPorts are the ports of plain DNS and DNS over TLS
+var blockedDNSPorts = []int{53, 853}
+
+// joinPorts lists ports separated by commas, as firewall rules take them
+func joinPorts(ports []int) string {
+	s := make([]string, len(ports))
+	for i, port := range ports {
+		s[i] = strconv.Itoa(port)
+	}
+	return strings.Join(s, ", ")
+}
+
+// Firewall describes the rules BlockOutsideDNS installed, as saved to
+// disk so they can be removed after a crash
+type Firewall struct {
+	CreatedAt time.Time `json:"created_at"`
+	Backend   string    `json:"backend"`            // "nftables", "pf" or "wfp"
+	PfToken   string    `json:"pf_token,omitempty"` // Reference taken when enabling pf, released on removal
+}
+
+// dnsExemption is a DNS server reachable despite the block: a forwarder,
+// which the system resolver may query directly for split DNS
+type dnsExemption struct {
+	ip   net.IP
+	port int
+}
+
+// firewallFilePath returns the path to the firewall state file
+func firewallFilePath() string {
+	return filepath.Join(StateDir(), "firewall.json")
+}
+
+// BlockOutsideDNS installs firewall rules that block outgoing plain DNS,
+// DNS over TLS, and DNS over HTTPS to well-known public resolvers, for
+// every program but the daemon, which is recognized by uid (Linux and
+// macOS) or by its executable (Windows). Servers of the forwarders stay
+// reachable. The rules replace any installed before and are recorded on
+// disk first, so that a crash cannot leave them behind for good.
+// Implementation is platform-specific
+func BlockOutsideDNS(uid int, forwarders []SplitDomain) error {
+	var exempt []dnsExemption
+	for _, f := range forwarders {
+		addr, port, err := f.splitServer()
+		if err != nil {
+			return err
+		}
+		n := 53
+		if port != "" {
+			if n, err = strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+				return fmt.Errorf("invalid split DNS server %q", f.Server)
+			}
+		}
+		exempt = append(exempt, dnsExemption{ip: net.ParseIP(addr), port: n})
+	}
+
+	// Keep the state of rules still in place, e.g. the pf token
+	state, _ := loadFirewall()
+	if state == nil {
+		state = &Firewall{}
+	}
+	if err := saveFirewall(state); err != nil {
+		return fmt.Errorf("failed to save firewall state: %w", err)
+	}
+
+	if err := blockOutsideDNS(state, uid, exempt); err != nil {
+		unblockOutsideDNS(state)
+		clearFirewall()
+		return err
+	}
+	return saveFirewall(state)
+}
+
+// UnblockOutsideDNS removes the rules BlockOutsideDNS installed, whether
+// by this process or one that crashed. Without any rules it does nothing.
+// Implementation is platform-specific
+func UnblockOutsideDNS() error {
+	state, err := loadFirewall()
+	if err != nil {
+		// A damaged state file still means rules may be in place
+		state = &Firewall{}
+	}
+	if state == nil {
+		return nil
+	}
+
+	if err := unblockOutsideDNS(state); err != nil {
+		return err
+	}
+	return clearFirewall()
+}
+
+// OutsideDNSBlocked reports whether rules from BlockOutsideDNS are
+// installed, or were when the process that installed them died
+func OutsideDNSBlocked() bool {
+	_, err := os.Stat(firewallFilePath())
+	return err == nil
+}
+
+// saveFirewall persists the firewall state to disk
+func saveFirewall(state *Firewall) error {
+	state.CreatedAt = time.Now()
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(firewallFilePath(), data, 0644)
+}
+
+// loadFirewall loads the firewall state, or nil if no rules are installed
+func loadFirewall() (*Firewall, error) {
+	data, err := os.ReadFile(firewallFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var state Firewall
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// clearFirewall removes the firewall state file
+func clearFirewall() error {
+	err := os.Remove(firewallFilePath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+//go:build darwin
+
+package system
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// pfAnchor is the anchor holding our rules. The default pf.conf evaluates
+// every anchor below com.apple, so no change to it is needed.
+const pfAnchor = "com.apple/filterdns"
+
+// pfTokenPattern finds the reference "pfctl -E" prints
+var pfTokenPattern = regexp.MustCompile(`Token : (\d+)`)
+
+// blockOutsideDNS loads our rules into a pf anchor and enables pf, taking
+// a reference that keeps it on until we release it. Blocked connections
+// are answered with a reset or ICMP, so programs fail fast.
+func blockOutsideDNS(state *Firewall, uid int, exempt []dnsExemption) error {
+	state.Backend = "pf"
+	ports := joinPorts(blockedDNSPorts)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "table <filterdns_doh> const { %s }\n", strings.Join(knownDoHServers, ", "))
+	b.WriteString("pass out quick on lo0 all\n")
+	fmt.Fprintf(&b, "pass out quick proto { tcp, udp } all user %d\n", uid)
+	for _, e := range exempt {
+		fmt.Fprintf(&b, "pass out quick proto { tcp, udp } to %s port %d\n", e.ip, e.port)
+	}
+	fmt.Fprintf(&b, "block return out quick proto { tcp, udp } to any port { %s }\n", ports)
+	b.WriteString("block return out quick proto { tcp, udp } to <filterdns_doh> port 443\n")
+
+	cmd := exec.Command("pfctl", "-a", pfAnchor, "-f", "-")
+	cmd.Stdin = strings.NewReader(b.String())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pfctl failed to load rules: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+
+	// Rules replaced while pf was already enabled by us keep the token
+	if state.PfToken != "" {
+		return nil
+	}
+	output, err := exec.Command("pfctl", "-E").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("pfctl failed to enable pf: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	if m := pfTokenPattern.FindSubmatch(output); m != nil {
+		state.PfToken = string(m[1])
+	}
+	return nil
+}
+
+// unblockOutsideDNS flushes our anchor and releases our reference on pf,
+// which turns it off again unless something else enabled it too
+func unblockOutsideDNS(state *Firewall) error {
+	if output, err := exec.Command("pfctl", "-a", pfAnchor, "-F", "all").CombinedOutput(); err != nil {
+		return fmt.Errorf("pfctl failed to flush rules: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	if state.PfToken != "" {
+		// A token from before a reboot is unknown to pf, which is fine
+		exec.Command("pfctl", "-X", state.PfToken).Run()
+		state.PfToken = ""
+	}
+	return nil
+}
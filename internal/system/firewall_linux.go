@@ -0,0 +1,80 @@
+//go:build linux
+
+package system
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// nftTable is the nftables table holding our rules; it is replaced and
+// deleted as a whole
+const nftTable = "inet filterdns"
+
+// blockOutsideDNS loads an nftables table whose output chain rejects DNS
+// from other users than uid, so that programs fail fast instead of
+// waiting for a timeout. Loopback traffic, which includes every query
+// to the proxy, passes.
+func blockOutsideDNS(state *Firewall, uid int, exempt []dnsExemption) error {
+	if _, err := exec.LookPath("nft"); err != nil {
+		return fmt.Errorf("blocking outside DNS needs nftables (the nft command): %w", err)
+	}
+	state.Backend = "nftables"
+
+	var doh4, doh6 []string
+	for _, server := range knownDoHServers {
+		if strings.Contains(server, ":") {
+			doh6 = append(doh6, server)
+		} else {
+			doh4 = append(doh4, server)
+		}
+	}
+	ports := joinPorts(blockedDNSPorts)
+
+	var b strings.Builder
+	// Declaring the table first makes deleting it succeed if it is new,
+	// and the whole script is applied at once or not at all
+	fmt.Fprintf(&b, "table %s\ndelete table %s\n", nftTable, nftTable)
+	fmt.Fprintf(&b, "table %s {\n", nftTable)
+	fmt.Fprintf(&b, "\tset doh4 { type ipv4_addr; elements = { %s } }\n", strings.Join(doh4, ", "))
+	fmt.Fprintf(&b, "\tset doh6 { type ipv6_addr; elements = { %s } }\n", strings.Join(doh6, ", "))
+	b.WriteString("\tchain output {\n\t\ttype filter hook output priority 0; policy accept;\n")
+	b.WriteString("\t\toifname \"lo\" accept\n")
+	fmt.Fprintf(&b, "\t\tmeta skuid %d accept\n", uid)
+	for _, e := range exempt {
+		family := "ip"
+		if e.ip.To4() == nil {
+			family = "ip6"
+		}
+		fmt.Fprintf(&b, "\t\t%s daddr %s udp dport %d accept\n", family, e.ip, e.port)
+		fmt.Fprintf(&b, "\t\t%s daddr %s tcp dport %d accept\n", family, e.ip, e.port)
+	}
+	fmt.Fprintf(&b, "\t\tudp dport { %s } reject\n", ports)
+	fmt.Fprintf(&b, "\t\ttcp dport { %s } reject with tcp reset\n", ports)
+	b.WriteString("\t\tip daddr @doh4 tcp dport 443 reject with tcp reset\n")
+	b.WriteString("\t\tip daddr @doh4 udp dport 443 reject\n")
+	b.WriteString("\t\tip6 daddr @doh6 tcp dport 443 reject with tcp reset\n")
+	b.WriteString("\t\tip6 daddr @doh6 udp dport 443 reject\n")
+	b.WriteString("\t}\n}\n")
+
+	return nft(b.String())
+}
+
+// unblockOutsideDNS deletes our nftables table, if it is there
+func unblockOutsideDNS(state *Firewall) error {
+	if _, err := exec.LookPath("nft"); err != nil {
+		return nil // Then there cannot be any rules
+	}
+	return nft(fmt.Sprintf("table %s\ndelete table %s\n", nftTable, nftTable))
+}
+
+// nft applies an nftables script
+func nft(script string) error {
+	cmd := exec.Command("nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(script)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("nft failed: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
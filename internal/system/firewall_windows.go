@@ -0,0 +1,321 @@
+//go:build windows
+
+package system
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// The Windows Filtering Platform API, which x/sys does not wrap. Struct
+// layouts follow fwpmtypes.h for 64-bit Windows.
+var (
+	fwpuclnt = windows.NewLazySystemDLL("fwpuclnt.dll")
+
+	procFwpmEngineOpen0           = fwpuclnt.NewProc("FwpmEngineOpen0")
+	procFwpmEngineClose0          = fwpuclnt.NewProc("FwpmEngineClose0")
+	procFwpmTransactionBegin0     = fwpuclnt.NewProc("FwpmTransactionBegin0")
+	procFwpmTransactionCommit0    = fwpuclnt.NewProc("FwpmTransactionCommit0")
+	procFwpmTransactionAbort0     = fwpuclnt.NewProc("FwpmTransactionAbort0")
+	procFwpmSubLayerAdd0          = fwpuclnt.NewProc("FwpmSubLayerAdd0")
+	procFwpmFilterAdd0            = fwpuclnt.NewProc("FwpmFilterAdd0")
+	procFwpmGetAppIdFromFileName0 = fwpuclnt.NewProc("FwpmGetAppIdFromFileName0")
+	procFwpmFreeMemory0           = fwpuclnt.NewProc("FwpmFreeMemory0")
+)
+
+var (
+	// FWPM_LAYER_ALE_AUTH_CONNECT_V4 and _V6: outgoing connections, and
+	// the first datagram to each UDP destination
+	layerConnectV4 = windows.GUID{Data1: 0xc38d57d1, Data2: 0x05a7, Data3: 0x4c33, Data4: [8]byte{0x90, 0x4f, 0x7f, 0xbc, 0xee, 0xe6, 0x0e, 0x82}}
+	layerConnectV6 = windows.GUID{Data1: 0x4a72393b, Data2: 0x319f, Data3: 0x44bc, Data4: [8]byte{0x84, 0xc3, 0xba, 0x54, 0xdc, 0xb3, 0xb6, 0xb4}}
+
+	// FWPM_CONDITION_* fields
+	conditionAppID      = windows.GUID{Data1: 0xd78e1e87, Data2: 0x8644, Data3: 0x4ea5, Data4: [8]byte{0x94, 0x37, 0xd8, 0x09, 0xec, 0xef, 0xc9, 0x71}}
+	conditionRemoteAddr = windows.GUID{Data1: 0xb235ae9a, Data2: 0x1d64, Data3: 0x49b8, Data4: [8]byte{0xa4, 0x4c, 0x5f, 0xf3, 0xd9, 0x09, 0x50, 0x45}}
+	conditionRemotePort = windows.GUID{Data1: 0xc35a604d, Data2: 0xd22b, Data3: 0x4e1a, Data4: [8]byte{0x91, 0xb4, 0x68, 0xf6, 0x74, 0xee, 0x67, 0x4b}}
+	conditionFlags      = windows.GUID{Data1: 0x632ce23b, Data2: 0x5167, Data3: 0x435c, Data4: [8]byte{0x86, 0xd7, 0xe9, 0x03, 0x68, 0x4a, 0xa8, 0x0c}}
+
+	// Our sublayer, which holds every filter we add
+	filterdnsSublayer = windows.GUID{Data1: 0x6a4f1b3e, Data2: 0x8c2d, Data3: 0x4e7a, Data4: [8]byte{0x9f, 0x51, 0x3d, 0x0b, 0x2c, 0x7e, 0x9a, 0x14}}
+)
+
+const (
+	rpcAuthnDefault = 0xffffffff // RPC_C_AUTHN_DEFAULT
+
+	sessionFlagDynamic = 0x1 // FWPM_SESSION_FLAG_DYNAMIC
+
+	fwpUint8       = 1  // FWP_UINT8
+	fwpUint16      = 2  // FWP_UINT16
+	fwpUint32      = 3  // FWP_UINT32
+	fwpByteArray16 = 11 // FWP_BYTE_ARRAY16_TYPE
+	fwpByteBlob    = 12 // FWP_BYTE_BLOB_TYPE
+
+	matchEqual       = 0 // FWP_MATCH_EQUAL
+	matchFlagsAllSet = 6 // FWP_MATCH_FLAGS_ALL_SET
+
+	actionBlock  = 0x1001 // FWP_ACTION_BLOCK
+	actionPermit = 0x1002 // FWP_ACTION_PERMIT
+
+	conditionFlagIsLoopback = 0x1 // FWP_CONDITION_FLAG_IS_LOOPBACK
+)
+
+type fwpmDisplayData0 struct {
+	name        *uint16
+	description *uint16
+}
+
+type fwpByteBlob0 struct {
+	size uint32
+	data *uint8
+}
+
+type fwpmSession0 struct {
+	sessionKey           windows.GUID
+	displayData          fwpmDisplayData0
+	flags                uint32
+	txnWaitTimeoutInMSec uint32
+	processID            uint32
+	sid                  *windows.SID
+	username             *uint16
+	kernelMode           int32
+}
+
+type fwpmSublayer0 struct {
+	subLayerKey  windows.GUID
+	displayData  fwpmDisplayData0
+	flags        uint32
+	providerKey  *windows.GUID
+	providerData fwpByteBlob0
+	weight       uint16
+}
+
+// fwpValue0 is FWP_VALUE0 and FWP_CONDITION_VALUE0: a type and a union
+// of small integers and pointers
+type fwpValue0 struct {
+	typ   uint32
+	value uintptr
+}
+
+type fwpmFilterCondition0 struct {
+	fieldKey       windows.GUID
+	matchType      uint32
+	conditionValue fwpValue0
+}
+
+type fwpmAction0 struct {
+	typ        uint32
+	filterType windows.GUID
+}
+
+type fwpmFilter0 struct {
+	filterKey           windows.GUID
+	displayData         fwpmDisplayData0
+	flags               uint32
+	providerKey         *windows.GUID
+	providerData        fwpByteBlob0
+	layerKey            windows.GUID
+	subLayerKey         windows.GUID
+	weight              fwpValue0
+	numFilterConditions uint32
+	filterCondition     *fwpmFilterCondition0
+	action              fwpmAction0
+	providerContextKey  [2]uint64 // Union with rawContext, 8-byte aligned
+	reserved            *windows.GUID
+	filterID            uint64
+	effectiveWeight     fwpValue0
+}
+
+// wfp holds the dynamic WFP session our filters live in. Windows deletes
+// them when it is closed, also when the process dies, so they cannot
+// outlast a crash.
+var wfp struct {
+	sync.Mutex
+	engine windows.Handle
+}
+
+// blockOutsideDNS adds WFP filters in a dynamic session, replacing the
+// session of earlier filters. uid is not used: the daemon is recognized
+// by its executable.
+func blockOutsideDNS(state *Firewall, uid int, exempt []dnsExemption) error {
+	state.Backend = "wfp"
+
+	wfp.Lock()
+	defer wfp.Unlock()
+
+	closeWFP()
+
+	name, _ := windows.UTF16PtrFromString("FilterDNS")
+	session := fwpmSession0{
+		displayData: fwpmDisplayData0{name: name},
+		flags:       sessionFlagDynamic,
+	}
+	var engine windows.Handle
+	if r, _, _ := procFwpmEngineOpen0.Call(0, rpcAuthnDefault, 0, uintptr(unsafe.Pointer(&session)), uintptr(unsafe.Pointer(&engine))); r != 0 {
+		return fmt.Errorf("failed to open the filtering engine: %w", windows.Errno(r))
+	}
+
+	if err := addDNSFilters(engine, exempt); err != nil {
+		procFwpmEngineClose0.Call(uintptr(engine))
+		return err
+	}
+	wfp.engine = engine
+	return nil
+}
+
+// unblockOutsideDNS closes our session, which removes its filters
+func unblockOutsideDNS(state *Firewall) error {
+	wfp.Lock()
+	defer wfp.Unlock()
+
+	closeWFP()
+	return nil
+}
+
+// closeWFP closes the session, if one is open (must be called with
+// wfp locked)
+func closeWFP() {
+	if wfp.engine != 0 {
+		procFwpmEngineClose0.Call(uintptr(wfp.engine))
+		wfp.engine = 0
+	}
+}
+
+// addDNSFilters adds our sublayer and its filters in one transaction.
+// Within the sublayer the highest weight wins: the daemon, loopback
+// traffic and forwarders are permitted before DNS ports and DoH servers
+// are blocked.
+func addDNSFilters(engine windows.Handle, exempt []dnsExemption) error {
+	if r, _, _ := procFwpmTransactionBegin0.Call(uintptr(engine), 0); r != 0 {
+		return fmt.Errorf("failed to begin a filtering transaction: %w", windows.Errno(r))
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			procFwpmTransactionAbort0.Call(uintptr(engine))
+		}
+	}()
+
+	name, _ := windows.UTF16PtrFromString("FilterDNS")
+	sublayer := fwpmSublayer0{
+		subLayerKey: filterdnsSublayer,
+		displayData: fwpmDisplayData0{name: name},
+	}
+	if r, _, _ := procFwpmSubLayerAdd0.Call(uintptr(engine), uintptr(unsafe.Pointer(&sublayer)), 0); r != 0 {
+		return fmt.Errorf("failed to add the filtering sublayer: %w", windows.Errno(r))
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	exePtr, err := windows.UTF16PtrFromString(exe)
+	if err != nil {
+		return err
+	}
+	var appID *fwpByteBlob0
+	if r, _, _ := procFwpmGetAppIdFromFileName0.Call(uintptr(unsafe.Pointer(exePtr)), uintptr(unsafe.Pointer(&appID))); r != 0 {
+		return fmt.Errorf("failed to get the application ID of %s: %w", exe, windows.Errno(r))
+	}
+	defer procFwpmFreeMemory0.Call(uintptr(unsafe.Pointer(&appID)))
+
+	var w wfpFilters
+	for _, layer := range []windows.GUID{layerConnectV4, layerConnectV6} {
+		v6 := layer == layerConnectV6
+
+		w.add(layer, "Permit FilterDNS", 15, actionPermit, fwpmFilterCondition0{
+			fieldKey:       conditionAppID,
+			matchType:      matchEqual,
+			conditionValue: fwpValue0{fwpByteBlob, uintptr(unsafe.Pointer(appID))},
+		})
+		w.add(layer, "Permit loopback", 14, actionPermit, fwpmFilterCondition0{
+			fieldKey:       conditionFlags,
+			matchType:      matchFlagsAllSet,
+			conditionValue: fwpValue0{fwpUint32, conditionFlagIsLoopback},
+		})
+		for _, e := range exempt {
+			if (e.ip.To4() == nil) == v6 {
+				w.add(layer, "Permit forwarder", 13, actionPermit, w.remoteAddr(e.ip), remotePort(e.port))
+			}
+		}
+
+		// Conditions on the same field match if any of them does
+		var ports, doh []fwpmFilterCondition0
+		for _, port := range blockedDNSPorts {
+			ports = append(ports, remotePort(port))
+		}
+		for _, server := range knownDoHServers {
+			if ip := net.ParseIP(server); (ip.To4() == nil) == v6 {
+				doh = append(doh, w.remoteAddr(ip))
+			}
+		}
+		w.add(layer, "Block DNS", 10, actionBlock, ports...)
+		w.add(layer, "Block DNS over HTTPS", 10, actionBlock, append(doh, remotePort(443))...)
+	}
+
+	for _, f := range w.filters {
+		r, _, _ := procFwpmFilterAdd0.Call(uintptr(engine), uintptr(unsafe.Pointer(f)), 0, 0)
+		if r != 0 {
+			return fmt.Errorf("failed to add filter %q: %w", windows.UTF16PtrToString(f.displayData.name), windows.Errno(r))
+		}
+	}
+	runtime.KeepAlive(&w)
+
+	if r, _, _ := procFwpmTransactionCommit0.Call(uintptr(engine)); r != 0 {
+		return fmt.Errorf("failed to commit the filters: %w", windows.Errno(r))
+	}
+	committed = true
+	return nil
+}
+
+// wfpFilters collects filters for FwpmFilterAdd0, and the memory their
+// conditions point to, which the garbage collector cannot see through
+// the uintptr of a condition value
+type wfpFilters struct {
+	filters []*fwpmFilter0
+	pinned  []*[16]byte
+}
+
+// add appends a filter with the given conditions
+func (w *wfpFilters) add(layer windows.GUID, name string, weight uint8, action uint32, conditions ...fwpmFilterCondition0) {
+	displayName, _ := windows.UTF16PtrFromString(name)
+	w.filters = append(w.filters, &fwpmFilter0{
+		displayData:         fwpmDisplayData0{name: displayName},
+		layerKey:            layer,
+		subLayerKey:         filterdnsSublayer,
+		weight:              fwpValue0{fwpUint8, uintptr(weight)},
+		numFilterConditions: uint32(len(conditions)),
+		filterCondition:     &conditions[0],
+		action:              fwpmAction0{typ: action},
+	})
+}
+
+// remoteAddr matches the remote address: a number in host byte order for
+// IPv4, a pointer to the 16 bytes for IPv6
+func (w *wfpFilters) remoteAddr(ip net.IP) fwpmFilterCondition0 {
+	c := fwpmFilterCondition0{fieldKey: conditionRemoteAddr, matchType: matchEqual}
+	if ip4 := ip.To4(); ip4 != nil {
+		c.conditionValue = fwpValue0{fwpUint32, uintptr(binary.BigEndian.Uint32(ip4))}
+		return c
+	}
+	addr := new([16]byte)
+	copy(addr[:], ip.To16())
+	w.pinned = append(w.pinned, addr)
+	c.conditionValue = fwpValue0{fwpByteArray16, uintptr(unsafe.Pointer(addr))}
+	return c
+}
+
+// remotePort matches the remote port
+func remotePort(port int) fwpmFilterCondition0 {
+	return fwpmFilterCondition0{
+		fieldKey:       conditionRemotePort,
+		matchType:      matchEqual,
+		conditionValue: fwpValue0{fwpUint16, uintptr(port)},
+	}
+}
@@ -0,0 +1,68 @@
+package system
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// netPollInterval is how often interface addresses are compared where the
+// OS has no change notifications the daemon can read
+const netPollInterval = 10 * time.Second
+
+// WatchNetwork calls changed whenever interfaces or routes change, e.g.
+// when Wi-Fi reconnects or a cable is plugged in, until ctx is done.
+// Changes come in bursts, so callers should wait for them to settle. If
+// the OS notifications fail, it compares interface addresses instead and
+// returns why.
+func WatchNetwork(ctx context.Context, changed func()) error {
+	err := watchNetwork(ctx, changed)
+	if err != nil && ctx.Err() == nil {
+		go pollNetwork(ctx, changed)
+	}
+	return err
+}
+
+// pollNetwork calls changed whenever the set of interface addresses
+// differs from the last look, until ctx is done
+func pollNetwork(ctx context.Context, changed func()) error {
+	last := addrFingerprint()
+	ticker := time.NewTicker(netPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if current := addrFingerprint(); current != last {
+				last = current
+				changed()
+			}
+		}
+	}
+}
+
+// addrFingerprint lists the addresses of the interfaces that are up
+func addrFingerprint() string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	var addrs []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		ifaceAddrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range ifaceAddrs {
+			addrs = append(addrs, iface.Name+"="+addr.String())
+		}
+	}
+	slices.Sort(addrs)
+	return strings.Join(addrs, ",")
+}
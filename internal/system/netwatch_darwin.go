@@ -0,0 +1,25 @@
+//go:build darwin
+
+package system
+
+import (
+	"context"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchNetwork listens for interface, address and route changes on a
+// routing socket
+func watchNetwork(ctx context.Context, changed func()) error {
+	fd, err := unix.Socket(unix.AF_ROUTE, unix.SOCK_RAW, unix.AF_UNSPEC)
+	if err != nil {
+		return err
+	}
+	unix.CloseOnExec(fd)
+	if err := unix.SetNonblock(fd, true); err != nil {
+		unix.Close(fd)
+		return err
+	}
+	return readChanges(ctx, os.NewFile(uintptr(fd), "route"), changed)
+}
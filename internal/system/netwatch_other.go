@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package system
+
+import "context"
+
+// watchNetwork compares interface addresses now and then, as there is no
+// change notification to read here
+func watchNetwork(ctx context.Context, changed func()) error {
+	return pollNetwork(ctx, changed)
+}
@@ -0,0 +1,28 @@
+//go:build linux || darwin
+
+package system
+
+import (
+	"context"
+	"os"
+)
+
+// readChanges calls changed for every message on a non-blocking socket
+// that announces network changes, until ctx is done
+func readChanges(ctx context.Context, f *os.File, changed func()) error {
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+
+	buf := make([]byte, 16<<10)
+	for {
+		if _, err := f.Read(buf); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		changed()
+	}
+}
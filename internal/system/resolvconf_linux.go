@@ -0,0 +1,271 @@
+//go:build linux
+
+package system
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// openresolvInterface is the record we add to openresolv. Its name sorts
+// it with the loopback records, and -x makes it the only one used.
+const openresolvInterface = "lo.filterdns"
+
+// isOpenresolv checks if openresolv's resolvconf generates resolv.conf,
+// as on Alpine and Void, and when dhcpcd hands its servers to it
+func isOpenresolv() bool {
+	if _, err := exec.LookPath("resolvconf"); err != nil {
+		return false
+	}
+	output, err := exec.Command("resolvconf", "--version").CombinedOutput()
+	return err == nil && strings.Contains(string(output), "openresolv")
+}
+
+// setDNSOpenresolv adds an exclusive record for the proxy to openresolv,
+// which regenerates resolv.conf from it and keeps the other records for
+// when it is deleted again
+func setDNSOpenresolv(servers []string) error {
+	backup := &DNSBackup{
+		Linux: &LinuxDNSBackup{System: "openresolv"},
+	}
+	if err := SaveBackup(backup); err != nil {
+		return fmt.Errorf("failed to save DNS backup: %w", err)
+	}
+
+	var content string
+	for _, server := range servers {
+		content += fmt.Sprintf("nameserver %s\n", server)
+	}
+	cmd := exec.Command("resolvconf", "-x", "-a", openresolvInterface)
+	cmd.Stdin = strings.NewReader(content)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("resolvconf -a failed: %s: %w", string(output), err)
+	}
+	return nil
+}
+
+// resetDNSOpenresolv deletes our record, leaving openresolv to go back to
+// the records of the interfaces
+func resetDNSOpenresolv() error {
+	// -f: no error if the record is already gone
+	if output, err := exec.Command("resolvconf", "-f", "-d", openresolvInterface).CombinedOutput(); err != nil {
+		return fmt.Errorf("resolvconf -d failed: %s: %w", string(output), err)
+	}
+	ClearBackup()
+	return nil
+}
+
+// netconfigFile holds SUSE's netconfig settings, among them how the DNS
+// servers in resolv.conf are chosen
+const netconfigFile = "/etc/sysconfig/network/config"
+
+// isNetconfig checks if SUSE's netconfig generates resolv.conf
+func isNetconfig() bool {
+	if _, err := exec.LookPath("netconfig"); err != nil {
+		return false
+	}
+	_, err := os.Stat(netconfigFile)
+	return err == nil
+}
+
+// setDNSNetconfig has netconfig use only the proxy, as static servers,
+// instead of the servers the interfaces obtained
+func setDNSNetconfig(servers []string) error {
+	values, err := readSysconfig(netconfigFile)
+	if err != nil {
+		return err
+	}
+
+	// Keep the original from a backup of an earlier run, which may still
+	// be in place
+	backup, _ := LoadBackup()
+	if backup == nil || backup.Linux == nil || backup.Linux.System != "netconfig" {
+		backup = &DNSBackup{
+			Linux: &LinuxDNSBackup{
+				System:           "netconfig",
+				NetconfigPolicy:  values["NETCONFIG_DNS_POLICY"],
+				NetconfigServers: values["NETCONFIG_DNS_STATIC_SERVERS"],
+			},
+		}
+	}
+	if err := SaveBackup(backup); err != nil {
+		return fmt.Errorf("failed to save DNS backup: %w", err)
+	}
+
+	return updateNetconfig("STATIC", strings.Join(servers, " "))
+}
+
+// resetDNSNetconfig puts back netconfig's original DNS policy and static
+// servers
+func resetDNSNetconfig() error {
+	backup, err := LoadBackup()
+	if err != nil {
+		return fmt.Errorf("failed to load DNS backup: %w", err)
+	}
+
+	// Without a backup, go back to SUSE's default
+	policy, servers := "auto", ""
+	if backup != nil && backup.Linux != nil && backup.Linux.System == "netconfig" {
+		policy, servers = backup.Linux.NetconfigPolicy, backup.Linux.NetconfigServers
+	}
+	if err := updateNetconfig(policy, servers); err != nil {
+		return err
+	}
+	ClearBackup()
+	return nil
+}
+
+// netconfigUsesProxy reports whether netconfig's static servers are the
+// local proxy
+func netconfigUsesProxy() bool {
+	values, err := readSysconfig(netconfigFile)
+	return err == nil && usesProxy(strings.Fields(values["NETCONFIG_DNS_STATIC_SERVERS"]))
+}
+
+// updateNetconfig sets netconfig's DNS policy and static servers and has
+// it generate resolv.conf again
+func updateNetconfig(policy, servers string) error {
+	err := writeSysconfig(netconfigFile, map[string]string{
+		"NETCONFIG_DNS_POLICY":         policy,
+		"NETCONFIG_DNS_STATIC_SERVERS": servers,
+	})
+	if err != nil {
+		return err
+	}
+	if output, err := exec.Command("netconfig", "update", "-f").CombinedOutput(); err != nil {
+		return fmt.Errorf("netconfig update failed: %s: %w", string(output), err)
+	}
+	return nil
+}
+
+// readSysconfig reads the KEY="value" lines of a sysconfig file
+func readSysconfig(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, found := strings.Cut(line, "="); found {
+			values[key] = strings.Trim(value, `"'`)
+		}
+	}
+	return values, scanner.Err()
+}
+
+// writeSysconfig sets keys of a sysconfig file, replacing the lines that
+// set them and appending those it does not have yet
+func writeSysconfig(path string, values map[string]string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	written := make(map[string]bool)
+	for i, line := range lines {
+		key, _, found := strings.Cut(strings.TrimSpace(line), "=")
+		if value, ok := values[key]; found && ok {
+			lines[i] = fmt.Sprintf("%s=%q", key, value)
+			written[key] = true
+		}
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !written[key] {
+			lines = append(lines, fmt.Sprintf("%s=%q", key, values[key]))
+		}
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// dhcpcdConf is dhcpcd's configuration, where "nohook resolv.conf" keeps
+// it from writing resolv.conf itself
+const (
+	dhcpcdConf   = "/etc/dhcpcd.conf"
+	dhcpcdNoHook = "nohook resolv.conf # added by FilterDNS while filtering"
+)
+
+// isDhcpcd checks if dhcpcd is running, which rewrites resolv.conf on
+// every lease unless told not to
+func isDhcpcd() bool {
+	if _, err := exec.LookPath("dhcpcd"); err != nil {
+		return false
+	}
+	for _, pidFile := range []string{"/run/dhcpcd.pid", "/run/dhcpcd/pid", "/var/run/dhcpcd.pid", "/var/run/dhcpcd/pid"} {
+		if _, err := os.Stat(pidFile); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// holdDhcpcd keeps dhcpcd from overwriting resolv.conf while filtering
+func holdDhcpcd() error {
+	data, err := os.ReadFile(dhcpcdConf)
+	if err != nil {
+		return err
+	}
+	if strings.Contains(string(data), dhcpcdNoHook) {
+		return nil
+	}
+	if err := os.WriteFile(dhcpcdConf, []byte(insertDhcpcdNoHook(string(data))), 0644); err != nil {
+		return err
+	}
+	reloadDhcpcd()
+	return nil
+}
+
+// insertDhcpcdNoHook adds the nohook line to dhcpcd.conf content before
+// the first interface, ssid or profile block, since options after one of
+// those only apply within it
+func insertDhcpcdNoHook(content string) string {
+	lines := strings.SplitAfter(content, "\n")
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && (fields[0] == "interface" || fields[0] == "ssid" || fields[0] == "profile") {
+			return strings.Join(lines[:i], "") + dhcpcdNoHook + "\n" + strings.Join(lines[i:], "")
+		}
+	}
+	return strings.TrimSuffix(content, "\n") + "\n" + dhcpcdNoHook + "\n"
+}
+
+// releaseDhcpcd removes the line holdDhcpcd added to dhcpcd.conf
+func releaseDhcpcd() error {
+	data, err := os.ReadFile(dhcpcdConf)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if !strings.Contains(string(data), dhcpcdNoHook) {
+		return nil
+	}
+	content := strings.Replace(string(data), dhcpcdNoHook+"\n", "", 1)
+	if err := os.WriteFile(dhcpcdConf, []byte(content), 0644); err != nil {
+		return err
+	}
+	reloadDhcpcd()
+	return nil
+}
+
+// reloadDhcpcd has dhcpcd read its configuration again
+func reloadDhcpcd() {
+	exec.Command("dhcpcd", "--rebind").Run()
+}
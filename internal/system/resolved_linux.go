@@ -0,0 +1,230 @@
+//go:build linux
+
+package system
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"syscall"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// systemd-resolved's D-Bus API, the one resolvectl itself uses
+const (
+	resolvedName    = "org.freedesktop.resolve1"
+	resolvedPath    = dbus.ObjectPath("/org/freedesktop/resolve1")
+	resolvedManager = "org.freedesktop.resolve1.Manager"
+	resolvedLink    = "org.freedesktop.resolve1.Link"
+)
+
+// resolvedAddress is a DNS server as resolved takes and lists it per link
+type resolvedAddress struct {
+	Family  int32
+	Address []byte
+}
+
+// resolvedServer is a DNS server in the manager's list of all of them;
+// link index 0 is the global configuration from resolved.conf
+type resolvedServer struct {
+	Ifindex int32
+	Family  int32
+	Address []byte
+}
+
+// resolvedDomain is a search or, with RoutingOnly, routing domain of a
+// link; "." with RoutingOnly is written "~." and routes every name
+type resolvedDomain struct {
+	Domain      string
+	RoutingOnly bool
+}
+
+// resolved talks to systemd-resolved over the system bus
+type resolved struct {
+	conn *dbus.Conn
+}
+
+// connectResolved returns a client for systemd-resolved on the shared
+// system bus connection
+func connectResolved() (*resolved, error) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the system bus: %w", err)
+	}
+	return &resolved{conn: conn}, nil
+}
+
+// call invokes a manager method on the link of the named interface
+func (r *resolved) call(method, iface string, args ...interface{}) error {
+	link, err := net.InterfaceByName(iface)
+	if err != nil {
+		return err
+	}
+	args = append([]interface{}{int32(link.Index)}, args...)
+	if err := r.conn.Object(resolvedName, resolvedPath).Call(resolvedManager+"."+method, 0, args...).Err; err != nil {
+		return fmt.Errorf("systemd-resolved %s failed on %s: %w", method, iface, err)
+	}
+	return nil
+}
+
+// setLinkDNS sets the DNS servers of an interface
+func (r *resolved) setLinkDNS(iface string, servers []string) error {
+	var addrs []resolvedAddress
+	for _, server := range servers {
+		ip := net.ParseIP(server)
+		if ip == nil {
+			return fmt.Errorf("invalid DNS server %q", server)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			addrs = append(addrs, resolvedAddress{Family: syscall.AF_INET, Address: ip4})
+		} else {
+			addrs = append(addrs, resolvedAddress{Family: syscall.AF_INET6, Address: ip.To16()})
+		}
+	}
+	return r.call("SetLinkDNS", iface, addrs)
+}
+
+// setLinkDomains sets the search and routing domains of an interface
+func (r *resolved) setLinkDomains(iface string, domains []resolvedDomain) error {
+	return r.call("SetLinkDomains", iface, domains)
+}
+
+// setLinkDefaultRoute sets whether names without a more specific routing
+// domain may be resolved on an interface
+func (r *resolved) setLinkDefaultRoute(iface string, enable bool) error {
+	return r.call("SetLinkDefaultRoute", iface, enable)
+}
+
+// setLinkDNSSEC sets DNSSEC validation for an interface: "yes", "no",
+// "allow-downgrade", or "" for resolved.conf's setting
+func (r *resolved) setLinkDNSSEC(iface, mode string) error {
+	return r.call("SetLinkDNSSEC", iface, mode)
+}
+
+// setLinkDNSOverTLS sets DNS over TLS for an interface: "yes", "no",
+// "opportunistic", or "" for resolved.conf's setting
+func (r *resolved) setLinkDNSOverTLS(iface, mode string) error {
+	return r.call("SetLinkDNSOverTLS", iface, mode)
+}
+
+// revertLink drops everything set on an interface, going back to what
+// its network configuration (e.g. DHCP) provides
+func (r *resolved) revertLink(iface string) error {
+	return r.call("RevertLink", iface)
+}
+
+// linkProperty reads a property of the link of the named interface into
+// value
+func (r *resolved) linkProperty(iface, property string, value interface{}) error {
+	link, err := net.InterfaceByName(iface)
+	if err != nil {
+		return err
+	}
+	var path dbus.ObjectPath
+	if err := r.conn.Object(resolvedName, resolvedPath).Call(resolvedManager+".GetLink", 0, int32(link.Index)).Store(&path); err != nil {
+		return fmt.Errorf("systemd-resolved GetLink failed on %s: %w", iface, err)
+	}
+	variant, err := r.conn.Object(resolvedName, path).GetProperty(resolvedLink + "." + property)
+	if err == nil {
+		err = variant.Store(value)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s of %s from systemd-resolved: %w", property, iface, err)
+	}
+	return nil
+}
+
+// linkDomains returns the search and routing domains of an interface
+func (r *resolved) linkDomains(iface string) ([]resolvedDomain, error) {
+	var domains []resolvedDomain
+	err := r.linkProperty(iface, "Domains", &domains)
+	return domains, err
+}
+
+// linkServers returns the DNS servers of an interface
+func (r *resolved) linkServers(iface string) ([]string, error) {
+	var addrs []resolvedAddress
+	if err := r.linkProperty(iface, "DNS", &addrs); err != nil {
+		return nil, err
+	}
+	var servers []string
+	for _, addr := range addrs {
+		servers = append(servers, net.IP(addr.Address).String())
+	}
+	return servers, nil
+}
+
+// servers returns the DNS servers resolved knows, global and per link
+func (r *resolved) servers() ([]resolvedServer, error) {
+	value, err := r.conn.Object(resolvedName, resolvedPath).GetProperty(resolvedManager + ".DNS")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DNS servers from systemd-resolved: %w", err)
+	}
+	var servers []resolvedServer
+	if err := value.Store(&servers); err != nil {
+		return nil, fmt.Errorf("failed to read DNS servers from systemd-resolved: %w", err)
+	}
+	return servers, nil
+}
+
+// links returns the global and per-link DNS servers, with every network
+// interface but loopback listed whether it has servers or not
+func (r *resolved) links() ([]ResolverLink, error) {
+	servers, err := r.servers()
+	if err != nil {
+		return nil, err
+	}
+
+	byIndex := map[int32][]string{}
+	for _, server := range servers {
+		byIndex[server.Ifindex] = append(byIndex[server.Ifindex], net.IP(server.Address).String())
+	}
+
+	links := []ResolverLink{{Name: resolvedGlobal, Servers: byIndex[0]}}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ifaces, func(i, j int) bool { return ifaces[i].Index < ifaces[j].Index })
+	for _, iface := range ifaces {
+		// resolved manages no DNS on loopback
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		links = append(links, ResolverLink{Name: iface.Name, Servers: byIndex[int32(iface.Index)]})
+	}
+	return links, nil
+}
+
+// filterLink points an interface at the given servers and has resolved
+// send it every name no other link claims with a more specific routing
+// domain. The link keeps its own search and routing domains, and split
+// DNS that VPNs register on their links goes on working. DNSSEC and DNS
+// over TLS are off for it, as the servers are the local proxy, which
+// speaks plain DNS and encrypts upstream itself.
+func (r *resolved) filterLink(iface string, servers []string) error {
+	domains, err := r.linkDomains(iface)
+	if err != nil {
+		return err
+	}
+	if routeAll := (resolvedDomain{Domain: ".", RoutingOnly: true}); !slices.Contains(domains, routeAll) {
+		domains = append(domains, routeAll)
+	}
+
+	if err := r.setLinkDNS(iface, servers); err != nil {
+		return err
+	}
+	if err := r.setLinkDomains(iface, domains); err != nil {
+		return err
+	}
+	if err := r.setLinkDNSSEC(iface, "no"); err != nil {
+		return err
+	}
+
+	// Ignore errors, resolved before 240 has neither of these
+	r.setLinkDefaultRoute(iface, true)
+	r.setLinkDNSOverTLS(iface, "no")
+	return nil
+}
@@ -0,0 +1,67 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"time"
+)
+
+// verifyDomain is resolved after a restore to check that DNS works
+const verifyDomain = "example.com"
+
+// RestoreNetwork undoes every change the client may have made to the
+// system's networking, whether or not a backup survived: it removes the
+// firewall rules against DNS leaks, restores DNS from the backup, points
+// anything still using the local proxy back at automatic DNS, removes the
+// backup and journal, and finally checks that names resolve with the
+// restored settings. The proxy must be stopped
+// first, or the check may pass through it.
+func RestoreNetwork() error {
+	var errs []error
+
+	if err := UnblockOutsideDNS(); err != nil {
+		errs = append(errs, fmt.Errorf("removing DNS firewall rules: %w", err))
+	}
+
+	if err := ResetDNS(); err != nil {
+		errs = append(errs, fmt.Errorf("restoring DNS from backup: %w", err))
+	}
+
+	// Whatever the backup missed, e.g. after it was lost or damaged
+	if err := restoreLeftovers(); err != nil {
+		errs = append(errs, fmt.Errorf("removing leftover settings: %w", err))
+	}
+
+	ClearBackup()
+	endOperation()
+
+	if err := VerifyResolution(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// VerifyResolution checks that the system resolver answers and no longer
+// points at the local proxy
+func VerifyResolution() error {
+	if servers, err := GetCurrentDNS(); err == nil && usesProxy(servers) {
+		return fmt.Errorf("system DNS still points at the local proxy (%v)", servers)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := net.DefaultResolver.LookupHost(ctx, verifyDomain); err != nil {
+		return fmt.Errorf("resolving %s failed with the restored settings: %w", verifyDomain, err)
+	}
+	return nil
+}
+
+// usesProxy reports whether a server list includes a loopback address
+// the proxy listens on
+func usesProxy(servers []string) bool {
+	return slices.Contains(servers, "127.0.0.1") || slices.Contains(servers, "::1")
+}
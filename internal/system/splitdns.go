@@ -0,0 +1,52 @@
+package system
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SplitDomain sends the names in a domain and its subdomains to a DNS
+// server of its own, e.g. an office's internal zone to the office's server
+type SplitDomain struct {
+	Domain string `json:"domain"` // e.g. "corp.example.com", "*.internal"
+	Server string `json:"server"` // IP address with an optional port, e.g. "192.168.1.1:53"
+}
+
+// SetSplitDNS registers the domains with the system resolver itself, so
+// they reach their servers also from apps that bypass the local proxy,
+// replacing the domains set before. An empty list removes them. Only
+// macOS has such resolvers; elsewhere the proxy's forwarders suffice.
+// Implementation is platform-specific
+func SetSplitDNS(domains []SplitDomain) error {
+	return setSplitDNS(domains)
+}
+
+// splitName returns the domain of d as the system resolver takes it,
+// without a wildcard or trailing dot, checking that it is a plain
+// domain name
+func (d SplitDomain) splitName() (string, error) {
+	name := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(d.Domain, "*."), "."))
+	if name == "" {
+		return "", fmt.Errorf("invalid split DNS domain %q", d.Domain)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || strings.Trim(label, "abcdefghijklmnopqrstuvwxyz0123456789-_") != "" {
+			return "", fmt.Errorf("invalid split DNS domain %q", d.Domain)
+		}
+	}
+	return name, nil
+}
+
+// splitServer returns the address and port of d's server, with port ""
+// for the default
+func (d SplitDomain) splitServer() (addr, port string, err error) {
+	if net.ParseIP(d.Server) != nil {
+		return d.Server, "", nil
+	}
+	host, port, err := net.SplitHostPort(d.Server)
+	if err != nil || net.ParseIP(host) == nil {
+		return "", "", fmt.Errorf("invalid split DNS server %q", d.Server)
+	}
+	return host, port, nil
+}
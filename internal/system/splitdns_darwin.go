@@ -0,0 +1,136 @@
+//go:build darwin
+
+package system
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// resolverDir holds supplementary resolvers, one file per domain, which
+// the system resolver consults before the servers of the network services
+const resolverDir = "/etc/resolver"
+
+// resolverMarker starts the resolver files we write, so that we only ever
+// replace or remove our own
+const resolverMarker = "# Split DNS set by FilterDNS, removed when filtering stops"
+
+// setSplitDNS writes a resolver file for each domain and removes ours for
+// domains no longer given. Files someone else wrote are left alone.
+func setSplitDNS(domains []SplitDomain) error {
+	wanted := make(map[string]bool)
+	for _, d := range domains {
+		name, err := d.splitName()
+		if err != nil {
+			return err
+		}
+		addr, port, err := d.splitServer()
+		if err != nil {
+			return err
+		}
+		// The first forwarder for a domain wins, as in the proxy
+		if wanted[name] {
+			continue
+		}
+		wanted[name] = true
+
+		path := filepath.Join(resolverDir, name)
+		if _, err := os.Stat(path); err == nil && !ownResolver(path) {
+			continue
+		}
+
+		content := resolverMarker + "\nnameserver " + addr + "\n"
+		if port != "" {
+			content += "port " + port + "\n"
+		}
+		if err := os.MkdirAll(resolverDir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", resolverDir, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+
+	err := removeResolvers(func(name string) bool { return !wanted[name] })
+	flushDNSCache()
+	return err
+}
+
+// clearSplitDNS removes every resolver file we wrote
+func clearSplitDNS() error {
+	err := removeResolvers(func(string) bool { return true })
+	flushDNSCache()
+	return err
+}
+
+// removeResolvers removes the resolver files we wrote for the domains
+// remove selects
+func removeResolvers(remove func(name string) bool) error {
+	for _, path := range ownResolvers() {
+		if !remove(filepath.Base(path)) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// ownResolvers returns the paths of the resolver files we wrote
+func ownResolvers() []string {
+	entries, err := os.ReadDir(resolverDir)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, entry := range entries {
+		path := filepath.Join(resolverDir, entry.Name())
+		if entry.Type().IsRegular() && ownResolver(path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// ownResolver reports whether we wrote the resolver file at path
+func ownResolver(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	return scanner.Scan() && scanner.Text() == resolverMarker
+}
+
+// splitDNSLinks returns the domains of the resolver files we wrote with
+// their servers, for inspectDNS
+func splitDNSLinks() []ResolverLink {
+	var links []ResolverLink
+	for _, path := range ownResolvers() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		link := ResolverLink{Name: path}
+		for _, line := range strings.Split(string(data), "\n") {
+			if server, ok := strings.CutPrefix(line, "nameserver "); ok {
+				link.Servers = append(link.Servers, strings.TrimSpace(server))
+			}
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// flushDNSCache makes the system resolver pick up changed settings
+func flushDNSCache() {
+	exec.Command("dscacheutil", "-flushcache").Run()
+	exec.Command("killall", "-HUP", "mDNSResponder").Run()
+}
@@ -0,0 +1,9 @@
+//go:build !darwin && !windows
+
+package system
+
+// setSplitDNS does nothing: the system resolver sends every name to the
+// local proxy, which forwards split DNS domains itself
+func setSplitDNS(domains []SplitDomain) error {
+	return nil
+}
@@ -0,0 +1,141 @@
+//go:build windows
+
+package system
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// nrptComment marks the Name Resolution Policy Table rules we add, so
+// that we only ever remove our own
+const nrptComment = "FilterDNS split DNS"
+
+// setSplitDNS replaces our NRPT rules with one per domain. NRPT cannot
+// name a port, so servers on another port than 53 are left to the proxy.
+func setSplitDNS(domains []SplitDomain) error {
+	script := removeNRPTScript
+	seen := make(map[string]bool)
+	for _, d := range domains {
+		name, err := d.splitName()
+		if err != nil {
+			return err
+		}
+		addr, port, err := d.splitServer()
+		if err != nil {
+			return err
+		}
+		// The first forwarder for a domain wins, as in the proxy
+		if seen[name] || (port != "" && port != "53") {
+			continue
+		}
+		seen[name] = true
+
+		// ".corp.example" covers the subdomains, "corp.example" itself
+		script += fmt.Sprintf("; Add-DnsClientNrptRule -Namespace '.%s','%s' -NameServers '%s' -Comment '%s'",
+			name, name, addr, nrptComment)
+	}
+	if _, err := powershell(script); err != nil {
+		return fmt.Errorf("failed to set NRPT rules: %w", err)
+	}
+	exec.Command("ipconfig", "/flushdns").Run()
+	return nil
+}
+
+// clearSplitDNS removes every NRPT rule we added
+func clearSplitDNS() error {
+	if _, err := powershell(removeNRPTScript); err != nil {
+		return fmt.Errorf("failed to remove NRPT rules: %w", err)
+	}
+	return nil
+}
+
+// removeNRPTScript removes the NRPT rules carrying our comment
+const removeNRPTScript = "Get-DnsClientNrptRule | Where-Object Comment -eq '" + nrptComment + "' | Remove-DnsClientNrptRule -Force"
+
+// splitDNSLinks returns our NRPT rules as namespaces with their servers,
+// for inspectDNS
+func splitDNSLinks() []ResolverLink {
+	script := "Get-DnsClientNrptRule | Where-Object Comment -eq '" + nrptComment + "' | " +
+		"ForEach-Object { ($_.Namespace -join ',') + ' ' + ($_.NameServers -join ' ') }"
+	output, err := powershell(script)
+	if err != nil {
+		return nil
+	}
+
+	var links []ResolverLink
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			links = append(links, ResolverLink{Name: "NRPT " + fields[0], Servers: fields[1:]})
+		}
+	}
+	return links
+}
+
+// powershell runs a script and returns its output, which goes into the
+// error if it fails
+func powershell(script string) (string, error) {
+	output, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return string(output), nil
+}
+
+// dohPolicyKey holds the DNS client's DNS over HTTPS policy
+const dohPolicyKey = `SOFTWARE\Policies\Microsoft\Windows NT\DNSClient`
+
+// dohProhibited is the DoHPolicy value that keeps Windows from upgrading
+// DNS to DoH on its own, which would send queries past the proxy
+const dohProhibited = 1
+
+// dohPolicy returns the DoHPolicy value, or nil if none is set
+func dohPolicy() *uint32 {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, dohPolicyKey, registry.QUERY_VALUE)
+	if err != nil {
+		return nil
+	}
+	defer k.Close()
+
+	value, _, err := k.GetIntegerValue("DoHPolicy")
+	if err != nil {
+		return nil
+	}
+	policy := uint32(value)
+	return &policy
+}
+
+// prohibitDoH turns off Windows' own DNS over HTTPS
+func prohibitDoH() error {
+	k, _, err := registry.CreateKey(registry.LOCAL_MACHINE, dohPolicyKey, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	return k.SetDWordValue("DoHPolicy", dohProhibited)
+}
+
+// restoreDoH puts back the DoHPolicy value dohPolicy found before
+// prohibitDoH, removing it if there was none
+func restoreDoH(previous *uint32) error {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, dohPolicyKey, registry.SET_VALUE)
+	if err == registry.ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+
+	if previous != nil {
+		return k.SetDWordValue("DoHPolicy", *previous)
+	}
+	if err := k.DeleteValue("DoHPolicy"); err != nil && err != registry.ErrNotExist {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package system
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// lsregister refreshes Launch Services' view of an app bundle
+const lsregister = "/System/Library/Frameworks/CoreServices.framework/Frameworks/LaunchServices.framework/Support/lsregister"
+
+// RegisterURLScheme registers the app bundle we run from with Launch
+// Services. On macOS the scheme itself is declared in the bundle's
+// Info.plist (CFBundleURLTypes), so this only makes sure Launch Services
+// picked it up, e.g. after the app was copied to /Applications.
+func RegisterURLScheme(scheme string) error {
+	exe := getExecutablePath()[0]
+
+	i := strings.Index(exe, ".app/")
+	if i < 0 {
+		return fmt.Errorf("not running from an app bundle, %s:// links need FilterDNS.app", scheme)
+	}
+	bundle := filepath.Clean(exe[:i+len(".app")])
+
+	if out, err := exec.Command(lsregister, "-f", bundle).CombinedOutput(); err != nil {
+		return fmt.Errorf("lsregister failed: %w: %s", err, out)
+	}
+	return nil
+}
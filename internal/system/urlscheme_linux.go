@@ -0,0 +1,46 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// urlHandlerDesktop is the desktop entry that makes us the scheme handler
+const urlHandlerDesktop = `[Desktop Entry]
+Type=Application
+Name=FilterDNS Client
+Exec="%s" %%u
+NoDisplay=true
+MimeType=x-scheme-handler/%s;
+`
+
+// RegisterURLScheme makes the current executable the handler for
+// scheme:// links for the current user
+func RegisterURLScheme(scheme string) error {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	dir := filepath.Join(dataHome, "applications")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	name := "filterdns-client-" + scheme + ".desktop"
+	entry := fmt.Sprintf(urlHandlerDesktop, getExecutablePath()[0], scheme)
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(entry), 0644); err != nil {
+		return err
+	}
+
+	if out, err := exec.Command("xdg-mime", "default", name, "x-scheme-handler/"+scheme).CombinedOutput(); err != nil {
+		return fmt.Errorf("xdg-mime failed: %w: %s", err, out)
+	}
+	return nil
+}
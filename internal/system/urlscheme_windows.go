@@ -0,0 +1,25 @@
+package system
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// RegisterURLScheme makes the current executable the handler for
+// scheme:// links for the current user
+func RegisterURLScheme(scheme string) error {
+	key := `HKCU\Software\Classes\` + scheme
+	command := fmt.Sprintf(`"%s" "%%1"`, getExecutablePath()[0])
+
+	entries := [][]string{
+		{"add", key, "/ve", "/d", "URL:FilterDNS", "/f"},
+		{"add", key, "/v", "URL Protocol", "/d", "", "/f"},
+		{"add", key + `\shell\open\command`, "/ve", "/d", command, "/f"},
+	}
+	for _, args := range entries {
+		if out, err := exec.Command("reg", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("reg %v failed: %w: %s", args, err, out)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package system
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// Kinds of VPN that DetectVPNs tells apart
+const (
+	VPNTailscale = "Tailscale"
+	VPNWireGuard = "WireGuard"
+	VPNZeroTier  = "ZeroTier"
+	VPNOther     = "VPN"
+)
+
+// TailscaleDNS is the address of Tailscale's MagicDNS resolver
+const TailscaleDNS = "100.100.100.100"
+
+// VPN is an active VPN or overlay network interface, with the domains its
+// own DNS servers resolve. Those need a split DNS forwarder, as the proxy
+// would otherwise send them to the profile.
+type VPN struct {
+	Interface string   `json:"interface"`
+	Kind      string   `json:"kind"`              // One of the VPN* kinds
+	Domains   []string `json:"domains,omitempty"` // e.g. "tail1234.ts.net", "corp.example.com"
+	Servers   []string `json:"servers,omitempty"` // Its DNS servers, without the local proxy
+
+	// Its servers answer every domain, not just its own, so queries go
+	// past the proxy
+	TakesAllDNS bool `json:"takesAllDns,omitempty"`
+}
+
+// cgnat is the shared address range Tailscale assigns from
+var cgnat = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// DetectVPNs lists the VPN interfaces that are up, recognized by name or,
+// for Tailscale, by address. Domains and servers come from the
+// platform's resolver configuration and, for Tailscale, its MagicDNS
+// settings; either may be empty where they cannot be found.
+func DetectVPNs() ([]VPN, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var vpns []VPN
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		kind := vpnKind(iface)
+		if kind == "" {
+			continue
+		}
+
+		vpn := VPN{Interface: iface.Name, Kind: kind}
+		domains, servers := linkDNS(iface)
+		if kind == VPNTailscale {
+			domains, servers = tailscaleDNS(domains, servers)
+		}
+		for _, domain := range domains {
+			domain = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(domain, "~"), "."))
+			if domain != "" && !slices.Contains(vpn.Domains, domain) {
+				vpn.Domains = append(vpn.Domains, domain)
+			}
+		}
+		for _, server := range servers {
+			if ip := net.ParseIP(server); ip != nil && !ip.IsLoopback() && !slices.Contains(vpn.Servers, server) {
+				vpn.Servers = append(vpn.Servers, server)
+			}
+		}
+		vpn.TakesAllDNS = len(vpn.Servers) > 0 && takesAllDNS(iface, vpn.Servers)
+		vpns = append(vpns, vpn)
+	}
+	return vpns, nil
+}
+
+// vpnKind tells what VPN an interface belongs to, "" if it looks like
+// none. Tunnels without a routable address, like the macOS system utuns,
+// do not count.
+func vpnKind(iface net.Interface) string {
+	name := strings.ToLower(iface.Name)
+	addrs, _ := iface.Addrs()
+
+	var routable, tailscale bool
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || ipnet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		routable = true
+		tailscale = tailscale || cgnat.Contains(ipnet.IP)
+	}
+
+	switch {
+	case strings.HasPrefix(name, "tailscale"):
+		return VPNTailscale
+	case !routable:
+		return ""
+	case strings.HasPrefix(name, "wg") || strings.Contains(name, "wireguard") || name == "nordlynx":
+		return VPNWireGuard
+	case strings.HasPrefix(name, "zt") || strings.Contains(name, "zerotier"):
+		return VPNZeroTier
+	case strings.HasPrefix(name, "utun") || strings.HasPrefix(name, "tun"):
+		if tailscale {
+			return VPNTailscale
+		}
+		return VPNOther
+	case strings.HasPrefix(name, "tap") || strings.HasPrefix(name, "ppp") || strings.HasPrefix(name, "ipsec") ||
+		strings.Contains(name, "openvpn") || strings.Contains(name, "vpn"):
+		return VPNOther
+	}
+	return ""
+}
+
+// tailscaleStatus is the part of "tailscale status --json" about MagicDNS
+type tailscaleStatus struct {
+	CurrentTailnet *struct {
+		MagicDNSSuffix  string
+		MagicDNSEnabled bool
+	}
+}
+
+// tailscaleDNS adds the tailnet's MagicDNS suffix and resolver to what the
+// system reports for the interface. Without the tailscale command, all of
+// ts.net goes to MagicDNS, as the Split DNS tab's Tailscale button does.
+func tailscaleDNS(domains, servers []string) ([]string, []string) {
+	var status tailscaleStatus
+	output, err := exec.Command(tailscaleCommand(), "status", "--json").Output()
+	if err != nil || json.Unmarshal(output, &status) != nil || status.CurrentTailnet == nil {
+		return append(domains, "ts.net"), append(servers, TailscaleDNS)
+	}
+
+	if tailnet := status.CurrentTailnet; tailnet.MagicDNSEnabled && tailnet.MagicDNSSuffix != "" {
+		domains = append(domains, tailnet.MagicDNSSuffix)
+		servers = append(servers, TailscaleDNS)
+	}
+	return domains, servers
+}
+
+// tailscaleCommand finds the tailscale command, which the apps for macOS
+// and Windows do not put on the PATH
+func tailscaleCommand() string {
+	if path, err := exec.LookPath("tailscale"); err == nil {
+		return path
+	}
+	for _, path := range tailscalePaths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return "tailscale"
+}
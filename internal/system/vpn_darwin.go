@@ -0,0 +1,90 @@
+//go:build darwin
+
+package system
+
+import (
+	"net"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// tailscalePaths are where the tailscale command is installed besides the
+// PATH, including inside the App Store app
+var tailscalePaths = []string{
+	"/Applications/Tailscale.app/Contents/MacOS/Tailscale",
+	"/usr/local/bin/tailscale",
+	"/opt/homebrew/bin/tailscale",
+}
+
+// linkDNS returns the domains and DNS servers of the resolvers scutil
+// lists for an interface, which VPN clients add there
+func linkDNS(iface net.Interface) (domains, servers []string) {
+	output, err := exec.Command("scutil", "--dns").Output()
+	if err != nil {
+		return nil, nil
+	}
+
+	// Resolvers are blocks of "key : value" lines, such as
+	// "nameserver[0] : 10.0.0.1" and "if_index : 17 (utun3)", each
+	// starting with "resolver #N"
+	var blockDomains, blockServers []string
+	var matches bool
+	flush := func() {
+		if matches {
+			domains = append(domains, blockDomains...)
+			servers = append(servers, blockServers...)
+		}
+		blockDomains, blockServers, matches = nil, nil, false
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "resolver #") {
+			flush()
+			continue
+		}
+		key, value, found := strings.Cut(line, " : ")
+		if !found {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case key == "domain" || strings.HasPrefix(key, "search domain"):
+			blockDomains = append(blockDomains, value)
+		case strings.HasPrefix(key, "nameserver"):
+			blockServers = append(blockServers, value)
+		case key == "if_index":
+			matches = strings.HasSuffix(value, "("+iface.Name+")")
+		}
+	}
+	flush()
+	return domains, servers
+}
+
+// takesAllDNS tells whether an interface's DNS servers are those of the
+// default resolver, the first scutil lists, which gets every domain no
+// other resolver claims
+func takesAllDNS(iface net.Interface, servers []string) bool {
+	output, err := exec.Command("scutil", "--dns").Output()
+	if err != nil {
+		return false
+	}
+
+	var inFirst bool
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "resolver #") {
+			if inFirst {
+				break
+			}
+			inFirst = true
+			continue
+		}
+		key, value, found := strings.Cut(line, " : ")
+		if inFirst && found && strings.HasPrefix(strings.TrimSpace(key), "nameserver") &&
+			slices.Contains(servers, strings.TrimSpace(value)) {
+			return true
+		}
+	}
+	return false
+}
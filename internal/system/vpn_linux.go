@@ -0,0 +1,55 @@
+//go:build linux
+
+package system
+
+import (
+	"net"
+	"slices"
+)
+
+// tailscalePaths are where the tailscale command is installed besides the
+// PATH
+var tailscalePaths = []string{"/usr/bin/tailscale", "/usr/local/bin/tailscale"}
+
+// linkDNS returns the routing domains and DNS servers systemd-resolved
+// has for an interface. VPN clients register them there; without
+// systemd-resolved there is nothing per interface to find.
+func linkDNS(iface net.Interface) (domains, servers []string) {
+	if !isSystemdResolved() {
+		return nil, nil
+	}
+	r, err := connectResolved()
+	if err != nil {
+		return nil, nil
+	}
+
+	linkDomains, _ := r.linkDomains(iface.Name)
+	for _, domain := range linkDomains {
+		if domain.Domain != "." {
+			domains = append(domains, domain.Domain)
+		}
+	}
+	servers, _ = r.linkServers(iface.Name)
+	return domains, servers
+}
+
+// takesAllDNS tells whether an interface's DNS servers answer every
+// domain: with systemd-resolved, whether its link is a default route for
+// DNS; otherwise, whether they are in resolv.conf
+func takesAllDNS(iface net.Interface, servers []string) bool {
+	if !isSystemdResolved() {
+		current, _ := getCurrentDNS()
+		return slices.ContainsFunc(servers, func(server string) bool { return slices.Contains(current, server) })
+	}
+	r, err := connectResolved()
+	if err != nil {
+		return false
+	}
+
+	var defaultRoute bool
+	if r.linkProperty(iface.Name, "DefaultRoute", &defaultRoute) == nil && defaultRoute {
+		return true
+	}
+	domains, _ := r.linkDomains(iface.Name)
+	return slices.ContainsFunc(domains, func(domain resolvedDomain) bool { return domain.Domain == "." })
+}
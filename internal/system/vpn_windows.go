@@ -0,0 +1,35 @@
+//go:build windows
+
+package system
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+// tailscalePaths are where the tailscale command is installed besides the
+// PATH
+var tailscalePaths = []string{`C:\Program Files\Tailscale\tailscale.exe`}
+
+// linkDNS returns the connection-specific DNS suffix and the DNS servers
+// of an adapter, which VPN clients set on theirs
+func linkDNS(iface net.Interface) (domains, servers []string) {
+	servers, _ = getDNSForInterface(iface.Index)
+
+	script := fmt.Sprintf("(Get-DnsClient -InterfaceIndex %d).ConnectionSpecificSuffix", iface.Index)
+	output, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script).Output()
+	if err != nil {
+		return nil, servers
+	}
+	return strings.Fields(string(output)), servers
+}
+
+// takesAllDNS tells whether an interface's DNS servers answer every
+// domain, which is always so on Windows: the resolver sends queries to
+// the servers of every adapter, the VPN's included, and takes the first
+// answer
+func takesAllDNS(iface net.Interface, servers []string) bool {
+	return true
+}
@@ -0,0 +1,20 @@
+package system
+
+import (
+	"slices"
+	"strings"
+)
+
+// WiFiNetworks returns the names (SSIDs) of the Wi-Fi networks the
+// computer is connected to, sorted; none if it has no Wi-Fi or the OS
+// tools to ask are missing
+func WiFiNetworks() []string {
+	var ssids []string
+	for _, ssid := range wifiSSIDs() {
+		if ssid = strings.TrimSpace(ssid); ssid != "" && !slices.Contains(ssids, ssid) {
+			ssids = append(ssids, ssid)
+		}
+	}
+	slices.Sort(ssids)
+	return ssids
+}
@@ -0,0 +1,38 @@
+//go:build darwin
+
+package system
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// wifiSSIDs asks networksetup about each Wi-Fi hardware port, from lines
+// like "Current Wi-Fi Network: CorpWiFi"
+func wifiSSIDs() []string {
+	ports, err := exec.Command("networksetup", "-listallhardwareports").Output()
+	if err != nil {
+		return nil
+	}
+
+	var ssids []string
+	wifi := false
+	for _, line := range strings.Split(string(ports), "\n") {
+		if port, ok := strings.CutPrefix(line, "Hardware Port: "); ok {
+			wifi = port == "Wi-Fi" || port == "AirPort"
+			continue
+		}
+		device, ok := strings.CutPrefix(line, "Device: ")
+		if !ok || !wifi {
+			continue
+		}
+		output, err := exec.Command("networksetup", "-getairportnetwork", strings.TrimSpace(device)).Output()
+		if err != nil {
+			continue
+		}
+		if _, ssid, found := strings.Cut(strings.TrimSpace(string(output)), "Network: "); found {
+			ssids = append(ssids, ssid)
+		}
+	}
+	return ssids
+}
@@ -0,0 +1,27 @@
+//go:build linux
+
+package system
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// wifiSSIDs asks NetworkManager, or failing that the wireless extensions
+// through iwgetid, which networks are connected
+func wifiSSIDs() []string {
+	if output, err := exec.Command("nmcli", "-t", "-f", "active,ssid", "dev", "wifi").Output(); err == nil {
+		var ssids []string
+		for _, line := range strings.Split(string(output), "\n") {
+			// Terse output escapes colons in values as "\:"
+			if ssid, ok := strings.CutPrefix(line, "yes:"); ok {
+				ssids = append(ssids, strings.ReplaceAll(ssid, `\:`, ":"))
+			}
+		}
+		return ssids
+	}
+	if output, err := exec.Command("iwgetid", "-r").Output(); err == nil {
+		return strings.Split(string(output), "\n")
+	}
+	return nil
+}
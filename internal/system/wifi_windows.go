@@ -0,0 +1,27 @@
+//go:build windows
+
+package system
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// wifiSSIDs reads the "SSID : CorpWiFi" lines netsh lists for each
+// connected wireless interface
+func wifiSSIDs() []string {
+	output, err := exec.Command("netsh", "wlan", "show", "interfaces").Output()
+	if err != nil {
+		return nil
+	}
+
+	var ssids []string
+	for _, line := range strings.Split(string(output), "\n") {
+		name, value, found := strings.Cut(line, ":")
+		// Not "BSSID", the access point's address
+		if found && strings.TrimSpace(name) == "SSID" {
+			ssids = append(ssids, strings.TrimSpace(value))
+		}
+	}
+	return ssids
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package top
+
+// makeRaw leaves the terminal alone: keys take effect after Enter
+func makeRaw(fd int) (func(), error) {
+	return func() {}, nil
+}
+
+// termSize assumes a classic console
+func termSize(fd int) (int, int) {
+	return 80, 24
+}
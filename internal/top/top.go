@@ -0,0 +1,254 @@
+// Package top is a full-screen terminal dashboard for the daemon, for
+// machines without the GUI: live queries, counters and the main controls.
+package top
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zkmkarlsruhe/filterdns-client/internal/daemon"
+	"github.com/zkmkarlsruhe/filterdns-client/internal/dns"
+)
+
+const (
+	// refreshInterval is how often status, counters and queries update
+	refreshInterval = time.Second
+
+	// pauseDuration is how long the pause key turns filtering off
+	pauseDuration = 15 * time.Minute
+
+	// keptQueries caps the query stream held for redrawing
+	keptQueries = 500
+)
+
+// Terminal control sequences
+const (
+	enterScreen = "\033[?1049h\033[?25l" // Alternate screen, hidden cursor
+	leaveScreen = "\033[?25h\033[?1049l"
+	clearScreen = "\033[H\033[2J"
+)
+
+// model is what the dashboard shows
+type model struct {
+	status      *daemon.Status
+	report      *daemon.StatsReport
+	queries     []dns.QueryLogEntry
+	lastSeq     uint64
+	blockedOnly bool
+	message     string // Result of the last key
+	problem     string // Why the last refresh failed, if it did
+}
+
+// Run shows the dashboard until q or Ctrl-C is pressed
+func Run(client *daemon.Client) error {
+	fd := int(os.Stdin.Fd())
+	restore, err := makeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("terminal does not support full-screen mode: %w", err)
+	}
+	defer restore()
+
+	fmt.Print(enterScreen)
+	defer fmt.Print(leaveScreen)
+
+	keys := make(chan byte)
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if _, err := os.Stdin.Read(buf); err != nil {
+				close(keys)
+				return
+			}
+			keys <- buf[0]
+		}
+	}()
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	m := &model{}
+	m.refresh(client)
+	for {
+		m.render(termSize(fd))
+
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			switch key {
+			case 'q', 'Q', 3: // 3 is Ctrl-C
+				return nil
+			case 'e', 'E':
+				m.toggle(client)
+			case 'p', 'P':
+				m.pause(client)
+			case 'b', 'B':
+				m.blockedOnly = !m.blockedOnly
+			case 'c', 'C':
+				m.queries = nil
+			}
+			m.refresh(client)
+		case <-ticker.C:
+			m.refresh(client)
+		}
+	}
+}
+
+// refresh fetches status, counters and the queries since the last refresh
+func (m *model) refresh(client *daemon.Client) {
+	status, err := client.Status()
+	if err != nil {
+		m.problem = "Daemon not reachable: " + err.Error()
+		return
+	}
+	m.status = status
+	m.problem = ""
+
+	if report, err := client.Stats(""); err == nil {
+		m.report = report
+	}
+
+	filter := dns.QueryFilter{After: m.lastSeq}
+	if m.lastSeq == 0 {
+		filter.Limit = keptQueries
+	}
+	entries, err := client.QueryLog(filter)
+	if err != nil {
+		m.problem = "Query log unavailable: " + err.Error()
+		return
+	}
+	for _, entry := range entries {
+		m.lastSeq = entry.Seq
+	}
+	m.queries = append(m.queries, entries...)
+	if len(m.queries) > keptQueries {
+		m.queries = m.queries[len(m.queries)-keptQueries:]
+	}
+}
+
+// toggle turns filtering on or off
+func (m *model) toggle(client *daemon.Client) {
+	var err error
+	if m.status != nil && (m.status.Running || m.status.PausedUntil != nil) {
+		_, err = client.Disable()
+		m.message = "Filtering disabled"
+	} else {
+		_, err = client.Enable()
+		m.message = "Filtering enabled"
+	}
+	if err != nil {
+		m.message = "Error: " + err.Error()
+	}
+}
+
+// pause pauses filtering for pauseDuration, or resumes a pause
+func (m *model) pause(client *daemon.Client) {
+	var err error
+	if m.status != nil && m.status.PausedUntil != nil {
+		_, err = client.Resume()
+		m.message = "Filtering resumed"
+	} else {
+		_, err = client.Pause(pauseDuration)
+		m.message = fmt.Sprintf("Filtering paused for %s", strings.TrimSuffix(pauseDuration.String(), "0s"))
+	}
+	if err != nil {
+		m.message = "Error: " + err.Error()
+	}
+}
+
+// render draws the whole screen
+func (m *model) render(width, height int) {
+	var lines []string
+	add := func(format string, args ...any) {
+		lines = append(lines, truncate(fmt.Sprintf(format, args...), width))
+	}
+
+	add("FilterDNS  %s", time.Now().Format("15:04:05"))
+	if s := m.status; s != nil {
+		add("Profile:   %s on %s", s.Profile, s.ServerURL)
+		add("Filtering: %s", filteringState(s))
+	} else {
+		add("Profile:   -")
+		add("Filtering: unknown")
+	}
+	if r := m.report; r != nil {
+		add("Session:   %d queries, %d blocked, %d cache hits", r.Session.Queries, r.Session.Blocked, r.CacheHits)
+		add("Today:     %d queries, %d blocked", r.Today.Queries, r.Today.Blocked)
+		if l := r.Latency; l != nil {
+			add("Latency:   %s median, %s p95", l.Median.Round(time.Millisecond), l.P95.Round(time.Millisecond))
+		} else {
+			add("Latency:   -")
+		}
+		var top []string
+		for _, domain := range r.TopBlocked {
+			top = append(top, fmt.Sprintf("%s (%d)", domain.Domain, domain.Count))
+		}
+		add("Blocked:   %s", strings.Join(top, ", "))
+	}
+	add("")
+
+	title := "Queries"
+	if m.blockedOnly {
+		title = "Blocked queries"
+	}
+	add("%s", title)
+	add("%-8s  %-8s %-6s %-10s %s", "TIME", "RESULT", "TYPE", "SOURCE", "DOMAIN")
+
+	// Newest at the bottom, as many as fit above the two footer lines
+	rows := height - len(lines) - 2
+	var shown []string
+	for i := len(m.queries) - 1; i >= 0 && len(shown) < rows; i-- {
+		entry := m.queries[i]
+		if m.blockedOnly && !entry.Blocked {
+			continue
+		}
+		result := entry.Rcode
+		if entry.Blocked {
+			result = "BLOCKED"
+		}
+		shown = append(shown, truncate(fmt.Sprintf("%s  %-8s %-6s %-10s %s", entry.Time.Format("15:04:05"),
+			result, entry.Type, entry.Source, entry.Domain), width))
+	}
+	for i := len(shown) - 1; i >= 0; i-- {
+		lines = append(lines, shown[i])
+	}
+	for len(lines) < height-2 {
+		lines = append(lines, "")
+	}
+
+	if m.problem != "" {
+		add("%s", m.problem)
+	} else {
+		add("%s", m.message)
+	}
+	add("[e] enable/disable  [p] pause %s/resume  [b] blocked only  [c] clear  [q] quit",
+		strings.TrimSuffix(pauseDuration.String(), "0s"))
+
+	// Raw mode leaves output processing on, but be explicit about returns
+	fmt.Print(clearScreen + strings.Join(lines, "\r\n"))
+}
+
+// filteringState describes whether filtering is on
+func filteringState(s *daemon.Status) string {
+	switch {
+	case s.PausedUntil != nil:
+		return "paused until " + s.PausedUntil.Local().Format("15:04")
+	case s.Sync != nil && !s.Sync.FilteringEnabled:
+		return "paused on the server"
+	case s.Running:
+		return "on"
+	default:
+		return "off"
+	}
+}
+
+// truncate cuts s to width runes
+func truncate(s string, width int) string {
+	if r := []rune(s); len(r) > width {
+		return string(r[:width])
+	}
+	return s
+}
@@ -0,0 +1,326 @@
+package update
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Build-time variables (set via -ldflags)
+var (
+	// Version of this build, e.g. "1.4.0"; development builds are "dev",
+	// or what git describe says between tags, e.g. "v1.4.0-3-gabcdef"
+	Version = "dev"
+
+	// DefaultReleaseURL is the release manifest checked unless the config
+	// sets another, e.g. "https://filterdns.example.com/releases/latest.json"
+	DefaultReleaseURL = ""
+
+	// PublicKey is the base64 Ed25519 key release binaries are signed
+	// with. Builds without one refuse to update.
+	PublicKey = ""
+)
+
+// maxBinarySize bounds the download, well above any real release
+const maxBinarySize = 256 << 20
+
+// Release is the manifest published at the release URL
+type Release struct {
+	Version string           `json:"version"`
+	Assets  map[string]Asset `json:"assets"` // By AssetName
+}
+
+// Asset is one platform binary of a release
+type Asset struct {
+	URL       string `json:"url"`       // Absolute, or relative to the manifest
+	SHA256    string `json:"sha256"`    // Hex
+	Signature string `json:"signature"` // Base64 Ed25519 signature of SignedMessage
+}
+
+// SignedMessage is what the signature of an asset covers: its name, the
+// release version and the binary's checksum, one per line. Signing the
+// version and name along with the binary keeps a manifest from passing
+// off an older release, or another platform's binary, as the latest.
+func SignedMessage(name, version, sha256Hex string) []byte {
+	return []byte(name + "\n" + version + "\n" + strings.ToLower(sha256Hex) + "\n")
+}
+
+// Update is the result of a release check
+type Update struct {
+	Current   string `json:"current"`
+	Latest    string `json:"latest"`
+	Available bool   `json:"available"` // Latest is newer than Current
+
+	// Current is not a release but a development build, which any
+	// release may replace
+	Development bool `json:"development,omitempty"`
+
+	asset Asset
+	base  *url.URL
+}
+
+// AssetName is the manifest key of the binary for this platform, e.g.
+// "filterdnsd-linux-amd64"
+func AssetName() (string, error) {
+	exe, err := executable()
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSuffix(filepath.Base(exe), ".exe")
+	return fmt.Sprintf("%s-%s-%s", name, runtime.GOOS, runtime.GOARCH), nil
+}
+
+// Check fetches the release manifest at releaseURL, or DefaultReleaseURL
+// if empty, and compares it with this build
+func Check(releaseURL string) (*Update, error) {
+	if releaseURL == "" {
+		releaseURL = DefaultReleaseURL
+	}
+	if releaseURL == "" {
+		return nil, fmt.Errorf("no release URL configured (set one with: filterdns-client config set update-url <url>)")
+	}
+	base, err := url.Parse(releaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid release URL: %w", err)
+	}
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Get(releaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("release server returned status %d", resp.StatusCode)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to parse release manifest: %w", err)
+	}
+
+	name, err := AssetName()
+	if err != nil {
+		return nil, err
+	}
+	asset, ok := release.Assets[name]
+	if !ok {
+		return nil, fmt.Errorf("release %s has no binary for %s", release.Version, name)
+	}
+	if err := verifyAsset(name, release.Version, asset); err != nil {
+		return nil, err
+	}
+
+	newer, err := isNewer(release.Version, Version)
+	if err != nil {
+		return nil, err
+	}
+	return &Update{
+		Current:     Version,
+		Latest:      release.Version,
+		Available:   newer,
+		Development: isDevelopment(Version),
+		asset:       asset,
+		base:        base,
+	}, nil
+}
+
+// Install downloads the release binary, verifies it against the signed
+// checksum, and puts it in place of the running executable, whose path
+// it returns. The running process is unaffected until it is restarted.
+// Releases not newer than this build are refused, except on development
+// builds.
+func (u *Update) Install() (string, error) {
+	if !u.Available && !u.Development {
+		return "", fmt.Errorf("release %s is not newer than %s", u.Latest, u.Current)
+	}
+	exe, err := executable()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := u.download()
+	if err != nil {
+		return "", err
+	}
+
+	// The checksum was verified against the signature by Check
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), u.asset.SHA256) {
+		return "", fmt.Errorf("checksum mismatch for release %s", u.Latest)
+	}
+
+	if err := replace(exe, data); err != nil {
+		return "", err
+	}
+	return exe, nil
+}
+
+// download fetches the asset's binary
+func (u *Update) download() ([]byte, error) {
+	ref, err := url.Parse(u.asset.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid binary URL: %w", err)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Get(u.base.ResolveReference(ref).String())
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBinarySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	if len(data) > maxBinarySize {
+		return nil, fmt.Errorf("download failed: binary larger than %d MB", maxBinarySize>>20)
+	}
+	return data, nil
+}
+
+// replace writes data next to exe and renames it over exe, so exe is
+// either the old or the new binary, never a partial one
+func replace(exe string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".filterdns-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to write update: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write update: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write update: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write update: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return fmt.Errorf("failed to write update: %w", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		// A running executable cannot be replaced, only renamed
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", exe, err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", exe, err)
+	}
+	return nil
+}
+
+// executable returns the resolved path of the running binary
+func executable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to get executable path: %w", err)
+	}
+	exe, err = filepath.EvalSymlinks(exe)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve symlinks: %w", err)
+	}
+	return exe, nil
+}
+
+// verifyAsset checks the signature of an asset over its name, the
+// release version and its checksum
+func verifyAsset(name, version string, asset Asset) error {
+	key, err := publicKey()
+	if err != nil {
+		return err
+	}
+	if _, err := hex.DecodeString(asset.SHA256); err != nil || len(asset.SHA256) != 2*sha256.Size {
+		return fmt.Errorf("invalid checksum for %s in release %s", name, version)
+	}
+	signature, err := base64.StdEncoding.DecodeString(asset.Signature)
+	if err != nil || !ed25519.Verify(key, SignedMessage(name, version, asset.SHA256), signature) {
+		return fmt.Errorf("invalid signature on release %s", version)
+	}
+	return nil
+}
+
+// publicKey decodes the release signing key built into this binary
+func publicKey() (ed25519.PublicKey, error) {
+	if PublicKey == "" {
+		return nil, fmt.Errorf("this build has no release signing key, updates are disabled")
+	}
+	key, err := base64.StdEncoding.DecodeString(PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid release signing key in this build")
+	}
+	return key, nil
+}
+
+// isDevelopment reports whether version is not a release: "dev", a git
+// hash, or a build past a tag such as "v1.4.0-3-gabcdef"
+func isDevelopment(version string) bool {
+	_, err := parseVersion(version)
+	return err != nil
+}
+
+// isNewer reports whether version latest is newer than current. Releases
+// are dotted numbers with an optional "v" prefix; a development build is
+// never older than a release.
+func isNewer(latest, current string) (bool, error) {
+	l, err := parseVersion(latest)
+	if err != nil {
+		return false, err
+	}
+	if isDevelopment(current) {
+		return false, nil
+	}
+	c, _ := parseVersion(current)
+
+	for i := 0; i < len(l) || i < len(c); i++ {
+		var a, b int
+		if i < len(l) {
+			a = l[i]
+		}
+		if i < len(c) {
+			b = c[i]
+		}
+		if a != b {
+			return a > b, nil
+		}
+	}
+	return false, nil
+}
+
+// parseVersion splits "v1.4.0" into its numbers
+func parseVersion(version string) ([]int, error) {
+	var parts []int
+	for _, field := range strings.Split(strings.TrimPrefix(version, "v"), ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q", version)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}